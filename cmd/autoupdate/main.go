@@ -2,27 +2,35 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
-	"github.com/OpenSlides/openslides-autoupdate-service/internal/autoupdate"
-	autoupdateHttp "github.com/OpenSlides/openslides-autoupdate-service/internal/http"
-	"github.com/OpenSlides/openslides-autoupdate-service/internal/projector"
-	"github.com/OpenSlides/openslides-autoupdate-service/internal/projector/slide"
-	"github.com/OpenSlides/openslides-autoupdate-service/internal/restrict"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/record"
 	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
 	"github.com/OpenSlides/openslides-autoupdate-service/pkg/auth"
 	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/models"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/nats"
 	"github.com/OpenSlides/openslides-autoupdate-service/pkg/redis"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/service"
 	"github.com/OpenSlides/openslides-permission-service/pkg/permission"
+	_ "github.com/jackc/pgx/v4/stdlib" // Postgres driver for DATASTORE=postgres.
 )
 
+// messageBus is the source of datastore updates and logout events. It is
+// implemented by redis, nats and the playback of a recording.
 type messageBus interface {
 	datastore.Updater
 	auth.LogoutEventer
@@ -41,21 +49,71 @@ func defaultEnv() map[string]string {
 		"AUTOUPDATE_HOST": "",
 		"AUTOUPDATE_PORT": "9012",
 
+		"DATASTORE":                 "reader",
 		"DATASTORE_READER_HOST":     "localhost",
 		"DATASTORE_READER_PORT":     "9010",
 		"DATASTORE_READER_PROTOCOL": "http",
 
+		"DATABASE_HOST": "localhost",
+		"DATABASE_PORT": "5432",
+		"DATABASE_NAME": "openslides",
+		"DATABASE_USER": "openslides",
+
 		"MESSAGING":        "fake",
 		"MESSAGE_BUS_HOST": "localhost",
 		"MESSAGE_BUS_PORT": "6379",
 		"REDIS_TEST_CONN":  "true",
 
+		"REDIS_CONSUMER_GROUP": "",
+
+		"NATS_URL":     "nats://localhost:4222",
+		"NATS_DURABLE": "",
+
 		"AUTH":          "fake",
 		"AUTH_PROTOCOL": "http",
 		"AUTH_HOST":     "localhost",
 		"AUTH_PORT":     "9004",
 
+		"AUTH_SESSION_CHECK": "10m",
+
+		"INTERNAL_AUTH_ROUTES": "/internal/autoupdate/query,/system/autoupdate/exists",
+
+		"CONNECT_RATE":  "0",
+		"CONNECT_BURST": "10",
+
+		"TRUSTED_PROXIES": "",
+
+		"CORS_ALLOWED_ORIGINS":   "",
+		"CORS_ALLOW_CREDENTIALS": "false",
+
 		"DEACTIVATE_PERMISSION":  "false",
+		"RESTRICT_SHARING":       "false",
+		"MEETING_ISOLATION":      "true",
+		"VOTE_URL":               "/system/vote",
+		"DISABLED_FEATURES":      "",
+		"BLOCKED_FIELDS":         "",
+		"MAX_REQUEST_DEPTH":      "15",
+		"MAX_REQUEST_KEYS":       "1000000",
+		"MAX_REQUEST_SIZE":       "1048576",
+		"REQUEST_TIMEOUT":        "10s",
+		"DEBOUNCE":               "0s",
+		"RESYNC_SLOW_CLIENTS":    "false",
+		"PROJECTOR_USERS":        "",
+		"PRUNE_TIME":             "10m",
+		"SERVER_TIME_INTERVAL":   "10s",
+		"CACHE_TTL":              "10m",
+		"CACHE_MAX_SIZE":         "0",
+		"DATASTORE_BATCH_WINDOW": "2ms",
+		"DATASTORE_ATTEMPTS":     "3",
+		"DATASTORE_BACKOFF":      "50ms",
+		"DATASTORE_TIMEOUT":      "0s",
+		"DATASTORE_COOLDOWN":     "5s",
+		"WARMUP":                 "",
+		"MODELS_FILE":            "",
+		"RECORD_FILE":            "",
+		"PLAYBACK_FILE":          "",
+		"PLAYBACK_SPEED":         "1",
+		"DEBUG_LISTEN_ADDR":      "",
 		"OPENSLIDES_DEVELOPMENT": "false",
 	}
 
@@ -70,8 +128,11 @@ func defaultEnv() map[string]string {
 
 func secret(name string, dev bool) (string, error) {
 	defaultSecrets := map[string]string{
-		"auth_token_key":  debugKey,
-		"auth_cookie_key": debugKey,
+		"auth_token_key":      debugKey,
+		"auth_cookie_key":     debugKey,
+		"postgres_password":   "openslides",
+		"admin_password":      "openslides",
+		"internal_auth_token": "openslides",
 	}
 
 	d, ok := defaultSecrets[name]
@@ -116,24 +177,47 @@ func run() error {
 	}
 
 	// Permission Service.
-	var perms restrict.Permissioner = &test.MockPermission{Default: true}
-	var updater autoupdate.UserUpdater = new(test.UserUpdater)
+	var perms service.Permissioner = &test.MockPermission{Default: true}
+	var updater service.UserUpdater = new(test.UserUpdater)
+	historicPerms := func(datastore.Getter) service.Permissioner { return perms }
 	permService := "fake"
 	if env["DEACTIVATE_PERMISSION"] == "false" {
 		permService = "permission"
 		p := permission.New(datastoreService)
 		perms = p
 		updater = p
+		historicPerms = func(ds datastore.Getter) service.Permissioner { return permission.New(ds) }
 	}
 	fmt.Println("Permission-Service: " + permService)
 
+	// Models.
+	var schema *models.Models
+	var modelOptions []service.Option
+	if fileName := env["MODELS_FILE"]; fileName != "" {
+		schema, err = loadModels(fileName)
+		if err != nil {
+			return fmt.Errorf("loading models: %w", err)
+		}
+		modelOptions = append(modelOptions, service.WithModels(schema))
+	}
+
 	// Restricter Service.
-	checker := restrict.RelationChecker(restrict.RelationLists, perms)
-	restricter := restrict.New(perms, checker)
+	restricter := service.DefaultRestricter(datastoreService, perms, modelOptions...)
+	if env["RESTRICT_SHARING"] == "true" {
+		relationLists := restrict.RelationLists
+		if schema != nil {
+			relationLists = schema.RelationLists()
+		}
 
-	// Create http mux to add urls.
-	mux := http.NewServeMux()
-	autoupdateHttp.Health(mux)
+		shared := restrict.NewShared(
+			restricter,
+			restrict.GroupFingerprint(datastoreService),
+			relationLists,
+			restrict.SharedCollections...,
+		)
+		datastoreService.RegisterChangeListener(shared.Invalidate)
+		restricter = shared
+	}
 
 	// Auth Service.
 	authService, err := buildAuth(env, r, closed, errHandler)
@@ -141,17 +225,192 @@ func run() error {
 		return fmt.Errorf("creating auth adapter: %w", err)
 	}
 
-	// Autoupdate Service.
-	service := autoupdate.New(datastoreService, restricter, updater, closed)
-	autoupdateHttp.Complex(mux, authService, service, service)
-	autoupdateHttp.Simple(mux, authService, service)
+	disabled, err := disabledFeatures(env["DISABLED_FEATURES"])
+	if err != nil {
+		return fmt.Errorf("reading DISABLED_FEATURES: %w", err)
+	}
+
+	maxDepth, err := strconv.Atoi(env["MAX_REQUEST_DEPTH"])
+	if err != nil {
+		return fmt.Errorf("reading MAX_REQUEST_DEPTH: %w", err)
+	}
+
+	maxKeys, err := strconv.Atoi(env["MAX_REQUEST_KEYS"])
+	if err != nil {
+		return fmt.Errorf("reading MAX_REQUEST_KEYS: %w", err)
+	}
 
-	// Projector Service.
-	projector.Register(datastoreService, slide.Slides())
+	maxSize, err := strconv.ParseInt(env["MAX_REQUEST_SIZE"], 10, 64)
+	if err != nil {
+		return fmt.Errorf("reading MAX_REQUEST_SIZE: %w", err)
+	}
+
+	requestTimeout, err := time.ParseDuration(env["REQUEST_TIMEOUT"])
+	if err != nil {
+		return fmt.Errorf("reading REQUEST_TIMEOUT: %w", err)
+	}
+
+	debounce, err := time.ParseDuration(env["DEBOUNCE"])
+	if err != nil {
+		return fmt.Errorf("reading DEBOUNCE: %w", err)
+	}
+
+	serviceOptions := []service.Option{
+		service.WithUserUpdater(updater),
+		service.WithHistoricPermission(historicPerms),
+		service.WithVoteURL(env["VOTE_URL"]),
+		service.WithDisabledFeatures(disabled...),
+		service.WithRequestLimits(maxDepth, maxKeys),
+		service.WithRequestBodyLimits(maxSize, requestTimeout),
+		service.WithDebounce(debounce),
+	}
+
+	serviceOptions = append(serviceOptions, modelOptions...)
+
+	blocked, err := blockedFields(env["BLOCKED_FIELDS"])
+	if err != nil {
+		return fmt.Errorf("reading BLOCKED_FIELDS: %w", err)
+	}
+	if len(blocked) > 0 {
+		serviceOptions = append(serviceOptions, service.WithBlocked(blocked...))
+	}
+
+	// Without the permission service, every user can see everything.
+	if env["MEETING_ISOLATION"] == "true" && env["DEACTIVATE_PERMISSION"] == "false" {
+		serviceOptions = append(serviceOptions, service.WithIsolation())
+	}
+
+	pruneTime, err := time.ParseDuration(env["PRUNE_TIME"])
+	if err != nil {
+		return fmt.Errorf("reading PRUNE_TIME: %w", err)
+	}
+	if pruneTime < time.Minute {
+		return fmt.Errorf("PRUNE_TIME has to be at least one minute, got %s", pruneTime)
+	}
+	serviceOptions = append(serviceOptions, service.WithPruneTime(pruneTime))
+
+	serverTimeInterval, err := time.ParseDuration(env["SERVER_TIME_INTERVAL"])
+	if err != nil {
+		return fmt.Errorf("reading SERVER_TIME_INTERVAL: %w", err)
+	}
+	serviceOptions = append(serviceOptions, service.WithServerTime(serverTimeInterval))
+
+	if counter, ok := r.(service.VoteCounter); ok {
+		serviceOptions = append(serviceOptions, service.WithVoteCount(counter))
+	}
+
+	if p, ok := r.(service.Pinger); ok {
+		serviceOptions = append(serviceOptions, service.WithReadyCheck("message_bus", p))
+	}
+
+	// The full update endpoint is optional. Without the secret, it is
+	// disabled.
+	if password, err := secret("admin_password", env["OPENSLIDES_DEVELOPMENT"] != "false"); err == nil {
+		serviceOptions = append(serviceOptions, service.WithAdminPassword(password))
+	} else {
+		fmt.Println("Full update endpoint disabled: no admin_password")
+	}
+
+	// The internal auth is optional. Without the secret, other services can not
+	// send requests on behalf of a user.
+	if token, err := secret("internal_auth_token", env["OPENSLIDES_DEVELOPMENT"] != "false"); err == nil {
+		var routes []string
+		for _, route := range strings.Split(env["INTERNAL_AUTH_ROUTES"], ",") {
+			if route = strings.TrimSpace(route); route != "" {
+				routes = append(routes, route)
+			}
+		}
+		serviceOptions = append(serviceOptions, service.WithInternalAuth(token, routes...))
+	} else {
+		fmt.Println("Internal auth disabled: no internal_auth_token")
+	}
+
+	connectRate, err := strconv.ParseFloat(env["CONNECT_RATE"], 64)
+	if err != nil {
+		return fmt.Errorf("reading CONNECT_RATE: %w", err)
+	}
+	connectBurst, err := strconv.Atoi(env["CONNECT_BURST"])
+	if err != nil {
+		return fmt.Errorf("reading CONNECT_BURST: %w", err)
+	}
+	if connectRate > 0 {
+		serviceOptions = append(serviceOptions, service.WithConnectLimit(connectRate, connectBurst))
+	}
+
+	if value := env["TRUSTED_PROXIES"]; value != "" {
+		proxies, err := parseProxies(value)
+		if err != nil {
+			return fmt.Errorf("reading TRUSTED_PROXIES: %w", err)
+		}
+		serviceOptions = append(serviceOptions, service.WithTrustedProxies(proxies...))
+	}
+
+	if origins := env["CORS_ALLOWED_ORIGINS"]; origins != "" {
+		var allowed []string
+		for _, origin := range strings.Split(origins, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				allowed = append(allowed, origin)
+			}
+		}
+		serviceOptions = append(serviceOptions, service.WithCORS(allowed, env["CORS_ALLOW_CREDENTIALS"] == "true"))
+	}
+
+	if env["RESYNC_SLOW_CLIENTS"] == "true" {
+		serviceOptions = append(serviceOptions, service.WithResync())
+	}
+
+	if users := env["PROJECTOR_USERS"]; users != "" {
+		var uids []int
+		for _, user := range strings.Split(users, ",") {
+			uid, err := strconv.Atoi(strings.TrimSpace(user))
+			if err != nil {
+				return fmt.Errorf("invalid user id in PROJECTOR_USERS: %w", err)
+			}
+			uids = append(uids, uid)
+		}
+		serviceOptions = append(serviceOptions, service.WithProjectorUsers(uids...))
+	}
+
+	if fileName := env["RECORD_FILE"]; fileName != "" {
+		f, err := os.Create(fileName)
+		if err != nil {
+			return fmt.Errorf("creating record file: %w", err)
+		}
+		defer f.Close()
+
+		fmt.Printf("Record to %s\n", fileName)
+		serviceOptions = append(serviceOptions, service.WithRecording(f))
+	}
+
+	// Autoupdate Service.
+	autoupdateService := service.New(
+		datastoreService,
+		authService,
+		restricter,
+		closed,
+		serviceOptions...,
+	)
+
+	if err := warmup(env["WARMUP"], autoupdateService); err != nil {
+		// The service also works with an empty cache.
+		log.Printf("Warmup failed: %v", err)
+	}
 
 	// Create http server.
 	listenAddr := ":" + env["AUTOUPDATE_PORT"]
-	srv := &http.Server{Addr: listenAddr, Handler: mux}
+	srv := &http.Server{Addr: listenAddr, Handler: autoupdateService.Handler()}
+
+	// Create internal http server for profiling.
+	var debugSrv *http.Server
+	if debugAddr := env["DEBUG_LISTEN_ADDR"]; debugAddr != "" {
+		debugSrv = &http.Server{Addr: debugAddr, Handler: autoupdateService.DebugHandler()}
+		go func() {
+			fmt.Printf("Listen for debug requests on %s\n", debugAddr)
+			if err := debugSrv.ListenAndServe(); err != http.ErrServerClosed {
+				log.Printf("Debug HTTP server failed: %v", err)
+			}
+		}()
+	}
 
 	// Shutdown logic in separate goroutine.
 	wait := make(chan error)
@@ -159,6 +418,15 @@ func run() error {
 		waitForShutdown()
 
 		close(closed)
+		if closer, ok := r.(interface{ Close() }); ok {
+			closer.Close()
+		}
+		if debugSrv != nil {
+			if err := debugSrv.Close(); err != nil {
+				log.Printf("Debug HTTP server shutdown: %v", err)
+			}
+		}
+
 		if err := srv.Shutdown(context.Background()); err != nil {
 			wait <- fmt.Errorf("HTTP server shutdown: %w", err)
 			return
@@ -191,13 +459,137 @@ func waitForShutdown() {
 	}()
 }
 
+// warmup loads the keys of the warmup request into the cache. The value can be
+// `default` for service.DefaultWarmup or a filename.
+func warmup(value string, s *service.Service) error {
+	if value == "" {
+		return nil
+	}
+
+	var request io.Reader = strings.NewReader(service.DefaultWarmup)
+	if value != "default" {
+		f, err := os.Open(value)
+		if err != nil {
+			return fmt.Errorf("opening warmup file: %w", err)
+		}
+		defer f.Close()
+		request = f
+	}
+
+	start := time.Now()
+	count, err := s.Warmup(context.Background(), request)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Warmup: loaded %d keys in %s\n", count, time.Since(start))
+	return nil
+}
+
 // buildDatastore configures the datastore service.
 func buildDatastore(env map[string]string, receiver datastore.Updater, closed <-chan struct{}, errHandler func(error)) (*datastore.Datastore, error) {
 	protocol := env["DATASTORE_READER_PROTOCOL"]
 	host := env["DATASTORE_READER_HOST"]
 	port := env["DATASTORE_READER_PORT"]
 	url := protocol + "://" + host + ":" + port
-	return datastore.New(url, closed, errHandler, receiver), nil
+
+	cacheTTL, err := time.ParseDuration(env["CACHE_TTL"])
+	if err != nil {
+		return nil, fmt.Errorf("reading CACHE_TTL: %w", err)
+	}
+	if cacheTTL < 0 {
+		return nil, fmt.Errorf("CACHE_TTL can not be negative, got %s", cacheTTL)
+	}
+
+	cacheMaxSize, err := strconv.Atoi(env["CACHE_MAX_SIZE"])
+	if err != nil {
+		return nil, fmt.Errorf("reading CACHE_MAX_SIZE: %w", err)
+	}
+	if cacheMaxSize < 0 {
+		return nil, fmt.Errorf("CACHE_MAX_SIZE can not be negative, got %d", cacheMaxSize)
+	}
+
+	batchWindow, err := time.ParseDuration(env["DATASTORE_BATCH_WINDOW"])
+	if err != nil {
+		return nil, fmt.Errorf("reading DATASTORE_BATCH_WINDOW: %w", err)
+	}
+
+	retry := datastore.DefaultRetry
+	retry.Attempts, err = strconv.Atoi(env["DATASTORE_ATTEMPTS"])
+	if err != nil {
+		return nil, fmt.Errorf("reading DATASTORE_ATTEMPTS: %w", err)
+	}
+
+	retry.Backoff, err = time.ParseDuration(env["DATASTORE_BACKOFF"])
+	if err != nil {
+		return nil, fmt.Errorf("reading DATASTORE_BACKOFF: %w", err)
+	}
+
+	retry.Timeout, err = time.ParseDuration(env["DATASTORE_TIMEOUT"])
+	if err != nil {
+		return nil, fmt.Errorf("reading DATASTORE_TIMEOUT: %w", err)
+	}
+
+	cooldown, err := time.ParseDuration(env["DATASTORE_COOLDOWN"])
+	if err != nil {
+		return nil, fmt.Errorf("reading DATASTORE_COOLDOWN: %w", err)
+	}
+
+	options := []datastore.Option{
+		datastore.WithCacheTTL(cacheTTL),
+		datastore.WithCacheSize(cacheMaxSize << 20),
+		datastore.WithBatchWindow(batchWindow),
+		datastore.WithRetry(retry),
+		datastore.WithCooldown(cooldown),
+	}
+
+	switch env["DATASTORE"] {
+	case "reader":
+		fmt.Printf("Datastore: %s\n", url)
+
+	case "postgres":
+		source, err := buildPostgres(env)
+		if err != nil {
+			return nil, fmt.Errorf("creating postgres source: %w", err)
+		}
+		options = append(options, datastore.WithSource(source))
+
+	default:
+		return nil, fmt.Errorf("unknown datastore %s", env["DATASTORE"])
+	}
+
+	return datastore.New(
+		url,
+		closed,
+		errHandler,
+		receiver,
+		options...,
+	), nil
+}
+
+// buildPostgres connects to the database of the datastore.
+func buildPostgres(env map[string]string) (*datastore.Postgres, error) {
+	password, err := secret("postgres_password", env["OPENSLIDES_DEVELOPMENT"] != "false")
+	if err != nil {
+		return nil, fmt.Errorf("getting postgres password: %w", err)
+	}
+
+	dsn := fmt.Sprintf(
+		"host='%s' port='%s' dbname='%s' user='%s' password='%s'",
+		env["DATABASE_HOST"],
+		env["DATABASE_PORT"],
+		env["DATABASE_NAME"],
+		env["DATABASE_USER"],
+		strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(password),
+	)
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+
+	fmt.Printf("Datastore: postgres on %s:%s\n", env["DATABASE_HOST"], env["DATABASE_PORT"])
+	return datastore.NewPostgres(db), nil
 }
 
 // buildReceiver builds the receiver needed by the datastore service. It uses
@@ -208,6 +600,7 @@ func buildReceiver(env map[string]string) (messageBus, error) {
 	fmt.Printf("Messaging Service: %s\n", serviceName)
 
 	var conn redis.Connection
+	var group string
 	switch serviceName {
 	case "redis":
 		redisAddress := env["MESSAGE_BUS_HOST"] + ":" + env["MESSAGE_BUS_PORT"]
@@ -219,18 +612,51 @@ func buildReceiver(env map[string]string) (messageBus, error) {
 		}
 
 		conn = c
+		group = env["REDIS_CONSUMER_GROUP"]
 
 	case "fake":
 		conn = redis.BlockingConn{}
+
+	case "nats":
+		n, err := nats.New(env["NATS_URL"], env["NATS_DURABLE"])
+		if err != nil {
+			return nil, fmt.Errorf("connect to nats: %w", err)
+		}
+		return n, nil
+
+	case "playback":
+		return buildPlayer(env)
+
 	default:
 		return nil, fmt.Errorf("unknown messagin service %s", serviceName)
 	}
 
-	return &redis.Redis{Conn: conn}, nil
+	return &redis.Redis{Conn: conn, Group: group}, nil
+}
+
+// buildPlayer creates a messaging service, that plays the changes of a
+// recording.
+func buildPlayer(env map[string]string) (messageBus, error) {
+	speed, err := strconv.ParseFloat(env["PLAYBACK_SPEED"], 64)
+	if err != nil {
+		return nil, fmt.Errorf("reading PLAYBACK_SPEED: %w", err)
+	}
+
+	f, err := os.Open(env["PLAYBACK_FILE"])
+	if err != nil {
+		return nil, fmt.Errorf("opening playback file: %w", err)
+	}
+	defer f.Close()
+
+	player, err := record.NewPlayer(f, speed)
+	if err != nil {
+		return nil, fmt.Errorf("reading playback file: %w", err)
+	}
+	return player, nil
 }
 
 // buildAuth returns the auth service needed by the http server.
-func buildAuth(env map[string]string, receiver auth.LogoutEventer, closed <-chan struct{}, errHandler func(error)) (autoupdateHttp.Authenticater, error) {
+func buildAuth(env map[string]string, receiver auth.LogoutEventer, closed <-chan struct{}, errHandler func(error)) (service.Authenticater, error) {
 	method := env["AUTH"]
 	switch method {
 	case "ticket":
@@ -254,8 +680,13 @@ func buildAuth(env map[string]string, receiver auth.LogoutEventer, closed <-chan
 		port := env["AUTH_PORT"]
 		url := protocol + "://" + host + ":" + port
 
+		sessionCheck, err := time.ParseDuration(env["AUTH_SESSION_CHECK"])
+		if err != nil {
+			return nil, fmt.Errorf("reading AUTH_SESSION_CHECK: %w", err)
+		}
+
 		fmt.Printf("Auth Service: %s\n", url)
-		return auth.New(url, receiver, closed, errHandler, []byte(tokenKey), []byte(cookieKey))
+		return auth.New(url, receiver, closed, errHandler, []byte(tokenKey), []byte(cookieKey), auth.WithSessionCheck(sessionCheck))
 	case "fake":
 		fmt.Println("Auth Method: FakeAuth (User ID 1 for all requests)")
 		return test.Auth(1), nil
@@ -277,3 +708,90 @@ func openSecret(name string) (string, error) {
 
 	return string(secret), nil
 }
+
+// disabledFeatures parses a comma separated list of features. It returns an
+// error, if a feature is unknown.
+func disabledFeatures(value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	known := make(map[string]bool)
+	for _, feature := range service.Features() {
+		known[feature] = true
+	}
+
+	features := strings.Split(value, ",")
+	for i, feature := range features {
+		feature = strings.TrimSpace(feature)
+		if !known[feature] {
+			return nil, fmt.Errorf("unknown feature `%s`, known features are: %s", feature, strings.Join(service.Features(), ", "))
+		}
+		features[i] = feature
+	}
+	return features, nil
+}
+
+// blockedFields parses a comma separated list of collections and fields in
+// the form collection/field. It returns an error, if an entry has another
+// form.
+func blockedFields(value string) ([]string, error) {
+	var entries []string
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, "/")
+		if len(parts) > 2 || parts[0] == "" || (len(parts) == 2 && parts[1] == "") {
+			return nil, fmt.Errorf("invalid entry `%s`, expected a collection or collection/field", entry)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// parseProxies parses a comma separated list of ip addresses and networks in
+// the CIDR notation.
+func parseProxies(value string) ([]*net.IPNet, error) {
+	var proxies []*net.IPNet
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid ip address `%s`", entry)
+			}
+
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+			}
+			bits := 8 * len(ip)
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network `%s`: %w", entry, err)
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
+// loadModels reads a models.yml file.
+func loadModels(fileName string) (*models.Models, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+
+	return models.Parse(f)
+}
@@ -7,6 +7,8 @@ require (
 	github.com/OpenSlides/openslides-permission-service v0.0.0-20210422132938-c40703f752e7
 	github.com/dgrijalva/jwt-go/v4 v4.0.0-preview1
 	github.com/gomodule/redigo v1.8.4
+	github.com/jackc/pgx/v4 v4.11.0
+	github.com/nats-io/nats.go v1.11.0
 	github.com/ostcar/topic v0.3.4-0.20200613094955-61bb28837a98
 	github.com/stretchr/testify v1.7.0
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
@@ -11,32 +11,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
+	"sync"
 	"time"
 
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/intern"
 	"github.com/ostcar/topic"
 )
 
-const (
-	// pruneTime defines how long a topic id will be valid. If a client needs
-	// more time to process the data, it will get an error and has to reconnect.
-	// A higher value means, that more memory is used.
-	pruneTime = 10 * time.Minute
-
-	// cacheResetTime defines when the cache should be reseted.
-	//
-	// When the datastore runs for a long time, its cache grows bigger and more
-	// calculated keys have to be calculated. A reset means, that everything
-	// gets cleaned.
-	//
-	// A high value means more memory and cpu usage after some time. A lower
-	// value means more Requests to the Datastore Service and therefore a slower
-	// responce time for the clients.
-	//
-	// TODO: This should be a high value, for example time.Hour. It is only a
-	// smal value, so it happens more often in development and we might find
-	// some bugs.
-	datastoreCacheResetTime = 10 * time.Second
-)
+// DefaultPruneTime defines how long a topic id will be valid. If a client
+// needs more time to process the data, it will get an error and has to
+// reconnect. A higher value means, that more memory is used.
+const DefaultPruneTime = 10 * time.Minute
 
 // Format of keys in the topic that shows, that a full update is necessary. It
 // is in the same namespace then model names. So make sure, there is no model
@@ -46,25 +32,67 @@ const fullUpdateFormat = "fullupdate/%d"
 // Autoupdate holds the state of the autoupdate service. It has to be initialized
 // with autoupdate.New().
 type Autoupdate struct {
+	// slowClientResyncs is used with sync/atomic. It has to be the first field
+	// to be 64-bit aligned on 32-bit systems.
+	slowClientResyncs uint64
+
 	datastore  Datastore
 	restricter Restricter
 	topic      *topic.Topic
+	relations  map[string]string
+
+	notifications *notifyStore
+	histories     *historyStore
+	positions     positionStore
+
+	debounce    time.Duration
+	debounceMu  sync.Mutex
+	pendingKeys map[string]bool
+
+	resync    bool
+	pruneTime time.Duration
+
+	projectorUsers map[int]bool
+
+	connMu      sync.Mutex
+	connections map[*Connection]bool
+
+	hooks []ConnectionHook
+
+	// collections are the known collections, used to validate keys. If nil,
+	// the keys are not validated.
+	collections map[string]bool
+
+	// invalidMu protects the fields to log invalid keys.
+	invalidMu           sync.Mutex
+	invalidKeys         map[string]bool
+	clientInvalidCount  int
+	clientInvalidLogged time.Time
 }
 
 // New creates a new autoupdate service.
-func New(datastore Datastore, restricter Restricter, userUpater UserUpdater, closed <-chan struct{}) *Autoupdate {
+func New(datastore Datastore, restricter Restricter, userUpater UserUpdater, closed <-chan struct{}, options ...Option) *Autoupdate {
 	a := &Autoupdate{
-		datastore:  datastore,
-		restricter: restricter,
-		topic:      topic.New(topic.WithClosed(closed)),
+		datastore:     datastore,
+		restricter:    restricter,
+		topic:         topic.New(topic.WithClosed(closed)),
+		pruneTime:     DefaultPruneTime,
+		connections:   make(map[*Connection]bool),
+		notifications: newNotifyStore(),
+		histories:     newHistoryStore(MaxStoredHistoryKeys),
+	}
+
+	for _, o := range options {
+		o(a)
 	}
 
 	// Update the topic when an data update is received.
 	a.datastore.RegisterChangeListener(func(data map[string]json.RawMessage) error {
 		keys := make([]string, 0, len(data))
 		for k := range data {
-			keys = append(keys, k)
+			keys = append(keys, intern.String(k))
 		}
+		keys = a.dropInvalidKeys(keys, false)
 
 		uids, err := userUpater.AdditionalUpdate(context.TODO(), data)
 		if err != nil {
@@ -75,12 +103,17 @@ func New(datastore Datastore, restricter Restricter, userUpater UserUpdater, clo
 			keys = append(keys, fmt.Sprintf(fullUpdateFormat, uid))
 		}
 
-		a.topic.Publish(keys...)
+		if p, ok := a.datastore.(provenancer); ok {
+			if key := provenanceTopicKey(p.Provenance()); key != "" {
+				keys = append(keys, key)
+			}
+		}
+
+		a.publish(keys)
 		return nil
 	})
 
 	go a.pruneTopic(closed)
-	go a.resetCache(closed)
 
 	return a
 }
@@ -89,12 +122,28 @@ func New(datastore Datastore, restricter Restricter, userUpater UserUpdater, clo
 // returns a Connection object, that can be used to receive the data.
 //
 // There is no need to "close" the Connection object.
-func (a *Autoupdate) Connect(userID int, kb KeysBuilder) *Connection {
-	return &Connection{
+func (a *Autoupdate) Connect(userID int, kb KeysBuilder, options ...ConnectionOption) *Connection {
+	c := &Connection{
 		autoupdate: a,
 		uid:        userID,
 		kb:         kb,
+		created:    time.Now(),
+
+		projectorMode: a.projectorUsers[userID],
 	}
+
+	for _, o := range options {
+		o(c)
+	}
+
+	if c.notify {
+		c.notifyRead = a.notifications.last()
+	}
+
+	if c.reconnect != 0 {
+		c.filter.restored = a.histories.take(userID, c.reconnect)
+	}
+	return c
 }
 
 // LastID returns the id of the last data update.
@@ -104,9 +153,30 @@ func (a *Autoupdate) LastID() uint64 {
 
 // Live writes data in json-format to the given writer until it closes. It
 // flushes after each message.
-func (a *Autoupdate) Live(ctx context.Context, userID int, w io.Writer, kb KeysBuilder) error {
-	conn := a.Connect(userID, kb)
-	encoder := json.NewEncoder(w)
+//
+// The registered ConnectionHooks are called for the connection.
+func (a *Autoupdate) Live(ctx context.Context, userID int, w io.Writer, kb KeysBuilder, options ...ConnectionOption) (err error) {
+	conn := a.Connect(userID, kb, options...)
+	counter := &countWriter{w: w}
+	encoder := newMessageEncoder(counter)
+
+	a.connMu.Lock()
+	a.connections[conn] = true
+	a.connMu.Unlock()
+
+	a.hookConnect(ctx, userID)
+
+	defer func() {
+		a.connMu.Lock()
+		delete(a.connections, conn)
+		a.connMu.Unlock()
+
+		if conn.checksum {
+			a.histories.add(userID, &conn.filter)
+		}
+
+		a.hookDisconnect(ctx, userID, err)
+	}()
 
 	for {
 		// connection.Next() blocks, until there is new data. It also unblocks,
@@ -116,14 +186,51 @@ func (a *Autoupdate) Live(ctx context.Context, userID int, w io.Writer, kb KeysB
 			return err
 		}
 
+		keyCount := len(data)
+		if conn.position {
+			conn.addPosition(data)
+		}
+
+		counter.count = 0
 		if err := encoder.Encode(data); err != nil {
 			return err
 		}
 
 		w.(flusher).Flush()
+
+		a.hookMessage(ctx, userID, keyCount, counter.count)
 	}
 }
 
+// ConnectionInfo describes an open connection.
+type ConnectionInfo struct {
+	UserID      int       `json:"user_id"`
+	Keys        int       `json:"keys"`
+	ChangeID    uint64    `json:"change_id"`
+	Created     time.Time `json:"created"`
+	LastMessage time.Time `json:"last_message"`
+}
+
+// Connections returns the connections, that are currently open with Live. They
+// are sorted by their age, the oldest first.
+//
+// Keys is the number of keys, the connection is subscribed to. ChangeID is the id of the
+// last change, that was sent to the connection. It can be compared with
+// LastID to find connections, that do not read their messages.
+func (a *Autoupdate) Connections() []ConnectionInfo {
+	a.connMu.Lock()
+	infos := make([]ConnectionInfo, 0, len(a.connections))
+	for c := range a.connections {
+		infos = append(infos, c.info())
+	}
+	a.connMu.Unlock()
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Created.Before(infos[j].Created)
+	})
+	return infos
+}
+
 type flusher interface {
 	Flush()
 }
@@ -139,26 +246,45 @@ func (a *Autoupdate) pruneTopic(closed <-chan struct{}) {
 		case <-closed:
 			return
 		case <-tick.C:
-			a.topic.Prune(time.Now().Add(-pruneTime))
+			until := time.Now().Add(-a.pruneTime)
+			a.topic.Prune(until)
+			a.positions.prune(until)
 		}
 	}
 }
 
-// resetCache runs in the background and cleans the cache from time to time.
-// Blocks until the service is closed.
-func (a *Autoupdate) resetCache(closed <-chan struct{}) {
-	tick := time.NewTicker(datastoreCacheResetTime)
-	defer tick.Stop()
+// ResetCache clears the cache of the datastore. Afterwards, every connection
+// gets a full update.
+//
+// The datastore removes unused keys by itself. This is only needed, if the
+// cache contains wrong data, for example after a migration of the datastore.
+func (a *Autoupdate) ResetCache() {
+	a.datastore.ResetCache()
+	a.topic.Publish(fmt.Sprintf(fullUpdateFormat, -1))
+}
 
-	for {
-		select {
-		case <-closed:
-			return
-		case <-tick.C:
-			a.datastore.ResetCache()
-			// After the cache was updated, every connection has to be recalculated.
-			a.topic.Publish(fmt.Sprintf(fullUpdateFormat, -1))
-		}
+// FullUpdate sends a full update to the connections of the given users.
+// Without user ids, every connection gets a full update.
+//
+// In difference to ResetCache, the values in the cache are not fetched again.
+func (a *Autoupdate) FullUpdate(uids ...int) {
+	if len(uids) == 0 {
+		a.topic.Publish(fmt.Sprintf(fullUpdateFormat, -1))
+		return
+	}
+
+	keys := make([]string, len(uids))
+	for i, uid := range uids {
+		keys[i] = fmt.Sprintf(fullUpdateFormat, uid)
+	}
+	a.topic.Publish(keys...)
+}
+
+// ResetCollection removes all keys of a collection from the cache of the
+// datastore. The connections, that use one of the keys, get the new values.
+func (a *Autoupdate) ResetCollection(collection string) {
+	if keys := a.datastore.ResetCollection(collection); len(keys) > 0 {
+		a.topic.Publish(keys...)
 	}
 }
 
@@ -181,3 +307,152 @@ func (a *Autoupdate) RestrictedData(ctx context.Context, uid int, keys ...string
 	}
 	return data, nil
 }
+
+// Visibility of an object for a user.
+const (
+	VisibilityNotFound  = "not_found"
+	VisibilityForbidden = "forbidden"
+	VisibilityVisible   = "visible"
+)
+
+// Visibility tells for each fqid, if the object exists and if the user with
+// the given id can see it.
+//
+// Only the id field of each object is fetched and restricted.
+func (a *Autoupdate) Visibility(ctx context.Context, uid int, fqids ...string) (map[string]string, error) {
+	keys := make([]string, len(fqids))
+	for i, fqid := range fqids {
+		keys[i] = fqid + "/id"
+	}
+
+	values, err := a.datastore.Get(ctx, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("get ids from datastore: %w", err)
+	}
+
+	visibility := make(map[string]string, len(fqids))
+	data := make(map[string]json.RawMessage, len(keys))
+	for i, key := range keys {
+		if values[i] == nil {
+			visibility[fqids[i]] = VisibilityNotFound
+			continue
+		}
+		data[key] = values[i]
+	}
+
+	if err := a.restricter.Restrict(ctx, uid, data); err != nil {
+		return nil, fmt.Errorf("restrict ids: %w", err)
+	}
+
+	for i, key := range keys {
+		value, ok := data[key]
+		if !ok {
+			continue
+		}
+
+		visibility[fqids[i]] = VisibilityVisible
+		if value == nil {
+			visibility[fqids[i]] = VisibilityForbidden
+		}
+	}
+	return visibility, nil
+}
+
+// Explanation tells, if a key is visible for a user and which rule decided
+// it.
+type Explanation struct {
+	Visible bool   `json:"visible"`
+	Reason  string `json:"reason"`
+}
+
+// Explain restricts the keys for the user with the given id and tells for
+// each key, which rule decided about its value.
+//
+// requestUID is the user, that asks for the explanation. Only the user
+// itself and superadmins can get the explanations of a user.
+//
+// It returns an error, if the restricter does not support explanations.
+func (a *Autoupdate) Explain(ctx context.Context, requestUID int, uid int, keys ...string) (map[string]Explanation, error) {
+	allowed, err := a.canExplain(ctx, requestUID, uid)
+	if err != nil {
+		return nil, fmt.Errorf("checking permission to explain: %w", err)
+	}
+
+	if !allowed {
+		return nil, explainForbiddenError{}
+	}
+
+	e, ok := a.restricter.(explainer)
+	if !ok {
+		return nil, fmt.Errorf("restricter %T does not support explain", a.restricter)
+	}
+
+	values, err := a.datastore.Get(ctx, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("get values for keys `%v` from datastore: %w", keys, err)
+	}
+
+	data := make(map[string]json.RawMessage, len(keys))
+	for i, key := range keys {
+		data[key] = values[i]
+	}
+
+	reasons, err := e.Explain(ctx, uid, data)
+	if err != nil {
+		return nil, fmt.Errorf("explain restriction: %w", err)
+	}
+
+	explanations := make(map[string]Explanation, len(keys))
+	for _, key := range keys {
+		explanations[key] = Explanation{
+			Visible: data[key] != nil,
+			Reason:  reasons[key],
+		}
+	}
+	return explanations, nil
+}
+
+// canExplain returns true, if the user requestUID is allowed to see the
+// explanations of the user uid. The anonymous user is never allowed.
+func (a *Autoupdate) canExplain(ctx context.Context, requestUID int, uid int) (bool, error) {
+	if requestUID == 0 {
+		return false, nil
+	}
+
+	if requestUID == uid {
+		return true, nil
+	}
+
+	key := fmt.Sprintf("user/%d/organisation_management_level", requestUID)
+	values, err := a.datastore.Get(ctx, key)
+	if err != nil {
+		return false, fmt.Errorf("getting %s: %w", key, err)
+	}
+
+	if values[0] == nil {
+		return false, nil
+	}
+
+	var level string
+	if err := json.Unmarshal(values[0], &level); err != nil {
+		return false, fmt.Errorf("decoding %s: %w", key, err)
+	}
+	return level == "superadmin", nil
+}
+
+// explainForbiddenError is returned, if a user asks for the explanations of
+// another user.
+type explainForbiddenError struct{}
+
+func (explainForbiddenError) Error() string {
+	return "Only superadmins can explain the restriction of other users"
+}
+
+func (explainForbiddenError) Type() string {
+	return "forbidden"
+}
+
+// StatusCode returns the http status code of the error.
+func (explainForbiddenError) StatusCode() int {
+	return 403
+}
@@ -3,12 +3,19 @@ package autoupdate_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/OpenSlides/openslides-autoupdate-service/internal/autoupdate"
 	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
 	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/testdata"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -32,6 +39,106 @@ func TestLive(t *testing.T) {
 	assert.JSONEq(t, `{"collection/1/bar":"Bar Value","collection/1/foo":"Foo Value"}`, w.lines[0])
 }
 
+func TestLiveReconnect(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, map[string]string{
+		"collection/1/foo": `"Foo Value"`,
+		"collection/1/bar": `"Bar Value"`,
+	})
+	s := autoupdate.New(ds, test.RestrictAllowed(), test.UserUpdater{}, closed)
+	kb := test.KeysBuilder{K: []string{"collection/1/foo", "collection/1/bar"}}
+
+	live := func(options ...autoupdate.ConnectionOption) map[string]json.RawMessage {
+		w := lineWriter{maxLines: 1}
+		err := s.Live(context.Background(), 1, &w, kb, options...)
+		require.True(t, errors.Is(err, errWriterFull))
+		require.Len(t, w.lines, 1)
+
+		var data map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal([]byte(w.lines[0]), &data))
+		return data
+	}
+
+	checksumOf := func(data map[string]json.RawMessage) uint64 {
+		var v string
+		require.NoError(t, json.Unmarshal(data[autoupdate.ChecksumKey], &v))
+		sum, err := strconv.ParseUint(v, 16, 64)
+		require.NoError(t, err)
+		return sum
+	}
+
+	first := live(autoupdate.WithChecksum())
+	require.Contains(t, first, "collection/1/foo")
+	sum := checksumOf(first)
+
+	t.Run("known checksum", func(t *testing.T) {
+		data := live(autoupdate.WithReconnect(sum))
+
+		assert.NotContains(t, data, "collection/1/foo", "unchanged value was sent again")
+		assert.Equal(t, sum, checksumOf(data))
+	})
+
+	t.Run("unknown checksum", func(t *testing.T) {
+		data := live(autoupdate.WithReconnect(sum + 1))
+
+		assert.Contains(t, data, "collection/1/foo")
+		assert.Equal(t, sum, checksumOf(data))
+	})
+}
+
+// hookMock records the calls of a ConnectionHook.
+type hookMock struct {
+	calls []string
+}
+
+func (h *hookMock) OnConnect(ctx context.Context, uid int) {
+	h.calls = append(h.calls, fmt.Sprintf("connect %d", uid))
+}
+
+func (h *hookMock) OnMessage(ctx context.Context, uid int, keys int, size int) {
+	h.calls = append(h.calls, fmt.Sprintf("message %d: %d keys, %d bytes", uid, keys, size))
+}
+
+func (h *hookMock) OnDisconnect(ctx context.Context, uid int, err error) {
+	h.calls = append(h.calls, fmt.Sprintf("disconnect %d: %v", uid, err))
+}
+
+func TestLiveConnectionHook(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, map[string]string{
+		"collection/1/foo": `"Foo Value"`,
+	})
+	hook := new(hookMock)
+	s := autoupdate.New(ds, test.RestrictAllowed(), test.UserUpdater{}, closed, autoupdate.WithConnectionHook(hook))
+	kb := test.KeysBuilder{K: []string{"collection/1/foo"}}
+
+	receiving := make(chan struct{})
+	w := lineWriter{maxLines: 2, received: receiving}
+	done := make(chan struct{})
+	var err error
+	go func() {
+		err = s.Live(context.Background(), 1, &w, kb)
+		close(done)
+	}()
+
+	<-receiving
+	ds.Send(map[string]string{"collection/1/foo": `"new data"`})
+	<-receiving
+	<-done
+
+	// lineWriter does not count the newline.
+	require.True(t, errors.Is(err, errWriterFull))
+	assert.Equal(t, []string{
+		"connect 1",
+		fmt.Sprintf("message 1: 1 keys, %d bytes", len(w.lines[0])),
+		"disconnect 1: " + err.Error(),
+	}, hook.calls)
+}
+
 func TestLiveFlushBetweenUpdates(t *testing.T) {
 	closed := make(chan struct{})
 	defer close(closed)
@@ -65,6 +172,135 @@ func TestLiveFlushBetweenUpdates(t *testing.T) {
 	assert.JSONEq(t, `{"collection/1/foo":"new data"}`, w.lines[1])
 }
 
+// positionDatastore is a datastore that knows its position.
+type positionDatastore struct {
+	*dsmock.MockDatastore
+	position *int64
+}
+
+func (d positionDatastore) Position() int {
+	return int(atomic.LoadInt64(d.position))
+}
+
+func TestLivePosition(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	position := int64(41)
+	ds := positionDatastore{
+		MockDatastore: dsmock.NewMockDatastore(closed, map[string]string{
+			"collection/1/foo": `"Foo Value"`,
+		}),
+		position: &position,
+	}
+	s := autoupdate.New(ds, test.RestrictAllowed(), test.UserUpdater{}, closed)
+	kb := test.KeysBuilder{K: []string{"collection/1/foo"}}
+
+	receiving := make(chan struct{})
+	w := lineWriter{maxLines: 2, received: receiving}
+	done := make(chan struct{})
+	go func() {
+		s.Live(context.Background(), 1, &w, kb, autoupdate.WithPosition())
+		close(done)
+	}()
+
+	<-receiving
+	atomic.StoreInt64(&position, 42)
+	ds.Send(map[string]string{"collection/1/foo": `"new data"`})
+	<-receiving
+	<-done
+
+	require.Len(t, w.lines, 2)
+	assert.JSONEq(t, `{"collection/1/foo":"Foo Value","_change_id":0}`, w.lines[0])
+	assert.JSONEq(t, `{"collection/1/foo":"new data","_change_id":1,"_position":42}`, w.lines[1])
+}
+
+func TestDebounce(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, map[string]string{
+		"collection/1/foo": `"Foo Value"`,
+		"collection/1/bar": `"Bar Value"`,
+	})
+	s := autoupdate.New(ds, test.RestrictAllowed(), test.UserUpdater{}, closed, autoupdate.WithDebounce(50*time.Millisecond))
+	c := s.Connect(1, test.KeysBuilder{K: []string{"collection/1/foo", "collection/1/bar"}})
+
+	if _, err := c.Next(context.Background()); err != nil {
+		t.Fatalf("Next returned unexpected error: %v", err)
+	}
+
+	ds.Send(map[string]string{"collection/1/foo": `"new foo"`})
+	ds.Send(map[string]string{"collection/1/bar": `"new bar"`})
+
+	data, err := c.Next(context.Background())
+	require.NoError(t, err, "Next returned unexpected error")
+	assert.Equal(t, map[string]json.RawMessage{
+		"collection/1/foo": []byte(`"new foo"`),
+		"collection/1/bar": []byte(`"new bar"`),
+	}, data)
+}
+
+func TestVisibility(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+	motion/1/title: visible
+	motion/2/title: forbidden
+	`))
+	perms := &test.MockPermission{Default: true, Data: map[string]bool{"motion/2/id": false}}
+	s := autoupdate.New(ds, restrict.New(perms, nil), test.UserUpdater{}, closed)
+
+	got, err := s.Visibility(context.Background(), 1, "motion/1", "motion/2", "motion/3")
+	require.NoError(t, err)
+
+	expect := map[string]string{
+		"motion/1": autoupdate.VisibilityVisible,
+		"motion/2": autoupdate.VisibilityForbidden,
+		"motion/3": autoupdate.VisibilityNotFound,
+	}
+	assert.Equal(t, expect, got)
+}
+
+func TestExplainOtherUser(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+	motion/1/title: visible
+	user/1/organisation_management_level: superadmin
+	user/2/organisation_management_level: can_manage_users
+	`))
+	perms := &test.MockPermission{Default: true}
+	s := autoupdate.New(ds, restrict.New(perms, nil), test.UserUpdater{}, closed)
+
+	for _, tt := range []struct {
+		name       string
+		requestUID int
+		uid        int
+		allowed    bool
+	}{
+		{"same user", 3, 3, true},
+		{"superadmin", 1, 3, true},
+		{"other user", 2, 3, false},
+		{"anonymous", 0, 0, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Explain(context.Background(), tt.requestUID, tt.uid, "motion/1/title")
+
+			if tt.allowed {
+				assert.NoError(t, err)
+				return
+			}
+
+			var errStatus interface{ StatusCode() int }
+			require.True(t, errors.As(err, &errStatus), "Got error %v, expected an error with a status code", err)
+			assert.Equal(t, 403, errStatus.StatusCode())
+		})
+	}
+}
+
 var errWriterFull = errors.New("first line full")
 
 // lineWriter fails after the first newline
@@ -101,3 +337,82 @@ func (w *lineWriter) Write(p []byte) (int, error) {
 }
 
 func (w *lineWriter) Flush() {}
+
+func BenchmarkRestrictedDataMeeting(b *testing.B) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := testdata.MeetingDatastore(closed)
+	s := autoupdate.New(ds, test.RestrictAllowed(), test.UserUpdater{}, closed)
+	keys := append(testdata.Keys("motion"), testdata.Keys("user")...)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.RestrictedData(context.Background(), 1, keys...); err != nil {
+			b.Fatalf("RestrictedData returned unexpected error: %v", err)
+		}
+	}
+}
+
+func TestAutoupdateFullUpdate(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, map[string]string{
+		"user/1/name": `"Hello World"`,
+	})
+	restricter := test.RestrictAllowed()
+	s := autoupdate.New(ds, restricter, test.UserUpdater{}, closed)
+	kb := test.KeysBuilder{K: test.Str("user/1/name")}
+
+	c1 := s.Connect(1, kb)
+	c2 := s.Connect(2, kb)
+	for _, c := range []*autoupdate.Connection{c1, c2} {
+		if _, err := c.Next(context.Background()); err != nil {
+			t.Fatalf("c.Next() returned an error: %v", err)
+		}
+	}
+
+	// Simulate a permission repair, that is not visible in the datastore.
+	restricter.Values = map[string]string{"user/1/name": `"Repaired"`}
+	s.FullUpdate(1)
+
+	data, err := c1.Next(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]json.RawMessage{"user/1/name": []byte(`"Repaired"`)}, data)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if !blocking(func() { c2.Next(ctx) }) {
+		t.Errorf("Connection of user 2 got a full update")
+	}
+}
+
+func TestAutoupdateConnections(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, map[string]string{
+		"user/1/name": `"Hello World"`,
+	})
+	s := autoupdate.New(ds, test.RestrictAllowed(), test.UserUpdater{}, closed)
+	kb := test.KeysBuilder{K: test.Str("user/1/name", "user/2/name")}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	received := make(chan struct{}, 1)
+	done := make(chan error)
+	go func() {
+		done <- s.Live(ctx, 5, &lineWriter{maxLines: 10, received: received}, kb)
+	}()
+	<-received
+
+	connections := s.Connections()
+	require.Len(t, connections, 1)
+	assert.Equal(t, 5, connections[0].UserID)
+	assert.Equal(t, 2, connections[0].Keys)
+	assert.Equal(t, s.LastID(), connections[0].ChangeID)
+
+	cancel()
+	<-done
+	assert.Empty(t, s.Connections(), "Connection was not removed after Live returned")
+}
@@ -0,0 +1,65 @@
+package autoupdate_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/restricttest"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/testdata"
+)
+
+// BenchmarkConnections opens many connections of different users at the same
+// time. Each connection receives the first message with all motions and
+// users, that the user can see.
+func BenchmarkConnections(b *testing.B) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	content := testdata.Scaled(testdata.BenchUserCount, testdata.BenchMotionCount)
+	ds := dsmock.NewMockDatastore(closed, content)
+	perms := restricttest.NewPermission(ds, restricttest.RequiredPermissions)
+	restricter := restrict.New(perms, restrict.RelationChecker(restrict.RelationLists, perms))
+	s := autoupdate.New(ds, restricter, test.UserUpdater{}, closed)
+
+	var keys []string
+	for k := range content {
+		if strings.HasPrefix(k, "motion/") || strings.HasPrefix(k, "user/") {
+			keys = append(keys, k)
+		}
+	}
+	kb := test.KeysBuilder{K: keys}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		var wg sync.WaitGroup
+		errs := make(chan error, testdata.BenchConnectionCount)
+		for c := 0; c < testdata.BenchConnectionCount; c++ {
+			// Spread the users over all groups.
+			uid := 1 + c*testdata.BenchUserCount/testdata.BenchConnectionCount
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := s.Connect(uid, kb).Next(context.Background()); err != nil {
+					errs <- err
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		if err := <-errs; err != nil {
+			b.Fatalf("Next returned unexpected error: %v", err)
+		}
+	}
+	b.ReportMetric(float64(len(keys)*testdata.BenchConnectionCount*b.N)/time.Since(start).Seconds(), "keys/s")
+}
@@ -0,0 +1,133 @@
+package autoupdate
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// ChecksumKey is the key in a message, that contains the checksum of all data,
+// the client has received.
+const ChecksumKey = "_checksum"
+
+// MaxStoredHistoryKeys is the number of keys of all closed connections, that
+// are remembered, so they can be continued with WithReconnect.
+const MaxStoredHistoryKeys = 1 << 20
+
+// WithChecksum adds the checksum of all data, that was sent to the
+// connection, to each message.
+//
+// When the connection is closed, the server remembers, which values the
+// client has received. The client can use the last received checksum to
+// reconnect with WithReconnect.
+func WithChecksum() ConnectionOption {
+	return func(c *Connection) {
+		c.checksum = true
+	}
+}
+
+// WithReconnect continues a previous connection of the same user. checksum
+// has to be the last checksum, the client received with WithChecksum.
+//
+// The first message only contains the values, that changed since the
+// previous connection. If the server does not know the checksum, for example
+// after a restart, the first message contains all values. The option also
+// enables WithChecksum.
+func WithReconnect(checksum uint64) ConnectionOption {
+	return func(c *Connection) {
+		c.checksum = true
+		c.reconnect = checksum
+	}
+}
+
+// addChecksum adds the checksum of the filter to the data.
+func (c *Connection) addChecksum(data map[string]json.RawMessage) map[string]json.RawMessage {
+	if data == nil {
+		data = make(map[string]json.RawMessage, 1)
+	}
+	data[ChecksumKey] = []byte(fmt.Sprintf(`"%016x"`, c.filter.sum))
+	return data
+}
+
+// historyKey identifies a stored history.
+type historyKey struct {
+	uid int
+	sum uint64
+}
+
+// historyStore remembers the checksums of closed connections, so a client can
+// reconnect without receiving all data again.
+//
+// It holds at most MaxStoredHistoryKeys keys of all histories together. When
+// it is full, the oldest histories are removed. A history with more keys is
+// not stored at all.
+type historyStore struct {
+	mu      sync.Mutex
+	maxSize int
+	size    int
+	order   *list.List
+	entries map[historyKey]*list.Element
+}
+
+// historyEntry is an element of historyStore.order.
+type historyEntry struct {
+	key     historyKey
+	history map[string]uint64
+}
+
+func newHistoryStore(maxSize int) *historyStore {
+	return &historyStore{
+		maxSize: maxSize,
+		order:   list.New(),
+		entries: make(map[historyKey]*list.Element),
+	}
+}
+
+// add saves the history of a filter. An older history with the same checksum
+// is replaced.
+func (s *historyStore) add(uid int, f *filter) {
+	if f.history == nil || len(f.history) > s.maxSize {
+		return
+	}
+
+	key := historyKey{uid: uid, sum: f.sum}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(key)
+
+	s.entries[key] = s.order.PushBack(historyEntry{key: key, history: f.history})
+	s.size += len(f.history)
+	for s.size > s.maxSize {
+		s.remove(s.order.Front().Value.(historyEntry).key)
+	}
+}
+
+// take returns and removes a history. It returns nil, if the history is not
+// known.
+func (s *historyStore) take(uid int, sum uint64) map[string]uint64 {
+	key := historyKey{uid: uid, sum: sum}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.remove(key)
+}
+
+// remove removes a history and returns it. It returns nil, if the history is
+// not known.
+//
+// The lock has to be held by the caller.
+func (s *historyStore) remove(key historyKey) map[string]uint64 {
+	e, ok := s.entries[key]
+	if !ok {
+		return nil
+	}
+
+	entry := s.order.Remove(e).(historyEntry)
+	delete(s.entries, key)
+	s.size -= len(entry.history)
+	return entry.history
+}
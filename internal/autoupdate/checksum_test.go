@@ -0,0 +1,56 @@
+package autoupdate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func historyFilter(sum uint64, keys ...string) *filter {
+	f := &filter{history: make(map[string]uint64), sum: sum}
+	for _, key := range keys {
+		f.history[key] = 1
+	}
+	return f
+}
+
+func TestHistoryStoreMaxSize(t *testing.T) {
+	s := newHistoryStore(3)
+
+	s.add(1, historyFilter(1, "a", "b"))
+	s.add(1, historyFilter(2, "c", "d"))
+	s.add(1, historyFilter(3, "a", "b", "c", "d"))
+
+	assert.Nil(t, s.take(1, 1), "the oldest history was not removed")
+	assert.NotNil(t, s.take(1, 2), "the newest history was removed")
+	assert.Nil(t, s.take(1, 3), "a history bigger than the store was stored")
+	assert.Equal(t, 0, s.size)
+}
+
+func TestHistoryStoreSameChecksum(t *testing.T) {
+	s := newHistoryStore(4)
+
+	s.add(1, historyFilter(1, "a"))
+	s.add(1, historyFilter(2, "b"))
+	s.add(1, historyFilter(1, "c"))
+	assert.Equal(t, 2, s.size)
+
+	// The history with checksum 2 is the oldest and gets removed first.
+	s.add(1, historyFilter(3, "d", "e", "f"))
+
+	assert.Nil(t, s.take(1, 2))
+	assert.Equal(t, map[string]uint64{"c": 1}, s.take(1, 1))
+	assert.Equal(t, 1, s.order.Len())
+}
+
+func TestHistoryStoreTake(t *testing.T) {
+	s := newHistoryStore(4)
+
+	s.add(1, historyFilter(1, "a", "b"))
+	assert.Nil(t, s.take(2, 1), "history of another user")
+	assert.NotNil(t, s.take(1, 1))
+	assert.Nil(t, s.take(1, 1), "history was taken twice")
+
+	assert.Equal(t, 0, s.size)
+	assert.Equal(t, 0, s.order.Len())
+}
@@ -4,8 +4,60 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 )
 
+// MaxMinInterval is the biggest interval, a client can request with
+// WithMinInterval.
+const MaxMinInterval = time.Minute
+
+// ConnectionOption is an optional argument for Connect and Live.
+type ConnectionOption func(*Connection)
+
+// WithMinInterval sets the minimum time between two messages of a
+// connection. All changes in this time are combined into one message.
+//
+// This can be used by clients that do not need instant updates, for example a
+// statistics dashboard.
+func WithMinInterval(d time.Duration) ConnectionOption {
+	return func(c *Connection) {
+		c.minInterval = d
+	}
+}
+
+// WithSkipFirstEmpty suppresses the first message of a connection, if it is
+// empty. This happens, when all requested keys do not exist or the user can not
+// see them. In this case, Next blocks until there is data for the user.
+//
+// This can be used by clients that poll for data and do not want an empty
+// object as answer.
+func WithSkipFirstEmpty() ConnectionOption {
+	return func(c *Connection) {
+		c.skipFirstEmpty = true
+	}
+}
+
+// WithProjectorUsers sets the users of projector screens. Their connections
+// ignore the full updates for single users.
+//
+// A full update for a user happens, when the permissions of the user change.
+// The content of a projector does not depend on the user. So connections of
+// projector screens do not have to be recalculated in this case. Full updates
+// for all users are still handled.
+//
+// The users are configured by the service and not by the clients. Otherwise
+// a client could keep data, that it is not allowed to see anymore.
+func WithProjectorUsers(uids ...int) Option {
+	return func(a *Autoupdate) {
+		a.projectorUsers = make(map[int]bool, len(uids))
+		for _, uid := range uids {
+			a.projectorUsers[uid] = true
+		}
+	}
+}
+
 // Connection holds the state of a client. It has to be created by colling
 // Connect() on a autoupdate.Service instance.
 type Connection struct {
@@ -14,20 +66,61 @@ type Connection struct {
 	kb         KeysBuilder
 	tid        uint64
 	filter     filter
+
+	minInterval time.Duration
+	lastMessage time.Time
+
+	skipFirstEmpty bool
+	projectorMode  bool
+
+	provenance          bool
+	provenanceChecked   bool
+	provenanceIsAllowed bool
+	pendingProvenance   []json.RawMessage
+
+	deleted bool
+
+	checksum  bool
+	reconnect uint64
+
+	notify        bool
+	notifyRead    uint64
+	pendingNotify []json.RawMessage
+
+	meetingID int
+
+	position bool
+
+	created  time.Time
+	keyCount int
+
+	// statsMu protects the fields, that are read by Autoupdate.Connections
+	// from another goroutine.
+	statsMu     sync.Mutex
+	sentTID     uint64
+	sentKeys    int
+	sentMessage time.Time
 }
 
 // Next returns the next data for the user.
 //
 // When Next is called for the first time, it does not block. In this case, it
-// is possible, that it returns an empty map.
+// is possible, that it returns an empty map. With the option
+// WithSkipFirstEmpty, it blocks until the map is not empty.
 //
 // On every other call, it blocks until there is new data. In this case, the map
 // is never empty.
 func (c *Connection) Next(ctx context.Context) (map[string]json.RawMessage, error) {
 	firstTime := c.filter.empty()
+	if !firstTime {
+		if err := c.waitInterval(ctx); err != nil {
+			return nil, err
+		}
+	}
+
 	var data map[string]json.RawMessage
 
-	for len(data) == 0 {
+	for len(data) == 0 && len(c.pendingNotify) == 0 {
 		keys, err := c.keys(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("getting keys: %w", err)
@@ -40,27 +133,98 @@ func (c *Connection) Next(ctx context.Context) (map[string]json.RawMessage, erro
 
 		c.filter.filter(data)
 
-		if firstTime {
+		if firstTime && !c.skipFirstEmpty {
 			// On firstTime return the data, even when it is empty.
-			return data, nil
+			break
+		}
+	}
+
+	if c.provenance && !firstTime {
+		if err := c.addProvenance(ctx, data); err != nil {
+			return nil, fmt.Errorf("adding provenance: %w", err)
 		}
 	}
 
+	if c.deleted && !firstTime {
+		if err := c.addDeleted(ctx, data); err != nil {
+			return nil, fmt.Errorf("adding deleted objects: %w", err)
+		}
+	}
+
+	if c.notify {
+		data = c.addNotify(data)
+	}
+
+	if c.checksum {
+		data = c.addChecksum(data)
+	}
+
+	c.lastMessage = time.Now()
+
+	c.statsMu.Lock()
+	c.sentTID = c.tid
+	c.sentKeys = c.keyCount
+	c.sentMessage = c.lastMessage
+	c.statsMu.Unlock()
+
 	return data, nil
 }
 
+// info returns the description of the connection.
+func (c *Connection) info() ConnectionInfo {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	return ConnectionInfo{
+		UserID:      c.uid,
+		Keys:        c.sentKeys,
+		ChangeID:    c.sentTID,
+		Created:     c.created,
+		LastMessage: c.sentMessage,
+	}
+}
+
+// waitInterval blocks until the minimum interval since the last message is
+// over. The changes in the meantime are collected by the topic and are
+// returned together afterwards.
+func (c *Connection) waitInterval(ctx context.Context) error {
+	wait := c.minInterval - time.Since(c.lastMessage)
+	if c.minInterval <= 0 || wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (c *Connection) keys(ctx context.Context) ([]string, error) {
+	var keys []string
 	if c.filter.empty() {
-		keys, err := c.allKeys(ctx)
+		allKeys, err := c.allKeys(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("get all keys: %w", err)
 		}
-		return keys, nil
+		keys = allKeys
+	} else {
+		nextKeys, err := c.nextKeys(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get next keys: %w", err)
+		}
+		keys = nextKeys
 	}
 
-	keys, err := c.nextKeys(ctx)
+	keys = c.autoupdate.dropInvalidKeys(keys, true)
+
+	keys, err := c.filterMeeting(ctx, keys)
 	if err != nil {
-		return nil, fmt.Errorf("get next keys: %w", err)
+		return nil, fmt.Errorf("filter keys of other meetings: %w", err)
 	}
 	return keys, nil
 }
@@ -74,27 +238,52 @@ func (c *Connection) allKeys(ctx context.Context) ([]string, error) {
 		return nil, fmt.Errorf("create keys for keysbuilder: %w", err)
 	}
 
-	return c.kb.Keys(), nil
+	keys := c.kb.Keys()
+	c.keyCount = len(keys)
+
+	// On a full update, the values are compared with the values, that were
+	// sent before. So only the changed values are sent again.
+	c.filter.prune(keys)
+	return keys, nil
 }
 
 // nextKeys blocks until there are new keys for the user.
 func (c *Connection) nextKeys(ctx context.Context) ([]string, error) {
 	var keys []string
-	for len(keys) == 0 {
+	for len(keys) == 0 && len(c.pendingNotify) == 0 {
 		// Blocks until the topic is closed (on server exit) or the context is done.
-		tid, changedKeys, err := c.autoupdate.topic.Receive(ctx, c.tid)
+		changedKeys, full, err := c.receive(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("get updated keys: %w", err)
+			return nil, err
+		}
+
+		if full {
+			return c.allKeys(ctx)
 		}
-		c.tid = tid
 
 		changedSlice := make(map[string]bool, len(changedKeys))
 		for _, key := range changedKeys {
+			if isProvenanceKey(key) {
+				if c.provenance {
+					c.pendingProvenance = append(c.pendingProvenance, json.RawMessage(strings.TrimPrefix(key, provenancePrefix)))
+				}
+				continue
+			}
+
+			if key == notifyTopicKey {
+				if c.notify {
+					if err := c.receiveNotify(ctx); err != nil {
+						return nil, fmt.Errorf("receiving notification: %w", err)
+					}
+				}
+				continue
+			}
+
 			var uid int
 			if _, err := fmt.Sscanf(key, fullUpdateFormat, &uid); err == nil {
 				// The key is a fullUpdate key. Do not use it, exept of a full
 				// update.
-				if uid == -1 || uid == c.uid {
+				if uid == -1 || (uid == c.uid && !c.projectorMode) {
 					return c.allKeys(ctx)
 				}
 				continue
@@ -111,11 +300,16 @@ func (c *Connection) nextKeys(ctx context.Context) ([]string, error) {
 		}
 
 		// Start with keys hat are new for the user.
-		keys = keysDiff(oldKeys, c.kb.Keys())
+		newKeys := c.kb.Keys()
+		c.keyCount = len(newKeys)
+		keys = keysDiff(oldKeys, newKeys)
 
-		// Append keys that are old but have been changed.
+		// Append keys that are old but have been changed. Also append
+		// relation-lists to changed objects. Their restricted value changes,
+		// when an object becomes invisible.
+		collections := changedCollections(changedSlice)
 		for _, key := range oldKeys {
-			if !changedSlice[key] {
+			if !changedSlice[key] && !c.autoupdate.pointsTo(key, collections) {
 				continue
 			}
 			keys = append(keys, key)
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/OpenSlides/openslides-autoupdate-service/internal/autoupdate"
 	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
@@ -218,6 +219,335 @@ func TestConnectionFilterData(t *testing.T) {
 	}
 }
 
+func TestConnectionMinInterval(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	datastore := dsmock.NewMockDatastore(closed, map[string]string{
+		"user/1/name": `"Hello World"`,
+		"user/2/name": `"Hello World"`,
+	})
+
+	s := autoupdate.New(datastore, test.RestrictAllowed(), test.UserUpdater{}, closed)
+	kb := test.KeysBuilder{K: test.Str("user/1/name", "user/2/name")}
+	interval := 100 * time.Millisecond
+	c := s.Connect(1, kb, autoupdate.WithMinInterval(interval))
+
+	if _, err := c.Next(context.Background()); err != nil {
+		t.Fatalf("c.Next() returned an error: %v", err)
+	}
+	start := time.Now()
+
+	datastore.Send(map[string]string{"user/1/name": `"new1"`})
+	go func() {
+		time.Sleep(interval / 2)
+		datastore.Send(map[string]string{"user/2/name": `"new2"`})
+	}()
+
+	data, err := c.Next(context.Background())
+	if err != nil {
+		t.Fatalf("c.Next() returned an error: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < interval {
+		t.Errorf("c.Next() returned after %s, expected at least %s", elapsed, interval)
+	}
+
+	expect := map[string]json.RawMessage{
+		"user/1/name": []byte(`"new1"`),
+		"user/2/name": []byte(`"new2"`),
+	}
+	assert.Equal(t, expect, data, "c.Next() should combine all changes in the interval")
+}
+
+// provenanceDatastore is a datastore that returns a provenance on each
+// update.
+type provenanceDatastore struct {
+	*dsmock.MockDatastore
+	provenance map[string]string
+}
+
+func (d provenanceDatastore) Provenance() map[string]string {
+	return d.provenance
+}
+
+func TestConnectionProvenance(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		level  string
+		expect bool
+	}{
+		{"superadmin", `"superadmin"`, true},
+		{"normal user", `""`, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			closed := make(chan struct{})
+			defer close(closed)
+
+			datastore := provenanceDatastore{
+				MockDatastore: dsmock.NewMockDatastore(closed, map[string]string{
+					"user/1/name":                          `"Hello World"`,
+					"user/1/organisation_management_level": tt.level,
+				}),
+				provenance: map[string]string{"user_id": "5", "action_name": "user.update", "position": "invalid"},
+			}
+
+			s := autoupdate.New(datastore, test.RestrictAllowed(), test.UserUpdater{}, closed)
+			c := s.Connect(1, test.KeysBuilder{K: test.Str("user/1/name")}, autoupdate.WithProvenance())
+
+			if _, err := c.Next(context.Background()); err != nil {
+				t.Fatalf("c.Next() returned an error: %v", err)
+			}
+
+			datastore.Send(map[string]string{"user/1/name": `"new name"`})
+			data, err := c.Next(context.Background())
+			if err != nil {
+				t.Fatalf("c.Next() returned an error: %v", err)
+			}
+
+			got, ok := data[autoupdate.ProvenanceKey]
+			if ok != tt.expect {
+				t.Fatalf("Got provenance %s, expected provenance: %t", got, tt.expect)
+			}
+
+			if tt.expect {
+				assert.JSONEq(t, `[{"user_id":5,"action_name":"user.update"}]`, string(got))
+			}
+		})
+	}
+}
+
+func TestConnectionSkipFirstEmpty(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	datastore := dsmock.NewMockDatastore(closed, nil)
+	s := autoupdate.New(datastore, test.RestrictAllowed(), test.UserUpdater{}, closed)
+	c := s.Connect(1, test.KeysBuilder{K: test.Str("user/1/name")}, autoupdate.WithSkipFirstEmpty())
+
+	received := make(chan map[string]json.RawMessage)
+	go func() {
+		data, err := c.Next(context.Background())
+		if err != nil {
+			t.Errorf("c.Next() returned an error: %v", err)
+		}
+		received <- data
+	}()
+
+	select {
+	case data := <-received:
+		t.Fatalf("c.Next() returned %v before there was data", data)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	datastore.Send(map[string]string{"user/1/name": `"Hello World"`})
+
+	select {
+	case data := <-received:
+		assert.Equal(t, map[string]json.RawMessage{"user/1/name": []byte(`"Hello World"`)}, data)
+	case <-time.After(time.Second):
+		t.Errorf("c.Next() did not return after the data was sent")
+	}
+}
+
+func TestConnectionDeleted(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	datastore := dsmock.NewMockDatastore(closed, map[string]string{
+		"user/1/id":   "1",
+		"user/1/name": `"Hello World"`,
+		"user/2/id":   "2",
+		"user/2/name": `"Hello World"`,
+	})
+
+	s := autoupdate.New(datastore, test.RestrictAllowed(), test.UserUpdater{}, closed)
+	c := s.Connect(1, test.KeysBuilder{K: test.Str("user/1/name", "user/2/name")}, autoupdate.WithDeleted())
+
+	if _, err := c.Next(context.Background()); err != nil {
+		t.Fatalf("c.Next() returned an error: %v", err)
+	}
+
+	// user/1 is deleted. On user/2 only the name is removed.
+	datastore.Send(map[string]string{"user/1/id": "", "user/1/name": "", "user/2/name": ""})
+	data, err := c.Next(context.Background())
+	if err != nil {
+		t.Fatalf("c.Next() returned an error: %v", err)
+	}
+
+	if v, ok := data["user/2/name"]; !ok || v != nil {
+		t.Errorf("Got user/2/name %q, expected null", v)
+	}
+
+	assert.JSONEq(t, `["user/1"]`, string(data[autoupdate.DeletedKey]))
+}
+
+func TestConnectionNotify(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	datastore := dsmock.NewMockDatastore(closed, map[string]string{
+		"user/1/name":         `"member"`,
+		"user/1/group_$_ids":  `["1"]`,
+		"user/1/group_$1_ids": `[1]`,
+		"user/2/name":         `"guest"`,
+		"user/2/group_$_ids":  `["2"]`,
+		"user/2/group_$2_ids": `[1]`,
+		"user/3/group_$_ids":  `["1","2"]`,
+		"user/3/group_$1_ids": `[1]`,
+		"user/3/group_$2_ids": `[1]`,
+	})
+
+	s := autoupdate.New(datastore, test.RestrictAllowed(), test.UserUpdater{}, closed)
+	member := s.Connect(1, test.KeysBuilder{K: test.Str("user/1/name")}, autoupdate.WithNotify())
+	guest := s.Connect(2, test.KeysBuilder{K: test.Str("user/2/name")}, autoupdate.WithNotify())
+
+	for _, c := range []*autoupdate.Connection{member, guest} {
+		if _, err := c.Next(context.Background()); err != nil {
+			t.Fatalf("c.Next() returned an error: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	if err := s.Notify(ctx, 3, autoupdate.Notification{Name: "applause", Message: []byte(`{"level":3}`), ToMeeting: 1}); err != nil {
+		t.Fatalf("Notify returned an error: %v", err)
+	}
+
+	if err := s.Notify(ctx, 3, autoupdate.Notification{Name: "typing", Message: []byte(`true`), ToUsers: []int{2}}); err != nil {
+		t.Fatalf("Notify returned an error: %v", err)
+	}
+
+	data, err := member.Next(ctx)
+	if err != nil {
+		t.Fatalf("c.Next() returned an error: %v", err)
+	}
+	assert.Equal(t, 1, len(data), "message contains only the notification")
+	assert.JSONEq(t, `[{"sender_user_id":3,"name":"applause","message":{"level":3}}]`, string(data[autoupdate.NotifyKey]))
+
+	data, err = guest.Next(ctx)
+	if err != nil {
+		t.Fatalf("c.Next() returned an error: %v", err)
+	}
+	assert.JSONEq(t, `[{"sender_user_id":3,"name":"typing","message":true}]`, string(data[autoupdate.NotifyKey]))
+}
+
+func TestNotifyInvalid(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	datastore := dsmock.NewMockDatastore(closed, map[string]string{
+		"user/1/group_$_ids":  `["1"]`,
+		"user/1/group_$1_ids": `[1]`,
+		"user/2/group_$_ids":  `["2"]`,
+		"user/2/group_$2_ids": `[1]`,
+	})
+	s := autoupdate.New(datastore, test.RestrictAllowed(), test.UserUpdater{}, closed)
+
+	for _, tt := range []struct {
+		name         string
+		uid          int
+		notification autoupdate.Notification
+	}{
+		{"anonymous", 0, autoupdate.Notification{Name: "applause", Message: []byte(`1`), ToUsers: []int{1}}},
+		{"invalid name", 1, autoupdate.Notification{Name: "Applause!", Message: []byte(`1`), ToUsers: []int{1}}},
+		{"invalid message", 1, autoupdate.Notification{Name: "applause", Message: []byte(`{`), ToUsers: []int{1}}},
+		{"no receivers", 1, autoupdate.Notification{Name: "applause", Message: []byte(`1`)}},
+		{"other meeting", 1, autoupdate.Notification{Name: "applause", Message: []byte(`1`), ToMeeting: 2}},
+		{"no shared meeting", 1, autoupdate.Notification{Name: "applause", Message: []byte(`1`), ToUsers: []int{2}}},
+		{"unknown user", 1, autoupdate.Notification{Name: "applause", Message: []byte(`1`), ToUsers: []int{404}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.Notify(context.Background(), tt.uid, tt.notification); err == nil {
+				t.Errorf("Notify returned no error")
+			}
+		})
+	}
+}
+
+func TestNotifyRateLimit(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	datastore := dsmock.NewMockDatastore(closed, map[string]string{
+		"user/1/group_$1_ids": `[1]`,
+	})
+	s := autoupdate.New(datastore, test.RestrictAllowed(), test.UserUpdater{}, closed)
+
+	n := autoupdate.Notification{Name: "applause", Message: []byte(`1`), ToMeeting: 1}
+	for i := 0; i < autoupdate.NotifyBurst; i++ {
+		if err := s.Notify(context.Background(), 1, n); err != nil {
+			t.Fatalf("Notification %d returned an error: %v", i, err)
+		}
+	}
+
+	err := s.Notify(context.Background(), 1, n)
+	var errStatus interface{ StatusCode() int }
+	if !errors.As(err, &errStatus) || errStatus.StatusCode() != 429 {
+		t.Errorf("Got error %v, expected an error with status 429", err)
+	}
+}
+
+func TestConnectionMeeting(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	datastore := dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+	meeting/1/name: first
+	meeting/2/name: second
+	motion/1:
+		title: first motion
+		meeting_id: 1
+	motion/2:
+		title: second motion
+		meeting_id: 2
+	user/1/username: jonny
+	`))
+
+	s := autoupdate.New(datastore, test.RestrictAllowed(), test.UserUpdater{}, closed)
+	kb := test.KeysBuilder{K: test.Str("meeting/1/name", "meeting/2/name", "motion/1/title", "motion/2/title", "user/1/username")}
+	c := s.Connect(1, kb, autoupdate.WithMeeting(1))
+
+	data, err := c.Next(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]json.RawMessage{
+		"meeting/1/name":  []byte(`"first"`),
+		"motion/1/title":  []byte(`"first motion"`),
+		"user/1/username": []byte(`"jonny"`),
+	}, data)
+
+	datastore.Send(map[string]string{"motion/1/title": `"new"`, "motion/2/title": `"new"`})
+	data, err = c.Next(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]json.RawMessage{"motion/1/title": []byte(`"new"`)}, data)
+}
+
+func TestConnectionKnownCollections(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	datastore := dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+	motion/1/title: first motion
+	moton/1/title: typo
+	user/1/username: jonny
+	`))
+
+	s := autoupdate.New(datastore, test.RestrictAllowed(), test.UserUpdater{}, closed, autoupdate.WithKnownCollections("motion", "user"))
+	kb := test.KeysBuilder{K: test.Str("motion/1/title", "moton/1/title", "user/1", "user/1/username")}
+	c := s.Connect(1, kb)
+
+	data, err := c.Next(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]json.RawMessage{
+		"motion/1/title":  []byte(`"first motion"`),
+		"user/1/username": []byte(`"jonny"`),
+	}, data)
+
+	datastore.Send(map[string]string{"motion/1/title": `"new"`, "moton/1/title": `"new"`})
+	data, err = c.Next(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]json.RawMessage{"motion/1/title": []byte(`"new"`)}, data)
+}
+
 func TestConntectionFilterOnlyOneKey(t *testing.T) {
 	closed := make(chan struct{})
 	defer close(closed)
@@ -364,6 +694,65 @@ func TestFullUpdate(t *testing.T) {
 		assert.Empty(t, data, "Data should be empty if data did not change")
 	})
 
+	t.Run("reset cache without changes", func(t *testing.T) {
+		c := s.Connect(1, kb)
+		if _, err := c.Next(context.Background()); err != nil {
+			t.Errorf("c.Next() returned an error: %v", err)
+		}
+
+		s.ResetCache()
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		// Wait until Next returned after the context is canceled, so data
+		// is not written after it was checked.
+		done := make(chan struct{})
+		var data map[string]json.RawMessage
+		isBlocking := blocking(func() {
+			defer close(done)
+			data, _ = c.Next(ctx)
+		})
+		cancel()
+		<-done
+
+		assert.True(t, isBlocking, "Next should only send changed values after a cache reset")
+		assert.Empty(t, data)
+	})
+
+	t.Run("same user in projector mode", func(t *testing.T) {
+		projectorRestricter := test.RestrictAllowed()
+		projectorUpdater := new(test.UserUpdater)
+		ps := autoupdate.New(datastore, projectorRestricter, projectorUpdater, closed, autoupdate.WithProjectorUsers(1))
+
+		c := ps.Connect(1, kb)
+		if _, err := c.Next(context.Background()); err != nil {
+			t.Errorf("c.Next() returned an error: %v", err)
+		}
+
+		projectorRestricter.Values = map[string]string{
+			"user/1/name": `"New Value"`,
+		}
+		// Send fulldata for same user.
+		projectorUpdater.UserIDs = []int{1}
+		datastore.Send(map[string]string{"some/5/data": "value"})
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		// Wait until Next returned after the context is canceled, so data
+		// is not written after it was checked.
+		done := make(chan struct{})
+		var data map[string]json.RawMessage
+		isBlocking := blocking(func() {
+			defer close(done)
+			data, _ = c.Next(ctx)
+		})
+		cancel()
+		<-done
+
+		assert.True(t, isBlocking, "Next should ignore the full update in projector mode")
+		assert.Empty(t, data)
+	})
+
 	t.Run("every user gets an full update on uid -1", func(t *testing.T) {
 		c := s.Connect(1, kb)
 		if _, err := c.Next(context.Background()); err != nil {
@@ -441,3 +830,60 @@ func TestNextNoReturnWhenDataIsRestricted(t *testing.T) {
 	})
 
 }
+
+func TestConnectionRelationOfInvisibleObject(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	datastore := dsmock.NewMockDatastore(closed, map[string]string{
+		"meeting/1/motion_ids": `[1,2]`,
+		"motion/1/title":       `"m1"`,
+		"motion/2/title":       `"m2"`,
+	})
+	restricter := test.RestrictAllowed()
+	relations := map[string]string{"meeting/motion_ids": "motion"}
+	s := autoupdate.New(datastore, restricter, test.UserUpdater{}, closed, autoupdate.WithRelations(relations))
+	kb := test.KeysBuilder{K: test.Str("meeting/1/motion_ids", "motion/1/title", "motion/2/title")}
+	c := s.Connect(1, kb)
+
+	if _, err := c.Next(context.Background()); err != nil {
+		t.Fatalf("c.Next() returned an error: %v", err)
+	}
+
+	// motion/2 gets invisible.
+	restricter.Values = map[string]string{
+		"meeting/1/motion_ids": `[1]`,
+		"motion/2/title":       `null`,
+	}
+	datastore.Send(map[string]string{"motion/2/title": `"hidden"`})
+
+	data, err := c.Next(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]json.RawMessage{
+		"meeting/1/motion_ids": []byte(`[1]`),
+		"motion/2/title":       []byte(`null`),
+	}, data)
+}
+
+func TestConnectionStress(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	keys := test.Str("user/1/name", "user/2/name", "user/3/name", "motion/1/title", "motion/2/title")
+	datastore := dsmock.NewMockDatastore(closed, nil)
+	s := autoupdate.New(datastore, test.RestrictAllowed(), test.UserUpdater{}, closed)
+
+	var readers []dsmock.ReadFunc
+	for i := 0; i < 5; i++ {
+		readers = append(readers, s.Connect(1, test.KeysBuilder{K: keys}).Next)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stress := dsmock.Stress{Keys: keys, Workers: 3, Updates: 50}
+	if err := stress.Run(ctx, datastore, readers...); err != nil {
+		t.Errorf("Run returned unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package autoupdate
+
+import "time"
+
+// WithDebounce collects the datastore updates for the duration d and publishes
+// them together. The window starts with the first update, so a constant stream
+// of updates is still published every d. Connections get one message for all
+// updates in this time.
+//
+// A value of 0 publishes each update immediately. This is the default.
+func WithDebounce(d time.Duration) Option {
+	return func(a *Autoupdate) {
+		a.debounce = d
+	}
+}
+
+// publish publishes the keys to the topic. With a debounce window, the keys
+// are collected until the window is over.
+func (a *Autoupdate) publish(keys []string) {
+	if a.debounce <= 0 {
+		a.positions.publish(a.topic, a.datastorePosition(), keys)
+		return
+	}
+
+	a.debounceMu.Lock()
+	defer a.debounceMu.Unlock()
+
+	if a.pendingKeys == nil {
+		a.pendingKeys = make(map[string]bool)
+		time.AfterFunc(a.debounce, a.flush)
+	}
+
+	for _, key := range keys {
+		a.pendingKeys[key] = true
+	}
+}
+
+// flush publishes the collected keys.
+func (a *Autoupdate) flush() {
+	a.debounceMu.Lock()
+	pending := a.pendingKeys
+	a.pendingKeys = nil
+	a.debounceMu.Unlock()
+
+	keys := make([]string, 0, len(pending))
+	for key := range pending {
+		keys = append(keys, key)
+	}
+	a.positions.publish(a.topic, a.datastorePosition(), keys)
+}
@@ -0,0 +1,79 @@
+package autoupdate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DeletedKey is the key in a message, that contains the objects that were
+// deleted.
+const DeletedKey = "_deleted"
+
+// WithDeleted adds the list of deleted objects to each message.
+//
+// A key with the value null can mean, that the object was deleted or that the
+// user can not see it anymore. With this option, each message contains the key
+// `_deleted` with the ids of the objects, that do not exist anymore, for
+// example `["motion/5"]`.
+func WithDeleted() ConnectionOption {
+	return func(c *Connection) {
+		c.deleted = true
+	}
+}
+
+// addDeleted adds the ids of the deleted objects to the data.
+//
+// An object is deleted, if one of its keys is null in the message and its id
+// field does not exist in the datastore.
+func (c *Connection) addDeleted(ctx context.Context, data map[string]json.RawMessage) error {
+	seen := make(map[string]bool)
+	var idKeys []string
+	for key, value := range data {
+		if value != nil {
+			continue
+		}
+
+		parts := strings.SplitN(key, "/", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		fqid := parts[0] + "/" + parts[1]
+		if seen[fqid] {
+			continue
+		}
+		seen[fqid] = true
+		idKeys = append(idKeys, fqid+"/id")
+	}
+
+	if len(idKeys) == 0 {
+		return nil
+	}
+
+	values, err := c.autoupdate.datastore.Get(ctx, idKeys...)
+	if err != nil {
+		return fmt.Errorf("getting id fields: %w", err)
+	}
+
+	var deleted []string
+	for i, key := range idKeys {
+		if values[i] == nil {
+			deleted = append(deleted, strings.TrimSuffix(key, "/id"))
+		}
+	}
+
+	if len(deleted) == 0 {
+		return nil
+	}
+	sort.Strings(deleted)
+
+	bs, err := json.Marshal(deleted)
+	if err != nil {
+		return fmt.Errorf("encoding deleted objects: %w", err)
+	}
+	data[DeletedKey] = bs
+	return nil
+}
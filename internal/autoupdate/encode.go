@@ -0,0 +1,145 @@
+package autoupdate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// encodeBufferSize is the size of the buffer of a messageEncoder. Values that
+// do not fit into the buffer are written directly to the writer.
+const encodeBufferSize = 4 << 10
+
+// messageEncoder writes the messages of a connection as json objects. Each
+// message is one line.
+//
+// In difference to json.Encoder, it does not build intermediate values. The
+// keys and small values are collected in a small buffer, big values are
+// written directly to the writer. So a message does not create garbage,
+// besides the sorting of the keys, and the memory of a connection does not
+// depend on the size of its messages.
+//
+// The values have to be valid json. They are not validated. Empty values are
+// written as null. Values with line breaks are compacted, so each message
+// stays one line. Keys are sorted like json.Encoder does.
+type messageEncoder struct {
+	w    io.Writer
+	buf  []byte
+	keys []string
+}
+
+func newMessageEncoder(w io.Writer) *messageEncoder {
+	return &messageEncoder{w: w}
+}
+
+// Encode writes the message to the writer.
+func (e *messageEncoder) Encode(data map[string]json.RawMessage) error {
+	e.keys = e.keys[:0]
+	for key := range data {
+		e.keys = append(e.keys, key)
+	}
+	sort.Strings(e.keys)
+
+	e.buf = append(e.buf[:0], '{')
+	for i, key := range e.keys {
+		if i > 0 {
+			e.buf = append(e.buf, ',')
+		}
+
+		e.buf = appendJSONString(e.buf, key)
+		e.buf = append(e.buf, ':')
+
+		if err := e.writeValue(data[key]); err != nil {
+			return fmt.Errorf("encoding value of %s: %w", key, err)
+		}
+	}
+	e.buf = append(e.buf, '}', '\n')
+
+	return e.flush()
+}
+
+// writeValue adds a value to the buffer. If the value is too big for the
+// buffer, the buffer and the value are written to the writer.
+func (e *messageEncoder) writeValue(value json.RawMessage) error {
+	if len(e.buf)+len(value) <= encodeBufferSize || bytes.IndexByte(value, '\n') >= 0 || bytes.IndexByte(value, '\r') >= 0 {
+		var err error
+		e.buf, err = appendJSONValue(e.buf, value)
+		if err != nil {
+			return err
+		}
+
+		if len(e.buf) > encodeBufferSize {
+			return e.flush()
+		}
+		return nil
+	}
+
+	if err := e.flush(); err != nil {
+		return err
+	}
+
+	if _, err := e.w.Write(value); err != nil {
+		return err
+	}
+	return nil
+}
+
+// flush writes the buffer to the writer and empties it. A buffer, that grew
+// by a big value, is released.
+func (e *messageEncoder) flush() error {
+	_, err := e.w.Write(e.buf)
+	e.buf = e.buf[:0]
+	if cap(e.buf) > 2*encodeBufferSize {
+		e.buf = nil
+	}
+	return err
+}
+
+// appendJSONValue appends a raw json value to buf. nil and empty values are
+// written as null.
+func appendJSONValue(buf []byte, value json.RawMessage) ([]byte, error) {
+	if len(value) == 0 {
+		return append(buf, "null"...), nil
+	}
+
+	if bytes.IndexByte(value, '\n') < 0 && bytes.IndexByte(value, '\r') < 0 {
+		return append(buf, value...), nil
+	}
+
+	// A line break can only be whitespace between json tokens. Inside of
+	// strings, it has to be escaped.
+	out := bytes.NewBuffer(buf)
+	if err := json.Compact(out, value); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// appendJSONString appends s as json string to buf.
+func appendJSONString(buf []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			// Let the json package handle unicode. Keys are ascii, so this
+			// should not happen.
+			bs, _ := json.Marshal(s)
+			return append(buf, bs...)
+		}
+	}
+
+	const hex = "0123456789abcdef"
+	buf = append(buf, '"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			buf = append(buf, '\\', c)
+		case c < 0x20:
+			buf = append(buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return append(buf, '"')
+}
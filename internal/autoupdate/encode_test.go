@@ -0,0 +1,129 @@
+package autoupdate
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/testdata"
+)
+
+func TestMessageEncoder(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		data   map[string]json.RawMessage
+		expect string
+	}{
+		{
+			"empty",
+			map[string]json.RawMessage{},
+			"{}\n",
+		},
+		{
+			"sorted keys",
+			map[string]json.RawMessage{"user/2/name": []byte(`"b"`), "user/1/name": []byte(`"a"`)},
+			`{"user/1/name":"a","user/2/name":"b"}` + "\n",
+		},
+		{
+			"nil value",
+			map[string]json.RawMessage{"user/1/name": nil},
+			`{"user/1/name":null}` + "\n",
+		},
+		{
+			"empty value",
+			map[string]json.RawMessage{"user/1/name": []byte{}},
+			`{"user/1/name":null}` + "\n",
+		},
+		{
+			"big value",
+			map[string]json.RawMessage{"user/1/name": []byte(`"` + strings.Repeat("a", 2*encodeBufferSize) + `"`), "user/2/name": []byte(`"b"`)},
+			`{"user/1/name":"` + strings.Repeat("a", 2*encodeBufferSize) + `","user/2/name":"b"}` + "\n",
+		},
+		{
+			"empty value",
+			map[string]json.RawMessage{"user/1/name": []byte{}},
+			`{"user/1/name":null}` + "\n",
+		},
+		{
+			"line breaks in value",
+			map[string]json.RawMessage{"user/1/ids": []byte("[\n  1,\r\n  2\n]")},
+			`{"user/1/ids":[1,2]}` + "\n",
+		},
+		{
+			"escaped key",
+			map[string]json.RawMessage{"a\"b\\c\n": []byte(`1`)},
+			`{"a\"b\\c\u000a":1}` + "\n",
+		},
+		{
+			"unicode key",
+			map[string]json.RawMessage{"üser": []byte(`1`)},
+			`{"üser":1}` + "\n",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := newMessageEncoder(&buf).Encode(tt.data); err != nil {
+				t.Fatalf("Encode returned unexpected error: %v", err)
+			}
+
+			if got := buf.String(); got != tt.expect {
+				t.Errorf("Got `%s`, expected `%s`", got, tt.expect)
+			}
+
+			var decoded map[string]json.RawMessage
+			if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+				t.Errorf("Output is not valid json: %v", err)
+			}
+		})
+	}
+}
+
+func TestMessageEncoderReuse(t *testing.T) {
+	var buf bytes.Buffer
+	e := newMessageEncoder(&buf)
+
+	if err := e.Encode(map[string]json.RawMessage{"a/1/a": []byte(`"long value"`), "a/1/b": []byte(`2`)}); err != nil {
+		t.Fatalf("Encode returned unexpected error: %v", err)
+	}
+
+	if err := e.Encode(map[string]json.RawMessage{"a/1/a": []byte(`1`)}); err != nil {
+		t.Fatalf("Encode returned unexpected error: %v", err)
+	}
+
+	expect := `{"a/1/a":"long value","a/1/b":2}` + "\n" + `{"a/1/a":1}` + "\n"
+	if got := buf.String(); got != expect {
+		t.Errorf("Got `%s`, expected `%s`", got, expect)
+	}
+}
+
+func BenchmarkMessageEncoder(b *testing.B) {
+	data := make(map[string]json.RawMessage)
+	for k, v := range testdata.Meeting() {
+		data[k] = []byte(v)
+	}
+
+	b.Run("messageEncoder", func(b *testing.B) {
+		e := newMessageEncoder(discard{})
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := e.Encode(data); err != nil {
+				b.Fatalf("Encode returned unexpected error: %v", err)
+			}
+		}
+	})
+
+	b.Run("json.Encoder", func(b *testing.B) {
+		e := json.NewEncoder(discard{})
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := e.Encode(data); err != nil {
+				b.Fatalf("Encode returned unexpected error: %v", err)
+			}
+		}
+	})
+}
+
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
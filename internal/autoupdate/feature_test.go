@@ -7,9 +7,9 @@ import (
 	"testing"
 
 	"github.com/OpenSlides/openslides-autoupdate-service/internal/autoupdate"
-	"github.com/OpenSlides/openslides-autoupdate-service/internal/keysbuilder"
 	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
 	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/keysbuilder"
 )
 
 var dataSet = map[string]string{
@@ -234,8 +234,37 @@ func TestFeatures(t *testing.T) {
 			`{
 				"d/1/d":       "d1",
 				"d/1/b_$_ids": ["1","2","3"],
+				"d/1/b_$1_ids": [1,2],
+				"d/1/b_$2_ids": [1],
+				"d/1/b_$3_ids": [],
 				"d/2/d":       "d2",
-				"d/2/b_$_ids": ["1","4"]
+				"d/2/b_$_ids": ["1","4"],
+				"d/2/b_$1_ids": [],
+				"d/2/b_$4_ids": [2]
+			}`,
+		},
+		{
+			"template fields without type",
+			`{
+				"collection": "d",
+				"ids": [1],
+				"fields": {
+					"b_$_ids": {
+						"type": "relation-list",
+						"collection": "b",
+						"fields": {
+							"b": null
+						}
+					}
+				}
+			}`,
+			`{
+				"d/1/b_$_ids": ["1","2","3"],
+				"d/1/b_$1_ids": [1,2],
+				"d/1/b_$2_ids": [1],
+				"d/1/b_$3_ids": [],
+				"b/1/b":       "b1",
+				"b/2/b":       "b2"
 			}`,
 		},
 		{
@@ -1,13 +1,33 @@
 package autoupdate
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"hash/maphash"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/intern"
 )
 
+// hashSeed is the seed for the checksums of the filter. It is random for each
+// process, so a client can not create values with the same checksum.
+var hashSeed = maphash.MakeSeed()
+
+// filter remembers a checksum of each value, that was sent to a client. It is
+// used to only send values that have changed.
+//
+// Only the checksums are saved and not the values itself. So the memory of a
+// connection only depends on the number of keys and not on the size of the
+// values. The checksums do not depend on the connection, so they can be
+// compared between connections of the same process.
 type filter struct {
-	hash    maphash.Hash
 	history map[string]uint64
+
+	// restored are the checksums of a previous connection of the client. They
+	// are only used for the first call of filter.
+	restored map[string]uint64
+
+	// sum is the checksum of all keys and values in history.
+	sum uint64
 }
 
 // filter has to be called on a reader that contains a decoded json object. It
@@ -19,29 +39,91 @@ func (f *filter) filter(data map[string]json.RawMessage) {
 	}
 
 	for key, value := range data {
-		if len(value) == 0 {
-			// Delete empty data
-			if f.history[key] == 0 {
-				// Data was empty before
-				delete(data, key)
-			}
-			f.history[key] = 0
-			continue
+		var new uint64
+		if len(value) > 0 {
+			new = checksum(value)
 		}
 
-		f.hash.Reset()
-		f.hash.Write(value)
-		new := f.hash.Sum64()
-		if old, ok := f.history[key]; ok && new == old {
+		old, ok := f.history[key]
+		if !ok {
+			// Keys, that were never sent, have the checksum 0 like empty
+			// values.
+			old = f.restored[key]
+		}
+
+		if old == new {
 			delete(data, key)
-			continue
 		}
-		f.history[key] = new
+		f.set(key, new)
+	}
+	f.restored = nil
+}
+
+// set saves the checksum of a key and updates the checksum of all values.
+func (f *filter) set(key string, sum uint64) {
+	old, ok := f.history[key]
+	if ok {
+		if old == sum {
+			return
+		}
+		f.sum -= entrySum(key, old)
+	} else {
+		key = intern.String(key)
+	}
+
+	f.history[key] = sum
+	f.sum += entrySum(key, sum)
+}
+
+// prune removes the checksums of all keys, that are not in keys.
+//
+// It is called on a full update. Afterwards, the filter only contains the
+// values of the requested keys. A key, that is requested again later, is sent
+// again.
+func (f *filter) prune(keys []string) {
+	if f.history == nil {
+		return
+	}
+
+	requested := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		requested[key] = true
+	}
+
+	for key, sum := range f.history {
+		if !requested[key] {
+			f.sum -= entrySum(key, sum)
+			delete(f.history, key)
+		}
 	}
-	return
 }
 
 // empty returns true, if the filter was not called before.
 func (f *filter) empty() bool {
 	return f.history == nil
 }
+
+// checksum returns the checksum of a value. It is never 0, because 0 is used
+// for values that do not exist.
+func checksum(value []byte) uint64 {
+	var h maphash.Hash
+	h.SetSeed(hashSeed)
+	h.Write(value)
+	sum := h.Sum64()
+	if sum == 0 {
+		return 1
+	}
+	return sum
+}
+
+// entrySum returns the checksum of a key with the checksum of its value.
+func entrySum(key string, sum uint64) uint64 {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], sum)
+
+	var h maphash.Hash
+	h.SetSeed(hashSeed)
+	h.WriteString(key)
+	h.Write(buf[:])
+	return h.Sum64()
+}
@@ -120,3 +120,56 @@ func TestFilterChange(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterChecksumNotZero(t *testing.T) {
+	var f filter
+	f.filter(map[string]json.RawMessage{"k1": []byte(`"v1"`)})
+
+	if f.history["k1"] == 0 {
+		t.Errorf("Checksum of an existing value is 0")
+	}
+}
+
+func TestFilterPrune(t *testing.T) {
+	var f filter
+	f.filter(map[string]json.RawMessage{"k1": []byte("v1"), "k2": []byte("v2")})
+
+	f.prune([]string{"k1"})
+
+	data := map[string]json.RawMessage{"k1": []byte("v1"), "k2": []byte("v2")}
+	f.filter(data)
+	assert.Equal(t, map[string]json.RawMessage{"k2": []byte("v2")}, data, "pruned key has to be sent again")
+}
+
+func TestFilterSum(t *testing.T) {
+	var f1 filter
+	f1.filter(map[string]json.RawMessage{"k1": []byte("v1")})
+	sumK1 := f1.sum
+	f1.filter(map[string]json.RawMessage{"k2": []byte("v2")})
+
+	var f2 filter
+	f2.filter(map[string]json.RawMessage{"k2": []byte("v2"), "k1": []byte("v1")})
+
+	assert.Equal(t, f1.sum, f2.sum, "same data has to have the same checksum")
+
+	f2.filter(map[string]json.RawMessage{"k2": []byte("other")})
+	assert.NotEqual(t, f1.sum, f2.sum, "different data has the same checksum")
+
+	f1.prune([]string{"k1"})
+	assert.Equal(t, sumK1, f1.sum, "checksum after prune")
+}
+
+func TestFilterRestored(t *testing.T) {
+	var old filter
+	old.filter(map[string]json.RawMessage{"k1": []byte("v1"), "k2": []byte("v2")})
+
+	f := filter{restored: old.history}
+	data := map[string]json.RawMessage{"k1": []byte("v1"), "k2": []byte("new"), "k3": []byte("v3")}
+	f.filter(data)
+
+	assert.Equal(t, map[string]json.RawMessage{"k2": []byte("new"), "k3": []byte("v3")}, data)
+
+	data = map[string]json.RawMessage{"k1": []byte("v1")}
+	f.filter(data)
+	assert.Empty(t, data, "restored value was sent")
+}
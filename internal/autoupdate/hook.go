@@ -0,0 +1,61 @@
+package autoupdate
+
+import (
+	"context"
+	"io"
+)
+
+// ConnectionHook is informed about the connections, that are opened with Live.
+//
+// The methods are called from the goroutine of the connection. A slow hook
+// delays the messages of the connection. ctx is the context given to Live. It
+// can be used to identify the connection.
+type ConnectionHook interface {
+	// OnConnect is called, before the first message is created.
+	OnConnect(ctx context.Context, uid int)
+
+	// OnMessage is called after a message was written. keys is the number of
+	// keys in the message and size the number of written bytes.
+	OnMessage(ctx context.Context, uid int, keys int, size int)
+
+	// OnDisconnect is called, when Live returns. err is the returned error.
+	OnDisconnect(ctx context.Context, uid int, err error)
+}
+
+// WithConnectionHook registers a ConnectionHook. The option can be used more
+// then once. The hooks are called in the order they were registered.
+func WithConnectionHook(hook ConnectionHook) Option {
+	return func(a *Autoupdate) {
+		a.hooks = append(a.hooks, hook)
+	}
+}
+
+func (a *Autoupdate) hookConnect(ctx context.Context, uid int) {
+	for _, hook := range a.hooks {
+		hook.OnConnect(ctx, uid)
+	}
+}
+
+func (a *Autoupdate) hookMessage(ctx context.Context, uid int, keys int, size int) {
+	for _, hook := range a.hooks {
+		hook.OnMessage(ctx, uid, keys, size)
+	}
+}
+
+func (a *Autoupdate) hookDisconnect(ctx context.Context, uid int, err error) {
+	for _, hook := range a.hooks {
+		hook.OnDisconnect(ctx, uid, err)
+	}
+}
+
+// countWriter counts the bytes, that are written to w.
+type countWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.count += n
+	return n, err
+}
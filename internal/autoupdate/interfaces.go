@@ -10,6 +10,13 @@ type Datastore interface {
 	Get(ctx context.Context, keys ...string) ([]json.RawMessage, error)
 	RegisterChangeListener(f func(map[string]json.RawMessage) error)
 	ResetCache()
+	ResetCollection(collection string) []string
+}
+
+// provenancer is an optional interface for the Datastore. It returns the
+// provenance of the update, that is currently processed.
+type provenancer interface {
+	Provenance() map[string]string
 }
 
 // Restricter restricts keys.
@@ -18,6 +25,12 @@ type Restricter interface {
 	Restrict(ctx context.Context, uid int, data map[string]json.RawMessage) error
 }
 
+// explainer is an optional interface for the Restricter. It restricts the
+// data and returns the rule that decided about each key.
+type explainer interface {
+	Explain(ctx context.Context, uid int, data map[string]json.RawMessage) (map[string]string, error)
+}
+
 // KeysBuilder holds the keys that are requested by a user.
 type KeysBuilder interface {
 	Update(ctx context.Context) error
@@ -0,0 +1,87 @@
+package autoupdate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// WithMeeting scopes the connection to one meeting.
+//
+// Keys of objects, that belong to another meeting, are dropped before they are
+// restricted. An object belongs to a meeting, if its field meeting_id points
+// to the meeting. Keys of objects without a meeting_id, for example users, are
+// not dropped. This saves the restricter work in installations with many
+// meetings, when a client only shows one of them.
+func WithMeeting(meetingID int) ConnectionOption {
+	return func(c *Connection) {
+		c.meetingID = meetingID
+	}
+}
+
+// filterMeeting removes the keys of objects, that belong to another meeting
+// than the meeting of the connection.
+func (c *Connection) filterMeeting(ctx context.Context, keys []string) ([]string, error) {
+	if c.meetingID == 0 {
+		return keys, nil
+	}
+
+	meetingStr := strconv.Itoa(c.meetingID)
+	seen := make(map[string]bool)
+	var meetingKeys []string
+	for _, key := range keys {
+		parts := strings.SplitN(key, "/", 3)
+		if len(parts) != 3 || parts[0] == "meeting" {
+			continue
+		}
+
+		fqid := parts[0] + "/" + parts[1]
+		if seen[fqid] {
+			continue
+		}
+		seen[fqid] = true
+		meetingKeys = append(meetingKeys, fqid+"/meeting_id")
+	}
+
+	otherMeeting := make(map[string]bool)
+	if len(meetingKeys) > 0 {
+		values, err := c.autoupdate.datastore.Get(ctx, meetingKeys...)
+		if err != nil {
+			return nil, fmt.Errorf("getting meeting_id fields: %w", err)
+		}
+
+		for i, key := range meetingKeys {
+			if values[i] == nil {
+				// Objects without a meeting and deleted objects are kept.
+				continue
+			}
+
+			var id int
+			if err := json.Unmarshal(values[i], &id); err != nil {
+				return nil, fmt.Errorf("decoding %s: %w", key, err)
+			}
+
+			if id != c.meetingID {
+				otherMeeting[strings.TrimSuffix(key, "/meeting_id")] = true
+			}
+		}
+	}
+
+	filtered := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts := strings.SplitN(key, "/", 3)
+		if len(parts) == 3 {
+			if parts[0] == "meeting" && parts[1] != meetingStr {
+				continue
+			}
+
+			if otherMeeting[parts[0]+"/"+parts[1]] {
+				continue
+			}
+		}
+		filtered = append(filtered, key)
+	}
+	return filtered, nil
+}
@@ -0,0 +1,414 @@
+package autoupdate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// NotifyKey is the key in a message, that contains the notifications for the
+// user.
+const NotifyKey = "_notify"
+
+// MaxNotifySize is the maximum size of the message of a notification in bytes.
+const MaxNotifySize = 4096
+
+// MaxNotifyReceivers is the maximum number of users in Notification.ToUsers.
+const MaxNotifyReceivers = 100
+
+// NotifyRate and NotifyBurst limit the notifications of each user. A user can
+// send NotifyBurst notifications at once and afterwards NotifyRate
+// notifications per second.
+const (
+	NotifyRate  = 5
+	NotifyBurst = 10
+)
+
+// notifyBufferSize is the number of notifications, that are kept for the
+// connections. A connection, that falls further behind, misses the older
+// notifications.
+const notifyBufferSize = 256
+
+// notifyTopicKey is published in the topic, when there are new notifications.
+// The notifications are not in the topic, only in the notifyStore. The key is
+// in the same namespace then model names.
+const notifyTopicKey = "notify/new"
+
+var validNotifyName = regexp.MustCompile(`^[a-z_]+$`)
+
+// Notification is a short lived message from one user to other users, for
+// example applause, a chat typing indicator or the presence in an editor.
+//
+// Notifications are not saved. Only connections, that are open when the
+// notification is sent, receive it.
+type Notification struct {
+	// Name is the channel of the notification, for example `applause`.
+	Name string `json:"name"`
+
+	// Message is the content of the notification. It can be any json value.
+	Message json.RawMessage `json:"message"`
+
+	// ToUsers are the ids of the users, that receive the notification.
+	ToUsers []int `json:"to_users,omitempty"`
+
+	// ToMeeting is the id of a meeting. All members of the meeting receive the
+	// notification.
+	ToMeeting int `json:"to_meeting,omitempty"`
+}
+
+// notifyEvent is a notification in the notifyStore.
+type notifyEvent struct {
+	id        uint64
+	toUsers   map[int]bool
+	toMeeting int
+
+	// message is the encoded notifyMessage. It is encoded once for all
+	// receivers.
+	message json.RawMessage
+}
+
+// notifyMessage is a notification, how it is sent to the client.
+type notifyMessage struct {
+	SenderUserID int             `json:"sender_user_id"`
+	Name         string          `json:"name"`
+	Message      json.RawMessage `json:"message"`
+}
+
+// notifyStore holds the last notifications in a ring buffer and limits the
+// notifications of each user with a token bucket.
+type notifyStore struct {
+	mu     sync.Mutex
+	lastID uint64
+	events [notifyBufferSize]notifyEvent
+
+	buckets   map[int]*notifyBucket
+	lastPrune time.Time
+}
+
+// notifyBucket contains the tokens of one user at the time last.
+type notifyBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newNotifyStore() *notifyStore {
+	return &notifyStore{buckets: make(map[int]*notifyBucket)}
+}
+
+// allow takes a token from the bucket of the user. It returns false, if the
+// bucket is empty.
+func (s *notifyStore) allow(uid int, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastPrune) >= time.Minute {
+		// Remove the buckets, that are full again.
+		s.lastPrune = now
+		for id, b := range s.buckets {
+			if b.tokens+now.Sub(b.last).Seconds()*NotifyRate >= NotifyBurst {
+				delete(s.buckets, id)
+			}
+		}
+	}
+
+	b, ok := s.buckets[uid]
+	if !ok {
+		b = &notifyBucket{tokens: NotifyBurst, last: now}
+		s.buckets[uid] = b
+	}
+
+	b.tokens = math.Min(NotifyBurst, b.tokens+now.Sub(b.last).Seconds()*NotifyRate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// add saves the event and overwrites the oldest one.
+func (s *notifyStore) add(event notifyEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	event.id = s.lastID
+	s.events[event.id%notifyBufferSize] = event
+}
+
+// last returns the id of the newest event.
+func (s *notifyStore) last() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastID
+}
+
+// since returns the events after the given id, that are still in the store,
+// and the id of the newest event.
+func (s *notifyStore) since(id uint64) ([]notifyEvent, uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lastID > notifyBufferSize && id < s.lastID-notifyBufferSize {
+		id = s.lastID - notifyBufferSize
+	}
+
+	var events []notifyEvent
+	for i := id + 1; i <= s.lastID; i++ {
+		events = append(events, s.events[i%notifyBufferSize])
+	}
+	return events, s.lastID
+}
+
+// WithNotify adds the notifications for the user to the messages.
+//
+// The notifications are sent in the key `_notify` as a list of objects with
+// the fields `sender_user_id`, `name` and `message`. A message can contain
+// only notifications.
+func WithNotify() ConnectionOption {
+	return func(c *Connection) {
+		c.notify = true
+	}
+}
+
+// Notify sends a notification from a user to the connections of the
+// receivers, that were opened with WithNotify.
+//
+// The anonymous user can not send notifications. To send a notification to a
+// meeting, the sender has to be a member of the meeting. To send a
+// notification to users, the sender has to share a meeting with each of them.
+// Each user can send NotifyRate notifications per second.
+func (a *Autoupdate) Notify(ctx context.Context, uid int, n Notification) error {
+	if uid == 0 {
+		return notifyError{msg: "Anonymous can not send notifications", status: 403}
+	}
+
+	if !validNotifyName.MatchString(n.Name) {
+		return notifyError{msg: fmt.Sprintf("invalid name %q, expected lower case letters and underscores", n.Name)}
+	}
+
+	if len(n.Message) == 0 || !json.Valid(n.Message) {
+		return notifyError{msg: "message has to be a json value"}
+	}
+
+	if len(n.Message) > MaxNotifySize {
+		return notifyError{msg: fmt.Sprintf("message is bigger then %d bytes", MaxNotifySize)}
+	}
+
+	if len(n.ToUsers) == 0 && n.ToMeeting == 0 {
+		return notifyError{msg: "notification needs to_users or to_meeting"}
+	}
+
+	if len(n.ToUsers) > MaxNotifyReceivers {
+		return notifyError{msg: fmt.Sprintf("notification has more then %d receivers", MaxNotifyReceivers)}
+	}
+
+	if !a.notifications.allow(uid, time.Now()) {
+		return notifyError{msg: "Too many notifications, try again later", status: 429}
+	}
+
+	if n.ToMeeting != 0 {
+		member, err := a.isMeetingMember(ctx, uid, n.ToMeeting)
+		if err != nil {
+			return fmt.Errorf("checking membership in meeting %d: %w", n.ToMeeting, err)
+		}
+
+		if !member {
+			return notifyError{msg: fmt.Sprintf("user is not a member of meeting %d", n.ToMeeting), status: 403}
+		}
+	}
+
+	if len(n.ToUsers) > 0 {
+		notShared, err := a.notSharingMeeting(ctx, uid, n.ToUsers)
+		if err != nil {
+			return fmt.Errorf("checking meetings of receivers: %w", err)
+		}
+
+		if notShared != 0 {
+			return notifyError{msg: fmt.Sprintf("user has no meeting with user %d", notShared), status: 403}
+		}
+	}
+
+	bs, err := json.Marshal(notifyMessage{
+		SenderUserID: uid,
+		Name:         n.Name,
+		Message:      n.Message,
+	})
+	if err != nil {
+		return fmt.Errorf("encoding notification: %w", err)
+	}
+
+	toUsers := make(map[int]bool, len(n.ToUsers))
+	for _, id := range n.ToUsers {
+		toUsers[id] = true
+	}
+
+	a.notifications.add(notifyEvent{
+		toUsers:   toUsers,
+		toMeeting: n.ToMeeting,
+		message:   bs,
+	})
+	a.topic.Publish(notifyTopicKey)
+	return nil
+}
+
+// isMeetingMember returns true, if the user is in a group of the meeting.
+func (a *Autoupdate) isMeetingMember(ctx context.Context, uid int, meetingID int) (bool, error) {
+	key := fmt.Sprintf("user/%d/group_$%d_ids", uid, meetingID)
+	values, err := a.datastore.Get(ctx, key)
+	if err != nil {
+		return false, fmt.Errorf("getting %s: %w", key, err)
+	}
+
+	if values[0] == nil {
+		return false, nil
+	}
+
+	var groupIDs []int
+	if err := json.Unmarshal(values[0], &groupIDs); err != nil {
+		return false, fmt.Errorf("decoding %s: %w", key, err)
+	}
+	return len(groupIDs) > 0, nil
+}
+
+// notSharingMeeting returns the first receiver, that is not in a meeting of
+// the user. It returns 0, if the user shares a meeting with all receivers.
+func (a *Autoupdate) notSharingMeeting(ctx context.Context, uid int, receivers []int) (int, error) {
+	meetingsKey := fmt.Sprintf("user/%d/group_$_ids", uid)
+	values, err := a.datastore.Get(ctx, meetingsKey)
+	if err != nil {
+		return 0, fmt.Errorf("getting %s: %w", meetingsKey, err)
+	}
+
+	var meetingIDs []string
+	if values[0] != nil {
+		if err := json.Unmarshal(values[0], &meetingIDs); err != nil {
+			return 0, fmt.Errorf("decoding %s: %w", meetingsKey, err)
+		}
+	}
+
+	var keys []string
+	for _, receiver := range receivers {
+		for _, meetingID := range meetingIDs {
+			keys = append(keys, fmt.Sprintf("user/%d/group_$%s_ids", receiver, meetingID))
+		}
+	}
+
+	values, err = a.datastore.Get(ctx, keys...)
+	if err != nil {
+		return 0, fmt.Errorf("getting groups of receivers: %w", err)
+	}
+
+	for i, receiver := range receivers {
+		if receiver == uid {
+			continue
+		}
+
+		shared := false
+		for j := range meetingIDs {
+			value := values[i*len(meetingIDs)+j]
+			if value == nil {
+				continue
+			}
+
+			var groupIDs []int
+			if err := json.Unmarshal(value, &groupIDs); err != nil {
+				return 0, fmt.Errorf("decoding %s: %w", keys[i*len(meetingIDs)+j], err)
+			}
+
+			if len(groupIDs) > 0 {
+				shared = true
+				break
+			}
+		}
+
+		if !shared {
+			return receiver, nil
+		}
+	}
+	return 0, nil
+}
+
+// receiveNotify remembers the new notifications for the next message, if the
+// user of the connection is a receiver.
+func (c *Connection) receiveNotify(ctx context.Context) error {
+	events, last := c.autoupdate.notifications.since(c.notifyRead)
+	c.notifyRead = last
+
+	for _, event := range events {
+		receiver, err := c.isNotifyReceiver(ctx, event)
+		if err != nil {
+			return fmt.Errorf("checking receiver: %w", err)
+		}
+
+		if receiver {
+			c.pendingNotify = append(c.pendingNotify, event.message)
+		}
+	}
+	return nil
+}
+
+// isNotifyReceiver returns true, if the user of the connection receives the
+// notification.
+func (c *Connection) isNotifyReceiver(ctx context.Context, event notifyEvent) (bool, error) {
+	if c.uid == 0 {
+		return false, nil
+	}
+
+	if event.toUsers[c.uid] {
+		return true, nil
+	}
+
+	if event.toMeeting == 0 {
+		return false, nil
+	}
+
+	return c.autoupdate.isMeetingMember(ctx, c.uid, event.toMeeting)
+}
+
+// addNotify adds the collected notifications to the data.
+func (c *Connection) addNotify(data map[string]json.RawMessage) map[string]json.RawMessage {
+	if len(c.pendingNotify) == 0 {
+		return data
+	}
+
+	bs, err := json.Marshal(c.pendingNotify)
+	c.pendingNotify = nil
+	if err != nil {
+		// Can not happen with a list of valid json values.
+		return data
+	}
+
+	if data == nil {
+		data = make(map[string]json.RawMessage, 1)
+	}
+	data[NotifyKey] = bs
+	return data
+}
+
+// notifyError is returned, if a notification can not be sent.
+type notifyError struct {
+	msg    string
+	status int
+}
+
+func (e notifyError) Error() string {
+	return e.msg
+}
+
+func (e notifyError) Type() string {
+	return "invalid_notification"
+}
+
+// StatusCode returns the http status code of the error.
+func (e notifyError) StatusCode() int {
+	if e.status == 0 {
+		return 400
+	}
+	return e.status
+}
@@ -0,0 +1,123 @@
+package autoupdate
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ostcar/topic"
+)
+
+// ChangeIDKey is the key in a streamed message, that contains the change id
+// of the message. It is the same id as ConnectionInfo.ChangeID.
+const ChangeIDKey = "_change_id"
+
+// PositionKey is the key in a streamed message, that contains the datastore
+// position of the message. It is only set, when the position is known.
+const PositionKey = "_position"
+
+// WithPosition adds the change id and the datastore position to each message.
+//
+// With them, a message can be compared with the event log of the datastore.
+func WithPosition() ConnectionOption {
+	return func(c *Connection) {
+		c.position = true
+	}
+}
+
+// positioner is an optional interface for the Datastore. It returns the
+// datastore position of the last update.
+type positioner interface {
+	Position() int
+}
+
+// datastorePosition returns the position of the datastore or 0, if it is
+// unknown.
+func (a *Autoupdate) datastorePosition() int {
+	if p, ok := a.datastore.(positioner); ok {
+		return p.Position()
+	}
+	return 0
+}
+
+// positionEntry is the datastore position of a change id.
+type positionEntry struct {
+	tid      uint64
+	position int
+	created  time.Time
+}
+
+// positionStore remembers the datastore position of the changes in the topic.
+//
+// Only changes, where the position changed, are saved. A change id without an
+// entry has the position of the entry before.
+type positionStore struct {
+	mu      sync.Mutex
+	entries []positionEntry
+}
+
+// publish publishes the keys to the topic and saves the position of the new
+// change id.
+//
+// The position is saved before a connection can read it. So a connection
+// always gets the position, that belongs to its change id.
+func (s *positionStore) publish(t *topic.Topic, position int, keys []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tid := t.Publish(keys...)
+
+	if position <= 0 {
+		return
+	}
+
+	if n := len(s.entries); n > 0 && s.entries[n-1].position == position {
+		return
+	}
+
+	s.entries = append(s.entries, positionEntry{tid: tid, position: position, created: time.Now()})
+}
+
+// at returns the position of a change id. It returns 0, if the position is
+// unknown.
+func (s *positionStore) at(tid uint64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := sort.Search(len(s.entries), func(i int) bool {
+		return s.entries[i].tid > tid
+	})
+	if i == 0 {
+		return 0
+	}
+	return s.entries[i-1].position
+}
+
+// prune removes the entries, that were created before the given time. The
+// newest entry is kept, because it is the position of all newer change ids.
+func (s *positionStore) prune(until time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := sort.Search(len(s.entries), func(i int) bool {
+		return !s.entries[i].created.Before(until)
+	})
+	if i == len(s.entries) {
+		i--
+	}
+	if i <= 0 {
+		return
+	}
+	s.entries = append(s.entries[:0], s.entries[i:]...)
+}
+
+// addPosition adds the change id and the datastore position of the
+// connection to a message.
+func (c *Connection) addPosition(data map[string]json.RawMessage) {
+	data[ChangeIDKey] = []byte(strconv.FormatUint(c.tid, 10))
+	if position := c.autoupdate.positions.at(c.tid); position > 0 {
+		data[PositionKey] = []byte(strconv.Itoa(position))
+	}
+}
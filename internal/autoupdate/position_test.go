@@ -0,0 +1,37 @@
+package autoupdate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ostcar/topic"
+)
+
+func TestPositionStore(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	top := topic.New(topic.WithClosed(closed))
+	var s positionStore
+
+	s.publish(top, 0, []string{"a/1/a"})  // tid 1, unknown position
+	s.publish(top, 10, []string{"a/1/a"}) // tid 2
+	top.Publish("fullupdate/1")           // tid 3, no new position
+	s.publish(top, 12, []string{"a/1/a"}) // tid 4
+
+	for tid, expect := range map[uint64]int{0: 0, 1: 0, 2: 10, 3: 10, 4: 12, 5: 12} {
+		if got := s.at(tid); got != expect {
+			t.Errorf("at(%d) returned %d, expected %d", tid, got, expect)
+		}
+	}
+
+	s.prune(time.Now().Add(time.Minute))
+
+	if got := s.at(4); got != 12 {
+		t.Errorf("at(4) after prune returned %d, expected 12", got)
+	}
+
+	if got := len(s.entries); got != 1 {
+		t.Errorf("store has %d entries after prune, expected 1", got)
+	}
+}
@@ -0,0 +1,136 @@
+package autoupdate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ProvenanceKey is the key in a message, that contains the provenance of the
+// changes in the message.
+const ProvenanceKey = "_provenance"
+
+// provenancePrefix is the prefix of topic keys, that contain the provenance of
+// a change. It is in the same namespace then model names.
+const provenancePrefix = "provenance/"
+
+// provenanceManagementLevels are the organisation management levels, that can
+// see the provenance of changes.
+var provenanceManagementLevels = map[string]bool{
+	"superadmin":              true,
+	"can_manage_organisation": true,
+}
+
+var validActionName = regexp.MustCompile(`^[a-z_]+\.[a-z_]+$`)
+
+// Provenance describes, who made a change.
+type Provenance struct {
+	UserID     int    `json:"user_id,omitempty"`
+	ActionName string `json:"action_name,omitempty"`
+	Position   int    `json:"position,omitempty"`
+}
+
+// WithProvenance adds the provenance of the changes to each message. The
+// provenance is only sent, if the user can manage the organisation.
+func WithProvenance() ConnectionOption {
+	return func(c *Connection) {
+		c.provenance = true
+	}
+}
+
+// sanitizeProvenance returns the known and valid fields of a provenance. It
+// returns nil, if there are none.
+func sanitizeProvenance(raw map[string]string) *Provenance {
+	var p Provenance
+	if id, err := strconv.Atoi(raw["user_id"]); err == nil && id > 0 {
+		p.UserID = id
+	}
+
+	if validActionName.MatchString(raw["action_name"]) {
+		p.ActionName = raw["action_name"]
+	}
+
+	if position, err := strconv.Atoi(raw["position"]); err == nil && position > 0 {
+		p.Position = position
+	}
+
+	if p == (Provenance{}) {
+		return nil
+	}
+	return &p
+}
+
+// provenanceTopicKey returns the topic key for a provenance. It returns an
+// empty string, if the provenance has no valid fields.
+func provenanceTopicKey(raw map[string]string) string {
+	p := sanitizeProvenance(raw)
+	if p == nil {
+		return ""
+	}
+
+	bs, err := json.Marshal(p)
+	if err != nil {
+		// Can not happen with this struct.
+		return ""
+	}
+	return provenancePrefix + string(bs)
+}
+
+// isProvenanceKey returns true, if the topic key contains a provenance.
+func isProvenanceKey(key string) bool {
+	return strings.HasPrefix(key, provenancePrefix)
+}
+
+// provenanceAllowed returns true, if the user of the connection can see the
+// provenance of changes. The result is only calculated once per connection.
+func (c *Connection) provenanceAllowed(ctx context.Context) (bool, error) {
+	if c.provenanceChecked {
+		return c.provenanceIsAllowed, nil
+	}
+
+	key := fmt.Sprintf("user/%d/organisation_management_level", c.uid)
+	values, err := c.autoupdate.datastore.Get(ctx, key)
+	if err != nil {
+		return false, fmt.Errorf("getting %s: %w", key, err)
+	}
+
+	var level string
+	if values[0] != nil {
+		if err := json.Unmarshal(values[0], &level); err != nil {
+			return false, fmt.Errorf("decoding %s: %w", key, err)
+		}
+	}
+
+	c.provenanceChecked = true
+	c.provenanceIsAllowed = c.uid != 0 && provenanceManagementLevels[level]
+	return c.provenanceIsAllowed, nil
+}
+
+// addProvenance adds the collected provenance to the data.
+func (c *Connection) addProvenance(ctx context.Context, data map[string]json.RawMessage) error {
+	if len(c.pendingProvenance) == 0 {
+		return nil
+	}
+
+	allowed, err := c.provenanceAllowed(ctx)
+	if err != nil {
+		return fmt.Errorf("checking provenance permission: %w", err)
+	}
+
+	pending := c.pendingProvenance
+	c.pendingProvenance = nil
+
+	if !allowed {
+		return nil
+	}
+
+	bs, err := json.Marshal(pending)
+	if err != nil {
+		return fmt.Errorf("encoding provenance: %w", err)
+	}
+	data[ProvenanceKey] = bs
+	return nil
+}
@@ -0,0 +1,66 @@
+package autoupdate
+
+import (
+	"strings"
+	"time"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/models"
+)
+
+// Option is an optional argument for New.
+type Option func(*Autoupdate)
+
+// WithPruneTime sets how long a topic id is valid. A client, that needs more
+// time to read a message, gets an error or a full update (see WithResync).
+// The default is DefaultPruneTime.
+func WithPruneTime(d time.Duration) Option {
+	return func(a *Autoupdate) {
+		a.pruneTime = d
+	}
+}
+
+// WithRelations sets the relation-list fields of the models. relations is a
+// map from `collection/field` to the collection the field points to. For
+// generic relation-lists, the collection is `*`. Template fields are given by
+// their template name like `user/group_$_ids`.
+//
+// When an object changes, a connection also sends the relation-lists, that
+// point to the collection of the object. So when an object becomes invisible
+// for a user, the user gets the relation-lists without the id of the object in
+// the same message.
+func WithRelations(relations map[string]string) Option {
+	return func(a *Autoupdate) {
+		a.relations = relations
+	}
+}
+
+// pointsTo returns true, if the key is a relation-list to one of the given
+// collections.
+func (a *Autoupdate) pointsTo(key string, collections map[string]bool) bool {
+	if len(a.relations) == 0 || len(collections) == 0 {
+		return false
+	}
+
+	parts := strings.SplitN(key, "/", 3)
+	if len(parts) != 3 {
+		return false
+	}
+
+	to, ok := a.relations[parts[0]+"/"+models.TemplateName(parts[2])]
+	if !ok {
+		return false
+	}
+
+	return to == "*" || collections[to]
+}
+
+// changedCollections returns the collections of the given keys.
+func changedCollections(keys map[string]bool) map[string]bool {
+	collections := make(map[string]bool)
+	for key := range keys {
+		if i := strings.IndexByte(key, '/'); i > 0 {
+			collections[key[:i]] = true
+		}
+	}
+	return collections
+}
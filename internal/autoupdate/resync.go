@@ -0,0 +1,81 @@
+package autoupdate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"github.com/ostcar/topic"
+)
+
+// WithResync handles slow clients with a full update.
+//
+// When a client needs more time then the prune time to read a message, its topic id
+// gets pruned. Without this option, the connection returns an error with the
+// method `SlowClient()`. With this option, the connection sends a full update
+// and continues.
+func WithResync() Option {
+	return func(a *Autoupdate) {
+		a.resync = true
+	}
+}
+
+// Metrics returns the counters of the autoupdate service.
+//
+// slow_client_resyncs is the number of full updates for clients, that where
+// too slow to read the changes.
+func (a *Autoupdate) Metrics() map[string]uint64 {
+	return map[string]uint64{
+		"slow_client_resyncs": atomic.LoadUint64(&a.slowClientResyncs),
+	}
+}
+
+// receive returns the next changed keys for the connection.
+//
+// If the topic id of the connection was pruned and the autoupdate service was
+// created with WithResync, it returns the keys for a full update.
+func (c *Connection) receive(ctx context.Context) (keys []string, full bool, err error) {
+	tid, keys, err := c.autoupdate.topic.Receive(ctx, c.tid)
+	if err != nil {
+		var errUnknown topic.UnknownIDError
+		if !errors.As(err, &errUnknown) {
+			return nil, false, fmt.Errorf("get updated keys: %w", err)
+		}
+
+		if !c.autoupdate.resync {
+			return nil, false, slowClientError{err: err}
+		}
+
+		atomic.AddUint64(&c.autoupdate.slowClientResyncs, 1)
+		log.Printf("Connection of user %d is too slow. Sending a full update: %v", c.uid, err)
+
+		c.tid = c.autoupdate.topic.LastID()
+		return nil, true, nil
+	}
+
+	c.tid = tid
+	return keys, false, nil
+}
+
+// slowClientError is returned, if a connection was too slow to read the
+// changes.
+type slowClientError struct {
+	err error
+}
+
+func (e slowClientError) Error() string {
+	return fmt.Sprintf("The connection was too slow to read the changes: %v", e.err)
+}
+
+func (e slowClientError) Unwrap() error {
+	return e.err
+}
+
+func (e slowClientError) Type() string {
+	return "slow_client"
+}
+
+// SlowClient marks the error as error of a slow client.
+func (e slowClientError) SlowClient() {}
@@ -0,0 +1,67 @@
+package autoupdate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+)
+
+func TestResync(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	for _, tt := range []struct {
+		name    string
+		options []Option
+		expect  uint64
+	}{
+		{"without resync", nil, 0},
+		{"with resync", []Option{WithResync()}, 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			datastore := dsmock.NewMockDatastore(closed, map[string]string{
+				"user/1/name": `"Hello World"`,
+			})
+			a := New(datastore, test.RestrictAllowed(), test.UserUpdater{}, closed, tt.options...)
+			a.topic.Publish("some/1/key")
+			c := a.Connect(1, test.KeysBuilder{K: test.Str("user/1/name")})
+
+			if _, err := c.Next(context.Background()); err != nil {
+				t.Fatalf("Next returned unexpected error: %v", err)
+			}
+
+			// Simulate a slow client by pruning its topic id.
+			datastore.Send(map[string]string{"user/1/name": `"new name"`})
+			datastore.Send(map[string]string{"user/1/name": `"newer name"`})
+			// The third update makes sure, that the second one is processed.
+			datastore.Send(map[string]string{"other/1/key": `"value"`})
+			a.topic.Prune(time.Now())
+
+			data, err := c.Next(context.Background())
+
+			if tt.expect == 0 {
+				var errSlow interface{ SlowClient() }
+				if !errors.As(err, &errSlow) {
+					t.Errorf("Next returned %v, expected an error of a slow client", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Next returned unexpected error: %v", err)
+			}
+
+			if got := string(data["user/1/name"]); got != `"newer name"` {
+				t.Errorf("Got user/1/name %s, expected \"newer name\"", got)
+			}
+
+			if got := a.Metrics()["slow_client_resyncs"]; got != tt.expect {
+				t.Errorf("Got %d resyncs, expected %d", got, tt.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package autoupdate
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxLoggedInvalidKeys is the number of invalid keys from the datastore, that
+// are remembered to log each of them only once. Further invalid keys are not
+// logged.
+const maxLoggedInvalidKeys = 1024
+
+// invalidKeyLogInterval is the minimum time between two log messages about
+// invalid keys, that were requested by clients.
+const invalidKeyLogInterval = time.Minute
+
+// validKey is the syntax of a key. It is the same as in the datastore.
+var validKey = regexp.MustCompile(`^([a-z]+|[a-z][a-z_]*[a-z])/[1-9][0-9]*/[a-z][a-z0-9_]*\$?[a-z0-9_]*$`)
+
+// WithKnownCollections validates the keys, that are published by the
+// datastore and the keys, that are requested by a keysbuilder. A key has to
+// have the form collection/id/field and the collection has to be one of the
+// given collections.
+//
+// Invalid keys are dropped. Each invalid key from the datastore is logged once,
+// so a typo in the data of the backend can be found. Invalid keys, that were
+// requested by clients, are only counted and logged once per minute.
+//
+// Without this option, the keys are not validated.
+func WithKnownCollections(collections ...string) Option {
+	return func(a *Autoupdate) {
+		a.collections = make(map[string]bool, len(collections))
+		for _, collection := range collections {
+			a.collections[collection] = true
+		}
+	}
+}
+
+// checkKey returns an error, if the key is invalid.
+func (a *Autoupdate) checkKey(key string) error {
+	if !validKey.MatchString(key) {
+		return fmt.Errorf("invalid syntax, expected collection/id/field")
+	}
+
+	collection := key[:strings.IndexByte(key, '/')]
+	if !a.collections[collection] {
+		return fmt.Errorf("unknown collection %s", collection)
+	}
+	return nil
+}
+
+// dropInvalidKeys returns the valid keys. fromClient tells, if the keys were
+// requested by a client or come from the datastore.
+//
+// The returned slice is the given slice, if all keys are valid.
+func (a *Autoupdate) dropInvalidKeys(keys []string, fromClient bool) []string {
+	if a.collections == nil {
+		return keys
+	}
+
+	var valid []string
+	for i, key := range keys {
+		err := a.checkKey(key)
+		if err == nil {
+			if valid != nil {
+				valid = append(valid, key)
+			}
+			continue
+		}
+
+		if valid == nil {
+			valid = make([]string, i, len(keys))
+			copy(valid, keys[:i])
+		}
+
+		if fromClient {
+			a.logClientInvalidKey(key, err)
+			continue
+		}
+		a.logInvalidKey(key, err)
+	}
+
+	if valid == nil {
+		return keys
+	}
+	return valid
+}
+
+// logInvalidKey logs an invalid key from the datastore, if it was not logged
+// before.
+func (a *Autoupdate) logInvalidKey(key string, err error) {
+	a.invalidMu.Lock()
+	defer a.invalidMu.Unlock()
+
+	if a.invalidKeys[key] || len(a.invalidKeys) >= maxLoggedInvalidKeys {
+		return
+	}
+
+	if a.invalidKeys == nil {
+		a.invalidKeys = make(map[string]bool)
+	}
+	a.invalidKeys[key] = true
+	log.Printf("Dropping invalid key %q from datastore: %v", key, err)
+}
+
+// logClientInvalidKey counts an invalid key, that was requested by a client.
+// The key is logged, if there was no log message in the last
+// invalidKeyLogInterval.
+func (a *Autoupdate) logClientInvalidKey(key string, err error) {
+	a.invalidMu.Lock()
+	defer a.invalidMu.Unlock()
+
+	a.clientInvalidCount++
+	if time.Since(a.clientInvalidLogged) < invalidKeyLogInterval {
+		return
+	}
+
+	log.Printf("Dropping invalid key %q from keysbuilder: %v (%d invalid keys from clients since the last message)", key, err, a.clientInvalidCount)
+	a.clientInvalidLogged = time.Now()
+	a.clientInvalidCount = 0
+}
@@ -0,0 +1,35 @@
+package autoupdate
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"testing"
+)
+
+func TestDropInvalidKeysBounded(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	a := &Autoupdate{collections: map[string]bool{"motion": true}}
+
+	for i := 0; i < 2*maxLoggedInvalidKeys; i++ {
+		key := fmt.Sprintf("moton/%d/title", i+1)
+		if got := a.dropInvalidKeys([]string{key, "motion/1/title"}, true); len(got) != 1 {
+			t.Fatalf("dropInvalidKeys returned %v, expected only the valid key", got)
+		}
+	}
+
+	if len(a.invalidKeys) != 0 {
+		t.Errorf("Invalid keys from clients are remembered")
+	}
+
+	for i := 0; i < 2*maxLoggedInvalidKeys; i++ {
+		a.dropInvalidKeys([]string{fmt.Sprintf("moton/%d/title", i+1)}, false)
+	}
+
+	if len(a.invalidKeys) != maxLoggedInvalidKeys {
+		t.Errorf("Got %d remembered invalid keys, expected %d", len(a.invalidKeys), maxLoggedInvalidKeys)
+	}
+}
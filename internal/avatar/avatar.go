@@ -0,0 +1,93 @@
+// Package avatar creates the calculated field `user/avatar_url`.
+//
+// The field contains the url of the mediafile that is used as profile picture
+// of the user. The client does not have to request the mediafile object to
+// render the picture.
+//
+// The value is the same for every user. The access check is done in the
+// restricter with the Checker from this package.
+package avatar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
+)
+
+const (
+	// Field is the name of the calculated field.
+	Field = "user/avatar_url"
+
+	// sourceField is the field that points to the mediafile.
+	sourceField = "avatar_mediafile_id"
+
+	// urlFormat is the url of a mediafile from the media service.
+	urlFormat = "/system/media/get/%d"
+)
+
+// Datastore gets values for keys and can register calculated fields.
+type Datastore interface {
+	datastore.CalculatedRegisterer
+}
+
+// Register adds the calculated field `user/avatar_url` to the datastore.
+func Register(ds Datastore) {
+	datastore.RegisterCalculated(ds, Field, func(ctx context.Context, fqfield string) ([]byte, []string, error) {
+		parts := strings.SplitN(fqfield, "/", 3)
+		if len(parts) != 3 {
+			return nil, nil, fmt.Errorf("invalid key %s, expected two '/'", fqfield)
+		}
+		sourceKey := parts[0] + "/" + parts[1] + "/" + sourceField
+		deps := []string{sourceKey}
+
+		fetch := datastore.NewFetcher(ds)
+		var mediafileID int
+		fetch.Value(ctx, &mediafileID, sourceKey)
+		if err := fetch.Error(); err != nil {
+			var errNotExist datastore.DoesNotExistError
+			if errors.As(err, &errNotExist) {
+				return nil, deps, nil
+			}
+			return nil, nil, fmt.Errorf("fetching avatar of %s: %w", fqfield, err)
+		}
+
+		if mediafileID == 0 {
+			return nil, deps, nil
+		}
+
+		value, err := json.Marshal(fmt.Sprintf(urlFormat, mediafileID))
+		return value, deps, err
+	})
+}
+
+// Checker returns a restrict.Checker for the calculated field. It removes the
+// url, if the user can not see the mediafile.
+func Checker(permer restrict.Permissioner) restrict.Checker {
+	return restrict.CheckerFunc(func(ctx context.Context, uid int, key string, value json.RawMessage) (json.RawMessage, error) {
+		var url string
+		if err := json.Unmarshal(value, &url); err != nil {
+			return nil, fmt.Errorf("decoding %s=%s: %w", key, value, err)
+		}
+
+		var mediafileID int
+		if _, err := fmt.Sscanf(url, urlFormat, &mediafileID); err != nil {
+			return nil, fmt.Errorf("invalid avatar url %s: %w", url, err)
+		}
+
+		mediafileKey := fmt.Sprintf("mediafile/%d/id", mediafileID)
+		allowed, err := permer.RestrictFQFields(ctx, uid, []string{mediafileKey})
+		if err != nil {
+			return nil, fmt.Errorf("check mediafile permission: %w", err)
+		}
+
+		if !allowed[mediafileKey] {
+			return nil, nil
+		}
+		return value, nil
+	})
+}
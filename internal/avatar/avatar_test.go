@@ -0,0 +1,73 @@
+package avatar_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/avatar"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAvatarURL(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, map[string]string{
+		"user/1/avatar_mediafile_id": `5`,
+	})
+	avatar.Register(ds)
+
+	fields, err := ds.Get(context.Background(), "user/1/avatar_url", "user/2/avatar_url")
+	require.NoError(t, err)
+	assert.Equal(t, `"/system/media/get/5"`, string(fields[0]))
+	assert.Nil(t, fields[1])
+}
+
+func TestAvatarURLUpdate(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, map[string]string{
+		"user/1/avatar_mediafile_id": `5`,
+	})
+	avatar.Register(ds)
+
+	// Fetch data once to fill the cache.
+	_, err := ds.Get(context.Background(), "user/1/avatar_url")
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	ds.RegisterChangeListener(func(map[string]json.RawMessage) error {
+		close(done)
+		return nil
+	})
+
+	ds.Send(map[string]string{"user/1/avatar_mediafile_id": `7`})
+	<-done
+
+	fields, err := ds.Get(context.Background(), "user/1/avatar_url")
+	require.NoError(t, err)
+	assert.Equal(t, `"/system/media/get/7"`, string(fields[0]))
+}
+
+func TestChecker(t *testing.T) {
+	permer := &test.MockPermission{
+		Data: map[string]bool{
+			"mediafile/5/id": true,
+			"mediafile/6/id": false,
+		},
+	}
+	checker := avatar.Checker(permer)
+
+	got, err := checker.Check(context.Background(), 1, "user/1/avatar_url", []byte(`"/system/media/get/5"`))
+	require.NoError(t, err)
+	assert.Equal(t, `"/system/media/get/5"`, string(got))
+
+	got, err = checker.Check(context.Background(), 1, "user/1/avatar_url", []byte(`"/system/media/get/6"`))
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
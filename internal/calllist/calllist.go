@@ -0,0 +1,240 @@
+// Package calllist creates the calculated field `meeting/motion_call_list`.
+//
+// The field contains the ids of all motions of a meeting in the order of the
+// call list. A client can show the order without requesting the sort fields
+// of every motion and category.
+//
+// The motions are sorted by their category first. The categories are sorted
+// as a tree by motion_category/parent_id and motion_category/weight. Each
+// category contains its motions sorted by motion/category_weight. The motions
+// without a category follow at the end, sorted by motion/sort_weight.
+package calllist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
+)
+
+// Field is the name of the calculated field.
+const Field = "meeting/motion_call_list"
+
+// Register adds the calculated field to the datastore.
+func Register(ds datastore.CalculatedRegisterer) {
+	datastore.RegisterCalculated(ds, Field, func(ctx context.Context, key string) ([]byte, []string, error) {
+		parts := strings.SplitN(key, "/", 3)
+		if len(parts) != 3 {
+			return nil, nil, fmt.Errorf("invalid key %s, expected two '/'", key)
+		}
+		fqid := parts[0] + "/" + parts[1]
+
+		ids, deps, err := callList(ctx, ds, fqid)
+		if err != nil {
+			return nil, nil, fmt.Errorf("calculating call list of %s: %w", fqid, err)
+		}
+
+		if ids == nil {
+			return nil, deps, nil
+		}
+
+		bs, err := json.Marshal(ids)
+		if err != nil {
+			return nil, nil, fmt.Errorf("encoding call list: %w", err)
+		}
+		return bs, deps, nil
+	})
+}
+
+// sortable is a motion or a category with the fields used for sorting.
+type sortable struct {
+	id       int
+	parentID int
+	weight   int
+}
+
+// callList returns the sorted motion ids of the meeting and the keys, that
+// were used. It returns nil, if the meeting does not exist.
+func callList(ctx context.Context, ds datastore.Getter, meetingFQID string) ([]int, []string, error) {
+	deps := []string{meetingFQID + "/id", meetingFQID + "/motion_ids", meetingFQID + "/motion_category_ids"}
+	values, err := ds.Get(ctx, deps...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching meeting: %w", err)
+	}
+
+	if values[0] == nil {
+		return nil, deps, nil
+	}
+
+	var motionIDs, categoryIDs []int
+	if err := decode(values[1], &motionIDs); err != nil {
+		return nil, nil, fmt.Errorf("decoding %s: %w", deps[1], err)
+	}
+	if err := decode(values[2], &categoryIDs); err != nil {
+		return nil, nil, fmt.Errorf("decoding %s: %w", deps[2], err)
+	}
+
+	categories, categoryKeys, err := fetchSortable(ctx, ds, "motion_category", categoryIDs, "parent_id", "weight")
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching categories: %w", err)
+	}
+	deps = append(deps, categoryKeys...)
+
+	motions, motionKeys, err := fetchSortable(ctx, ds, "motion", motionIDs, "category_id", "category_weight")
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching motions: %w", err)
+	}
+	deps = append(deps, motionKeys...)
+
+	var noCategory []int
+	byCategory := make(map[int][]sortable)
+	for _, m := range motions {
+		if m.parentID == 0 {
+			noCategory = append(noCategory, m.id)
+			continue
+		}
+		byCategory[m.parentID] = append(byCategory[m.parentID], m)
+	}
+
+	ids := make([]int, 0, len(motionIDs))
+	for _, category := range sortTree(categories) {
+		inCategory := byCategory[category]
+		sortByWeight(inCategory)
+		for _, m := range inCategory {
+			ids = append(ids, m.id)
+		}
+		delete(byCategory, category)
+	}
+
+	// Motions with a category, that is not part of the meeting, are handled
+	// like motions without a category.
+	for _, inCategory := range byCategory {
+		for _, m := range inCategory {
+			noCategory = append(noCategory, m.id)
+		}
+	}
+
+	if len(noCategory) > 0 {
+		uncategorized, sortKeys, err := fetchSortable(ctx, ds, "motion", noCategory, "", "sort_weight")
+		if err != nil {
+			return nil, nil, fmt.Errorf("fetching motions without category: %w", err)
+		}
+		deps = append(deps, sortKeys...)
+
+		sortByWeight(uncategorized)
+		for _, m := range uncategorized {
+			ids = append(ids, m.id)
+		}
+	}
+
+	return ids, deps, nil
+}
+
+// fetchSortable fetches the parent field and the weight field of the objects
+// with one request. If parentField is empty, only the weight is fetched.
+func fetchSortable(ctx context.Context, ds datastore.Getter, collection string, ids []int, parentField, weightField string) ([]sortable, []string, error) {
+	fields := []string{weightField}
+	if parentField != "" {
+		fields = append(fields, parentField)
+	}
+
+	keys := make([]string, 0, len(ids)*len(fields))
+	for _, id := range ids {
+		for _, field := range fields {
+			keys = append(keys, fmt.Sprintf("%s/%d/%s", collection, id, field))
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, nil, nil
+	}
+
+	values, err := ds.Get(ctx, keys...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching %s: %w", collection, err)
+	}
+
+	objects := make([]sortable, len(ids))
+	for i, id := range ids {
+		objects[i].id = id
+		idx := i * len(fields)
+
+		if err := decode(values[idx], &objects[i].weight); err != nil {
+			return nil, nil, fmt.Errorf("decoding %s: %w", keys[idx], err)
+		}
+
+		if parentField != "" {
+			if err := decode(values[idx+1], &objects[i].parentID); err != nil {
+				return nil, nil, fmt.Errorf("decoding %s: %w", keys[idx+1], err)
+			}
+		}
+	}
+	return objects, keys, nil
+}
+
+// sortTree returns the ids of the objects in the order of a depth-first walk
+// through the tree. Children are sorted by their weight. Objects with an
+// unknown parent are handled as roots.
+func sortTree(objects []sortable) []int {
+	known := make(map[int]bool, len(objects))
+	for _, o := range objects {
+		known[o.id] = true
+	}
+
+	children := make(map[int][]sortable)
+	for _, o := range objects {
+		parent := o.parentID
+		if !known[parent] {
+			parent = 0
+		}
+		children[parent] = append(children[parent], o)
+	}
+
+	ids := make([]int, 0, len(objects))
+	seen := make(map[int]bool, len(objects))
+	var walk func(parent int)
+	walk = func(parent int) {
+		nodes := children[parent]
+		sortByWeight(nodes)
+		for _, node := range nodes {
+			if seen[node.id] {
+				// Invalid data with a cycle.
+				continue
+			}
+			seen[node.id] = true
+			ids = append(ids, node.id)
+			walk(node.id)
+		}
+	}
+	walk(0)
+	return ids
+}
+
+// sortByWeight sorts the objects by weight and then by id.
+func sortByWeight(objects []sortable) {
+	sort.Slice(objects, func(i, j int) bool {
+		if objects[i].weight != objects[j].weight {
+			return objects[i].weight < objects[j].weight
+		}
+		return objects[i].id < objects[j].id
+	})
+}
+
+// decode decodes a value from the datastore. A value, that does not exist, is
+// left unchanged.
+func decode(value json.RawMessage, v interface{}) error {
+	if value == nil {
+		return nil
+	}
+	return json.Unmarshal(value, v)
+}
+
+// Checker returns a restrict.Checker for the calculated field. It removes the
+// motions, that the user can not see.
+func Checker(permer restrict.Permissioner) restrict.Checker {
+	return restrict.RelationChecker(map[string]string{Field: "motion"}, permer)[Field]
+}
@@ -0,0 +1,108 @@
+package calllist_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/calllist"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const data = `
+meeting/1:
+	id: 1
+	motion_ids: [1, 2, 3, 4, 5, 6]
+	motion_category_ids: [1, 2, 3]
+meeting/2/id: 2
+motion_category:
+	1:
+		weight: 2
+	2:
+		weight: 1
+	3:
+		parent_id: 2
+		weight: 1
+motion:
+	1:
+		category_id: 1
+		category_weight: 1
+	2:
+		category_id: 3
+		category_weight: 1
+	3:
+		category_id: 2
+		category_weight: 5
+	4:
+		sort_weight: 2
+	5:
+		sort_weight: 1
+	6:
+		category_id: 2
+		category_weight: 1
+`
+
+func TestCallList(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(data))
+	calllist.Register(ds)
+
+	for _, tt := range []struct {
+		key    string
+		expect string
+	}{
+		{"meeting/1/motion_call_list", "[6,3,2,1,5,4]"},
+		{"meeting/2/motion_call_list", "[]"},
+		{"meeting/3/motion_call_list", ""},
+	} {
+		t.Run(tt.key, func(t *testing.T) {
+			values, err := ds.Get(context.Background(), tt.key)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expect, string(values[0]))
+		})
+	}
+}
+
+func TestCallListUpdate(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(data))
+	calllist.Register(ds)
+
+	// Fetch data once to fill the cache.
+	_, err := ds.Get(context.Background(), "meeting/1/motion_call_list")
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	ds.RegisterChangeListener(func(map[string]json.RawMessage) error {
+		close(done)
+		return nil
+	})
+
+	ds.Send(map[string]string{"motion_category/1/weight": "0"})
+	<-done
+
+	values, err := ds.Get(context.Background(), "meeting/1/motion_call_list")
+	require.NoError(t, err)
+	assert.Equal(t, "[1,6,3,2,5,4]", string(values[0]))
+}
+
+func TestChecker(t *testing.T) {
+	permer := &test.MockPermission{
+		Data: map[string]bool{
+			"motion/1/id": true,
+			"motion/3/id": true,
+		},
+	}
+	checker := calllist.Checker(permer)
+
+	value, err := checker.Check(context.Background(), 1, "meeting/1/motion_call_list", []byte("[3,2,1]"))
+	require.NoError(t, err)
+	assert.Equal(t, "[3,1]", string(value))
+}
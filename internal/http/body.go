@@ -0,0 +1,144 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// BodyLimit limits, how a request body is read.
+type BodyLimit struct {
+	// MaxSize is the maximum size of the body in bytes. 0 means no limit.
+	MaxSize int64
+
+	// Timeout is the maximum time to read the body. 0 means no limit.
+	Timeout time.Duration
+}
+
+// limitBody reads the body of the request before next is called. If the body
+// is bigger than limit.MaxSize, the status code is 413. If the body is not
+// read in limit.Timeout, the status code is 408.
+//
+// On a timeout, a http/1 connection is closed, so a client that sends the
+// body slowly does not hold the socket.
+func limitBody(next http.Handler, limit BodyLimit) http.Handler {
+	if limit.MaxSize <= 0 && limit.Timeout <= 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := readBody(r, limit)
+		if err != nil {
+			var errTimeout bodyTimeoutError
+			if errors.As(err, &errTimeout) {
+				closeWithTimeout(w, errTimeout)
+				return
+			}
+			handleError(w, err, true)
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// readBody reads the body with the limits.
+func readBody(r *http.Request, limit BodyLimit) ([]byte, error) {
+	var body io.Reader = r.Body
+	if limit.MaxSize > 0 {
+		body = io.LimitReader(r.Body, limit.MaxSize+1)
+	}
+
+	type result struct {
+		data []byte
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		data, err := io.ReadAll(body)
+		done <- result{data, err}
+	}()
+
+	var timeout <-chan time.Time
+	if limit.Timeout > 0 {
+		timer := time.NewTimer(limit.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case res := <-done:
+		if res.err != nil {
+			return nil, fmt.Errorf("reading body: %w", res.err)
+		}
+
+		if limit.MaxSize > 0 && int64(len(res.data)) > limit.MaxSize {
+			return nil, bodyTooLargeError{maxSize: limit.MaxSize}
+		}
+		return res.data, nil
+
+	case <-timeout:
+		return nil, bodyTimeoutError{timeout: limit.Timeout}
+
+	case <-r.Context().Done():
+		return nil, r.Context().Err()
+	}
+}
+
+// closeWithTimeout writes the timeout error to the client.
+//
+// The goroutine in readBody still reads from the body. For http/1, the
+// connection is hijacked and closed, which stops the read. For http/2, the
+// stream is reset, when the handler returns.
+func closeWithTimeout(w http.ResponseWriter, err bodyTimeoutError) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		handleError(w, err, true)
+		return
+	}
+
+	conn, buf, hjErr := hj.Hijack()
+	if hjErr != nil {
+		handleError(w, err, true)
+		return
+	}
+	defer conn.Close()
+
+	var body bytes.Buffer
+	rec := bodyRecorder{header: make(http.Header), body: &body}
+	handleError(&rec, err, true)
+
+	fmt.Fprintf(buf, "HTTP/1.1 %d %s\r\n", rec.status, http.StatusText(rec.status))
+	fmt.Fprintf(buf, "Content-Type: %s\r\n", rec.header.Get("Content-Type"))
+	fmt.Fprintf(buf, "Content-Length: %d\r\n", body.Len())
+	fmt.Fprint(buf, "Connection: close\r\n\r\n")
+	body.WriteTo(buf)
+	buf.Flush()
+}
+
+// bodyRecorder is a http.ResponseWriter that writes the response into a
+// buffer.
+type bodyRecorder struct {
+	header http.Header
+	status int
+	body   io.Writer
+}
+
+func (r *bodyRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *bodyRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(p)
+}
+
+func (r *bodyRecorder) WriteHeader(status int) {
+	r.status = status
+}
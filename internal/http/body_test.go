@@ -0,0 +1,102 @@
+package http_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ahttp "github.com/OpenSlides/openslides-autoupdate-service/internal/http"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBodyLimitSize(t *testing.T) {
+	mux := http.NewServeMux()
+	limit := ahttp.BodyLimit{MaxSize: 100}
+	ahttp.Complex(mux, test.Auth(1), new(test.DataProvider), &liverMock{content: strings.NewReader("")}, limit)
+
+	t.Run("small body", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/system/autoupdate", strings.NewReader(`[{"ids":[1],"collection":"user","fields":{"name":null}}]`))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != 200 {
+			t.Errorf("Got status %d, expected 200: %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("big body", func(t *testing.T) {
+		body := `[{"ids":[1],"collection":"user","fields":{"name":null}}` + strings.Repeat(" ", 100) + "]"
+		req := httptest.NewRequest("POST", "/system/autoupdate", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Got status %d, expected 413", rec.Code)
+		}
+
+		assert.JSONEq(t, `{"error": {"type": "invalid_request", "msg": "Request body is bigger than 100 bytes", "code": "body_too_large"}}`, rec.Body.String())
+	})
+}
+
+// idLister is a query.IDLister without ids.
+type idLister struct{}
+
+func (idLister) CachedIDs(collection string) []int {
+	return nil
+}
+
+func TestBodyLimitQuery(t *testing.T) {
+	mux := http.NewServeMux()
+	ahttp.Query(mux, test.Auth(1), idLister{}, new(test.DataProvider), ahttp.BodyLimit{MaxSize: 100})
+
+	body := `{"collection":"motion"}` + strings.Repeat(" ", 100)
+	req := httptest.NewRequest("POST", "/internal/autoupdate/query", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Got status %d, expected 413: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestBodyLimitTimeout(t *testing.T) {
+	mux := http.NewServeMux()
+	limit := ahttp.BodyLimit{Timeout: 10 * time.Millisecond}
+	ahttp.Complex(mux, test.Auth(1), new(test.DataProvider), &liverMock{content: strings.NewReader("")}, limit)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+
+	// Send the header and only a part of the body.
+	fmt.Fprint(conn, "POST /system/autoupdate HTTP/1.1\r\nHost: localhost\r\nContent-Length: 100\r\n\r\n[{")
+
+	conn.SetDeadline(time.Now().Add(time.Second))
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("Reading response: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusRequestTimeout {
+		t.Errorf("Got status %d, expected 408", resp.StatusCode)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	assert.JSONEq(t, `{"error": {"type": "invalid_request", "msg": "Request body was not sent in 10ms", "code": "body_timeout"}}`, string(body))
+
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Connection was not closed, read returned %v", err)
+	}
+}
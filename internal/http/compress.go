@@ -0,0 +1,71 @@
+package http
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+// compressionLevel is the gzip level for the stream. With lower levels, the
+// compressor of the standard library does not use data from before the last
+// flush to find matches.
+const compressionLevel = 7
+
+// gzipResponseWriter compresses the body of a response with gzip.
+//
+// The compressor is kept for the whole connection. When the stream is flushed
+// after each message, the sliding window of the compressor is not reset. So
+// later messages can reference field names and values from earlier messages.
+// This compresses a stream of similar messages much better then compressing
+// each message on its own.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz *gzip.Writer
+}
+
+// Write compresses the given data.
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	return w.gz.Write(p)
+}
+
+// Flush sends all pending compressed data to the client.
+func (w *gzipResponseWriter) Flush() {
+	w.gz.Flush()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// compress returns a response writer that compresses the body, if the client
+// supports it. If not, the given response writer is returned.
+//
+// The returned function has to be called, when the response is finished.
+func compress(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, func()) {
+	if !acceptsGzip(r) {
+		return w, func() {}
+	}
+
+	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	// The error can be ignored, because the level is valid.
+	gz, _ := gzip.NewWriterLevel(w, compressionLevel)
+
+	gw := &gzipResponseWriter{
+		ResponseWriter: w,
+		gz:             gz,
+	}
+	return gw, func() { gw.gz.Close() }
+}
+
+// acceptsGzip returns true, if the client sends gzip in the Accept-Encoding
+// header.
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		encoding = strings.TrimSpace(strings.SplitN(encoding, ";", 2)[0])
+		if encoding == "gzip" {
+			return true
+		}
+	}
+	return false
+}
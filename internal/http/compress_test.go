@@ -0,0 +1,85 @@
+package http_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/autoupdate"
+	ahttp "github.com/OpenSlides/openslides-autoupdate-service/internal/http"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+)
+
+// messageLiver writes each message and flushes afterwards.
+type messageLiver struct {
+	messages []string
+}
+
+func (m *messageLiver) Live(ctx context.Context, uid int, w io.Writer, kb autoupdate.KeysBuilder, options ...autoupdate.ConnectionOption) error {
+	for _, msg := range m.messages {
+		io.WriteString(w, msg)
+		w.(http.Flusher).Flush()
+	}
+	return nil
+}
+
+func TestCompression(t *testing.T) {
+	msg := `{"motion/1/title":"A long title that is repeated in every message"}` + "\n"
+	liver := &messageLiver{messages: []string{msg, msg, msg}}
+
+	mux := http.NewServeMux()
+	ahttp.Simple(mux, test.Auth(1), liver)
+
+	req := httptest.NewRequest("GET", "/system/autoupdate/keys?motion/1/title", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Got Content-Encoding `%s`, expected gzip", got)
+	}
+
+	compressedSize := rec.Body.Len()
+
+	r, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("Creating gzip reader: %v", err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Reading compressed body: %v", err)
+	}
+
+	expect := msg + msg + msg
+	if string(got) != expect {
+		t.Errorf("Got `%s`, expected `%s`", got, expect)
+	}
+
+	// The messages two and three should only be references to the first one.
+	if compressedSize >= len(msg)*2 {
+		t.Errorf("Compressed size is %d bytes, expected less then %d", compressedSize, len(msg)*2)
+	}
+}
+
+func TestNoCompression(t *testing.T) {
+	liver := &messageLiver{messages: []string{"content"}}
+
+	mux := http.NewServeMux()
+	ahttp.Simple(mux, test.Auth(1), liver)
+
+	req := httptest.NewRequest("GET", "/system/autoupdate/keys?motion/1/title", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Got Content-Encoding `%s`, expected none", got)
+	}
+
+	if got := rec.Body.String(); !bytes.Equal([]byte(got), []byte("content")) {
+		t.Errorf("Got `%s`, expected `content`", got)
+	}
+}
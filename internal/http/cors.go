@@ -0,0 +1,68 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CORS adds the CORS headers to the responses of the public endpoints under
+// /system/, so browsers can call the service from other origins.
+//
+// The origins are the allowed values of the Origin header, for example
+// `https://localhost:8000`. The origin `*` allows all origins. With
+// credentials, browsers send cookies and the auth header with the request.
+// Credentials are only allowed for the origins, that are listed explicitly.
+// Other origins, that are allowed by `*`, get the wildcard without
+// credentials. Otherwise any website could make requests in the name of the
+// user.
+//
+// Preflight requests are answered directly. Requests from other origins are
+// served without CORS headers, so the browser blocks them.
+func CORS(next http.Handler, origins []string, credentials bool) http.Handler {
+	if len(origins) == 0 {
+		return next
+	}
+
+	allowed := make(map[string]bool, len(origins))
+	for _, o := range origins {
+		allowed[o] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !strings.HasPrefix(r.URL.Path, "/system/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		switch {
+		case allowed[origin]:
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			if credentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+
+		case allowed["*"]:
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+			if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+			}
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		// Let the browser read the new auth token of the auth service.
+		w.Header().Set("Access-Control-Expose-Headers", "Authentication")
+		next.ServeHTTP(w, r)
+	})
+}
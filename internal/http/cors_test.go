@@ -0,0 +1,63 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	ahttp "github.com/OpenSlides/openslides-autoupdate-service/internal/http"
+)
+
+func TestCORS(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	for _, tt := range []struct {
+		name        string
+		origins     []string
+		credentials bool
+		method      string
+		url         string
+		origin      string
+		status      int
+		allowOrigin string
+		allowCreds  string
+	}{
+		{"no origin", []string{"http://a"}, false, "GET", "/system/autoupdate", "", 200, "", ""},
+		{"allowed origin", []string{"http://a"}, false, "GET", "/system/autoupdate", "http://a", 200, "http://a", ""},
+		{"other origin", []string{"http://a"}, false, "GET", "/system/autoupdate", "http://b", 200, "", ""},
+		{"all origins", []string{"*"}, false, "GET", "/system/autoupdate", "http://b", 200, "*", ""},
+		{"credentials", []string{"http://a"}, true, "GET", "/system/autoupdate", "http://a", 200, "http://a", "true"},
+		{"all origins with credentials", []string{"*"}, true, "GET", "/system/autoupdate", "http://b", 200, "*", ""},
+		{"listed origin and all origins with credentials", []string{"*", "http://a"}, true, "GET", "/system/autoupdate", "http://a", 200, "http://a", "true"},
+		{"preflight", []string{"http://a"}, false, "OPTIONS", "/system/autoupdate", "http://a", 204, "http://a", ""},
+		{"internal", []string{"*"}, false, "GET", "/internal/autoupdate/metrics", "http://a", 200, "", ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := ahttp.CORS(next, tt.origins, tt.credentials)
+
+			req := httptest.NewRequest(tt.method, tt.url, nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.method == "OPTIONS" {
+				req.Header.Set("Access-Control-Request-Method", "POST")
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("Got status %d, expected %d", rec.Code, tt.status)
+			}
+
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.allowOrigin {
+				t.Errorf("Got Access-Control-Allow-Origin `%s`, expected `%s`", got, tt.allowOrigin)
+			}
+
+			if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != tt.allowCreds {
+				t.Errorf("Got Access-Control-Allow-Credentials `%s`, expected `%s`", got, tt.allowCreds)
+			}
+		})
+	}
+}
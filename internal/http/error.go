@@ -1,6 +1,10 @@
 package http
 
-import "fmt"
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
 
 type invalidRequestError struct {
 	err error
@@ -13,3 +17,97 @@ func (e invalidRequestError) Error() string {
 func (e invalidRequestError) Type() string {
 	return "invalid_request"
 }
+
+// internalAuthError is returned, if a request with the internal auth header is
+// not allowed.
+type internalAuthError struct {
+	msg    string
+	status int
+}
+
+func (e internalAuthError) Error() string {
+	return e.msg
+}
+
+func (e internalAuthError) Type() string {
+	return "auth"
+}
+
+func (e internalAuthError) StatusCode() int {
+	return e.status
+}
+
+// tooManyRequestsError is returned, if a client opens too many connections.
+type tooManyRequestsError struct{}
+
+func (e tooManyRequestsError) Error() string {
+	return "Too many new connections, try again later"
+}
+
+func (e tooManyRequestsError) Type() string {
+	return "rate_limit"
+}
+
+func (e tooManyRequestsError) StatusCode() int {
+	return http.StatusTooManyRequests
+}
+
+// bodyTooLargeError is returned, if the body of a request is too big.
+type bodyTooLargeError struct {
+	maxSize int64
+}
+
+func (e bodyTooLargeError) Error() string {
+	return fmt.Sprintf("Request body is bigger than %d bytes", e.maxSize)
+}
+
+func (e bodyTooLargeError) Type() string {
+	return "invalid_request"
+}
+
+func (e bodyTooLargeError) Code() string {
+	return "body_too_large"
+}
+
+func (e bodyTooLargeError) StatusCode() int {
+	return http.StatusRequestEntityTooLarge
+}
+
+// bodyTimeoutError is returned, if the body of a request is not read in time.
+type bodyTimeoutError struct {
+	timeout time.Duration
+}
+
+func (e bodyTimeoutError) Error() string {
+	return fmt.Sprintf("Request body was not sent in %s", e.timeout)
+}
+
+func (e bodyTimeoutError) Type() string {
+	return "invalid_request"
+}
+
+func (e bodyTimeoutError) Code() string {
+	return "body_timeout"
+}
+
+func (e bodyTimeoutError) StatusCode() int {
+	return http.StatusRequestTimeout
+}
+
+// notVisibleError is returned, if an object does not exist or the user can
+// not see it.
+type notVisibleError struct {
+	fqid string
+}
+
+func (e notVisibleError) Error() string {
+	return fmt.Sprintf("Object %s does not exist or is not visible", e.fqid)
+}
+
+func (e notVisibleError) Type() string {
+	return "not_found"
+}
+
+func (e notVisibleError) StatusCode() int {
+	return http.StatusNotFound
+}
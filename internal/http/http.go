@@ -2,44 +2,96 @@
 package http
 
 import (
+	"bytes"
 	"context"
+	"crypto/subtle"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	httppprof "net/http/pprof"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/OpenSlides/openslides-autoupdate-service/internal/keysbuilder"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/query"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/keysbuilder"
 )
 
-const prefix = "/system/autoupdate"
+const (
+	prefix         = "/system/autoupdate"
+	internalPrefix = "/internal/autoupdate"
+)
 
 // Complex builds the requested keys from the body of a request. The
 // body has to be in the format specified in the keysbuilder package.
-func Complex(mux *http.ServeMux, auth Authenticater, db keysbuilder.DataProvider, liver Liver) {
+//
+// The optional url argument min_interval (for example `?min_interval=5s`) sets
+// the minimum time between two messages. Changes in the meantime are sent
+// together.
+//
+// With the url argument `provenance=1`, organisation managers get the
+// provenance of the changes in each message.
+//
+// With the url argument `skip_first_empty=1`, the first message is not sent,
+// if it is empty. The connection waits until there is data for the user.
+//
+// With the url argument `deleted=1`, each message contains the ids of the
+// objects that were deleted.
+//
+// With the url argument `notify=1`, the messages contain the notifications
+// for the user. See Notify.
+//
+// With the url argument `position=1`, each message contains the change id and
+// the datastore position.
+//
+// The body is read with the given limit before the keys are built. The
+// options are used to create the keysbuilder, for example to set the limits
+// of a request.
+func Complex(mux *http.ServeMux, auth Authenticater, db keysbuilder.DataProvider, liver Liver, limit BodyLimit, kbOptions ...keysbuilder.Option) {
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/octet-stream")
 
 		defer r.Body.Close()
 		uid := auth.FromContext(r.Context())
 
-		kb, err := keysbuilder.ManyFromJSON(r.Body, db, uid)
+		options, err := connectionOptions(r)
+		if err != nil {
+			handleError(w, err, true)
+			return
+		}
+
+		kb, err := keysbuilder.ManyFromJSON(r.Body, db, uid, kbOptions...)
 		if err != nil {
 			handleError(w, err, true)
 			return
 		}
 
-		// TODO: This should not be run here. This is only for development
-		kb.Update(r.Context())
+		// Build the keys before the first message, so a request that exceeds
+		// a limit gets a status code.
+		if err := kb.Update(r.Context()); err != nil {
+			handleError(w, fmt.Errorf("building keys: %w", err), true)
+			return
+		}
+
+		cw, closeCompression := compress(w, r)
+		defer closeCompression()
 
 		// This blocks until the request is done.
-		if err := liver.Live(r.Context(), uid, w, kb); err != nil {
-			handleError(w, err, false)
+		if err := liver.Live(r.Context(), uid, cw, kb, options...); err != nil {
+			streamError(cw, err)
 			return
 		}
 	})
 
-	mux.Handle(prefix, validRequest(authMiddleware(handler, auth)))
+	mux.Handle(prefix, validRequest(authMiddleware(limitBody(handler, limit), auth)))
 }
 
 // Simple builds a keysbuilder from the url query. It expects a comma
@@ -58,9 +110,298 @@ func Simple(mux *http.ServeMux, auth Authenticater, liver Liver) {
 
 		uid := auth.FromContext(r.Context())
 
+		cw, closeCompression := compress(w, r)
+		defer closeCompression()
+
 		// This blocks until the request is done.
-		if err := liver.Live(r.Context(), uid, w, kb); err != nil {
-			handleError(w, err, false)
+		if err := liver.Live(r.Context(), uid, cw, kb); err != nil {
+			streamError(cw, err)
+			return
+		}
+	})
+
+	mux.Handle(url, validRequest(authMiddleware(handler, auth)))
+}
+
+// Notify sends a notification from the user of the request to other users.
+// The request has to be a POST request with a json object as body, for
+// example:
+//
+//	{"name": "applause", "message": {"level": 3}, "to_meeting": 1}
+//
+// The receivers are given with `to_users` (a list of user ids) and
+// `to_meeting` (a meeting id). The notification is sent to their connections,
+// that were opened with the url argument `notify=1`.
+func Notify(mux *http.ServeMux, auth Authenticater, notifier Notifier, limit BodyLimit) {
+	url := prefix + "/notify"
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			handleError(w, invalidRequestError{fmt.Errorf("Only POST requests are supported")}, true)
+			return
+		}
+
+		defer r.Body.Close()
+		uid := auth.FromContext(r.Context())
+
+		var notification autoupdate.Notification
+		if err := json.NewDecoder(r.Body).Decode(&notification); err != nil {
+			handleError(w, invalidRequestError{fmt.Errorf("decoding notification: %w", err)}, true)
+			return
+		}
+
+		if err := notifier.Notify(r.Context(), uid, notification); err != nil {
+			handleError(w, fmt.Errorf("sending notification: %w", err), true)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, `{"notify": true}`)
+	})
+
+	mux.Handle(url, validRequest(authMiddleware(limitBody(handler, limit), auth)))
+}
+
+// Query evaluates a query from the body of the request. The body has to be
+// in the format specified in the query package. The result is a json list of
+// the matching objects.
+//
+// Only the objects with the ids of the IDLister are queried. For the cache of
+// the datastore, these are only the objects, that are in the cache.
+func Query(mux *http.ServeMux, auth Authenticater, lister query.IDLister, db query.DataProvider, limit BodyLimit) {
+	url := internalPrefix + "/query"
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		defer r.Body.Close()
+		uid := auth.FromContext(r.Context())
+
+		q, err := query.FromJSON(r.Body)
+		if err != nil {
+			handleError(w, err, true)
+			return
+		}
+
+		objects, err := q.Run(r.Context(), lister, db, uid)
+		if err != nil {
+			handleError(w, fmt.Errorf("running query: %w", err), true)
+			return
+		}
+
+		if err := json.NewEncoder(w).Encode(objects); err != nil {
+			handleError(w, fmt.Errorf("encoding query result: %w", err), false)
+			return
+		}
+	})
+
+	mux.Handle(url, validRequest(authMiddleware(limitBody(handler, limit), auth)))
+}
+
+// Exists tells for a list of fqids, if the objects exist and if the user can
+// see them. It expects a comma separated list of fqids as url query.
+//
+// The result is a json object from each fqid to one of the values
+// `visible`, `forbidden` or `not_found`.
+func Exists(mux *http.ServeMux, auth Authenticater, visibilityer Visibilityer) {
+	url := prefix + "/exists"
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		fqids := strings.Split(r.URL.RawQuery, ",")
+		for _, fqid := range fqids {
+			parts := strings.Split(fqid, "/")
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				handleError(w, invalidRequestError{fmt.Errorf("invalid fqid %q", fqid)}, true)
+				return
+			}
+		}
+
+		uid := auth.FromContext(r.Context())
+
+		visibility, err := visibilityer.Visibility(r.Context(), uid, fqids...)
+		if err != nil {
+			handleError(w, fmt.Errorf("getting visibility: %w", err), true)
+			return
+		}
+
+		if err := json.NewEncoder(w).Encode(visibility); err != nil {
+			handleError(w, fmt.Errorf("encoding visibility: %w", err), false)
+			return
+		}
+	})
+
+	mux.Handle(url, validRequest(authMiddleware(handler, auth)))
+}
+
+// History returns the changes of one object. The fqid is given as url
+// argument, for example `?fqid=motion/5`.
+//
+// The result is a json list of objects with the fields `position`,
+// `timestamp` and `user_id` sorted by position. The user has to see the
+// object. If the user can not see the user, that made a change, its user_id is
+// 0.
+//
+// With the url arguments `position` and `fields`, for example
+// `?fqid=motion/5&position=3&fields=title,text`, the values of the fields at
+// this position are returned instead. They are restricted with the
+// permissions, that the user had at this position. historic can be nil. In
+// this case, a position is an invalid request.
+func History(mux *http.ServeMux, auth Authenticater, visibilityer Visibilityer, historian Historian, historic HistoricDataer) {
+	url := prefix + "/history"
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		fqid := r.URL.Query().Get("fqid")
+		parts := strings.Split(fqid, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			handleError(w, invalidRequestError{fmt.Errorf("invalid fqid %q", fqid)}, true)
+			return
+		}
+
+		uid := auth.FromContext(r.Context())
+
+		if v := r.URL.Query().Get("position"); v != "" {
+			historicObject(w, r, historic, uid, fqid, v)
+			return
+		}
+
+		visibility, err := visibilityer.Visibility(r.Context(), uid, fqid)
+		if err != nil {
+			handleError(w, fmt.Errorf("getting visibility: %w", err), true)
+			return
+		}
+
+		if visibility[fqid] != autoupdate.VisibilityVisible {
+			handleError(w, notVisibleError{fqid: fqid}, true)
+			return
+		}
+
+		history, err := historian.History(r.Context(), fqid)
+		if err != nil {
+			handleError(w, fmt.Errorf("getting history: %w", err), true)
+			return
+		}
+
+		entries := history[fqid]
+		if entries == nil {
+			entries = []datastore.HistoryEntry{}
+		}
+
+		var userFQIDs []string
+		seen := make(map[int]bool)
+		for _, entry := range entries {
+			if entry.UserID == 0 || seen[entry.UserID] {
+				continue
+			}
+			seen[entry.UserID] = true
+			userFQIDs = append(userFQIDs, fmt.Sprintf("user/%d", entry.UserID))
+		}
+
+		if len(userFQIDs) > 0 {
+			users, err := visibilityer.Visibility(r.Context(), uid, userFQIDs...)
+			if err != nil {
+				handleError(w, fmt.Errorf("getting visibility of users: %w", err), true)
+				return
+			}
+
+			for i, entry := range entries {
+				if users[fmt.Sprintf("user/%d", entry.UserID)] != autoupdate.VisibilityVisible {
+					entries[i].UserID = 0
+				}
+			}
+		}
+
+		if err := json.NewEncoder(w).Encode(entries); err != nil {
+			handleError(w, fmt.Errorf("encoding history: %w", err), false)
+			return
+		}
+	})
+
+	mux.Handle(url, validRequest(authMiddleware(handler, auth)))
+}
+
+// historicObject writes the values of the fields of an object at a position
+// of the datastore.
+func historicObject(w http.ResponseWriter, r *http.Request, historic HistoricDataer, uid int, fqid string, rawPosition string) {
+	if historic == nil {
+		handleError(w, invalidRequestError{errors.New("the data of a position is not available")}, true)
+		return
+	}
+
+	position, err := strconv.Atoi(rawPosition)
+	if err != nil || position < 1 {
+		handleError(w, invalidRequestError{fmt.Errorf("invalid position %q, expected a positive number", rawPosition)}, true)
+		return
+	}
+
+	idKey := fqid + "/id"
+	keys := []string{idKey}
+	for _, field := range strings.Split(r.URL.Query().Get("fields"), ",") {
+		if field == "" || field == "id" {
+			continue
+		}
+		keys = append(keys, fqid+"/"+field)
+	}
+
+	data, err := historic.RestrictedDataAt(r.Context(), uid, position, keys...)
+	if err != nil {
+		handleError(w, fmt.Errorf("getting data at position %d: %w", position, err), true)
+		return
+	}
+
+	if data[idKey] == nil {
+		handleError(w, notVisibleError{fqid: fqid}, true)
+		return
+	}
+
+	for key, value := range data {
+		if value == nil {
+			delete(data, key)
+		}
+	}
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		handleError(w, fmt.Errorf("encoding data: %w", err), false)
+		return
+	}
+}
+
+// Explain tells for a user and a list of keys, if the keys are visible for
+// the user and which rule decided it. The body has to be a json object like
+// `{"user_id": 5, "keys": ["motion/1/title"]}`.
+//
+// The request is authenticated. Only the user itself and superadmins can get
+// the explanations of a user. The handler must only be used on an internal
+// listen address anyway.
+func Explain(mux *http.ServeMux, auth Authenticater, explainer Explainer) {
+	url := internalPrefix + "/explain"
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		defer r.Body.Close()
+
+		var body struct {
+			UserID int      `json:"user_id"`
+			Keys   []string `json:"keys"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			handleError(w, invalidRequestError{fmt.Errorf("decoding body: %w", err)}, true)
+			return
+		}
+
+		keys := &keysbuilder.Simple{K: body.Keys}
+		if err := keys.Validate(); err != nil || len(body.Keys) == 0 {
+			handleError(w, invalidRequestError{fmt.Errorf("invalid keys")}, true)
+			return
+		}
+
+		explanations, err := explainer.Explain(r.Context(), auth.FromContext(r.Context()), body.UserID, body.Keys...)
+		if err != nil {
+			handleError(w, fmt.Errorf("explaining keys: %w", err), true)
+			return
+		}
+
+		if err := json.NewEncoder(w).Encode(explanations); err != nil {
+			handleError(w, fmt.Errorf("encoding explanations: %w", err), false)
 			return
 		}
 	})
@@ -68,17 +409,413 @@ func Simple(mux *http.ServeMux, auth Authenticater, liver Liver) {
 	mux.Handle(url, validRequest(authMiddleware(handler, auth)))
 }
 
+// MaxProfileDuration is the maximum duration of a cpu profile.
+const MaxProfileDuration = 2 * time.Minute
+
+// Profile captures a profile of the service and writes it to the response.
+//
+// The url argument `type` is `cpu`, `heap` or `allocs`. A cpu profile is
+// recorded for the time given by the url argument `duration` (for example
+// `?type=cpu&duration=30s`). The default is 10 seconds. The result can be
+// read with `go tool pprof`.
+//
+// This handler does not authenticate the request. It must only be used on an
+// internal listen address.
+func Profile(mux *http.ServeMux) {
+	url := internalPrefix + "/profile"
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		profileType := r.URL.Query().Get("type")
+		switch profileType {
+		case "cpu":
+			duration := 10 * time.Second
+			if v := r.URL.Query().Get("duration"); v != "" {
+				d, err := time.ParseDuration(v)
+				if err != nil {
+					handleError(w, invalidRequestError{fmt.Errorf("invalid duration %q: %w", v, err)}, true)
+					return
+				}
+
+				if d <= 0 || d > MaxProfileDuration {
+					handleError(w, invalidRequestError{fmt.Errorf("duration has to be between 0 and %s", MaxProfileDuration)}, true)
+					return
+				}
+				duration = d
+			}
+
+			var buf bytes.Buffer
+			if err := pprof.StartCPUProfile(&buf); err != nil {
+				handleError(w, invalidRequestError{fmt.Errorf("starting cpu profile: %w", err)}, true)
+				return
+			}
+
+			timer := time.NewTimer(duration)
+			select {
+			case <-timer.C:
+			case <-r.Context().Done():
+				timer.Stop()
+			}
+			pprof.StopCPUProfile()
+
+			if r.Context().Err() != nil {
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Content-Disposition", `attachment; filename="cpu.pprof"`)
+			if _, err := buf.WriteTo(w); err != nil {
+				log.Printf("Writing cpu profile: %v", err)
+			}
+
+		case "heap", "allocs":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pprof"`, profileType))
+			if err := pprof.Lookup(profileType).WriteTo(w, 0); err != nil {
+				handleError(w, fmt.Errorf("writing %s profile: %w", profileType, err), false)
+				return
+			}
+
+		default:
+			handleError(w, invalidRequestError{fmt.Errorf("unknown profile type %q, expected cpu, heap or allocs", profileType)}, true)
+		}
+	})
+
+	mux.Handle(url, validRequest(handler))
+}
+
+// Pprof adds the handlers of net/http/pprof under the url /debug/pprof/.
+//
+// This handler does not authenticate the request. It must only be used on an
+// internal listen address.
+func Pprof(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", httppprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", httppprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", httppprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", httppprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", httppprof.Trace)
+}
+
+// RuntimeMetrics returns metrics of the go runtime as json object, for example
+// the number of goroutines and the memory of the heap.
+//
+// This handler does not authenticate the request. It must only be used on an
+// internal listen address.
+func RuntimeMetrics(mux *http.ServeMux) {
+	url := "/debug/runtime"
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+
+		metrics := map[string]uint64{
+			"goroutines":         uint64(runtime.NumGoroutine()),
+			"heap_alloc_bytes":   mem.HeapAlloc,
+			"heap_inuse_bytes":   mem.HeapInuse,
+			"heap_objects":       mem.HeapObjects,
+			"sys_bytes":          mem.Sys,
+			"total_alloc_bytes":  mem.TotalAlloc,
+			"gc_runs":            uint64(mem.NumGC),
+			"gc_pause_total_ns":  mem.PauseTotalNs,
+			"stack_inuse_bytes":  mem.StackInuse,
+			"next_gc_heap_bytes": mem.NextGC,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(metrics); err != nil {
+			handleError(w, fmt.Errorf("encoding runtime metrics: %w", err), false)
+			return
+		}
+	})
+
+	mux.Handle(url, validRequest(handler))
+}
+
+// Metrics returns the counters of the given metricers as json object.
+//
+// This handler does not authenticate the request. It must only be used on an
+// internal listen address.
+func Metrics(mux *http.ServeMux, metricers ...Metricer) {
+	url := internalPrefix + "/metrics"
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		metrics := make(map[string]uint64)
+		for _, m := range metricers {
+			for k, v := range m.Metrics() {
+				metrics[k] = v
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(metrics); err != nil {
+			handleError(w, fmt.Errorf("encoding metrics: %w", err), false)
+			return
+		}
+	})
+
+	mux.Handle(url, validRequest(handler))
+}
+
+// ResetCache clears the cache of the service. Every connection gets a full
+// update afterwards. The request has to be a POST request.
+//
+// With the url argument `collection` (for example `?collection=motion`), only
+// the keys of this collection are removed from the cache.
+//
+// This handler does not authenticate the request. It must only be used on an
+// internal listen address.
+func ResetCache(mux *http.ServeMux, resetter CacheResetter) {
+	url := internalPrefix + "/reset_cache"
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			handleError(w, invalidRequestError{fmt.Errorf("Only POST requests are supported")}, true)
+			return
+		}
+
+		if collection := r.URL.Query().Get("collection"); collection != "" {
+			resetter.ResetCollection(collection)
+		} else {
+			resetter.ResetCache()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, `{"reset": true}`)
+	})
+
+	mux.Handle(url, handler)
+}
+
+// Connections returns the open connections as json object. Each connection
+// contains the user id, the number of subscribed keys, the id of the last
+// change, that was sent to the connection, its age and the time of the last
+// message. `last_change_id` is the id of the newest change.
+//
+// This handler does not authenticate the request. It must only be used on an
+// internal listen address.
+func Connections(mux *http.ServeMux, lister ConnectionLister) {
+	url := internalPrefix + "/connections"
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		type connection struct {
+			autoupdate.ConnectionInfo
+			Age string `json:"age"`
+		}
+
+		now := time.Now()
+		infos := lister.Connections()
+		connections := make([]connection, len(infos))
+		for i, info := range infos {
+			connections[i] = connection{info, now.Sub(info.Created).Round(time.Second).String()}
+		}
+
+		response := struct {
+			LastChangeID uint64       `json:"last_change_id"`
+			Connections  []connection `json:"connections"`
+		}{lister.LastID(), connections}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			handleError(w, fmt.Errorf("encoding connections: %w", err), false)
+			return
+		}
+	})
+
+	mux.Handle(url, validRequest(handler))
+}
+
+// FullUpdate sends a full update to all connections. The request has to be a
+// POST request.
+//
+// With the url argument `user_id` (for example `?user_id=5`), only the
+// connections of this user get a full update.
+//
+// The request has to use http basic auth with the given password. The username
+// is ignored.
+func FullUpdate(mux *http.ServeMux, updater FullUpdater, password string) {
+	url := internalPrefix + "/full_update"
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			handleError(w, invalidRequestError{fmt.Errorf("Only POST requests are supported")}, true)
+			return
+		}
+
+		_, got, ok := r.BasicAuth()
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="autoupdate"`)
+			http.Error(w, "Invalid password", http.StatusUnauthorized)
+			return
+		}
+
+		var uids []int
+		if v := r.URL.Query().Get("user_id"); v != "" {
+			uid, err := strconv.Atoi(v)
+			if err != nil {
+				handleError(w, invalidRequestError{fmt.Errorf("invalid user_id %q: %w", v, err)}, true)
+				return
+			}
+			uids = append(uids, uid)
+		}
+
+		updater.FullUpdate(uids...)
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, `{"full_update": true}`)
+	})
+
+	mux.Handle(url, handler)
+}
+
 // Health tells, if the service is running.
-func Health(mux *http.ServeMux) {
+//
+// If config is not empty, the response also contains the effective
+// configuration of the service.
+func Health(mux *http.ServeMux, config map[string]string) {
 	url := prefix + "/health"
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/octet-stream")
+		if len(config) == 0 {
+			fmt.Fprintln(w, `{"healthy": true}`)
+			return
+		}
+
+		health := struct {
+			Healthy bool              `json:"healthy"`
+			Config  map[string]string `json:"config"`
+		}{true, config}
+
+		if err := json.NewEncoder(w).Encode(health); err != nil {
+			handleError(w, fmt.Errorf("encoding health: %w", err), false)
+			return
+		}
+	})
+
+	mux.Handle(url, handler)
+}
+
+// readyTimeout is the maximum time for each check of the ready handler.
+const readyTimeout = 2 * time.Second
+
+// Liveness tells, that the process is running. It is meant as liveness probe
+// for kubernetes and does not check the dependencies.
+func Liveness(mux *http.ServeMux) {
+	url := "/system/health"
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		fmt.Fprintln(w, `{"healthy": true}`)
 	})
 
 	mux.Handle(url, handler)
 }
 
+// Ready tells, if all dependencies of the service are reachable. It is meant
+// as readiness probe for kubernetes.
+//
+// The response contains the state of each dependency. It is `ok` or
+// `unavailable`. The error is only logged, because it can contain internal
+// addresses. If one dependency is not reachable, the status code is 503.
+func Ready(mux *http.ServeMux, dependencies map[string]Pinger) {
+	url := "/system/ready"
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+		defer cancel()
+
+		ready := struct {
+			Ready        bool              `json:"ready"`
+			Dependencies map[string]string `json:"dependencies"`
+		}{true, make(map[string]string, len(dependencies))}
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for name, dep := range dependencies {
+			wg.Add(1)
+			go func(name string, dep Pinger) {
+				defer wg.Done()
+
+				state := "ok"
+				if err := dep.Ping(ctx); err != nil {
+					log.Printf("Ready check of %s: %v", name, err)
+					state = "unavailable"
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				ready.Dependencies[name] = state
+				if state != "ok" {
+					ready.Ready = false
+				}
+			}(name, dep)
+		}
+		wg.Wait()
+
+		w.Header().Set("Content-Type", "application/json")
+		if !ready.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		if err := json.NewEncoder(w).Encode(ready); err != nil {
+			handleError(w, fmt.Errorf("encoding ready state: %w", err), false)
+			return
+		}
+	})
+
+	mux.Handle(url, handler)
+}
+
+// connectionOptions reads the connection options from the url arguments of
+// the request.
+func connectionOptions(r *http.Request) ([]autoupdate.ConnectionOption, error) {
+	var options []autoupdate.ConnectionOption
+
+	if v := r.URL.Query().Get("min_interval"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, invalidRequestError{fmt.Errorf("invalid min_interval %q: %w", v, err)}
+		}
+
+		if d < 0 || d > autoupdate.MaxMinInterval {
+			return nil, invalidRequestError{fmt.Errorf("min_interval has to be between 0 and %s", autoupdate.MaxMinInterval)}
+		}
+		options = append(options, autoupdate.WithMinInterval(d))
+	}
+
+	if r.URL.Query().Get("provenance") == "1" {
+		options = append(options, autoupdate.WithProvenance())
+	}
+
+	if r.URL.Query().Get("skip_first_empty") == "1" {
+		options = append(options, autoupdate.WithSkipFirstEmpty())
+	}
+
+	if r.URL.Query().Get("deleted") == "1" {
+		options = append(options, autoupdate.WithDeleted())
+	}
+
+	if r.URL.Query().Get("notify") == "1" {
+		options = append(options, autoupdate.WithNotify())
+	}
+
+	if r.URL.Query().Get("position") == "1" {
+		options = append(options, autoupdate.WithPosition())
+	}
+
+	if r.URL.Query().Get("checksum") == "1" {
+		options = append(options, autoupdate.WithChecksum())
+	}
+
+	if v := r.URL.Query().Get("reconnect"); v != "" {
+		checksum, err := strconv.ParseUint(v, 16, 64)
+		if err != nil {
+			return nil, invalidRequestError{fmt.Errorf("invalid reconnect %q, expected a checksum", v)}
+		}
+		options = append(options, autoupdate.WithReconnect(checksum))
+	}
+
+	if v := r.URL.Query().Get("meeting"); v != "" {
+		meetingID, err := strconv.Atoi(v)
+		if err != nil || meetingID <= 0 {
+			return nil, invalidRequestError{fmt.Errorf("invalid meeting %q, expected a positive id", v)}
+		}
+		options = append(options, autoupdate.WithMeeting(meetingID))
+	}
+
+	return options, nil
+}
+
 func authMiddleware(next http.Handler, auth Authenticater) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx, err := auth.Authenticate(w, r)
@@ -114,10 +851,48 @@ func handleError(w http.ResponseWriter, err error, writeStatusCode bool) {
 		return
 	}
 
+	var errDetailed DetailedError
+	if errors.As(err, &errDetailed) {
+		if writeStatusCode {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+
+		var out struct {
+			Error struct {
+				Type     string `json:"type"`
+				Msg      string `json:"msg"`
+				Code     string `json:"code"`
+				Path     string `json:"path,omitempty"`
+				Expected string `json:"expected,omitempty"`
+				Got      string `json:"got,omitempty"`
+			} `json:"error"`
+		}
+		out.Error.Type = errDetailed.Type()
+		out.Error.Msg = errDetailed.Error()
+		out.Error.Code = errDetailed.Code()
+		out.Error.Path = errDetailed.Path()
+		out.Error.Expected = errDetailed.Expected()
+		out.Error.Got = errDetailed.Got()
+
+		if err := json.NewEncoder(w).Encode(out); err != nil {
+			log.Printf("Encoding error: %v", err)
+		}
+		return
+	}
+
 	var errClient ClientError
 	if errors.As(err, &errClient) {
 		if writeStatusCode {
-			w.WriteHeader(http.StatusBadRequest)
+			status := http.StatusBadRequest
+			if s, ok := errClient.(interface{ StatusCode() int }); ok {
+				status = s.StatusCode()
+			}
+			w.WriteHeader(status)
+		}
+
+		if c, ok := errClient.(interface{ Code() string }); ok && c.Code() != "" {
+			fmt.Fprintf(w, `{"error": {"type": "%s", "msg": "%s", "code": "%s"}}`, errClient.Type(), quote(errClient.Error()), quote(c.Code()))
+			return
 		}
 
 		fmt.Fprintf(w, `{"error": {"type": "%s", "msg": "%s"}}`, errClient.Type(), quote(errClient.Error()))
@@ -131,6 +906,90 @@ func handleError(w http.ResponseWriter, err error, writeStatusCode bool) {
 	fmt.Fprintln(w, `{"error": {"type": "InternalError", "msg": "Ups, something went wrong!"}}`)
 }
 
+// Reasons, why the server ended a stream. The reason is sent in the last
+// message of the stream.
+const (
+	// ReasonShutdown means, that the server is shutting down. The client
+	// should reconnect.
+	ReasonShutdown = "shutdown"
+
+	// ReasonAuth means, that the session of the user ended. The client has to
+	// login again.
+	ReasonAuth = "auth"
+
+	// ReasonSlowClient means, that the client did not read the messages fast
+	// enough. The client should reconnect.
+	ReasonSlowClient = "slow_client"
+
+	// ReasonInvalidRequest means, that the request can not be handled. The
+	// client should not reconnect with the same request.
+	ReasonInvalidRequest = "invalid_request"
+
+	// ReasonInternalError means, that there was an error in the server.
+	ReasonInternalError = "internal_error"
+)
+
+// streamError writes the last message of a stream. It is an error object with
+// the field `reason`, that tells, why the stream ended.
+//
+// If the client closed the connection, nothing is written.
+func streamError(w io.Writer, err error) {
+	if errors.Is(err, context.Canceled) {
+		// Client closed connection.
+		return
+	}
+
+	var out struct {
+		Error struct {
+			Type   string `json:"type"`
+			Msg    string `json:"msg"`
+			Code   string `json:"code,omitempty"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	}
+
+	var closing interface {
+		Closing()
+	}
+	var slowClient interface {
+		SlowClient()
+	}
+	var errClient ClientError
+
+	switch {
+	case errors.As(err, &closing):
+		out.Error.Type = "closing"
+		out.Error.Msg = "The server is shutting down"
+		out.Error.Reason = ReasonShutdown
+
+	case errors.As(err, &slowClient) && errors.As(err, &errClient):
+		out.Error.Type = errClient.Type()
+		out.Error.Msg = errClient.Error()
+		out.Error.Reason = ReasonSlowClient
+
+	case errors.As(err, &errClient):
+		out.Error.Type = errClient.Type()
+		out.Error.Msg = errClient.Error()
+		out.Error.Reason = ReasonInvalidRequest
+		if c, ok := errClient.(interface{ Code() string }); ok {
+			out.Error.Code = c.Code()
+		}
+		if s, ok := errClient.(interface{ StatusCode() int }); ok && s.StatusCode() == http.StatusUnauthorized {
+			out.Error.Reason = ReasonAuth
+		}
+
+	default:
+		log.Printf("Internal Error: %v", err)
+		out.Error.Type = "InternalError"
+		out.Error.Msg = "Ups, something went wrong!"
+		out.Error.Reason = ReasonInternalError
+	}
+
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		log.Printf("Encoding error: %v", err)
+	}
+}
+
 // quote decodes changes quotation marks with a backslash to make sure, they are
 // valid json.
 func quote(s string) string {
@@ -3,22 +3,28 @@ package http_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/OpenSlides/openslides-autoupdate-service/internal/autoupdate"
 	ahttp "github.com/OpenSlides/openslides-autoupdate-service/internal/http"
 	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type liverMock struct {
 	content io.Reader
 }
 
-func (m *liverMock) Live(ctx context.Context, uid int, w io.Writer, kb autoupdate.KeysBuilder) error {
+func (m *liverMock) Live(ctx context.Context, uid int, w io.Writer, kb autoupdate.KeysBuilder, options ...autoupdate.ConnectionOption) error {
 	io.Copy(w, m.content)
 	return nil
 }
@@ -53,7 +59,7 @@ func TestComplexHandler(t *testing.T) {
 	liver := &liverMock{
 		content: strings.NewReader("content"),
 	}
-	ahttp.Complex(mux, test.Auth(1), new(test.DataProvider), liver)
+	ahttp.Complex(mux, test.Auth(1), new(test.DataProvider), liver, ahttp.BodyLimit{})
 
 	req, _ := http.NewRequest("GET", "/system/autoupdate", strings.NewReader(`[{"ids":[1],"collection":"user","fields":{"name":null}}]`))
 	req.ProtoMajor = 2
@@ -73,9 +79,729 @@ func TestComplexHandler(t *testing.T) {
 	}
 }
 
+type visibilityMock struct{}
+
+func (visibilityMock) Visibility(ctx context.Context, uid int, fqids ...string) (map[string]string, error) {
+	out := make(map[string]string, len(fqids))
+	for _, fqid := range fqids {
+		out[fqid] = "visible"
+	}
+	return out, nil
+}
+
+func TestExists(t *testing.T) {
+	mux := http.NewServeMux()
+	ahttp.Exists(mux, test.Auth(1), visibilityMock{})
+
+	t.Run("Valid", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/system/autoupdate/exists?motion/1,user/5", nil)
+		req.ProtoMajor = 2
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != 200 {
+			t.Errorf("Got status %d, expected 200", rec.Code)
+		}
+
+		got, _ := io.ReadAll(rec.Body)
+		expect := `{"motion/1":"visible","user/5":"visible"}` + "\n"
+		if string(got) != expect {
+			t.Errorf("Got `%s`, expected `%s`", got, expect)
+		}
+	})
+
+	t.Run("Invalid fqid", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/system/autoupdate/exists?motion/1/title", nil)
+		req.ProtoMajor = 2
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != 400 {
+			t.Errorf("Got status %d, expected 400", rec.Code)
+		}
+	})
+}
+
+type notifierMock struct {
+	uid          int
+	notification autoupdate.Notification
+}
+
+func (n *notifierMock) Notify(ctx context.Context, uid int, notification autoupdate.Notification) error {
+	n.uid = uid
+	n.notification = notification
+	return nil
+}
+
+func TestNotify(t *testing.T) {
+	mux := http.NewServeMux()
+	notifier := new(notifierMock)
+	ahttp.Notify(mux, test.Auth(1), notifier, ahttp.BodyLimit{})
+
+	t.Run("Valid", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/system/autoupdate/notify", strings.NewReader(`{"name":"applause","message":{"level":3},"to_meeting":1}`))
+		req.ProtoMajor = 2
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != 200 {
+			t.Errorf("Got status %d, expected 200", rec.Code)
+		}
+
+		assert.Equal(t, 1, notifier.uid)
+		assert.Equal(t, "applause", notifier.notification.Name)
+		assert.JSONEq(t, `{"level":3}`, string(notifier.notification.Message))
+		assert.Equal(t, 1, notifier.notification.ToMeeting)
+	})
+
+	t.Run("GET", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/system/autoupdate/notify", nil)
+		req.ProtoMajor = 2
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != 400 {
+			t.Errorf("Got status %d, expected 400", rec.Code)
+		}
+	})
+
+	t.Run("Invalid body", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/system/autoupdate/notify", strings.NewReader(`{"name":`))
+		req.ProtoMajor = 2
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != 400 {
+			t.Errorf("Got status %d, expected 400", rec.Code)
+		}
+	})
+}
+
+// historyVisibilityMock can see all objects except motion/2 and user/6.
+type historyVisibilityMock struct{}
+
+func (historyVisibilityMock) Visibility(ctx context.Context, uid int, fqids ...string) (map[string]string, error) {
+	out := make(map[string]string, len(fqids))
+	for _, fqid := range fqids {
+		out[fqid] = autoupdate.VisibilityVisible
+		if fqid == "motion/2" || fqid == "user/6" {
+			out[fqid] = autoupdate.VisibilityForbidden
+		}
+	}
+	return out, nil
+}
+
+type historianMock map[string][]datastore.HistoryEntry
+
+func (h historianMock) History(ctx context.Context, fqids ...string) (map[string][]datastore.HistoryEntry, error) {
+	return h, nil
+}
+
+// historicDataMock returns data at position 3. At this position, motion/1 has
+// the title "old" and the text is not visible. motion/2 is visible.
+type historicDataMock struct{}
+
+func (historicDataMock) RestrictedDataAt(ctx context.Context, uid int, position int, keys ...string) (map[string]json.RawMessage, error) {
+	data := map[string]json.RawMessage{
+		"motion/1/id":    []byte("1"),
+		"motion/1/title": []byte(`"old"`),
+		"motion/2/id":    []byte("2"),
+	}
+
+	out := make(map[string]json.RawMessage, len(keys))
+	for _, key := range keys {
+		out[key] = nil
+		if position == 3 {
+			out[key] = data[key]
+		}
+	}
+	return out, nil
+}
+
+func TestHistory(t *testing.T) {
+	mux := http.NewServeMux()
+	ahttp.History(mux, test.Auth(1), historyVisibilityMock{}, historianMock{
+		"motion/1": {
+			{Position: 3, Timestamp: 1000, UserID: 5},
+			{Position: 7, Timestamp: 2000, UserID: 6},
+		},
+	}, historicDataMock{})
+
+	for _, tt := range []struct {
+		name   string
+		query  string
+		status int
+		expect string
+	}{
+		{
+			"Visible",
+			"fqid=motion/1",
+			200,
+			`[{"position":3,"timestamp":1000,"user_id":5},{"position":7,"timestamp":2000,"user_id":0}]`,
+		},
+		{
+			"No changes",
+			"fqid=motion/3",
+			200,
+			`[]`,
+		},
+		{
+			"Not visible",
+			"fqid=motion/2",
+			404,
+			`{"error":{"type":"not_found","msg":"Object motion/2 does not exist or is not visible"}}`,
+		},
+		{
+			"Invalid fqid",
+			"fqid=motion/1/title",
+			400,
+			`{"error":{"type":"invalid_request","msg":"Invalid request: invalid fqid \"motion/1/title\""}}`,
+		},
+		{
+			"Position",
+			"fqid=motion/1&position=3&fields=title,text",
+			200,
+			`{"motion/1/id":1,"motion/1/title":"old"}`,
+		},
+		{
+			"Position visible only in the past",
+			"fqid=motion/2&position=3",
+			200,
+			`{"motion/2/id":2}`,
+		},
+		{
+			"Position not visible",
+			"fqid=motion/1&position=7&fields=title",
+			404,
+			`{"error":{"type":"not_found","msg":"Object motion/1 does not exist or is not visible"}}`,
+		},
+		{
+			"Invalid position",
+			"fqid=motion/1&position=first",
+			400,
+			`{"error":{"type":"invalid_request","msg":"Invalid request: invalid position \"first\", expected a positive number"}}`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/system/autoupdate/history?"+tt.query, nil)
+			req.ProtoMajor = 2
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("Got status %d, expected %d", rec.Code, tt.status)
+			}
+
+			assert.JSONEq(t, tt.expect, rec.Body.String())
+		})
+	}
+}
+
+type explainerMock struct{}
+
+func (explainerMock) Explain(ctx context.Context, requestUID int, uid int, keys ...string) (map[string]autoupdate.Explanation, error) {
+	if requestUID != uid {
+		return nil, forbiddenError{}
+	}
+
+	out := make(map[string]autoupdate.Explanation, len(keys))
+	for _, key := range keys {
+		out[key] = autoupdate.Explanation{Visible: uid == 1, Reason: "mock"}
+	}
+	return out, nil
+}
+
+type forbiddenError struct{}
+
+func (forbiddenError) Error() string   { return "forbidden" }
+func (forbiddenError) Type() string    { return "forbidden" }
+func (forbiddenError) StatusCode() int { return 403 }
+
+func TestExplain(t *testing.T) {
+	mux := http.NewServeMux()
+	ahttp.Explain(mux, test.Auth(1), explainerMock{})
+
+	for _, tt := range []struct {
+		name   string
+		body   string
+		status int
+		expect string
+	}{
+		{
+			"Valid",
+			`{"user_id": 1, "keys": ["motion/1/title"]}`,
+			200,
+			`{"motion/1/title":{"visible":true,"reason":"mock"}}` + "\n",
+		},
+		{
+			"Other user",
+			`{"user_id": 2, "keys": ["motion/1/title"]}`,
+			403,
+			"",
+		},
+		{
+			"Invalid key",
+			`{"user_id": 1, "keys": ["motion/1"]}`,
+			400,
+			"",
+		},
+		{
+			"No keys",
+			`{"user_id": 1}`,
+			400,
+			"",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/internal/autoupdate/explain", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("Got status %d, expected %d", rec.Code, tt.status)
+			}
+
+			if tt.expect == "" {
+				return
+			}
+
+			got, _ := io.ReadAll(rec.Body)
+			if string(got) != tt.expect {
+				t.Errorf("Got `%s`, expected `%s`", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestProfile(t *testing.T) {
+	mux := http.NewServeMux()
+	ahttp.Profile(mux)
+
+	for _, tt := range []struct {
+		name   string
+		url    string
+		status int
+	}{
+		{"cpu", "?type=cpu&duration=50ms", 200},
+		{"heap", "?type=heap", 200},
+		{"allocs", "?type=allocs", 200},
+		{"unknown type", "?type=goroutine", 400},
+		{"invalid duration", "?type=cpu&duration=abc", 400},
+		{"duration too long", "?type=cpu&duration=1h", 400},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/internal/autoupdate/profile"+tt.url, nil)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("Got status %d, expected %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+
+			if tt.status == 200 && rec.Body.Len() == 0 {
+				t.Errorf("Got empty profile")
+			}
+		})
+	}
+}
+
+type metricerMock map[string]uint64
+
+func (m metricerMock) Metrics() map[string]uint64 {
+	return m
+}
+
+func TestMetrics(t *testing.T) {
+	mux := http.NewServeMux()
+	ahttp.Metrics(mux, metricerMock{"foo": 1}, metricerMock{"bar": 2})
+
+	req := httptest.NewRequest("GET", "/internal/autoupdate/metrics", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != 200 {
+		t.Errorf("Got status %d, expected 200: %s", rec.Code, rec.Body.String())
+	}
+
+	expect := `{"bar":2,"foo":1}` + "\n"
+	if got := rec.Body.String(); got != expect {
+		t.Errorf("Got `%s`, expected `%s`", got, expect)
+	}
+}
+
+type resetterMock struct {
+	called     bool
+	collection string
+}
+
+func (r *resetterMock) ResetCache() {
+	r.called = true
+}
+
+func (r *resetterMock) ResetCollection(collection string) {
+	r.collection = collection
+}
+
+func TestRuntimeMetrics(t *testing.T) {
+	mux := http.NewServeMux()
+	ahttp.RuntimeMetrics(mux)
+
+	req := httptest.NewRequest("GET", "/debug/runtime", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Result().StatusCode != 200 {
+		t.Fatalf("Got status %s, expected %s", rec.Result().Status, http.StatusText(200))
+	}
+
+	var metrics map[string]uint64
+	if err := json.NewDecoder(rec.Body).Decode(&metrics); err != nil {
+		t.Fatalf("Decoding response: %v", err)
+	}
+
+	if metrics["goroutines"] == 0 || metrics["heap_alloc_bytes"] == 0 {
+		t.Errorf("Got metrics %v, expected goroutines and heap_alloc_bytes", metrics)
+	}
+}
+
+func TestPprof(t *testing.T) {
+	mux := http.NewServeMux()
+	ahttp.Pprof(mux)
+
+	req := httptest.NewRequest("GET", "/debug/pprof/heap", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Result().StatusCode != 200 {
+		t.Errorf("Got status %s, expected %s", rec.Result().Status, http.StatusText(200))
+	}
+}
+
+func TestResetCache(t *testing.T) {
+	mux := http.NewServeMux()
+	resetter := new(resetterMock)
+	ahttp.ResetCache(mux, resetter)
+
+	t.Run("GET", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/internal/autoupdate/reset_cache", nil)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != 400 {
+			t.Errorf("Got status %d, expected 400", rec.Code)
+		}
+
+		if resetter.called {
+			t.Errorf("Cache was reset on a GET request")
+		}
+	})
+
+	t.Run("POST", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/internal/autoupdate/reset_cache", nil)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != 200 {
+			t.Errorf("Got status %d, expected 200: %s", rec.Code, rec.Body.String())
+		}
+
+		if !resetter.called {
+			t.Errorf("Cache was not reset")
+		}
+	})
+
+	t.Run("collection", func(t *testing.T) {
+		resetter.called = false
+		req := httptest.NewRequest("POST", "/internal/autoupdate/reset_cache?collection=motion", nil)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != 200 {
+			t.Errorf("Got status %d, expected 200: %s", rec.Code, rec.Body.String())
+		}
+
+		if resetter.called {
+			t.Errorf("The whole cache was reset")
+		}
+
+		if resetter.collection != "motion" {
+			t.Errorf("Got reset of collection %q, expected motion", resetter.collection)
+		}
+	})
+}
+
+type authErrorMock struct{}
+
+func (authErrorMock) Error() string   { return "Auth cookie is expired" }
+func (authErrorMock) Type() string    { return "auth" }
+func (authErrorMock) Code() string    { return "token_expired" }
+func (authErrorMock) StatusCode() int { return http.StatusUnauthorized }
+
+type failingAuth struct{}
+
+func (failingAuth) Authenticate(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+	return nil, authErrorMock{}
+}
+
+func (failingAuth) FromContext(ctx context.Context) int {
+	return 0
+}
+
+func TestAuthError(t *testing.T) {
+	mux := http.NewServeMux()
+	ahttp.Simple(mux, failingAuth{}, &liverMock{})
+
+	req := httptest.NewRequest("GET", "/system/autoupdate/keys?user/1/name", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Got status %d, expected 401", rec.Code)
+	}
+
+	assert.JSONEq(t, `{"error": {"type": "auth", "msg": "Auth cookie is expired", "code": "token_expired"}}`, rec.Body.String())
+}
+
+type errLiver struct {
+	err error
+}
+
+func (l errLiver) Live(ctx context.Context, uid int, w io.Writer, kb autoupdate.KeysBuilder, options ...autoupdate.ConnectionOption) error {
+	fmt.Fprintln(w, `{"user/1/name":"hugo"}`)
+	return l.err
+}
+
+type closingErrorMock struct{}
+
+func (closingErrorMock) Error() string { return "closing" }
+func (closingErrorMock) Closing()      {}
+
+type slowClientErrorMock struct{}
+
+func (slowClientErrorMock) Error() string { return "too slow" }
+func (slowClientErrorMock) Type() string  { return "slow_client" }
+func (slowClientErrorMock) SlowClient()   {}
+
+func TestStreamCloseReason(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		err    error
+		expect string
+	}{
+		{
+			"shutdown",
+			closingErrorMock{},
+			`{"error":{"type":"closing","msg":"The server is shutting down","reason":"shutdown"}}`,
+		},
+		{
+			"auth",
+			fmt.Errorf("receiving: %w", authErrorMock{}),
+			`{"error":{"type":"auth","msg":"Auth cookie is expired","code":"token_expired","reason":"auth"}}`,
+		},
+		{
+			"slow client",
+			slowClientErrorMock{},
+			`{"error":{"type":"slow_client","msg":"too slow","reason":"slow_client"}}`,
+		},
+		{
+			"internal error",
+			errors.New("some error"),
+			`{"error":{"type":"InternalError","msg":"Ups, something went wrong!","reason":"internal_error"}}`,
+		},
+		{
+			"client closed",
+			context.Canceled,
+			"",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			ahttp.Simple(mux, test.Auth(1), errLiver{err: tt.err})
+
+			req := httptest.NewRequest("GET", "/system/autoupdate/keys?user/1/name", nil)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+			if tt.expect == "" {
+				if len(lines) != 1 {
+					t.Errorf("Got %d messages, expected only the data: %s", len(lines), rec.Body.String())
+				}
+				return
+			}
+
+			if len(lines) != 2 {
+				t.Fatalf("Got %d messages, expected 2: %s", len(lines), rec.Body.String())
+			}
+			assert.JSONEq(t, tt.expect, lines[1])
+		})
+	}
+}
+
+type connectionListerMock []autoupdate.ConnectionInfo
+
+func (c connectionListerMock) Connections() []autoupdate.ConnectionInfo {
+	return c
+}
+
+func (c connectionListerMock) LastID() uint64 {
+	return 7
+}
+
+func TestConnections(t *testing.T) {
+	mux := http.NewServeMux()
+	created := time.Now().Add(-time.Minute)
+	ahttp.Connections(mux, connectionListerMock{
+		{UserID: 5, Keys: 10, ChangeID: 3, Created: created, LastMessage: created},
+	})
+
+	req := httptest.NewRequest("GET", "/internal/autoupdate/connections", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != 200 {
+		t.Fatalf("Got status %d, expected 200: %s", rec.Code, rec.Body.String())
+	}
+
+	var got struct {
+		LastChangeID uint64 `json:"last_change_id"`
+		Connections  []struct {
+			UserID   int    `json:"user_id"`
+			Keys     int    `json:"keys"`
+			ChangeID uint64 `json:"change_id"`
+			Age      string `json:"age"`
+		} `json:"connections"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("Decoding response: %v", err)
+	}
+
+	assert.Equal(t, uint64(7), got.LastChangeID)
+	require.Len(t, got.Connections, 1)
+	assert.Equal(t, 5, got.Connections[0].UserID)
+	assert.Equal(t, 10, got.Connections[0].Keys)
+	assert.Equal(t, uint64(3), got.Connections[0].ChangeID)
+	assert.Equal(t, "1m0s", got.Connections[0].Age)
+}
+
+type fullUpdaterMock struct {
+	called bool
+	uids   []int
+}
+
+func (f *fullUpdaterMock) FullUpdate(uids ...int) {
+	f.called = true
+	f.uids = uids
+}
+
+func TestFullUpdate(t *testing.T) {
+	mux := http.NewServeMux()
+	updater := new(fullUpdaterMock)
+	ahttp.FullUpdate(mux, updater, "secret")
+
+	t.Run("wrong password", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/internal/autoupdate/full_update", nil)
+		req.SetBasicAuth("admin", "wrong")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != 401 {
+			t.Errorf("Got status %d, expected 401", rec.Code)
+		}
+
+		if updater.called {
+			t.Errorf("Full update was sent with a wrong password")
+		}
+	})
+
+	t.Run("all users", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/internal/autoupdate/full_update", nil)
+		req.SetBasicAuth("admin", "secret")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != 200 {
+			t.Errorf("Got status %d, expected 200: %s", rec.Code, rec.Body.String())
+		}
+
+		if !updater.called || len(updater.uids) != 0 {
+			t.Errorf("Got full update for %v, expected one for all users", updater.uids)
+		}
+	})
+
+	t.Run("one user", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/internal/autoupdate/full_update?user_id=5", nil)
+		req.SetBasicAuth("admin", "secret")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != 200 {
+			t.Errorf("Got status %d, expected 200: %s", rec.Code, rec.Body.String())
+		}
+
+		if len(updater.uids) != 1 || updater.uids[0] != 5 {
+			t.Errorf("Got full update for %v, expected [5]", updater.uids)
+		}
+	})
+}
+
+func TestErrorDetails(t *testing.T) {
+	mux := http.NewServeMux()
+	ahttp.Complex(mux, test.Auth(1), new(test.DataProvider), &liverMock{}, ahttp.BodyLimit{})
+
+	for _, tt := range []struct {
+		name   string
+		body   string
+		expect string
+	}{
+		{
+			"Missing collection",
+			`[{"ids":[1],"fields":{"name":null}}]`,
+			`{"type":"SyntaxError","msg":"no collection","code":"missing_collection","path":"[0].collection"}`,
+		},
+		{
+			"Nested field",
+			`[{"ids":[1],"collection":"foo","fields":{"name":null}},{"ids":[1],"collection":"foo","fields":{"bar_id":{"type":"relation","fields":{"name":null}}}}]`,
+			`{"type":"SyntaxError","msg":"field \"bar_id\": no collection","code":"missing_collection","path":"[1].fields.bar_id.collection"}`,
+		},
+		{
+			"Unknown type",
+			`[{"ids":[1],"collection":"foo","fields":{"bar_id":{"type":"unknown"}}}]`,
+			`{"type":"SyntaxError","msg":"field \"bar_id\": unknown type unknown","code":"unknown_type","path":"[0].fields.bar_id.type"}`,
+		},
+		{
+			"Wrong type",
+			`[{"ids":[1],"collection":"foo","fields":{"bar_id":{"type":"relation","collection":5,"fields":{"name":null}}}}]`,
+			`{"type":"SyntaxError","msg":"field \"bar_id\": wrong type at field ` + "`collection`" + `. Got number, expected string","code":"wrong_type","path":"[0].fields.bar_id.collection","expected":"string","got":"number"}`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/system/autoupdate", strings.NewReader(tt.body))
+			req.ProtoMajor = 2
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != 400 {
+				t.Errorf("Got status %d, expected 400", rec.Code)
+			}
+
+			var data struct {
+				Error json.RawMessage `json:"error"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &data); err != nil {
+				t.Fatalf("Can not decode body `%s`: %v", rec.Body.String(), err)
+			}
+
+			if string(data.Error) != tt.expect {
+				t.Errorf("Got error %s, expected %s", data.Error, tt.expect)
+			}
+		})
+	}
+}
+
 func TestHealth(t *testing.T) {
 	mux := http.NewServeMux()
-	ahttp.Health(mux)
+	ahttp.Health(mux, nil)
 
 	req := httptest.NewRequest("", "/system/autoupdate/health", nil)
 	rec := httptest.NewRecorder()
@@ -92,6 +818,80 @@ func TestHealth(t *testing.T) {
 	}
 }
 
+func TestHealthConfig(t *testing.T) {
+	mux := http.NewServeMux()
+	ahttp.Health(mux, map[string]string{"prune_time": "10m0s"})
+
+	req := httptest.NewRequest("", "/system/autoupdate/health", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	got, _ := io.ReadAll(rec.Body)
+	expect := `{"healthy":true,"config":{"prune_time":"10m0s"}}` + "\n"
+	if string(got) != expect {
+		t.Errorf("Got %s, expected %s", got, expect)
+	}
+}
+
+type pingerMock struct {
+	err error
+}
+
+func (p pingerMock) Ping(ctx context.Context) error {
+	return p.err
+}
+
+func TestLiveness(t *testing.T) {
+	mux := http.NewServeMux()
+	ahttp.Liveness(mux)
+
+	req := httptest.NewRequest("", "/system/health", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Result().StatusCode != 200 {
+		t.Errorf("Got status %s, expected %s", rec.Result().Status, http.StatusText(200))
+	}
+}
+
+func TestReady(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		deps   map[string]ahttp.Pinger
+		status int
+		expect string
+	}{
+		{
+			"ready",
+			map[string]ahttp.Pinger{"datastore": pingerMock{}, "auth": pingerMock{}},
+			200,
+			`{"ready":true,"dependencies":{"datastore":"ok","auth":"ok"}}`,
+		},
+		{
+			"not ready",
+			map[string]ahttp.Pinger{"datastore": pingerMock{}, "message_bus": pingerMock{errors.New("no connection")}},
+			503,
+			`{"ready":false,"dependencies":{"datastore":"ok","message_bus":"unavailable"}}`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			ahttp.Ready(mux, tt.deps)
+
+			req := httptest.NewRequest("", "/system/ready", nil)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Result().StatusCode != tt.status {
+				t.Errorf("Got status %s, expected %s", rec.Result().Status, http.StatusText(tt.status))
+			}
+
+			got, _ := io.ReadAll(rec.Body)
+			assert.JSONEq(t, tt.expect, string(got))
+		})
+	}
+}
+
 func TestErrors(t *testing.T) {
 	mux := http.NewServeMux()
 	liver := &liverMock{
@@ -102,7 +902,7 @@ func TestErrors(t *testing.T) {
 			"foo/1/name": []byte(`"hugo"`),
 		},
 	}
-	ahttp.Complex(mux, test.Auth(1), db, liver)
+	ahttp.Complex(mux, test.Auth(1), db, liver, ahttp.BodyLimit{})
 
 	for _, tt := range []struct {
 		name    string
@@ -177,6 +977,39 @@ func TestErrors(t *testing.T) {
 			`SyntaxError`,
 			"wrong type at field `ids`. Got string, expected number",
 		},
+		{
+			"Invalid nested field",
+			httptest.NewRequest(
+				"GET",
+				"/system/autoupdate",
+				strings.NewReader(`[{"ids":[1],"collection":"foo","fields":{"name":null}},{"ids":[1],"collection":"foo","fields":{"bar_id":{"type":"relation","fields":{"name":null}}}}]`),
+			),
+			400,
+			`SyntaxError`,
+			`field "bar_id": no collection`,
+		},
+		{
+			"Min interval too big",
+			httptest.NewRequest(
+				"GET",
+				"/system/autoupdate?min_interval=1h",
+				strings.NewReader(`[{"ids":[1],"collection":"foo","fields":{"name":null}}]`),
+			),
+			400,
+			`invalid_request`,
+			"Invalid request: min_interval has to be between 0 and 1m0s",
+		},
+		{
+			"Invalid meeting",
+			httptest.NewRequest(
+				"GET",
+				"/system/autoupdate?meeting=abc",
+				strings.NewReader(`[{"ids":[1],"collection":"foo","fields":{"name":null}}]`),
+			),
+			400,
+			`invalid_request`,
+			`Invalid request: invalid meeting "abc", expected a positive id`,
+		},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.request.ProtoMajor = 2
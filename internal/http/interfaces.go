@@ -2,10 +2,12 @@ package http
 
 import (
 	"context"
+	"encoding/json"
 	"io"
 	"net/http"
 
 	"github.com/OpenSlides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
 )
 
 // Authenticater gives an user id for an request. Returns 0 for anonymous.
@@ -15,12 +17,86 @@ type Authenticater interface {
 }
 
 // ClientError is an expected error that are returned to the client.
+//
+// The status code is 400. A ClientError can change it with a method
+// `StatusCode() int`. With a method `Code() string`, the response contains a
+// machine readable name of the error.
 type ClientError interface {
 	Type() string
 	Error() string
 }
 
+// DetailedError is a ClientError that knows, where the error is in the
+// request.
+type DetailedError interface {
+	ClientError
+
+	// Code is a machine readable name of the error.
+	Code() string
+
+	// Path is the json path to the invalid value.
+	Path() string
+
+	// Expected and Got are the json types of a value with the wrong type.
+	Expected() string
+	Got() string
+}
+
 // Liver provides a Live method, that writes continues data to the given writer.
 type Liver interface {
-	Live(ctx context.Context, uid int, w io.Writer, kb autoupdate.KeysBuilder) error
+	Live(ctx context.Context, uid int, w io.Writer, kb autoupdate.KeysBuilder, options ...autoupdate.ConnectionOption) error
+}
+
+// Visibilityer tells, if objects exist and if a user can see them.
+type Visibilityer interface {
+	Visibility(ctx context.Context, uid int, fqids ...string) (map[string]string, error)
+}
+
+// Historian returns the changes of objects in the datastore.
+type Historian interface {
+	History(ctx context.Context, fqids ...string) (map[string][]datastore.HistoryEntry, error)
+}
+
+// HistoricDataer returns restricted data at a position of the datastore.
+type HistoricDataer interface {
+	RestrictedDataAt(ctx context.Context, uid int, position int, keys ...string) (map[string]json.RawMessage, error)
+}
+
+// Explainer tells for a user, if keys are visible and why. requestUID is the
+// user, that asks for the explanation.
+type Explainer interface {
+	Explain(ctx context.Context, requestUID int, uid int, keys ...string) (map[string]autoupdate.Explanation, error)
+}
+
+// Notifier sends notifications to the connections of other users.
+type Notifier interface {
+	Notify(ctx context.Context, uid int, n autoupdate.Notification) error
+}
+
+// Metricer returns counters of a part of the service.
+type Metricer interface {
+	Metrics() map[string]uint64
+}
+
+// CacheResetter clears the cache of the service.
+type CacheResetter interface {
+	ResetCache()
+	ResetCollection(collection string)
+}
+
+// ConnectionLister returns the open connections and the id of the last
+// change.
+type ConnectionLister interface {
+	Connections() []autoupdate.ConnectionInfo
+	LastID() uint64
+}
+
+// FullUpdater sends full updates to connections.
+type FullUpdater interface {
+	FullUpdate(uids ...int)
+}
+
+// Pinger checks, if a dependency of the service is reachable.
+type Pinger interface {
+	Ping(ctx context.Context) error
 }
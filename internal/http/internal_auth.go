@@ -0,0 +1,92 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// InternalAuthHeader is the http header, that contains the token of a
+	// trusted backend service.
+	InternalAuthHeader = "X-Internal-Auth"
+
+	// InternalUserHeader is the http header, that contains the id of the user,
+	// on whose behalf a backend service sends the request.
+	InternalUserHeader = "X-User-Id"
+)
+
+// DefaultInternalRoutes are the urls, that accept the internal auth, if no
+// other routes are given.
+var DefaultInternalRoutes = []string{
+	internalPrefix + "/query",
+	prefix + "/exists",
+}
+
+// InternalAuth wraps an Authenticater so other backend services can send
+// requests on behalf of a user.
+//
+// A request with the header X-Internal-Auth is not authenticated by auth.
+// Instead, the header has to contain the token and the header X-User-Id the
+// id of the user. This is only allowed for the given routes. If no routes are
+// given, DefaultInternalRoutes are used. Requests without the header are
+// handled by auth.
+func InternalAuth(auth Authenticater, token string, routes ...string) Authenticater {
+	if len(routes) == 0 {
+		routes = DefaultInternalRoutes
+	}
+
+	allowed := make(map[string]bool, len(routes))
+	for _, r := range routes {
+		allowed[r] = true
+	}
+
+	return &internalAuth{
+		Authenticater: auth,
+		token:         []byte(token),
+		routes:        allowed,
+	}
+}
+
+type internalAuth struct {
+	Authenticater
+	token  []byte
+	routes map[string]bool
+}
+
+type internalUserKey struct{}
+
+// Authenticate uses the internal auth, if the request has the header
+// X-Internal-Auth.
+func (a *internalAuth) Authenticate(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+	got := r.Header.Get(InternalAuthHeader)
+	if got == "" {
+		return a.Authenticater.Authenticate(w, r)
+	}
+
+	if !a.routes[r.URL.Path] {
+		return nil, internalAuthError{msg: "Internal auth is not allowed for this route", status: http.StatusForbidden}
+	}
+
+	if subtle.ConstantTimeCompare([]byte(got), a.token) != 1 {
+		return nil, internalAuthError{msg: "Invalid internal auth token", status: http.StatusUnauthorized}
+	}
+
+	uid, err := strconv.Atoi(r.Header.Get(InternalUserHeader))
+	if err != nil || uid < 0 {
+		return nil, invalidRequestError{fmt.Errorf("invalid user id in header %s", InternalUserHeader)}
+	}
+
+	return context.WithValue(r.Context(), internalUserKey{}, uid), nil
+}
+
+// FromContext returns the user id of an internal request or asks the wrapped
+// Authenticater.
+func (a *internalAuth) FromContext(ctx context.Context) int {
+	if uid, ok := ctx.Value(internalUserKey{}).(int); ok {
+		return uid
+	}
+	return a.Authenticater.FromContext(ctx)
+}
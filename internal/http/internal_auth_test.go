@@ -0,0 +1,100 @@
+package http_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	ahttp "github.com/OpenSlides/openslides-autoupdate-service/internal/http"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+)
+
+// uidVisibility returns the user id as visibility of each fqid.
+type uidVisibility struct{}
+
+func (uidVisibility) Visibility(ctx context.Context, uid int, fqids ...string) (map[string]string, error) {
+	out := make(map[string]string, len(fqids))
+	for _, fqid := range fqids {
+		out[fqid] = fmt.Sprintf("user%d", uid)
+	}
+	return out, nil
+}
+
+func TestInternalAuth(t *testing.T) {
+	auth := ahttp.InternalAuth(test.Auth(1), "secret", "/system/autoupdate/exists")
+
+	mux := http.NewServeMux()
+	ahttp.Exists(mux, auth, uidVisibility{})
+	ahttp.Simple(mux, auth, &liverMock{})
+
+	for _, tt := range []struct {
+		name   string
+		url    string
+		header map[string]string
+		status int
+		body   string
+	}{
+		{
+			"Without internal auth",
+			"/system/autoupdate/exists?motion/1",
+			nil,
+			200,
+			`{"motion/1":"user1"}` + "\n",
+		},
+		{
+			"On behalf of user",
+			"/system/autoupdate/exists?motion/1",
+			map[string]string{ahttp.InternalAuthHeader: "secret", ahttp.InternalUserHeader: "5"},
+			200,
+			`{"motion/1":"user5"}` + "\n",
+		},
+		{
+			"Anonymous",
+			"/system/autoupdate/exists?motion/1",
+			map[string]string{ahttp.InternalAuthHeader: "secret", ahttp.InternalUserHeader: "0"},
+			200,
+			`{"motion/1":"user0"}` + "\n",
+		},
+		{
+			"Wrong token",
+			"/system/autoupdate/exists?motion/1",
+			map[string]string{ahttp.InternalAuthHeader: "wrong", ahttp.InternalUserHeader: "5"},
+			401,
+			`{"error": {"type": "auth", "msg": "Invalid internal auth token"}}`,
+		},
+		{
+			"Without user",
+			"/system/autoupdate/exists?motion/1",
+			map[string]string{ahttp.InternalAuthHeader: "secret"},
+			400,
+			"",
+		},
+		{
+			"Route not allowed",
+			"/system/autoupdate/keys?user/1/name",
+			map[string]string{ahttp.InternalAuthHeader: "secret", ahttp.InternalUserHeader: "5"},
+			403,
+			`{"error": {"type": "auth", "msg": "Internal auth is not allowed for this route"}}`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.url, nil)
+			req.ProtoMajor = 2
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("Got status %d, expected %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+
+			if tt.body != "" && rec.Body.String() != tt.body {
+				t.Errorf("Got `%s`, expected `%s`", rec.Body.String(), tt.body)
+			}
+		})
+	}
+}
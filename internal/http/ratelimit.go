@@ -0,0 +1,200 @@
+package http
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimiter limits the number of new connections per ip address and per
+// user with a token bucket.
+//
+// Has to be created with NewRateLimiter.
+type RateLimiter struct {
+	rate    float64
+	burst   float64
+	proxies []*net.IPNet
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+
+	limitedIP   uint64
+	limitedUser uint64
+}
+
+// bucket contains the tokens of one ip address or user at the time last.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter initializes a RateLimiter. Each ip address and each user can
+// open burst connections at once and afterwards rate connections per second.
+//
+// The header X-Forwarded-For is only used, if the request comes from one of the
+// trustedProxies.
+func NewRateLimiter(rate float64, burst int, trustedProxies ...*net.IPNet) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		proxies: trustedProxies,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// allow takes a token from the bucket of the key. It returns false and the
+// time until the next token, if the bucket is empty.
+func (l *RateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prune(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// prune removes the buckets, that are full again. It runs at most once a
+// minute.
+func (l *RateLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < time.Minute {
+		return
+	}
+	l.lastPrune = now
+
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Metrics returns the number of rejected connections.
+func (l *RateLimiter) Metrics() map[string]uint64 {
+	return map[string]uint64{
+		"connect_limited_ip":   atomic.LoadUint64(&l.limitedIP),
+		"connect_limited_user": atomic.LoadUint64(&l.limitedUser),
+	}
+}
+
+// ConnectLimit wraps an Authenticater, so new connections to the autoupdate
+// endpoints are limited by the limiter. Other routes are not limited.
+//
+// The ip address is the remote address of the request. If the request comes
+// from a trusted proxy, the last address in the header X-Forwarded-For, that
+// is not a trusted proxy, is used. Anonymous users are only limited by their ip
+// address. A rejected request gets the status code 429.
+func ConnectLimit(auth Authenticater, limiter *RateLimiter) Authenticater {
+	return &connectLimit{
+		Authenticater: auth,
+		limiter:       limiter,
+	}
+}
+
+type connectLimit struct {
+	Authenticater
+	limiter *RateLimiter
+}
+
+// Authenticate authenticates the request and checks the limits afterwards.
+func (c *connectLimit) Authenticate(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+	ctx, err := c.Authenticater.Authenticate(w, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.URL.Path != prefix && r.URL.Path != prefix+"/keys" {
+		return ctx, nil
+	}
+
+	now := time.Now()
+	if ok, wait := c.limiter.allow("ip/"+c.limiter.clientIP(r), now); !ok {
+		atomic.AddUint64(&c.limiter.limitedIP, 1)
+		return nil, rateLimitError(w, wait)
+	}
+
+	if uid := c.Authenticater.FromContext(ctx); uid != 0 {
+		if ok, wait := c.limiter.allow("user/"+strconv.Itoa(uid), now); !ok {
+			atomic.AddUint64(&c.limiter.limitedUser, 1)
+			return nil, rateLimitError(w, wait)
+		}
+	}
+
+	return ctx, nil
+}
+
+// rateLimitError sets the header Retry-After and returns the error for a
+// rejected connection.
+func rateLimitError(w http.ResponseWriter, wait time.Duration) error {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	return tooManyRequestsError{}
+}
+
+// clientIP returns the ip address of the client.
+//
+// The header X-Forwarded-For can be set by any client. So it is only read, if
+// the request comes from a trusted proxy. Each proxy appends the address, it
+// got the request from. The addresses are read from the end until an address
+// is found, that is not a trusted proxy.
+func (l *RateLimiter) clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	if !l.trusted(ip) {
+		return ip
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr := strings.TrimSpace(forwarded[i])
+		if addr == "" {
+			continue
+		}
+
+		ip = addr
+		if !l.trusted(ip) {
+			break
+		}
+	}
+	return ip
+}
+
+// trusted returns true, if the ip address belongs to a trusted proxy.
+func (l *RateLimiter) trusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	for _, proxy := range l.proxies {
+		if proxy.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
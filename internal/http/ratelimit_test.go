@@ -0,0 +1,125 @@
+package http_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ahttp "github.com/OpenSlides/openslides-autoupdate-service/internal/http"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+)
+
+func TestConnectLimit(t *testing.T) {
+	connect := func(mux *http.ServeMux, url, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("ip", func(t *testing.T) {
+		limiter := ahttp.NewRateLimiter(0.001, 2)
+		mux := http.NewServeMux()
+		ahttp.Simple(mux, ahttp.ConnectLimit(test.Auth(0), limiter), &liverMock{content: strings.NewReader("")})
+
+		for i := 0; i < 2; i++ {
+			if rec := connect(mux, "/system/autoupdate/keys?user/1/name", "1.2.3.4"); rec.Code != 200 {
+				t.Fatalf("Connection %d got status %d, expected 200", i, rec.Code)
+			}
+		}
+
+		rec := connect(mux, "/system/autoupdate/keys?user/1/name", "1.2.3.4")
+		if rec.Code != http.StatusTooManyRequests {
+			t.Errorf("Got status %d, expected 429", rec.Code)
+		}
+		if rec.Header().Get("Retry-After") == "" {
+			t.Errorf("Response has no header Retry-After")
+		}
+
+		if rec := connect(mux, "/system/autoupdate/keys?user/1/name", "5.6.7.8"); rec.Code != 200 {
+			t.Errorf("Other ip got status %d, expected 200", rec.Code)
+		}
+
+		if got := limiter.Metrics()["connect_limited_ip"]; got != 1 {
+			t.Errorf("Got %d limited ips, expected 1", got)
+		}
+	})
+
+	t.Run("user", func(t *testing.T) {
+		limiter := ahttp.NewRateLimiter(0.001, 1)
+		mux := http.NewServeMux()
+		ahttp.Simple(mux, ahttp.ConnectLimit(test.Auth(1), limiter), &liverMock{content: strings.NewReader("")})
+
+		if rec := connect(mux, "/system/autoupdate/keys?user/1/name", "1.2.3.4"); rec.Code != 200 {
+			t.Fatalf("Got status %d, expected 200", rec.Code)
+		}
+
+		if rec := connect(mux, "/system/autoupdate/keys?user/1/name", "5.6.7.8"); rec.Code != http.StatusTooManyRequests {
+			t.Errorf("Same user from other ip got status %d, expected 429", rec.Code)
+		}
+
+		if got := limiter.Metrics()["connect_limited_user"]; got != 1 {
+			t.Errorf("Got %d limited users, expected 1", got)
+		}
+	})
+
+	t.Run("other routes", func(t *testing.T) {
+		limiter := ahttp.NewRateLimiter(0.001, 1)
+		mux := http.NewServeMux()
+		ahttp.Exists(mux, ahttp.ConnectLimit(test.Auth(1), limiter), visibilityMock{})
+
+		for i := 0; i < 3; i++ {
+			if rec := connect(mux, "/system/autoupdate/exists?motion/1", "1.2.3.4"); rec.Code != 200 {
+				t.Errorf("Request %d got status %d, expected 200", i, rec.Code)
+			}
+		}
+	})
+}
+
+func TestConnectLimitTrustedProxies(t *testing.T) {
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/8")
+
+	connect := func(mux *http.ServeMux, remote, forwarded string) int {
+		req := httptest.NewRequest("GET", "/system/autoupdate/keys?user/1/name", nil)
+		req.RemoteAddr = remote + ":1234"
+		req.Header.Set("X-Forwarded-For", forwarded)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Run("untrusted client", func(t *testing.T) {
+		limiter := ahttp.NewRateLimiter(0.001, 1, proxies)
+		mux := http.NewServeMux()
+		ahttp.Simple(mux, ahttp.ConnectLimit(test.Auth(0), limiter), &liverMock{content: strings.NewReader("")})
+
+		if code := connect(mux, "1.2.3.4", "5.5.5.1"); code != 200 {
+			t.Fatalf("Got status %d, expected 200", code)
+		}
+
+		if code := connect(mux, "1.2.3.4", "5.5.5.2"); code != http.StatusTooManyRequests {
+			t.Errorf("Client with other X-Forwarded-For got status %d, expected 429", code)
+		}
+	})
+
+	t.Run("trusted proxy", func(t *testing.T) {
+		limiter := ahttp.NewRateLimiter(0.001, 1, proxies)
+		mux := http.NewServeMux()
+		ahttp.Simple(mux, ahttp.ConnectLimit(test.Auth(0), limiter), &liverMock{content: strings.NewReader("")})
+
+		if code := connect(mux, "10.0.0.1", "9.9.9.9, 1.2.3.4, 10.0.0.2"); code != 200 {
+			t.Fatalf("Got status %d, expected 200", code)
+		}
+
+		if code := connect(mux, "10.0.0.1", "1.2.3.4"); code != http.StatusTooManyRequests {
+			t.Errorf("Same client behind the proxy got status %d, expected 429", code)
+		}
+
+		if code := connect(mux, "10.0.0.1", "5.6.7.8"); code != 200 {
+			t.Errorf("Other client behind the proxy got status %d, expected 200", code)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package projector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
+)
+
+// Block hides the keys, that are removed by the Blocked restricter, from the
+// slides. So the content of a projection does not contain blocked fields and a
+// projection of a blocked collection has no content.
+func (s *SlideStore) Block(b *restrict.Blocked) {
+	s.blocked = b
+}
+
+// blockedDatastore is a Datastore, that returns blocked keys as nil.
+type blockedDatastore struct {
+	Datastore
+	blocked *restrict.Blocked
+}
+
+// Get fetches the keys from the datastore and removes the blocked values.
+func (d blockedDatastore) Get(ctx context.Context, keys ...string) ([]json.RawMessage, error) {
+	values, err := d.Datastore.Get(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]json.RawMessage, len(keys))
+	for i, key := range keys {
+		data[key] = values[i]
+	}
+
+	if err := d.blocked.Filter(data, nil); err != nil {
+		return nil, fmt.Errorf("removing blocked keys: %w", err)
+	}
+
+	filtered := make([]json.RawMessage, len(keys))
+	for i, key := range keys {
+		filtered[i] = data[key]
+	}
+	return filtered, nil
+}
+
+// TemplateField fetches the template field with the filtered Get.
+func (d blockedDatastore) TemplateField(ctx context.Context, fqid, field, replacement string, value interface{}) ([]string, error) {
+	return datastore.TemplateField(ctx, d, fqid, field, replacement, value)
+}
@@ -0,0 +1,71 @@
+package projector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
+)
+
+// ChyronField is the calculated field with the chyron of a projector.
+//
+// It contains the name and structure level of the current speaker and the
+// chyron colors of the projector. Streaming overlays can subscribe to this
+// one key instead of a full projection.
+const ChyronField = "projector/chyron"
+
+// ChyronSlide is the name of the slide, that renders ChyronField. The slide
+// gets a Projection, where ProjectorID returns the id of the projector.
+const ChyronSlide = "current_speaker_chyron"
+
+// registerChyron adds the calculated field ChyronField to the datastore.
+func registerChyron(ds Datastore, slides *SlideStore) {
+	datastore.RegisterCalculated(ds, ChyronField, func(ctx context.Context, fqfield string) ([]byte, []string, error) {
+		parts := strings.SplitN(fqfield, "/", 3)
+		if len(parts) != 3 {
+			return nil, nil, fmt.Errorf("invalid key %s, expected two '/'", fqfield)
+		}
+
+		projectorID, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid projector id in key %s: %w", fqfield, err)
+		}
+
+		deps := []string{parts[0] + "/" + parts[1] + "/meeting_id"}
+		values, err := ds.Get(ctx, deps...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("fetching projector %d: %w", projectorID, err)
+		}
+
+		if values[0] == nil {
+			return nil, deps, nil
+		}
+
+		var meetingID int
+		if err := json.Unmarshal(values[0], &meetingID); err != nil {
+			return nil, nil, fmt.Errorf("decoding %s: %w", deps[0], err)
+		}
+
+		slider := slides.Get(ChyronSlide)
+		if slider == nil {
+			return nil, nil, fmt.Errorf("unknown slide %s", ChyronSlide)
+		}
+
+		p7on := Projection{Type: ChyronSlide, MeetingID: meetingID, projectorID: projectorID}
+		bs, keys, err := Render(ctx, ds, slider, &p7on)
+		if err != nil {
+			return nil, nil, fmt.Errorf("calculating chyron: %w", err)
+		}
+		return bs, append(deps, keys...), nil
+	})
+}
+
+// ChyronChecker returns a restrict.Checker for ChyronField. A user can see the
+// chyron, if the user can see the projector.
+func ChyronChecker(permer restrict.Permissioner) restrict.Checker {
+	return fieldChecker(permer, "id")
+}
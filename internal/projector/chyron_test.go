@@ -0,0 +1,27 @@
+package projector_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/projector"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChyron(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, map[string]string{
+		"projector/1/meeting_id": "5",
+	})
+	projector.Register(ds, testSlides())
+
+	fields, err := ds.Get(context.Background(), "projector/1/chyron", "projector/2/chyron")
+	require.NoError(t, err, "Get returned unexpected error")
+
+	assert.JSONEq(t, `{"projector":1,"meeting":5}`, string(fields[0]))
+	assert.Nil(t, fields[1], "chyron of a projector that does not exist")
+}
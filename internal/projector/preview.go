@@ -0,0 +1,97 @@
+package projector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
+)
+
+// PreviewField is the calculated field with the rendered content of the
+// queued projections of a projector.
+//
+// The value is a list of objects with the fields `projection_id` and
+// `content` in the order of projector/preview_projection_ids. So an operator
+// can see the previews without requesting each projection. If a projection
+// can not be rendered, its object has the field `error` instead of `content`.
+const PreviewField = "projector/preview_content"
+
+// previewSourceField is the field of the projector with the queued
+// projections.
+const previewSourceField = "preview_projection_ids"
+
+// preview is one entry of PreviewField.
+type preview struct {
+	ProjectionID int             `json:"projection_id"`
+	Content      json.RawMessage `json:"content,omitempty"`
+	Error        string          `json:"error,omitempty"`
+}
+
+// projectionFields are the fields of a projection, that decide how it is
+// rendered.
+var projectionFields = []string{"type", "content_object_id", "meeting_id", "options"}
+
+// registerPreview adds the calculated field PreviewField to the datastore.
+func registerPreview(ds Datastore, slides *SlideStore) {
+	datastore.RegisterCalculated(ds, PreviewField, func(ctx context.Context, fqfield string) ([]byte, []string, error) {
+		parts := strings.SplitN(fqfield, "/", 3)
+		if len(parts) != 3 {
+			return nil, nil, fmt.Errorf("invalid key %s, expected two '/'", fqfield)
+		}
+		fqid := parts[0] + "/" + parts[1]
+
+		deps := []string{fqid + "/id", fqid + "/" + previewSourceField}
+		values, err := ds.Get(ctx, deps...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("fetching %s: %w", fqid, err)
+		}
+
+		if values[0] == nil {
+			return nil, deps, nil
+		}
+
+		var ids []int
+		if values[1] != nil {
+			if err := json.Unmarshal(values[1], &ids); err != nil {
+				return nil, nil, fmt.Errorf("decoding %s: %w", deps[1], err)
+			}
+		}
+
+		previews := make([]preview, 0, len(ids))
+		for _, id := range ids {
+			projectionFQID := fmt.Sprintf("projection/%d", id)
+			bs, keys, err := content(ctx, ds, slides, projectionFQID)
+			if err != nil {
+				// One broken projection should not hide the other previews.
+				// The preview is calculated again, when the projection
+				// changes.
+				previews = append(previews, preview{ProjectionID: id, Error: err.Error()})
+				for _, field := range projectionFields {
+					deps = append(deps, projectionFQID+"/"+field)
+				}
+				continue
+			}
+			deps = append(deps, keys...)
+
+			if bs == nil {
+				continue
+			}
+			previews = append(previews, preview{ProjectionID: id, Content: bs})
+		}
+
+		bs, err := json.Marshal(previews)
+		if err != nil {
+			return nil, nil, fmt.Errorf("encoding previews: %w", err)
+		}
+		return bs, deps, nil
+	})
+}
+
+// PreviewChecker returns a restrict.Checker for PreviewField. A user can see
+// the previews, if the user can see the field projector/preview_projection_ids.
+func PreviewChecker(permer restrict.Permissioner) restrict.Checker {
+	return fieldChecker(permer, previewSourceField)
+}
@@ -0,0 +1,105 @@
+package projector_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/projector"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreview(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+	projector/1:
+		id: 1
+		preview_projection_ids: [2, 1, 3]
+	projection:
+		1:
+			type: test1
+		2:
+			content_object_id: test_model/1
+		4:
+			type: unknown_slide
+	projector/2/id: 2
+	projector/4:
+		id: 4
+		preview_projection_ids: [4, 1]
+	`))
+	projector.Register(ds, testSlides())
+
+	t.Run("previews", func(t *testing.T) {
+		fields, err := ds.Get(context.Background(), "projector/1/preview_content")
+		require.NoError(t, err, "Get returned unexpected error")
+
+		expect := `[
+			{"projection_id": 2, "content": "test_model"},
+			{"projection_id": 1, "content": "abc"}
+		]`
+		assert.JSONEq(t, expect, string(fields[0]))
+	})
+
+	t.Run("broken projection", func(t *testing.T) {
+		fields, err := ds.Get(context.Background(), "projector/4/preview_content")
+		require.NoError(t, err, "Get returned unexpected error")
+
+		expect := `[
+			{"projection_id": 4, "error": "unknown slide unknown_slide"},
+			{"projection_id": 1, "content": "abc"}
+		]`
+		assert.JSONEq(t, expect, string(fields[0]))
+	})
+
+	t.Run("no previews", func(t *testing.T) {
+		fields, err := ds.Get(context.Background(), "projector/2/preview_content")
+		require.NoError(t, err, "Get returned unexpected error")
+		assert.JSONEq(t, `[]`, string(fields[0]))
+	})
+
+	t.Run("projector does not exist", func(t *testing.T) {
+		fields, err := ds.Get(context.Background(), "projector/3/preview_content")
+		require.NoError(t, err, "Get returned unexpected error")
+		assert.Nil(t, fields[0])
+	})
+
+	t.Run("update of a projection", func(t *testing.T) {
+		// Register a listener that tells, when cache is updated.
+		done := make(chan struct{})
+		ds.RegisterChangeListener(func(data map[string]json.RawMessage) error {
+			close(done)
+			return nil
+		})
+
+		ds.Send(map[string]string{"test_model/1/field": `"new"`})
+		<-done
+
+		fields, err := ds.Get(context.Background(), "projector/1/preview_content")
+		require.NoError(t, err, "Get returned unexpected error")
+
+		expect := `[
+			{"projection_id": 2, "content": "calculated with new"},
+			{"projection_id": 1, "content": "abc"}
+		]`
+		assert.JSONEq(t, expect, string(fields[0]))
+	})
+}
+
+func TestPreviewChecker(t *testing.T) {
+	perms := &test.MockPermission{Data: map[string]bool{"projector/1/preview_projection_ids": true}}
+	checker := projector.PreviewChecker(perms)
+	value := []byte(`[{"projection_id":1,"content":"abc"}]`)
+
+	got, err := checker.Check(context.Background(), 1, "projector/1/preview_content", value)
+	require.NoError(t, err)
+	assert.Equal(t, string(value), string(got), "allowed preview")
+
+	got, err = checker.Check(context.Background(), 1, "projector/2/preview_content", value)
+	require.NoError(t, err)
+	assert.Nil(t, got, "forbidden preview")
+}
@@ -7,68 +7,70 @@ import (
 	"strings"
 
 	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
 )
 
 // Datastore gets values for keys and informs, if they change.
 type Datastore interface {
 	Get(ctx context.Context, keys ...string) ([]json.RawMessage, error)
+
+	// TemplateField decodes the value of a template field for one
+	// replacement, for example `structure_level_$` for a meeting id.
+	TemplateField(ctx context.Context, fqid, field, replacement string, value interface{}) ([]string, error)
+
 	RegisterCalculatedField(field string, f func(ctx context.Context, key string, changed map[string]json.RawMessage) ([]byte, error))
 }
 
 // Register initializes a new projector.
+//
+// The calculated fields are registered on ds. If the slides have blocked
+// keys, the slides get the data without them.
 func Register(ds Datastore, slides *SlideStore) {
-	hotKeys := make(map[string][]string)
-	ds.RegisterCalculatedField("projection/content", func(ctx context.Context, fqfield string, changed map[string]json.RawMessage) ([]byte, error) {
-		if changed != nil {
-			var needUpdate bool
-			for _, k := range hotKeys[fqfield] {
-				if _, ok := changed[k]; ok {
-					needUpdate = true
-					break
-				}
-			}
-			if !needUpdate {
-				old, err := ds.Get(ctx, fqfield)
-				if err != nil {
-					return nil, fmt.Errorf("getting old value: %w", err)
-				}
-				return old[0], nil
-			}
-		}
+	if slides.blocked != nil {
+		ds = blockedDatastore{Datastore: ds, blocked: slides.blocked}
+	}
 
+	datastore.RegisterCalculated(ds, "projection/content", func(ctx context.Context, fqfield string) ([]byte, []string, error) {
 		parts := strings.SplitN(fqfield, "/", 3)
 		if len(parts) != 3 {
-			return nil, fmt.Errorf("invalid key %s, expected two '/'", fqfield)
+			return nil, nil, fmt.Errorf("invalid key %s, expected two '/'", fqfield)
 		}
 
-		var p7on Projection
-		keys, err := datastore.Object(ctx, ds, parts[0]+"/"+parts[1], &p7on)
-		if err != nil {
-			return nil, fmt.Errorf("fetching projection %s from datastore: %w", parts[1], err)
-		}
+		return content(ctx, ds, slides, parts[0]+"/"+parts[1])
+	})
 
-		if !p7on.exists() {
-			return nil, nil
-		}
+	registerPreview(ds, slides)
+	registerChyron(ds, slides)
+}
 
-		slideName, err := p7on.slideName()
-		if err != nil {
-			return nil, fmt.Errorf("getting slide name: %w", err)
-		}
+// content renders the projection with the given fqid. It returns nil, if the
+// projection does not exist.
+func content(ctx context.Context, ds Datastore, slides *SlideStore, fqid string) ([]byte, []string, error) {
+	var p7on Projection
+	keys, err := datastore.Object(ctx, ds, fqid, &p7on)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching projection %s from datastore: %w", fqid, err)
+	}
 
-		slider := slides.Get(slideName)
-		if slider == nil {
-			return nil, fmt.Errorf("unknown slide %s", slideName)
-		}
+	if !p7on.exists() {
+		return nil, keys, nil
+	}
 
-		bs, slideKeys, err := slider.Slide(context.Background(), ds, &p7on)
-		if err != nil {
-			return nil, fmt.Errorf("calculating slide: %w", err)
-		}
-		keys = append(keys, slideKeys...)
-		hotKeys[fqfield] = keys
-		return bs, nil
-	})
+	slideName, err := p7on.slideName()
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting slide name: %w", err)
+	}
+
+	slider := slides.Get(slideName)
+	if slider == nil {
+		return nil, nil, fmt.Errorf("unknown slide %s", slideName)
+	}
+
+	bs, slideKeys, err := Render(context.Background(), ds, slider, &p7on)
+	if err != nil {
+		return nil, nil, fmt.Errorf("calculating slide: %w", err)
+	}
+	return bs, append(keys, slideKeys...), nil
 }
 
 // Projection holds the meta data to render a projection on a projecter.
@@ -77,6 +79,19 @@ type Projection struct {
 	Type            string `json:"type"`
 	ContentObjectID string `json:"content_object_id"`
 	MeetingID       int    `json:"meeting_id"`
+
+	// Options are slide specific settings of the projection.
+	Options json.RawMessage `json:"options,omitempty"`
+
+	// projectorID is only set, if a slide is rendered for a calculated field
+	// of a projector instead of a projection.
+	projectorID int
+}
+
+// ProjectorID returns the id of the projector, if the slide is rendered for a
+// calculated field of a projector. For a projection, it returns 0.
+func (p *Projection) ProjectorID() int {
+	return p.projectorID
 }
 
 func (p *Projection) exists() bool {
@@ -93,3 +108,26 @@ func (p *Projection) slideName() (string, error) {
 	}
 	return p.ContentObjectID[:i], nil
 }
+
+// fieldChecker returns a restrict.Checker for a calculated field. A user can
+// see the calculated field, if the user can see the given field of the same
+// object.
+func fieldChecker(permer restrict.Permissioner, field string) restrict.Checker {
+	return restrict.CheckerFunc(func(ctx context.Context, uid int, key string, value json.RawMessage) (json.RawMessage, error) {
+		parts := strings.SplitN(key, "/", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid key %s, expected two '/'", key)
+		}
+
+		sourceKey := parts[0] + "/" + parts[1] + "/" + field
+		allowed, err := permer.RestrictFQFields(ctx, uid, []string{sourceKey})
+		if err != nil {
+			return nil, fmt.Errorf("check permission of %s: %w", sourceKey, err)
+		}
+
+		if !allowed[sourceKey] {
+			return nil, nil
+		}
+		return value, nil
+	})
+}
@@ -3,11 +3,14 @@ package projector_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/OpenSlides/openslides-autoupdate-service/internal/projector"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
 	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -179,19 +182,101 @@ func TestProjectionUpdateOtherKey(t *testing.T) {
 
 func testSlides() *projector.SlideStore {
 	s := new(projector.SlideStore)
-	s.AddFunc("test1", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, keys []string, err error) {
-		return []byte(`"abc"`), nil, nil
+	s.AddFunc("test1", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, err error) {
+		return []byte(`"abc"`), nil
 	})
-	s.AddFunc("test_model", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, keys []string, err error) {
-		field, err := ds.Get(ctx, "test_model/1/field")
-		if field[0] == nil {
-			return []byte(`"test_model"`), []string{"test_model/1/field"}, nil
+	s.AddFunc("test_model", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, err error) {
+		fetch := datastore.NewFetcher(ds)
+		field := fetch.String(ctx, "test_model/1/field")
+		if err := fetch.Error(); err != nil {
+			var errNotExist datastore.DoesNotExistError
+			if !errors.As(err, &errNotExist) {
+				return nil, err
+			}
+			return []byte(`"test_model"`), nil
 		}
-		return []byte(fmt.Sprintf(`"calculated with %s"`, string(field[0][1:len(field[0])-1]))), []string{"test_model/1/field"}, nil
+		return []byte(fmt.Sprintf(`"calculated with %s"`, field)), nil
 	})
-	s.AddFunc("projection", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, keys []string, err error) {
-		bs, err := json.Marshal(p7on)
-		return bs, nil, err
+	s.AddFunc(projector.ChyronSlide, func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, err error) {
+		return []byte(fmt.Sprintf(`{"projector":%d,"meeting":%d}`, p7on.ProjectorID(), p7on.MeetingID)), nil
+	})
+	s.AddFunc("projection", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, err error) {
+		return json.Marshal(p7on)
 	})
 	return s
 }
+
+func TestProjectionFeatureDisabled(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, map[string]string{
+		"projection/1/type": `"poll"`,
+		"projection/2/type": `"test1"`,
+	})
+
+	slides := testSlides()
+	slides.AddFeature("polls", func(s *projector.SlideStore) {
+		s.AddFunc("poll", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, err error) {
+			return []byte(`"poll"`), nil
+		})
+	})
+	slides.Disable("polls")
+	projector.Register(ds, slides)
+
+	assert.Equal(t, []string{"polls"}, slides.Features())
+
+	fields, err := ds.Get(context.Background(), "projection/1/content", "projection/2/content")
+	require.NoError(t, err, "Get returned unexpected error")
+	assert.JSONEq(t, `{"error":"feature disabled","feature":"polls"}`, string(fields[0]))
+	assert.JSONEq(t, `"abc"`, string(fields[1]))
+}
+
+func TestProjectionBlocked(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, map[string]string{
+		"projection/1/content_object_id": `"test_model/1"`,
+		"projection/2/content_object_id": `"chat_group/1"`,
+		"test_model/1/field":             `"secret"`,
+	})
+
+	slides := testSlides()
+	slides.Block(restrict.NewBlocked(nil, "test_model/field", "chat_group"))
+	projector.Register(ds, slides)
+
+	fields, err := ds.Get(context.Background(), "projection/1/content", "projection/2/content")
+	require.NoError(t, err, "Get returned unexpected error")
+	assert.JSONEq(t, `"test_model"`, string(fields[0]), "blocked field is rendered")
+	assert.Nil(t, fields[1], "projection of a blocked collection has content")
+}
+
+func TestRenderRecordsKeys(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+	user/1:
+		structure_level_$: ["1"]
+		structure_level_$1: Bern
+	`))
+
+	slider := projector.SliderFunc(func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) ([]byte, error) {
+		if _, err := ds.Get(ctx, "user/1/username", "user/1/username"); err != nil {
+			return nil, err
+		}
+
+		var level string
+		if _, err := ds.TemplateField(ctx, "user/1", "structure_level_$", "1", &level); err != nil {
+			return nil, err
+		}
+		return []byte(fmt.Sprintf(`"%s"`, level)), nil
+	})
+
+	bs, keys, err := projector.Render(context.Background(), ds, slider, &projector.Projection{})
+	require.NoError(t, err)
+
+	assert.Equal(t, `"Bern"`, string(bs))
+	assert.ElementsMatch(t, []string{"user/1/username", "user/1/structure_level_$", "user/1/structure_level_$1"}, keys)
+}
@@ -0,0 +1,108 @@
+package projector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
+)
+
+// ServerTimeField is the calculated field with the time of the server as unix
+// time in milliseconds.
+//
+// The value is updated periodically, so projector screens can compare it with
+// their own clock. Countdown slides use the difference to show the same time
+// on every screen. Only the clients, that request the field, get the updates.
+const ServerTimeField = "projector/server_time"
+
+// ServerTimeDatastore can register calculated fields and update them, when the
+// time changed.
+type ServerTimeDatastore interface {
+	datastore.CalculatedRegisterer
+	UpdateCalculatedField(field string)
+}
+
+// clock holds the time of the last tick. The calculated field returns the
+// same value until the next tick, so datastore updates do not change it.
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+func (c *clock) millis() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now.UnixNano() / int64(time.Millisecond)
+}
+
+// ServerTime updates the calculated field ServerTimeField. It has to be
+// created with RegisterServerTime.
+type ServerTime struct {
+	ds    ServerTimeDatastore
+	clock *clock
+}
+
+// RegisterServerTime adds the calculated field ServerTimeField to the
+// datastore. The field is not updated until Run is called.
+func RegisterServerTime(ds ServerTimeDatastore) *ServerTime {
+	c := &clock{now: time.Now()}
+
+	datastore.RegisterCalculated(ds, ServerTimeField, func(ctx context.Context, fqfield string) ([]byte, []string, error) {
+		parts := strings.SplitN(fqfield, "/", 3)
+		if len(parts) != 3 {
+			return nil, nil, fmt.Errorf("invalid key %s, expected two '/'", fqfield)
+		}
+
+		deps := []string{parts[0] + "/" + parts[1] + "/id"}
+		values, err := ds.Get(ctx, deps...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("fetching %s: %w", deps[0], err)
+		}
+
+		if values[0] == nil {
+			return nil, deps, nil
+		}
+
+		return []byte(strconv.FormatInt(c.millis(), 10)), deps, nil
+	})
+
+	return &ServerTime{ds: ds, clock: c}
+}
+
+// Run updates the clock and the calculated keys every interval. It blocks
+// until closed is closed.
+//
+// It should be called after all fields and change listeners are registered.
+// The update only calculates the requested keys again. It does not send
+// requests to the datastore.
+func (s *ServerTime) Run(interval time.Duration, closed <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case now := <-ticker.C:
+			s.clock.set(now)
+			s.ds.UpdateCalculatedField(ServerTimeField)
+		}
+	}
+}
+
+// ServerTimeChecker returns a restrict.Checker for ServerTimeField. A user can
+// see the server time, if the user can see the projector.
+func ServerTimeChecker(permer restrict.Permissioner) restrict.Checker {
+	return fieldChecker(permer, "id")
+}
@@ -0,0 +1,51 @@
+package projector_test
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/projector"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerTime(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, map[string]string{
+		"projector/1/id": "1",
+	})
+	serverTime := projector.RegisterServerTime(ds)
+
+	fields, err := ds.Get(context.Background(), "projector/1/server_time", "projector/2/server_time")
+	require.NoError(t, err, "Get returned unexpected error")
+	assert.Nil(t, fields[1], "server time of a projector that does not exist")
+
+	first, err := strconv.ParseInt(string(fields[0]), 10, 64)
+	require.NoError(t, err, "server time is not a number")
+
+	received := make(chan map[string]json.RawMessage, 1)
+	ds.RegisterChangeListener(func(data map[string]json.RawMessage) error {
+		select {
+		case received <- data:
+		default:
+		}
+		return nil
+	})
+	go serverTime.Run(10*time.Millisecond, closed)
+
+	select {
+	case data := <-received:
+		next, err := strconv.ParseInt(string(data["projector/1/server_time"]), 10, 64)
+		require.NoError(t, err, "server time is not a number")
+		assert.Greater(t, next, first)
+		assert.NotContains(t, data, "projector/2/server_time")
+	case <-time.After(time.Second):
+		t.Fatalf("No update of the server time")
+	}
+}
@@ -8,14 +8,14 @@ import (
 
 // AgendaItem renders the agenda_item slide.
 func AgendaItem(store *projector.SlideStore) {
-	store.AddFunc("agenda_item", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, keys []string, err error) {
-		return []byte(`"TODO"`), nil, nil
+	store.AddFunc("agenda_item", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, err error) {
+		return []byte(`"TODO"`), nil
 	})
 }
 
 // AgendaItemList renders the agenda_item_list slide.
 func AgendaItemList(store *projector.SlideStore) {
-	store.AddFunc("agenda_item_list", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, keys []string, err error) {
-		return []byte(`"TODO"`), nil, nil
+	store.AddFunc("agenda_item_list", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, err error) {
+		return []byte(`"TODO"`), nil
 	})
 }
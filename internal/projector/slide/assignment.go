@@ -8,7 +8,7 @@ import (
 
 // Assignment renders the assignment slide.
 func Assignment(store *projector.SlideStore) {
-	store.AddFunc("assignment", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, keys []string, err error) {
-		return []byte(`"TODO"`), nil, nil
+	store.AddFunc("assignment", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, err error) {
+		return []byte(`"TODO"`), nil
 	})
 }
@@ -35,12 +35,12 @@ type dbSpeaker struct {
 
 // ListOfSpeaker renders current list of speaker slide.
 func ListOfSpeaker(store *projector.SlideStore) {
-	store.AddFunc("list_of_speakers", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, hotkeys []string, err error) {
+	store.AddFunc("list_of_speakers", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, err error) {
 		return renderListOfSpeakers(ctx, ds, p7on.ContentObjectID, p7on.MeetingID)
 	})
 }
 
-func renderListOfSpeakers(ctx context.Context, ds projector.Datastore, losFQID string, meetingID int) (encoded []byte, keys []string, err error) {
+func renderListOfSpeakers(ctx context.Context, ds projector.Datastore, losFQID string, meetingID int) (encoded []byte, err error) {
 	fetch := datastore.NewFetcher(ds)
 	defer func() {
 		if err == nil {
@@ -61,9 +61,10 @@ func renderListOfSpeakers(ctx context.Context, ds projector.Datastore, losFQID s
 
 		var user dbUser
 		fetch.Object(ctx, &user, "user/%d", speaker.UserID)
+		user.loadMeeting(ctx, fetch, fmt.Sprintf("user/%d", speaker.UserID), meetingID)
 
 		s := outputSpeaker{
-			User:         user.String(meetingID),
+			User:         user.String(),
 			Marked:       speaker.Marked,
 			PointOfOrder: speaker.PointOfOrder,
 			Weight:       speaker.Weight,
@@ -84,7 +85,7 @@ func renderListOfSpeakers(ctx context.Context, ds projector.Datastore, losFQID s
 	}
 
 	if err := fetch.Error(); err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	idx := strings.Index(los.ContentObjectID, "/")
@@ -109,14 +110,14 @@ func renderListOfSpeakers(ctx context.Context, ds projector.Datastore, losFQID s
 	}
 	b, err := json.Marshal(slideData)
 	if err != nil {
-		return nil, nil, fmt.Errorf("encoding outgoing data: %w", err)
+		return nil, fmt.Errorf("encoding outgoing data: %w", err)
 	}
-	return b, fetch.Keys(), nil
+	return b, nil
 }
 
 // CurrentListOfSpeakers renders the current_list_of_speakers slide.
 func CurrentListOfSpeakers(store *projector.SlideStore) {
-	store.AddFunc("current_list_of_speakers", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, keys []string, err error) {
+	store.AddFunc("current_list_of_speakers", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, err error) {
 		fetch := datastore.NewFetcher(ds)
 		defer func() {
 			if err == nil {
@@ -126,38 +127,141 @@ func CurrentListOfSpeakers(store *projector.SlideStore) {
 
 		projectorID := fetch.Int(ctx, "projection/%d/current_projector_id", p7on.ID)
 		meetingID := fetch.Int(ctx, "projector/%d/meeting_id", projectorID)
-		referenceProjectorID := fetch.Int(ctx, "meeting/%d/reference_projector_id", meetingID)
-		referenceP7onIDs := fetch.Ints(ctx, "projector/%d/current_projection_ids", referenceProjectorID)
-
-		var losID int
-		for _, pID := range referenceP7onIDs {
-			contentObjectID := fetch.String(ctx, "projection/%d/content_object_id", pID)
-			losID = fetch.Int(ctx, "%s/list_of_speakers_id", contentObjectID)
-
-			if losID != 0 {
-				break
-			}
-		}
+		losID := currentListOfSpeakersID(ctx, fetch.Value, meetingID)
 		if losID == 0 {
-			return []byte("{}"), fetch.Keys(), nil
+			return []byte("{}"), nil
 		}
 
 		if err := fetch.Error(); err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 
-		content, keys, err := renderListOfSpeakers(ctx, ds, fmt.Sprintf("list_of_speakers/%d", losID), p7on.MeetingID)
+		content, err := renderListOfSpeakers(ctx, ds, fmt.Sprintf("list_of_speakers/%d", losID), p7on.MeetingID)
 		if err != nil {
-			return nil, nil, fmt.Errorf("render list of speakers %d: %w", losID, err)
+			return nil, fmt.Errorf("render list of speakers %d: %w", losID, err)
 		}
-		keys = append(keys, fetch.Keys()...)
-		return content, keys, nil
+		return content, nil
 	})
 }
 
+// valueFunc fetches a value from the datastore like datastore.Fetcher.Value.
+type valueFunc func(ctx context.Context, value interface{}, keyFmt string, a ...interface{})
+
+// currentListOfSpeakersID returns the id of the list of speakers of the first
+// projection on the reference projector of the meeting, that has one. It
+// returns 0, if there is no such projection.
+func currentListOfSpeakersID(ctx context.Context, value valueFunc, meetingID int) int {
+	var referenceProjectorID int
+	value(ctx, &referenceProjectorID, "meeting/%d/reference_projector_id", meetingID)
+
+	var referenceP7onIDs []int
+	value(ctx, &referenceP7onIDs, "projector/%d/current_projection_ids", referenceProjectorID)
+
+	for _, pID := range referenceP7onIDs {
+		var contentObjectID string
+		value(ctx, &contentObjectID, "projection/%d/content_object_id", pID)
+		if contentObjectID == "" {
+			continue
+		}
+
+		var losID int
+		value(ctx, &losID, "%s/list_of_speakers_id", contentObjectID)
+		if losID != 0 {
+			return losID
+		}
+	}
+	return 0
+}
+
 // CurrentSpeakerChyron renders the current_speaker_chyron slide.
+//
+// It is used for projections and for the calculated field
+// projector.ChyronField. It contains the current speaker of the current list
+// of speakers and the chyron colors of the projector. Missing values are
+// empty.
 func CurrentSpeakerChyron(store *projector.SlideStore) {
-	store.AddFunc("current_speaker_chyron", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, keys []string, err error) {
-		return []byte(`"TODO"`), nil, nil
+	store.AddFunc(projector.ChyronSlide, func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, err error) {
+		optional := &optionalFetcher{ds: ds}
+
+		projectorID := p7on.ProjectorID()
+		if projectorID == 0 {
+			optional.Value(ctx, &projectorID, "projection/%d/current_projector_id", p7on.ID)
+		}
+
+		var chyron struct {
+			BackgroundColor string `json:"background_color"`
+			FontColor       string `json:"font_color"`
+			SpeakerName     string `json:"current_speaker_name,omitempty"`
+			SpeakerLevel    string `json:"current_speaker_level,omitempty"`
+		}
+		optional.Value(ctx, &chyron.BackgroundColor, "projector/%d/chyron_background_color", projectorID)
+		optional.Value(ctx, &chyron.FontColor, "projector/%d/chyron_font_color", projectorID)
+
+		var speakerIDs []int
+		if losID := currentListOfSpeakersID(ctx, optional.Value, p7on.MeetingID); losID != 0 {
+			optional.Value(ctx, &speakerIDs, "list_of_speakers/%d/speaker_ids", losID)
+		}
+
+		if optional.err != nil {
+			return nil, optional.err
+		}
+
+		fetch := datastore.NewFetcher(ds)
+		for _, id := range speakerIDs {
+			var speaker dbSpeaker
+			fetch.Object(ctx, &speaker, "speaker/%d", id)
+			if speaker.BeginTime == 0 || speaker.EndTime != 0 {
+				continue
+			}
+
+			var user dbUser
+			fetch.Object(ctx, &user, "user/%d", speaker.UserID)
+			user.loadMeeting(ctx, fetch, fmt.Sprintf("user/%d", speaker.UserID), p7on.MeetingID)
+
+			// The level is a field of its own, so the overlay can style it.
+			chyron.SpeakerName = user.shortName("")
+			chyron.SpeakerLevel = user.structureLevel()
+			break
+		}
+
+		if err := fetch.Error(); err != nil {
+			return nil, err
+		}
+
+		b, err := json.Marshal(chyron)
+		if err != nil {
+			return nil, fmt.Errorf("encoding chyron: %w", err)
+		}
+		return b, nil
 	})
 }
+
+// optionalFetcher fetches values like datastore.Fetcher. Keys, that do not
+// exist, are the zero value instead of an error.
+type optionalFetcher struct {
+	ds  projector.Datastore
+	err error
+}
+
+// Value fetches a value from the datastore.
+func (f *optionalFetcher) Value(ctx context.Context, value interface{}, keyFmt string, a ...interface{}) {
+	if f.err != nil {
+		return
+	}
+
+	key := fmt.Sprintf(keyFmt, a...)
+
+	values, err := f.ds.Get(ctx, key)
+	if err != nil {
+		f.err = fmt.Errorf("fetching %s: %w", key, err)
+		return
+	}
+
+	if values[0] == nil {
+		return
+	}
+
+	if err := json.Unmarshal(values[0], value); err != nil {
+		f.err = fmt.Errorf("decoding %s: %w", key, err)
+	}
+}
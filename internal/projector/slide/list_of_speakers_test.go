@@ -2,11 +2,14 @@ package slide_test
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"testing"
 
 	"github.com/OpenSlides/openslides-autoupdate-service/internal/projector"
 	"github.com/OpenSlides/openslides-autoupdate-service/internal/projector/slide"
 	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/testdata"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -123,16 +126,19 @@ func TestListOfSpeakers(t *testing.T) {
 				"user/10/first_name",
 				"user/10/last_name",
 				"user/10/structure_level_$",
+				"user/10/meeting_user_ids",
 				"user/20/username",
 				"user/20/title",
 				"user/20/first_name",
 				"user/20/last_name",
 				"user/20/structure_level_$",
+				"user/20/meeting_user_ids",
 				"user/30/username",
 				"user/30/title",
 				"user/30/first_name",
 				"user/30/last_name",
 				"user/30/structure_level_$",
+				"user/30/meeting_user_ids",
 			},
 		},
 		{
@@ -183,11 +189,13 @@ func TestListOfSpeakers(t *testing.T) {
 				"user/10/first_name",
 				"user/10/last_name",
 				"user/10/structure_level_$",
+				"user/10/meeting_user_ids",
 				"user/30/username",
 				"user/30/title",
 				"user/30/first_name",
 				"user/30/last_name",
 				"user/30/structure_level_$",
+				"user/30/meeting_user_ids",
 			},
 		},
 	} {
@@ -200,7 +208,7 @@ func TestListOfSpeakers(t *testing.T) {
 				ContentObjectID: "list_of_speakers/1",
 			}
 
-			bs, keys, err := losSlide.Slide(context.Background(), ds, p7on)
+			bs, keys, err := projector.Render(context.Background(), ds, losSlide, p7on)
 			assert.NoError(t, err)
 			assert.JSONEq(t, tt.expect, string(bs))
 			assert.ElementsMatch(t, tt.expectKeys, keys)
@@ -208,6 +216,37 @@ func TestListOfSpeakers(t *testing.T) {
 	}
 }
 
+func TestListOfSpeakersMeetingData(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	s := new(projector.SlideStore)
+	slide.ListOfSpeaker(s)
+	losSlide := s.Get("list_of_speakers")
+
+	ds := testdata.MeetingDatastore(closed)
+
+	// Every list of speakers would take to long with the race detector.
+	for id := 1; id <= testdata.MotionCount; id += 20 {
+		p7on := &projector.Projection{
+			ContentObjectID: fmt.Sprintf("list_of_speakers/%d", id),
+		}
+
+		bs, _, err := projector.Render(context.Background(), ds, losSlide, p7on)
+		require.NoError(t, err, "list_of_speakers/%d", id)
+
+		var got struct {
+			Title    string            `json:"title"`
+			Waiting  []json.RawMessage `json:"waiting"`
+			Finished []json.RawMessage `json:"finished"`
+		}
+		require.NoError(t, json.Unmarshal(bs, &got))
+		assert.NotEmpty(t, got.Title, "list_of_speakers/%d has no title", id)
+		assert.Len(t, got.Waiting, 2, "list_of_speakers/%d", id)
+		assert.Len(t, got.Finished, 1, "list_of_speakers/%d", id)
+	}
+}
+
 func TestCurrentListOfSpeakers(t *testing.T) {
 	closed := make(chan struct{})
 	defer close(closed)
@@ -266,7 +305,7 @@ func TestCurrentListOfSpeakers(t *testing.T) {
 			Type:            "current_list_of_speakers",
 		}
 
-		bs, keys, err := slide.Slide(context.Background(), ds, p7on)
+		bs, keys, err := projector.Render(context.Background(), ds, slide, p7on)
 
 		assert.NoError(t, err)
 		expect := `{
@@ -307,11 +346,89 @@ func TestCurrentListOfSpeakers(t *testing.T) {
 			"user/10/first_name",
 			"user/10/last_name",
 			"user/10/structure_level_$",
+			"user/10/meeting_user_ids",
 		}
 		assert.ElementsMatch(t, expectKeys, keys)
 	})
 }
 
+func TestCurrentSpeakerChyron(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	s := new(projector.SlideStore)
+	slide.CurrentSpeakerChyron(s)
+
+	chyronSlide := s.Get(projector.ChyronSlide)
+	require.NotNilf(t, chyronSlide, "Slide with name `%s` not found.", projector.ChyronSlide)
+
+	data := dsmock.YAMLData(`
+	projection/1/current_projector_id: 50
+	projector/50:
+		meeting_id: 6
+		chyron_background_color: "#123456"
+		chyron_font_color: "#ffffff"
+	meeting/6/reference_projector_id: 60
+	projector/60/current_projection_ids: [2]
+	projection/2/content_object_id: topic/5
+	topic/5/list_of_speakers_id: 7
+	list_of_speakers/7/speaker_ids: [8, 9]
+
+	speaker/8:
+		user_id: 10
+		begin_time: 100
+		end_time: 200
+	speaker/9:
+		user_id: 11
+		begin_time: 300
+
+	user/11:
+		first_name: Jonny
+		last_name: Bo
+		meeting_user_ids: [3]
+	meeting_user/3:
+		meeting_id: 6
+		structure_level: Berlin
+	`)
+
+	for _, tt := range []struct {
+		name   string
+		data   map[string]string
+		expect string
+	}{
+		{
+			"current speaker",
+			data,
+			`{"background_color":"#123456","font_color":"#ffffff","current_speaker_name":"J. Bo","current_speaker_level":"Berlin"}`,
+		},
+		{
+			"no current speaker",
+			changeData(data, map[string]string{"speaker/9/end_time": "400"}),
+			`{"background_color":"#123456","font_color":"#ffffff"}`,
+		},
+		{
+			"no list of speakers",
+			changeData(data, map[string]string{"projector/60/current_projection_ids": "[]"}),
+			`{"background_color":"#123456","font_color":"#ffffff"}`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ds := dsmock.NewMockDatastore(closed, tt.data)
+
+			p7on := &projector.Projection{
+				ID:        1,
+				Type:      projector.ChyronSlide,
+				MeetingID: 6,
+			}
+
+			bs, keys, err := projector.Render(context.Background(), ds, chyronSlide, p7on)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expect, string(bs))
+			assert.Contains(t, keys, "projector/50/chyron_background_color")
+		})
+	}
+}
+
 func changeData(orig, change map[string]string) map[string]string {
 	out := make(map[string]string)
 	for k, v := range orig {
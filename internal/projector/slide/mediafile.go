@@ -8,7 +8,7 @@ import (
 
 // Mediafile renders the mediafile slide.
 func Mediafile(store *projector.SlideStore) {
-	store.AddFunc("mediafile", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, keys []string, err error) {
-		return []byte(`"TODO"`), nil, nil
+	store.AddFunc("mediafile", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, err error) {
+		return []byte(`"TODO"`), nil
 	})
 }
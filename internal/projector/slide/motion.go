@@ -8,14 +8,14 @@ import (
 
 // Motion renders the motion slide.
 func Motion(store *projector.SlideStore) {
-	store.AddFunc("motion", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, keys []string, err error) {
-		return []byte(`"TODO"`), nil, nil
+	store.AddFunc("motion", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, err error) {
+		return []byte(`"TODO"`), nil
 	})
 }
 
 // MotionBlock renders the motion_block slide.
 func MotionBlock(store *projector.SlideStore) {
-	store.AddFunc("motion_block", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, keys []string, err error) {
-		return []byte(`"TODO"`), nil, nil
+	store.AddFunc("motion_block", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, err error) {
+		return []byte(`"TODO"`), nil
 	})
 }
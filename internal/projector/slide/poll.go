@@ -2,13 +2,195 @@ package slide
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
 
 	"github.com/OpenSlides/openslides-autoupdate-service/internal/projector"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
 )
 
 // Poll renders the poll slide.
 func Poll(store *projector.SlideStore) {
-	store.AddFunc("poll", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, keys []string, err error) {
-		return []byte(`"TODO"`), nil, nil
+	store.AddFunc("poll", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, err error) {
+		return []byte(`"TODO"`), nil
 	})
 }
+
+// pollStatePublished is the value of poll/state, when the results can be
+// shown.
+const pollStatePublished = "published"
+
+// dbPoll is a poll with the fields needed for the result bars.
+type dbPoll struct {
+	Title           string `json:"title"`
+	ContentObjectID string `json:"content_object_id"`
+	State           string `json:"state"`
+	Pollmethod      string `json:"pollmethod"`
+	PercentBase     string `json:"onehundred_percent_base"`
+	OptionIDs       []int  `json:"option_ids"`
+	VotesValid      string `json:"votesvalid"`
+	VotesInvalid    string `json:"votesinvalid"`
+	VotesCast       string `json:"votescast"`
+}
+
+// dbOption is an option of a poll.
+type dbOption struct {
+	Yes     string `json:"yes"`
+	No      string `json:"no"`
+	Abstain string `json:"abstain"`
+}
+
+// outputBar is one segment of the result bar.
+type outputBar struct {
+	Name    string   `json:"name"`
+	Value   string   `json:"value"`
+	Percent *float64 `json:"percent,omitempty"`
+}
+
+// PollResultBars renders the poll_result_bars slide.
+//
+// It shows a published motion poll as bars for yes, no and abstain. The
+// percentages are calculated with poll/onehundred_percent_base and rounded to
+// three decimal places, so the client does not have to calculate them. A
+// value, that is not part of the percent base, has no percentage. Before the
+// poll is published, the slide contains no results.
+func PollResultBars(store *projector.SlideStore) {
+	store.AddFunc("poll_result_bars", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, err error) {
+		fetch := datastore.NewFetcher(ds)
+
+		var poll dbPoll
+		fetch.Object(ctx, &poll, p7on.ContentObjectID)
+		if err := fetch.Error(); err != nil {
+			return nil, fmt.Errorf("fetching poll: %w", err)
+		}
+
+		out := struct {
+			Title           string      `json:"title"`
+			ContentObjectID string      `json:"content_object_id"`
+			State           string      `json:"state"`
+			Pollmethod      string      `json:"pollmethod"`
+			PercentBase     string      `json:"onehundred_percent_base"`
+			Bars            []outputBar `json:"bars,omitempty"`
+			VotesValid      string      `json:"votesvalid,omitempty"`
+			VotesInvalid    string      `json:"votesinvalid,omitempty"`
+			VotesCast       string      `json:"votescast,omitempty"`
+		}{
+			Title:           poll.Title,
+			ContentObjectID: poll.ContentObjectID,
+			State:           poll.State,
+			Pollmethod:      poll.Pollmethod,
+			PercentBase:     poll.PercentBase,
+		}
+
+		if poll.State == pollStatePublished && len(poll.OptionIDs) > 0 {
+			var option dbOption
+			fetch.Object(ctx, &option, "option/%d", poll.OptionIDs[0])
+			if err := fetch.Error(); err != nil {
+				return nil, fmt.Errorf("fetching option: %w", err)
+			}
+
+			bars, err := pollBars(poll, option)
+			if err != nil {
+				return nil, fmt.Errorf("calculating bars: %w", err)
+			}
+
+			out.Bars = bars
+			out.VotesValid = poll.VotesValid
+			out.VotesInvalid = poll.VotesInvalid
+			out.VotesCast = poll.VotesCast
+		}
+
+		b, err := json.Marshal(out)
+		if err != nil {
+			return nil, fmt.Errorf("encoding outgoing data: %w", err)
+		}
+		return b, nil
+	})
+}
+
+// pollBars returns the bars of the option for the pollmethod of the poll.
+func pollBars(poll dbPoll, option dbOption) ([]outputBar, error) {
+	var bars []outputBar
+	switch poll.Pollmethod {
+	case "Y":
+		bars = []outputBar{{Name: "yes", Value: option.Yes}}
+	case "YN":
+		bars = []outputBar{{Name: "yes", Value: option.Yes}, {Name: "no", Value: option.No}}
+	default:
+		bars = []outputBar{{Name: "yes", Value: option.Yes}, {Name: "no", Value: option.No}, {Name: "abstain", Value: option.Abstain}}
+	}
+
+	values := make(map[string]float64, len(bars))
+	for _, bar := range bars {
+		v, err := decimal(bar.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", bar.Name, err)
+		}
+		values[bar.Name] = v
+	}
+
+	// inBase tells, which values are part of the percent base. With the
+	// bases valid and cast, base is the total number of votes.
+	var base float64
+	fixedBase := false
+	inBase := make(map[string]bool)
+	switch poll.PercentBase {
+	case "Y":
+		inBase["yes"] = true
+	case "YN":
+		inBase["yes"] = true
+		inBase["no"] = true
+	case "YNA":
+		inBase["yes"] = true
+		inBase["no"] = true
+		inBase["abstain"] = true
+	case "valid", "cast":
+		total := poll.VotesValid
+		if poll.PercentBase == "cast" {
+			total = poll.VotesCast
+		}
+
+		v, err := decimal(total)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for votes%s: %w", poll.PercentBase, err)
+		}
+		base = v
+		fixedBase = true
+		for name := range values {
+			inBase[name] = true
+		}
+	default:
+		// Percent base `disabled` or unknown: no percentages.
+		return bars, nil
+	}
+
+	if !fixedBase {
+		for name := range inBase {
+			base += values[name]
+		}
+	}
+
+	if base <= 0 {
+		return bars, nil
+	}
+
+	for i, bar := range bars {
+		if !inBase[bar.Name] {
+			continue
+		}
+		percent := math.Round(values[bar.Name]/base*100*1000) / 1000
+		bars[i].Percent = &percent
+	}
+	return bars, nil
+}
+
+// decimal parses a decimal value from the datastore, for example "5.000000".
+// An empty value is 0.
+func decimal(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
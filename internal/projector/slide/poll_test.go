@@ -0,0 +1,139 @@
+package slide_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/projector"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/projector/slide"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollResultBars(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	s := new(projector.SlideStore)
+	slide.PollResultBars(s)
+
+	barsSlide := s.Get("poll_result_bars")
+	require.NotNilf(t, barsSlide, "Slide with name `poll_result_bars` not found.")
+
+	data := dsmock.YAMLData(`
+	poll/1:
+		title: Vote
+		content_object_id: motion/5
+		state: published
+		pollmethod: YNA
+		onehundred_percent_base: YNA
+		option_ids: [3]
+		votesvalid: "7.000000"
+		votesinvalid: "1.000000"
+		votescast: "8.000000"
+	option/3:
+		yes: "4.000000"
+		no: "2.000000"
+		abstain: "1.000000"
+	`)
+
+	for _, tt := range []struct {
+		name   string
+		change map[string]string
+		expect string
+	}{
+		{
+			"YNA",
+			nil,
+			`[
+				{"name":"yes","value":"4.000000","percent":57.143},
+				{"name":"no","value":"2.000000","percent":28.571},
+				{"name":"abstain","value":"1.000000","percent":14.286}
+			]`,
+		},
+		{
+			"YN",
+			map[string]string{"poll/1/onehundred_percent_base": `"YN"`},
+			`[
+				{"name":"yes","value":"4.000000","percent":66.667},
+				{"name":"no","value":"2.000000","percent":33.333},
+				{"name":"abstain","value":"1.000000"}
+			]`,
+		},
+		{
+			"cast",
+			map[string]string{"poll/1/onehundred_percent_base": `"cast"`},
+			`[
+				{"name":"yes","value":"4.000000","percent":50},
+				{"name":"no","value":"2.000000","percent":25},
+				{"name":"abstain","value":"1.000000","percent":12.5}
+			]`,
+		},
+		{
+			"disabled",
+			map[string]string{"poll/1/onehundred_percent_base": `"disabled"`},
+			`[
+				{"name":"yes","value":"4.000000"},
+				{"name":"no","value":"2.000000"},
+				{"name":"abstain","value":"1.000000"}
+			]`,
+		},
+		{
+			"pollmethod YN",
+			map[string]string{"poll/1/pollmethod": `"YN"`},
+			`[
+				{"name":"yes","value":"4.000000","percent":66.667},
+				{"name":"no","value":"2.000000","percent":33.333}
+			]`,
+		},
+		{
+			"no votes",
+			map[string]string{"option/3/yes": `"0.000000"`, "option/3/no": `"0.000000"`, "option/3/abstain": `"0.000000"`},
+			`[
+				{"name":"yes","value":"0.000000"},
+				{"name":"no","value":"0.000000"},
+				{"name":"abstain","value":"0.000000"}
+			]`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ds := dsmock.NewMockDatastore(closed, changeData(data, tt.change))
+			p7on := &projector.Projection{
+				Type:            "poll_result_bars",
+				ContentObjectID: "poll/1",
+			}
+
+			bs, keys, err := projector.Render(context.Background(), ds, barsSlide, p7on)
+			require.NoError(t, err)
+
+			var got map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal(bs, &got))
+			assert.JSONEq(t, tt.expect, string(got["bars"]))
+			assert.JSONEq(t, `"8.000000"`, string(got["votescast"]))
+			assert.Contains(t, keys, "option/3/yes")
+		})
+	}
+
+	t.Run("not published", func(t *testing.T) {
+		ds := dsmock.NewMockDatastore(closed, changeData(data, map[string]string{"poll/1/state": `"finished"`}))
+		p7on := &projector.Projection{
+			Type:            "poll_result_bars",
+			ContentObjectID: "poll/1",
+		}
+
+		bs, keys, err := projector.Render(context.Background(), ds, barsSlide, p7on)
+		require.NoError(t, err)
+
+		expect := `{
+			"title": "Vote",
+			"content_object_id": "motion/5",
+			"state": "finished",
+			"pollmethod": "YNA",
+			"onehundred_percent_base": "YNA"
+		}`
+		assert.JSONEq(t, expect, string(bs))
+		assert.Contains(t, keys, "poll/1/state")
+	})
+}
@@ -2,22 +2,16 @@ package slide
 
 import "github.com/OpenSlides/openslides-autoupdate-service/internal/projector"
 
-// Slides returns all OpenSlides-Slides.
+// Slides returns all OpenSlides-Slides grouped by their feature.
 func Slides() *projector.SlideStore {
 	s := new(projector.SlideStore)
-	AgendaItem(s)
-	AgendaItemList(s)
-	Assignment(s)
-	ListOfSpeaker(s)
-	CurrentListOfSpeakers(s)
-	CurrentSpeakerChyron(s)
-	Mediafile(s)
-	Motion(s)
-	MotionBlock(s)
-	Poll(s)
-	ProjectorCountdown(s)
-	ProjectorMessage(s)
-	Topic(s)
-	User(s)
+	s.AddFeature("agenda", AgendaItem, AgendaItemList, Topic)
+	s.AddFeature("assignments", Assignment)
+	s.AddFeature("list_of_speakers", ListOfSpeaker, CurrentListOfSpeakers, CurrentSpeakerChyron)
+	s.AddFeature("mediafiles", Mediafile)
+	s.AddFeature("motions", Motion, MotionBlock)
+	s.AddFeature("polls", Poll, PollResultBars)
+	s.AddFeature("projector", ProjectorCountdown, ProjectorMessage)
+	s.AddFeature("users", User)
 	return s
 }
@@ -2,53 +2,167 @@ package slide
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/OpenSlides/openslides-autoupdate-service/internal/projector"
 	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
 )
 
 type dbUser struct {
-	Username  string         `json:"username"`
-	Title     string         `json:"title"`
-	FirstName string         `json:"first_name"`
-	LastName  string         `json:"last_name"`
-	Level     map[int]string `json:"structure_level_$"`
-}
-
-func (u dbUser) String(meetingID int) string {
-	parts := func(sp ...string) []string {
-		var full []string
-		for _, s := range sp {
-			if s == "" {
-				continue
+	Username       string `json:"username"`
+	Title          string `json:"title"`
+	FirstName      string `json:"first_name"`
+	LastName       string `json:"last_name"`
+	MeetingUserIDs []int  `json:"meeting_user_ids"`
+
+	meetingUser dbMeetingUser
+	level       string
+}
+
+// dbMeetingUser holds the data of a user that is specific for one meeting.
+type dbMeetingUser struct {
+	MeetingID      int    `json:"meeting_id"`
+	Number         string `json:"number"`
+	StructureLevel string `json:"structure_level"`
+}
+
+// loadMeeting fetches the data of the user with the given fqid, that is
+// specific for a meeting.
+//
+// This is the meeting_user object of the meeting. If the user has no
+// meeting_user for that meeting, it stays the zero value. The meeting ids of
+// all meeting_user objects of the user are fetched with one request, so only
+// the matching object has to be fetched.
+//
+// If the meeting_user has no structure level, the structure level is fetched
+// from the template field user/structure_level_$.
+func (u *dbUser) loadMeeting(ctx context.Context, fetch *datastore.Fetcher, fqid string, meetingID int) {
+	if len(u.MeetingUserIDs) > 0 {
+		var meetingIDs []int
+		fetch.RelationField(ctx, &meetingIDs, "meeting_user", "meeting_id", "%s/meeting_user_ids", fqid)
+		for i, id := range meetingIDs {
+			if id == meetingID {
+				fetch.Object(ctx, &u.meetingUser, "meeting_user/%d", u.MeetingUserIDs[i])
+				break
 			}
-			full = append(full, s)
 		}
-		return full
-	}(u.Title, u.FirstName, u.LastName)
+	}
+
+	if u.meetingUser.StructureLevel != "" {
+		u.level = u.meetingUser.StructureLevel
+		return
+	}
+	fetch.TemplateField(ctx, fqid, "structure_level_$", strconv.Itoa(meetingID), &u.level)
+}
+
+// nameParts returns the title, first name and last name of the user, that are
+// not empty.
+func (u dbUser) nameParts() []string {
+	var parts []string
+	for _, s := range []string{u.Title, u.FirstName, u.LastName} {
+		if s == "" {
+			continue
+		}
+		parts = append(parts, s)
+	}
+	return parts
+}
+
+// structureLevel returns the structure level of the user in the meeting.
+//
+// loadMeeting has to be called before.
+func (u dbUser) structureLevel() string {
+	return u.level
+}
 
+// shortName returns the name of the user in the short form of ShortName with
+// the given level. If the user has no first and last name, the username is
+// returned.
+func (u dbUser) shortName(level string) string {
+	if name := ShortName(u.FirstName, u.LastName, level); name != "" {
+		return name
+	}
+	return u.Username
+}
+
+// ShortName builds the short name of a user in the form "F. Lastname (Level)",
+// for example for a chyron. The first name is only abbreviated, if there is a
+// last name. If first and last name are empty, ShortName returns an empty
+// string.
+func ShortName(firstName, lastName, level string) string {
+	var name string
+	switch {
+	case firstName != "" && lastName != "":
+		initial, _ := utf8.DecodeRuneInString(firstName)
+		name = string(initial) + ". " + lastName
+	case lastName != "":
+		name = lastName
+	case firstName != "":
+		name = firstName
+	default:
+		return ""
+	}
+
+	if level != "" {
+		name += fmt.Sprintf(" (%s)", level)
+	}
+	return name
+}
+
+// String returns the full name of the user with the structure level in the
+// meeting.
+//
+// loadMeeting has to be called before.
+func (u dbUser) String() string {
+	parts := u.nameParts()
 	if len(parts) == 0 {
 		return u.Username
 	}
 
-	if level := u.Level[meetingID]; level != "" {
+	if level := u.structureLevel(); level != "" {
 		parts = append(parts, fmt.Sprintf("(%s)", level))
 	}
 
 	return strings.Join(parts, " ")
 }
 
+// userOptions are the projection options of the user slide.
+type userOptions struct {
+	// ShortName renders the name as "F. Lastname (Level)".
+	ShortName bool `json:"short_name"`
+}
+
 // User renders the user slide.
+//
+// With the projection option `short_name`, the slide contains the short name
+// of the user, that is used for chyrons.
 func User(store *projector.SlideStore) {
-	store.AddFunc("user", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, keys []string, err error) {
+	store.AddFunc("user", func(ctx context.Context, ds projector.Datastore, p7on *projector.Projection) (encoded []byte, err error) {
+		var options userOptions
+		if len(p7on.Options) > 0 {
+			if err := json.Unmarshal(p7on.Options, &options); err != nil {
+				return nil, fmt.Errorf("decoding projection options: %w", err)
+			}
+		}
+
+		fetch := datastore.NewFetcher(ds)
+
 		var u dbUser
-		keys, err = datastore.Object(ctx, ds, p7on.ContentObjectID, &u)
-		if err != nil {
-			return nil, nil, fmt.Errorf("getting user object: %w", err)
+		fetch.Object(ctx, &u, p7on.ContentObjectID)
+		u.loadMeeting(ctx, fetch, p7on.ContentObjectID, 1)
+		if err := fetch.Error(); err != nil {
+			return nil, fmt.Errorf("getting user object: %w", err)
+		}
+
+		name := u.String()
+		if options.ShortName {
+			name = u.shortName(u.structureLevel())
 		}
 
-		return []byte(fmt.Sprintf(`{"user":"%s"}`, u.String(1))), keys, nil
+		return []byte(fmt.Sprintf(`{"user":"%s"}`, name)), nil
 	})
 }
@@ -2,6 +2,9 @@ package slide_test
 
 import (
 	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/OpenSlides/openslides-autoupdate-service/internal/projector"
@@ -98,7 +101,7 @@ func TestUser(t *testing.T) {
 				ContentObjectID: "user/1",
 			}
 
-			bs, keys, err := userSlide.Slide(context.Background(), ds, p7on)
+			bs, keys, err := projector.Render(context.Background(), ds, userSlide, p7on)
 			assert.NoError(t, err)
 			assert.JSONEq(t, tt.expect, string(bs))
 			expectedKeys := []string{
@@ -107,9 +110,155 @@ func TestUser(t *testing.T) {
 				"user/1/first_name",
 				"user/1/last_name",
 				"user/1/structure_level_$",
+				"user/1/meeting_user_ids",
 				"user/1/structure_level_$1",
 			}
 			assert.ElementsMatch(t, keys, expectedKeys)
 		})
 	}
 }
+
+func TestUserMeetingUser(t *testing.T) {
+	s := new(projector.SlideStore)
+	slide.User(s)
+	userSlide := s.Get("user")
+
+	closed := make(chan struct{})
+	defer close(closed)
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+	user/1:
+		first_name: Jonny
+		last_name: Bo
+		structure_level_$: ["1"]
+		structure_level_$1: Bern
+		meeting_user_ids: [5, 6]
+
+	meeting_user:
+		5:
+			meeting_id: 1
+			structure_level: Zürich
+		6:
+			meeting_id: 2
+			structure_level: Genf
+	`))
+
+	p7on := &projector.Projection{
+		ContentObjectID: "user/1",
+	}
+
+	bs, keys, err := projector.Render(context.Background(), ds, userSlide, p7on)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"user":"Jonny Bo (Zürich)"}`, string(bs))
+	assert.Contains(t, keys, "user/1/meeting_user_ids")
+	assert.Contains(t, keys, "meeting_user/5/structure_level")
+	assert.Contains(t, keys, "meeting_user/6/meeting_id")
+}
+
+// countingDatastore counts the requests to the datastore.
+type countingDatastore struct {
+	projector.Datastore
+	requests int
+}
+
+func (d *countingDatastore) Get(ctx context.Context, keys ...string) ([]json.RawMessage, error) {
+	d.requests++
+	return d.Datastore.Get(ctx, keys...)
+}
+
+func TestUserMeetingUserRequests(t *testing.T) {
+	s := new(projector.SlideStore)
+	slide.User(s)
+	userSlide := s.Get("user")
+
+	requests := func(meetingUsers int) int {
+		closed := make(chan struct{})
+		defer close(closed)
+
+		data := map[string]string{"user/1/first_name": `"Jonny"`}
+		ids := make([]string, meetingUsers)
+		for i := range ids {
+			id := strconv.Itoa(i + 1)
+			ids[i] = id
+			data["meeting_user/"+id+"/meeting_id"] = strconv.Itoa(meetingUsers - i)
+			data["meeting_user/"+id+"/structure_level"] = `"level"`
+		}
+		data["user/1/meeting_user_ids"] = "[" + strings.Join(ids, ",") + "]"
+
+		ds := &countingDatastore{Datastore: dsmock.NewMockDatastore(closed, data)}
+		bs, _, err := projector.Render(context.Background(), ds, userSlide, &projector.Projection{ContentObjectID: "user/1"})
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"user":"Jonny (level)"}`, string(bs))
+		return ds.requests
+	}
+
+	assert.Equal(t, requests(1), requests(20), "the number of requests depends on the number of meeting users")
+}
+
+func TestUserShortName(t *testing.T) {
+	s := new(projector.SlideStore)
+	slide.User(s)
+	userSlide := s.Get("user")
+
+	for _, tt := range []struct {
+		name   string
+		data   string
+		expect string
+	}{
+		{
+			"Firstname Lastname Level",
+			`
+			user/1:
+				title: Dr.
+				first_name: Jonny
+				last_name: Bo
+				structure_level_$: ["1"]
+				structure_level_$1: Bern
+			`,
+			`{"user":"J. Bo (Bern)"}`,
+		},
+		{
+			"Firstname Lastname",
+			`
+			user/1:
+				first_name: Ümit
+				last_name: Bo
+			`,
+			`{"user":"Ü. Bo"}`,
+		},
+		{
+			"Only Firstname",
+			`
+			user/1:
+				first_name: Jonny
+				structure_level_$: ["1"]
+				structure_level_$1: Bern
+			`,
+			`{"user":"Jonny (Bern)"}`,
+		},
+		{
+			"Only Username",
+			`
+			user/1:
+				username: jonny123
+				structure_level_$: ["1"]
+				structure_level_$1: Bern
+			`,
+			`{"user":"jonny123"}`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			closed := make(chan struct{})
+			defer close(closed)
+			ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(tt.data))
+
+			p7on := &projector.Projection{
+				ContentObjectID: "user/1",
+				Options:         []byte(`{"short_name":true}`),
+			}
+
+			bs, _, err := projector.Render(context.Background(), ds, userSlide, p7on)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.expect, string(bs))
+		})
+	}
+}
@@ -2,24 +2,48 @@ package projector
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
 )
 
 // SlideStore holds the slides by name.
+//
+// Slides can belong to a feature. When a feature is disabled, its slides are
+// not calculated.
 type SlideStore struct {
 	slides map[string]Slider
+
+	// features is a map from a slide name to the feature of the slide.
+	features map[string]string
+	disabled map[string]bool
+
+	// blocked removes blocked keys from the data of the slides. See Block.
+	blocked *restrict.Blocked
+
+	// current is the feature, that is used by Add.
+	current string
 }
 
 // Add registers a slide for a name.
 func (s *SlideStore) Add(name string, slide Slider) {
 	if s.slides == nil {
 		s.slides = make(map[string]Slider)
+		s.features = make(map[string]string)
 	}
 
 	if _, ok := s.slides[name]; ok {
 		panic(fmt.Sprintf("Slide with name %s does already exist", name))
 	}
 	s.slides[name] = slide
+
+	if s.current != "" {
+		s.features[name] = s.current
+	}
 }
 
 // AddFunc is a helper to add a SliderFunc.
@@ -27,20 +51,132 @@ func (s *SlideStore) AddFunc(name string, f SliderFunc) {
 	s.Add(name, f)
 }
 
+// AddFeature calls each register function with the store. All slides, that
+// are added by the functions, belong to the feature.
+func (s *SlideStore) AddFeature(feature string, register ...func(*SlideStore)) {
+	s.current = feature
+	defer func() { s.current = "" }()
+
+	for _, r := range register {
+		r(s)
+	}
+}
+
+// Features returns the sorted names of all features.
+func (s *SlideStore) Features() []string {
+	seen := make(map[string]bool)
+	var features []string
+	for _, feature := range s.features {
+		if seen[feature] {
+			continue
+		}
+		seen[feature] = true
+		features = append(features, feature)
+	}
+	sort.Strings(features)
+	return features
+}
+
+// Disable disables the slides of the given features. Unknown features are
+// ignored.
+//
+// Instead of a disabled slide, Get returns a slide with the content
+// {"error": "feature disabled", "feature": FEATURE_NAME}.
+func (s *SlideStore) Disable(features ...string) {
+	if s.disabled == nil {
+		s.disabled = make(map[string]bool)
+	}
+
+	for _, feature := range features {
+		s.disabled[feature] = true
+	}
+}
+
 // Get returns a Slide for a name.
 func (s *SlideStore) Get(name string) Slider {
+	if feature := s.features[name]; s.disabled[feature] {
+		return disabledSlide(feature)
+	}
 	return s.slides[name]
 }
 
+// disabledSlide returns a slide for a disabled feature. It does not depend on
+// any key.
+func disabledSlide(feature string) Slider {
+	return SliderFunc(func(ctx context.Context, ds Datastore, p7on *Projection) ([]byte, error) {
+		content := struct {
+			Error   string `json:"error"`
+			Feature string `json:"feature"`
+		}{
+			"feature disabled",
+			feature,
+		}
+
+		bs, err := json.Marshal(content)
+		if err != nil {
+			return nil, fmt.Errorf("encoding content: %w", err)
+		}
+		return bs, nil
+	})
+}
+
 // Slider knows how to create a slide.
+//
+// A slide does not return the keys it depends on. Every key, that the slide
+// requests from the datastore, is recorded by Render.
 type Slider interface {
-	Slide(ctx context.Context, ds Datastore, p7on *Projection) (encoded []byte, keys []string, err error)
+	Slide(ctx context.Context, ds Datastore, p7on *Projection) (encoded []byte, err error)
 }
 
 // SliderFunc is a function that implements the Slider interface.
-type SliderFunc func(ctx context.Context, ds Datastore, p7on *Projection) (encoded []byte, keys []string, err error)
+type SliderFunc func(ctx context.Context, ds Datastore, p7on *Projection) (encoded []byte, err error)
 
 // Slide calls the func.
-func (f SliderFunc) Slide(ctx context.Context, ds Datastore, p7on *Projection) (encoded []byte, keys []string, err error) {
+func (f SliderFunc) Slide(ctx context.Context, ds Datastore, p7on *Projection) (encoded []byte, err error) {
 	return f(ctx, ds, p7on)
 }
+
+// Render calls the slider and returns the content with all keys, that the
+// slider requested from the datastore. The content has to be calculated again,
+// when one of these keys changes.
+func Render(ctx context.Context, ds Datastore, slider Slider, p7on *Projection) ([]byte, []string, error) {
+	r := &recorder{Datastore: ds}
+	bs, err := slider.Slide(ctx, r, p7on)
+	if err != nil {
+		return nil, nil, err
+	}
+	return bs, r.keys, nil
+}
+
+// recorder is a Datastore that remembers all keys, that are requested. Each
+// key is only recorded once.
+type recorder struct {
+	Datastore
+
+	mu   sync.Mutex
+	seen map[string]bool
+	keys []string
+}
+
+// Get records the keys and fetches them from the datastore.
+func (r *recorder) Get(ctx context.Context, keys ...string) ([]json.RawMessage, error) {
+	r.mu.Lock()
+	if r.seen == nil {
+		r.seen = make(map[string]bool)
+	}
+	for _, key := range keys {
+		if !r.seen[key] {
+			r.seen[key] = true
+			r.keys = append(r.keys, key)
+		}
+	}
+	r.mu.Unlock()
+
+	return r.Datastore.Get(ctx, keys...)
+}
+
+// TemplateField fetches the template field with the recorder, so its keys are
+// recorded.
+func (r *recorder) TemplateField(ctx context.Context, fqid, field, replacement string, value interface{}) ([]string, error) {
+	return datastore.TemplateField(ctx, r, fqid, field, replacement, value)
+}
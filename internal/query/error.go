@@ -0,0 +1,34 @@
+package query
+
+// InvalidError is returned when the query is not valid.
+type InvalidError struct {
+	msg string
+}
+
+func (e InvalidError) Error() string {
+	return e.msg
+}
+
+// Type returns the name of the error.
+func (e InvalidError) Type() string {
+	return "SyntaxError"
+}
+
+// JSONError is returned when the query is not valid json.
+type JSONError struct {
+	err error
+}
+
+func (e JSONError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the thrown error.
+func (e JSONError) Unwrap() error {
+	return e.err
+}
+
+// Type returns the name of the error.
+func (e JSONError) Type() string {
+	return "JsonError"
+}
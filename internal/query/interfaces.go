@@ -0,0 +1,16 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// IDLister returns the ids of a collection that are known by the service.
+type IDLister interface {
+	CachedIDs(collection string) []int
+}
+
+// DataProvider returns restricted values for keys.
+type DataProvider interface {
+	RestrictedData(ctx context.Context, uid int, keys ...string) (map[string]json.RawMessage, error)
+}
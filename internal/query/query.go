@@ -0,0 +1,217 @@
+// Package query evaluates small declarative queries against the datastore
+// cache.
+//
+// A query looks like this:
+//
+//	{
+//		"collection": "motion",
+//		"filter": {"meeting_id": 5},
+//		"fields": ["title", "number"],
+//		"order": "-number",
+//		"limit": 10
+//	}
+//
+// Only objects that are already in the cache are considered. All values are
+// restricted for the requesting user before the filter is applied.
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Query describes which objects and fields should be returned.
+type Query struct {
+	Collection string                     `json:"collection"`
+	Filter     map[string]json.RawMessage `json:"filter"`
+	Fields     []string                   `json:"fields"`
+	Order      string                     `json:"order"`
+	Limit      int                        `json:"limit"`
+}
+
+// FromJSON reads a query from json.
+func FromJSON(r io.Reader) (*Query, error) {
+	var q Query
+	if err := json.NewDecoder(r).Decode(&q); err != nil {
+		if err == io.EOF {
+			return nil, InvalidError{msg: "No data"}
+		}
+		return nil, JSONError{err}
+	}
+
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+func (q *Query) validate() error {
+	if q.Collection == "" {
+		return InvalidError{msg: "no collection"}
+	}
+
+	if strings.Contains(q.Collection, "/") {
+		return InvalidError{msg: fmt.Sprintf("invalid collection %s", q.Collection)}
+	}
+
+	if len(q.Fields) == 0 {
+		return InvalidError{msg: "no fields"}
+	}
+
+	for _, field := range append(q.fields(), q.orderField()) {
+		if strings.Contains(field, "/") {
+			return InvalidError{msg: fmt.Sprintf("invalid field %s", field)}
+		}
+	}
+
+	if q.Limit < 0 {
+		return InvalidError{msg: "limit has to be a positive number"}
+	}
+	return nil
+}
+
+// orderField returns the field name from the order attribute without the
+// direction prefix.
+func (q *Query) orderField() string {
+	return strings.TrimPrefix(q.Order, "-")
+}
+
+// fields returns all fields, that have to be fetched for each object.
+func (q *Query) fields() []string {
+	fields := []string{"id"}
+	fields = append(fields, q.Fields...)
+	for field := range q.Filter {
+		fields = append(fields, field)
+	}
+	if q.Order != "" {
+		fields = append(fields, q.orderField())
+	}
+	return fields
+}
+
+// Run evaluates the query for the user with the given id.
+//
+// The returned objects only contain the requested fields and the id. Objects
+// that the user can not see are not returned.
+func (q *Query) Run(ctx context.Context, lister IDLister, dp DataProvider, uid int) ([]map[string]json.RawMessage, error) {
+	ids := lister.CachedIDs(q.Collection)
+	fields := q.fields()
+
+	keys := make([]string, 0, len(ids)*len(fields))
+	for _, id := range ids {
+		for _, field := range fields {
+			keys = append(keys, fqfield(q.Collection, id, field))
+		}
+	}
+
+	data, err := dp.RestrictedData(ctx, uid, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("getting restricted data: %w", err)
+	}
+
+	type object struct {
+		order  json.RawMessage
+		values map[string]json.RawMessage
+	}
+
+	var objects []object
+	for _, id := range ids {
+		if data[fqfield(q.Collection, id, "id")] == nil {
+			// Object does not exist or the user can not see it.
+			continue
+		}
+
+		if !q.matches(data, id) {
+			continue
+		}
+
+		values := make(map[string]json.RawMessage, len(q.Fields)+1)
+		values["id"] = data[fqfield(q.Collection, id, "id")]
+		for _, field := range q.Fields {
+			values[field] = data[fqfield(q.Collection, id, field)]
+		}
+
+		var order json.RawMessage
+		if q.Order != "" {
+			order = data[fqfield(q.Collection, id, q.orderField())]
+		}
+
+		objects = append(objects, object{order: order, values: values})
+	}
+
+	if q.Order != "" {
+		desc := strings.HasPrefix(q.Order, "-")
+		sort.SliceStable(objects, func(i, j int) bool {
+			if desc {
+				return less(objects[j].order, objects[i].order)
+			}
+			return less(objects[i].order, objects[j].order)
+		})
+	}
+
+	if q.Limit > 0 && len(objects) > q.Limit {
+		objects = objects[:q.Limit]
+	}
+
+	result := make([]map[string]json.RawMessage, len(objects))
+	for i, o := range objects {
+		result[i] = o.values
+	}
+	return result, nil
+}
+
+// matches returns true, if the object with the given id matches all filters.
+func (q *Query) matches(data map[string]json.RawMessage, id int) bool {
+	for field, expect := range q.Filter {
+		if !jsonEqual(data[fqfield(q.Collection, id, field)], expect) {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonEqual returns true, if the two json values are the same ignoring
+// whitespace. A nil value is the same as json null.
+func jsonEqual(a, b json.RawMessage) bool {
+	if a == nil {
+		a = []byte("null")
+	}
+	if b == nil {
+		b = []byte("null")
+	}
+
+	var ca, cb bytes.Buffer
+	if err := json.Compact(&ca, a); err != nil {
+		return false
+	}
+	if err := json.Compact(&cb, b); err != nil {
+		return false
+	}
+	return bytes.Equal(ca.Bytes(), cb.Bytes())
+}
+
+// less compares two json values. Numbers are compared by value, everything
+// else by its json representation. Missing values are sorted first.
+func less(a, b json.RawMessage) bool {
+	if a == nil || b == nil {
+		return a == nil && b != nil
+	}
+
+	na, errA := strconv.ParseFloat(string(a), 64)
+	nb, errB := strconv.ParseFloat(string(b), 64)
+	if errA == nil && errB == nil {
+		return na < nb
+	}
+
+	return string(a) < string(b)
+}
+
+func fqfield(collection string, id int, field string) string {
+	return collection + "/" + strconv.Itoa(id) + "/" + field
+}
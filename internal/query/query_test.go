@@ -0,0 +1,123 @@
+package query_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/query"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type listerMock []int
+
+func (l listerMock) CachedIDs(collection string) []int {
+	return l
+}
+
+func TestQueryRun(t *testing.T) {
+	dp := &test.DataProvider{
+		Data: map[string]json.RawMessage{
+			"motion/1/id":         []byte(`1`),
+			"motion/1/title":      []byte(`"first"`),
+			"motion/1/meeting_id": []byte(`5`),
+			"motion/1/weight":     []byte(`20`),
+			"motion/2/id":         []byte(`2`),
+			"motion/2/title":      []byte(`"second"`),
+			"motion/2/meeting_id": []byte(`5`),
+			"motion/2/weight":     []byte(`3`),
+			"motion/3/id":         []byte(`3`),
+			"motion/3/title":      []byte(`"other meeting"`),
+			"motion/3/meeting_id": []byte(`6`),
+			"motion/3/weight":     []byte(`1`),
+		},
+	}
+
+	for _, tt := range []struct {
+		name   string
+		query  string
+		expect string
+	}{
+		{
+			"All",
+			`{"collection":"motion","fields":["title"]}`,
+			`[{"id":1,"title":"first"},{"id":2,"title":"second"},{"id":3,"title":"other meeting"}]`,
+		},
+		{
+			"Filter",
+			`{"collection":"motion","fields":["title"],"filter":{"meeting_id":5}}`,
+			`[{"id":1,"title":"first"},{"id":2,"title":"second"}]`,
+		},
+		{
+			"Order",
+			`{"collection":"motion","fields":["title"],"order":"weight"}`,
+			`[{"id":3,"title":"other meeting"},{"id":2,"title":"second"},{"id":1,"title":"first"}]`,
+		},
+		{
+			"Order descending with limit",
+			`{"collection":"motion","fields":["title"],"order":"-weight","limit":1}`,
+			`[{"id":1,"title":"first"}]`,
+		},
+		{
+			"Unknown ids",
+			`{"collection":"motion","fields":["title"],"filter":{"meeting_id":404}}`,
+			`[]`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := query.FromJSON(strings.NewReader(tt.query))
+			require.NoError(t, err)
+
+			got, err := q.Run(context.Background(), listerMock{1, 2, 3, 4}, dp, 1)
+			require.NoError(t, err)
+
+			bs, err := json.Marshal(got)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expect, string(bs))
+		})
+	}
+}
+
+func TestQueryRestricted(t *testing.T) {
+	dp := &test.DataProvider{
+		Data: map[string]json.RawMessage{
+			"motion/2/id":    []byte(`2`),
+			"motion/2/title": []byte(`"second"`),
+		},
+	}
+
+	q, err := query.FromJSON(strings.NewReader(`{"collection":"motion","fields":["title"]}`))
+	require.NoError(t, err)
+
+	// motion/1/id is not in the data provider, so the motion is not visible.
+	got, err := q.Run(context.Background(), listerMock{1, 2}, dp, 1)
+	require.NoError(t, err)
+
+	require.Len(t, got, 1)
+	assert.Equal(t, `"second"`, string(got[0]["title"]))
+}
+
+func TestQueryInvalid(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		query string
+	}{
+		{"No collection", `{"fields":["title"]}`},
+		{"No fields", `{"collection":"motion"}`},
+		{"Invalid field", `{"collection":"motion","fields":["title/1"]}`},
+		{"Negative limit", `{"collection":"motion","fields":["title"],"limit":-1}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := query.FromJSON(strings.NewReader(tt.query))
+
+			var errInvalid query.InvalidError
+			if !errors.As(err, &errInvalid) {
+				t.Errorf("FromJSON() returned err `%v`, expected an InvalidError", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package record
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Player plays the change events of a recording. It implements the
+// datastore.Updater and the auth.LogoutEventer interface, so it can be used
+// instead of the messaging service.
+type Player struct {
+	changes []Event
+	speed   float64
+	start   time.Time
+	next    int
+}
+
+// NewPlayer reads a recording. The changes are played back with the given
+// speed. A speed of 2 plays the recording in half the time. A speed of 0
+// plays all changes without waiting.
+func NewPlayer(r io.Reader, speed float64) (*Player, error) {
+	if speed < 0 {
+		return nil, fmt.Errorf("speed has to be positive, got %f", speed)
+	}
+
+	var changes []Event
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("decoding line %d: %w", line, err)
+		}
+
+		if event.Type == TypeChange {
+			changes = append(changes, event)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading recording: %w", err)
+	}
+
+	return &Player{
+		changes: changes,
+		speed:   speed,
+	}, nil
+}
+
+// Update returns the next change of the recording. It blocks until the time
+// of the change. After the last change, it blocks until closing is closed.
+//
+// The time is measured from the first call to Update.
+func (p *Player) Update(closing <-chan struct{}) (map[string]json.RawMessage, error) {
+	if p.start.IsZero() {
+		p.start = time.Now()
+	}
+
+	if p.next >= len(p.changes) {
+		<-closing
+		return nil, closingError{}
+	}
+
+	event := p.changes[p.next]
+
+	if p.speed > 0 {
+		at := p.start.Add(time.Duration(float64(event.Time)/p.speed) * time.Millisecond)
+		timer := time.NewTimer(time.Until(at))
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-closing:
+			return nil, closingError{}
+		}
+	}
+
+	p.next++
+	return event.Data, nil
+}
+
+// LogoutEvent blocks until closing is closed. A recording does not contain
+// logout events.
+func (p *Player) LogoutEvent(closing <-chan struct{}) ([]string, error) {
+	<-closing
+	return nil, closingError{}
+}
+
+type closingError struct{}
+
+func (e closingError) Closing()      {}
+func (e closingError) Error() string { return "closing" }
@@ -0,0 +1,244 @@
+// Package record records the change events and the messages to the clients
+// of a running service and plays the change events back.
+//
+// A recording is a file with one json object per line. Each object is an
+// Event. The user ids and the values of personal fields are anonymized.
+//
+// The Player reads a recording and can be used as the messaging service of a
+// fresh instance. This turns the events of a real meeting into a reproducible
+// test case.
+package record
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/autoupdate"
+)
+
+// Types of an Event.
+const (
+	TypeChange  = "change"
+	TypeMessage = "message"
+)
+
+// anonymizedValue replaces the values of the AnonymizedFields.
+const anonymizedValue = `"***"`
+
+// AnonymizedFields are the fields, whose values are replaced in a recording.
+// Template fields are given by their template name.
+//
+// It contains all fields with personal data of a user and the personal notes.
+var AnonymizedFields = map[string]bool{
+	"user/username":                true,
+	"user/title":                   true,
+	"user/first_name":              true,
+	"user/last_name":               true,
+	"user/gender":                  true,
+	"user/email":                   true,
+	"user/last_email_send":         true,
+	"user/last_login":              true,
+	"user/password":                true,
+	"user/default_password":        true,
+	"user/avatar_url":              true,
+	"user/default_number":          true,
+	"user/default_structure_level": true,
+	"user/number_$":                true,
+	"user/structure_level_$":       true,
+	"user/about_me_$":              true,
+	"user/comment_$":               true,
+	"personal_note/note":           true,
+}
+
+// Event is one line of a recording.
+type Event struct {
+	// Time is the time since the start of the recording in milliseconds.
+	Time int64 `json:"time"`
+
+	// Type is TypeChange or TypeMessage.
+	Type string `json:"type"`
+
+	// Connection is a number for each client connection. It is only set for
+	// messages.
+	Connection int `json:"connection,omitempty"`
+
+	// User is the anonymized user id of a message. The ids are numbered in
+	// the order the users connected. The anonymous user is always 0.
+	User int `json:"user,omitempty"`
+
+	Data map[string]json.RawMessage `json:"data"`
+}
+
+// Liver writes the messages of a connection to a writer. It is the same as
+// http.Liver.
+type Liver interface {
+	Live(ctx context.Context, uid int, w io.Writer, kb autoupdate.KeysBuilder, options ...autoupdate.ConnectionOption) error
+}
+
+// Recorder writes events to a writer.
+type Recorder struct {
+	mu          sync.Mutex
+	encoder     *json.Encoder
+	errHandler  func(error)
+	start       time.Time
+	users       map[int]int
+	connections int
+}
+
+// NewRecorder initializes a Recorder. The recording starts now.
+//
+// Errors from writing the messages of the clients are given to errHandler. They
+// do not interrupt the connections.
+func NewRecorder(w io.Writer, errHandler func(error)) *Recorder {
+	return &Recorder{
+		encoder:    json.NewEncoder(w),
+		errHandler: errHandler,
+		start:      time.Now(),
+		users:      make(map[int]int),
+	}
+}
+
+// Change records a change event.
+//
+// Change can be used as a datastore change listener.
+func (r *Recorder) Change(data map[string]json.RawMessage) error {
+	if err := r.write(Event{Type: TypeChange, Data: anonymize(data)}); err != nil {
+		return fmt.Errorf("recording change: %w", err)
+	}
+	return nil
+}
+
+// Liver returns a Liver, that records all messages of the given Liver.
+func (r *Recorder) Liver(l Liver) Liver {
+	return liverFunc(func(ctx context.Context, uid int, w io.Writer, kb autoupdate.KeysBuilder, options ...autoupdate.ConnectionOption) error {
+		r.mu.Lock()
+		r.connections++
+		connection := r.connections
+		user := r.anonymousUser(uid)
+		r.mu.Unlock()
+
+		rw := &recordWriter{
+			Writer:     w,
+			recorder:   r,
+			connection: connection,
+			user:       user,
+		}
+		return l.Live(ctx, uid, rw, kb, options...)
+	})
+}
+
+// anonymousUser returns the anonymized id of a user. Has to be called with
+// the lock.
+func (r *Recorder) anonymousUser(uid int) int {
+	if uid == 0 {
+		return 0
+	}
+
+	id, ok := r.users[uid]
+	if !ok {
+		id = len(r.users) + 1
+		r.users[uid] = id
+	}
+	return id
+}
+
+func (r *Recorder) write(event Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event.Time = time.Since(r.start).Milliseconds()
+	if err := r.encoder.Encode(event); err != nil {
+		return fmt.Errorf("writing event: %w", err)
+	}
+	return nil
+}
+
+// recordWriter records each message, that is written to it.
+type recordWriter struct {
+	io.Writer
+	recorder   *Recorder
+	connection int
+	user       int
+}
+
+// Write writes the message and records it. The autoupdate service writes each
+// message with one call.
+//
+// An error from recording the message is given to the error handler of the
+// recorder and not returned. The client should not notice the recording.
+func (w *recordWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(p, &data); err != nil {
+		// Not a message, for example an error.
+		return n, nil
+	}
+
+	event := Event{
+		Type:       TypeMessage,
+		Connection: w.connection,
+		User:       w.user,
+		Data:       anonymize(data),
+	}
+	if err := w.recorder.write(event); err != nil {
+		w.recorder.errHandler(fmt.Errorf("recording message: %w", err))
+	}
+	return n, nil
+}
+
+// Flush flushes the underlying writer.
+func (w *recordWriter) Flush() {
+	if f, ok := w.Writer.(interface{ Flush() }); ok {
+		f.Flush()
+	}
+}
+
+type liverFunc func(ctx context.Context, uid int, w io.Writer, kb autoupdate.KeysBuilder, options ...autoupdate.ConnectionOption) error
+
+func (f liverFunc) Live(ctx context.Context, uid int, w io.Writer, kb autoupdate.KeysBuilder, options ...autoupdate.ConnectionOption) error {
+	return f(ctx, uid, w, kb, options...)
+}
+
+// anonymize returns a copy of the data, where the values of the
+// AnonymizedFields are replaced.
+func anonymize(data map[string]json.RawMessage) map[string]json.RawMessage {
+	anonymized := make(map[string]json.RawMessage, len(data))
+	for key, value := range data {
+		if value != nil && AnonymizedFields[templateKey(key)] {
+			value = json.RawMessage(anonymizedValue)
+		}
+		anonymized[key] = value
+	}
+	return anonymized
+}
+
+// templateKey returns `collection/field` for a key. If the field is a template
+// field with a replacement, the template name is used.
+//
+// templateKey("user/1/about_me_$5") -> "user/about_me_$".
+func templateKey(key string) string {
+	parts := strings.SplitN(key, "/", 3)
+	if len(parts) != 3 {
+		return key
+	}
+
+	field := parts[2]
+	if i := strings.IndexByte(field, '$'); i >= 0 {
+		rest := field[i+1:]
+		if j := strings.IndexByte(rest, '_'); j >= 0 {
+			field = field[:i+1] + rest[j:]
+		} else {
+			field = field[:i+1]
+		}
+	}
+	return parts[0] + "/" + field
+}
@@ -0,0 +1,153 @@
+package record_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/record"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type liverMock struct {
+	messages []string
+}
+
+func (l liverMock) Live(ctx context.Context, uid int, w io.Writer, kb autoupdate.KeysBuilder, options ...autoupdate.ConnectionOption) error {
+	for _, m := range l.messages {
+		if _, err := w.Write([]byte(m + "\n")); err != nil {
+			return err
+		}
+		w.(interface{ Flush() }).Flush()
+	}
+	return nil
+}
+
+func TestRecorder(t *testing.T) {
+	buf := new(bytes.Buffer)
+	recorder := record.NewRecorder(buf, func(err error) {
+		t.Errorf("Recorder returned error: %v", err)
+	})
+
+	err := recorder.Change(map[string]json.RawMessage{
+		"user/5/username":    []byte(`"hugo"`),
+		"user/5/about_me_$1": []byte(`"secret"`),
+		"motion/1/title":     []byte(`"title"`),
+	})
+	require.NoError(t, err)
+
+	liver := recorder.Liver(liverMock{messages: []string{`{"user/5/first_name":"Hugo","motion/1/title":"title"}`}})
+
+	for _, uid := range []int{5, 7, 5} {
+		w := httptest.NewRecorder()
+		require.NoError(t, liver.Live(context.Background(), uid, w, test.KeysBuilder{}))
+		assert.Equal(t, `{"user/5/first_name":"Hugo","motion/1/title":"title"}`+"\n", w.Body.String(), "client got changed message")
+	}
+
+	var events []record.Event
+	decoder := json.NewDecoder(buf)
+	for {
+		var event record.Event
+		if err := decoder.Decode(&event); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			t.Fatalf("decoding event: %v", err)
+		}
+		event.Time = 0
+		events = append(events, event)
+	}
+
+	expect := []record.Event{
+		{
+			Type: record.TypeChange,
+			Data: map[string]json.RawMessage{
+				"user/5/username":    []byte(`"***"`),
+				"user/5/about_me_$1": []byte(`"***"`),
+				"motion/1/title":     []byte(`"title"`),
+			},
+		},
+	}
+	for i, user := range []int{1, 2, 1} {
+		expect = append(expect, record.Event{
+			Type:       record.TypeMessage,
+			Connection: i + 1,
+			User:       user,
+			Data: map[string]json.RawMessage{
+				"user/5/first_name": []byte(`"***"`),
+				"motion/1/title":    []byte(`"title"`),
+			},
+		})
+	}
+
+	assert.Equal(t, expect, events)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestRecorderWriteError(t *testing.T) {
+	var gotErr error
+	recorder := record.NewRecorder(failingWriter{}, func(err error) {
+		gotErr = err
+	})
+
+	liver := recorder.Liver(liverMock{messages: []string{`{"motion/1/title":"title"}`}})
+
+	w := httptest.NewRecorder()
+	err := liver.Live(context.Background(), 1, w, test.KeysBuilder{})
+
+	require.NoError(t, err, "Live returned the error of the recording")
+	assert.Equal(t, `{"motion/1/title":"title"}`+"\n", w.Body.String())
+	assert.Error(t, gotErr, "error handler was not called")
+}
+
+func TestAnonymizedFieldsContainPersonalData(t *testing.T) {
+	for _, field := range restrict.PersonalDataFields {
+		assert.True(t, record.AnonymizedFields[field], "personal field %s is not anonymized", field)
+	}
+}
+
+func TestPlayer(t *testing.T) {
+	recording := strings.Join([]string{
+		`{"time":0,"type":"change","data":{"motion/1/title":"first"}}`,
+		`{"time":5,"type":"message","connection":1,"user":1,"data":{"motion/1/title":"first"}}`,
+		`{"time":10,"type":"change","data":{"motion/1/title":"second"}}`,
+	}, "\n")
+
+	player, err := record.NewPlayer(strings.NewReader(recording), 0)
+	require.NoError(t, err)
+
+	closing := make(chan struct{})
+
+	data, err := player.Update(closing)
+	require.NoError(t, err)
+	assert.Equal(t, `"first"`, string(data["motion/1/title"]))
+
+	data, err = player.Update(closing)
+	require.NoError(t, err)
+	assert.Equal(t, `"second"`, string(data["motion/1/title"]))
+
+	close(closing)
+	_, err = player.Update(closing)
+
+	var errClosing interface{ Closing() }
+	assert.True(t, errors.As(err, &errClosing), "Update after last change returned %v, expected a closing error", err)
+}
+
+func TestPlayerInvalid(t *testing.T) {
+	_, err := record.NewPlayer(strings.NewReader(`{"time":0`), 1)
+	assert.Error(t, err)
+}
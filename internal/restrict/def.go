@@ -1,147 +0,0 @@
-// Code generated with models.txt DO NOT EDIT.
-package restrict
-
-// RelationLists is list from all relation-list and generic-relation-list the
-// model where it directs to. generic-relation-list habe '*' als value. The list
-// contains also all template-fields that contain relation-list and
-// geneeric-relation-lists.
-//
-// The map is automaticly created from the models.yml file.
-var RelationLists = map[string]string{
-	"agenda_item/child_ids":                                    "agenda_item",
-	"agenda_item/current_projector_ids":                        "projector",
-	"agenda_item/projection_ids":                               "projection",
-	"agenda_item/tag_ids":                                      "tag",
-	"assignment/attachment_ids":                                "mediafile",
-	"assignment/candidate_ids":                                 "assignment_candidate",
-	"assignment/current_projector_ids":                         "projector",
-	"assignment/poll_ids":                                      "poll",
-	"assignment/projection_ids":                                "projection",
-	"assignment/tag_ids":                                       "tag",
-	"committee/forward_to_committee_ids":                       "committee",
-	"committee/manager_ids":                                    "user",
-	"committee/meeting_ids":                                    "meeting",
-	"committee/member_ids":                                     "user",
-	"committee/receive_forwardings_from_committee_ids":         "committee",
-	"group/mediafile_access_group_ids":                         "mediafile",
-	"group/mediafile_inherited_access_group_ids":               "mediafile",
-	"group/poll_ids":                                           "poll",
-	"group/read_comment_section_ids":                           "motion_comment_section",
-	"group/user_ids":                                           "user",
-	"group/write_comment_section_ids":                          "motion_comment_section",
-	"list_of_speakers/current_projector_ids":                   "projector",
-	"list_of_speakers/projection_ids":                          "projection",
-	"list_of_speakers/speaker_ids":                             "speaker",
-	"mediafile/access_group_ids":                               "group",
-	"mediafile/attachment_ids":                                 "*",
-	"mediafile/child_ids":                                      "mediafile",
-	"mediafile/current_projector_ids":                          "projector",
-	"mediafile/inherited_access_group_ids":                     "group",
-	"mediafile/projection_ids":                                 "projection",
-	"meeting/agenda_item_ids":                                  "agenda_item",
-	"meeting/assignment_candidate_ids":                         "assignment_candidate",
-	"meeting/assignment_ids":                                   "assignment",
-	"meeting/assignment_poll_default_group_ids":                "group",
-	"meeting/group_ids":                                        "group",
-	"meeting/guest_ids":                                        "user",
-	"meeting/list_of_speakers_ids":                             "list_of_speakers",
-	"meeting/mediafile_ids":                                    "mediafile",
-	"meeting/motion_block_ids":                                 "motion_block",
-	"meeting/motion_category_ids":                              "motion_category",
-	"meeting/motion_change_recommendation_ids":                 "motion_change_recommendation",
-	"meeting/motion_comment_ids":                               "motion_comment",
-	"meeting/motion_comment_section_ids":                       "motion_comment_section",
-	"meeting/motion_ids":                                       "motion",
-	"meeting/motion_poll_default_group_ids":                    "group",
-	"meeting/motion_state_ids":                                 "motion_state",
-	"meeting/motion_statute_paragraph_ids":                     "motion_statute_paragraph",
-	"meeting/motion_submitter_ids":                             "motion_submitter",
-	"meeting/motion_workflow_ids":                              "motion_workflow",
-	"meeting/option_ids":                                       "option",
-	"meeting/personal_note_ids":                                "personal_note",
-	"meeting/poll_default_group_ids":                           "group",
-	"meeting/poll_ids":                                         "poll",
-	"meeting/present_user_ids":                                 "user",
-	"meeting/projection_ids":                                   "projection",
-	"meeting/projectiondefault_ids":                            "projectiondefault",
-	"meeting/projector_countdown_ids":                          "projector_countdown",
-	"meeting/projector_ids":                                    "projector",
-	"meeting/projector_message_ids":                            "projector_message",
-	"meeting/speaker_ids":                                      "speaker",
-	"meeting/tag_ids":                                          "tag",
-	"meeting/temporary_user_ids":                               "user",
-	"meeting/topic_ids":                                        "topic",
-	"meeting/vote_ids":                                         "vote",
-	"motion/amendment_ids":                                     "motion",
-	"motion/attachment_ids":                                    "mediafile",
-	"motion/change_recommendation_ids":                         "motion_change_recommendation",
-	"motion/comment_ids":                                       "motion_comment",
-	"motion/current_projector_ids":                             "projector",
-	"motion/derived_motion_ids":                                "motion",
-	"motion/option_ids":                                        "option",
-	"motion/personal_note_ids":                                 "personal_note",
-	"motion/poll_ids":                                          "poll",
-	"motion/projection_ids":                                    "projection",
-	"motion/recommendation_extension_reference_ids":            "*",
-	"motion/referenced_in_motion_recommendation_extension_ids": "motion",
-	"motion/sort_child_ids":                                    "motion",
-	"motion/submitter_ids":                                     "motion_submitter",
-	"motion/supporter_ids":                                     "user",
-	"motion/tag_ids":                                           "tag",
-	"motion_block/current_projector_ids":                       "projector",
-	"motion_block/motion_ids":                                  "motion",
-	"motion_block/projection_ids":                              "projection",
-	"motion_category/child_ids":                                "motion_category",
-	"motion_category/motion_ids":                               "motion",
-	"motion_comment_section/comment_ids":                       "motion_comment",
-	"motion_comment_section/read_group_ids":                    "group",
-	"motion_comment_section/write_group_ids":                   "group",
-	"motion_state/motion_ids":                                  "motion",
-	"motion_state/motion_recommendation_ids":                   "motion",
-	"motion_state/next_state_ids":                              "motion_state",
-	"motion_state/previous_state_ids":                          "motion_state",
-	"motion_statute_paragraph/motion_ids":                      "motion",
-	"motion_workflow/state_ids":                                "motion_state",
-	"option/vote_ids":                                          "vote",
-	"organisation/committee_ids":                               "committee",
-	"organisation/resource_ids":                                "resource",
-	"organisation/role_ids":                                    "role",
-	"poll/current_projector_ids":                               "projector",
-	"poll/entitled_group_ids":                                  "group",
-	"poll/option_ids":                                          "option",
-	"poll/projection_ids":                                      "projection",
-	"poll/voted_ids":                                           "user",
-	"projector/current_element_ids":                            "*",
-	"projector/current_projection_ids":                         "projection",
-	"projector/history_projection_ids":                         "projection",
-	"projector/preview_projection_ids":                         "projection",
-	"projector/projectiondefault_ids":                          "projectiondefault",
-	"projector_countdown/current_projector_ids":                "projector",
-	"projector_countdown/projection_ids":                       "projection",
-	"projector_message/current_projector_ids":                  "projector",
-	"projector_message/projection_ids":                         "projection",
-	"role/user_ids":                                            "user",
-	"tag/tagged_ids":                                           "*",
-	"topic/attachment_ids":                                     "mediafile",
-	"topic/current_projector_ids":                              "projector",
-	"topic/option_ids":                                         "option",
-	"topic/projection_ids":                                     "projection",
-	"topic/tag_ids":                                            "tag",
-	"user/assignment_candidate_$_ids":                          "assignment_candidate",
-	"user/committee_as_manager_ids":                            "committee",
-	"user/committee_as_member_ids":                             "committee",
-	"user/current_projector_$_ids":                             "projector",
-	"user/group_$_ids":                                         "group",
-	"user/guest_meeting_ids":                                   "meeting",
-	"user/is_present_in_meeting_ids":                           "meeting",
-	"user/option_$_ids":                                        "option",
-	"user/personal_note_$_ids":                                 "personal_note",
-	"user/poll_voted_$_ids":                                    "poll",
-	"user/projection_$_ids":                                    "projection",
-	"user/speaker_$_ids":                                       "speaker",
-	"user/submitted_motion_$_ids":                              "motion_submitter",
-	"user/supported_motion_$_ids":                              "motion",
-	"user/vote_$_ids":                                          "vote",
-	"user/vote_delegated_vote_$_ids":                           "vote",
-	"user/vote_delegations_$_from_ids":                         "user",
-}
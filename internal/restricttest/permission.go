@@ -0,0 +1,222 @@
+package restricttest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+)
+
+// Permission is a Permissioner, that decides with the groups of the user in
+// the datastore. It is a simple version of the permission service.
+//
+// A user can see a field, if it is a member of the meeting of the object and
+// has the required permission of the collection in one of its groups. The
+// meeting of an object is the field meeting_id. For users, it is each meeting
+// of the field group_$_ids.
+//
+// Members of the admin group of a meeting (meeting/admin_group_id) have all
+// permissions. A can_manage permission implies the can_see permission of the
+// same collection. A user can always see its own user object. The anonymous
+// user can not see anything, use restrict.Anonymous for it.
+type Permission struct {
+	ds       datastore.Getter
+	required map[string]string
+}
+
+// RequiredPermissions is a map from a collection to the permission, that a
+// member of a meeting needs to see the objects of that collection. It can be
+// used with NewPermission.
+var RequiredPermissions = map[string]string{
+	"agenda_item":                  "agenda_item.can_see",
+	"assignment":                   "assignment.can_see",
+	"assignment_candidate":         "assignment.can_see",
+	"assignment_poll":              "assignment.can_see",
+	"chat_group":                   "chat.can_manage",
+	"group":                        "",
+	"list_of_speakers":             "list_of_speakers.can_see",
+	"mediafile":                    "mediafile.can_see",
+	"meeting":                      "",
+	"meeting_user":                 "user.can_see",
+	"motion":                       "motion.can_see",
+	"motion_block":                 "motion.can_see",
+	"motion_category":              "motion.can_see",
+	"motion_change_recommendation": "motion.can_see",
+	"motion_comment":               "motion.can_see",
+	"motion_comment_section":       "motion.can_see",
+	"motion_poll":                  "motion.can_see",
+	"motion_state":                 "motion.can_see",
+	"motion_statute_paragraph":     "motion.can_see",
+	"motion_submitter":             "motion.can_see",
+	"motion_workflow":              "motion.can_see",
+	"personal_note":                "",
+	"projection":                   "projector.can_see",
+	"projector":                    "projector.can_see",
+	"projector_countdown":          "projector.can_see",
+	"projector_message":            "projector.can_see",
+	"speaker":                      "list_of_speakers.can_see",
+	"tag":                          "",
+	"topic":                        "agenda_item.can_see",
+	"user":                         "user.can_see",
+}
+
+// NewPermission initializes a Permission.
+//
+// required is a map from a collection to the permission, that is needed to see
+// objects of that collection, for example RequiredPermissions. An empty
+// permission means, that all members of the meeting can see the objects.
+// Collections that are not in the map can not be seen.
+func NewPermission(ds datastore.Getter, required map[string]string) *Permission {
+	return &Permission{
+		ds:       ds,
+		required: required,
+	}
+}
+
+// meetingPerms are the permissions of a user in a meeting.
+type meetingPerms struct {
+	member bool
+	admin  bool
+	perms  map[string]bool
+}
+
+// RestrictFQFields implements the Permissioner interface.
+func (p *Permission) RestrictFQFields(ctx context.Context, uid int, fqfields []string) (map[string]bool, error) {
+	allowed := make(map[string]bool, len(fqfields))
+	if uid == 0 {
+		return allowed, nil
+	}
+
+	perms := make(map[string]meetingPerms)
+	for _, fqfield := range fqfields {
+		parts := strings.Split(fqfield, "/")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid key %s", fqfield)
+		}
+		collection, id := parts[0], parts[1]
+
+		if collection == "user" && id == strconv.Itoa(uid) {
+			allowed[fqfield] = true
+			continue
+		}
+
+		required, ok := p.required[collection]
+		if !ok {
+			continue
+		}
+
+		meetingIDs, err := p.meetings(ctx, collection, id)
+		if err != nil {
+			return nil, fmt.Errorf("getting meetings of %s/%s: %w", collection, id, err)
+		}
+
+		for _, meetingID := range meetingIDs {
+			mp, ok := perms[meetingID]
+			if !ok {
+				mp, err = p.meetingPerms(ctx, uid, meetingID)
+				if err != nil {
+					return nil, fmt.Errorf("getting permissions in meeting %s: %w", meetingID, err)
+				}
+				perms[meetingID] = mp
+			}
+
+			if mp.member && (mp.admin || required == "" || hasPerm(mp.perms, required)) {
+				allowed[fqfield] = true
+				break
+			}
+		}
+	}
+	return allowed, nil
+}
+
+// meetings returns the meeting ids of an object.
+func (p *Permission) meetings(ctx context.Context, collection, id string) ([]string, error) {
+	switch collection {
+	case "meeting":
+		return []string{id}, nil
+
+	case "user":
+		var meetingIDs []string
+		if err := p.get(ctx, fmt.Sprintf("user/%s/group_$_ids", id), &meetingIDs); err != nil {
+			return nil, err
+		}
+		return meetingIDs, nil
+
+	default:
+		var meetingID int
+		if err := p.get(ctx, fmt.Sprintf("%s/%s/meeting_id", collection, id), &meetingID); err != nil {
+			return nil, err
+		}
+		if meetingID == 0 {
+			return nil, nil
+		}
+		return []string{strconv.Itoa(meetingID)}, nil
+	}
+}
+
+// meetingPerms returns the permissions of the user in a meeting.
+func (p *Permission) meetingPerms(ctx context.Context, uid int, meetingID string) (meetingPerms, error) {
+	var groupIDs []int
+	if err := p.get(ctx, fmt.Sprintf("user/%d/group_$%s_ids", uid, meetingID), &groupIDs); err != nil {
+		return meetingPerms{}, err
+	}
+
+	if len(groupIDs) == 0 {
+		return meetingPerms{}, nil
+	}
+
+	var adminGroupID int
+	if err := p.get(ctx, fmt.Sprintf("meeting/%s/admin_group_id", meetingID), &adminGroupID); err != nil {
+		return meetingPerms{}, err
+	}
+
+	mp := meetingPerms{member: true, perms: make(map[string]bool)}
+	for _, groupID := range groupIDs {
+		if groupID == adminGroupID {
+			mp.admin = true
+		}
+
+		var perms []string
+		if err := p.get(ctx, fmt.Sprintf("group/%d/permissions", groupID), &perms); err != nil {
+			return meetingPerms{}, err
+		}
+		for _, perm := range perms {
+			mp.perms[perm] = true
+		}
+	}
+	return mp, nil
+}
+
+// get decodes the value of a key. If the key does not exist, value is not
+// changed.
+func (p *Permission) get(ctx context.Context, key string, value interface{}) error {
+	values, err := p.ds.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("getting %s: %w", key, err)
+	}
+
+	if values[0] == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(values[0], value); err != nil {
+		return fmt.Errorf("decoding %s: %w", key, err)
+	}
+	return nil
+}
+
+// hasPerm returns true, if the permission is in perms or implied by a
+// permission in perms.
+func hasPerm(perms map[string]bool, perm string) bool {
+	if perms[perm] {
+		return true
+	}
+
+	if strings.HasSuffix(perm, ".can_see") {
+		return perms[strings.TrimSuffix(perm, ".can_see")+".can_manage"]
+	}
+	return false
+}
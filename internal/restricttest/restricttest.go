@@ -0,0 +1,248 @@
+// Package restricttest describes permission scenarios as data tables and runs
+// them against a restricter.
+//
+// A scenario contains the data of the datastore, the user, its permissions
+// and the keys the user is expected to see. For example:
+//
+//	restricttest.Run(t, []restricttest.Scenario{
+//		{
+//			Name:        "can see motions",
+//			UID:         1,
+//			Permissions: map[int][]string{1: {"motion.can_see"}},
+//			Visible:     []string{"motion/1/title"},
+//		},
+//	}, newRestricter, restricttest.WithData("motion/1/meeting_id: 1\nmotion/1/title: foo"))
+//
+// The Permissioner of this package decides with the groups in the datastore,
+// so the restricters can be tested without the permission service.
+package restricttest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+)
+
+// groupOffset is added to the meeting id to get the id of the generated group
+// of a scenario.
+const groupOffset = 1000
+
+// Restricter restricts keys. It is the same as autoupdate.Restricter.
+type Restricter interface {
+	Restrict(ctx context.Context, uid int, data map[string]json.RawMessage) error
+}
+
+// explainer is implemented by restricters, that can tell, which rule decided
+// about each key. It is used for the error messages.
+type explainer interface {
+	Explain(ctx context.Context, uid int, data map[string]json.RawMessage) (map[string]string, error)
+}
+
+// Scenario is one row in a permission table.
+type Scenario struct {
+	// Name is the name of the subtest.
+	Name string
+
+	// UID is the user, that requests the keys. 0 is the anonymous user.
+	UID int
+
+	// Data is the content of the datastore in the format of dsmock.YAMLData.
+	// If it is empty, the data of WithData is used.
+	Data string
+
+	// Permissions are the permissions of the user for each meeting id. For
+	// each meeting, a group with the id 1000+meeting id and the permissions
+	// is created and the user becomes a member. More complex setups can be
+	// described in Data.
+	Permissions map[int][]string
+
+	// Visible are the keys, the user is expected to see. They have to exist
+	// in the data.
+	Visible []string
+
+	// Hidden are the keys, the user is expected not to see. They have to
+	// exist in the data, so a typo does not pass as hidden key.
+	Hidden []string
+
+	// Values are keys with the expected value after the restriction, for
+	// example a filtered relation-list. The keys are also expected to be
+	// visible.
+	Values map[string]string
+}
+
+// data returns the content of the datastore with the generated groups.
+func (s Scenario) data() (map[string]string, error) {
+	data := dsmock.YAMLData(s.Data)
+	if len(s.Permissions) == 0 {
+		return data, nil
+	}
+
+	meetingsKey := fmt.Sprintf("user/%d/group_$_ids", s.UID)
+	var meetingIDs []string
+	if v, ok := data[meetingsKey]; ok {
+		if err := json.Unmarshal([]byte(v), &meetingIDs); err != nil {
+			return nil, fmt.Errorf("decoding %s: %w", meetingsKey, err)
+		}
+	}
+
+	for meetingID, perms := range s.Permissions {
+		groupID := groupOffset + meetingID
+		encodedPerms, err := json.Marshal(perms)
+		if err != nil {
+			return nil, fmt.Errorf("encoding permissions: %w", err)
+		}
+
+		data[fmt.Sprintf("group/%d/meeting_id", groupID)] = fmt.Sprint(meetingID)
+		data[fmt.Sprintf("group/%d/permissions", groupID)] = string(encodedPerms)
+		data[fmt.Sprintf("user/%d/group_$%d_ids", s.UID, meetingID)] = fmt.Sprintf("[%d]", groupID)
+		meetingIDs = append(meetingIDs, fmt.Sprint(meetingID))
+	}
+
+	sort.Strings(meetingIDs)
+	encoded, err := json.Marshal(meetingIDs)
+	if err != nil {
+		return nil, fmt.Errorf("encoding meeting ids: %w", err)
+	}
+	data[meetingsKey] = string(encoded)
+	return data, nil
+}
+
+// keys returns all keys, that the scenario checks.
+func (s Scenario) keys() []string {
+	keys := make([]string, 0, len(s.Visible)+len(s.Hidden)+len(s.Values))
+	keys = append(keys, s.Visible...)
+	keys = append(keys, s.Hidden...)
+	for k := range s.Values {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Option is an optional argument for Run.
+type Option func(*config)
+
+type config struct {
+	data string
+}
+
+// WithData sets the data of all scenarios, that do not have their own Data.
+func WithData(data string) Option {
+	return func(c *config) {
+		c.data = data
+	}
+}
+
+// Run runs each scenario as a subtest.
+//
+// newRestricter is called for each scenario with the datastore of the
+// scenario. The Restricter can use NewPermission as Permissioner.
+func Run(t *testing.T, scenarios []Scenario, newRestricter func(ds datastore.Getter) Restricter, options ...Option) {
+	t.Helper()
+
+	var cfg config
+	for _, o := range options {
+		o(&cfg)
+	}
+
+	for _, s := range scenarios {
+		s := s
+		if s.Data == "" {
+			s.Data = cfg.data
+		}
+
+		t.Run(s.Name, func(t *testing.T) {
+			closed := make(chan struct{})
+			defer close(closed)
+
+			content, err := s.data()
+			if err != nil {
+				t.Fatalf("Invalid scenario: %v", err)
+			}
+			ds := dsmock.NewMockDatastore(closed, content)
+
+			keys := s.keys()
+			values, err := ds.Get(context.Background(), keys...)
+			if err != nil {
+				t.Fatalf("Getting keys from datastore: %v", err)
+			}
+
+			data := make(map[string]json.RawMessage, len(keys))
+			for i, key := range keys {
+				if values[i] == nil {
+					t.Errorf("Key %s does not exist in the data of the scenario", key)
+				}
+				data[key] = values[i]
+			}
+
+			if t.Failed() {
+				return
+			}
+
+			reasons, err := restrict(newRestricter(ds), s.UID, data)
+			if err != nil {
+				t.Fatalf("Restrict returned unexpected error: %v", err)
+			}
+
+			for _, key := range s.Visible {
+				if data[key] == nil {
+					t.Errorf("Key %s is hidden, expected it to be visible%s", key, reasons.of(key))
+				}
+			}
+
+			for _, key := range s.Hidden {
+				if data[key] != nil {
+					t.Errorf("Key %s is visible, expected it to be hidden%s", key, reasons.of(key))
+				}
+			}
+
+			for key, expect := range s.Values {
+				if got := data[key]; !jsonEqual(got, []byte(expect)) {
+					t.Errorf("Key %s has value `%s`, expected `%s`%s", key, got, expect, reasons.of(key))
+				}
+			}
+		})
+	}
+}
+
+// explanation are the rules, that decided about each key.
+type explanation map[string]string
+
+// of returns the rule for a key as part of an error message.
+func (e explanation) of(key string) string {
+	reason, ok := e[key]
+	if !ok {
+		return ""
+	}
+	return " (" + reason + ")"
+}
+
+// restrict calls Explain, if the restricter supports it, or Restrict
+// otherwise.
+func restrict(r Restricter, uid int, data map[string]json.RawMessage) (explanation, error) {
+	if e, ok := r.(explainer); ok {
+		reasons, err := e.Explain(context.Background(), uid, data)
+		return reasons, err
+	}
+	return nil, r.Restrict(context.Background(), uid, data)
+}
+
+// jsonEqual returns true, if both values are the same json value. A nil value
+// is only equal to nil.
+func jsonEqual(a, b []byte) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return strings.TrimSpace(string(a)) == strings.TrimSpace(string(b))
+	}
+	return reflect.DeepEqual(va, vb)
+}
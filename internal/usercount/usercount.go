@@ -0,0 +1,209 @@
+// Package usercount creates calculated fields with the number of users of a
+// meeting.
+//
+// With these fields, a client can show the number of users without requesting
+// all users of the meeting. The value is the same for every user, that can
+// see the meeting.
+package usercount
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
+)
+
+const (
+	// UserAmount is the number of users in the meeting.
+	UserAmount = "meeting/user_amount"
+
+	// ActiveUserAmount is the number of users in the meeting, that are
+	// active.
+	ActiveUserAmount = "meeting/active_user_amount"
+
+	// PresentUserAmount is the number of users, that are present in the
+	// meeting. It is calculated from the field user/is_present_in_meeting_ids
+	// of the users of the meeting.
+	PresentUserAmount = "meeting/present_user_amount"
+)
+
+// Fields are the names of all calculated fields of this package.
+var Fields = []string{UserAmount, ActiveUserAmount, PresentUserAmount}
+
+// Register adds the calculated fields to the datastore.
+func Register(ds datastore.CalculatedRegisterer) {
+	datastore.RegisterCalculated(ds, UserAmount, func(ctx context.Context, key string) ([]byte, []string, error) {
+		return count(ctx, ds, key, "meeting_user_ids", nil)
+	})
+
+	datastore.RegisterCalculated(ds, PresentUserAmount, func(ctx context.Context, key string) ([]byte, []string, error) {
+		return count(ctx, ds, key, "meeting_user_ids", userFilter("is_present_in_meeting_ids", isPresent))
+	})
+
+	datastore.RegisterCalculated(ds, ActiveUserAmount, func(ctx context.Context, key string) ([]byte, []string, error) {
+		return count(ctx, ds, key, "meeting_user_ids", userFilter("is_active", isActive))
+	})
+}
+
+// count returns the number of ids in a field of the meeting. If filter is not
+// nil, only the ids returned by the filter are counted.
+//
+// It returns nil, if the meeting does not exist.
+func count(
+	ctx context.Context,
+	ds datastore.Getter,
+	key string,
+	field string,
+	filter filterFunc,
+) ([]byte, []string, error) {
+	parts := strings.SplitN(key, "/", 3)
+	if len(parts) != 3 {
+		return nil, nil, fmt.Errorf("invalid key %s, expected two '/'", key)
+	}
+	fqid := parts[0] + "/" + parts[1]
+
+	deps := []string{fqid + "/id", fqid + "/" + field}
+	values, err := ds.Get(ctx, deps...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching %s: %w", fqid, err)
+	}
+
+	if values[0] == nil {
+		return nil, deps, nil
+	}
+
+	var ids []int
+	if values[1] != nil {
+		if err := json.Unmarshal(values[1], &ids); err != nil {
+			return nil, nil, fmt.Errorf("decoding %s: %w", deps[1], err)
+		}
+	}
+
+	if filter != nil {
+		meetingID, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid meeting id in %s: %w", key, err)
+		}
+
+		filtered, filterDeps, err := filter(ctx, ds, meetingID, ids)
+		if err != nil {
+			return nil, nil, fmt.Errorf("filtering %s: %w", deps[1], err)
+		}
+		ids = filtered
+		deps = append(deps, filterDeps...)
+	}
+
+	return []byte(fmt.Sprint(len(ids))), deps, nil
+}
+
+// filterFunc returns the meeting users of a meeting, that match a condition,
+// and the keys, that were used.
+type filterFunc func(ctx context.Context, ds datastore.Getter, meetingID int, meetingUserIDs []int) ([]int, []string, error)
+
+// isActive is true, if the value of user/is_active is true.
+func isActive(value json.RawMessage, meetingID int) (bool, error) {
+	return string(value) == "true", nil
+}
+
+// isPresent is true, if the value of user/is_present_in_meeting_ids contains
+// the meeting.
+func isPresent(value json.RawMessage, meetingID int) (bool, error) {
+	if value == nil {
+		return false, nil
+	}
+
+	var ids []int
+	if err := json.Unmarshal(value, &ids); err != nil {
+		return false, fmt.Errorf("decoding meeting ids: %w", err)
+	}
+
+	for _, id := range ids {
+		if id == meetingID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// userFilter returns a filterFunc, that checks a field of the user of each
+// meeting user.
+func userFilter(field string, match func(value json.RawMessage, meetingID int) (bool, error)) filterFunc {
+	return func(ctx context.Context, ds datastore.Getter, meetingID int, meetingUserIDs []int) ([]int, []string, error) {
+		return filterUsers(ctx, ds, meetingID, meetingUserIDs, field, match)
+	}
+}
+
+// filterUsers returns the meeting users, where the field of the user matches.
+func filterUsers(
+	ctx context.Context,
+	ds datastore.Getter,
+	meetingID int,
+	meetingUserIDs []int,
+	field string,
+	match func(value json.RawMessage, meetingID int) (bool, error),
+) ([]int, []string, error) {
+	userKeys := make([]string, len(meetingUserIDs))
+	for i, id := range meetingUserIDs {
+		userKeys[i] = fmt.Sprintf("meeting_user/%d/user_id", id)
+	}
+
+	userIDs, err := ds.Get(ctx, userKeys...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching users: %w", err)
+	}
+
+	fieldKeys := make([]string, len(meetingUserIDs))
+	for i, rawID := range userIDs {
+		var userID int
+		if rawID != nil {
+			if err := json.Unmarshal(rawID, &userID); err != nil {
+				return nil, nil, fmt.Errorf("decoding %s: %w", userKeys[i], err)
+			}
+		}
+		fieldKeys[i] = fmt.Sprintf("user/%d/%s", userID, field)
+	}
+
+	values, err := ds.Get(ctx, fieldKeys...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching %s: %w", field, err)
+	}
+
+	var ids []int
+	for i, value := range values {
+		ok, err := match(value, meetingID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("checking %s: %w", fieldKeys[i], err)
+		}
+
+		if ok {
+			ids = append(ids, meetingUserIDs[i])
+		}
+	}
+	return ids, append(userKeys, fieldKeys...), nil
+}
+
+// Checker returns a restrict.Checker for the calculated fields. It removes the
+// value, if the user can not see the meeting.
+func Checker(permer restrict.Permissioner) restrict.Checker {
+	return restrict.CheckerFunc(func(ctx context.Context, uid int, key string, value json.RawMessage) (json.RawMessage, error) {
+		parts := strings.SplitN(key, "/", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid key %s, expected two '/'", key)
+		}
+
+		meetingKey := parts[0] + "/" + parts[1] + "/id"
+		allowed, err := permer.RestrictFQFields(ctx, uid, []string{meetingKey})
+		if err != nil {
+			return nil, fmt.Errorf("check meeting permission: %w", err)
+		}
+
+		if !allowed[meetingKey] {
+			return nil, nil
+		}
+		return value, nil
+	})
+}
@@ -0,0 +1,121 @@
+package usercount_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/usercount"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const data = `
+meeting/1/meeting_user_ids: [10, 11, 12]
+meeting/2/id: 2
+meeting_user/10/user_id: 1
+meeting_user/11/user_id: 2
+meeting_user/12/user_id: 3
+user/1:
+	is_active: true
+	is_present_in_meeting_ids: [1, 2]
+user/2/is_active: false
+user/3:
+	is_active: true
+	is_present_in_meeting_ids: [2]
+`
+
+func TestUserCount(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(data))
+	usercount.Register(ds)
+
+	for _, tt := range []struct {
+		key    string
+		expect string
+	}{
+		{"meeting/1/user_amount", "3"},
+		{"meeting/1/active_user_amount", "2"},
+		{"meeting/1/present_user_amount", "1"},
+		{"meeting/2/user_amount", "0"},
+		{"meeting/2/active_user_amount", "0"},
+		{"meeting/3/user_amount", ""},
+	} {
+		t.Run(tt.key, func(t *testing.T) {
+			values, err := ds.Get(context.Background(), tt.key)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expect, string(values[0]))
+		})
+	}
+}
+
+func TestActiveUserAmountUpdate(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(data))
+	usercount.Register(ds)
+
+	// Fetch data once to fill the cache.
+	_, err := ds.Get(context.Background(), "meeting/1/active_user_amount")
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	ds.RegisterChangeListener(func(map[string]json.RawMessage) error {
+		close(done)
+		return nil
+	})
+
+	ds.Send(map[string]string{"user/2/is_active": "true"})
+	<-done
+
+	values, err := ds.Get(context.Background(), "meeting/1/active_user_amount")
+	require.NoError(t, err)
+	assert.Equal(t, "3", string(values[0]))
+}
+
+func TestPresentUserAmountUpdate(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(data))
+	usercount.Register(ds)
+
+	// Fetch data once to fill the cache.
+	_, err := ds.Get(context.Background(), "meeting/1/present_user_amount")
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	ds.RegisterChangeListener(func(map[string]json.RawMessage) error {
+		close(done)
+		return nil
+	})
+
+	ds.Send(map[string]string{"user/3/is_present_in_meeting_ids": "[1, 2]"})
+	<-done
+
+	values, err := ds.Get(context.Background(), "meeting/1/present_user_amount")
+	require.NoError(t, err)
+	assert.Equal(t, "2", string(values[0]))
+}
+
+func TestChecker(t *testing.T) {
+	permer := &test.MockPermission{
+		Data: map[string]bool{
+			"meeting/1/id": true,
+		},
+	}
+	checker := usercount.Checker(permer)
+
+	value, err := checker.Check(context.Background(), 1, "meeting/1/user_amount", []byte("3"))
+	require.NoError(t, err)
+	assert.Equal(t, "3", string(value))
+
+	value, err = checker.Check(context.Background(), 1, "meeting/2/user_amount", []byte("3"))
+	require.NoError(t, err)
+	assert.Nil(t, value)
+}
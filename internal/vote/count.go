@@ -0,0 +1,147 @@
+package vote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
+)
+
+// CountField is the calculated field with the number of votes, that were cast
+// in a poll. The value is sent by the vote service on the message bus while
+// the poll is open. Before the first message, the field does not exist.
+const CountField = "poll/vote_count"
+
+// CountEventer receives the counter messages of the vote service. It blocks
+// until there is a new message and returns the number of votes for each
+// poll id.
+type CountEventer interface {
+	VoteCount(closing <-chan struct{}) (map[int]int, error)
+}
+
+// CountDatastore can register calculated fields and update them, when the vote
+// service sends a new count.
+type CountDatastore interface {
+	datastore.CalculatedRegisterer
+	UpdateCalculatedField(field string)
+}
+
+// counter holds the last vote count of each poll.
+type counter struct {
+	mu     sync.Mutex
+	counts map[int]int
+}
+
+func (c *counter) set(counts map[int]int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for pollID, count := range counts {
+		c.counts[pollID] = count
+	}
+}
+
+func (c *counter) get(pollID int) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	count, ok := c.counts[pollID]
+	return count, ok
+}
+
+// Count receives the vote counts and updates the calculated field
+// `poll/vote_count`. It has to be created with RegisterCount.
+type Count struct {
+	ds         CountDatastore
+	eventer    CountEventer
+	counter    *counter
+	errHandler func(error)
+}
+
+// RegisterCount adds the calculated field `poll/vote_count` to the datastore.
+// The counts are not received until Run is called.
+func RegisterCount(ds CountDatastore, eventer CountEventer, errHandler func(error)) *Count {
+	c := &counter{counts: make(map[int]int)}
+
+	datastore.RegisterCalculated(ds, CountField, func(ctx context.Context, key string) ([]byte, []string, error) {
+		var pollID int
+		if _, err := fmt.Sscanf(key, "poll/%d/", &pollID); err != nil {
+			return nil, nil, fmt.Errorf("invalid key %s: %w", key, err)
+		}
+
+		count, ok := c.get(pollID)
+		if !ok {
+			return nil, nil, nil
+		}
+		return []byte(strconv.Itoa(count)), nil, nil
+	})
+
+	return &Count{
+		ds:         ds,
+		eventer:    eventer,
+		counter:    c,
+		errHandler: errHandler,
+	}
+}
+
+// Run saves the counts from the eventer and updates the calculated keys. It
+// blocks until closed is closed.
+//
+// It should be called after all fields and change listeners are registered.
+func (c *Count) Run(closed <-chan struct{}) {
+	for {
+		select {
+		case <-closed:
+			return
+		default:
+		}
+
+		counts, err := c.eventer.VoteCount(closed)
+		if err != nil {
+			select {
+			case <-closed:
+				return
+			default:
+			}
+
+			c.errHandler(fmt.Errorf("receiving vote count: %w", err))
+			time.Sleep(time.Second)
+			continue
+		}
+
+		if len(counts) == 0 {
+			continue
+		}
+
+		c.counter.set(counts)
+		c.ds.UpdateCalculatedField(CountField)
+	}
+}
+
+// CountChecker returns a restrict.Checker for the field poll/vote_count. The
+// value is only visible for users, that can see the poll.
+func CountChecker(permer restrict.Permissioner) restrict.Checker {
+	return restrict.CheckerFunc(func(ctx context.Context, uid int, key string, value json.RawMessage) (json.RawMessage, error) {
+		parts := strings.SplitN(key, "/", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid key %s, expected two '/'", key)
+		}
+
+		pollKey := parts[0] + "/" + parts[1] + "/id"
+		allowed, err := permer.RestrictFQFields(ctx, uid, []string{pollKey})
+		if err != nil {
+			return nil, fmt.Errorf("check poll permission: %w", err)
+		}
+
+		if !allowed[pollKey] {
+			return nil, nil
+		}
+		return value, nil
+	})
+}
@@ -0,0 +1,77 @@
+package vote_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/vote"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countEventer sends the counts from a channel.
+type countEventer chan map[int]int
+
+func (c countEventer) VoteCount(closing <-chan struct{}) (map[int]int, error) {
+	select {
+	case counts := <-c:
+		return counts, nil
+	case <-closing:
+		return nil, closingError{}
+	}
+}
+
+type closingError struct{}
+
+func (closingError) Closing()      {}
+func (closingError) Error() string { return "closing" }
+
+func TestVoteCount(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, nil)
+	eventer := make(countEventer)
+	count := vote.RegisterCount(ds, eventer, func(err error) { t.Errorf("Unexpected error: %v", err) })
+
+	fields, err := ds.Get(context.Background(), "poll/1/vote_count", "poll/2/vote_count")
+	require.NoError(t, err)
+	assert.Nil(t, fields[0], "before the first count")
+
+	received := make(chan map[string]json.RawMessage, 1)
+	ds.RegisterChangeListener(func(data map[string]json.RawMessage) error {
+		received <- data
+		return nil
+	})
+	go count.Run(closed)
+
+	eventer <- map[int]int{1: 5, 3: 1}
+
+	data := <-received
+	assert.Equal(t, map[string]json.RawMessage{"poll/1/vote_count": []byte("5")}, data)
+
+	fields, err = ds.Get(context.Background(), "poll/1/vote_count", "poll/3/vote_count")
+	require.NoError(t, err)
+	assert.Equal(t, "5", string(fields[0]))
+	assert.Equal(t, "1", string(fields[1]), "count received before the first request")
+}
+
+func TestCountChecker(t *testing.T) {
+	permer := &test.MockPermission{
+		Data: map[string]bool{
+			"poll/1/id": true,
+		},
+	}
+	checker := vote.CountChecker(permer)
+
+	got, err := checker.Check(context.Background(), 1, "poll/1/vote_count", []byte("5"))
+	require.NoError(t, err)
+	assert.Equal(t, "5", string(got))
+
+	got, err = checker.Check(context.Background(), 1, "poll/2/vote_count", []byte("5"))
+	require.NoError(t, err)
+	assert.Nil(t, got, "poll not visible")
+}
@@ -0,0 +1,199 @@
+// Package vote creates the calculated field `poll/vote_service`.
+//
+// When a poll is started, the field contains everything a client needs to
+// send its ballot to the vote service. When the poll is not started or it is
+// an analog poll, the field does not exist.
+//
+// The calculated value is the same for every user. The Checker from this
+// package removes the field, if the user can not see the poll, and adds, if
+// the user is allowed to vote.
+//
+// The calculated field `poll/vote_count` contains the number of votes of an
+// open poll. It is fed by the counter messages of the vote service, so the
+// clients do not have to ask the vote service.
+package vote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
+)
+
+const (
+	// Field is the name of the calculated field.
+	Field = "poll/vote_service"
+
+	// DefaultURL is the url of the vote service, that is used when no other
+	// url is configured.
+	DefaultURL = "/system/vote"
+
+	// stateStarted is the value of poll/state for started polls.
+	stateStarted = "started"
+
+	// typeAnalog is the value of poll/type for polls that are not handled by
+	// the vote service.
+	typeAnalog = "analog"
+)
+
+// sourceFields are the fields of a poll, the calculated field depends on.
+var sourceFields = []string{"state", "type", "meeting_id", "entitled_group_ids"}
+
+// Datastore gets values for keys and can register calculated fields.
+type Datastore interface {
+	datastore.CalculatedRegisterer
+}
+
+// Service is the value of the calculated field.
+type Service struct {
+	URL              string `json:"url"`
+	PollID           int    `json:"poll_id"`
+	MeetingID        int    `json:"meeting_id"`
+	EntitledGroupIDs []int  `json:"entitled_group_ids"`
+
+	// CanVote is set by the Checker for the requesting user.
+	CanVote bool `json:"can_vote"`
+}
+
+// Register adds the calculated field `poll/vote_service` to the datastore.
+//
+// The argument url is the url of the vote service as the client sees it.
+func Register(ds Datastore, url string) {
+	datastore.RegisterCalculated(ds, Field, func(ctx context.Context, fqfield string) ([]byte, []string, error) {
+		parts := strings.SplitN(fqfield, "/", 3)
+		if len(parts) != 3 {
+			return nil, nil, fmt.Errorf("invalid key %s, expected two '/'", fqfield)
+		}
+		fqid := parts[0] + "/" + parts[1]
+
+		value, err := calculate(ctx, ds, url, fqid)
+		return value, sourceKeys(fqid), err
+	})
+}
+
+// calculate returns the value of the calculated field for a poll.
+func calculate(ctx context.Context, ds datastore.Getter, url, fqid string) ([]byte, error) {
+	fetch := datastore.NewFetcher(ds)
+	state := fetch.String(ctx, "%s/state", fqid)
+	if err := fetch.Error(); err != nil {
+		var errNotExist datastore.DoesNotExistError
+		if errors.As(err, &errNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("fetching state of %s: %w", fqid, err)
+	}
+
+	if state != stateStarted {
+		return nil, nil
+	}
+
+	var pollType string
+	fetch.Value(ctx, &pollType, "%s/type", fqid)
+	if err := fetch.Error(); err != nil {
+		var errNotExist datastore.DoesNotExistError
+		if !errors.As(err, &errNotExist) {
+			return nil, fmt.Errorf("fetching type of %s: %w", fqid, err)
+		}
+	}
+
+	if pollType == typeAnalog {
+		return nil, nil
+	}
+
+	fetch = datastore.NewFetcher(ds)
+	meetingID := fetch.Int(ctx, "%s/meeting_id", fqid)
+	if err := fetch.Error(); err != nil {
+		return nil, fmt.Errorf("fetching meeting of %s: %w", fqid, err)
+	}
+
+	var groupIDs []int
+	fetch.Value(ctx, &groupIDs, "%s/entitled_group_ids", fqid)
+	if err := fetch.Error(); err != nil {
+		var errNotExist datastore.DoesNotExistError
+		if !errors.As(err, &errNotExist) {
+			return nil, fmt.Errorf("fetching entitled groups of %s: %w", fqid, err)
+		}
+	}
+
+	var pollID int
+	if _, err := fmt.Sscanf(fqid, "poll/%d", &pollID); err != nil {
+		return nil, fmt.Errorf("invalid poll id in %s: %w", fqid, err)
+	}
+
+	return json.Marshal(Service{
+		URL:              url,
+		PollID:           pollID,
+		MeetingID:        meetingID,
+		EntitledGroupIDs: groupIDs,
+	})
+}
+
+// sourceKeys returns the keys of the poll, the calculated field depends on.
+func sourceKeys(fqid string) []string {
+	keys := make([]string, len(sourceFields))
+	for i, field := range sourceFields {
+		keys[i] = fqid + "/" + field
+	}
+	return keys
+}
+
+// Checker returns a restrict.Checker for the calculated field. It removes the
+// value, if the user can not see the poll. Otherwise it sets, if the user is
+// in one of the entitled groups.
+func Checker(permer restrict.Permissioner, ds datastore.Getter) restrict.Checker {
+	return restrict.CheckerFunc(func(ctx context.Context, uid int, key string, value json.RawMessage) (json.RawMessage, error) {
+		var service Service
+		if err := json.Unmarshal(value, &service); err != nil {
+			return nil, fmt.Errorf("decoding %s=%s: %w", key, value, err)
+		}
+
+		pollKey := fmt.Sprintf("poll/%d/id", service.PollID)
+		allowed, err := permer.RestrictFQFields(ctx, uid, []string{pollKey})
+		if err != nil {
+			return nil, fmt.Errorf("check poll permission: %w", err)
+		}
+
+		if !allowed[pollKey] {
+			return nil, nil
+		}
+
+		if uid == 0 {
+			return value, nil
+		}
+
+		fetch := datastore.NewFetcher(ds)
+		var userGroupIDs []int
+		fetch.Value(ctx, &userGroupIDs, "user/%d/group_$%d_ids", uid, service.MeetingID)
+		if err := fetch.Error(); err != nil {
+			var errNotExist datastore.DoesNotExistError
+			if !errors.As(err, &errNotExist) {
+				return nil, fmt.Errorf("fetching groups of user %d: %w", uid, err)
+			}
+		}
+
+		service.CanVote = intersect(userGroupIDs, service.EntitledGroupIDs)
+		if !service.CanVote {
+			return value, nil
+		}
+		return json.Marshal(service)
+	})
+}
+
+// intersect returns true, if the two lists have at least one common element.
+func intersect(a, b []int) bool {
+	set := make(map[int]bool, len(a))
+	for _, v := range a {
+		set[v] = true
+	}
+
+	for _, v := range b {
+		if set[v] {
+			return true
+		}
+	}
+	return false
+}
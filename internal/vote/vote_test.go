@@ -0,0 +1,113 @@
+package vote_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/vote"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVoteService(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, map[string]string{
+		"poll/1/state":              `"started"`,
+		"poll/1/type":               `"named"`,
+		"poll/1/meeting_id":         `7`,
+		"poll/1/entitled_group_ids": `[2,3]`,
+
+		"poll/2/state":      `"created"`,
+		"poll/2/type":       `"named"`,
+		"poll/2/meeting_id": `7`,
+
+		"poll/3/state":      `"started"`,
+		"poll/3/type":       `"analog"`,
+		"poll/3/meeting_id": `7`,
+	})
+	vote.Register(ds, "/vote")
+
+	fields, err := ds.Get(context.Background(), "poll/1/vote_service", "poll/2/vote_service", "poll/3/vote_service", "poll/4/vote_service")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"url":"/vote","poll_id":1,"meeting_id":7,"entitled_group_ids":[2,3],"can_vote":false}`, string(fields[0]))
+	assert.Nil(t, fields[1], "created poll")
+	assert.Nil(t, fields[2], "analog poll")
+	assert.Nil(t, fields[3], "not existing poll")
+}
+
+func TestVoteServiceUpdate(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, map[string]string{
+		"poll/1/state":      `"created"`,
+		"poll/1/type":       `"named"`,
+		"poll/1/meeting_id": `7`,
+	})
+	vote.Register(ds, "/vote")
+
+	// Fetch data once to fill the cache.
+	fields, err := ds.Get(context.Background(), "poll/1/vote_service")
+	require.NoError(t, err)
+	require.Nil(t, fields[0])
+
+	done := make(chan struct{})
+	ds.RegisterChangeListener(func(map[string]json.RawMessage) error {
+		close(done)
+		return nil
+	})
+
+	ds.Send(map[string]string{"poll/1/state": `"started"`})
+	<-done
+
+	fields, err = ds.Get(context.Background(), "poll/1/vote_service")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"url":"/vote","poll_id":1,"meeting_id":7,"entitled_group_ids":null,"can_vote":false}`, string(fields[0]))
+}
+
+func TestChecker(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, map[string]string{
+		"user/1/group_$7_ids": `[3]`,
+		"user/2/group_$7_ids": `[4]`,
+	})
+
+	permer := &test.MockPermission{
+		Data: map[string]bool{
+			"poll/1/id": true,
+		},
+	}
+	checker := vote.Checker(permer, ds)
+
+	value := []byte(`{"url":"/vote","poll_id":1,"meeting_id":7,"entitled_group_ids":[2,3],"can_vote":false}`)
+
+	for _, tt := range []struct {
+		name   string
+		uid    int
+		value  string
+		expect string
+	}{
+		{"entitled", 1, string(value), `{"url":"/vote","poll_id":1,"meeting_id":7,"entitled_group_ids":[2,3],"can_vote":true}`},
+		{"not entitled", 2, string(value), string(value)},
+		{"unknown user", 3, string(value), string(value)},
+		{"poll not visible", 1, `{"url":"/vote","poll_id":2,"meeting_id":7,"entitled_group_ids":[2,3],"can_vote":false}`, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checker.Check(context.Background(), tt.uid, "poll/1/vote_service", []byte(tt.value))
+			require.NoError(t, err)
+
+			if tt.expect == "" {
+				assert.Nil(t, got)
+				return
+			}
+			assert.JSONEq(t, tt.expect, string(got))
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dgrijalva/jwt-go/v4"
@@ -23,6 +24,10 @@ const cookieName = "refreshId"
 const authHeader = "Authentication"
 const authPath = "/internal/auth/authenticate"
 
+// userLogoutFormat is the format of a logout event, that revokes all sessions
+// of a user.
+const userLogoutFormat = "user/%d"
+
 // Auth authenticates a request against the openslides-auth-service.
 //
 // Has to be initialized with auth.New().
@@ -36,10 +41,30 @@ type Auth struct {
 
 	tokenKey  []byte
 	cookieKey []byte
+
+	sessionCheck time.Duration
+}
+
+// Option is an optional argument for New.
+type Option func(*Auth)
+
+// WithSessionCheck validates the session of each open request periodically
+// with the auth service. Long requests outlive the access token. If the auth
+// service does not renew the token anymore, the context of the request is
+// closed with an error with the code CodeInvalidSession. The default is 0,
+// which disables the check.
+func WithSessionCheck(interval time.Duration) Option {
+	return func(a *Auth) {
+		a.sessionCheck = interval
+	}
 }
 
 // New initializes an Auth service.
-func New(authServiceURL string, logoutEventer LogoutEventer, closed <-chan struct{}, errHandler func(error), tokenKey, cookieKey []byte) (*Auth, error) {
+func New(authServiceURL string, logoutEventer LogoutEventer, closed <-chan struct{}, errHandler func(error), tokenKey, cookieKey []byte, options ...Option) (*Auth, error) {
+	if errHandler == nil {
+		errHandler = func(error) {}
+	}
+
 	a := &Auth{
 		closed:           closed,
 		errHandler:       errHandler,
@@ -50,6 +75,10 @@ func New(authServiceURL string, logoutEventer LogoutEventer, closed <-chan struc
 		cookieKey:        cookieKey,
 	}
 
+	for _, o := range options {
+		o(a)
+	}
+
 	// Make sure the topic is not empty
 	a.logedoutSessions.Publish("")
 
@@ -63,10 +92,13 @@ func New(authServiceURL string, logoutEventer LogoutEventer, closed <-chan struc
 }
 
 // Authenticate uses the headers from the given request to get the user id. The
-// returned context will be cancled, if the session is revoked.
+// returned context will be cancled, if the session is revoked. In this case,
+// the Err method of the context returns an error with the code
+// CodeInvalidSession.
 func (a *Auth) Authenticate(w http.ResponseWriter, r *http.Request) (ctx context.Context, err error) {
 	p := new(payload)
-	if err := a.loadToken(w, r, p); err != nil {
+	token, cookie, err := a.loadToken(w, r, p)
+	if err != nil {
 		return nil, fmt.Errorf("reading token: %w", err)
 	}
 
@@ -76,39 +108,86 @@ func (a *Auth) Authenticate(w http.ResponseWriter, r *http.Request) (ctx context
 		return r.Context(), nil
 	}
 
-	_, sessionIDs, err := a.logedoutSessions.Receive(context.Background(), 0)
+	// Revocations of all sessions of the user before this request are ignored,
+	// because the user can login again afterwards.
+	lastCID, sessionIDs, err := a.logedoutSessions.Receive(context.Background(), 0)
 	if err != nil {
 		return nil, fmt.Errorf("getting already logged out sessions: %w", err)
 	}
 	for _, sid := range sessionIDs {
 		if sid == p.SessionID {
-			return nil, &authError{"invalid session", nil}
+			return nil, &authError{"invalid session", CodeInvalidSession, nil}
 		}
 	}
 
-	ctx, cancelCtx := context.WithCancel(context.WithValue(r.Context(), userIDType, p.UserID))
+	sctx := newSessionContext(context.WithValue(r.Context(), userIDType, p.UserID))
+
+	if a.sessionCheck > 0 {
+		go a.checkSession(sctx, token, cookie)
+	}
+
+	userLogout := fmt.Sprintf(userLogoutFormat, p.UserID)
 
 	go func() {
-		defer cancelCtx()
+		defer sctx.end(nil)
 
-		var cid uint64
+		cid := lastCID
 		var sessionIDs []string
 		var err error
 		for {
-			cid, sessionIDs, err = a.logedoutSessions.Receive(ctx, cid)
+			cid, sessionIDs, err = a.logedoutSessions.Receive(sctx, cid)
 			if err != nil {
 				return
 			}
 
 			for _, sid := range sessionIDs {
 				if sid == p.SessionID {
+					sctx.end(authError{"Session was logged out", CodeInvalidSession, nil})
+					return
+				}
+
+				if sid == userLogout {
+					sctx.end(authError{"All sessions of the user were logged out", CodeInvalidSession, nil})
 					return
 				}
 			}
 		}
 	}()
 
-	return ctx, nil
+	return sctx, nil
+}
+
+// checkSession renews the token periodically with the auth service until the
+// context is done. If the auth service rejects the token, the context is closed.
+func (a *Auth) checkSession(ctx *sessionContext, token, cookie string) {
+	tick := time.NewTicker(a.sessionCheck)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+
+		newToken, err := a.refreshToken(ctx, token, cookie)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			var errAuth authError
+			if errors.As(err, &errAuth) {
+				ctx.end(authError{"Session is not valid anymore", CodeInvalidSession, err})
+				return
+			}
+
+			// The auth service is not reachable. Try again later.
+			a.errHandler(fmt.Errorf("checking session: %w", err))
+			continue
+		}
+		token = newToken
+	}
 }
 
 // FromContext returnes the user id from a context returned by Authenticate().
@@ -162,59 +241,97 @@ func (a *Auth) pruneLogoutEvent(closed <-chan struct{}) {
 
 // loadToken loads and validates the ticket. If the token is expires, it tries
 // to renews it and writes the new token to the responsewriter.
-func (a *Auth) loadToken(w http.ResponseWriter, r *http.Request, payload jwt.Claims) error {
+//
+// It returns the encoded token and cookie. They are empty for anonymous
+// requests.
+func (a *Auth) loadToken(w http.ResponseWriter, r *http.Request, payload jwt.Claims) (string, string, error) {
 	header := r.Header.Get(authHeader)
 	cookie, err := r.Cookie(cookieName)
 	if err != nil && err != http.ErrNoCookie {
-		return fmt.Errorf("reading cookie: %w", err)
+		return "", "", fmt.Errorf("reading cookie: %w", err)
 	}
 
 	encodedToken := strings.TrimPrefix(header, "bearer ")
 
 	if cookie == nil && header == encodedToken {
 		// No token and no auth cookie. Handle the request as anonymous requst.
-		return nil
+		return "", "", nil
 	}
 
 	if cookie == nil && header != encodedToken {
-		return authError{"Can not find auth cookie", nil}
+		return "", "", authError{"Can not find auth cookie", CodeMissingToken, nil}
 	}
 
 	if cookie != nil && header == encodedToken {
-		return authError{"Can not find auth token", nil}
+		return "", "", authError{"Can not find auth token", CodeMissingToken, nil}
 	}
 
 	encodedCookie := strings.TrimPrefix(cookie.Value, "bearer%20")
 
 	_, err = jwt.Parse(encodedCookie, jwt.KnownKeyfunc(jwt.SigningMethodHS256, a.cookieKey))
 	if err != nil {
-		var invalid *jwt.InvalidSignatureError
-		if errors.As(err, &invalid) {
-			return authError{"Invalid auth ticket", err}
+		var expired *jwt.TokenExpiredError
+		if errors.As(err, &expired) {
+			return "", "", authError{"Auth cookie is expired", CodeTokenExpired, err}
 		}
-		return fmt.Errorf("validating auth cookie: %w", err)
+		return "", "", authError{"Invalid auth ticket", CodeInvalidToken, err}
 	}
 
 	_, err = jwt.ParseWithClaims(encodedToken, payload, jwt.KnownKeyfunc(jwt.SigningMethodHS256, a.tokenKey))
 	if err != nil {
-		var invalid *jwt.InvalidSignatureError
-		if errors.As(err, &invalid) {
-			return authError{"Invalid auth ticket", err}
-		}
-
 		var expired *jwt.TokenExpiredError
 		if !errors.As(err, &expired) {
-			return fmt.Errorf("validating auth token: %w", err)
+			return "", "", authError{"Invalid auth ticket", CodeInvalidToken, err}
 		}
 
 		token, err := a.refreshToken(r.Context(), encodedToken, encodedCookie)
 		if err != nil {
-			return fmt.Errorf("refreshing token: %w", err)
+			return "", "", fmt.Errorf("refreshing token: %w", err)
 		}
 		w.Header().Set(authHeader, "bearer "+token)
+		encodedToken = token
 	}
 
-	return nil
+	return encodedToken, encodedCookie, nil
+}
+
+// sessionContext is the context of an authenticated request. It is closed,
+// when the session ends. In this case, Err returns the reason.
+type sessionContext struct {
+	context.Context
+	cancel context.CancelFunc
+
+	mu  sync.Mutex
+	err error
+}
+
+func newSessionContext(parent context.Context) *sessionContext {
+	ctx, cancel := context.WithCancel(parent)
+	return &sessionContext{Context: ctx, cancel: cancel}
+}
+
+// end closes the context. If err is not nil and the context was not closed
+// before, Err returns it.
+func (c *sessionContext) end(err error) {
+	c.mu.Lock()
+	if c.err == nil && c.Context.Err() == nil {
+		c.err = err
+	}
+	c.mu.Unlock()
+
+	c.cancel()
+}
+
+// Err returns the reason, why the session ended or the error of the parent
+// context.
+func (c *sessionContext) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+	return c.Context.Err()
 }
 
 func (a *Auth) refreshToken(ctx context.Context, token, cookie string) (string, error) {
@@ -232,6 +349,10 @@ func (a *Auth) refreshToken(ctx context.Context, token, cookie string) (string,
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		return "", authError{"Can not refresh token", CodeTokenExpired, fmt.Errorf("auth-service returned status %s", resp.Status)}
+	}
+
 	if resp.StatusCode != 200 {
 		return "", fmt.Errorf("auth-service returned status %s", resp.Status)
 	}
@@ -247,13 +368,33 @@ func (a *Auth) refreshToken(ctx context.Context, token, cookie string) (string,
 		if rPayload.Message == "" {
 			rPayload.Message = "Can not refresh token"
 		}
-		return "", authError{rPayload.Message, nil}
+		return "", authError{rPayload.Message, CodeTokenExpired, nil}
 
 	}
 
 	return newToken, nil
 }
 
+// Ping checks, if the auth service is reachable. Each response with a status
+// code lower than 500 means, that it is reachable.
+func (a *Auth) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", a.authServiceURL+authPath, nil)
+	if err != nil {
+		return fmt.Errorf("creating auth request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request to auth service: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("auth-service returned status %s", resp.Status)
+	}
+	return nil
+}
+
 type authString string
 
 const userIDType authString = "user_id"
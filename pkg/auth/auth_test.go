@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -198,6 +199,70 @@ func TestAuth(t *testing.T) {
 	}
 }
 
+func TestAuthErrorCodes(t *testing.T) {
+	const secret = "auth-dev-key"
+
+	sign := func(claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatalf("Can not sign token: %v", err)
+		}
+		return token
+	}
+
+	validCookie := "refreshId=bearer%20" + sign(jwt.MapClaims{"sessionId": "123"})
+	expiredCookie := "refreshId=bearer%20" + sign(jwt.MapClaims{"sessionId": "123", "exp": 123})
+	expiredHeader := "bearer " + sign(jwt.MapClaims{"userId": 1, "sessionId": "123", "exp": 123})
+
+	authSRV := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "session is expired", http.StatusForbidden)
+	}))
+	defer authSRV.Close()
+
+	a, err := auth.New(authSRV.URL, nil, nil, nil, []byte(secret), []byte(secret))
+	if err != nil {
+		t.Fatalf("Can not create auth service: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		cookie string
+		header string
+		code   string
+	}{
+		{"missing cookie", "", expiredHeader, auth.CodeMissingToken},
+		{"malformed token", validCookie, "bearer malformed", auth.CodeInvalidToken},
+		{"expired cookie", expiredCookie, expiredHeader, auth.CodeTokenExpired},
+		{"refresh rejected", validCookie, expiredHeader, auth.CodeTokenExpired},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("Authentication", tt.header)
+			if tt.cookie != "" {
+				r.Header.Set("Cookie", tt.cookie)
+			}
+
+			_, err := a.Authenticate(httptest.NewRecorder(), r)
+
+			var authErr interface {
+				Code() string
+				StatusCode() int
+			}
+			if !errors.As(err, &authErr) {
+				t.Fatalf("Got error `%v`, expected an auth error", err)
+			}
+
+			if authErr.Code() != tt.code {
+				t.Errorf("Got code %s, expected %s", authErr.Code(), tt.code)
+			}
+
+			if authErr.StatusCode() != http.StatusUnauthorized {
+				t.Errorf("Got status %d, expected 401", authErr.StatusCode())
+			}
+		})
+	}
+}
+
 func TestFromContext(t *testing.T) {
 	a, err := auth.New("", nil, nil, nil, []byte(""), []byte(""))
 	if err != nil {
@@ -260,6 +325,11 @@ func TestLogout(t *testing.T) {
 		if lastErr != nil {
 			t.Errorf("Got error on logout: %v", err)
 		}
+
+		var errCode interface{ Code() string }
+		if !errors.As(ctx.Err(), &errCode) || errCode.Code() != auth.CodeInvalidSession {
+			t.Errorf("Got context error `%v`, expected code %s", ctx.Err(), auth.CodeInvalidSession)
+		}
 	})
 
 	t.Run("Already closed session", func(t *testing.T) {
@@ -302,6 +372,40 @@ func TestLogout(t *testing.T) {
 			t.Errorf("Got error on logout: %v", err)
 		}
 	})
+
+	t.Run("Closing all sessions of user", func(t *testing.T) {
+		ctx1, err := a.Authenticate(validSession(t, withSessionID("session4")))
+		if err != nil {
+			t.Fatalf("Can not authenticat: %v", err)
+		}
+
+		ctx2, err := a.Authenticate(validSession(t, withSessionID("session5")))
+		if err != nil {
+			t.Fatalf("Can not authenticat: %v", err)
+		}
+
+		logouter.Send([]string{"user/1"})
+
+		for _, ctx := range []context.Context{ctx1, ctx2} {
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+				t.Fatalf("context is not closed after logout of the user")
+			}
+		}
+
+		// The user can login again.
+		ctx, err := a.Authenticate(validSession(t, withSessionID("session6")))
+		if err != nil {
+			t.Fatalf("Can not authenticate after logout of the user: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			t.Errorf("context of new session is closed")
+		case <-time.After(time.Millisecond):
+		}
+	})
 }
 
 func validSession(t *testing.T, opts ...validOption) (http.ResponseWriter, *http.Request) {
@@ -350,3 +454,44 @@ func withSessionID(sid string) validOption {
 		v.sessionID = sid
 	}
 }
+
+func TestSessionCheck(t *testing.T) {
+	var rejected int32
+	authSRV := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&rejected) == 1 {
+			http.Error(w, `{"message": "session is expired"}`, http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Authentication", "bearer NEWTOKEN")
+	}))
+	defer authSRV.Close()
+
+	a, err := auth.New(authSRV.URL, nil, nil, nil, []byte(""), []byte(""), auth.WithSessionCheck(time.Millisecond))
+	if err != nil {
+		t.Fatalf("Can not create auth service: %v", err)
+	}
+
+	ctx, err := a.Authenticate(validSession(t))
+	if err != nil {
+		t.Fatalf("Can not authenticate: %v", err)
+	}
+
+	// Wait for some successful checks.
+	time.Sleep(5 * time.Millisecond)
+	if err := ctx.Err(); err != nil {
+		t.Fatalf("Context was closed while the session is valid: %v", err)
+	}
+
+	atomic.StoreInt32(&rejected, 1)
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("Context was not closed after the session was rejected")
+	}
+
+	var errCode interface{ Code() string }
+	if !errors.As(ctx.Err(), &errCode) || errCode.Code() != auth.CodeInvalidSession {
+		t.Errorf("Got context error `%v`, expected code %s", ctx.Err(), auth.CodeInvalidSession)
+	}
+}
@@ -1,7 +1,30 @@
 package auth
 
+import "net/http"
+
+// Codes of the auth errors. They are sent to the client, so it can decide, if
+// it has to login again.
+const (
+	// CodeMissingToken means, that the request contains only the token or
+	// only the cookie.
+	CodeMissingToken = "missing_token"
+
+	// CodeInvalidToken means, that the token or the cookie is not a valid
+	// jwt or has a wrong signature.
+	CodeInvalidToken = "invalid_token"
+
+	// CodeTokenExpired means, that the token is expired and could not be
+	// renewed, because the cookie is also expired or the auth service
+	// rejected it. The client has to login again.
+	CodeTokenExpired = "token_expired"
+
+	// CodeInvalidSession means, that the session was logged out.
+	CodeInvalidSession = "invalid_session"
+)
+
 type authError struct {
 	msg     string
+	code    string
 	wrapped error
 }
 
@@ -13,6 +36,16 @@ func (a authError) Error() string {
 	return a.msg
 }
 
+// Code returns one of the Code constants.
+func (a authError) Code() string {
+	return a.code
+}
+
+// StatusCode is the http status code for the error.
+func (authError) StatusCode() int {
+	return http.StatusUnauthorized
+}
+
 func (a authError) Unwrap() error {
 	return a.wrapped
 }
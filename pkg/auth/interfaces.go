@@ -3,7 +3,8 @@ package auth
 // LogoutEventer tells, when a sessionID gets revoked.
 //
 // The method LogoutEvent has to block until there are new data. The returned
-// data is a list of sessionIDs that are revoked.
+// data is a list of sessionIDs that are revoked. An entry `user/ID`, for example
+// `user/5`, revokes all sessions of the user with this id.
 type LogoutEventer interface {
 	LogoutEvent(<-chan struct{}) ([]string, error)
 }
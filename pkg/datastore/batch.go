@@ -0,0 +1,81 @@
+package datastore
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// batcher combines the keys of requests in a short time window into one
+// request to the datastore.
+//
+// The cache makes sure, that the same key is not requested twice at the same
+// time. The batcher makes sure, that different keys, requested by different
+// connections at the same time, are fetched with one request.
+type batcher struct {
+	window time.Duration
+	fetch  func(keys []string) (map[string]json.RawMessage, error)
+
+	mu      sync.Mutex
+	pending *batch
+}
+
+// batch is one request to the datastore with the keys of many callers.
+type batch struct {
+	keys map[string]bool
+	done chan struct{}
+	data map[string]json.RawMessage
+	err  error
+}
+
+// request fetches the given keys. It blocks until the batch, that contains the
+// keys, is fetched.
+func (b *batcher) request(keys []string) (map[string]json.RawMessage, error) {
+	if b.window <= 0 {
+		return b.fetch(keys)
+	}
+
+	b.mu.Lock()
+	if b.pending == nil {
+		b.pending = &batch{
+			keys: make(map[string]bool),
+			done: make(chan struct{}),
+		}
+		time.AfterFunc(b.window, b.run)
+	}
+
+	current := b.pending
+	for _, key := range keys {
+		current.keys[key] = true
+	}
+	b.mu.Unlock()
+
+	<-current.done
+	if current.err != nil {
+		return nil, current.err
+	}
+
+	data := make(map[string]json.RawMessage, len(keys))
+	for _, key := range keys {
+		if value, ok := current.data[key]; ok {
+			data[key] = value
+		}
+	}
+	return data, nil
+}
+
+// run fetches the pending batch.
+func (b *batcher) run() {
+	b.mu.Lock()
+	current := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	keys := make([]string, 0, len(current.keys))
+	for key := range current.keys {
+		keys = append(keys, key)
+	}
+
+	current.data, current.err = b.fetch(keys)
+	close(current.done)
+}
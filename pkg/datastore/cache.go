@@ -2,10 +2,16 @@ package datastore
 
 import (
 	"bytes"
+	"container/list"
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/intern"
 )
 
 const (
@@ -33,13 +39,47 @@ type cache struct {
 	mu      sync.RWMutex
 	data    map[string]json.RawMessage
 	pending map[string]chan struct{}
+
+	// accessed contains the keys that were read since the last call of
+	// Evict. It is only used, if evictUnused is true.
+	accessed map[string]bool
+
+	// evictUnused tells, if Evict is called. Without it, the read keys are
+	// not saved.
+	evictUnused bool
+
+	// size is the approximate memory of all keys and values in bytes.
+	size int
+
+	// maxSize is the maximum of size. If it is exceeded, the least recently
+	// used keys are removed and given to onEvict. 0 means no limit.
+	maxSize  int
+	onEvict  func(key string)
+	lru      *list.List
+	elements map[string]*list.Element
+
+	// keep tells, if a key must not be removed because of maxSize, for
+	// example a calculated key. It is called while the cache is locked.
+	keep func(key string) bool
+
+	// inUse counts the running GetOrSet calls for each key. These keys are
+	// not removed because of maxSize, before they are returned.
+	inUse map[string]int
+
+	// dropped is true, after the cache was replaced. A dropped cache does not
+	// intern its keys anymore.
+	dropped bool
 }
 
 // newCache creates an initialized cache instance.
 func newCache() *cache {
 	return &cache{
-		data:    make(map[string]json.RawMessage),
-		pending: make(map[string]chan struct{}),
+		data:     make(map[string]json.RawMessage),
+		pending:  make(map[string]chan struct{}),
+		accessed: make(map[string]bool),
+		lru:      list.New(),
+		elements: make(map[string]*list.Element),
+		inUse:    make(map[string]int),
 	}
 }
 
@@ -65,7 +105,17 @@ func newCache() *cache {
 func (c *cache) GetOrSet(ctx context.Context, keys []string, set cacheSetFunc) ([]json.RawMessage, error) {
 	c.mu.Lock()
 	missingKeys := c.notExistToPending(keys)
+	for _, key := range keys {
+		if c.evictUnused {
+			c.accessed[key] = true
+		}
+		c.inUse[key]++
+		if e, ok := c.elements[key]; ok {
+			c.lru.MoveToFront(e)
+		}
+	}
 	c.mu.Unlock()
+	defer c.release(keys)
 
 	// Fetch missing keys.
 	if len(missingKeys) > 0 {
@@ -128,6 +178,19 @@ func (c *cache) GetOrSet(ctx context.Context, keys []string, set cacheSetFunc) (
 	return values, nil
 }
 
+// release marks the keys as not used by a GetOrSet call.
+func (c *cache) release(keys []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		c.inUse[key]--
+		if c.inUse[key] <= 0 {
+			delete(c.inUse, key)
+		}
+	}
+}
+
 // fetchMissing loads the given keys with the set method. Does not update keys
 // that are already in the cache.
 //
@@ -193,6 +256,121 @@ func (c *cache) SetIfExist(data map[string]json.RawMessage) {
 	}
 }
 
+// Changed returns the values of data that differ from the values in the
+// cache. Keys that do not exist in the cache or are pending are always
+// returned, because their old value is unknown.
+func (c *cache) Changed(data map[string]json.RawMessage) map[string]json.RawMessage {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	changed := make(map[string]json.RawMessage, len(data))
+	for key, value := range data {
+		if c.keyState(key) == stExist {
+			if bytes.Equal(value, []byte("null")) {
+				value = nil
+			}
+
+			if bytes.Equal(c.data[key], value) {
+				continue
+			}
+		}
+		changed[key] = data[key]
+	}
+	return changed
+}
+
+// Keys returns all keys, that exist in the cache.
+func (c *cache) Keys() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	keys := make([]string, 0, len(c.data))
+	for key := range c.data {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Evict removes all keys from the cache, that were not read since the last
+// call of Evict. Pending keys and keys marked by keep are not removed. It
+// returns the removed keys.
+func (c *cache) Evict() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var evicted []string
+	for key := range c.data {
+		if c.accessed[key] || c.keyState(key) != stExist {
+			continue
+		}
+
+		if c.keep != nil && c.keep(key) {
+			continue
+		}
+		c.remove(key)
+		evicted = append(evicted, key)
+	}
+	c.accessed = make(map[string]bool)
+	return evicted
+}
+
+// ResetCollection removes all keys of a collection from the cache. Pending
+// keys are not removed. It returns the removed keys.
+func (c *cache) ResetCollection(collection string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prefix := collection + "/"
+	var removed []string
+	for key := range c.data {
+		if !strings.HasPrefix(key, prefix) || c.keyState(key) != stExist {
+			continue
+		}
+		c.remove(key)
+		removed = append(removed, key)
+	}
+	return removed
+}
+
+// Size returns the approximate memory of all keys and values in bytes.
+func (c *cache) Size() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.size
+}
+
+// IDs returns all ids of a collection, that have at least one field in the
+// cache.
+func (c *cache) IDs(collection string) []int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	prefix := collection + "/"
+	seen := make(map[int]bool)
+	var ids []int
+	for key := range c.data {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		rest := key[len(prefix):]
+		idx := strings.IndexByte(rest, '/')
+		if idx == -1 {
+			continue
+		}
+
+		id, err := strconv.Atoi(rest[:idx])
+		if err != nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
 // Returns the state of a key.
 //
 // The cache has to be in read lock to call this method.
@@ -224,11 +402,87 @@ func (c *cache) set(key string, value json.RawMessage) {
 	if bytes.Equal(value, []byte("null")) {
 		value = nil
 	}
+
+	old, exists := c.data[key]
+	if exists {
+		c.size -= len(old)
+	} else {
+		if !c.dropped {
+			key = intern.Add(key)
+		}
+		c.size += len(key)
+	}
+	c.size += len(value)
+
 	c.data[key] = value
 	if p, ok := c.pending[key]; ok {
 		close(p)
 		delete(c.pending, key)
 	}
+
+	if c.maxSize > 0 {
+		if e, ok := c.elements[key]; ok {
+			c.lru.MoveToFront(e)
+		} else {
+			c.elements[key] = c.lru.PushFront(key)
+		}
+		c.shrink()
+	}
+}
+
+// shrink removes the least recently used keys until the size is below
+// maxSize. The most recently used key, keys of running GetOrSet calls and keys
+// marked by keep are never removed. So the size can stay above maxSize, until
+// these keys are released.
+//
+// The cache has to be in write lock to call this method.
+func (c *cache) shrink() {
+	e := c.lru.Back()
+	for c.size > c.maxSize && e != nil && e != c.lru.Front() {
+		prev := e.Prev()
+
+		key := e.Value.(string)
+		if c.inUse[key] == 0 && (c.keep == nil || !c.keep(key)) {
+			c.remove(key)
+			if c.onEvict != nil {
+				c.onEvict(key)
+			}
+		}
+		e = prev
+	}
+}
+
+// remove deletes an existing key from the cache.
+//
+// The cache has to be in write lock to call this method.
+func (c *cache) remove(key string) {
+	c.size -= len(key) + len(c.data[key])
+	delete(c.data, key)
+	if !c.dropped {
+		intern.Remove(key)
+	}
+
+	if e, ok := c.elements[key]; ok {
+		c.lru.Remove(e)
+		delete(c.elements, key)
+	}
+}
+
+// drop releases the interned keys of the cache. It is called, when the cache
+// is replaced. Running GetOrSet calls can still use the cache, but its keys
+// are not interned anymore.
+func (c *cache) drop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.dropped {
+		return
+	}
+
+	c.dropped = true
+	for key := range c.data {
+		intern.Remove(key)
+	}
 }
 
 // notExistToPending sets all given keys, that do not exist in the cache, to pending.
@@ -133,6 +133,76 @@ func TestCacheSetIfExist(t *testing.T) {
 	}
 }
 
+func TestCacheChanged(t *testing.T) {
+	c := newCache()
+	c.GetOrSet(context.Background(), []string{"key1", "key2", "key3"}, func(keys []string, set func(string, json.RawMessage)) error {
+		set("key1", []byte("1"))
+		set("key2", []byte("2"))
+		return nil
+	})
+
+	got := c.Changed(map[string]json.RawMessage{
+		"key1": json.RawMessage("1"),
+		"key2": json.RawMessage("new"),
+		"key3": json.RawMessage("null"),
+		"key4": json.RawMessage("4"),
+	})
+
+	expect := map[string]json.RawMessage{
+		"key2": json.RawMessage("new"),
+		"key4": json.RawMessage("4"),
+	}
+	require.Equal(t, expect, got)
+}
+
+func TestCacheEvict(t *testing.T) {
+	c := newCache()
+	c.evictUnused = true
+	setFunc := func(keys []string, set func(string, json.RawMessage)) error {
+		for _, key := range keys {
+			set(key, []byte("value"))
+		}
+		return nil
+	}
+	c.GetOrSet(context.Background(), []string{"key1", "key2"}, setFunc)
+
+	// The keys were read since the last call.
+	require.Empty(t, c.Evict())
+
+	c.GetOrSet(context.Background(), []string{"key1"}, setFunc)
+	require.Equal(t, []string{"key2"}, c.Evict())
+
+	// key2 has to be fetched again.
+	var fetched []string
+	c.GetOrSet(context.Background(), []string{"key1", "key2"}, func(keys []string, set func(string, json.RawMessage)) error {
+		fetched = keys
+		return setFunc(keys, set)
+	})
+	require.Equal(t, []string{"key2"}, fetched)
+}
+
+func TestCacheWithoutEvict(t *testing.T) {
+	c := newCache()
+	c.GetOrSet(context.Background(), []string{"key1", "key2"}, func(keys []string, set func(string, json.RawMessage)) error {
+		for _, key := range keys {
+			set(key, []byte("value"))
+		}
+		return nil
+	})
+
+	require.Empty(t, c.accessed, "read keys are saved without Evict")
+}
+
+func TestCacheEvictKeep(t *testing.T) {
+	c := newCache()
+	c.keep = func(key string) bool { return key == "key1" }
+	c.Set("key1", []byte("value"))
+	c.Set("key2", []byte("value"))
+
+	require.Equal(t, []string{"key2"}, c.Evict())
+	require.Equal(t, []string{"key1"}, c.Keys())
+}
+
 func TestCacheSetIfExistParallelToGetOrSet(t *testing.T) {
 	c := newCache()
 
@@ -278,3 +348,70 @@ func TestCacheFailInOthetGetOrSetCall(t *testing.T) {
 		t.Errorf("second GetOrSet returned `%v`, expected `value`", data[0])
 	}
 }
+
+func TestCacheIDs(t *testing.T) {
+	c := newCache()
+	c.Set("motion/2/title", []byte(`"foo"`))
+	c.Set("motion/1/title", []byte(`"bar"`))
+	c.Set("motion/1/text", []byte(`"bar"`))
+	c.Set("motion_block/3/title", []byte(`"baz"`))
+
+	require.Equal(t, []int{1, 2}, c.IDs("motion"))
+}
+
+func TestCacheMaxSize(t *testing.T) {
+	c := newCache()
+	c.maxSize = 20
+	var evicted []string
+	c.onEvict = func(key string) { evicted = append(evicted, key) }
+
+	c.Set("a/1/f", []byte("12345"))
+	c.Set("a/2/f", []byte("12345"))
+	require.Equal(t, 20, c.Size())
+
+	// Reading a/1/f makes a/2/f the least recently used key.
+	_, err := c.GetOrSet(context.Background(), []string{"a/1/f"}, func([]string, func(string, json.RawMessage)) error {
+		return errors.New("key should be in the cache")
+	})
+	require.NoError(t, err)
+
+	c.Set("a/3/f", []byte("12345"))
+
+	require.Equal(t, []string{"a/2/f"}, evicted)
+	require.Equal(t, 20, c.Size())
+	require.Equal(t, []int{1, 3}, c.IDs("a"))
+}
+
+func TestCacheMaxSizeGetOrSetBiggerThenCache(t *testing.T) {
+	c := newCache()
+	c.maxSize = 20
+
+	keys := []string{"a/1/f", "a/2/f", "a/3/f", "a/4/f"}
+	got, err := c.GetOrSet(context.Background(), keys, func(keys []string, set func(string, json.RawMessage)) error {
+		for _, key := range keys {
+			set(key, []byte("12345"))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, got, 4)
+	for i, value := range got {
+		require.Equal(t, "12345", string(value), "value of %s", keys[i])
+	}
+
+	// After the call, the cache shrinks with the next key.
+	c.Set("a/5/f", []byte("12345"))
+	require.Equal(t, 20, c.Size())
+}
+
+func TestCacheMaxSizeKeep(t *testing.T) {
+	c := newCache()
+	c.maxSize = 20
+	c.keep = func(key string) bool { return key == "a/1/f" }
+
+	c.Set("a/1/f", []byte("12345"))
+	c.Set("a/2/f", []byte("12345"))
+	c.Set("a/3/f", []byte("12345"))
+
+	require.Equal(t, []int{1, 3}, c.IDs("a"))
+}
@@ -0,0 +1,148 @@
+package datastore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// calculatedPruneTime is the time, after that the dependencies of a calculated
+// key are removed, if the key was not calculated in the meantime.
+//
+// The datastore calculates all keys in its cache on each update. A key, that
+// was not calculated for this time, was removed from the cache or there was no
+// update. In the second case, the key is calculated again on the next update
+// instead of using the cached value.
+const calculatedPruneTime = 10 * time.Minute
+
+// CalculatedFunc calculates the value of a calculated key. It returns the
+// value and the keys, that were used for the calculation. The value is only
+// calculated again, when one of these keys changes.
+//
+// A Fetcher returns the used keys with Fetcher.Keys().
+type CalculatedFunc func(ctx context.Context, key string) (value []byte, deps []string, err error)
+
+// CalculatedRegisterer is a datastore, where calculated fields can be
+// registered. It is implemented by Datastore.
+type CalculatedRegisterer interface {
+	Getter
+	RegisterCalculatedField(field string, f func(ctx context.Context, key string, changed map[string]json.RawMessage) ([]byte, error))
+}
+
+// RegisterCalculated registers a calculated field, that only gets calculated
+// again, when one of its dependencies changes. Otherwise the cached value is
+// used.
+//
+// field has to be in the form `collection/field`, for example
+// `projection/content`. The field exists for every object of the collection.
+func RegisterCalculated(ds CalculatedRegisterer, field string, f CalculatedFunc) {
+	deps := newCalculatedDeps()
+
+	ds.RegisterCalculatedField(field, func(ctx context.Context, key string, changed map[string]json.RawMessage) ([]byte, error) {
+		if changed != nil {
+			keyDeps, ok := deps.get(key, time.Now())
+			if ok && !depsChanged(keyDeps, changed) {
+				old, err := ds.Get(ctx, key)
+				if err != nil {
+					return nil, fmt.Errorf("getting old value: %w", err)
+				}
+				return old[0], nil
+			}
+		}
+
+		value, keys, err := f(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+
+		deps.set(key, keys, time.Now())
+		return value, nil
+	})
+}
+
+// calculatedDeps are the dependencies of the calculated keys of one field.
+type calculatedDeps struct {
+	mu        sync.Mutex
+	entries   map[string]depsEntry
+	lastPrune time.Time
+}
+
+// depsEntry are the dependencies of one key and the last time, they were used.
+type depsEntry struct {
+	keys map[string]bool
+	used time.Time
+}
+
+func newCalculatedDeps() *calculatedDeps {
+	return &calculatedDeps{
+		entries:   make(map[string]depsEntry),
+		lastPrune: time.Now(),
+	}
+}
+
+// get returns the dependencies of a key.
+func (d *calculatedDeps) get(key string, now time.Time) (map[string]bool, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.prune(now)
+
+	entry, ok := d.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry.used = now
+	d.entries[key] = entry
+	return entry.keys, true
+}
+
+// set saves the dependencies of a key.
+func (d *calculatedDeps) set(key string, keys []string, now time.Time) {
+	keyDeps := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		keyDeps[k] = true
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.prune(now)
+	d.entries[key] = depsEntry{keys: keyDeps, used: now}
+}
+
+// prune removes the entries, that were not used for calculatedPruneTime. It
+// runs at most once in this time. Has to be called with the lock.
+func (d *calculatedDeps) prune(now time.Time) {
+	if now.Sub(d.lastPrune) < calculatedPruneTime {
+		return
+	}
+	d.lastPrune = now
+
+	for key, entry := range d.entries {
+		if now.Sub(entry.used) >= calculatedPruneTime {
+			delete(d.entries, key)
+		}
+	}
+}
+
+// depsChanged returns true, if one of the dependencies is in changed.
+func depsChanged(deps map[string]bool, changed map[string]json.RawMessage) bool {
+	if len(deps) < len(changed) {
+		for k := range deps {
+			if _, ok := changed[k]; ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	for k := range changed {
+		if deps[k] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package datastore
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalculatedDepsPrune(t *testing.T) {
+	start := time.Now()
+	deps := newCalculatedDeps()
+	deps.lastPrune = start
+
+	deps.set("projection/1/content", []string{"motion/1/title"}, start)
+	deps.set("projection/2/content", []string{"motion/2/title"}, start)
+
+	// projection/1 is still in the cache and gets calculated.
+	if _, ok := deps.get("projection/1/content", start.Add(calculatedPruneTime/2)); !ok {
+		t.Fatalf("Dependencies of projection/1/content are missing")
+	}
+
+	deps.set("projection/3/content", []string{"motion/3/title"}, start.Add(calculatedPruneTime+time.Second))
+
+	if _, ok := deps.entries["projection/2/content"]; ok {
+		t.Errorf("Dependencies of the unused key projection/2/content were not removed")
+	}
+
+	for _, key := range []string{"projection/1/content", "projection/3/content"} {
+		if _, ok := deps.entries[key]; !ok {
+			t.Errorf("Dependencies of the used key %s were removed", key)
+		}
+	}
+}
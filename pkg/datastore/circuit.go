@@ -0,0 +1,76 @@
+package datastore
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultCooldown is the default time, the circuit breaker stays open.
+const DefaultCooldown = 5 * time.Second
+
+// errCircuitOpen is returned, when a request is not sent, because the
+// datastore reader is unavailable.
+var errCircuitOpen = errors.New("datastore reader is unavailable, circuit breaker is open")
+
+// WithCooldown sets the time, in that requests to the datastore reader fail
+// immediately, after a request failed with all retries. This prevents, that
+// all connections send requests to a datastore reader, that is down. Keys in
+// the cache are still returned. A value of 0 disables the circuit breaker. The
+// default is DefaultCooldown.
+func WithCooldown(d time.Duration) Option {
+	return func(ds *Datastore) {
+		ds.circuit.cooldown = d
+	}
+}
+
+// circuitBreaker fails requests for a cooldown period after a request failed.
+type circuitBreaker struct {
+	cooldown time.Duration
+
+	mu        sync.Mutex
+	openUntil time.Time
+	opened    uint64
+}
+
+// allow returns an error, if the circuit is open.
+func (c *circuitBreaker) allow() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Now().Before(c.openUntil) {
+		return errCircuitOpen
+	}
+	return nil
+}
+
+// fail opens the circuit. It returns false, if the circuit breaker is
+// disabled.
+func (c *circuitBreaker) fail() bool {
+	if c.cooldown <= 0 {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.openUntil = time.Now().Add(c.cooldown)
+	c.opened++
+	return true
+}
+
+// succeed closes the circuit.
+func (c *circuitBreaker) succeed() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.openUntil = time.Time{}
+}
+
+// metrics returns the state of the circuit breaker.
+func (c *circuitBreaker) metrics() (open bool, opened uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return time.Now().Before(c.openUntil), c.opened
+}
@@ -14,39 +14,119 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const urlPath = "/internal/datastore/reader/get_many"
 
+// DefaultCacheTTL is the default time after that unused keys are removed from
+// the cache.
+const DefaultCacheTTL = 10 * time.Minute
+
+// DefaultBatchWindow is the default time, in that keys from different callers
+// are combined into one request.
+const DefaultBatchWindow = 2 * time.Millisecond
+
+// Option is an optional argument for New.
+type Option func(*Datastore)
+
+// WithCacheTTL sets the time after that keys, that were not read, are removed
+// from the cache. The keys are checked every ttl, so a key is removed between
+// ttl and two times ttl after it was read the last time. Calculated keys are
+// not removed. A value of 0 disables the removal. The default is
+// DefaultCacheTTL.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(d *Datastore) {
+		d.cacheTTL = ttl
+	}
+}
+
+// WithBatchWindow sets the time, in that missing keys from different callers
+// are combined into one request to the datastore. A higher value means less
+// requests but a higher latency. A value of 0 sends each request
+// immediately. The default is DefaultBatchWindow.
+func WithBatchWindow(window time.Duration) Option {
+	return func(d *Datastore) {
+		d.batcher.window = window
+	}
+}
+
+// WithCacheSize sets the maximum memory of the cache in bytes. The size is
+// approximated by the length of the keys and values. If the cache gets bigger,
+// the least recently used keys are removed. Calculated keys and the keys of
+// running requests are not removed. A value of 0 means no limit, which is the
+// default.
+func WithCacheSize(bytes int) Option {
+	return func(d *Datastore) {
+		d.maxCacheSize = bytes
+	}
+}
+
 // Datastore can be used to get values from the datastore-service.
 //
 // Has to be created with datastore.New().
 type Datastore struct {
+	// updateGaps and cacheEvictions are accessed atomically and have to be
+	// the first fields for 64 bit alignment.
+	updateGaps     uint64
+	cacheEvictions uint64
+
 	url              string
 	cache            *cache
 	keychanger       Updater
 	changeListeners  []func(map[string]json.RawMessage) error
+	provenance       map[string]string
 	calculatedFields map[string]func(ctx context.Context, key string, changed map[string]json.RawMessage) ([]byte, error)
 	calculatedKeys   map[string]string
+	calculatedMu     sync.Mutex
 	closed           <-chan struct{}
+	cacheTTL         time.Duration
+	maxCacheSize     int
+	batcher          *batcher
+	retry            Retry
+	client           *http.Client
+	circuit          *circuitBreaker
+	errHandler       func(error)
+	source           Source
+	lastPosition     int
+	positionMu       sync.Mutex
 
 	resetMu sync.Mutex
+
+	// registerMu protects changeListeners and calculatedFields.
+	registerMu sync.RWMutex
 }
 
 // New returns a new Datastore object.
-func New(url string, closed <-chan struct{}, errHandler func(error), keychanger Updater) *Datastore {
+func New(url string, closed <-chan struct{}, errHandler func(error), keychanger Updater, options ...Option) *Datastore {
 	d := &Datastore{
-		cache:            newCache(),
 		url:              url + urlPath,
 		keychanger:       keychanger,
 		closed:           closed,
 		calculatedFields: make(map[string]func(ctx context.Context, key string, changed map[string]json.RawMessage) ([]byte, error)),
 		calculatedKeys:   make(map[string]string),
+		cacheTTL:         DefaultCacheTTL,
+		retry:            DefaultRetry,
+		circuit:          &circuitBreaker{cooldown: DefaultCooldown},
+		errHandler:       errHandler,
+	}
+	d.batcher = &batcher{window: DefaultBatchWindow, fetch: d.requestKeys}
+
+	for _, o := range options {
+		o(d)
 	}
 
+	d.cache = d.emptyCache()
+
+	d.client = &http.Client{Timeout: d.retry.Timeout}
+
 	go d.receiveKeyChanges(errHandler)
 
+	if d.cacheTTL > 0 {
+		go d.evictUnused()
+	}
+
 	return d
 }
 
@@ -64,12 +144,43 @@ func (d *Datastore) Get(ctx context.Context, keys ...string) ([]json.RawMessage,
 	return values, nil
 }
 
+// TemplateField fetches the value of a template field for one replacement.
+//
+// See the function datastore.TemplateField for details.
+func (d *Datastore) TemplateField(ctx context.Context, fqid, field, replacement string, value interface{}) ([]string, error) {
+	return TemplateField(ctx, d, fqid, field, replacement, value)
+}
+
 // RegisterChangeListener registers a function that is called whenever an
 // datastore update happens.
+//
+// Keys, that are in the cache with the same value, are not given to the
+// function. If no key of an update has changed, the function is not called.
 func (d *Datastore) RegisterChangeListener(f func(map[string]json.RawMessage) error) {
+	d.registerMu.Lock()
+	defer d.registerMu.Unlock()
+
 	d.changeListeners = append(d.changeListeners, f)
 }
 
+// listeners returns a copy of the registered change listeners.
+func (d *Datastore) listeners() []func(map[string]json.RawMessage) error {
+	d.registerMu.RLock()
+	defer d.registerMu.RUnlock()
+
+	listeners := make([]func(map[string]json.RawMessage) error, len(d.changeListeners))
+	copy(listeners, d.changeListeners)
+	return listeners
+}
+
+// Provenance returns the provenance of the update, that is currently
+// processed. It can only be used inside a change listener.
+//
+// It returns nil, if the updater does not support provenance.
+func (d *Datastore) Provenance() map[string]string {
+	return d.provenance
+}
+
 // RegisterCalculatedField creates a virtual field that is not in the datastore
 // but is created at runtime.
 //
@@ -80,9 +191,44 @@ func (d *Datastore) RegisterChangeListener(f func(map[string]json.RawMessage) er
 // is called with `changed==nil`. On every ds-update, `f` is called again with the
 // data, that has changed.
 func (d *Datastore) RegisterCalculatedField(field string, f func(ctx context.Context, key string, changed map[string]json.RawMessage) ([]byte, error)) {
+	d.registerMu.Lock()
+	defer d.registerMu.Unlock()
+
 	d.calculatedFields[field] = f
 }
 
+// calculatedField returns the calculate function of a calculated field.
+func (d *Datastore) calculatedField(field string) (func(ctx context.Context, key string, changed map[string]json.RawMessage) ([]byte, error), bool) {
+	d.registerMu.RLock()
+	defer d.registerMu.RUnlock()
+
+	f, ok := d.calculatedFields[field]
+	return f, ok
+}
+
+// UpdateCalculatedField calculates all requested keys of a calculated field
+// again and sends the changed values to the change listeners.
+//
+// It is for calculated fields, that do not only depend on the datastore, for
+// example the vote count from the vote service. The calculate function of the
+// field gets nil as changed keys.
+func (d *Datastore) UpdateCalculatedField(field string) {
+	d.resetMu.Lock()
+	defer d.resetMu.Unlock()
+
+	data := d.recalculate(nil, field, d.errHandler)
+	if len(data) == 0 {
+		return
+	}
+
+	d.provenance = nil
+	for _, f := range d.listeners() {
+		if err := f(data); err != nil {
+			d.errHandler(err)
+		}
+	}
+}
+
 // splitCalculatedKeys splits a list of keys in calculated keys and "normal"
 // keys. The calculated keys are returned as map that point to the field name.
 func (d *Datastore) splitCalculatedKeys(keys []string) (map[string]string, []string) {
@@ -96,8 +242,7 @@ func (d *Datastore) splitCalculatedKeys(keys []string) (map[string]string, []str
 		}
 
 		field := parts[0] + "/" + parts[2]
-		_, ok := d.calculatedFields[field]
-		if !ok {
+		if _, ok := d.calculatedField(field); !ok {
 			normal = append(normal, k)
 			continue
 		}
@@ -106,11 +251,170 @@ func (d *Datastore) splitCalculatedKeys(keys []string) (map[string]string, []str
 	return calculated, normal
 }
 
-// ResetCache clears the internal cache.
+// CachedIDs returns the ids of all objects of a collection that are currently
+// in the cache.
+//
+// It does not ask the datastore service. Objects, that where never requested
+// are not returned.
+func (d *Datastore) CachedIDs(collection string) []int {
+	return d.currentCache().IDs(collection)
+}
+
+// ResetCache clears the internal cache. The keys of the old cache are removed
+// from the intern table.
 func (d *Datastore) ResetCache() {
 	d.resetMu.Lock()
-	d.cache = newCache()
+	old := d.cache
+	d.cache = d.emptyCache()
 	d.resetMu.Unlock()
+
+	old.drop()
+}
+
+// ResetCollection removes all keys of a collection from the cache. The keys
+// are fetched again, when they are requested the next time. It returns the
+// removed keys.
+func (d *Datastore) ResetCollection(collection string) []string {
+	d.resetMu.Lock()
+	defer d.resetMu.Unlock()
+
+	removed := d.cache.ResetCollection(collection)
+
+	d.calculatedMu.Lock()
+	for _, key := range removed {
+		delete(d.calculatedKeys, key)
+	}
+	d.calculatedMu.Unlock()
+	return removed
+}
+
+// Metrics returns the state of the circuit breaker, the number of missed
+// updates and the size of the cache.
+//
+// datastore_unavailable is 1, if the circuit breaker is open. Requests for keys,
+// that are not in the cache, fail in this time. datastore_circuit_opened is the
+// number of times, the circuit breaker was opened. datastore_update_gaps is the
+// number of times, that updates from the message bus were missed.
+// cache_size_bytes is the approximate memory of the cache and cache_evictions
+// the number of keys, that were removed because of WithCacheSize.
+func (d *Datastore) Metrics() map[string]uint64 {
+	open, opened := d.circuit.metrics()
+	var unavailable uint64
+	if open {
+		unavailable = 1
+	}
+
+	return map[string]uint64{
+		"datastore_unavailable":    unavailable,
+		"datastore_circuit_opened": opened,
+		"datastore_update_gaps":    atomic.LoadUint64(&d.updateGaps),
+		"cache_size_bytes":         uint64(d.currentCache().Size()),
+		"cache_evictions":          atomic.LoadUint64(&d.cacheEvictions),
+	}
+}
+
+// pinger is an optional interface for a Source, that can tell, if it is
+// reachable.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping checks, if the datastore reader or the source is reachable. It does not
+// use the cache or the circuit breaker.
+//
+// Each response of the datastore reader with a status code lower than 500 means,
+// that it is reachable.
+func (d *Datastore) Ping(ctx context.Context) error {
+	if d.source != nil {
+		if p, ok := d.source.(pinger); ok {
+			return p.Ping(ctx)
+		}
+		return nil
+	}
+
+	requestData, err := keysToGetManyRequest(nil)
+	if err != nil {
+		return fmt.Errorf("creating GetManyRequest: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", d.url, bytes.NewReader(requestData))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request to datastore reader: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("datastore reader returned status %s", resp.Status)
+	}
+	return nil
+}
+
+// emptyCache creates a new cache with the configured size limit.
+func (d *Datastore) emptyCache() *cache {
+	c := newCache()
+	c.maxSize = d.maxCacheSize
+	c.evictUnused = d.cacheTTL > 0
+	c.onEvict = d.onEvict
+	c.keep = d.isCalculated
+	return c
+}
+
+// onEvict is called, when the cache removes a key because of its size limit.
+// It is called while the cache is locked.
+func (d *Datastore) onEvict(key string) {
+	atomic.AddUint64(&d.cacheEvictions, 1)
+}
+
+// isCalculated returns true, if the key is a calculated key in the cache.
+//
+// Calculated keys are not removed because of the size limit or the cache
+// ttl. They are only calculated again, while they are in the cache. Without
+// them, the connections, that requested them, would not get updates.
+func (d *Datastore) isCalculated(key string) bool {
+	d.calculatedMu.Lock()
+	defer d.calculatedMu.Unlock()
+
+	_, ok := d.calculatedKeys[key]
+	return ok
+}
+
+// currentCache returns the cache. It can be replaced by ResetCache.
+func (d *Datastore) currentCache() *cache {
+	d.resetMu.Lock()
+	defer d.resetMu.Unlock()
+
+	return d.cache
+}
+
+// CacheTTL returns the time after that unused keys are removed from the
+// cache. See WithCacheTTL.
+func (d *Datastore) CacheTTL() time.Duration {
+	return d.cacheTTL
+}
+
+// evictUnused removes keys from the cache, that were not read for some time.
+// Blocks until the service is closed.
+func (d *Datastore) evictUnused() {
+	tick := time.NewTicker(d.cacheTTL)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-d.closed:
+			return
+		case <-tick.C:
+			// Calculated keys are not removed. They are not read by the
+			// connections, while their value does not change, but the
+			// connections need their updates.
+			d.currentCache().Evict()
+		}
+	}
 }
 
 // receiveKeyChanges listens for updates and saves then into the topic. This
@@ -134,20 +438,32 @@ func (d *Datastore) receiveKeyChanges(errHandler func(error)) {
 			continue
 		}
 
+		if missing, ok := d.detectGap(); ok {
+			data = d.recoverGap(missing, data, errHandler)
+		}
+
 		// The lock prefents a cache reset while data is updating.
 		d.resetMu.Lock()
+
+		// Drop keys that did not change, so idempotent writes do not trigger
+		// the listeners.
+		data = d.cache.Changed(data)
+		if len(data) == 0 {
+			d.resetMu.Unlock()
+			continue
+		}
+
+		d.provenance = nil
+		if p, ok := d.keychanger.(ProvenanceUpdater); ok {
+			d.provenance = p.Provenance()
+		}
 		d.cache.SetIfExist(data)
 
-		for key, field := range d.calculatedKeys {
-			bs, err := d.calculatedFields[field](context.Background(), key, data)
-			if err != nil {
-				errHandler(fmt.Errorf("calculate key %s: %w", key, err))
-				continue
-			}
-			d.cache.Set(key, bs)
+		for key, value := range d.recalculate(data, "", errHandler) {
+			data[key] = value
 		}
 
-		for _, f := range d.changeListeners {
+		for _, f := range d.listeners() {
 			if err := f(data); err != nil {
 				errHandler(err)
 			}
@@ -159,7 +475,7 @@ func (d *Datastore) receiveKeyChanges(errHandler func(error)) {
 func (d *Datastore) loadKeys(ctx context.Context, keys []string, set func(string, json.RawMessage)) error {
 	calculatedKeys, normalKeys := d.splitCalculatedKeys(keys)
 	if len(normalKeys) > 0 {
-		data, err := d.requestKeys(normalKeys)
+		data, err := d.batcher.request(normalKeys)
 		if err != nil {
 			return fmt.Errorf("requesting keys from datastore: %w", err)
 		}
@@ -169,19 +485,117 @@ func (d *Datastore) loadKeys(ctx context.Context, keys []string, set func(string
 	}
 
 	for key, field := range calculatedKeys {
-		calculated, err := d.calculatedFields[field](ctx, key, nil)
+		calculate, _ := d.calculatedField(field)
+		calculated, err := calculate(ctx, key, nil)
 		if err != nil {
 			return fmt.Errorf("calculating key %s: %w", key, err)
 		}
+		d.calculatedMu.Lock()
 		d.calculatedKeys[key] = field
+		d.calculatedMu.Unlock()
 		set(key, calculated)
 	}
 	return nil
 }
 
+// recalculate calculates the calculated keys again and returns the keys,
+// that have a new value. If field is not empty, only the keys of this field
+// are calculated.
+//
+// Has to be called with the resetMu.
+func (d *Datastore) recalculate(changed map[string]json.RawMessage, field string, errHandler func(error)) map[string]json.RawMessage {
+	// Calculated keys are only published, if their value changed. A changed
+	// dependency often results in the same content, for example when a slide
+	// does not show the changed field.
+	calculated := make(map[string]json.RawMessage)
+	for key, keyField := range d.calculatedKeysCopy() {
+		if field != "" && keyField != field {
+			continue
+		}
+
+		calculate, ok := d.calculatedField(keyField)
+		if !ok {
+			continue
+		}
+
+		bs, err := calculate(context.Background(), key, changed)
+		if err != nil {
+			errHandler(fmt.Errorf("calculate key %s: %w", key, err))
+			continue
+		}
+
+		if len(d.cache.Changed(map[string]json.RawMessage{key: bs})) == 0 {
+			continue
+		}
+		d.cache.Set(key, bs)
+		calculated[key] = bs
+	}
+	return calculated
+}
+
+// calculatedKeysCopy returns a copy of the calculated keys, that are in the
+// cache.
+func (d *Datastore) calculatedKeysCopy() map[string]string {
+	d.calculatedMu.Lock()
+	defer d.calculatedMu.Unlock()
+
+	keys := make(map[string]string, len(d.calculatedKeys))
+	for k, v := range d.calculatedKeys {
+		keys[k] = v
+	}
+	return keys
+}
+
 // requestKeys request a list of keys by the datastore. If an error happens, no
 // key is returned.
+//
+// Failed requests are retried as configured with WithRetry. If all attempts
+// fail, the circuit breaker is opened (see WithCooldown).
 func (d *Datastore) requestKeys(keys []string) (map[string]json.RawMessage, error) {
+	if err := d.circuit.allow(); err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		data, err := d.requestKeysOnce(keys)
+		if err == nil {
+			d.circuit.succeed()
+			return data, nil
+		}
+
+		if !isRetryable(err) {
+			return nil, err
+		}
+
+		if attempt+1 >= d.retry.Attempts {
+			if d.circuit.fail() {
+				d.errHandler(fmt.Errorf("opening circuit breaker for %s: %w", d.circuit.cooldown, err))
+			}
+			return nil, err
+		}
+
+		timer := time.NewTimer(d.retry.wait(attempt))
+		select {
+		case <-timer.C:
+		case <-d.closed:
+			timer.Stop()
+			return nil, err
+		}
+	}
+}
+
+// requestKeysOnce sends one request to the datastore.
+func (d *Datastore) requestKeysOnce(keys []string) (map[string]json.RawMessage, error) {
+	if d.source != nil {
+		ctx := context.Background()
+		if d.retry.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, d.retry.Timeout)
+			defer cancel()
+		}
+		return d.source.Get(ctx, keys...)
+	}
+
 	requestData, err := keysToGetManyRequest(keys)
 	if err != nil {
 		return nil, fmt.Errorf("creating GetManyRequest: %w", err)
@@ -194,18 +608,23 @@ func (d *Datastore) requestKeys(keys []string) (map[string]json.RawMessage, erro
 
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := d.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("requesting keys `%v`: %w", keys, err)
+		return nil, retryableError{fmt.Errorf("requesting keys `%v`: %w", keys, err)}
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("datastore returned status %s", resp.Status)
+		body, readErr := io.ReadAll(resp.Body)
+		err := fmt.Errorf("datastore returned status %s: %s", resp.Status, body)
+		if readErr != nil {
+			err = fmt.Errorf("datastore returned status %s", resp.Status)
+		}
+
+		if retryableStatus(resp.StatusCode) {
+			return nil, retryableError{err}
 		}
-		return nil, fmt.Errorf("datastore returned status %s: %s", resp.Status, body)
+		return nil, err
 	}
 
 	responseData, err := getManyResponceToKeyValue(resp.Body)
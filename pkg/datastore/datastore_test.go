@@ -4,12 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
 	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
 	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/intern"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -54,6 +58,202 @@ func TestDataStoreGetMultiValue(t *testing.T) {
 	}
 }
 
+func TestDataStoreBatchRequests(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ts := dsmock.NewDatastoreServer(closed, map[string]string{
+		"collection/1/field": `"v1"`,
+		"collection/2/field": `"v2"`,
+	})
+	d := datastore.New(ts.TS.URL, closed, func(error) {}, ts, datastore.WithBatchWindow(50*time.Millisecond))
+
+	var wg sync.WaitGroup
+	for _, key := range []string{"collection/1/field", "collection/2/field"} {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			got, err := d.Get(context.Background(), key)
+			assert.NoError(t, err, "Get() returned an unexpected error")
+			assert.Len(t, got, 1)
+		}(key)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, ts.RequestCount)
+}
+
+func TestDataStoreRetry(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		status      int
+		expectCalls int
+		expectErr   bool
+	}{
+		{"unavailable", http.StatusServiceUnavailable, 3, false},
+		{"bad request", http.StatusBadRequest, 1, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			closed := make(chan struct{})
+			defer close(closed)
+
+			var calls int
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls < 3 {
+					http.Error(w, "error", tt.status)
+					return
+				}
+				fmt.Fprintln(w, `{"collection":{"1":{"field":"value"}}}`)
+			}))
+			defer ts.Close()
+
+			retry := datastore.Retry{Attempts: 3, Backoff: time.Millisecond}
+			d := datastore.New(ts.URL, closed, func(error) {}, nil, datastore.WithRetry(retry), datastore.WithBatchWindow(0))
+
+			_, err := d.Get(context.Background(), "collection/1/field")
+
+			if tt.expectErr != (err != nil) {
+				t.Errorf("Got error %v, expected error: %t", err, tt.expectErr)
+			}
+
+			if calls != tt.expectCalls {
+				t.Errorf("Got %d requests, expected %d", calls, tt.expectCalls)
+			}
+		})
+	}
+}
+
+func TestDataStoreCircuitBreaker(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "error", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	var handled []error
+	d := datastore.New(
+		ts.URL,
+		closed,
+		func(err error) { handled = append(handled, err) },
+		nil,
+		datastore.WithRetry(datastore.Retry{Attempts: 1}),
+		datastore.WithBatchWindow(0),
+		datastore.WithCooldown(time.Minute),
+	)
+
+	_, err := d.Get(context.Background(), "collection/1/field")
+	require.Error(t, err)
+
+	// The second request fails without a request to the datastore reader.
+	_, err = d.Get(context.Background(), "collection/1/field")
+	require.Error(t, err)
+
+	assert.Equal(t, 1, calls)
+	assert.Len(t, handled, 1)
+	metrics := d.Metrics()
+	assert.Equal(t, uint64(1), metrics["datastore_unavailable"])
+	assert.Equal(t, uint64(1), metrics["datastore_circuit_opened"])
+}
+
+func TestDataStoreInjectedError(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(
+		closed,
+		map[string]string{"collection/1/field": `"value"`},
+		datastore.WithRetry(datastore.Retry{Attempts: 2, Backoff: time.Millisecond}),
+		datastore.WithBatchWindow(0),
+		datastore.WithCooldown(time.Minute),
+	)
+	ds.InjectError(http.StatusServiceUnavailable, "collection/1/field")
+
+	_, err := ds.Get(context.Background(), "collection/1/field")
+	require.Error(t, err)
+
+	// The circuit breaker is open, so removing the error does not help.
+	ds.InjectError(0, "collection/1/field")
+	_, err = ds.Get(context.Background(), "collection/1/field")
+	require.Error(t, err)
+
+	metrics := ds.Metrics()
+	assert.Equal(t, uint64(1), metrics["datastore_unavailable"])
+	assert.Equal(t, uint64(1), metrics["datastore_circuit_opened"])
+}
+
+func TestDataStoreInjectedLatency(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(
+		closed,
+		map[string]string{"collection/1/field": `"value"`},
+		datastore.WithRetry(datastore.Retry{Attempts: 1, Timeout: 10 * time.Millisecond}),
+		datastore.WithBatchWindow(0),
+		datastore.WithCooldown(0),
+	)
+	ds.InjectLatency(time.Second)
+
+	start := time.Now()
+	_, err := ds.Get(context.Background(), "collection/1/field")
+	require.Error(t, err)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second), "Get did not respect the timeout")
+
+	ds.InjectLatency(0)
+	got, err := ds.Get(context.Background(), "collection/1/field")
+	require.NoError(t, err)
+	assert.Equal(t, []json.RawMessage{[]byte(`"value"`)}, got)
+}
+
+func TestDataStorePing(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	status := http.StatusBadRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "error", status)
+	}))
+	defer ts.Close()
+
+	d := datastore.New(ts.URL, closed, func(error) {}, nil)
+
+	// Each answer of the datastore reader means, that it is reachable.
+	require.NoError(t, d.Ping(context.Background()))
+
+	status = http.StatusServiceUnavailable
+	require.Error(t, d.Ping(context.Background()))
+}
+
+type sourceMock map[string]json.RawMessage
+
+func (s sourceMock) Get(ctx context.Context, keys ...string) (map[string]json.RawMessage, error) {
+	data := make(map[string]json.RawMessage)
+	for _, key := range keys {
+		if v, ok := s[key]; ok {
+			data[key] = v
+		}
+	}
+	return data, nil
+}
+
+func TestDataStoreSource(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	source := sourceMock{"collection/1/field": []byte(`"from source"`)}
+	d := datastore.New("http://invalid", closed, func(error) {}, nil, datastore.WithSource(source))
+
+	got, err := d.Get(context.Background(), "collection/1/field", "collection/2/field")
+	require.NoError(t, err)
+
+	assert.Equal(t, []json.RawMessage{[]byte(`"from source"`), nil}, got)
+}
+
 func TestCalculatedFields(t *testing.T) {
 	closed := make(chan struct{})
 	defer close(closed)
@@ -152,6 +352,125 @@ func TestCalculatedFieldsNewDataInReceiverAfterGet(t *testing.T) {
 	assert.Equal(t, "\"normal_field is \"new value\"\"", string(got[0]))
 }
 
+func TestCalculatedFieldsPublishOnlyChanged(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+	ts := dsmock.NewDatastoreServer(closed, map[string]string{
+		"collection/1/normal_field": `"original value"`,
+	})
+	ds := datastore.New(ts.TS.URL, closed, func(error) {}, ts)
+	ds.RegisterCalculatedField("collection/myfield", func(ctx context.Context, key string, changed map[string]json.RawMessage) ([]byte, error) {
+		fields, err := ds.Get(context.Background(), "collection/1/normal_field")
+		if err != nil {
+			return nil, err
+		}
+
+		if string(fields[0]) == `"new value"` {
+			return []byte(`"new"`), nil
+		}
+		return []byte(`"same"`), nil
+	})
+
+	// Call Get once to fill the cache
+	_, err := ds.Get(context.Background(), "collection/1/myfield")
+	require.NoError(t, err)
+
+	received := make(chan map[string]json.RawMessage, 1)
+	ds.RegisterChangeListener(func(data map[string]json.RawMessage) error {
+		received <- data
+		return nil
+	})
+
+	ts.Send(map[string]string{"collection/1/normal_field": `"other value"`})
+	data := <-received
+	assert.NotContains(t, data, "collection/1/myfield", "unchanged calculated key was published")
+
+	ts.Send(map[string]string{"collection/1/normal_field": `"new value"`})
+	data = <-received
+	assert.Equal(t, `"new"`, string(data["collection/1/myfield"]))
+}
+
+func TestUpdateCalculatedField(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+	ts := dsmock.NewDatastoreServer(closed, nil)
+	ds := datastore.New(ts.TS.URL, closed, func(error) {}, ts)
+
+	var mu sync.Mutex
+	value := "1"
+	ds.RegisterCalculatedField("collection/myfield", func(ctx context.Context, key string, changed map[string]json.RawMessage) ([]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return []byte(value), nil
+	})
+
+	_, err := ds.Get(context.Background(), "collection/1/myfield")
+	require.NoError(t, err)
+
+	var received map[string]json.RawMessage
+	ds.RegisterChangeListener(func(data map[string]json.RawMessage) error {
+		received = data
+		return nil
+	})
+
+	ds.UpdateCalculatedField("collection/myfield")
+	assert.Nil(t, received, "unchanged calculated key was published")
+
+	mu.Lock()
+	value = "2"
+	mu.Unlock()
+
+	ds.UpdateCalculatedField("collection/myfield")
+	assert.Equal(t, map[string]json.RawMessage{"collection/1/myfield": []byte("2")}, received)
+
+	got, err := ds.Get(context.Background(), "collection/1/myfield")
+	require.NoError(t, err)
+	assert.Equal(t, "2", string(got[0]))
+}
+
+func TestRegisterCalculated(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+	ts := dsmock.NewDatastoreServer(closed, map[string]string{
+		"collection/1/normal_field": `"original value"`,
+		"collection/1/other_field":  `"other value"`,
+	})
+	ds := datastore.New(ts.TS.URL, closed, func(error) {}, ts)
+
+	var calls int
+	datastore.RegisterCalculated(ds, "collection/myfield", func(ctx context.Context, key string) ([]byte, []string, error) {
+		calls++
+		fields, err := ds.Get(ctx, "collection/1/normal_field")
+		if err != nil {
+			return nil, nil, err
+		}
+		return []byte(fmt.Sprintf(`"normal_field is %s"`, fields[0])), []string{"collection/1/normal_field"}, nil
+	})
+
+	received := make(chan struct{}, 2)
+	ds.RegisterChangeListener(func(map[string]json.RawMessage) error {
+		received <- struct{}{}
+		return nil
+	})
+
+	_, err := ds.Get(context.Background(), "collection/1/myfield", "collection/1/other_field")
+	require.NoError(t, err)
+
+	// A change of a field, that is not a dependency, does not calculate the
+	// value again.
+	ts.Send(map[string]string{"collection/1/other_field": `"new other value"`})
+	<-received
+	assert.Equal(t, 1, calls)
+
+	ts.Send(map[string]string{"collection/1/normal_field": `"new value"`})
+	<-received
+	assert.Equal(t, 2, calls)
+
+	got, err := ds.Get(context.Background(), "collection/1/myfield")
+	require.NoError(t, err)
+	assert.Equal(t, `"normal_field is "new value""`, string(got[0]))
+}
+
 func TestCalculatedFieldsRequireNormalFieldFetchedAtTheSameTime(t *testing.T) {
 	closed := make(chan struct{})
 	defer close(closed)
@@ -209,6 +528,152 @@ func TestChangeListeners(t *testing.T) {
 	assert.Equal(t, map[string]json.RawMessage{"my/1/key": []byte(`"my value"`)}, receivedData)
 }
 
+func TestChangeListenersUnchangedKeys(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+	ts := dsmock.NewDatastoreServer(closed, map[string]string{
+		"my/1/key":   `"my value"`,
+		"my/1/other": `"other value"`,
+	})
+	ds := datastore.New(ts.TS.URL, closed, func(error) {}, ts)
+
+	// Fetch keys to fill the cache.
+	ds.Get(context.Background(), "my/1/key", "my/1/other")
+
+	received := make(chan map[string]json.RawMessage, 2)
+	ds.RegisterChangeListener(func(data map[string]json.RawMessage) error {
+		received <- data
+		return nil
+	})
+
+	// An update without a changed value should not call the listener.
+	ts.Send(map[string]string{"my/1/key": `"my value"`})
+	ts.Send(map[string]string{"my/1/key": `"my value"`, "my/1/other": `"new value"`})
+
+	assert.Equal(t, map[string]json.RawMessage{"my/1/other": []byte(`"new value"`)}, <-received)
+	assert.Len(t, received, 0)
+}
+
+// positionUpdater is a datastore server, that sends the datastore position of
+// each update. Updates with the position 0 are lost.
+type positionUpdater struct {
+	*dsmock.DatastoreServer
+	positions chan int
+	last      []int
+}
+
+func (u *positionUpdater) send(position int, values map[string]string) {
+	u.positions <- position
+	u.Send(values)
+}
+
+func (u *positionUpdater) Update(closing <-chan struct{}) (map[string]json.RawMessage, error) {
+	data, err := u.DatastoreServer.Update(closing)
+	if err != nil {
+		return nil, err
+	}
+
+	position := <-u.positions
+	if position == 0 {
+		u.last = nil
+		return nil, nil
+	}
+	u.last = []int{position}
+	return data, nil
+}
+
+func (u *positionUpdater) Positions() []int {
+	return u.last
+}
+
+func TestChangeListenersGap(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+	ts := dsmock.NewDatastoreServer(closed, map[string]string{
+		"my/1/key":   `"my value"`,
+		"my/1/other": `"other value"`,
+	})
+	updater := &positionUpdater{DatastoreServer: ts, positions: make(chan int, 1)}
+	ds := datastore.New(ts.TS.URL, closed, func(error) {}, updater)
+
+	// Fetch keys to fill the cache.
+	ds.Get(context.Background(), "my/1/key", "my/1/other")
+
+	received := make(chan map[string]json.RawMessage, 3)
+	ds.RegisterChangeListener(func(data map[string]json.RawMessage) error {
+		received <- data
+		return nil
+	})
+
+	updater.send(1, map[string]string{"my/1/key": `"v1"`})
+	assert.Equal(t, map[string]json.RawMessage{"my/1/key": []byte(`"v1"`)}, <-received)
+
+	// The update with position 2 gets lost.
+	updater.send(0, map[string]string{"my/1/other": `"missed"`})
+	updater.send(3, map[string]string{"my/1/key": `"v3"`})
+
+	assert.Equal(t, map[string]json.RawMessage{
+		"my/1/key":   []byte(`"v3"`),
+		"my/1/other": []byte(`"missed"`),
+	}, <-received)
+	assert.Equal(t, uint64(1), ds.Metrics()["datastore_update_gaps"])
+}
+
+func TestDataStoreCacheSize(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+	ts := dsmock.NewDatastoreServer(closed, map[string]string{
+		"collection/1/field": `"value1"`,
+		"collection/2/field": `"value2"`,
+		"collection/3/field": `"value3"`,
+	})
+
+	// Each key needs 26 bytes. So only two keys fit into the cache.
+	d := datastore.New(ts.TS.URL, closed, func(error) {}, ts, datastore.WithCacheSize(60))
+
+	ctx := context.Background()
+	for _, key := range []string{"collection/1/field", "collection/2/field", "collection/1/field", "collection/3/field"} {
+		_, err := d.Get(ctx, key)
+		require.NoError(t, err)
+	}
+
+	// collection/2 was used least recently.
+	assert.Equal(t, []int{1, 3}, d.CachedIDs("collection"))
+
+	metrics := d.Metrics()
+	assert.Equal(t, uint64(1), metrics["cache_evictions"])
+	assert.Equal(t, uint64(52), metrics["cache_size_bytes"])
+}
+
+func TestDataStoreCacheSizeCalculated(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+	ts := dsmock.NewDatastoreServer(closed, map[string]string{
+		"collection/1/field": `"value1"`,
+		"collection/2/field": `"value2"`,
+	})
+
+	d := datastore.New(ts.TS.URL, closed, func(error) {}, ts, datastore.WithCacheSize(60))
+	var calculated int
+	d.RegisterCalculatedField("collection/calc", func(ctx context.Context, key string, changed map[string]json.RawMessage) ([]byte, error) {
+		if changed == nil {
+			calculated++
+		}
+		return []byte(`"value"`), nil
+	})
+
+	ctx := context.Background()
+	for _, key := range []string{"collection/1/calc", "collection/1/field", "collection/2/field", "collection/1/calc"} {
+		_, err := d.Get(ctx, key)
+		require.NoError(t, err)
+	}
+
+	// The calculated key was the least recently used key, but it was not
+	// removed. So it was not calculated a second time.
+	assert.Equal(t, 1, calculated)
+	assert.Equal(t, uint64(1), d.Metrics()["cache_evictions"])
+}
+
 func TestResetCache(t *testing.T) {
 	closed := make(chan struct{})
 	defer close(closed)
@@ -225,6 +690,47 @@ func TestResetCache(t *testing.T) {
 	assert.Equal(t, 2, ts.RequestCount)
 }
 
+func TestResetCacheIntern(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+	ts := dsmock.NewDatastoreServer(closed, nil)
+	ds := datastore.New(ts.TS.URL, closed, func(error) {}, ts)
+
+	before := intern.Default.Len()
+
+	keys := make([]string, 100)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("intern/%d/key", i+1)
+	}
+	ds.Get(context.Background(), keys...)
+	require.Equal(t, before+len(keys), intern.Default.Len(), "keys were not interned")
+
+	ds.ResetCache()
+
+	// The keys of the old cache are released.
+	assert.Equal(t, before, intern.Default.Len())
+}
+
+func TestResetCollection(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+	ts := dsmock.NewDatastoreServer(closed, nil)
+	ds := datastore.New(ts.TS.URL, closed, func(error) {}, ts)
+
+	// Fetch keys to fill the cache.
+	ds.Get(context.Background(), "some/1/key", "other/1/key")
+
+	removed := ds.ResetCollection("some")
+	assert.Equal(t, []string{"some/1/key"}, removed)
+
+	// Only the removed key is fetched again.
+	ts.RequestCount = 0
+	ds.Get(context.Background(), "other/1/key")
+	assert.Equal(t, 0, ts.RequestCount)
+	ds.Get(context.Background(), "some/1/key")
+	assert.Equal(t, 1, ts.RequestCount)
+}
+
 func TestResetWhileUpdate(t *testing.T) {
 	closed := make(chan struct{})
 	defer close(closed)
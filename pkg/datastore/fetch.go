@@ -88,6 +88,80 @@ func (f *Fetcher) String(ctx context.Context, keyFmt string, a ...interface{}) s
 	return s
 }
 
+// Strings fetches a string slice from the datastore.
+func (f *Fetcher) Strings(ctx context.Context, keyFmt string, a ...interface{}) []string {
+	var sSlice []string
+	f.Value(ctx, &sSlice, keyFmt, a...)
+	return sSlice
+}
+
+// Bool fetches a boolean from the datastore.
+func (f *Fetcher) Bool(ctx context.Context, keyFmt string, a ...interface{}) bool {
+	var b bool
+	f.Value(ctx, &b, keyFmt, a...)
+	return b
+}
+
+// RelationField fetches one field of all objects, that a relation-list field
+// points to. All values are fetched with one request to the datastore.
+//
+// The argument value has to be a pointer to a slice. The n-th element of the
+// slice is the field of the n-th id in the relation-list. If the field does
+// not exist in the datastore, the element is the zero value.
+//
+// For example, to get the user ids of all submitters of a motion:
+//
+//	var userIDs []int
+//	fetch.RelationField(ctx, &userIDs, "motion_submitter", "user_id", "motion/%d/submitter_ids", 5)
+func (f *Fetcher) RelationField(ctx context.Context, value interface{}, collection, field string, keyFmt string, a ...interface{}) {
+	ids := f.Ints(ctx, keyFmt, a...)
+	if f.err != nil {
+		return
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = collection + "/" + strconv.Itoa(id) + "/" + field
+	}
+
+	values, err := f.ds.Get(ctx, keys...)
+	if err != nil {
+		f.err = fmt.Errorf("fetching related keys %v: %w", keys, err)
+		return
+	}
+
+	v := reflect.ValueOf(value).Elem()
+	slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+	for i, raw := range values {
+		if raw == nil {
+			continue
+		}
+
+		if err := json.Unmarshal(raw, slice.Index(i).Addr().Interface()); err != nil {
+			f.err = fmt.Errorf("decoding %s: %w", keys[i], err)
+			return
+		}
+	}
+	v.Set(slice)
+	f.keys = append(f.keys, keys...)
+}
+
+// TemplateField fetches the value of a template field for one replacement.
+//
+// See datastore.TemplateField for the arguments.
+func (f *Fetcher) TemplateField(ctx context.Context, fqID, field, replacement string, value interface{}) {
+	if f.err != nil {
+		return
+	}
+
+	keys, err := TemplateField(ctx, f.ds, fqID, field, replacement, value)
+	if err != nil {
+		f.err = fmt.Errorf("fetching template field %s/%s: %w", fqID, field, err)
+		return
+	}
+	f.keys = append(f.keys, keys...)
+}
+
 // Keys returns all datastore keys that where fetched in the process.
 func (f *Fetcher) Keys() []string {
 	return f.keys
@@ -255,6 +329,56 @@ func Object(ctx context.Context, ds Getter, fqid string, value interface{}) ([]s
 	return keys, nil
 }
 
+// TemplateField fetches the value of a template field for one replacement.
+//
+// The argument `field` has to be the name of the template field, for example
+// `structure_level_$`. First, the list of replacements is fetched from
+// `fqid/structure_level_$`. If the given replacement is in that list, the value
+// of `fqid/structure_level_$<replacement>` is decoded into `value`. Otherwise
+// `value` is not changed.
+//
+// The first return value are the fqfields that where requested. This is also
+// the template field, so a caller gets informed when the replacement is added.
+func TemplateField(ctx context.Context, ds Getter, fqid, field, replacement string, value interface{}) ([]string, error) {
+	if !strings.Contains(field, "$") {
+		return nil, fmt.Errorf("field %s is not a template field", field)
+	}
+
+	templateKey := fqid + "/" + field
+	keys := []string{templateKey}
+
+	var replacements []string
+	if err := get(ctx, ds, templateKey, &replacements); err != nil {
+		var errNotExist DoesNotExistError
+		if errors.As(err, &errNotExist) {
+			return keys, nil
+		}
+		return nil, fmt.Errorf("fetching template key %s: %w", templateKey, err)
+	}
+
+	var found bool
+	for _, r := range replacements {
+		if r == replacement {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return keys, nil
+	}
+
+	key := strings.Replace(templateKey, "$", "$"+replacement, 1)
+	keys = append(keys, key)
+	if err := get(ctx, ds, key, value); err != nil {
+		var errNotExist DoesNotExistError
+		if errors.As(err, &errNotExist) {
+			return keys, nil
+		}
+		return nil, fmt.Errorf("fetching %s: %w", key, err)
+	}
+	return keys, nil
+}
+
 // DoesNotExistError is thowen by the methods of a Fether when an field does not
 // exist.
 type DoesNotExistError string
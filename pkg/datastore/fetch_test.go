@@ -163,3 +163,97 @@ func TestObjectTemplateDoesNotExist(t *testing.T) {
 	}
 	assert.ElementsMatch(t, expectKeys, keys)
 }
+
+func TestTemplateField(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+	ds := dsmock.NewMockDatastore(closed, map[string]string{
+		"user/1/structure_level_$":  `["1","2"]`,
+		"user/1/structure_level_$1": `"Bern"`,
+	})
+
+	for _, tt := range []struct {
+		name        string
+		replacement string
+		expect      string
+		expectKeys  []string
+	}{
+		{
+			"Existing",
+			"1",
+			"Bern",
+			[]string{"user/1/structure_level_$", "user/1/structure_level_$1"},
+		},
+		{
+			"In list but no value",
+			"2",
+			"",
+			[]string{"user/1/structure_level_$", "user/1/structure_level_$2"},
+		},
+		{
+			"Unknown replacement",
+			"3",
+			"",
+			[]string{"user/1/structure_level_$"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var level string
+			keys, err := ds.TemplateField(context.Background(), "user/1", "structure_level_$", tt.replacement, &level)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expect, level)
+			assert.ElementsMatch(t, tt.expectKeys, keys)
+		})
+	}
+}
+
+func TestFetcherTypes(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+	ds := dsmock.NewMockDatastore(closed, map[string]string{
+		"testmodel/1/number":  "42",
+		"testmodel/1/numbers": "[1,2]",
+		"testmodel/1/text":    `"my text"`,
+		"testmodel/1/texts":   `["a","b"]`,
+		"testmodel/1/flag":    "true",
+	})
+
+	fetch := datastore.NewFetcher(ds)
+	ctx := context.Background()
+
+	assert.Equal(t, 42, fetch.Int(ctx, "testmodel/%d/number", 1))
+	assert.Equal(t, []int{1, 2}, fetch.Ints(ctx, "testmodel/%d/numbers", 1))
+	assert.Equal(t, "my text", fetch.String(ctx, "testmodel/%d/text", 1))
+	assert.Equal(t, []string{"a", "b"}, fetch.Strings(ctx, "testmodel/%d/texts", 1))
+	assert.True(t, fetch.Bool(ctx, "testmodel/%d/flag", 1))
+	require.NoError(t, fetch.Error())
+
+	fetch.Bool(ctx, "testmodel/%d/text", 1)
+	require.Error(t, fetch.Error())
+	assert.Contains(t, fetch.Error().Error(), "testmodel/1/text", "error message should contain the key")
+}
+
+func TestFetcherRelationField(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+	ds := dsmock.NewMockDatastore(closed, map[string]string{
+		"motion/5/submitter_ids":       "[3,1,2]",
+		"motion_submitter/1/user_id":   "10",
+		"motion_submitter/3/user_id":   "30",
+		"motion_submitter/2/something": "20",
+	})
+
+	fetch := datastore.NewFetcher(ds)
+	var userIDs []int
+	fetch.RelationField(context.Background(), &userIDs, "motion_submitter", "user_id", "motion/%d/submitter_ids", 5)
+
+	require.NoError(t, fetch.Error())
+	assert.Equal(t, []int{30, 10, 0}, userIDs)
+	assert.Equal(t, []string{
+		"motion/5/submitter_ids",
+		"motion_submitter/3/user_id",
+		"motion_submitter/1/user_id",
+		"motion_submitter/2/user_id",
+	}, fetch.Keys())
+}
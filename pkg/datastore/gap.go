@@ -0,0 +1,112 @@
+package datastore
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync/atomic"
+)
+
+// positions returns the datastore positions of the last update. It returns
+// nil, if the updater does not know the positions.
+func (d *Datastore) positions() []int {
+	if p, ok := d.keychanger.(PositionUpdater); ok {
+		return p.Positions()
+	}
+
+	if p, ok := d.keychanger.(ProvenanceUpdater); ok {
+		position, err := strconv.Atoi(p.Provenance()["position"])
+		if err == nil {
+			return []int{position}
+		}
+	}
+	return nil
+}
+
+// Position returns the datastore position of the last update. It returns 0,
+// if the position is unknown.
+func (d *Datastore) Position() int {
+	d.positionMu.Lock()
+	defer d.positionMu.Unlock()
+	return d.lastPosition
+}
+
+// detectGap returns true, if the positions of the last update do not follow
+// the positions of the updates before. This means, that updates were missed.
+//
+// It returns the first missing position.
+func (d *Datastore) detectGap() (int, bool) {
+	positions := d.positions()
+	sort.Ints(positions)
+
+	var missing int
+	for _, position := range positions {
+		if d.lastPosition > 0 && position > d.lastPosition+1 && missing == 0 {
+			missing = d.lastPosition + 1
+		}
+
+		if position > d.lastPosition {
+			d.positionMu.Lock()
+			d.lastPosition = position
+			d.positionMu.Unlock()
+		}
+	}
+	return missing, missing > 0
+}
+
+// refetchCached requests all keys in the cache from the datastore. Keys that
+// do not exist anymore are returned with the value nil.
+//
+// Calculated keys are not requested. They are calculated again, when the
+// result is processed as update.
+func (d *Datastore) refetchCached() (map[string]json.RawMessage, error) {
+	d.resetMu.Lock()
+	keys := d.cache.Keys()
+	d.resetMu.Unlock()
+
+	_, normalKeys := d.splitCalculatedKeys(keys)
+	if len(normalKeys) == 0 {
+		return nil, nil
+	}
+
+	data, err := d.requestKeys(normalKeys)
+	if err != nil {
+		return nil, fmt.Errorf("requesting keys from datastore: %w", err)
+	}
+
+	for _, key := range normalKeys {
+		if _, ok := data[key]; !ok {
+			data[key] = nil
+		}
+	}
+	return data, nil
+}
+
+// recoverGap is called, when updates were missed. It adds the values of all
+// cached keys, that have changed, to the data of the current update. So the
+// change listeners get informed about the missed changes.
+//
+// If the keys can not be requested, the cache is reset.
+func (d *Datastore) recoverGap(missing int, data map[string]json.RawMessage, errHandler func(error)) map[string]json.RawMessage {
+	atomic.AddUint64(&d.updateGaps, 1)
+	errHandler(fmt.Errorf("missed datastore updates since position %d, refetching cached keys", missing))
+
+	missed, err := d.refetchCached()
+	if err != nil {
+		errHandler(fmt.Errorf("refetching cached keys: %w", err))
+		d.ResetCache()
+		return data
+	}
+
+	if data == nil {
+		data = make(map[string]json.RawMessage, len(missed))
+	}
+
+	for key, value := range missed {
+		if _, ok := data[key]; !ok {
+			data[key] = value
+		}
+	}
+	return data
+}
@@ -0,0 +1,128 @@
+package datastore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// historyPath is the path of the datastore reader, that returns the history
+// information of objects.
+const historyPath = "/internal/datastore/reader/history_information"
+
+// HistoryEntry is one change of an object in the history of the datastore.
+type HistoryEntry struct {
+	Position  int   `json:"position"`
+	Timestamp int64 `json:"timestamp"`
+	UserID    int   `json:"user_id"`
+}
+
+// History returns the changes of the objects sorted by position. It uses the
+// route history_information of the datastore reader. The cache is not used.
+//
+// Objects without changes are not in the returned map.
+func (d *Datastore) History(ctx context.Context, fqids ...string) (map[string][]HistoryEntry, error) {
+	if d.source != nil {
+		return nil, errors.New("the datastore source does not support the history")
+	}
+
+	requestData, err := json.Marshal(struct {
+		FQIDs []string `json:"fqids"`
+	}{fqids})
+	if err != nil {
+		return nil, fmt.Errorf("encoding request: %w", err)
+	}
+
+	url := strings.TrimSuffix(d.url, urlPath) + historyPath
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(requestData))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sending request to datastore reader: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("datastore returned status %s: %s", resp.Status, body)
+	}
+
+	var history map[string][]HistoryEntry
+	if err := json.NewDecoder(resp.Body).Decode(&history); err != nil {
+		return nil, fmt.Errorf("decoding history: %w", err)
+	}
+
+	for _, entries := range history {
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Position < entries[j].Position
+		})
+	}
+	return history, nil
+}
+
+// AtPosition returns a Getter, that returns the values as they were at the
+// given position of the datastore. It sends every request to the datastore
+// reader. The cache and the calculated fields are not used.
+func (d *Datastore) AtPosition(position int) Getter {
+	return &positionGetter{d: d, position: position}
+}
+
+// positionGetter gets values at one position of the datastore.
+type positionGetter struct {
+	d        *Datastore
+	position int
+}
+
+// Get returns the values of the keys at the position. Keys, that did not
+// exist at the position, are nil.
+func (g *positionGetter) Get(ctx context.Context, keys ...string) ([]json.RawMessage, error) {
+	if g.d.source != nil {
+		return nil, errors.New("the datastore source does not support positions")
+	}
+
+	requestData, err := json.Marshal(struct {
+		Requests []string `json:"requests"`
+		Position int      `json:"position"`
+	}{keys, g.position})
+	if err != nil {
+		return nil, fmt.Errorf("creating GetManyRequest: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", g.d.url, bytes.NewReader(requestData))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("requesting keys at position %d: %w", g.position, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("datastore returned status %s: %s", resp.Status, body)
+	}
+
+	data, err := getManyResponceToKeyValue(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("parse responce: %w", err)
+	}
+
+	values := make([]json.RawMessage, len(keys))
+	for i, key := range keys {
+		values[i] = data[key]
+	}
+	return values, nil
+}
@@ -0,0 +1,70 @@
+package datastore_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataStoreHistory(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	var gotPath string
+	var gotBody struct {
+		FQIDs []string `json:"fqids"`
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Write([]byte(`{"motion/1":[
+			{"position":7,"timestamp":2000,"user_id":6,"information":["Motion updated"]},
+			{"position":3,"timestamp":1000,"user_id":5,"information":["Motion created"]}
+		]}`))
+	}))
+	defer ts.Close()
+
+	d := datastore.New(ts.URL, closed, func(error) {}, nil)
+
+	history, err := d.History(context.Background(), "motion/1")
+	require.NoError(t, err)
+
+	assert.Equal(t, "/internal/datastore/reader/history_information", gotPath)
+	assert.Equal(t, []string{"motion/1"}, gotBody.FQIDs)
+	assert.Equal(t, map[string][]datastore.HistoryEntry{
+		"motion/1": {
+			{Position: 3, Timestamp: 1000, UserID: 5},
+			{Position: 7, Timestamp: 2000, UserID: 6},
+		},
+	}, history)
+}
+
+func TestDataStoreAtPosition(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	var gotBody struct {
+		Requests []string `json:"requests"`
+		Position int      `json:"position"`
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Write([]byte(`{"motion":{"1":{"title":"old title"}}}`))
+	}))
+	defer ts.Close()
+
+	d := datastore.New(ts.URL, closed, func(error) {}, nil)
+
+	values, err := d.AtPosition(3).Get(context.Background(), "motion/1/title", "motion/1/text")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"motion/1/title", "motion/1/text"}, gotBody.Requests)
+	assert.Equal(t, 3, gotBody.Position)
+	assert.Equal(t, []json.RawMessage{[]byte(`"old title"`), nil}, values)
+}
@@ -7,3 +7,17 @@ import "encoding/json"
 type Updater interface {
 	Update(<-chan struct{}) (map[string]json.RawMessage, error)
 }
+
+// ProvenanceUpdater is an Updater, that can also tell who made the last
+// update.
+type ProvenanceUpdater interface {
+	Updater
+	Provenance() map[string]string
+}
+
+// PositionUpdater is an Updater, that knows the datastore positions of the
+// messages, that were returned by the last call to Update.
+type PositionUpdater interface {
+	Updater
+	Positions() []int
+}
@@ -0,0 +1,190 @@
+package datastore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// Source returns the values for keys. Keys that do not exist are not
+// returned.
+type Source interface {
+	Get(ctx context.Context, keys ...string) (map[string]json.RawMessage, error)
+}
+
+// WithSource sets the source, where keys are read from, when they are not in
+// the cache. The default is the datastore reader from the url given to New.
+//
+// Retries and the circuit breaker are also used for the source.
+func WithSource(s Source) Option {
+	return func(d *Datastore) {
+		d.source = s
+	}
+}
+
+// Postgres reads keys directly from the database of the datastore.
+//
+// It uses the table `models` with the columns `fqid`, `data` and `deleted`,
+// that is written by the datastore writer.
+type Postgres struct {
+	db *sql.DB
+}
+
+// NewPostgres creates a Postgres source. The db has to be connected to the
+// database of the datastore.
+func NewPostgres(db *sql.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+// Ping checks the connection to the database.
+func (p *Postgres) Ping(ctx context.Context) error {
+	if err := p.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("connecting to postgres: %w", err)
+	}
+	return nil
+}
+
+// maxPostgresParameters is the maximum number of parameters of one postgres
+// query. Postgres does not allow more than 65535.
+const maxPostgresParameters = 65535
+
+// Get returns the values for the keys. Deleted objects do not exist.
+//
+// If there are more fqids than parameters allowed in one query, they are
+// requested with more than one query.
+func (p *Postgres) Get(ctx context.Context, keys ...string) (map[string]json.RawMessage, error) {
+	fields := fieldsByFQID(keys)
+
+	fqids := make([]string, 0, len(fields))
+	for fqid := range fields {
+		fqids = append(fqids, fqid)
+	}
+
+	data := make(map[string]json.RawMessage)
+	for _, chunk := range chunkFQIDs(fqids, maxPostgresParameters) {
+		if err := p.getChunk(ctx, chunk, fields, data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// getChunk reads the models of the fqids with one query and adds the
+// requested fields to data.
+func (p *Postgres) getChunk(ctx context.Context, fqids []string, fields map[string][]string, data map[string]json.RawMessage) error {
+	placeholders := make([]string, len(fqids))
+	args := make([]interface{}, len(fqids))
+	for i, fqid := range fqids {
+		args[i] = fqid
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+
+	query := fmt.Sprintf(
+		"SELECT fqid, data FROM models WHERE fqid IN (%s) AND deleted = false",
+		strings.Join(placeholders, ","),
+	)
+
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return postgresError(fmt.Errorf("querying models `%v`: %w", fqids, err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var fqid string
+		var model []byte
+		if err := rows.Scan(&fqid, &model); err != nil {
+			return fmt.Errorf("reading row: %w", err)
+		}
+
+		if err := modelToKeyValue(fqid, model, fields[fqid], data); err != nil {
+			return fmt.Errorf("parsing model %s: %w", fqid, err)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return postgresError(fmt.Errorf("reading rows: %w", err))
+	}
+	return nil
+}
+
+// sqlStater is implemented by the errors of the postgres server. SQLState
+// returns the error code.
+type sqlStater interface {
+	SQLState() string
+}
+
+// postgresError marks err as retryable, if it is a connection or a
+// serialization error. Other errors, for example syntax or permission errors,
+// would fail again.
+func postgresError(err error) error {
+	var errState sqlStater
+	if errors.As(err, &errState) {
+		code := errState.SQLState()
+		// Class 08 are connection exceptions, 40001 is serialization_failure
+		// and 40P01 is deadlock_detected.
+		if strings.HasPrefix(code, "08") || code == "40001" || code == "40P01" {
+			return retryableError{err}
+		}
+		return err
+	}
+
+	var errNet net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &errNet) {
+		return retryableError{err}
+	}
+	return err
+}
+
+// chunkFQIDs splits the fqids into parts with at most size elements.
+func chunkFQIDs(fqids []string, size int) [][]string {
+	var chunks [][]string
+	for len(fqids) > size {
+		chunks = append(chunks, fqids[:size])
+		fqids = fqids[size:]
+	}
+	if len(fqids) > 0 {
+		chunks = append(chunks, fqids)
+	}
+	return chunks
+}
+
+// fieldsByFQID groups the keys by their fqid. Invalid keys are ignored.
+func fieldsByFQID(keys []string) map[string][]string {
+	fields := make(map[string][]string)
+	for _, key := range keys {
+		idx := strings.LastIndex(key, "/")
+		if idx < 0 || strings.Count(key, "/") != 2 {
+			continue
+		}
+
+		fqid := key[:idx]
+		fields[fqid] = append(fields[fqid], key[idx+1:])
+	}
+	return fields
+}
+
+// modelToKeyValue adds the requested fields of the json encoded model to the
+// data. Fields, that do not exist in the model or have the value null, are
+// not added.
+func modelToKeyValue(fqid string, model []byte, fields []string, data map[string]json.RawMessage) error {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(model, &values); err != nil {
+		return fmt.Errorf("decoding model: %w", err)
+	}
+
+	for _, field := range fields {
+		value, ok := values[field]
+		if !ok || string(value) == "null" {
+			continue
+		}
+		data[fqid+"/"+field] = value
+	}
+	return nil
+}
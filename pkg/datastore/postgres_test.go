@@ -0,0 +1,82 @@
+package datastore
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldsByFQID(t *testing.T) {
+	got := fieldsByFQID([]string{"motion/1/title", "motion/1/text", "user/5/username", "invalid", "too/many/parts/here"})
+
+	assert.Equal(t, map[string][]string{
+		"motion/1": {"title", "text"},
+		"user/5":   {"username"},
+	}, got)
+}
+
+func TestModelToKeyValue(t *testing.T) {
+	data := make(map[string]json.RawMessage)
+	model := []byte(`{"id": 1, "title": "my motion", "text": null, "sequential_number": 5}`)
+
+	err := modelToKeyValue("motion/1", model, []string{"title", "text", "unknown", "id"}, data)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]json.RawMessage{
+		"motion/1/title": []byte(`"my motion"`),
+		"motion/1/id":    []byte(`1`),
+	}, data)
+}
+
+func TestChunkFQIDs(t *testing.T) {
+	fqids := []string{"motion/1", "motion/2", "motion/3", "motion/4", "motion/5"}
+
+	assert.Equal(t, [][]string{
+		{"motion/1", "motion/2"},
+		{"motion/3", "motion/4"},
+		{"motion/5"},
+	}, chunkFQIDs(fqids, 2))
+
+	assert.Equal(t, [][]string{fqids}, chunkFQIDs(fqids, 5))
+	assert.Empty(t, chunkFQIDs(nil, 2))
+}
+
+type fakePgError struct {
+	code string
+}
+
+func (e fakePgError) Error() string {
+	return "postgres error " + e.code
+}
+
+func (e fakePgError) SQLState() string {
+	return e.code
+}
+
+func TestPostgresError(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"connection failure", fakePgError{"08006"}, true},
+		{"serialization failure", fakePgError{"40001"}, true},
+		{"deadlock", fakePgError{"40P01"}, true},
+		{"syntax error", fakePgError{"42601"}, false},
+		{"permission denied", fakePgError{"42501"}, false},
+		{"bad connection", driver.ErrBadConn, true},
+		{"other error", errors.New("some error"), false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := postgresError(fmt.Errorf("querying: %w", tt.err))
+
+			assert.Equal(t, tt.retryable, isRetryable(err))
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package datastore
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// DefaultRetry is the default retry configuration of a Datastore.
+var DefaultRetry = Retry{
+	Attempts:   3,
+	Backoff:    50 * time.Millisecond,
+	MaxBackoff: 2 * time.Second,
+	Jitter:     0.5,
+}
+
+// Retry configures, how requests to the datastore reader are retried.
+//
+// Only network errors, timeouts and the status codes 429 and 5xx are retried.
+// Other status codes like 400 are returned immediately. With the Postgres
+// source, only connection and serialization errors are retried.
+type Retry struct {
+	// Attempts is the maximum number of requests for the same keys. A value of
+	// 1 or less means no retry.
+	Attempts int
+
+	// Backoff is the time before the first retry. It is doubled on each
+	// following retry.
+	Backoff time.Duration
+
+	// MaxBackoff is the maximum time between two attempts. 0 means no
+	// maximum.
+	MaxBackoff time.Duration
+
+	// Jitter is the random part of each backoff between 0 and 1. With 0.5, the
+	// time is reduced by up to 50%. This prevents, that many instances retry at
+	// the same time.
+	Jitter float64
+
+	// Timeout is the maximum time of one attempt. 0 means no timeout.
+	Timeout time.Duration
+}
+
+// WithRetry sets the retry configuration. The default is DefaultRetry.
+func WithRetry(r Retry) Option {
+	return func(d *Datastore) {
+		d.retry = r
+	}
+}
+
+// wait returns the time to wait before the next attempt. attempt is the number
+// of the failed attempt starting with 0.
+func (r Retry) wait(attempt int) time.Duration {
+	wait := r.Backoff
+	for i := 0; i < attempt; i++ {
+		wait *= 2
+		if r.MaxBackoff > 0 && wait > r.MaxBackoff {
+			break
+		}
+	}
+
+	if r.MaxBackoff > 0 && wait > r.MaxBackoff {
+		wait = r.MaxBackoff
+	}
+
+	if r.Jitter > 0 {
+		wait -= time.Duration(float64(wait) * r.Jitter * rand.Float64())
+	}
+	return wait
+}
+
+// retryableError is an error of a request to the datastore reader, that can
+// be retried.
+type retryableError struct {
+	err error
+}
+
+func (e retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e retryableError) Unwrap() error {
+	return e.err
+}
+
+// isRetryable returns true, if the error can be retried.
+func isRetryable(err error) bool {
+	var errRetry retryableError
+	return errors.As(err, &errRetry)
+}
+
+// retryableStatus returns true, if a request with the status code can be
+// retried.
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
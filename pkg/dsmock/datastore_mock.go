@@ -2,95 +2,12 @@ package dsmock
 
 import (
 	"encoding/json"
-	"fmt"
-	"strconv"
-	"strings"
 	"sync"
+	"time"
 
 	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
-	"gopkg.in/yaml.v3"
 )
 
-// YAMLData creates key values from a yaml object.
-//
-// It is expected, that the input is a constant string. So there can not be any
-// error at runtime. Therefore this function does not return an error but panics
-// to get the developer a fast feetback.
-func YAMLData(input string) map[string]string {
-	input = strings.ReplaceAll(input, "\t", "  ")
-
-	var db map[string]interface{}
-	if err := yaml.Unmarshal([]byte(input), &db); err != nil {
-		panic(err)
-	}
-
-	data := make(map[string]string)
-	for dbKey, dbValue := range db {
-		parts := strings.Split(dbKey, "/")
-		switch len(parts) {
-		case 1:
-			map1, ok := dbValue.(map[interface{}]interface{})
-			if !ok {
-				panic(fmt.Errorf("invalid type in db key %s: %T", dbKey, dbValue))
-			}
-			for rawID, rawObject := range map1 {
-				id, ok := rawID.(int)
-				if !ok {
-					panic(fmt.Errorf("invalid id type: got %T expected int", rawID))
-				}
-				field, ok := rawObject.(map[string]interface{})
-				if !ok {
-					panic(fmt.Errorf("invalid object type: got %T, expected map[string]interface{}", rawObject))
-				}
-
-				for fieldName, fieldValue := range field {
-					fqfield := fmt.Sprintf("%s/%d/%s", dbKey, id, fieldName)
-					bs, err := json.Marshal(fieldValue)
-					if err != nil {
-						panic(fmt.Errorf("creating test db. Key %s: %w", fqfield, err))
-					}
-					data[fqfield] = string(bs)
-				}
-
-				idField := fmt.Sprintf("%s/%d/id", dbKey, id)
-				data[idField] = strconv.Itoa(id)
-			}
-
-		case 2:
-			field, ok := dbValue.(map[string]interface{})
-			if !ok {
-				panic(fmt.Errorf("invalid object type: got %T, expected map[string]interface{}", dbValue))
-			}
-
-			for fieldName, fieldValue := range field {
-				fqfield := fmt.Sprintf("%s/%s/%s", parts[0], parts[1], fieldName)
-				bs, err := json.Marshal(fieldValue)
-				if err != nil {
-					panic(fmt.Errorf("creating test db. Key %s: %w", fqfield, err))
-				}
-				data[fqfield] = string(bs)
-			}
-
-			idField := fmt.Sprintf("%s/%s/id", parts[0], parts[1])
-			data[idField] = parts[1]
-
-		case 3:
-			bs, err := json.Marshal(dbValue)
-			if err != nil {
-				panic(fmt.Errorf("creating test db. Key %s: %w", dbKey, err))
-			}
-			data[dbKey] = string(bs)
-
-			idField := fmt.Sprintf("%s/%s/id", parts[0], parts[1])
-			data[idField] = parts[1]
-		default:
-			panic(fmt.Errorf("invalid db key %s", dbKey))
-		}
-	}
-
-	return data
-}
-
 // MockDatastore implements the autoupdate.Datastore interface.
 type MockDatastore struct {
 	*datastore.Datastore
@@ -98,14 +15,17 @@ type MockDatastore struct {
 }
 
 // NewMockDatastore create a MockDatastore with data.
-func NewMockDatastore(closed <-chan struct{}, data map[string]string) *MockDatastore {
+//
+// The options are passed to the datastore, for example to configure the retry
+// behavior.
+func NewMockDatastore(closed <-chan struct{}, data map[string]string, options ...datastore.Option) *MockDatastore {
 	dsServer := NewDatastoreServer(closed, data)
 
 	s := &MockDatastore{
 		server: dsServer,
 	}
 
-	s.Datastore = datastore.New(dsServer.TS.URL, closed, func(error) {}, s.server)
+	s.Datastore = datastore.New(dsServer.TS.URL, closed, func(error) {}, s.server, options...)
 
 	return s
 }
@@ -118,6 +38,19 @@ func (d *MockDatastore) Send(data map[string]string) {
 	d.server.Send(data)
 }
 
+// InjectError lets each request to the datastore, that contains one of the
+// keys, fail with the given http status code. The status 0 removes the error.
+//
+// Keys in the cache are not requested. Use ResetCache to request them again.
+func (d *MockDatastore) InjectError(status int, keys ...string) {
+	d.server.InjectError(status, keys...)
+}
+
+// InjectLatency delays each request to the datastore by the given duration.
+func (d *MockDatastore) InjectLatency(latency time.Duration) {
+	d.server.InjectLatency(latency)
+}
+
 // Update implements the datastore.Updater interface.
 func (d *MockDatastore) Update(close <-chan struct{}) (map[string]json.RawMessage, error) {
 	return d.server.Update(close)
@@ -7,6 +7,8 @@ import (
 	"net/http/httptest"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 )
 
 type getManyRequest struct {
@@ -23,6 +25,10 @@ type DatastoreServer struct {
 	Values       *datastoreValues
 
 	c chan map[string]json.RawMessage
+
+	injectMu sync.Mutex
+	errors   map[string]int
+	latency  time.Duration
 }
 
 // NewDatastoreServer creates a new DatastoreServer.
@@ -40,6 +46,21 @@ func NewDatastoreServer(close <-chan struct{}, data map[string]string) *Datastor
 		}
 		defer r.Body.Close()
 
+		latency, status := d.injected(data.Keys)
+		if latency > 0 {
+			select {
+			case <-time.After(latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		if status != 0 {
+			http.Error(w, "injected error", status)
+			d.RequestCount++
+			return
+		}
+
 		responceData := make(map[string]map[string]map[string]json.RawMessage)
 		for _, key := range data.Keys {
 			if !validKey(key) {
@@ -85,6 +106,48 @@ func NewDatastoreServer(close <-chan struct{}, data map[string]string) *Datastor
 	return d
 }
 
+// InjectError lets each request, that contains one of the keys, fail with the
+// given http status code. The status 0 removes the error for the keys.
+func (d *DatastoreServer) InjectError(status int, keys ...string) {
+	d.injectMu.Lock()
+	defer d.injectMu.Unlock()
+
+	if d.errors == nil {
+		d.errors = make(map[string]int)
+	}
+
+	for _, key := range keys {
+		if status == 0 {
+			delete(d.errors, key)
+			continue
+		}
+		d.errors[key] = status
+	}
+}
+
+// InjectLatency delays each request by the given duration. The value 0
+// removes the latency.
+func (d *DatastoreServer) InjectLatency(latency time.Duration) {
+	d.injectMu.Lock()
+	defer d.injectMu.Unlock()
+
+	d.latency = latency
+}
+
+// injected returns the latency and the status code of an injected error for
+// a request. The status is 0, if there is no error for the keys.
+func (d *DatastoreServer) injected(keys []string) (time.Duration, int) {
+	d.injectMu.Lock()
+	defer d.injectMu.Unlock()
+
+	for _, key := range keys {
+		if status, ok := d.errors[key]; ok {
+			return d.latency, status
+		}
+	}
+	return d.latency, 0
+}
+
 // Update returnes keys that have changed. Blocks until keys are send with
 // the Send-method.
 func (d *DatastoreServer) Update(closing <-chan struct{}) (map[string]json.RawMessage, error) {
@@ -0,0 +1,20 @@
+package dsmock
+
+import (
+	"fmt"
+	"io"
+)
+
+// NewMockDatastoreFromExampleData creates a MockDatastore with the content of
+// an example data file of the backend (example-data.json).
+//
+// The file is a json object from collections to objects. See LoadYAML for the
+// supported formats.
+func NewMockDatastoreFromExampleData(closed <-chan struct{}, r io.Reader) (*MockDatastore, error) {
+	data, err := LoadYAML(r)
+	if err != nil {
+		return nil, fmt.Errorf("loading example data: %w", err)
+	}
+
+	return NewMockDatastore(closed, data), nil
+}
@@ -0,0 +1,59 @@
+package dsmock_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const exampleData = `{
+	"_migration_index": 1,
+	"organisation": {
+		"1": {"id": 1, "name": "Test Organisation", "committee_ids": [1]}
+	},
+	"user": {
+		"1": {
+			"id": 1,
+			"username": "admin",
+			"group_$_ids": ["1"],
+			"group_$1_ids": [2],
+			"default_number": null
+		}
+	}
+}`
+
+func TestNewMockDatastoreFromExampleData(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds, err := dsmock.NewMockDatastoreFromExampleData(closed, strings.NewReader(exampleData))
+	require.NoError(t, err)
+
+	values, err := ds.Get(
+		context.Background(),
+		"organisation/1/name",
+		"user/1/username",
+		"user/1/group_$1_ids",
+		"user/1/default_number",
+		"user/1/id",
+	)
+	require.NoError(t, err)
+
+	got := make([]string, len(values))
+	for i, v := range values {
+		got[i] = string(v)
+	}
+	assert.Equal(t, []string{`"Test Organisation"`, `"admin"`, `[2]`, ``, `1`}, got)
+}
+
+func TestNewMockDatastoreFromExampleDataInvalid(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	_, err := dsmock.NewMockDatastoreFromExampleData(closed, strings.NewReader(`{"user": [1, 2]}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,238 @@
+package dsmock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ReadFunc reads values from the datastore, for example from an autoupdate
+// connection. Keys, that are not part of the stress test, are ignored.
+type ReadFunc func(ctx context.Context) (map[string]json.RawMessage, error)
+
+// Stress sends random updates to a MockDatastore on many goroutines while
+// readers read the same keys. It should be used with `go test -race` to find
+// data races between the datastore, its cache and the readers.
+//
+// Each key gets a counter as value, that is increased by each update. The keys
+// are split between the workers, so the updates of one key are send in order.
+// Stress checks, that a reader never gets an older value than before and that
+// the datastore returns the last value of each key after all updates.
+type Stress struct {
+	// Keys are the keys, that are updated.
+	Keys []string
+
+	// Workers is the number of goroutines, that send updates. The default
+	// is 10.
+	Workers int
+
+	// Updates is the number of updates per worker. The default is 100.
+	Updates int
+
+	// Seed is used to choose the keys of each update.
+	Seed int64
+}
+
+// Run sends the updates to the datastore and calls each reader in its own
+// goroutine until all updates are processed.
+//
+// If there are no readers, the keys are read with ds.Get. The readers are
+// stopped by canceling their context. Errors after the cancel are ignored.
+func (s Stress) Run(ctx context.Context, ds *MockDatastore, readers ...ReadFunc) error {
+	if len(s.Keys) == 0 {
+		return fmt.Errorf("no keys given")
+	}
+
+	workers := s.Workers
+	if workers <= 0 {
+		workers = 10
+	}
+	if workers > len(s.Keys) {
+		workers = len(s.Keys)
+	}
+
+	updates := s.Updates
+	if updates <= 0 {
+		updates = 100
+	}
+
+	if len(readers) == 0 {
+		readers = []ReadFunc{s.getReader(ds)}
+	}
+
+	readCtx, stopReaders := context.WithCancel(ctx)
+	defer stopReaders()
+
+	errs := make(chan error, len(readers)+1)
+	var wg sync.WaitGroup
+	for _, read := range readers {
+		wg.Add(1)
+		go func(read ReadFunc) {
+			defer wg.Done()
+			if err := s.check(readCtx, read); err != nil && readCtx.Err() == nil {
+				errs <- err
+			}
+		}(read)
+	}
+
+	expected := make([]map[string]int, workers)
+	var sendWG sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		sendWG.Add(1)
+		go func(w int) {
+			defer sendWG.Done()
+			expected[w] = s.send(ds, w, workers, updates)
+		}(w)
+	}
+	sendWG.Wait()
+
+	last := make(map[string]int)
+	for _, e := range expected {
+		for key, value := range e {
+			last[key] = value
+		}
+	}
+
+	if err := waitForValues(ctx, ds, last); err != nil {
+		errs <- err
+	}
+
+	stopReaders()
+	wg.Wait()
+	close(errs)
+
+	return <-errs
+}
+
+// send sends the updates of one worker and returns the last value of each of
+// its keys.
+func (s Stress) send(ds *MockDatastore, worker, workers, updates int) map[string]int {
+	var keys []string
+	for i := worker; i < len(s.Keys); i += workers {
+		keys = append(keys, s.Keys[i])
+	}
+
+	r := rand.New(rand.NewSource(s.Seed + int64(worker)))
+	values := make(map[string]int, len(keys))
+	for i := 0; i < updates; i++ {
+		data := make(map[string]string)
+		for n := r.Intn(len(keys)) + 1; n > 0; n-- {
+			key := keys[r.Intn(len(keys))]
+			if _, ok := data[key]; ok {
+				continue
+			}
+			values[key]++
+			data[key] = strconv.Itoa(values[key])
+		}
+		ds.Send(data)
+	}
+	return values
+}
+
+// check calls the reader until it returns an error. It returns an error, if a
+// value is not a number or older than a value read before.
+func (s Stress) check(ctx context.Context, read ReadFunc) error {
+	seen := make(map[string]int)
+	for {
+		data, err := read(ctx)
+		if err != nil {
+			return fmt.Errorf("reading: %w", err)
+		}
+
+		for key, value := range data {
+			if !s.isKey(key) {
+				continue
+			}
+
+			got, err := counter(value)
+			if err != nil {
+				return fmt.Errorf("invalid value for key %s: %w", key, err)
+			}
+
+			if got < seen[key] {
+				return fmt.Errorf("got value %d for key %s after value %d", got, key, seen[key])
+			}
+			seen[key] = got
+		}
+	}
+}
+
+// getReader returns a ReadFunc that reads all keys with ds.Get.
+func (s Stress) getReader(ds *MockDatastore) ReadFunc {
+	return func(ctx context.Context) (map[string]json.RawMessage, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		values, err := ds.Get(ctx, s.Keys...)
+		if err != nil {
+			return nil, fmt.Errorf("get: %w", err)
+		}
+
+		data := make(map[string]json.RawMessage, len(s.Keys))
+		for i, key := range s.Keys {
+			data[key] = values[i]
+		}
+		return data, nil
+	}
+}
+
+func (s Stress) isKey(key string) bool {
+	for _, k := range s.Keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// waitForValues waits until the datastore returns the expected values.
+func waitForValues(ctx context.Context, ds *MockDatastore, expected map[string]int) error {
+	keys := make([]string, 0, len(expected))
+	for key := range expected {
+		keys = append(keys, key)
+	}
+
+	for {
+		values, err := ds.Get(ctx, keys...)
+		if err != nil {
+			return fmt.Errorf("get: %w", err)
+		}
+
+		var wrong error
+		for i, key := range keys {
+			got, err := counter(values[i])
+			if err != nil {
+				return fmt.Errorf("invalid value for key %s: %w", key, err)
+			}
+
+			if got != expected[key] {
+				wrong = fmt.Errorf("got value %d for key %s, expected %d", got, key, expected[key])
+				break
+			}
+		}
+
+		if wrong == nil {
+			return nil
+		}
+
+		select {
+		case <-time.After(time.Millisecond):
+		case <-ctx.Done():
+			return wrong
+		}
+	}
+}
+
+// counter parses the value of a key. A key that does not exist has the value
+// 0.
+func counter(value json.RawMessage) (int, error) {
+	if value == nil {
+		return 0, nil
+	}
+	return strconv.Atoi(string(value))
+}
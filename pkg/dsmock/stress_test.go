@@ -0,0 +1,28 @@
+package dsmock_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+)
+
+func TestStress(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, nil)
+	stress := dsmock.Stress{
+		Keys:    []string{"user/1/name", "user/2/name", "user/3/name", "motion/1/title"},
+		Workers: 3,
+		Updates: 50,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := stress.Run(ctx, ds); err != nil {
+		t.Errorf("Run returned unexpected error: %v", err)
+	}
+}
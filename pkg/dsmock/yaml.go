@@ -0,0 +1,157 @@
+package dsmock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLData creates key values from a yaml object.
+//
+// It is expected, that the input is a constant string. So there can not be any
+// error at runtime. Therefore this function does not return an error but panics
+// to get the developer a fast feetback.
+//
+// Tabs are replaced with spaces, so the input can be indented like go code.
+// See LoadYAML for the format.
+func YAMLData(input string) map[string]string {
+	input = strings.ReplaceAll(input, "\t", "  ")
+
+	data, err := LoadYAML(strings.NewReader(input))
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// LoadYAML reads a yaml or json document and returns the key values.
+//
+// The keys of the document can be collections, fqids or fqfields:
+//
+//	user:
+//	  1:
+//	    username: admin
+//	user/2:
+//	  username: hugo
+//	user/3/username: tom
+//
+// The value of a collection can also be a list of objects with an id, like in
+// the example data of the backend:
+//
+//	user:
+//	  - id: 1
+//	    username: admin
+//
+// For each object, the field id is created. Keys starting with an underscore,
+// for example `_migration_index`, are ignored.
+func LoadYAML(r io.Reader) (map[string]string, error) {
+	var db map[string]interface{}
+	if err := yaml.NewDecoder(r).Decode(&db); err != nil {
+		if err == io.EOF {
+			return map[string]string{}, nil
+		}
+		return nil, fmt.Errorf("decoding yaml: %w", err)
+	}
+
+	data := make(map[string]string)
+	for dbKey, dbValue := range db {
+		if strings.HasPrefix(dbKey, "_") {
+			continue
+		}
+
+		parts := strings.Split(dbKey, "/")
+		var err error
+		switch len(parts) {
+		case 1:
+			err = addCollection(data, dbKey, dbValue)
+
+		case 2:
+			err = addObject(data, parts[0], parts[1], dbValue)
+
+		case 3:
+			if err = addValue(data, dbKey, dbValue); err == nil {
+				data[parts[0]+"/"+parts[1]+"/id"] = parts[1]
+			}
+
+		default:
+			err = fmt.Errorf("invalid db key %s", dbKey)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// addCollection adds all objects of a collection. The value can be a map from
+// ids to objects or a list of objects.
+func addCollection(data map[string]string, collection string, value interface{}) error {
+	switch objects := value.(type) {
+	case map[interface{}]interface{}:
+		for rawID, object := range objects {
+			if err := addObject(data, collection, fmt.Sprint(rawID), object); err != nil {
+				return err
+			}
+		}
+
+	case map[string]interface{}:
+		for id, object := range objects {
+			if err := addObject(data, collection, id, object); err != nil {
+				return err
+			}
+		}
+
+	case []interface{}:
+		for _, object := range objects {
+			fields, ok := object.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("invalid object type in collection %s: got %T, expected an object", collection, object)
+			}
+
+			if err := addObject(data, collection, fmt.Sprint(fields["id"]), fields); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return fmt.Errorf("invalid type in db key %s: %T", collection, value)
+	}
+	return nil
+}
+
+// addObject adds all fields of an object and its id field.
+func addObject(data map[string]string, collection, id string, value interface{}) error {
+	if _, err := strconv.Atoi(id); err != nil {
+		return fmt.Errorf("invalid id %s in collection %s: expected int", id, collection)
+	}
+
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("invalid object type of %s/%s: got %T, expected an object", collection, id, value)
+	}
+
+	for field, fieldValue := range fields {
+		if err := addValue(data, collection+"/"+id+"/"+field, fieldValue); err != nil {
+			return err
+		}
+	}
+
+	data[collection+"/"+id+"/id"] = id
+	return nil
+}
+
+// addValue adds the json encoded value.
+func addValue(data map[string]string, key string, value interface{}) error {
+	bs, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("creating test db. Key %s: %w", key, err)
+	}
+	data[key] = string(bs)
+	return nil
+}
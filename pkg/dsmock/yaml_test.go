@@ -0,0 +1,74 @@
+package dsmock_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadYAML(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		input  string
+		expect map[string]string
+	}{
+		{
+			"collection",
+			`
+			user:
+			  1:
+			    username: admin
+			    group_ids: [1, 2]
+			`,
+			map[string]string{"user/1/username": `"admin"`, "user/1/group_ids": `[1,2]`, "user/1/id": `1`},
+		},
+		{
+			"fqid and fqfield",
+			`
+			user/1:
+			  username: admin
+			user/2/username: hugo
+			`,
+			map[string]string{"user/1/username": `"admin"`, "user/1/id": `1`, "user/2/username": `"hugo"`, "user/2/id": `2`},
+		},
+		{
+			"list of objects",
+			`
+			_migration_index: 5
+			user:
+			  - id: 1
+			    username: admin
+			`,
+			map[string]string{"user/1/username": `"admin"`, "user/1/id": `1`},
+		},
+		{
+			"json with string ids",
+			`{"user": {"1": {"username": "admin", "is_active": true}}}`,
+			map[string]string{"user/1/username": `"admin"`, "user/1/is_active": `true`, "user/1/id": `1`},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			input := strings.ReplaceAll(tt.input, "\n\t\t\t", "\n")
+			got, err := dsmock.LoadYAML(strings.NewReader(input))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expect, got)
+		})
+	}
+}
+
+func TestLoadYAMLInvalid(t *testing.T) {
+	for _, input := range []string{
+		`user/1/name/other: 5`,
+		`user: [5]`,
+		`user/abc: {name: hugo}`,
+		`user: {1: 5}`,
+	} {
+		t.Run(input, func(t *testing.T) {
+			_, err := dsmock.LoadYAML(strings.NewReader(input))
+			assert.Error(t, err)
+		})
+	}
+}
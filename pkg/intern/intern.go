@@ -0,0 +1,154 @@
+// Package intern deduplicates strings, that are held in many places.
+//
+// A key like `motion/123/title` is saved in the cache of the datastore, in
+// the topic, in the keysbuilder and in the filter of each connection. Each of
+// them usually has its own copy of the string. The cache of the datastore adds
+// its keys with Add and removes them with Remove. All other places use String
+// to get the copy of the cache:
+//
+//	key = intern.String(key)
+//
+// So the table never holds more keys than the caches. Keys, that are not in a
+// cache, for example keys requested by a client, are not added.
+//
+// Interning is only an optimization. A string, that is not interned, works the
+// same.
+package intern
+
+import (
+	"sync"
+)
+
+// shardCount is the number of independent parts of a table. Each part has its
+// own lock, so connections do not wait for each other.
+const shardCount = 64
+
+// Default is the table, that is used by String, Add and Remove.
+var Default = New()
+
+// String returns the interned version of s from the Default table.
+func String(s string) string {
+	return Default.String(s)
+}
+
+// Add adds s to the Default table.
+func Add(s string) string {
+	return Default.Add(s)
+}
+
+// Remove removes s from the Default table.
+func Remove(s string) {
+	Default.Remove(s)
+}
+
+// Table holds interned strings.
+//
+// It has to be created with New.
+type Table struct {
+	shards [shardCount]shard
+}
+
+type shard struct {
+	mu      sync.RWMutex
+	strings map[string]entry
+}
+
+// entry is an interned string and the number of calls to Add, that were not
+// removed.
+type entry struct {
+	s    string
+	refs int
+}
+
+// New initializes a Table.
+func New() *Table {
+	t := new(Table)
+	for i := range t.shards {
+		t.shards[i].strings = make(map[string]entry)
+	}
+	return t
+}
+
+// String returns the interned string with the same content as s. If s was not
+// added, it is returned unchanged.
+func (t *Table) String(s string) string {
+	sh := t.shard(s)
+
+	sh.mu.RLock()
+	e, ok := sh.strings[s]
+	sh.mu.RUnlock()
+
+	if !ok {
+		return s
+	}
+	return e.s
+}
+
+// Add interns s and returns the interned string. The string stays in the table
+// until Remove is called as often as Add.
+func (t *Table) Add(s string) string {
+	sh := t.shard(s)
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	e, ok := sh.strings[s]
+	if !ok {
+		// Copy s. It could be a part of a bigger string, that should not be
+		// held by the table.
+		e.s = string([]byte(s))
+	}
+	e.refs++
+	sh.strings[e.s] = e
+	return e.s
+}
+
+// Remove reverts one call of Add.
+func (t *Table) Remove(s string) {
+	sh := t.shard(s)
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	e, ok := sh.strings[s]
+	if !ok {
+		return
+	}
+
+	e.refs--
+	if e.refs <= 0 {
+		delete(sh.strings, s)
+		return
+	}
+	sh.strings[s] = e
+}
+
+// Len returns the number of strings in the table.
+func (t *Table) Len() int {
+	var n int
+	for i := range t.shards {
+		sh := &t.shards[i]
+		sh.mu.RLock()
+		n += len(sh.strings)
+		sh.mu.RUnlock()
+	}
+	return n
+}
+
+// Metrics returns the number of strings in the table.
+func (t *Table) Metrics() map[string]uint64 {
+	return map[string]uint64{
+		"intern_strings": uint64(t.Len()),
+	}
+}
+
+// shard returns the part of the table, that holds s.
+func (t *Table) shard(s string) *shard {
+	// FNV-1a
+	h := uint32(2166136261)
+	for i := 0; i < len(s); i++ {
+		h ^= uint32(s[i])
+		h *= 16777619
+	}
+	return &t.shards[h%shardCount]
+}
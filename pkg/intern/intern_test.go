@@ -0,0 +1,144 @@
+package intern_test
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/intern"
+)
+
+// data returns the pointer to the bytes of a string.
+func data(s string) uintptr {
+	return (*[2]uintptr)(unsafe.Pointer(&s))[0]
+}
+
+func TestString(t *testing.T) {
+	table := intern.New()
+
+	first := table.Add(strings.Repeat("motion/1/title", 1))
+	second := table.String("motion/1/" + "title")
+
+	if first != second {
+		t.Fatalf("Got %q and %q, expected the same content", first, second)
+	}
+
+	if data(first) != data(second) {
+		t.Errorf("Interned strings do not share memory")
+	}
+
+	if got := table.Len(); got != 1 {
+		t.Errorf("Table has %d strings, expected 1", got)
+	}
+}
+
+func TestStringNotAdded(t *testing.T) {
+	table := intern.New()
+
+	s := "motion/1/" + "title"
+	got := table.String(s)
+
+	if data(got) != data(s) {
+		t.Errorf("String returned a copy of a string, that was not added")
+	}
+
+	if got := table.Len(); got != 0 {
+		t.Errorf("Table has %d strings, expected 0", got)
+	}
+}
+
+func TestAddCopiesSubstring(t *testing.T) {
+	table := intern.New()
+
+	long := "motion/1/title,motion/2/title"
+	interned := table.Add(long[:14])
+
+	if interned != "motion/1/title" {
+		t.Fatalf("Got %q, expected motion/1/title", interned)
+	}
+
+	if data(interned) == data(long) {
+		t.Errorf("Interned string uses the memory of the bigger string")
+	}
+}
+
+func TestRemove(t *testing.T) {
+	table := intern.New()
+
+	table.Add("motion/1/title")
+	table.Add("motion/1/title")
+
+	table.Remove("motion/1/title")
+	if got := table.Len(); got != 1 {
+		t.Errorf("Table has %d strings after the first remove, expected 1", got)
+	}
+
+	table.Remove("motion/1/title")
+	if got := table.Len(); got != 0 {
+		t.Errorf("Table has %d strings after the second remove, expected 0", got)
+	}
+}
+
+// BenchmarkString compares the memory of keys, that are held by the cache and
+// by many connections, with and without interning.
+func BenchmarkString(b *testing.B) {
+	const keyCount = 10_000
+	const connectionCount = 20
+
+	raw := make([][]byte, keyCount)
+	for i := range raw {
+		raw[i] = []byte(fmt.Sprintf("motion/%d/title", i))
+	}
+
+	for _, tt := range []struct {
+		name   string
+		intern bool
+	}{
+		{"copies", false},
+		{"interned", true},
+	} {
+		b.Run(tt.name, func(b *testing.B) {
+			var retained int64
+			for i := 0; i < b.N; i++ {
+				table := intern.New()
+
+				before := heapInUse()
+
+				cache := make([]string, keyCount)
+				for j, key := range raw {
+					cache[j] = string(key)
+					if tt.intern {
+						cache[j] = table.Add(cache[j])
+					}
+				}
+
+				connections := make([][]string, connectionCount)
+				for c := range connections {
+					connections[c] = make([]string, keyCount)
+					for j, key := range raw {
+						connections[c][j] = string(key)
+						if tt.intern {
+							connections[c][j] = table.String(connections[c][j])
+						}
+					}
+				}
+
+				retained += int64(heapInUse() - before)
+				runtime.KeepAlive(cache)
+				runtime.KeepAlive(connections)
+				runtime.KeepAlive(table)
+			}
+			b.ReportMetric(float64(retained)/float64(b.N), "retained-B/op")
+		})
+	}
+}
+
+// heapInUse returns the memory of all reachable objects.
+func heapInUse() uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
@@ -0,0 +1,164 @@
+package keysbuilder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// WithSchema uses the schema to find the type of relation fields, that are
+// requested without a type.
+//
+//	{
+//		"ids": [1],
+//		"collection": "motion",
+//		"fields": {
+//			"category_id": {"fields": {"name": null}},
+//			"tag_ids": {"fields": {"name": null}}
+//		}
+//	}
+//
+// Without a schema, each relation field needs a type. The fields of a generic
+// relation always need a type, because their collection is only known, when
+// the value is fetched.
+func WithSchema(schema Schema) Option {
+	return func(b *Builder) {
+		b.schema = schema
+	}
+}
+
+// autoField is a relation field without a type. It is replaced with the field
+// type from the schema, before the keys are build.
+//
+// The field is only decoded, when the type is known. Without a schema, it is
+// an error like any other field without a type.
+type autoField struct {
+	raw json.RawMessage
+}
+
+func (a *autoField) keys(key string, value json.RawMessage, data map[string]fieldDescription) error {
+	return errors.New("field without type")
+}
+
+func (a *autoField) depth() int {
+	return 1
+}
+
+// resolveTypes replaces the fields without a type in fields of the collection
+// with the field types from the schema.
+func resolveTypes(schema Schema, collection string, fields fieldsMap) *InvalidError {
+	for name, description := range fields.fields {
+		resolved, err := resolveType(schema, collection, name, description)
+		if err != nil {
+			return &InvalidError{sub: err, msg: "Error on field", field: name}
+		}
+		fields.fields[name] = resolved
+	}
+	return nil
+}
+
+// resolveType returns the description of one field with the type from the
+// schema.
+func resolveType(schema Schema, collection, name string, description fieldDescription) (fieldDescription, *InvalidError) {
+	switch d := description.(type) {
+	case *templateField:
+		if d.values == nil {
+			return d, nil
+		}
+
+		values, err := resolveType(schema, collection, name, d.values)
+		if err != nil {
+			return nil, &InvalidError{sub: err, msg: "Error in template sub", field: "template", values: true}
+		}
+		d.values = values
+		return d, nil
+
+	case *relationField:
+		return d, resolveTypes(schema, d.collection, d.fieldsMap)
+
+	case *relationListField:
+		return d, resolveTypes(schema, d.collection, d.fieldsMap)
+
+	case *autoField:
+		return d.resolve(schema, collection, name)
+
+	case *genericRelationField:
+		// The collection is unknown, so the fields need a type.
+		return d, resolveTypes(nil, "", d.fieldsMap)
+
+	case *genericRelationListField:
+		return d, resolveTypes(nil, "", d.fieldsMap)
+
+	default:
+		return d, nil
+	}
+}
+
+// resolve returns the field with the type from the schema.
+func (a *autoField) resolve(schema Schema, collection, name string) (fieldDescription, *InvalidError) {
+	var fieldType, to string
+	var ok bool
+	if schema != nil {
+		fieldType, to, ok = schema.RelationType(collection, name)
+	}
+	if !ok {
+		return nil, &InvalidError{msg: "no type", code: CodeMissingType, attr: "type"}
+	}
+
+	var field struct {
+		Fields fieldsMap `json:"fields"`
+		Preset string    `json:"preset"`
+		Filter string    `json:"filter"`
+	}
+	if err := json.Unmarshal(a.raw, &field); err != nil {
+		return nil, asInvalid(err)
+	}
+
+	fields, err := withPreset(field.Fields, field.Preset)
+	if err != nil {
+		return nil, asInvalid(err)
+	}
+
+	f, err := parseFilter(field.Filter)
+	if err != nil {
+		return nil, asInvalid(err)
+	}
+
+	if f != nil && fieldType != ftRelationList {
+		return nil, &InvalidError{msg: fmt.Sprintf("filter can only be used with a relation-list, %s/%s is a %s", collection, name, fieldType), code: CodeInvalidFilter, attr: "filter"}
+	}
+
+	switch fieldType {
+	case ftRelation:
+		return &relationField{collection: to, fieldsMap: fields}, resolveTypes(schema, to, fields)
+
+	case ftRelationList:
+		return &relationListField{
+			relationField: relationField{collection: to, fieldsMap: fields},
+			filter:        f,
+		}, resolveTypes(schema, to, fields)
+
+	case ftGenericRelation:
+		return &genericRelationField{fieldsMap: fields}, resolveTypes(nil, "", fields)
+
+	case ftGenericRelationList:
+		return &genericRelationListField{genericRelationField{fieldsMap: fields}}, resolveTypes(nil, "", fields)
+
+	default:
+		return nil, &InvalidError{msg: fmt.Sprintf("unknown type %s", fieldType), code: CodeUnknownType, attr: "type"}
+	}
+}
+
+// asInvalid converts an error from decoding a field to an InvalidError.
+func asInvalid(err error) *InvalidError {
+	var invalid InvalidError
+	var jerr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &invalid):
+	case errors.As(err, &jerr):
+		invalid = wrongTypeError(jerr)
+	default:
+		invalid = InvalidError{msg: err.Error()}
+	}
+	return &invalid
+}
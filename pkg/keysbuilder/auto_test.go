@@ -0,0 +1,195 @@
+package keysbuilder_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/keysbuilder"
+)
+
+// schemaMock implements keysbuilder.Schema. The keys are `collection/field`,
+// the values `type to`.
+type schemaMock map[string]string
+
+func (s schemaMock) RelationType(collection, field string) (string, string, bool) {
+	v, ok := s[collection+"/"+field]
+	if !ok {
+		return "", "", false
+	}
+	parts := strings.SplitN(v, " ", 2)
+	return parts[0], parts[1], true
+}
+
+func TestWithSchema(t *testing.T) {
+	schema := schemaMock{
+		"user/note_id":     "relation note",
+		"user/group_ids":   "relation-list group",
+		"user/group_$_ids": "relation-list group",
+		"group/meeting_id": "relation meeting",
+	}
+
+	data := map[string]json.RawMessage{
+		"user/1/note_id":      []byte("1"),
+		"user/1/group_ids":    []byte("[1,2]"),
+		"user/1/group_$_ids":  []byte(`["5"]`),
+		"user/1/group_$5_ids": []byte("[2]"),
+		"group/1/meeting_id":  []byte("5"),
+		"group/2/meeting_id":  []byte("5"),
+		"group/1/is_default":  []byte("true"),
+		"group/2/is_default":  []byte("false"),
+		"meeting/5/name":      []byte(`"meeting"`),
+	}
+
+	for _, tt := range []struct {
+		name    string
+		request string
+		keys    []string
+	}{
+		{
+			"relation",
+			`{
+				"ids": [1],
+				"collection": "user",
+				"fields": {"note_id": {"fields": {"text": null}}}
+			}`,
+			strs("user/1/note_id", "note/1/text"),
+		},
+		{
+			"relation-list with filter",
+			`{
+				"ids": [1],
+				"collection": "user",
+				"fields": {"group_ids": {"filter": "is_default == true", "fields": {"name": null}}}
+			}`,
+			strs("user/1/group_ids", "group/1/name"),
+		},
+		{
+			"nested",
+			`{
+				"ids": [1],
+				"collection": "user",
+				"fields": {"group_ids": {"fields": {"meeting_id": {"fields": {"name": null}}}}}
+			}`,
+			strs("user/1/group_ids", "group/1/meeting_id", "group/2/meeting_id", "meeting/5/name"),
+		},
+		{
+			"template",
+			`{
+				"ids": [1],
+				"collection": "user",
+				"fields": {"group_$_ids": {"type": "template", "values": {"fields": {"name": null}}}}
+			}`,
+			strs("user/1/group_$_ids", "user/1/group_$5_ids", "group/2/name"),
+		},
+		{
+			"with type",
+			`{
+				"ids": [1],
+				"collection": "user",
+				"fields": {"note_id": {"type": "relation", "collection": "other_note", "fields": {"text": null}}}
+			}`,
+			strs("user/1/note_id", "other_note/1/text"),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := keysbuilder.FromJSON(strings.NewReader(tt.request), &test.DataProvider{Data: data}, 1, keysbuilder.WithSchema(schema))
+			if err != nil {
+				t.Fatalf("FromJSON returned unexpected error: %v", err)
+			}
+
+			if err := b.Update(context.Background()); err != nil {
+				t.Fatalf("Update returned unexpected error: %v", err)
+			}
+
+			if diff := cmpSet(set(tt.keys...), set(b.Keys()...)); diff != nil {
+				t.Errorf("Got %v", diff)
+			}
+		})
+	}
+}
+
+func TestWithSchemaInvalid(t *testing.T) {
+	schema := schemaMock{
+		"user/note_id":           "relation note",
+		"meeting/agenda_item_id": "generic-relation *",
+	}
+
+	for _, tt := range []struct {
+		name    string
+		request string
+		schema  keysbuilder.Schema
+		code    string
+		path    string
+	}{
+		{
+			"no schema",
+			`{
+				"ids": [1],
+				"collection": "user",
+				"fields": {"note_id": {"fields": {"text": null}}}
+			}`,
+			nil,
+			keysbuilder.CodeMissingType,
+			"fields.note_id.type",
+		},
+		{
+			"unknown field",
+			`{
+				"ids": [1],
+				"collection": "user",
+				"fields": {"other_id": {"fields": {"text": null}}}
+			}`,
+			schema,
+			keysbuilder.CodeMissingType,
+			"fields.other_id.type",
+		},
+		{
+			"filter on relation",
+			`{
+				"ids": [1],
+				"collection": "user",
+				"fields": {"note_id": {"filter": "a == 1", "fields": {"text": null}}}
+			}`,
+			schema,
+			keysbuilder.CodeInvalidFilter,
+			"fields.note_id.filter",
+		},
+		{
+			"generic relation",
+			`{
+				"ids": [1],
+				"collection": "meeting",
+				"fields": {"agenda_item_id": {"fields": {"item_id": {"fields": {"name": null}}}}}
+			}`,
+			schema,
+			keysbuilder.CodeMissingType,
+			"fields.agenda_item_id.fields.item_id.type",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var options []keysbuilder.Option
+			if tt.schema != nil {
+				options = append(options, keysbuilder.WithSchema(tt.schema))
+			}
+
+			_, err := keysbuilder.FromJSON(strings.NewReader(tt.request), &test.DataProvider{}, 1, options...)
+
+			var errInvalid keysbuilder.InvalidError
+			if !errors.As(err, &errInvalid) {
+				t.Fatalf("FromJSON returned %v, expected an InvalidError", err)
+			}
+
+			if errInvalid.Code() != tt.code {
+				t.Errorf("Got error code %s, expected %s", errInvalid.Code(), tt.code)
+			}
+
+			if got := errInvalid.Path(); got != tt.path {
+				t.Errorf("Got path %s, expected %s", got, tt.path)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package keysbuilder_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/keysbuilder"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/testdata"
+)
+
+// benchRequest requests the motions with their submitters and all users of
+// the meeting, like the motion list of the client.
+const benchRequest = `{
+	"ids": [1],
+	"collection": "meeting",
+	"fields": {
+		"name": null,
+		"motion_ids": {
+			"type": "relation-list",
+			"collection": "motion",
+			"fields": {
+				"number": null,
+				"title": null,
+				"text": null,
+				"agenda_item_id": {
+					"type": "relation",
+					"collection": "agenda_item",
+					"fields": {"weight": null}
+				},
+				"submitter_ids": {
+					"type": "relation-list",
+					"collection": "motion_submitter",
+					"fields": {
+						"user_id": {
+							"type": "relation",
+							"collection": "user",
+							"fields": {"first_name": null, "last_name": null}
+						}
+					}
+				}
+			}
+		},
+		"user_ids": {
+			"type": "relation-list",
+			"collection": "user",
+			"fields": {"username": null, "first_name": null, "last_name": null}
+		}
+	}
+}`
+
+func BenchmarkKeysbuilder(b *testing.B) {
+	data := make(map[string]json.RawMessage)
+	for k, v := range testdata.Scaled(testdata.BenchUserCount, testdata.BenchMotionCount) {
+		data[k] = []byte(v)
+	}
+	dataProvider := &test.DataProvider{Data: data}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	start := time.Now()
+	var keyCount int
+	for i := 0; i < b.N; i++ {
+		kb, err := keysbuilder.FromJSON(strings.NewReader(benchRequest), dataProvider, 1)
+		if err != nil {
+			b.Fatalf("FromJSON returned unexpected error: %v", err)
+		}
+
+		if err := kb.Update(context.Background()); err != nil {
+			b.Fatalf("Update returned unexpected error: %v", err)
+		}
+		keyCount += len(kb.Keys())
+	}
+	b.ReportMetric(float64(keyCount)/time.Since(start).Seconds(), "keys/s")
+}
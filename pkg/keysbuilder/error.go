@@ -0,0 +1,243 @@
+package keysbuilder
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Machine readable codes of an InvalidError.
+const (
+	CodeInvalid           = "invalid"
+	CodeNoData            = "no_data"
+	CodeMissingIDs        = "missing_ids"
+	CodeInvalidID         = "invalid_id"
+	CodeMissingCollection = "missing_collection"
+	CodeMissingFields     = "missing_fields"
+	CodeMissingType       = "missing_type"
+	CodeUnknownType       = "unknown_type"
+	CodeInvalidFrom       = "invalid_from"
+	CodeWrongType         = "wrong_type"
+	CodeTooDeep           = "too_deep"
+	CodeTooManyKeys       = "too_many_keys"
+	CodeUnknownPreset     = "unknown_preset"
+	CodeInvalidFilter     = "invalid_filter"
+	CodeWrongCollection   = "wrong_collection"
+)
+
+// InvalidError is an error that happens on an invalid request.
+type InvalidError struct {
+	msg   string
+	field string
+	sub   *InvalidError
+
+	// code is the machine readable name of the error.
+	code string
+
+	// attr is the attribute of the json object, that is invalid.
+	attr string
+
+	// values is true, if the error happend in the values of a template.
+	values bool
+
+	// index is the position of the invalid body in a list of bodies or an
+	// empty string.
+	index string
+
+	// expected and got are the json types of a wrong type error.
+	expected string
+	got      string
+}
+
+func (e InvalidError) Error() string {
+	fields, last := e.fields()
+	if fields == nil {
+		return e.msg
+	}
+	return fmt.Sprintf("field \"%s\": %s", strings.Join(fields, "."), last.msg)
+}
+
+// Type returns the name of the error.
+func (e InvalidError) Type() string {
+	return "SyntaxError"
+}
+
+// Code returns a machine readable name of the error.
+func (e InvalidError) Code() string {
+	last := e.last()
+	if last.code == "" {
+		return CodeInvalid
+	}
+	return last.code
+}
+
+// Path returns the json path to the invalid value, for example
+// `[0].fields.group_ids.collection`.
+func (e InvalidError) Path() string {
+	var parts []string
+	if e.index != "" {
+		parts = append(parts, e.index)
+	}
+
+	for cur := &e; cur != nil; cur = cur.sub {
+		switch {
+		case cur.values:
+			parts = append(parts, "values")
+		case cur.field != "":
+			parts = append(parts, "fields", cur.field)
+		}
+
+		if cur.sub == nil && cur.attr != "" {
+			parts = append(parts, cur.attr)
+		}
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// Expected returns the expected json type of a wrong type error.
+func (e InvalidError) Expected() string {
+	return e.last().expected
+}
+
+// Got returns the given json type of a wrong type error.
+func (e InvalidError) Got() string {
+	return e.last().got
+}
+
+// Fields returns a list of field names from the parent to this error.
+func (e InvalidError) Fields() []string {
+	fields, _ := e.fields()
+	return fields
+}
+
+func (e InvalidError) fields() ([]string, *InvalidError) {
+	if e.field == "" {
+		return nil, nil
+	}
+	if e.sub == nil {
+		return []string{e.field}, &e
+	}
+	var fields []string
+	last := &e
+	for last.sub != nil {
+		fields = append(fields, last.field)
+		last = last.sub
+	}
+	return fields, last
+}
+
+// last returns the innermost error.
+func (e InvalidError) last() *InvalidError {
+	last := &e
+	for last.sub != nil {
+		last = last.sub
+	}
+	return last
+}
+
+// wrongTypeError converts a json type error to an InvalidError.
+func wrongTypeError(err *json.UnmarshalTypeError) InvalidError {
+	var expectType string
+	switch err.Type.Kind() {
+	case reflect.Struct, reflect.Map:
+		expectType = "object"
+	case reflect.Slice:
+		expectType = "list"
+	case reflect.Int:
+		expectType = "number"
+	default:
+		expectType = err.Type.Kind().String()
+	}
+
+	return InvalidError{
+		msg:      fmt.Sprintf("wrong type at field `%s`. Got %s, expected %v", err.Field, err.Value, expectType),
+		code:     CodeWrongType,
+		attr:     err.Field,
+		expected: expectType,
+		got:      err.Value,
+	}
+}
+
+// JSONError is returned when invalid json is parsed or the json can not be
+// decoded as a keysbuilder.
+type JSONError struct {
+	err error
+}
+
+func (e JSONError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the thrown error.
+func (e JSONError) Unwrap() error {
+	return e.err
+}
+
+// Type returns the name of the error.
+func (e JSONError) Type() string {
+	return "JsonError"
+}
+
+// ValueError in returned by keysbuilder.Update(), when the value of a key has
+// not the expected format.
+type ValueError struct {
+	key        string
+	gotType    string
+	expectType reflect.Type
+	expect     string
+	err        error
+}
+
+func (e ValueError) Error() string {
+	if e.expect != "" {
+		return fmt.Sprintf("invalid value in key %s. Got %s, expected %s", e.key, e.gotType, e.expect)
+	}
+	return fmt.Sprintf("invalid value in key %s. Got %s, expected %s", e.key, e.gotType, e.expectType)
+}
+
+// Type returns the name of the error.
+func (e ValueError) Type() string {
+	return "ValueError"
+}
+
+func (e ValueError) Unwrap() error {
+	return e.err
+}
+
+// LimitError is returned, when a request exceeds a limit of the Builder.
+type LimitError struct {
+	msg  string
+	code string
+}
+
+func (e LimitError) Error() string {
+	return e.msg
+}
+
+// Type returns the name of the error.
+func (e LimitError) Type() string {
+	return "LimitError"
+}
+
+// Code returns a machine readable name of the error.
+func (e LimitError) Code() string {
+	return e.code
+}
+
+// Path returns an empty string. A limit error is not at a specific position
+// of the request.
+func (e LimitError) Path() string {
+	return ""
+}
+
+// Expected returns an empty string.
+func (e LimitError) Expected() string {
+	return ""
+}
+
+// Got returns an empty string.
+func (e LimitError) Got() string {
+	return ""
+}
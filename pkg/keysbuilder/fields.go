@@ -0,0 +1,618 @@
+/*
+This file defines the different field types. The body (the "root" field) is also
+defined.
+
+Each fieldtype implements the fieldDescription interface by implementing the
+build method.
+
+Each field is processed by creating a field object from json. Therefore most
+field types also implement the json.Unmarshaler interface.
+
+Most fields have sub-keys. Together the different fields create a tree like
+object starting from the body.
+
+After the tree is parsed, the build-methods are used to receive the acutal keys.
+
+Each build-call parses its branches concurrently. But it is important that the
+build method only returns, when all sub-jobs are done.
+*/
+
+package keysbuilder
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	ftRelation            = "relation"
+	ftRelationList        = "relation-list"
+	ftGenericRelation     = "generic-relation"
+	ftGenericRelationList = "generic-relation-list"
+	ftTemplate            = "template"
+)
+
+// body holds the information which keys are requested by the client.
+//
+// Instead of a list of ids, the body can have the attribute "from". It is a
+// key to a relation-list field. The body then requests the objects of this
+// field. The ids are calculated again, when the value of the field changes.
+//
+// {
+//	"from": "meeting/5/motion_ids",
+//	"collection": "motion",
+//	"fields": {"title": null}
+// }
+//
+// The attribute "filter" requests only the objects that match a condition. See
+// the type filter for the syntax.
+type body struct {
+	ids        []int
+	from       string
+	filter     *filter
+	collection string
+	fieldsMap
+
+	// index is the position of the body in a list of bodies or an empty
+	// string. It is used in errors.
+	index string
+}
+
+// UnmarshallJSON builds a body object from json. It looks for the type argument
+// in the fields and decodes the fields accorently.
+func (b *body) UnmarshalJSON(data []byte) error {
+	var field struct {
+		IDs        []int     `json:"ids"`
+		From       string    `json:"from"`
+		Collection string    `json:"collection"`
+		Fields     fieldsMap `json:"fields"`
+		Preset     string    `json:"preset"`
+		Filter     string    `json:"filter"`
+	}
+
+	// Read and validate the data.
+	if err := json.Unmarshal(data, &field); err != nil {
+		return err
+	}
+	if field.From != "" {
+		if len(field.IDs) != 0 {
+			return InvalidError{msg: "ids and from can not be used together", code: CodeInvalidFrom, attr: "from"}
+		}
+		if !isFQField(field.From) {
+			return InvalidError{msg: fmt.Sprintf("from has to be a key like motion/1/field, got %s", field.From), code: CodeInvalidFrom, attr: "from"}
+		}
+	} else if len(field.IDs) == 0 {
+		return InvalidError{msg: "no ids", code: CodeMissingIDs, attr: "ids"}
+	}
+	for _, id := range field.IDs {
+		if id <= 0 {
+			return InvalidError{msg: "id has to be a positve number", code: CodeInvalidID, attr: "ids"}
+		}
+	}
+
+	if field.Collection == "" {
+		return InvalidError{msg: "no collection", code: CodeMissingCollection, attr: "collection"}
+	}
+
+	fields, err := withPreset(field.Fields, field.Preset)
+	if err != nil {
+		return err
+	}
+
+	f, err := parseFilter(field.Filter)
+	if err != nil {
+		return err
+	}
+
+	// Set the body fields.
+	b.ids = field.IDs
+	b.from = field.From
+	b.filter = f
+	b.collection = field.Collection
+	b.fieldsMap = fields
+	return nil
+}
+
+func (b *body) keys(data map[string]fieldDescription) error {
+	if b.from != "" {
+		// The key of the relation-list is requested like any other relation
+		// field, so the ids are loaded from its value.
+		addKey(data, b.from, &relationListField{
+			relationField: relationField{collection: b.collection, fieldsMap: b.fieldsMap},
+			filter:        b.filter,
+		})
+		return nil
+	}
+
+	for _, id := range b.ids {
+		cid := buildCollectionID(b.collection, id)
+		addObject(data, cid, b.fieldsMap, b.filter)
+	}
+	return nil
+}
+
+func (b *body) depth() int {
+	if b.from != "" {
+		return 1 + b.fieldsMap.depth()
+	}
+	return b.fieldsMap.depth()
+}
+
+// isFQField returns true, if the key has the form collection/id/field with a
+// positive id.
+func isFQField(key string) bool {
+	parts := strings.Split(key, keySep)
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return false
+	}
+
+	id, err := strconv.Atoi(parts[1])
+	return err == nil && id > 0
+}
+
+// isFQID returns true, if the value has the form collection/id with a positive
+// id.
+func isFQID(value string) bool {
+	parts := strings.Split(value, keySep)
+	if len(parts) != 2 || parts[0] == "" {
+		return false
+	}
+
+	id, err := strconv.Atoi(parts[1])
+	return err == nil && id > 0
+}
+
+// fqidError is returned, when the value of a generic relation is not a fqid.
+func fqidError(key, value string) ValueError {
+	return ValueError{key: key, gotType: strconv.Quote(value), expect: "a fqid like motion/1"}
+}
+
+// relationField is a fieldtype that redirects to one other collection.
+//
+// {
+//	"ids": [1],
+//	"collection": "user",
+//	"fields": {
+//		"note_id": {
+//			"type": "relation",
+//			"collection": "note",
+//			"fields": {"important": null}
+//		}
+//	}
+// }
+type relationField struct {
+	collection string
+	fieldsMap
+}
+
+func (r *relationField) UnmarshalJSON(data []byte) error {
+	var field struct {
+		Collection string    `json:"collection"`
+		Fields     fieldsMap `json:"fields"`
+		Preset     string    `json:"preset"`
+	}
+	if err := json.Unmarshal(data, &field); err != nil {
+		return err
+	}
+	if field.Collection == "" {
+		return InvalidError{msg: "no collection", code: CodeMissingCollection, attr: "collection"}
+	}
+
+	fields, err := withPreset(field.Fields, field.Preset)
+	if err != nil {
+		return err
+	}
+	r.collection = field.Collection
+	r.fieldsMap = fields
+	return nil
+}
+
+func (r *relationField) keys(key string, value json.RawMessage, data map[string]fieldDescription) error {
+	var id int
+	if err := json.Unmarshal(value, &id); err != nil {
+		return fmt.Errorf("decoding value for key %s: %w", key, err)
+	}
+
+	cid := buildCollectionID(r.collection, id)
+	r.fieldsMap.keys(cid, data)
+	return nil
+}
+
+func (r *relationField) depth() int {
+	return 1 + r.fieldsMap.depth()
+}
+
+// relationListField is a fieldtype like relation, but redirects to a list of objects.
+//
+// The optional attribute "filter" requests only the objects that match a
+// condition.
+//
+// {
+//	"ids": [1],
+//	"collection": "meeting",
+//	"fields": {
+//		"agenda_item_ids": {
+//			"type": "relation-list",
+//			"collection": "agenda_item",
+//			"filter": "is_hidden == false",
+//			"fields": {"item_number": null}
+//		}
+//	}
+// }
+type relationListField struct {
+	relationField
+	filter *filter
+}
+
+func (r *relationListField) UnmarshalJSON(data []byte) error {
+	if err := r.relationField.UnmarshalJSON(data); err != nil {
+		return err
+	}
+
+	var field struct {
+		Filter string `json:"filter"`
+	}
+	if err := json.Unmarshal(data, &field); err != nil {
+		return err
+	}
+
+	f, err := parseFilter(field.Filter)
+	if err != nil {
+		return err
+	}
+	r.filter = f
+	return nil
+}
+
+func (r *relationListField) keys(key string, value json.RawMessage, data map[string]fieldDescription) error {
+	var ids []int
+	if err := json.Unmarshal(value, &ids); err != nil {
+		return fmt.Errorf("decoding value for key %s: %w", key, err)
+	}
+
+	for _, id := range ids {
+		cid := buildCollectionID(r.collection, id)
+		addObject(data, cid, r.fieldsMap, r.filter)
+	}
+	return nil
+}
+
+// genericRelationField is like a relationField but the collection is given from the restricter.
+//
+// The value of the field is a fqid like `motion/5`. The collection is read
+// from the value, so the fields are requested from whatever collection the
+// value points to. A null value does not request any keys.
+//
+//{
+//	"ids": [1],
+//	"collection": "user",
+//	"fields": {
+//		"most_seen": {
+//			"type": "generic-relation",
+//			"fields": {"name": null}
+//		}
+//	}
+// }
+type genericRelationField struct {
+	fieldsMap
+}
+
+func (g *genericRelationField) UnmarshalJSON(data []byte) error {
+	var field struct {
+		Fields fieldsMap `json:"fields"`
+		Preset string    `json:"preset"`
+	}
+	if err := json.Unmarshal(data, &field); err != nil {
+		return err
+	}
+
+	fields, err := withPreset(field.Fields, field.Preset)
+	if err != nil {
+		return err
+	}
+	g.fieldsMap = fields
+	return nil
+}
+
+func (g *genericRelationField) keys(key string, value json.RawMessage, data map[string]fieldDescription) error {
+	var cid *string
+	if err := json.Unmarshal(value, &cid); err != nil {
+		return fmt.Errorf("decoding value for key %s: %w", key, err)
+	}
+
+	if cid == nil {
+		return nil
+	}
+
+	if !isFQID(*cid) {
+		return fqidError(key, *cid)
+	}
+
+	g.fieldsMap.keys(*cid, data)
+	return nil
+}
+
+func (g *genericRelationField) depth() int {
+	return 1 + g.fieldsMap.depth()
+}
+
+// genericRelationListField is like a genericRelationField but with a list of relations.
+//
+// {
+//	"ids": [1],
+//	"collection": "user",
+//	"fields": {
+//		"seen": {
+//			"type": "generic-relation-list",
+//			"fields": {"name": null}
+//		}
+//	}
+// }
+type genericRelationListField struct {
+	genericRelationField
+}
+
+func (g *genericRelationListField) keys(key string, value json.RawMessage, data map[string]fieldDescription) error {
+	var cids []string
+	if err := json.Unmarshal(value, &cids); err != nil {
+		return fmt.Errorf("decoding value for key %s: %w", key, err)
+	}
+
+	for _, cid := range cids {
+		if !isFQID(cid) {
+			return fqidError(key, cid)
+		}
+		g.fieldsMap.keys(cid, data)
+	}
+	return nil
+}
+
+// templateField requests a list of fields from a template.
+//
+// Template fields do not need the type "template". A field with a name like
+// `group_$_ids` or `structure_level_$` is always handled as template field. A
+// description of such a field is used for the values of the template. So
+// `"group_$_ids": null` requests also `group_$1_ids`, `group_$2_ids`, ...
+//
+// {
+//	"ids": [1],
+//	"collection": "user",
+//	"fields": {
+//		"group_$_ids": {
+//			"type": "template",
+//			"values": {
+//				"type": "relation-list",
+//				"collection": "group",
+//				"fields": {"name": null}
+//			}
+//		}
+//	}
+// }
+type templateField struct {
+	values fieldDescription
+}
+
+func (t *templateField) UnmarshalJSON(data []byte) error {
+	var field struct {
+		Values json.RawMessage `json:"values"`
+	}
+	if err := json.Unmarshal(data, &field); err != nil {
+		return fmt.Errorf("decode template field: %w", err)
+	}
+	if len(field.Values) == 0 {
+		return nil
+	}
+
+	values, err := unmarshalField(field.Values)
+	if err != nil {
+		if jerr, ok := err.(*json.UnmarshalTypeError); ok {
+			err = wrongTypeError(jerr)
+		}
+		if sub, ok := err.(InvalidError); ok {
+			return InvalidError{sub: &sub, msg: "Error in template sub", field: "template", values: true}
+		}
+		return fmt.Errorf("decoding sub attribute of template field: %w", err)
+	}
+	t.values = values
+	return nil
+}
+
+func (t *templateField) keys(key string, value json.RawMessage, data map[string]fieldDescription) error {
+	var values []string
+	if err := json.Unmarshal(value, &values); err != nil {
+		return fmt.Errorf("decoding value for key %s: %w", key, err)
+	}
+
+	for _, value := range values {
+		key := strings.Replace(key, "$", "$"+value, 1)
+		addKey(data, key, t.values)
+	}
+	return nil
+}
+
+func (t *templateField) depth() int {
+	if t.values == nil {
+		return 0
+	}
+	return t.values.depth()
+}
+
+// isTemplateField returns true, if the field name is the name of a template
+// field like `group_$_ids` and not a field with a replacement like
+// `group_$1_ids`.
+func isTemplateField(name string) bool {
+	return strings.Contains(name, "$_") || strings.HasSuffix(name, "$")
+}
+
+// unmarshalField uses the type-attribute in the json object get the field-type.
+// Afterwards, the json is parsed as this field-type and returned.
+func unmarshalField(data []byte) (fieldDescription, error) {
+	var t *struct {
+		Type   string          `json:"type"`
+		Fields json.RawMessage `json:"fields"`
+		Preset string          `json:"preset"`
+	}
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, nil
+	}
+
+	if t.Type == "" && (t.Fields != nil || t.Preset != "") {
+		// The type is read from the schema (see WithSchema).
+		return &autoField{raw: data}, nil
+	}
+
+	var r fieldDescription
+	switch t.Type {
+	case ftRelation:
+		r = new(relationField)
+
+	case ftRelationList:
+		r = new(relationListField)
+
+	case ftGenericRelation:
+		r = new(genericRelationField)
+
+	case ftGenericRelationList:
+		r = new(genericRelationListField)
+
+	case ftTemplate:
+		r = new(templateField)
+
+	case "":
+		return nil, InvalidError{msg: "no type", code: CodeMissingType, attr: "type"}
+
+	default:
+		return nil, InvalidError{msg: fmt.Sprintf("unknown type %s", t.Type), code: CodeUnknownType, attr: "type"}
+	}
+
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// fieldsMap is a map from each field of another field descrption.
+//
+// For example, user_id to a relation-field and group_ids to a
+// relation-list-field.
+//
+// A fieldsMap knows how to be decoded from json and how to build the keys from
+// it.
+type fieldsMap struct {
+	fields map[string]fieldDescription
+}
+
+func (f *fieldsMap) UnmarshalJSON(data []byte) error {
+	var fm map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fm); err != nil {
+		if jerr, ok := err.(*json.UnmarshalTypeError); ok {
+			return InvalidError{
+				msg:      fmt.Sprintf("fields has to be an object, got %s", jerr.Value),
+				code:     CodeWrongType,
+				attr:     "fields",
+				expected: "object",
+				got:      jerr.Value,
+			}
+		}
+		return fmt.Errorf("decode fields: %w", err)
+	}
+
+	f.fields = make(map[string]fieldDescription, len(fm))
+	for name, field := range fm {
+		fd, err := unmarshalField(field)
+		if err != nil {
+			if sub, ok := err.(InvalidError); ok {
+				return InvalidError{sub: &sub, msg: "Error on field", field: name}
+			}
+			if jerr, ok := err.(*json.UnmarshalTypeError); ok {
+				sub := wrongTypeError(jerr)
+				return InvalidError{sub: &sub, msg: "Error on field", field: name}
+			}
+			return err
+		}
+
+		if isTemplateField(name) {
+			if _, ok := fd.(*templateField); !ok {
+				fd = &templateField{values: fd}
+			}
+		}
+		f.fields[name] = fd
+	}
+	return nil
+}
+
+func (f *fieldsMap) keys(cid string, data map[string]fieldDescription) {
+	for field, description := range f.fields {
+		addKey(data, buildGenericKey(cid, field), description)
+	}
+}
+
+func (f *fieldsMap) depth() int {
+	var deepest int
+	for _, description := range f.fields {
+		if description == nil {
+			continue
+		}
+
+		if d := description.depth(); d > deepest {
+			deepest = d
+		}
+	}
+	return deepest
+}
+
+// addKey adds a key with its description to data. If the key is already in
+// data with another description, the descriptions are merged. This happens,
+// when more then one body or field requests the same key.
+func addKey(data map[string]fieldDescription, key string, description fieldDescription) {
+	old, ok := data[key]
+	if !ok || (old == nil && requested(description)) {
+		data[key] = description
+		return
+	}
+
+	if old == description || (description == nil && requested(old)) {
+		return
+	}
+
+	if merged, ok := old.(mergedField); ok {
+		data[key] = append(merged[:len(merged):len(merged)], description)
+		return
+	}
+	data[key] = mergedField{old, description}
+}
+
+// mergedField is a list of descriptions for the same key.
+type mergedField []fieldDescription
+
+func (m mergedField) keys(key string, value json.RawMessage, data map[string]fieldDescription) error {
+	for _, description := range m {
+		if description == nil {
+			continue
+		}
+
+		if err := description.keys(key, value, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m mergedField) depth() int {
+	var deepest int
+	for _, description := range m {
+		if description == nil {
+			continue
+		}
+
+		if d := description.depth(); d > deepest {
+			deepest = d
+		}
+	}
+	return deepest
+}
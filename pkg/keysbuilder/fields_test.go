@@ -5,8 +5,8 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/OpenSlides/openslides-autoupdate-service/internal/keysbuilder"
 	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/keysbuilder"
 )
 
 func TestJSONValid(t *testing.T) {
@@ -118,6 +118,27 @@ func TestRequestErrors(t *testing.T) {
 			"no ids",
 			strs(),
 		},
+		{
+			"ids and from",
+			`{
+				"ids": [5],
+				"from": "meeting/5/motion_ids",
+				"collection": "motion",
+				"fields": {"title": null}
+			}`,
+			"ids and from can not be used together",
+			strs(),
+		},
+		{
+			"from no fqfield",
+			`{
+				"from": "meeting/5",
+				"collection": "motion",
+				"fields": {"title": null}
+			}`,
+			"from has to be a key like motion/1/field, got meeting/5",
+			strs(),
+		},
 		{
 			"Relation no collection",
 			`{
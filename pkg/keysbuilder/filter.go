@@ -0,0 +1,161 @@
+package keysbuilder
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// filter is a condition on a field of an object. Only objects that match the
+// condition are requested.
+//
+// A filter is written as `field operator value`, for example
+// `is_hidden == false` or `weight >= 10`. The value has to be a json value.
+// The operators `==` and `!=` can be used for every value. The operators `<`,
+// `<=`, `>` and `>=` can only be used for numbers and strings.
+//
+// Objects, where the field does not exist or the user can not see it, do not
+// match.
+type filter struct {
+	field    string
+	operator string
+	value    interface{}
+}
+
+// parseFilter parses a filter expression. It returns nil, if the expression is
+// empty.
+func parseFilter(expr string) (*filter, error) {
+	if expr == "" {
+		return nil, nil
+	}
+
+	parts := strings.SplitN(strings.TrimSpace(expr), " ", 3)
+	if len(parts) != 3 || parts[0] == "" {
+		return nil, filterError("filter has to be like `field == value`, got `%s`", expr)
+	}
+
+	f := filter{field: parts[0], operator: parts[1]}
+	if err := json.Unmarshal([]byte(parts[2]), &f.value); err != nil {
+		return nil, filterError("invalid value in filter `%s`: %v", expr, err)
+	}
+
+	switch f.operator {
+	case "==", "!=":
+	case "<", "<=", ">", ">=":
+		switch f.value.(type) {
+		case float64, string:
+		default:
+			return nil, filterError("operator %s can only be used with numbers and strings, got `%s`", f.operator, expr)
+		}
+	default:
+		return nil, filterError("unknown operator %s in filter `%s`", f.operator, expr)
+	}
+
+	return &f, nil
+}
+
+// match returns true, if the value of the field matches the filter.
+func (f *filter) match(value json.RawMessage) bool {
+	var got interface{}
+	if err := json.Unmarshal(value, &got); err != nil {
+		return false
+	}
+
+	switch f.operator {
+	case "==":
+		return reflect.DeepEqual(got, f.value)
+	case "!=":
+		return !reflect.DeepEqual(got, f.value)
+	}
+
+	var cmp int
+	switch want := f.value.(type) {
+	case float64:
+		n, ok := got.(float64)
+		if !ok {
+			return false
+		}
+		switch {
+		case n < want:
+			cmp = -1
+		case n > want:
+			cmp = 1
+		}
+
+	case string:
+		s, ok := got.(string)
+		if !ok {
+			return false
+		}
+		cmp = strings.Compare(s, want)
+
+	default:
+		return false
+	}
+
+	switch f.operator {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+func filterError(format string, a ...interface{}) InvalidError {
+	return InvalidError{msg: fmt.Sprintf(format, a...), code: CodeInvalidFilter, attr: "filter"}
+}
+
+// addObject requests the fields of an object. If a filter is given, only the
+// filter field is requested. The other fields are requested when the value of
+// the filter field matches.
+func addObject(data map[string]fieldDescription, cid string, fields fieldsMap, f *filter) {
+	if f == nil {
+		fields.keys(cid, data)
+		return
+	}
+
+	addKey(data, buildGenericKey(cid, f.field), &filteredObject{cid: cid, filter: f, fields: fields})
+}
+
+// filteredObject is the description of the filter field of an object. The key
+// is only needed to evaluate the filter. It is not send to the client, if it
+// was not requested in another way.
+type filteredObject struct {
+	cid    string
+	filter *filter
+	fields fieldsMap
+}
+
+func (o *filteredObject) keys(key string, value json.RawMessage, data map[string]fieldDescription) error {
+	if o.filter.match(value) {
+		o.fields.keys(o.cid, data)
+	}
+	return nil
+}
+
+func (o *filteredObject) depth() int {
+	return o.fields.depth()
+}
+
+// requested returns false, if the key of the description is only needed to
+// evaluate a filter.
+func requested(description fieldDescription) bool {
+	switch d := description.(type) {
+	case *filteredObject:
+		return false
+
+	case mergedField:
+		for _, sub := range d {
+			if requested(sub) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,142 @@
+package keysbuilder_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/keysbuilder"
+)
+
+func TestFilter(t *testing.T) {
+	data := map[string]json.RawMessage{
+		"meeting/1/agenda_item_ids": []byte("[1,2,3,4]"),
+		"agenda_item/1/is_hidden":   []byte("false"),
+		"agenda_item/2/is_hidden":   []byte("true"),
+		"agenda_item/3/is_hidden":   []byte("false"),
+		"agenda_item/1/weight":      []byte("5"),
+		"agenda_item/2/weight":      []byte("10"),
+		"agenda_item/3/weight":      []byte("20"),
+		"agenda_item/1/item_number": []byte(`"A"`),
+		"agenda_item/2/item_number": []byte(`"B"`),
+		"agenda_item/3/item_number": []byte(`"C"`),
+	}
+
+	for _, tt := range []struct {
+		name    string
+		request string
+		expect  []string
+	}{
+		{
+			"relation-list",
+			`{
+				"ids": [1],
+				"collection": "meeting",
+				"fields": {
+					"agenda_item_ids": {
+						"type": "relation-list",
+						"collection": "agenda_item",
+						"filter": "is_hidden == false",
+						"fields": {"item_number": null}
+					}
+				}
+			}`,
+			strs("meeting/1/agenda_item_ids", "agenda_item/1/item_number", "agenda_item/3/item_number"),
+		},
+		{
+			"filter field requested",
+			`{
+				"ids": [1],
+				"collection": "meeting",
+				"fields": {
+					"agenda_item_ids": {
+						"type": "relation-list",
+						"collection": "agenda_item",
+						"filter": "is_hidden != true",
+						"fields": {"item_number": null, "is_hidden": null}
+					}
+				}
+			}`,
+			strs(
+				"meeting/1/agenda_item_ids",
+				"agenda_item/1/item_number",
+				"agenda_item/1/is_hidden",
+				"agenda_item/3/item_number",
+				"agenda_item/3/is_hidden",
+			),
+		},
+		{
+			"from",
+			`{
+				"from": "meeting/1/agenda_item_ids",
+				"collection": "agenda_item",
+				"filter": "weight >= 10",
+				"fields": {"item_number": null}
+			}`,
+			strs("meeting/1/agenda_item_ids", "agenda_item/2/item_number", "agenda_item/3/item_number"),
+		},
+		{
+			"ids",
+			`{
+				"ids": [1, 2, 3, 4],
+				"collection": "agenda_item",
+				"filter": "item_number < \"B\"",
+				"fields": {"weight": null}
+			}`,
+			strs("agenda_item/1/weight"),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := keysbuilder.FromJSON(strings.NewReader(tt.request), &test.DataProvider{Data: data}, 1)
+			if err != nil {
+				t.Fatalf("FromJSON returned unexpected error: %v", err)
+			}
+
+			if err := b.Update(context.Background()); err != nil {
+				t.Fatalf("Update returned unexpected error: %v", err)
+			}
+
+			keys := b.Keys()
+			if diff := cmpSet(set(tt.expect...), set(keys...)); diff != nil {
+				t.Errorf("Got keys %v, expected %v", diff, tt.expect)
+			}
+
+			if len(keys) != len(tt.expect) {
+				t.Errorf("Got %d keys, expected %d: %v", len(keys), len(tt.expect), keys)
+			}
+		})
+	}
+}
+
+func TestFilterInvalid(t *testing.T) {
+	for _, filter := range []string{
+		"is_hidden",
+		"is_hidden false",
+		"is_hidden = false",
+		"is_hidden == nope",
+		"is_hidden < false",
+	} {
+		t.Run(filter, func(t *testing.T) {
+			request, _ := json.Marshal(map[string]interface{}{
+				"ids":        []int{1},
+				"collection": "agenda_item",
+				"filter":     filter,
+				"fields":     map[string]interface{}{"item_number": nil},
+			})
+
+			_, err := keysbuilder.FromJSON(strings.NewReader(string(request)), new(test.DataProvider), 1)
+
+			var errInvalid keysbuilder.InvalidError
+			if !errors.As(err, &errInvalid) {
+				t.Fatalf("FromJSON returned %v, expected an InvalidError", err)
+			}
+
+			if errInvalid.Code() != keysbuilder.CodeInvalidFilter {
+				t.Errorf("Got error code %s, expected %s", errInvalid.Code(), keysbuilder.CodeInvalidFilter)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package keysbuilder
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// DataProvider decodes a restricted value for an key.
+type DataProvider interface {
+	RestrictedData(ctx context.Context, uid int, keys ...string) (map[string]json.RawMessage, error)
+}
+
+type fieldDescription interface {
+	keys(key string, value json.RawMessage, data map[string]fieldDescription) error
+
+	// depth returns the number of relations from this field to the deepest
+	// field.
+	depth() int
+}
+
+// Schema tells the type of relation fields. fieldType is one of relation,
+// relation-list, generic-relation and generic-relation-list. to is the
+// collection, the field points to. ok is false, if the field is not a
+// relation.
+//
+// The models.Models from pkg/models implements this interface.
+type Schema interface {
+	RelationType(collection, field string) (fieldType string, to string, ok bool)
+}
@@ -0,0 +1,66 @@
+package keysbuilder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// FromJSON creates a Keysbuilder from json.
+func FromJSON(r io.Reader, dataProvider DataProvider, uid int, options ...Option) (*Builder, error) {
+	var b body
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		if err == io.EOF {
+			return nil, InvalidError{msg: "No data", code: CodeNoData}
+		}
+		if sub, ok := err.(InvalidError); ok {
+			return nil, sub
+		}
+		return nil, JSONError{err}
+	}
+
+	return newBuilder(dataProvider, uid, []body{b}, options)
+}
+
+// ManyFromJSON creates a list of Keysbuilder objects from a json list.
+func ManyFromJSON(r io.Reader, dataProvider DataProvider, uid int, options ...Option) (*Builder, error) {
+	var rawBodies []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&rawBodies); err != nil {
+		if err == io.EOF {
+			return nil, InvalidError{msg: "No data", code: CodeNoData}
+		}
+		if jerr, ok := err.(*json.SyntaxError); ok {
+			return nil, JSONError{jerr}
+		}
+		if jerr, ok := err.(*json.UnmarshalTypeError); ok {
+			return nil, wrongTypeError(jerr)
+		}
+		return nil, fmt.Errorf("decode keysrequest: %w", err)
+	}
+
+	if len(rawBodies) == 0 {
+		return nil, InvalidError{msg: "No data", code: CodeNoData}
+	}
+
+	bs := make([]body, len(rawBodies))
+	for i, raw := range rawBodies {
+		if err := json.Unmarshal(raw, &bs[i]); err != nil {
+			var invalid InvalidError
+			var jerr *json.UnmarshalTypeError
+			switch {
+			case errors.As(err, &invalid):
+			case errors.As(err, &jerr):
+				invalid = wrongTypeError(jerr)
+			default:
+				return nil, fmt.Errorf("decode keysrequest %d: %w", i, err)
+			}
+
+			invalid.index = fmt.Sprintf("[%d]", i)
+			return nil, invalid
+		}
+		bs[i].index = fmt.Sprintf("[%d]", i)
+	}
+
+	return newBuilder(dataProvider, uid, bs, options)
+}
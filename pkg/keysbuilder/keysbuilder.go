@@ -7,10 +7,39 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/intern"
 )
 
 const keySep = "/"
 
+// Default limits of a Builder.
+const (
+	DefaultMaxDepth = 15
+	DefaultMaxKeys  = 1_000_000
+)
+
+// Option is an optional argument for FromJSON and ManyFromJSON.
+type Option func(*Builder)
+
+// WithMaxDepth sets the maximum number of relations from a body to its
+// deepest field. Requests with more relations are rejected. A value of 0
+// means no limit. The default is DefaultMaxDepth.
+func WithMaxDepth(n int) Option {
+	return func(b *Builder) {
+		b.maxDepth = n
+	}
+}
+
+// WithMaxKeys sets the maximum number of keys, a Builder can generate. Update
+// returns an error, if there are more keys. A value of 0 means no limit. The
+// default is DefaultMaxKeys.
+func WithMaxKeys(n int) Option {
+	return func(b *Builder) {
+		b.maxKeys = n
+	}
+}
+
 // Builder builds the keys. It is not save for concourent use. There is one
 // Builder instance per client. It is not allowed to call builder.Update() more
 // then once or at the same time as builder.Keys(). It is ok to call
@@ -22,6 +51,54 @@ type Builder struct {
 	uid          int
 	bodies       []body
 	keys         []string
+
+	maxDepth int
+	maxKeys  int
+
+	relations map[string]string
+	schema    Schema
+}
+
+// newBuilder initializes a Builder and validates its limits.
+func newBuilder(dataProvider DataProvider, uid int, bodies []body, options []Option) (*Builder, error) {
+	b := &Builder{
+		dataProvider: dataProvider,
+		uid:          uid,
+		bodies:       bodies,
+		maxDepth:     DefaultMaxDepth,
+		maxKeys:      DefaultMaxKeys,
+	}
+
+	for _, o := range options {
+		o(b)
+	}
+
+	for _, body := range bodies {
+		if err := resolveTypes(b.schema, body.collection, body.fieldsMap); err != nil {
+			err.index = body.index
+			return nil, *err
+		}
+	}
+
+	if b.maxDepth > 0 {
+		for _, body := range bodies {
+			if d := body.depth(); d > b.maxDepth {
+				return nil, LimitError{
+					msg:  fmt.Sprintf("request has %d levels of relations, only %d are allowed", d, b.maxDepth),
+					code: CodeTooDeep,
+				}
+			}
+		}
+	}
+
+	if b.relations != nil {
+		for _, body := range bodies {
+			if err := body.checkRelations(b.relations); err != nil {
+				return nil, *err
+			}
+		}
+	}
+	return b, nil
 }
 
 // Update triggers a key update. It generates the list of keys, that can be
@@ -46,10 +123,15 @@ func (b *Builder) Update(ctx context.Context) (err error) {
 	b.keys = b.keys[:0]
 	var needed []string
 	processed := make(map[string]fieldDescription)
+	seen := make(map[string]bool)
 	for {
 		// Get all keys and descriptions
 		for key, description := range process {
-			b.keys = append(b.keys, key)
+			if !seen[key] && requested(description) {
+				seen[key] = true
+				b.keys = append(b.keys, intern.String(key))
+			}
+
 			if description == nil {
 				continue
 			}
@@ -58,6 +140,13 @@ func (b *Builder) Update(ctx context.Context) (err error) {
 			processed[key] = description
 		}
 
+		if b.maxKeys > 0 && len(b.keys) > b.maxKeys {
+			return LimitError{
+				msg:  fmt.Sprintf("request has more then %d keys", b.maxKeys),
+				code: CodeTooManyKeys,
+			}
+		}
+
 		if len(needed) == 0 {
 			break
 		}
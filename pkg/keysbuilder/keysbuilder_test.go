@@ -8,8 +8,8 @@ import (
 	"testing"
 	"time"
 
-	"github.com/OpenSlides/openslides-autoupdate-service/internal/keysbuilder"
 	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/keysbuilder"
 )
 
 func TestKeys(t *testing.T) {
@@ -261,6 +261,62 @@ func TestKeys(t *testing.T) {
 			},
 			strs("user/1/likes", "other/1/name", "other/2/name"),
 		},
+		{
+			"Generic list field different collections",
+			`{
+				"ids": [1],
+				"collection": "user",
+				"fields": {
+					"likes": {
+						"type": "generic-relation-list",
+						"fields": {"name": null}
+					}
+				}
+			}`,
+			map[string]json.RawMessage{
+				"user/1/likes": []byte(`["motion/1","assignment/2"]`),
+			},
+			strs("user/1/likes", "motion/1/name", "assignment/2/name"),
+		},
+		{
+			"Generic field null",
+			`{
+				"ids": [1],
+				"collection": "user",
+				"fields": {
+					"likes": {
+						"type": "generic-relation",
+						"fields": {"name": null}
+					}
+				}
+			}`,
+			map[string]json.RawMessage{
+				"user/1/likes": []byte(`null`),
+			},
+			strs("user/1/likes"),
+		},
+		{
+			"From relation list",
+			`{
+				"from": "meeting/5/motion_ids",
+				"collection": "motion",
+				"fields": {"title": null}
+			}`,
+			map[string]json.RawMessage{
+				"meeting/5/motion_ids": []byte(`[1,2]`),
+			},
+			strs("meeting/5/motion_ids", "motion/1/title", "motion/2/title"),
+		},
+		{
+			"From relation list empty",
+			`{
+				"from": "meeting/5/motion_ids",
+				"collection": "motion",
+				"fields": {"title": null}
+			}`,
+			nil,
+			strs("meeting/5/motion_ids"),
+		},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
 			dataProvider := &test.DataProvider{Data: tt.data}
@@ -431,6 +487,30 @@ func TestUpdate(t *testing.T) {
 			strs("user/1/group_ids", "group/2/perm_ids", "perm/2/name", "perm/1/name"),
 			1,
 		},
+		{
+			"Template replacements change",
+			`{
+				"ids": [1],
+				"collection": "user",
+				"fields": {"group_$_ids": null}
+			}`,
+			map[string]json.RawMessage{"user/1/group_$_ids": []byte(`["1","2"]`)},
+			map[string]json.RawMessage{"user/1/group_$_ids": []byte(`["2","3"]`)},
+			strs("user/1/group_$_ids", "user/1/group_$2_ids", "user/1/group_$3_ids"),
+			1,
+		},
+		{
+			"From relation list changes",
+			`{
+				"from": "meeting/5/motion_ids",
+				"collection": "motion",
+				"fields": {"title": null}
+			}`,
+			map[string]json.RawMessage{"meeting/5/motion_ids": []byte("[1,2]")},
+			map[string]json.RawMessage{"meeting/5/motion_ids": []byte("[2,3]")},
+			strs("meeting/5/motion_ids", "motion/2/title", "motion/3/title"),
+			1,
+		},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
 			dataProvider := &test.DataProvider{Data: tt.data}
@@ -499,6 +579,68 @@ func TestConcurency(t *testing.T) {
 	}
 }
 
+func TestManyRequestsSameKey(t *testing.T) {
+	jsonData := `
+	[
+		{
+			"ids": [7],
+			"collection": "meeting",
+			"fields": {
+				"motion_ids": {
+					"type": "relation-list",
+					"collection": "motion",
+					"fields": {"title": null}
+				}
+			}
+		}, {
+			"ids": [7],
+			"collection": "meeting",
+			"fields": {
+				"motion_ids": {
+					"type": "relation-list",
+					"collection": "motion",
+					"fields": {"number": null}
+				},
+				"agenda_item_ids": null
+			}
+		}, {
+			"from": "meeting/7/motion_ids",
+			"collection": "motion",
+			"fields": {"title": null, "text": null}
+		}
+	]`
+	data := map[string]json.RawMessage{
+		"meeting/7/motion_ids": []byte("[1,2]"),
+	}
+	b, err := keysbuilder.ManyFromJSON(strings.NewReader(jsonData), &test.DataProvider{Data: data}, 1)
+	if err != nil {
+		t.Fatalf("ManyFromJSON returned unexpected error: %v", err)
+	}
+
+	if err := b.Update(context.Background()); err != nil {
+		t.Fatalf("Update returned unexpected error: %v", err)
+	}
+
+	keys := b.Keys()
+	expect := strs(
+		"meeting/7/motion_ids",
+		"meeting/7/agenda_item_ids",
+		"motion/1/title",
+		"motion/1/number",
+		"motion/1/text",
+		"motion/2/title",
+		"motion/2/number",
+		"motion/2/text",
+	)
+	if diff := cmpSet(set(expect...), set(keys...)); diff != nil {
+		t.Errorf("Got keys %v, expected %v", diff, expect)
+	}
+
+	if len(keys) != len(expect) {
+		t.Errorf("Got %d keys, expected %d: %v", len(keys), len(expect), keys)
+	}
+}
+
 func TestManyRequests(t *testing.T) {
 	jsonData := `
 	[
@@ -584,6 +726,40 @@ func TestError(t *testing.T) {
 	}
 }
 
+func TestGenericInvalidValue(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		fieldType string
+		value     string
+	}{
+		{"no collection", "generic-relation", `"5"`},
+		{"no id", "generic-relation", `"motion/"`},
+		{"key", "generic-relation", `"motion/5/title"`},
+		{"in list", "generic-relation-list", `["motion/1", "motion"]`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			request := `{
+				"ids": [1],
+				"collection": "user",
+				"fields": {"likes": {"type": "` + tt.fieldType + `", "fields": {"name": null}}}
+			}`
+			dataProvider := &test.DataProvider{Data: map[string]json.RawMessage{"user/1/likes": []byte(tt.value)}}
+
+			b, err := keysbuilder.FromJSON(strings.NewReader(request), dataProvider, 1)
+			if err != nil {
+				t.Fatalf("FromJSON returned unexpected error: %v", err)
+			}
+
+			err = b.Update(context.Background())
+
+			var errValue keysbuilder.ValueError
+			if !errors.As(err, &errValue) {
+				t.Errorf("Update returned %v, expected a ValueError", err)
+			}
+		})
+	}
+}
+
 func TestRequestCount(t *testing.T) {
 	dataProvider := new(test.DataProvider)
 	json := `{
@@ -660,3 +836,81 @@ func TestLazy(t *testing.T) {
 		t.Errorf("Got %s, expected %s", got, expect)
 	}
 }
+
+func TestLimits(t *testing.T) {
+	request := `{
+		"ids": [1],
+		"collection": "user",
+		"fields": {
+			"group_ids": {
+				"type": "relation-list",
+				"collection": "group",
+				"fields": {
+					"perm_ids": {
+						"type": "relation-list",
+						"collection": "perm",
+						"fields": {"name": null}
+					}
+				}
+			}
+		}
+	}`
+
+	dataProvider := &test.DataProvider{Data: map[string]json.RawMessage{
+		"user/1/group_ids": []byte("[1,2]"),
+		"group/1/perm_ids": []byte("[1,2]"),
+		"group/2/perm_ids": []byte("[3]"),
+	}}
+
+	t.Run("depth", func(t *testing.T) {
+		_, err := keysbuilder.FromJSON(strings.NewReader(request), dataProvider, 1, keysbuilder.WithMaxDepth(1))
+
+		var errLimit keysbuilder.LimitError
+		if !errors.As(err, &errLimit) {
+			t.Fatalf("FromJSON returned %v, expected a LimitError", err)
+		}
+
+		if errLimit.Code() != keysbuilder.CodeTooDeep {
+			t.Errorf("Got error code %s, expected %s", errLimit.Code(), keysbuilder.CodeTooDeep)
+		}
+	})
+
+	t.Run("depth ok", func(t *testing.T) {
+		if _, err := keysbuilder.FromJSON(strings.NewReader(request), dataProvider, 1, keysbuilder.WithMaxDepth(2)); err != nil {
+			t.Errorf("FromJSON returned unexpected error: %v", err)
+		}
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		b, err := keysbuilder.FromJSON(strings.NewReader(request), dataProvider, 1, keysbuilder.WithMaxKeys(5))
+		if err != nil {
+			t.Fatalf("FromJSON returned unexpected error: %v", err)
+		}
+
+		err = b.Update(context.Background())
+
+		var errLimit keysbuilder.LimitError
+		if !errors.As(err, &errLimit) {
+			t.Fatalf("Update returned %v, expected a LimitError", err)
+		}
+
+		if errLimit.Code() != keysbuilder.CodeTooManyKeys {
+			t.Errorf("Got error code %s, expected %s", errLimit.Code(), keysbuilder.CodeTooManyKeys)
+		}
+
+		if keys := b.Keys(); len(keys) != 0 {
+			t.Errorf("Got keys %v after error, expected none", keys)
+		}
+	})
+
+	t.Run("keys ok", func(t *testing.T) {
+		b, err := keysbuilder.FromJSON(strings.NewReader(request), dataProvider, 1, keysbuilder.WithMaxKeys(6))
+		if err != nil {
+			t.Fatalf("FromJSON returned unexpected error: %v", err)
+		}
+
+		if err := b.Update(context.Background()); err != nil {
+			t.Errorf("Update returned unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package keysbuilder
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Presets are named lists of fields. A request can use a preset with the
+// attribute "preset" instead of or together with the attribute "fields". The
+// fields of the request are added to the fields of the preset.
+//
+//	{
+//		"ids": [1],
+//		"collection": "motion",
+//		"preset": "motion-list",
+//		"fields": {"text": null}
+//	}
+//
+// The values are the fields in the same format as the attribute "fields". A
+// preset can use other presets.
+var Presets = map[string]string{
+	"user-name": `{
+		"title": null,
+		"first_name": null,
+		"last_name": null,
+		"username": null,
+		"structure_level_$": null
+	}`,
+
+	"motion-list": `{
+		"title": null,
+		"number": null,
+		"sequential_number": null,
+		"category_id": null,
+		"block_id": null,
+		"tag_ids": null,
+		"state_id": {
+			"type": "relation",
+			"collection": "motion_state",
+			"fields": {"name": null, "css_class": null}
+		},
+		"submitter_ids": {
+			"type": "relation-list",
+			"collection": "motion_submitter",
+			"fields": {
+				"weight": null,
+				"user_id": {
+					"type": "relation",
+					"collection": "user",
+					"preset": "user-name"
+				}
+			}
+		}
+	}`,
+
+	"agenda-list": `{
+		"item_number": null,
+		"comment": null,
+		"closed": null,
+		"type": null,
+		"is_hidden": null,
+		"is_internal": null,
+		"level": null,
+		"weight": null,
+		"parent_id": null,
+		"duration": null,
+		"content_object_id": {
+			"type": "generic-relation",
+			"fields": {"title": null, "number": null}
+		}
+	}`,
+
+	"speaker-list": `{
+		"begin_time": null,
+		"end_time": null,
+		"weight": null,
+		"marked": null,
+		"point_of_order": null,
+		"user_id": {
+			"type": "relation",
+			"collection": "user",
+			"preset": "user-name"
+		}
+	}`,
+}
+
+// withPreset returns the fields of the preset together with the given fields.
+//
+// Without a preset, the given fields are returned. It is an error, if there
+// is neither a preset nor fields.
+func withPreset(fields fieldsMap, preset string) (fieldsMap, error) {
+	if preset == "" {
+		if fields.fields == nil {
+			return fieldsMap{}, InvalidError{msg: "no fields", code: CodeMissingFields, attr: "fields"}
+		}
+		return fields, nil
+	}
+
+	raw, ok := Presets[preset]
+	if !ok {
+		return fieldsMap{}, InvalidError{msg: fmt.Sprintf("unknown preset %s", preset), code: CodeUnknownPreset, attr: "preset"}
+	}
+
+	var presetFields fieldsMap
+	if err := json.Unmarshal([]byte(raw), &presetFields); err != nil {
+		return fieldsMap{}, fmt.Errorf("decoding preset %s: %w", preset, err)
+	}
+
+	for name, description := range fields.fields {
+		presetFields.fields[name] = description
+	}
+	return presetFields, nil
+}
@@ -0,0 +1,82 @@
+package keysbuilder_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/keysbuilder"
+)
+
+func TestPresetsValid(t *testing.T) {
+	for name := range keysbuilder.Presets {
+		t.Run(name, func(t *testing.T) {
+			request := fmt.Sprintf(`{"ids": [1], "collection": "some", "preset": "%s"}`, name)
+			if _, err := keysbuilder.FromJSON(strings.NewReader(request), new(test.DataProvider), 1); err != nil {
+				t.Errorf("Preset is invalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestPreset(t *testing.T) {
+	request := `{
+		"ids": [1],
+		"collection": "speaker",
+		"preset": "speaker-list",
+		"fields": {
+			"user_id": {
+				"type": "relation",
+				"collection": "user",
+				"fields": {"username": null}
+			},
+			"extra": null
+		}
+	}`
+
+	dataProvider := &test.DataProvider{Data: map[string]json.RawMessage{
+		"speaker/1/user_id": []byte("5"),
+	}}
+
+	b, err := keysbuilder.FromJSON(strings.NewReader(request), dataProvider, 1)
+	if err != nil {
+		t.Fatalf("FromJSON returned unexpected error: %v", err)
+	}
+
+	if err := b.Update(context.Background()); err != nil {
+		t.Fatalf("Update returned unexpected error: %v", err)
+	}
+
+	expect := strs(
+		"speaker/1/begin_time",
+		"speaker/1/end_time",
+		"speaker/1/weight",
+		"speaker/1/marked",
+		"speaker/1/point_of_order",
+		"speaker/1/user_id",
+		"speaker/1/extra",
+		"user/5/username",
+	)
+	if diff := cmpSet(set(expect...), set(b.Keys()...)); diff != nil {
+		t.Errorf("Got keys %v, expected %v", diff, expect)
+	}
+}
+
+func TestPresetUnknown(t *testing.T) {
+	request := `{"ids": [1], "collection": "motion", "preset": "unknown"}`
+
+	_, err := keysbuilder.FromJSON(strings.NewReader(request), new(test.DataProvider), 1)
+
+	var errInvalid keysbuilder.InvalidError
+	if !errors.As(err, &errInvalid) {
+		t.Fatalf("FromJSON returned %v, expected an InvalidError", err)
+	}
+
+	if errInvalid.Code() != keysbuilder.CodeUnknownPreset {
+		t.Errorf("Got error code %s, expected %s", errInvalid.Code(), keysbuilder.CodeUnknownPreset)
+	}
+}
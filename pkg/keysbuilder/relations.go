@@ -0,0 +1,102 @@
+package keysbuilder
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/models"
+)
+
+// WithRelations checks the relation fields of a request against the models.
+// relations is a map from `collection/field` to the collection the field
+// points to, for example from models.Models.Relations.
+//
+// A request, that follows a relation or relation-list field to another
+// collection, is rejected. Fields, that are not in relations, are not checked,
+// for example calculated fields.
+func WithRelations(relations map[string]string) Option {
+	return func(b *Builder) {
+		b.relations = relations
+	}
+}
+
+// checkRelations returns an error, if a relation field in the fields of the
+// collection points to another collection then given in relations.
+func checkRelations(relations map[string]string, collection string, fields fieldsMap) *InvalidError {
+	for name, description := range fields.fields {
+		if err := checkRelation(relations, collection, name, description); err != nil {
+			return &InvalidError{sub: err, msg: "Error on field", field: name}
+		}
+	}
+	return nil
+}
+
+// checkRelation checks one field.
+func checkRelation(relations map[string]string, collection, name string, description fieldDescription) *InvalidError {
+	var to string
+	var fields fieldsMap
+	switch d := description.(type) {
+	case *templateField:
+		if d.values == nil {
+			return nil
+		}
+		if err := checkRelation(relations, collection, name, d.values); err != nil {
+			return &InvalidError{sub: err, msg: "Error in template sub", field: "template", values: true}
+		}
+		return nil
+
+	case *relationField:
+		to = d.collection
+		fields = d.fieldsMap
+
+	case *relationListField:
+		to = d.collection
+		fields = d.fieldsMap
+
+	case mergedField:
+		for _, sub := range d {
+			if err := checkRelation(relations, collection, name, sub); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		// The collection of generic relations is only known at runtime.
+		return nil
+	}
+
+	expected, ok := relations[collection+keySep+models.TemplateName(name)]
+	if ok && expected != to {
+		return &InvalidError{
+			msg:  fmt.Sprintf("%s/%s points to %s, not %s", collection, name, expected, to),
+			code: CodeWrongCollection,
+			attr: "collection",
+		}
+	}
+
+	return checkRelations(relations, to, fields)
+}
+
+// checkRelations checks the relation fields of the body and its attribute
+// `from`.
+func (b *body) checkRelations(relations map[string]string) *InvalidError {
+	if b.from != "" {
+		parts := strings.Split(b.from, keySep)
+		expected, ok := relations[parts[0]+keySep+models.TemplateName(parts[2])]
+		if ok && expected != b.collection {
+			return &InvalidError{
+				msg:   fmt.Sprintf("%s points to %s, not %s", b.from, expected, b.collection),
+				code:  CodeWrongCollection,
+				attr:  "collection",
+				index: b.index,
+			}
+		}
+	}
+
+	if err := checkRelations(relations, b.collection, b.fieldsMap); err != nil {
+		err.index = b.index
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package keysbuilder_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/keysbuilder"
+)
+
+func TestWithRelations(t *testing.T) {
+	relations := map[string]string{
+		"user/group_$_ids":   "group",
+		"group/meeting_id":   "meeting",
+		"meeting/motion_ids": "motion",
+	}
+
+	for _, tt := range []struct {
+		name    string
+		request string
+		path    string
+	}{
+		{
+			"valid",
+			`{
+				"ids": [1],
+				"collection": "user",
+				"fields": {
+					"group_$_ids": {
+						"type": "relation-list",
+						"collection": "group",
+						"fields": {"meeting_id": {"type": "relation", "collection": "meeting", "fields": {"name": null}}}
+					},
+					"calculated_ids": {"type": "relation-list", "collection": "anything", "fields": {"name": null}}
+				}
+			}`,
+			"",
+		},
+		{
+			"wrong collection",
+			`{
+				"ids": [1],
+				"collection": "user",
+				"fields": {
+					"group_$_ids": {
+						"type": "relation-list",
+						"collection": "group",
+						"fields": {"meeting_id": {"type": "relation", "collection": "motion", "fields": {"name": null}}}
+					}
+				}
+			}`,
+			"fields.group_$_ids.values.fields.meeting_id.collection",
+		},
+		{
+			"wrong from",
+			`{
+				"from": "meeting/1/motion_ids",
+				"collection": "group",
+				"fields": {"name": null}
+			}`,
+			"collection",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := keysbuilder.FromJSON(strings.NewReader(tt.request), &test.DataProvider{}, 1, keysbuilder.WithRelations(relations))
+
+			if tt.path == "" {
+				if err != nil {
+					t.Fatalf("FromJSON returned unexpected error: %v", err)
+				}
+				return
+			}
+
+			var errInvalid keysbuilder.InvalidError
+			if !errors.As(err, &errInvalid) {
+				t.Fatalf("FromJSON returned %v, expected an InvalidError", err)
+			}
+
+			if errInvalid.Code() != keysbuilder.CodeWrongCollection {
+				t.Errorf("Got error code %s, expected %s", errInvalid.Code(), keysbuilder.CodeWrongCollection)
+			}
+
+			if got := errInvalid.Path(); got != tt.path {
+				t.Errorf("Got path %s, expected %s", got, tt.path)
+			}
+		})
+	}
+}
+
+func TestWithRelationsManyRequests(t *testing.T) {
+	request := `[
+		{"ids": [1], "collection": "meeting", "fields": {"name": null}},
+		{"ids": [1], "collection": "meeting", "fields": {"motion_ids": {"type": "relation-list", "collection": "user", "fields": {"name": null}}}}
+	]`
+
+	_, err := keysbuilder.ManyFromJSON(strings.NewReader(request), &test.DataProvider{}, 1, keysbuilder.WithRelations(map[string]string{"meeting/motion_ids": "motion"}))
+
+	var errInvalid keysbuilder.InvalidError
+	if !errors.As(err, &errInvalid) {
+		t.Fatalf("ManyFromJSON returned %v, expected an InvalidError", err)
+	}
+
+	if got := errInvalid.Path(); got != "[1].fields.motion_ids.collection" {
+		t.Errorf("Got path %s, expected [1].fields.motion_ids.collection", got)
+	}
+}
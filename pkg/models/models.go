@@ -0,0 +1,244 @@
+// Package models reads the models.yml of OpenSlides.
+//
+// The models.yml defines the collections, their fields, the relations between
+// them and the restriction modes of the fields. The other packages can use
+// this information instead of their own lists. For example:
+//
+//	m, err := models.Parse(file)
+//	autoupdate.WithRelations(m.RelationLists())
+//	restrict.NewAnonymous(permer, ds, m.RestrictionModes())
+//	keysbuilder.WithRelations(m.Relations())
+//	keysbuilder.WithSchema(m)
+package models
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Field types of the models.yml, that are relations.
+const (
+	TypeRelation            = "relation"
+	TypeRelationList        = "relation-list"
+	TypeGenericRelation     = "generic-relation"
+	TypeGenericRelationList = "generic-relation-list"
+	TypeTemplate            = "template"
+)
+
+// Field is a field of a collection.
+type Field struct {
+	// Collection is the name of the collection of the field.
+	Collection string
+
+	// Name is the name of the field. Template fields have the name of the
+	// template, for example `group_$_ids`.
+	Name string
+
+	// Type is the type of the field. For template fields, it is the type of
+	// the replaced fields.
+	Type string
+
+	// To is the collection, the relation points to. It is `*` for generic
+	// relations and empty, if the field is not a relation.
+	To string
+
+	// RestrictionMode is the restriction mode of the field, for example `A`.
+	RestrictionMode string
+
+	// Template is true, if the field is a template field.
+	Template bool
+}
+
+// Models is the parsed content of a models.yml.
+type Models struct {
+	fields map[string]Field
+}
+
+// Parse reads a models.yml.
+func Parse(r io.Reader) (*Models, error) {
+	var raw map[string]map[string]yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("decoding models: %w", err)
+	}
+
+	m := &Models{fields: make(map[string]Field)}
+	for collection, fields := range raw {
+		for name, node := range fields {
+			field, err := parseField(collection, name, &node)
+			if err != nil {
+				return nil, fmt.Errorf("parsing field %s/%s: %w", collection, name, err)
+			}
+			m.fields[collection+"/"+name] = field
+		}
+	}
+	return m, nil
+}
+
+// fieldDef is the definition of a field in the models.yml.
+type fieldDef struct {
+	Type            string    `yaml:"type"`
+	To              yaml.Node `yaml:"to"`
+	RestrictionMode string    `yaml:"restriction_mode"`
+	Fields          yaml.Node `yaml:"fields"`
+}
+
+func parseField(collection, name string, node *yaml.Node) (Field, error) {
+	field := Field{Collection: collection, Name: name}
+
+	if node.Kind == yaml.ScalarNode {
+		field.Type = node.Value
+		return field, nil
+	}
+
+	var def fieldDef
+	if err := node.Decode(&def); err != nil {
+		return Field{}, fmt.Errorf("decoding field: %w", err)
+	}
+	field.Type = def.Type
+	field.RestrictionMode = def.RestrictionMode
+
+	if def.Type == TypeTemplate {
+		// The type and the relation is defined by the replaced fields.
+		sub, err := parseField(collection, name, &def.Fields)
+		if err != nil {
+			return Field{}, fmt.Errorf("template: %w", err)
+		}
+		field.Type = sub.Type
+		field.To = sub.To
+		field.Template = true
+		return field, nil
+	}
+
+	switch def.Type {
+	case TypeRelation, TypeRelationList:
+		to, err := relationTo(&def.To)
+		if err != nil {
+			return Field{}, err
+		}
+		field.To = to
+	case TypeGenericRelation, TypeGenericRelationList:
+		field.To = "*"
+	}
+	return field, nil
+}
+
+// relationTo returns the collection of the attribute `to` of a relation. It
+// can be a string like `motion/category_id` or an object with the attribute
+// `collection`.
+func relationTo(node *yaml.Node) (string, error) {
+	if node.Kind == yaml.ScalarNode {
+		parts := strings.Split(node.Value, "/")
+		if len(parts) != 2 || parts[0] == "" {
+			return "", fmt.Errorf("invalid value of `to` in line %d, expected one `/`: %s", node.Line, node.Value)
+		}
+		return parts[0], nil
+	}
+
+	var to struct {
+		Collection string `yaml:"collection"`
+	}
+	if err := node.Decode(&to); err != nil {
+		return "", fmt.Errorf("decoding `to` in line %d: %w", node.Line, err)
+	}
+	if to.Collection == "" {
+		return "", fmt.Errorf("`to` in line %d has no collection", node.Line)
+	}
+	return to.Collection, nil
+}
+
+// Collections returns the names of all collections, sorted by name.
+func (m *Models) Collections() []string {
+	seen := make(map[string]bool)
+	var collections []string
+	for _, field := range m.fields {
+		if seen[field.Collection] {
+			continue
+		}
+		seen[field.Collection] = true
+		collections = append(collections, field.Collection)
+	}
+	sort.Strings(collections)
+	return collections
+}
+
+// Field returns the field of a collection. For template fields, the name of a
+// replaced field can be used, for example `group_$1_ids`.
+func (m *Models) Field(collection, name string) (Field, bool) {
+	field, ok := m.fields[collection+"/"+TemplateName(name)]
+	return field, ok
+}
+
+// RelationType returns the type of a relation field and the collection it
+// points to. For generic relations, the collection is `*`. ok is false, if
+// the field does not exist or is not a relation.
+//
+// It can be used as keysbuilder.Schema.
+func (m *Models) RelationType(collection, name string) (fieldType string, to string, ok bool) {
+	field, exists := m.Field(collection, name)
+	if !exists || field.To == "" {
+		return "", "", false
+	}
+	return field.Type, field.To, true
+}
+
+// RelationLists returns all relation-list and generic-relation-list fields
+// as a map from `collection/field` to the collection they point to. Generic
+// relation-lists point to `*`. Template fields are given by their template
+// name.
+//
+// This is the same format as restrict.RelationLists.
+func (m *Models) RelationLists() map[string]string {
+	relations := make(map[string]string)
+	for key, field := range m.fields {
+		if field.Type == TypeRelationList || field.Type == TypeGenericRelationList {
+			relations[key] = field.To
+		}
+	}
+	return relations
+}
+
+// Relations returns all relation and relation-list fields like
+// RelationLists. Generic relations are not included.
+func (m *Models) Relations() map[string]string {
+	relations := make(map[string]string)
+	for key, field := range m.fields {
+		if field.Type == TypeRelation || field.Type == TypeRelationList {
+			relations[key] = field.To
+		}
+	}
+	return relations
+}
+
+// RestrictionModes returns the restriction modes of all fields as a map from
+// `collection/field` to the mode. Fields without mode are not included.
+//
+// It can be used for restrict.NewAnonymous.
+func (m *Models) RestrictionModes() map[string]string {
+	modes := make(map[string]string)
+	for key, field := range m.fields {
+		if field.RestrictionMode != "" {
+			modes[key] = field.RestrictionMode
+		}
+	}
+	return modes
+}
+
+// TemplateName returns the name of the template of a replaced template
+// field, for example `group_$_ids` for `group_$1_ids` or `structure_level_$`
+// for `structure_level_$1`. Other names are returned unchanged.
+func TemplateName(name string) string {
+	idx := strings.IndexByte(name, '$')
+	if idx == -1 {
+		return name
+	}
+
+	end := idx + 1
+	for end < len(name) && name[end] != '_' {
+		end++
+	}
+	return name[:idx+1] + name[end:]
+}
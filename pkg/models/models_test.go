@@ -0,0 +1,121 @@
+package models_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const yml = `---
+motion:
+  id: number
+  title:
+    type: string
+    restriction_mode: A
+  category_id:
+    type: relation
+    to: motion_category/motion_ids
+    restriction_mode: A
+  tag_ids:
+    type: relation-list
+    to:
+      collection: tag
+      field: tagged_ids
+  attachment_ids:
+    type: generic-relation-list
+    to:
+      collections:
+        - mediafile
+      field: attachment_ids
+motion_category:
+  id: number
+  motion_ids:
+    type: relation-list
+    to: motion/category_id
+tag:
+  id: number
+user:
+  group_$_ids:
+    type: template
+    replacement: meeting_id
+    fields:
+      type: relation-list
+      to: group/user_ids
+`
+
+func TestModels(t *testing.T) {
+	m, err := models.Parse(strings.NewReader(yml))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"motion", "motion_category", "tag", "user"}, m.Collections())
+
+	assert.Equal(t, map[string]string{
+		"motion/tag_ids":             "tag",
+		"motion/attachment_ids":      "*",
+		"motion_category/motion_ids": "motion",
+		"user/group_$_ids":           "group",
+	}, m.RelationLists())
+
+	assert.Equal(t, map[string]string{
+		"motion/category_id":         "motion_category",
+		"motion/tag_ids":             "tag",
+		"motion_category/motion_ids": "motion",
+		"user/group_$_ids":           "group",
+	}, m.Relations())
+
+	assert.Equal(t, map[string]string{
+		"motion/title":       "A",
+		"motion/category_id": "A",
+	}, m.RestrictionModes())
+
+	field, ok := m.Field("user", "group_$5_ids")
+	require.True(t, ok)
+	assert.Equal(t, models.Field{
+		Collection: "user",
+		Name:       "group_$_ids",
+		Type:       models.TypeRelationList,
+		To:         "group",
+		Template:   true,
+	}, field)
+
+	_, ok = m.Field("motion", "unknown")
+	assert.False(t, ok)
+
+	fieldType, to, ok := m.RelationType("motion", "category_id")
+	assert.True(t, ok)
+	assert.Equal(t, models.TypeRelation, fieldType)
+	assert.Equal(t, "motion_category", to)
+
+	_, _, ok = m.RelationType("motion", "title")
+	assert.False(t, ok)
+}
+
+func TestModelsInvalidTo(t *testing.T) {
+	_, err := models.Parse(strings.NewReader(`
+motion:
+  category_id:
+    type: relation
+    to: motion_category
+`))
+	assert.Error(t, err)
+}
+
+func TestTemplateName(t *testing.T) {
+	for _, tt := range []struct {
+		field  string
+		expect string
+	}{
+		{"title", "title"},
+		{"group_$_ids", "group_$_ids"},
+		{"group_$1_ids", "group_$_ids"},
+		{"structure_level_$", "structure_level_$"},
+		{"structure_level_$12", "structure_level_$"},
+	} {
+		if got := models.TemplateName(tt.field); got != tt.expect {
+			t.Errorf("TemplateName(%s) = %s, expected %s", tt.field, got, tt.expect)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+// Package nats receives datastore updates and logout events from a NATS
+// JetStream server.
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	natsgo "github.com/nats-io/nats.go"
+)
+
+const (
+	// fieldChangedSubject is the subject of the datastore updates. The data
+	// of a message is a json object from fqfields to their values.
+	fieldChangedSubject = "ModifiedFields"
+
+	// logoutSubject is the subject of the logout events. The data of a
+	// message is the session id.
+	logoutSubject = "logout"
+
+	// voteCountSubject is the subject of the vote counts of the vote service.
+	// The data of a message is a json object from poll ids to the number of
+	// votes.
+	voteCountSubject = "vote_count"
+
+	// lastLogoutDuration decides how many old logout messages are received.
+	lastLogoutDuration = 15 * time.Minute
+
+	// pollTimeout is the time after that a subscription checks, if the
+	// service is closing.
+	pollTimeout = time.Second
+)
+
+// provenanceHeaders are the message headers, that describe a datastore update.
+var provenanceHeaders = []string{"user_id", "action_name", "position"}
+
+// subscription is a subscription to one subject.
+type subscription interface {
+	NextMsg(timeout time.Duration) (*natsgo.Msg, error)
+}
+
+// NATS holds the state of the NATS receiver.
+//
+// Has to be created with nats.New().
+type NATS struct {
+	conn    *natsgo.Conn
+	updates subscription
+	logouts subscription
+	counts  subscription
+	ack     func(*natsgo.Msg) error
+	reject  func(*natsgo.Msg) error
+
+	unacked        *natsgo.Msg
+	lastProvenance map[string]string
+}
+
+// New connects to a NATS server and subscribes to the datastore updates and
+// logout events.
+//
+// If durable is not empty, the datastore updates are received with a durable
+// consumer of this name. Each update is acknowledged after it was processed.
+// After a restart, updates that were not acknowledged are received again.
+// Each instance of the autoupdate service needs its own durable name.
+//
+// Updates, that can not be decoded, are rejected, so the server does not send
+// them again. The server publishes an advisory for each rejected message, that
+// can be used as dead letter queue.
+func New(url, durable string) (*NATS, error) {
+	conn, err := natsgo.Connect(url)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to nats: %w", err)
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("creating jetstream context: %w", err)
+	}
+
+	updateOptions := []natsgo.SubOpt{natsgo.DeliverNew(), natsgo.ManualAck()}
+	if durable != "" {
+		updateOptions = append(updateOptions, natsgo.Durable(durable))
+	}
+
+	updates, err := js.SubscribeSync(fieldChangedSubject, updateOptions...)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("subscribing to %s: %w", fieldChangedSubject, err)
+	}
+
+	logouts, err := js.SubscribeSync(logoutSubject, natsgo.StartTime(time.Now().Add(-lastLogoutDuration)))
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("subscribing to %s: %w", logoutSubject, err)
+	}
+
+	// The vote counts are only relevant while a poll is open. Each message
+	// contains the full count, so they are not read from a stream.
+	counts, err := conn.SubscribeSync(voteCountSubject)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("subscribing to %s: %w", voteCountSubject, err)
+	}
+
+	return &NATS{
+		conn:    conn,
+		updates: updates,
+		logouts: logouts,
+		counts:  counts,
+		ack:     func(msg *natsgo.Msg) error { return msg.Ack() },
+		reject:  func(msg *natsgo.Msg) error { return msg.Term() },
+	}, nil
+}
+
+// Update is a blocking function that returns, when there is new data.
+//
+// The returned data is acknowledged on the next call of Update.
+func (n *NATS) Update(closing <-chan struct{}) (map[string]json.RawMessage, error) {
+	if n.unacked != nil {
+		if err := n.ack(n.unacked); err != nil {
+			return nil, fmt.Errorf("acknowledge message: %w", err)
+		}
+		n.unacked = nil
+	}
+
+	msg, err := next(closing, n.updates)
+	if err != nil {
+		return nil, fmt.Errorf("receiving update: %w", err)
+	}
+
+	data, err := decodeUpdate(msg)
+	if err != nil {
+		if rejectErr := n.reject(msg); rejectErr != nil {
+			return nil, fmt.Errorf("rejecting invalid update: %v: %w", err, rejectErr)
+		}
+		return nil, fmt.Errorf("rejected invalid update: %w", err)
+	}
+
+	var provenance map[string]string
+	for _, header := range provenanceHeaders {
+		value := msg.Header.Get(header)
+		if value == "" {
+			continue
+		}
+
+		if provenance == nil {
+			provenance = make(map[string]string)
+		}
+		provenance[header] = value
+	}
+
+	n.lastProvenance = provenance
+	n.unacked = msg
+	return data, nil
+}
+
+// decodeUpdate returns the data of an update message.
+func decodeUpdate(msg *natsgo.Msg) (map[string]json.RawMessage, error) {
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(msg.Data, &data); err != nil {
+		return nil, fmt.Errorf("decoding update: %w", err)
+	}
+
+	for key := range data {
+		if strings.Count(key, "/") != 2 {
+			return nil, fmt.Errorf("invalid key %s", key)
+		}
+	}
+	return data, nil
+}
+
+// Provenance returns the provenance of the data, that was returned by the last
+// call to Update. It contains the headers user_id, action_name and position,
+// if they were in the message.
+func (n *NATS) Provenance() map[string]string {
+	return n.lastProvenance
+}
+
+// LogoutEvent is a blocking function that returns, when a session was revoked.
+//
+// The data of a message is the revoked session id. A message with the header
+// userId revokes all sessions of the user.
+func (n *NATS) LogoutEvent(closing <-chan struct{}) ([]string, error) {
+	msg, err := next(closing, n.logouts)
+	if err != nil {
+		return nil, fmt.Errorf("receiving logout event: %w", err)
+	}
+
+	var sessionIDs []string
+	if len(msg.Data) > 0 {
+		sessionIDs = append(sessionIDs, string(msg.Data))
+	}
+
+	if uid := msg.Header.Get("userId"); uid != "" {
+		sessionIDs = append(sessionIDs, "user/"+uid)
+	}
+	return sessionIDs, nil
+}
+
+// VoteCount is a blocking function that returns, when the vote service sent
+// new vote counts. It returns the number of votes for each poll id.
+func (n *NATS) VoteCount(closing <-chan struct{}) (map[int]int, error) {
+	msg, err := next(closing, n.counts)
+	if err != nil {
+		return nil, fmt.Errorf("receiving vote count: %w", err)
+	}
+
+	var counts map[int]int
+	if err := json.Unmarshal(msg.Data, &counts); err != nil {
+		return nil, fmt.Errorf("decoding vote count: %w", err)
+	}
+	return counts, nil
+}
+
+// Ping returns an error, if the connection to the NATS server is lost.
+func (n *NATS) Ping(ctx context.Context) error {
+	if !n.conn.IsConnected() {
+		return errors.New("no connection to nats")
+	}
+	return nil
+}
+
+// Close closes the connection to the NATS server.
+func (n *NATS) Close() {
+	if n.conn != nil {
+		n.conn.Close()
+	}
+}
+
+// next blocks until the next message of the subscription. It returns a
+// closingError, if closing is closed.
+func next(closing <-chan struct{}, sub subscription) (*natsgo.Msg, error) {
+	for {
+		select {
+		case <-closing:
+			return nil, closingError{}
+		default:
+		}
+
+		msg, err := sub.NextMsg(pollTimeout)
+		if err != nil {
+			if errors.Is(err, natsgo.ErrTimeout) {
+				continue
+			}
+			return nil, err
+		}
+		return msg, nil
+	}
+}
+
+type closingError struct{}
+
+func (e closingError) Closing()      {}
+func (e closingError) Error() string { return "closing" }
@@ -0,0 +1,183 @@
+package nats
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	natsgo "github.com/nats-io/nats.go"
+)
+
+type subscriptionMock struct {
+	msgs []*natsgo.Msg
+}
+
+func (s *subscriptionMock) NextMsg(timeout time.Duration) (*natsgo.Msg, error) {
+	if len(s.msgs) == 0 {
+		return nil, natsgo.ErrTimeout
+	}
+
+	msg := s.msgs[0]
+	s.msgs = s.msgs[1:]
+	return msg, nil
+}
+
+func newTestNATS(updates, logouts []*natsgo.Msg, acked *[]*natsgo.Msg) *NATS {
+	return &NATS{
+		updates: &subscriptionMock{msgs: updates},
+		logouts: &subscriptionMock{msgs: logouts},
+		ack: func(msg *natsgo.Msg) error {
+			*acked = append(*acked, msg)
+			return nil
+		},
+		reject: func(msg *natsgo.Msg) error {
+			return errors.New("unexpected reject")
+		},
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	closing := make(chan struct{})
+	defer close(closing)
+
+	first := &natsgo.Msg{
+		Data:   []byte(`{"user/1/name": "Helga", "user/2/name": "Isolde"}`),
+		Header: natsgo.Header{"user_id": {"5"}, "position": {"17"}},
+	}
+	second := &natsgo.Msg{Data: []byte(`{"user/1/name": "Hubert"}`)}
+
+	var acked []*natsgo.Msg
+	n := newTestNATS([]*natsgo.Msg{first, second}, nil, &acked)
+
+	data, err := n.Update(closing)
+	if err != nil {
+		t.Fatalf("Update() returned an unexpected error: %v", err)
+	}
+
+	if len(data) != 2 || string(data["user/1/name"]) != `"Helga"` {
+		t.Errorf("Update() returned %v, expected two keys", data)
+	}
+
+	if p := n.Provenance(); len(p) != 2 || p["user_id"] != "5" || p["position"] != "17" {
+		t.Errorf("Provenance() returned %v, expected user_id and position", p)
+	}
+
+	if len(acked) != 0 {
+		t.Errorf("Message was acknowledged before it was processed")
+	}
+
+	if _, err := n.Update(closing); err != nil {
+		t.Fatalf("second Update() returned an unexpected error: %v", err)
+	}
+
+	if len(acked) != 1 || acked[0] != first {
+		t.Errorf("Second Update() acknowledged %v, expected the first message", acked)
+	}
+
+	if p := n.Provenance(); p != nil {
+		t.Errorf("Provenance() returned %v, expected nil", p)
+	}
+}
+
+func TestUpdateInvalidKey(t *testing.T) {
+	closing := make(chan struct{})
+	defer close(closing)
+
+	invalid := &natsgo.Msg{Data: []byte(`{"user/1": "Helga"}`)}
+	valid := &natsgo.Msg{Data: []byte(`{"user/1/name": "Helga"}`)}
+
+	var acked []*natsgo.Msg
+	n := newTestNATS([]*natsgo.Msg{invalid, valid}, nil, &acked)
+
+	var rejected []*natsgo.Msg
+	n.reject = func(msg *natsgo.Msg) error {
+		rejected = append(rejected, msg)
+		return nil
+	}
+
+	if _, err := n.Update(closing); err == nil {
+		t.Errorf("Update() did not return an error, expected one")
+	}
+
+	if len(rejected) != 1 || rejected[0] != invalid {
+		t.Errorf("Update() rejected %v, expected the invalid message", rejected)
+	}
+
+	data, err := n.Update(closing)
+	if err != nil {
+		t.Fatalf("Update() after the invalid message returned an error: %v", err)
+	}
+
+	if string(data["user/1/name"]) != `"Helga"` {
+		t.Errorf("Update() returned %v, expected the valid message", data)
+	}
+
+	if len(acked) != 0 {
+		t.Errorf("The invalid message was acknowledged instead of rejected")
+	}
+}
+
+func TestUpdateClosing(t *testing.T) {
+	closing := make(chan struct{})
+	close(closing)
+
+	var acked []*natsgo.Msg
+	n := newTestNATS(nil, nil, &acked)
+
+	_, err := n.Update(closing)
+
+	var errClosing interface{ Closing() }
+	if !errors.As(err, &errClosing) {
+		t.Errorf("Update() returned %v, expected a closing error", err)
+	}
+}
+
+func TestLogoutEvent(t *testing.T) {
+	closing := make(chan struct{})
+	defer close(closing)
+
+	var acked []*natsgo.Msg
+	n := newTestNATS(nil, []*natsgo.Msg{{Data: []byte("session1")}}, &acked)
+
+	sessionIDs, err := n.LogoutEvent(closing)
+	if err != nil {
+		t.Fatalf("LogoutEvent() returned an unexpected error: %v", err)
+	}
+
+	if len(sessionIDs) != 1 || sessionIDs[0] != "session1" {
+		t.Errorf("LogoutEvent() returned %v, expected [session1]", sessionIDs)
+	}
+}
+
+func TestLogoutEventUser(t *testing.T) {
+	closing := make(chan struct{})
+	defer close(closing)
+
+	var acked []*natsgo.Msg
+	n := newTestNATS(nil, []*natsgo.Msg{{Header: natsgo.Header{"userId": {"5"}}}}, &acked)
+
+	sessionIDs, err := n.LogoutEvent(closing)
+	if err != nil {
+		t.Fatalf("LogoutEvent() returned an unexpected error: %v", err)
+	}
+
+	if len(sessionIDs) != 1 || sessionIDs[0] != "user/5" {
+		t.Errorf("LogoutEvent() returned %v, expected [user/5]", sessionIDs)
+	}
+}
+
+func TestVoteCount(t *testing.T) {
+	closing := make(chan struct{})
+	defer close(closing)
+
+	n := &NATS{counts: &subscriptionMock{msgs: []*natsgo.Msg{{Data: []byte(`{"1":5,"2":3}`)}}}}
+
+	counts, err := n.VoteCount(closing)
+	if err != nil {
+		t.Fatalf("VoteCount() returned an unexpected error: %v", err)
+	}
+
+	if len(counts) != 2 || counts[1] != 5 || counts[2] != 3 {
+		t.Errorf("VoteCount() returned %v, expected map[1:5 2:3]", counts)
+	}
+}
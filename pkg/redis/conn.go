@@ -2,6 +2,7 @@ package redis
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
@@ -44,6 +45,36 @@ func (s *Pool) XREAD(count, stream, id string) (interface{}, error) {
 	return conn.Do("XREAD", "COUNT", count, "BLOCK", "0", "STREAMS", stream, id)
 }
 
+// XGROUPCREATE creates a consumer group for a stream. The stream is created,
+// if it does not exist. It is not an error, if the group already exists.
+func (s *Pool) XGROUPCREATE(stream, group, id string) error {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("XGROUP", "CREATE", stream, group, id, "MKSTREAM")
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+// XREADGROUP reads messages from one stream as a consumer of a group.
+func (s *Pool) XREADGROUP(group, consumer, count, stream, id string) (interface{}, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+	return conn.Do("XREADGROUP", "GROUP", group, consumer, "COUNT", count, "BLOCK", "0", "STREAMS", stream, id)
+}
+
+// XACK acknowledges messages of a consumer group.
+func (s *Pool) XACK(stream, group string, ids ...string) error {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	args := redis.Args{}.Add(stream, group).AddFlat(ids)
+	_, err := conn.Do("XACK", args...)
+	return err
+}
+
 // BlockingConn is a fake implementation of the redis connection. It does not
 // create a connection but blocks forever.
 type BlockingConn struct{}
@@ -0,0 +1,80 @@
+package redis
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// consumerName is the name of the autoupdate service in its consumer group.
+const consumerName = "autoupdate"
+
+// updateGroup reads the autoupdate stream as a member of the consumer group
+// r.Group.
+//
+// The messages, that were returned by the last call, are acknowledged, when
+// updateGroup is called again. At this time, the data was processed. On
+// start, the messages, that were received but not acknowledged before a
+// crash, are read again.
+func (r *Redis) updateGroup(closing <-chan struct{}) (map[string]json.RawMessage, error) {
+	conn, ok := r.Conn.(GroupConnection)
+	if !ok {
+		return nil, fmt.Errorf("redis connection does not support consumer groups")
+	}
+
+	if !r.groupCreated {
+		if err := conn.XGROUPCREATE(fieldChangedTopic, r.Group, "$"); err != nil {
+			return nil, fmt.Errorf("creating consumer group %s: %w", r.Group, err)
+		}
+		r.groupCreated = true
+	}
+
+	if len(r.unacked) > 0 {
+		if err := conn.XACK(fieldChangedTopic, r.Group, r.unacked...); err != nil {
+			return nil, fmt.Errorf("acknowledge messages: %w", err)
+		}
+		r.unacked = nil
+	}
+
+	var data map[string]json.RawMessage
+	var provenance map[string]string
+	var positions []int
+	err := closingFunc(closing, func() error {
+		for {
+			// The id 0 returns the pending messages of this consumer. The id >
+			// waits for new messages.
+			id := ">"
+			if !r.recovered {
+				id = "0"
+			}
+
+			reply, err := conn.XREADGROUP(r.Group, consumerName, maxMessages, fieldChangedTopic, id)
+			_, d, p, err := autoupdateStream(reply, err)
+			if err != nil {
+				return err
+			}
+
+			ids := streamIDs(reply)
+			if len(ids) == 0 && !r.recovered {
+				r.recovered = true
+				continue
+			}
+
+			r.unacked = ids
+			data = d
+			provenance = p
+			positions = streamPositions(reply)
+			return nil
+		}
+	})
+	r.lastProvenance = provenance
+	r.lastPositions = positions
+
+	if err != nil {
+		if err == errNil {
+			// No new data
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get xreadgroup data from redis: %w", err)
+	}
+	return data, nil
+}
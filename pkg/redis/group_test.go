@@ -0,0 +1,119 @@
+package redis_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/redis"
+)
+
+// groupConnMock simulates a stream with one consumer group.
+type groupConnMock struct {
+	mockConn
+	entries   [][]interface{}
+	delivered int
+	pending   []string
+	acked     []string
+}
+
+func (c *groupConnMock) add(id string, kv ...interface{}) {
+	c.entries = append(c.entries, []interface{}{id, kv})
+}
+
+func (c *groupConnMock) XGROUPCREATE(stream, group, id string) error {
+	return nil
+}
+
+func (c *groupConnMock) XREADGROUP(group, consumer, count, stream, id string) (interface{}, error) {
+	var elements []interface{}
+	if id == "0" {
+		for _, pendingID := range c.pending {
+			for _, e := range c.entries {
+				if e[0] == pendingID {
+					elements = append(elements, e)
+				}
+			}
+		}
+		return []interface{}{[]interface{}{stream, elements}}, nil
+	}
+
+	if c.delivered == len(c.entries) {
+		return nil, nil
+	}
+
+	for _, e := range c.entries[c.delivered:] {
+		elements = append(elements, e)
+		c.pending = append(c.pending, e[0].(string))
+	}
+	c.delivered = len(c.entries)
+	return []interface{}{[]interface{}{stream, elements}}, nil
+}
+
+func (c *groupConnMock) XACK(stream, group string, ids ...string) error {
+	c.acked = append(c.acked, ids...)
+	for _, id := range ids {
+		for i, pendingID := range c.pending {
+			if pendingID == id {
+				c.pending = append(c.pending[:i], c.pending[i+1:]...)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func TestUpdateGroupRecovery(t *testing.T) {
+	closing := make(chan struct{})
+	defer close(closing)
+
+	conn := new(groupConnMock)
+	conn.add("1-0", "user/1/name", "Helga")
+
+	r := &redis.Redis{Conn: conn, Group: "autoupdate1"}
+	data, err := r.Update(closing)
+	if err != nil {
+		t.Fatalf("Update() returned an unexpected error %v", err)
+	}
+
+	expect := map[string]json.RawMessage{"user/1/name": []byte("Helga")}
+	if !cmpMap(data, expect) {
+		t.Errorf("Update() returned %v, expected %v", data, expect)
+	}
+
+	// Simulate a crash before the data was processed.
+	conn.add("2-0", "user/1/name", "Hubert")
+	r = &redis.Redis{Conn: conn, Group: "autoupdate1"}
+
+	data, err = r.Update(closing)
+	if err != nil {
+		t.Fatalf("Update() after restart returned an unexpected error %v", err)
+	}
+
+	if !cmpMap(data, expect) {
+		t.Errorf("Update() after restart returned %v, expected the pending data %v", data, expect)
+	}
+
+	data, err = r.Update(closing)
+	if err != nil {
+		t.Fatalf("third Update() returned an unexpected error %v", err)
+	}
+
+	expect = map[string]json.RawMessage{"user/1/name": []byte("Hubert")}
+	if !cmpMap(data, expect) {
+		t.Errorf("third Update() returned %v, expected %v", data, expect)
+	}
+
+	if !cmpSlice(conn.acked, []string{"1-0"}) {
+		t.Errorf("Acknowledged %v, expected [1-0]", conn.acked)
+	}
+}
+
+func TestUpdateGroupNoGroupConnection(t *testing.T) {
+	closing := make(chan struct{})
+	defer close(closing)
+
+	r := &redis.Redis{Conn: mockConn{}, Group: "autoupdate1"}
+	if _, err := r.Update(closing); err == nil {
+		t.Errorf("Update() did not return an error, expected one")
+	}
+}
@@ -4,3 +4,19 @@ package redis
 type Connection interface {
 	XREAD(count, stream, lastID string) (interface{}, error)
 }
+
+// GroupConnection is a connection, that supports consumer groups.
+type GroupConnection interface {
+	Connection
+
+	// XGROUPCREATE creates a consumer group. It does not return an error, if
+	// the group already exists.
+	XGROUPCREATE(stream, group, id string) error
+
+	// XREADGROUP reads messages from a stream as a member of a consumer
+	// group.
+	XREADGROUP(group, consumer, count, stream, id string) (interface{}, error)
+
+	// XACK acknowledges messages of a consumer group.
+	XACK(stream, group string, ids ...string) error
+}
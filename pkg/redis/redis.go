@@ -3,6 +3,7 @@
 package redis
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
@@ -21,32 +22,64 @@ const (
 
 	// lastLogoutDuration decides how many old logout messages are received.
 	lastLogoutDuration = 15 * time.Minute
+
+	// voteCountTopic is the redis key name of the stream with the vote
+	// counts of the vote service.
+	voteCountTopic = "vote_count"
 )
 
 // Redis holds the state of the redis receiver.
 type Redis struct {
-	Conn             Connection
+	Conn Connection
+
+	// Group is the name of a consumer group. If it is set, the autoupdate
+	// stream is read with the consumer group and Conn has to implement
+	// GroupConnection. Each instance of the autoupdate service needs its own
+	// group.
+	Group string
+
 	lastAutoupdateID string
 	lastLogoutID     string
+	lastVoteCountID  string
+	lastProvenance   map[string]string
+	lastPositions    []int
+
+	groupCreated bool
+	recovered    bool
+	unacked      []string
 }
 
 // Update is a blocking function that returns, when there is new data.
+//
+// If a consumer group is used, the returned data is acknowledged on the next
+// call of Update.
 func (r *Redis) Update(closing <-chan struct{}) (map[string]json.RawMessage, error) {
+	if r.Group != "" {
+		return r.updateGroup(closing)
+	}
+
 	id := r.lastAutoupdateID
 	if id == "" {
 		id = "$"
 	}
 
 	var data map[string]json.RawMessage
+	var provenance map[string]string
+	var positions []int
 	err := closingFunc(closing, func() error {
-		newID, d, err := autoupdateStream(r.Conn.XREAD(maxMessages, fieldChangedTopic, id))
+		reply, err := r.Conn.XREAD(maxMessages, fieldChangedTopic, id)
+		newID, d, p, err := autoupdateStream(reply, err)
 		if err != nil {
 			return err
 		}
 		id = newID
 		data = d
+		provenance = p
+		positions = streamPositions(reply)
 		return nil
 	})
+	r.lastProvenance = provenance
+	r.lastPositions = positions
 
 	if err != nil {
 		if err == errNil {
@@ -61,6 +94,22 @@ func (r *Redis) Update(closing <-chan struct{}) (map[string]json.RawMessage, err
 	return data, nil
 }
 
+// Provenance returns the provenance of the data, that was returned by the last
+// call to Update. It contains the fields user_id, action_name and position, if
+// they were in the stream.
+//
+// If Update returned the data of more then one message, the provenance is
+// from the last message, that had a provenance.
+func (r *Redis) Provenance() map[string]string {
+	return r.lastProvenance
+}
+
+// Positions returns the datastore positions of all messages, that were
+// returned by the last call to Update.
+func (r *Redis) Positions() []int {
+	return r.lastPositions
+}
+
 // LogoutEvent is a blocking function that returns, when a session was revoked.
 func (r *Redis) LogoutEvent(closing <-chan struct{}) ([]string, error) {
 	id := r.lastLogoutID
@@ -93,6 +142,65 @@ func (r *Redis) LogoutEvent(closing <-chan struct{}) ([]string, error) {
 	return sessionIDs, nil
 }
 
+// VoteCount is a blocking function that returns, when the vote service sent
+// new vote counts. It returns the number of votes for each poll id.
+//
+// Each message of the stream has the poll ids as keys and the counts as
+// values. Only messages, that are sent after the first call, are received.
+func (r *Redis) VoteCount(closing <-chan struct{}) (map[int]int, error) {
+	id := r.lastVoteCountID
+	if id == "" {
+		id = "$"
+	}
+
+	var counts map[int]int
+	err := closingFunc(closing, func() error {
+		newID, c, err := voteCountStream(r.Conn.XREAD(maxMessages, voteCountTopic, id))
+		if err != nil {
+			return err
+		}
+		id = newID
+		counts = c
+		return nil
+	})
+
+	if err != nil {
+		if err == errNil {
+			// No new data
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get xread data from redis: %w", err)
+	}
+	if id != "" {
+		r.lastVoteCountID = id
+	}
+	return counts, nil
+}
+
+// Ping checks the connection to redis. It does nothing, if the connection
+// does not support a test.
+//
+// The redis client does not support a context. So Ping returns, when ctx is
+// done, and leaves the test behind.
+func (r *Redis) Ping(ctx context.Context) error {
+	tester, ok := r.Conn.(interface{ TestConn() error })
+	if !ok {
+		return nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- tester.TestConn()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for redis: %w", ctx.Err())
+	}
+}
+
 // closingFunc calls f in a separat goroutine. If closing is closed, the
 // function returned, leaving the goroutine behind.
 //
@@ -1,9 +1,11 @@
 package redis_test
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/OpenSlides/openslides-autoupdate-service/pkg/redis"
 )
@@ -64,3 +66,28 @@ func TestRedisError(t *testing.T) {
 		t.Errorf("Update() returned %v, expected no keys.", keys)
 	}
 }
+
+type blockingConn struct {
+	mockConn
+	unblock chan struct{}
+}
+
+func (c blockingConn) TestConn() error {
+	<-c.unblock
+	return nil
+}
+
+func TestPingContext(t *testing.T) {
+	conn := blockingConn{unblock: make(chan struct{})}
+	defer close(conn.unblock)
+
+	r := &redis.Redis{Conn: conn}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	err := r.Ping(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Ping returned %v, expected %v", err, context.DeadlineExceeded)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -48,6 +49,11 @@ func stream(reply interface{}, err error) (string, map[string][]byte, error) {
 		if !ok {
 			return "", nil, fmt.Errorf("invalid input. Stream ID has to be a string, got %T", element[0])
 		}
+		if element[1] == nil {
+			// Pending entries, that were deleted from the stream, have no
+			// values.
+			continue
+		}
 		kv, ok := element[1].([]interface{})
 		if !ok {
 			return "", nil, fmt.Errorf("invalid input. Key values has to be a list of strings, got %T", element[1])
@@ -72,25 +78,99 @@ func stream(reply interface{}, err error) (string, map[string][]byte, error) {
 	return id, retData, nil
 }
 
+// streamElements returns the elements of a stream reply as two-tuples of id
+// and key values. It expects, that the reply was already validated by
+// stream().
+func streamElements(reply interface{}) [][]interface{} {
+	streams, _ := reply.([]interface{})
+	if len(streams) == 0 {
+		return nil
+	}
+
+	stream1, _ := streams[0].([]interface{})
+	if len(stream1) != 2 {
+		return nil
+	}
+
+	data, _ := stream1[1].([]interface{})
+	elements := make([][]interface{}, 0, len(data))
+	for _, v := range data {
+		element, _ := v.([]interface{})
+		if len(element) != 2 {
+			continue
+		}
+		elements = append(elements, element)
+	}
+	return elements
+}
+
+// streamIDs returns the ids of all elements in a stream reply.
+func streamIDs(reply interface{}) []string {
+	var ids []string
+	for _, element := range streamElements(reply) {
+		if id, ok := tostr(element[0]); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// streamPositions returns the datastore positions of all elements in a stream
+// reply. Elements without a position are skipped.
+func streamPositions(reply interface{}) []int {
+	var positions []int
+	for _, element := range streamElements(reply) {
+		kv, _ := element[1].([]interface{})
+		for i := 0; i < len(kv)-1; i += 2 {
+			if key, _ := tostr(kv[i]); key != "position" {
+				continue
+			}
+
+			value, _ := tostr(kv[i+1])
+			if position, err := strconv.Atoi(value); err == nil {
+				positions = append(positions, position)
+			}
+		}
+	}
+	return positions
+}
+
+// provenanceFields are the fields in the autoupdate stream, that are not
+// fqfields but describe the change.
+var provenanceFields = map[string]bool{
+	"user_id":     true,
+	"action_name": true,
+	"position":    true,
+}
+
 // autoupdateStream parses a redis autoupdateStream object to an autoupdate.KeyChanges object.
 //
-// The first return value is the redis autoupdateStream id. The second one is the data and
-// the third is an error.
-func autoupdateStream(reply interface{}, err error) (string, map[string]json.RawMessage, error) {
+// The first return value is the redis autoupdateStream id. The second one is
+// the data, the third the provenance of the change and the fourth is an error.
+func autoupdateStream(reply interface{}, err error) (string, map[string]json.RawMessage, map[string]string, error) {
 	id, data, err := stream(reply, err)
 	if err != nil {
-		return "", nil, err
+		return "", nil, nil, err
 	}
 
 	converted := make(map[string]json.RawMessage, len(data))
+	var provenance map[string]string
 	for key, value := range data {
+		if provenanceFields[key] {
+			if provenance == nil {
+				provenance = make(map[string]string)
+			}
+			provenance[key] = string(value)
+			continue
+		}
+
 		if strings.Count(key, "/") != 2 {
-			return "", nil, fmt.Errorf("invalid key %s", key)
+			return "", nil, nil, fmt.Errorf("invalid key %s", key)
 		}
 
 		converted[key] = json.RawMessage(value)
 	}
-	return id, converted, nil
+	return id, converted, provenance, nil
 }
 
 // logoutStream parses a redis logoutStream object to an list of sessionsIDs.
@@ -105,15 +185,41 @@ func logoutStream(reply interface{}, err error) (string, []string, error) {
 
 	var sessionIDs []string
 	for key, value := range data {
-		if key != "sessionId" {
-			continue
+		switch key {
+		case "sessionId":
+			sessionIDs = append(sessionIDs, string(value))
+		case "userId":
+			// All sessions of the user are revoked.
+			sessionIDs = append(sessionIDs, "user/"+string(value))
 		}
-
-		sessionIDs = append(sessionIDs, string(value))
 	}
 	return id, sessionIDs, nil
 }
 
+// voteCountStream parses a redis reply with vote counts. The keys of the
+// messages are poll ids and the values the number of votes.
+func voteCountStream(reply interface{}, err error) (string, map[int]int, error) {
+	id, data, err := stream(reply, err)
+	if err != nil {
+		return "", nil, err
+	}
+
+	counts := make(map[int]int, len(data))
+	for key, value := range data {
+		pollID, err := strconv.Atoi(key)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid poll id %q: %w", key, err)
+		}
+
+		count, err := strconv.Atoi(string(value))
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid vote count %q for poll %d: %w", value, pollID, err)
+		}
+		counts[pollID] = count
+	}
+	return id, counts, nil
+}
+
 // tostr converts an interface with value string or []byte to string this is an
 // helper, because the test-code generates strings but the redis code generates
 // []bytes.
@@ -29,7 +29,7 @@ func TestStream(t *testing.T) {
 		t.Fatalf("Data is invalid json: %v", err)
 	}
 
-	id, retData, err := autoupdateStream(data, nil)
+	id, retData, _, err := autoupdateStream(data, nil)
 	if err != nil {
 		t.Errorf("Returned unexpected error %v", err)
 	}
@@ -46,6 +46,75 @@ func TestStream(t *testing.T) {
 	}
 }
 
+func TestStreamProvenance(t *testing.T) {
+	var data interface{}
+	err := json.Unmarshal([]byte(`
+	[
+		[
+			"stream1",
+			[
+				[
+					"12345-0",
+					["user/1/name", "Helga", "user_id", "5", "action_name", "user.update", "position", "42"]
+				]
+			]
+		]
+	]`), &data)
+	if err != nil {
+		t.Fatalf("Data is invalid json: %v", err)
+	}
+
+	_, retData, provenance, err := autoupdateStream(data, nil)
+	if err != nil {
+		t.Errorf("Returned unexpected error %v", err)
+	}
+
+	if len(retData) != 1 {
+		t.Errorf("Got %v, expected only user/1/name", retData)
+	}
+
+	expect := map[string]string{"user_id": "5", "action_name": "user.update", "position": "42"}
+	for k, v := range expect {
+		if provenance[k] != v {
+			t.Errorf("provenance[%s] = `%s`, expected `%s`", k, provenance[k], v)
+		}
+	}
+}
+
+func TestVoteCountStream(t *testing.T) {
+	var data interface{}
+	err := json.Unmarshal([]byte(`
+	[
+		[
+			"vote_count",
+			[
+				["12345-0", ["1", "5", "2", "3"]],
+				["12346-0", ["1", "6"]]
+			]
+		]
+	]`), &data)
+	if err != nil {
+		t.Fatalf("Data is invalid json: %v", err)
+	}
+
+	id, counts, err := voteCountStream(data, nil)
+	if err != nil {
+		t.Fatalf("Returned unexpected error %v", err)
+	}
+
+	if id != "12346-0" {
+		t.Errorf("Expected id to be 12346-0, got: %v", id)
+	}
+
+	if len(counts) != 2 || counts[1] != 6 || counts[2] != 3 {
+		t.Errorf("Got %v, expected map[1:6 2:3]", counts)
+	}
+
+	if _, _, err := voteCountStream([]interface{}{[]interface{}{"vote_count", []interface{}{[]interface{}{"1-0", []interface{}{"poll/1", "5"}}}}}, nil); err == nil {
+		t.Errorf("Expected an error for an invalid poll id")
+	}
+}
+
 func TestStreamInvalidData(t *testing.T) {
 	td := []struct {
 		name string
@@ -76,7 +145,7 @@ func TestStreamInvalidData(t *testing.T) {
 				t.Fatalf("Data is invalid json: %v", err)
 			}
 
-			_, _, err = autoupdateStream(data, nil)
+			_, _, _, err = autoupdateStream(data, nil)
 			if err == nil {
 				t.Fatalf("Expected an error, got none")
 			}
@@ -99,3 +168,26 @@ func cmpMap(one, two map[string]json.RawMessage) bool {
 	}
 	return true
 }
+
+func TestStreamPositions(t *testing.T) {
+	var data interface{}
+	err := json.Unmarshal([]byte(`
+	[
+		[
+			"stream1",
+			[
+				["12345-0", ["user/1/name", "Helga", "position", "7"]],
+				["12346-0", ["user/1/name", "Hubert"]],
+				["12347-0", ["position", "8", "user/3/name", "Igor"]]
+			]
+		]
+	]`), &data)
+	if err != nil {
+		t.Fatalf("Data is invalid json: %v", err)
+	}
+
+	got := streamPositions(data)
+	if len(got) != 2 || got[0] != 7 || got[1] != 8 {
+		t.Errorf("streamPositions() returned %v, expected [7 8]", got)
+	}
+}
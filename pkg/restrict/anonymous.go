@@ -0,0 +1,556 @@
+package restrict
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/models"
+)
+
+// AnonymousPermissions is a map from a collection to the permission, the
+// anonymous user needs to see the fields of that collection with the
+// restriction mode A.
+//
+// The anonymous user can see all objects of a meeting, that have an empty
+// permission, as long as the meeting has enabled anonymous users. Collections
+// that are not in this map can not be seen by the anonymous user.
+var AnonymousPermissions = map[string]string{
+	"agenda_item":                  "agenda_item.can_see",
+	"assignment":                   "assignment.can_see",
+	"assignment_candidate":         "assignment.can_see",
+	"group":                        "",
+	"list_of_speakers":             "list_of_speakers.can_see",
+	"mediafile":                    "mediafile.can_see",
+	"meeting":                      "",
+	"meeting_user":                 "user.can_see",
+	"motion":                       "motion.can_see",
+	"motion_block":                 "motion.can_see",
+	"motion_category":              "motion.can_see",
+	"motion_change_recommendation": "motion.can_see",
+	"motion_state":                 "motion.can_see",
+	"motion_statute_paragraph":     "motion.can_see",
+	"motion_submitter":             "motion.can_see",
+	"motion_workflow":              "motion.can_see",
+	"projection":                   "projector.can_see",
+	"projector":                    "projector.can_see",
+	"projector_countdown":          "projector.can_see",
+	"projector_message":            "projector.can_see",
+	"speaker":                      "list_of_speakers.can_see",
+	"tag":                          "",
+	"topic":                        "agenda_item.can_see",
+	"user":                         "user.can_see",
+}
+
+// anonymousModePermissions is a map from a collection and a restriction mode
+// other then A (collection/mode) to the permission, that is needed to see the
+// fields of the mode. Fields with a mode that is not in this map or in
+// AnonymousPermissions can not be seen by the anonymous user.
+var anonymousModePermissions = map[string]string{
+	"agenda_item/B":  "agenda_item.can_see_internal",
+	"agenda_item/C":  "agenda_item.can_manage",
+	"meeting/B":      "meeting.can_see_frontpage",
+	"meeting/C":      "meeting.can_see_livestream",
+	"meeting/D":      "user.can_see",
+	"meeting_user/B": "user.can_see_extra_data",
+	"user/B":         "user.can_see_extra_data",
+	"user/C":         "user.can_manage",
+}
+
+// Anonymous is a Permissioner that decides the permissions for the
+// anonymous user (user id 0). All other users are checked by the underlying
+// Permissioner.
+//
+// The anonymous user can only see objects of meetings, where
+// meeting/enable_anonymous is true. In this case it gets the permissions of
+// the default group of the meeting. Like for other users, some objects need
+// more than the permission of the collection, for example motions in a state
+// with restrictions or mediafiles with access groups.
+type Anonymous struct {
+	permer Permissioner
+	ds     datastore.Getter
+	modes  map[string]string
+}
+
+// NewAnonymous initializes an Anonymous Permissioner.
+//
+// modes is a map from a field (collection/field) to its restriction mode like
+// RestrictionModes or models.Models.RestrictionModes. The mode of a field
+// decides, which permission the anonymous user needs to see it.
+func NewAnonymous(permer Permissioner, ds datastore.Getter, modes map[string]string) *Anonymous {
+	return &Anonymous{
+		permer: permer,
+		ds:     ds,
+		modes:  modes,
+	}
+}
+
+// RestrictFQFields implements the Permissioner interface.
+func (a *Anonymous) RestrictFQFields(ctx context.Context, uid int, fqfields []string) (map[string]bool, error) {
+	if uid != 0 {
+		return a.permer.RestrictFQFields(ctx, uid, fqfields)
+	}
+
+	allowed := make(map[string]bool, len(fqfields))
+
+	// The meetings of each fqid.
+	meetingsOf := make(map[string][]string)
+
+	// The required permission of each fqfield.
+	required := make(map[string]string, len(fqfields))
+	for _, fqfield := range fqfields {
+		perm, ok := a.anonymousPermission(fqfield)
+		if !ok {
+			continue
+		}
+
+		required[fqfield] = perm
+		fqid, _ := splitFQField(fqfield)
+		meetingsOf[fqid] = nil
+	}
+
+	if err := a.loadMeetings(ctx, meetingsOf); err != nil {
+		return nil, fmt.Errorf("loading meetings: %w", err)
+	}
+
+	groups, err := a.defaultGroups(ctx, meetingsOf)
+	if err != nil {
+		return nil, fmt.Errorf("loading default groups: %w", err)
+	}
+
+	// The meeting, that allowed at least one field of a fqid.
+	meetingOf := make(map[string]string)
+	for fqfield, perm := range required {
+		fqid, _ := splitFQField(fqfield)
+		for _, meetingID := range meetingsOf[fqid] {
+			group, ok := groups[meetingID]
+			if !ok {
+				// Anonymous is not enabled in this meeting.
+				continue
+			}
+
+			if perm == "" || hasPerm(group.perms, perm) {
+				allowed[fqfield] = true
+				meetingOf[fqid] = meetingID
+				break
+			}
+		}
+	}
+
+	hidden, err := a.hiddenObjects(ctx, meetingOf, groups)
+	if err != nil {
+		return nil, fmt.Errorf("checking objects: %w", err)
+	}
+
+	for fqfield := range allowed {
+		fqid, _ := splitFQField(fqfield)
+		if hidden[fqid] {
+			delete(allowed, fqfield)
+		}
+	}
+	return allowed, nil
+}
+
+// anonymousPermission returns the permission, the anonymous user needs to see
+// a fqfield. It is derived from the restriction mode of the field.
+//
+// ok is false, if the anonymous user can not see the field at all.
+func (a *Anonymous) anonymousPermission(fqfield string) (perm string, ok bool) {
+	parts := strings.Split(fqfield, "/")
+	if len(parts) != 3 {
+		return "", false
+	}
+	collection := parts[0]
+
+	mode, ok := a.modes[collection+"/"+models.TemplateName(parts[2])]
+	if !ok {
+		return "", false
+	}
+
+	if mode == "A" {
+		perm, ok = AnonymousPermissions[collection]
+		return perm, ok
+	}
+
+	if _, ok := AnonymousPermissions[collection]; !ok {
+		return "", false
+	}
+
+	perm, ok = anonymousModePermissions[collection+"/"+mode]
+	return perm, ok
+}
+
+// loadMeetings sets the meeting ids of each fqid in the given map.
+//
+// For meetings, this is the meeting itself. For users, this are all meetings
+// where the user is a member. For all other objects, it is the value of the
+// field meeting_id.
+func (a *Anonymous) loadMeetings(ctx context.Context, meetingsOf map[string][]string) error {
+	fqids := make([]string, 0, len(meetingsOf))
+	keys := make([]string, 0, len(meetingsOf))
+	for fqid := range meetingsOf {
+		collection, id := splitFQField(fqid)
+		switch collection {
+		case "meeting":
+			meetingsOf[fqid] = []string{id}
+			continue
+		case "user":
+			keys = append(keys, fqid+"/group_$_ids")
+		default:
+			keys = append(keys, fqid+"/meeting_id")
+		}
+		fqids = append(fqids, fqid)
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	values, err := a.ds.Get(ctx, keys...)
+	if err != nil {
+		return fmt.Errorf("getting meeting ids: %w", err)
+	}
+
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+
+		if strings.HasSuffix(keys[i], "/group_$_ids") {
+			var meetingIDs []string
+			if err := json.Unmarshal(value, &meetingIDs); err != nil {
+				return fmt.Errorf("decoding %s: %w", keys[i], err)
+			}
+			meetingsOf[fqids[i]] = meetingIDs
+			continue
+		}
+
+		var meetingID int
+		if err := json.Unmarshal(value, &meetingID); err != nil {
+			return fmt.Errorf("decoding %s: %w", keys[i], err)
+		}
+		meetingsOf[fqids[i]] = []string{fmt.Sprint(meetingID)}
+	}
+	return nil
+}
+
+// defaultGroup is the default group of a meeting, that has enabled anonymous.
+type defaultGroup struct {
+	id    int
+	perms map[string]bool
+}
+
+// defaultGroups returns the default group for each meeting in the given map.
+// Meetings that have not enabled anonymous are not in the returned map.
+func (a *Anonymous) defaultGroups(ctx context.Context, meetingsOf map[string][]string) (map[string]defaultGroup, error) {
+	var meetingIDs []string
+	seen := make(map[string]bool)
+	for _, ids := range meetingsOf {
+		for _, id := range ids {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			meetingIDs = append(meetingIDs, id)
+		}
+	}
+
+	groups := make(map[string]defaultGroup, len(meetingIDs))
+	for _, meetingID := range meetingIDs {
+		fetch := datastore.NewFetcher(a.ds)
+		enabled := fetch.Bool(ctx, "meeting/%s/enable_anonymous", meetingID)
+		if err := fetch.Error(); err != nil {
+			var errNotExist datastore.DoesNotExistError
+			if errors.As(err, &errNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("checking meeting/%s/enable_anonymous: %w", meetingID, err)
+		}
+
+		if !enabled {
+			continue
+		}
+
+		groupID := fetch.Int(ctx, "meeting/%s/default_group_id", meetingID)
+		groupPerms := fetch.Strings(ctx, "group/%d/permissions", groupID)
+		if err := fetch.Error(); err != nil {
+			var errNotExist datastore.DoesNotExistError
+			if !errors.As(err, &errNotExist) {
+				return nil, fmt.Errorf("getting default group permissions of meeting %s: %w", meetingID, err)
+			}
+		}
+
+		group := defaultGroup{id: groupID, perms: make(map[string]bool, len(groupPerms))}
+		for _, perm := range groupPerms {
+			group.perms[perm] = true
+		}
+		groups[meetingID] = group
+	}
+	return groups, nil
+}
+
+// anonymousObjectFields are the fields of the collections, where the
+// permission of the collection is not enough to see an object. The values of
+// this fields decide, if the anonymous user can see the object.
+var anonymousObjectFields = map[string][]string{
+	"agenda_item":                  {"is_hidden", "is_internal"},
+	"mediafile":                    {"is_public", "inherited_access_group_ids"},
+	"motion":                       {"state_id"},
+	"motion_change_recommendation": {"internal", "motion_id"},
+	"motion_submitter":             {"motion_id"},
+}
+
+// hiddenObjects returns the fqids, that the anonymous user can not see, even
+// when the default group has the permission of the collection.
+//
+// meetingOf is a map from a fqid to the meeting, that allowed the object.
+//
+// The rules are:
+//   - Hidden agenda items need agenda_item.can_manage and internal agenda
+//     items need agenda_item.can_see_internal.
+//   - Mediafiles need mediafile.can_manage, if they are not public and the
+//     default group is not in inherited_access_group_ids.
+//   - Motions need one of the permissions in the restrictions of their state.
+//   - Change recommendations and submitters are hidden with their motion.
+//     Internal change recommendations need motion.can_manage.
+func (a *Anonymous) hiddenObjects(ctx context.Context, meetingOf map[string]string, groups map[string]defaultGroup) (map[string]bool, error) {
+	var keys []string
+	for fqid := range meetingOf {
+		collection, _ := splitFQField(fqid)
+		for _, field := range anonymousObjectFields[collection] {
+			keys = append(keys, fqid+"/"+field)
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	values, err := a.getValues(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("getting object fields: %w", err)
+	}
+
+	// The motions, that have to be checked, with the meeting that allowed
+	// them.
+	motions := make(map[string]string)
+	for fqid, meetingID := range meetingOf {
+		collection, _ := splitFQField(fqid)
+		switch collection {
+		case "motion":
+			motions[fqid] = meetingID
+
+		case "motion_change_recommendation", "motion_submitter":
+			var motionID int
+			if err := decodeValue(values, fqid+"/motion_id", &motionID); err != nil {
+				return nil, err
+			}
+			if motionID != 0 {
+				motions[fmt.Sprintf("motion/%d", motionID)] = meetingID
+			}
+		}
+	}
+
+	hiddenMotions, err := a.hiddenMotions(ctx, motions, groups)
+	if err != nil {
+		return nil, fmt.Errorf("checking motions: %w", err)
+	}
+
+	hidden := make(map[string]bool)
+	for fqid, meetingID := range meetingOf {
+		group := groups[meetingID]
+		collection, _ := splitFQField(fqid)
+
+		switch collection {
+		case "agenda_item":
+			var isHidden, isInternal bool
+			if err := decodeValue(values, fqid+"/is_hidden", &isHidden); err != nil {
+				return nil, err
+			}
+			if err := decodeValue(values, fqid+"/is_internal", &isInternal); err != nil {
+				return nil, err
+			}
+
+			if isHidden && !hasPerm(group.perms, "agenda_item.can_manage") ||
+				isInternal && !hasPerm(group.perms, "agenda_item.can_see_internal") {
+				hidden[fqid] = true
+			}
+
+		case "mediafile":
+			var isPublic bool
+			var accessGroups []int
+			if err := decodeValue(values, fqid+"/is_public", &isPublic); err != nil {
+				return nil, err
+			}
+			if err := decodeValue(values, fqid+"/inherited_access_group_ids", &accessGroups); err != nil {
+				return nil, err
+			}
+
+			if !isPublic && !containsInt(accessGroups, group.id) && !hasPerm(group.perms, "mediafile.can_manage") {
+				hidden[fqid] = true
+			}
+
+		case "motion":
+			hidden[fqid] = hiddenMotions[fqid]
+
+		case "motion_change_recommendation", "motion_submitter":
+			var motionID int
+			var internal bool
+			if err := decodeValue(values, fqid+"/motion_id", &motionID); err != nil {
+				return nil, err
+			}
+			if err := decodeValue(values, fqid+"/internal", &internal); err != nil {
+				return nil, err
+			}
+
+			if hiddenMotions[fmt.Sprintf("motion/%d", motionID)] || internal && !hasPerm(group.perms, "motion.can_manage") {
+				hidden[fqid] = true
+			}
+		}
+	}
+	return hidden, nil
+}
+
+// hiddenMotions returns the motions, that the anonymous user can not see
+// because of the restrictions of their state.
+//
+// motions is a map from the fqid of a motion to the meeting, that allowed it.
+func (a *Anonymous) hiddenMotions(ctx context.Context, motions map[string]string, groups map[string]defaultGroup) (map[string]bool, error) {
+	if len(motions) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(motions))
+	for fqid := range motions {
+		keys = append(keys, fqid+"/state_id")
+	}
+
+	values, err := a.getValues(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("getting states: %w", err)
+	}
+
+	stateOf := make(map[string]int, len(motions))
+	stateKeys := make([]string, 0, len(motions))
+	for fqid := range motions {
+		var stateID int
+		if err := decodeValue(values, fqid+"/state_id", &stateID); err != nil {
+			return nil, err
+		}
+		if stateID == 0 {
+			continue
+		}
+		stateOf[fqid] = stateID
+		stateKeys = append(stateKeys, fmt.Sprintf("motion_state/%d/restrictions", stateID))
+	}
+
+	if len(stateKeys) == 0 {
+		return nil, nil
+	}
+
+	values, err = a.getValues(ctx, stateKeys)
+	if err != nil {
+		return nil, fmt.Errorf("getting restrictions: %w", err)
+	}
+
+	hidden := make(map[string]bool)
+	for fqid, meetingID := range motions {
+		if stateOf[fqid] == 0 {
+			continue
+		}
+
+		var restrictions []string
+		if err := decodeValue(values, fmt.Sprintf("motion_state/%d/restrictions", stateOf[fqid]), &restrictions); err != nil {
+			return nil, err
+		}
+
+		if len(restrictions) == 0 {
+			continue
+		}
+
+		hidden[fqid] = true
+		perms := groups[meetingID].perms
+		for _, restriction := range restrictions {
+			// The anonymous user is never a submitter.
+			if restriction == "is_submitter" {
+				continue
+			}
+
+			if hasPerm(perms, restriction) || perms["motion.can_manage"] {
+				hidden[fqid] = false
+				break
+			}
+		}
+	}
+	return hidden, nil
+}
+
+// getValues returns the values of the keys as a map. Keys that do not exist
+// are not in the map.
+func (a *Anonymous) getValues(ctx context.Context, keys []string) (map[string]json.RawMessage, error) {
+	values, err := a.ds.Get(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]json.RawMessage, len(keys))
+	for i, value := range values {
+		if value != nil {
+			data[keys[i]] = value
+		}
+	}
+	return data, nil
+}
+
+// decodeValue decodes the value of a key. If the key does not exist, v is
+// not changed.
+func decodeValue(values map[string]json.RawMessage, key string, v interface{}) error {
+	value, ok := values[key]
+	if !ok {
+		return nil
+	}
+
+	if err := json.Unmarshal(value, v); err != nil {
+		return fmt.Errorf("decoding %s: %w", key, err)
+	}
+	return nil
+}
+
+// containsInt returns true, if the slice contains the value.
+func containsInt(slice []int, value int) bool {
+	for _, v := range slice {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPerm returns true, if the permission is in perms or implied by a
+// permission in perms.
+//
+// A can_manage permission implies all can_see permissions of the same
+// collection, for example user.can_see_extra_data.
+func hasPerm(perms map[string]bool, perm string) bool {
+	if perms[perm] {
+		return true
+	}
+
+	collection, action := splitPerm(perm)
+	if strings.HasPrefix(action, "can_see") {
+		return perms[collection+".can_manage"]
+	}
+	return false
+}
+
+// splitPerm splits a permission into its collection and its action.
+func splitPerm(perm string) (string, string) {
+	i := strings.IndexByte(perm, '.')
+	if i < 0 {
+		return perm, ""
+	}
+	return perm[:i], perm[i+1:]
+}
@@ -0,0 +1,244 @@
+package restrict_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
+)
+
+func TestAnonymous(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+	meeting:
+		1:
+			enable_anonymous: true
+			default_group_id: 1
+		2:
+			enable_anonymous: false
+			default_group_id: 2
+
+	group:
+		1:
+			meeting_id: 1
+			permissions: [motion.can_manage, user.can_see]
+		2:
+			meeting_id: 2
+			permissions: [motion.can_see, agenda_item.can_see]
+
+	motion/1/meeting_id: 1
+	motion/2/meeting_id: 2
+	agenda_item/1/meeting_id: 1
+	personal_note/1/meeting_id: 1
+
+	user/1:
+		group_$_ids: ["1"]
+		username: hugo
+		password: secret
+	`))
+
+	perms := &test.MockPermission{Default: true}
+	anonymous := restrict.NewAnonymous(perms, ds, restrict.RestrictionModes)
+
+	for _, tt := range []struct {
+		key    string
+		expect bool
+	}{
+		{"meeting/1/name", true},
+		{"meeting/2/name", false},
+		{"group/1/name", true},
+		{"motion/1/title", true},
+		{"motion/2/title", false},
+		{"agenda_item/1/item_number", false},
+		{"personal_note/1/note", false},
+		{"user/1/username", true},
+		{"user/1/password", false},
+		{"user/1/email", false},
+		{"user/1/personal_note_$1_ids", false},
+		{"meeting/1/present_user_ids", true},
+		{"meeting/1/conference_stream_url", false},
+		{"motion/404/title", false},
+	} {
+		t.Run(tt.key, func(t *testing.T) {
+			got, err := anonymous.RestrictFQFields(context.Background(), 0, []string{tt.key})
+			if err != nil {
+				t.Fatalf("RestrictFQFields returned unexpected error: %v", err)
+			}
+
+			if got[tt.key] != tt.expect {
+				t.Errorf("RestrictFQFields returned %t for %s, expected %t", got[tt.key], tt.key, tt.expect)
+			}
+		})
+	}
+
+	t.Run("other user", func(t *testing.T) {
+		got, err := anonymous.RestrictFQFields(context.Background(), 1, []string{"motion/2/title"})
+		if err != nil {
+			t.Fatalf("RestrictFQFields returned unexpected error: %v", err)
+		}
+
+		if !got["motion/2/title"] || !perms.Called["motion/2/title"] {
+			t.Errorf("RestrictFQFields for user 1 was not delegated")
+		}
+	})
+}
+
+func TestAnonymousRestrictionModes(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	// The default group has all permissions for restriction mode A.
+	var perms []string
+	for _, perm := range restrict.AnonymousPermissions {
+		if perm != "" {
+			perms = append(perms, fmt.Sprintf("%q", perm))
+		}
+	}
+
+	data := dsmock.YAMLData(fmt.Sprintf(`
+	meeting/1:
+		enable_anonymous: true
+		default_group_id: 1
+	group/1/permissions: [%s]
+	user/1/group_$_ids: ["1"]
+	`, strings.Join(perms, ",")))
+	for collection := range restrict.AnonymousPermissions {
+		if collection != "meeting" && collection != "user" {
+			data[collection+"/1/meeting_id"] = "1"
+		}
+	}
+	data["mediafile/1/is_public"] = "true"
+
+	ds := dsmock.NewMockDatastore(closed, data)
+	anonymous := restrict.NewAnonymous(&test.MockPermission{}, ds, restrict.RestrictionModes)
+
+	for field, mode := range restrict.RestrictionModes {
+		collection := strings.Split(field, "/")[0]
+		key := collection + "/1/" + strings.Replace(strings.Split(field, "/")[1], "$", "$1", 1)
+		_, known := restrict.AnonymousPermissions[collection]
+		// The member lists of a meeting (mode D) need user.can_see, that is
+		// also a permission for mode A.
+		expect := known && (mode == "A" || collection == "meeting" && mode == "D")
+
+		got, err := anonymous.RestrictFQFields(context.Background(), 0, []string{key})
+		if err != nil {
+			t.Fatalf("RestrictFQFields returned unexpected error: %v", err)
+		}
+
+		if got[key] != expect {
+			t.Errorf("RestrictFQFields returned %t for %s with mode %s, expected %t", got[key], key, mode, expect)
+		}
+	}
+}
+
+func TestAnonymousObjects(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+	meeting/1:
+		enable_anonymous: true
+		default_group_id: 1
+
+	group/1:
+		meeting_id: 1
+		permissions: [motion.can_see, mediafile.can_see, agenda_item.can_see]
+
+	motion:
+		1:
+			meeting_id: 1
+			state_id: 1
+		2:
+			meeting_id: 1
+			state_id: 2
+		3:
+			meeting_id: 1
+			state_id: 3
+
+	motion_state/1/restrictions: []
+	motion_state/2/restrictions: [motion.can_manage_metadata, is_submitter]
+	motion_state/3/restrictions: [motion.can_see_internal]
+
+	motion_change_recommendation:
+		1:
+			meeting_id: 1
+			motion_id: 1
+		2:
+			meeting_id: 1
+			motion_id: 1
+			internal: true
+		3:
+			meeting_id: 1
+			motion_id: 2
+
+	motion_submitter:
+		1:
+			meeting_id: 1
+			motion_id: 1
+		2:
+			meeting_id: 1
+			motion_id: 2
+
+	mediafile:
+		1:
+			meeting_id: 1
+			is_public: true
+		2:
+			meeting_id: 1
+			is_public: false
+			inherited_access_group_ids: [1]
+		3:
+			meeting_id: 1
+			is_public: false
+			inherited_access_group_ids: [2]
+
+	agenda_item:
+		1:
+			meeting_id: 1
+		2:
+			meeting_id: 1
+			is_hidden: true
+		3:
+			meeting_id: 1
+			is_internal: true
+	`))
+
+	anonymous := restrict.NewAnonymous(&test.MockPermission{}, ds, restrict.RestrictionModes)
+
+	for _, tt := range []struct {
+		key    string
+		expect bool
+	}{
+		{"motion/1/title", true},
+		{"motion/2/title", false},
+		{"motion/3/title", false},
+		{"motion_change_recommendation/1/text", true},
+		{"motion_change_recommendation/2/text", false},
+		{"motion_change_recommendation/3/text", false},
+		{"motion_submitter/1/weight", true},
+		{"motion_submitter/2/weight", false},
+		{"mediafile/1/title", true},
+		{"mediafile/2/title", true},
+		{"mediafile/3/title", false},
+		{"agenda_item/1/item_number", true},
+		{"agenda_item/2/item_number", false},
+		{"agenda_item/3/item_number", false},
+	} {
+		t.Run(tt.key, func(t *testing.T) {
+			got, err := anonymous.RestrictFQFields(context.Background(), 0, []string{tt.key})
+			if err != nil {
+				t.Fatalf("RestrictFQFields returned unexpected error: %v", err)
+			}
+
+			if got[tt.key] != tt.expect {
+				t.Errorf("RestrictFQFields returned %t for %s, expected %t", got[tt.key], tt.key, tt.expect)
+			}
+		})
+	}
+}
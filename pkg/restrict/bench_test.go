@@ -0,0 +1,60 @@
+package restrict_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/testdata"
+)
+
+func BenchmarkRestrict(b *testing.B) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	content := testdata.Scaled(testdata.BenchUserCount, testdata.BenchMotionCount)
+	ds := dsmock.NewMockDatastore(closed, content)
+	restricter := newScenarioRestricter(ds)
+
+	var keys []string
+	for k := range content {
+		if strings.HasPrefix(k, "motion/") || strings.HasPrefix(k, "user/") {
+			keys = append(keys, k)
+		}
+	}
+
+	values, err := ds.Get(context.Background(), keys...)
+	if err != nil {
+		b.Fatalf("Get returned unexpected error: %v", err)
+	}
+
+	for _, bench := range []struct {
+		name string
+		uid  int
+	}{
+		{"admin", 1},
+		{"staff", 20},
+		{"delegate", 500},
+		{"default group", testdata.BenchUserCount},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				data := make(map[string]json.RawMessage, len(keys))
+				for j, key := range keys {
+					data[key] = values[j]
+				}
+
+				if err := restricter.Restrict(context.Background(), bench.uid, data); err != nil {
+					b.Fatalf("Restrict returned unexpected error: %v", err)
+				}
+			}
+			b.ReportMetric(float64(len(keys)*b.N)/time.Since(start).Seconds(), "keys/s")
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package restrict
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/models"
+)
+
+// Blocked is a restricter, that removes keys from all responses regardless of
+// the permissions of the user. This is also true for superadmins. It is
+// usually used after the permission restricter in a Chain.
+//
+// Each entry is a collection, for example `chat_group`, or a field in the form
+// collection/field, for example `motion/reason`. A deployment can use it to
+// disable a feature completely.
+//
+// The ids of blocked collections are also removed from relation lists, for
+// example from meeting/1/chat_group_ids, and generic relations to a blocked
+// collection, for example projection/1/content_object_id, are removed.
+type Blocked struct {
+	blocked       map[string]bool
+	relationLists map[string]string
+}
+
+// NewBlocked initializes a Blocked restricter.
+//
+// relationLists are the relation lists of the models like RelationLists.
+func NewBlocked(relationLists map[string]string, entries ...string) *Blocked {
+	blocked := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		blocked[entry] = true
+	}
+
+	return &Blocked{
+		blocked:       blocked,
+		relationLists: relationLists,
+	}
+}
+
+// Restrict implements the autoupdate.Restricter interface.
+func (b *Blocked) Restrict(ctx context.Context, uid int, data map[string]json.RawMessage) error {
+	return b.Filter(data, nil)
+}
+
+// Explain restricts the data like Restrict. It returns the reason `blocked`
+// for each removed key.
+func (b *Blocked) Explain(ctx context.Context, uid int, data map[string]json.RawMessage) (map[string]string, error) {
+	reasons := make(map[string]string)
+	if err := b.Filter(data, reasons); err != nil {
+		return nil, err
+	}
+	return reasons, nil
+}
+
+// Filter removes the blocked keys and the relations to blocked collections
+// from data. If reasons is not nil, the removed keys are written into it.
+//
+// It does not need a user, so it can also be used for data, that is not sent
+// to a specific user, for example the data of a projector.
+func (b *Blocked) Filter(data map[string]json.RawMessage, reasons map[string]string) error {
+	if len(b.blocked) == 0 {
+		return nil
+	}
+
+	for k, v := range data {
+		if v == nil {
+			continue
+		}
+
+		t := strings.Split(k, "/")
+		if len(t) != 3 {
+			continue
+		}
+
+		collectionField := t[0] + "/" + models.TemplateName(t[2])
+		if b.blocked[t[0]] || b.blocked[collectionField] {
+			data[k] = nil
+			if reasons != nil {
+				reasons[k] = "blocked"
+			}
+			continue
+		}
+
+		filtered, err := b.filterRelation(t[2], collectionField, v)
+		if err != nil {
+			return fmt.Errorf("filter relation %s: %w", k, err)
+		}
+
+		if filtered == nil && reasons != nil {
+			reasons[k] = "blocked"
+		}
+		data[k] = filtered
+	}
+	return nil
+}
+
+// filterRelation removes blocked collections from the value of a relation
+// field.
+func (b *Blocked) filterRelation(field, collectionField string, value json.RawMessage) (json.RawMessage, error) {
+	target, ok := b.relationLists[collectionField]
+	if ok && strings.Contains(field, "$_") {
+		// The field is the list of replacements of a template field.
+		return value, nil
+	}
+
+	switch {
+	case ok && target == "*":
+		var fqids []string
+		if err := json.Unmarshal(value, &fqids); err != nil {
+			return nil, fmt.Errorf("decoding generic relation list: %w", err)
+		}
+
+		filtered := make([]string, 0, len(fqids))
+		for _, fqid := range fqids {
+			if collection, _ := splitFQID(fqid); !b.blocked[collection] {
+				filtered = append(filtered, fqid)
+			}
+		}
+
+		if len(filtered) == len(fqids) {
+			return value, nil
+		}
+		return json.Marshal(filtered)
+
+	case ok:
+		if b.blocked[target] {
+			return []byte("[]"), nil
+		}
+		return value, nil
+
+	case strings.HasSuffix(field, "_id") && bytes.HasPrefix(value, []byte(`"`)):
+		// Generic relation.
+		var fqid string
+		if err := json.Unmarshal(value, &fqid); err != nil {
+			return nil, fmt.Errorf("decoding generic relation: %w", err)
+		}
+
+		if collection, _ := splitFQID(fqid); b.blocked[collection] {
+			return nil, nil
+		}
+		return value, nil
+
+	default:
+		return value, nil
+	}
+}
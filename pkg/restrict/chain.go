@@ -0,0 +1,79 @@
+package restrict
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Chain runs restricters one after another. Each restricter gets the data,
+// that was restricted by the previous restricters.
+//
+// This can be used to add a restricter, that removes or changes some values,
+// after the permission restricter.
+type Chain struct {
+	restricters []DataRestricter
+}
+
+// NewChain initializes a Chain. The restricters are called in the given order.
+func NewChain(restricters ...DataRestricter) *Chain {
+	return &Chain{restricters: restricters}
+}
+
+// Restrict implements the autoupdate.Restricter interface.
+func (c *Chain) Restrict(ctx context.Context, uid int, data map[string]json.RawMessage) error {
+	for i, r := range c.restricters {
+		if err := r.Restrict(ctx, uid, data); err != nil {
+			return fmt.Errorf("restricter %d (%T): %w", i, r, err)
+		}
+	}
+	return nil
+}
+
+// Explain restricts the data like Restrict. The first restricter has to
+// support explanations. A key, that is removed by a later restricter, gets
+// the explanation of this restricter or, if it does not support explanations,
+// this restricter as reason.
+func (c *Chain) Explain(ctx context.Context, uid int, data map[string]json.RawMessage) (map[string]string, error) {
+	if len(c.restricters) == 0 {
+		return make(map[string]string), nil
+	}
+
+	e, ok := c.restricters[0].(explainer)
+	if !ok {
+		return nil, fmt.Errorf("restricter %T does not support explain", c.restricters[0])
+	}
+
+	reasons, err := e.Explain(ctx, uid, data)
+	if err != nil {
+		return nil, fmt.Errorf("restricter 0 (%T): %w", c.restricters[0], err)
+	}
+
+	for i, r := range c.restricters[1:] {
+		visible := make(map[string]bool, len(data))
+		for k, v := range data {
+			visible[k] = v != nil
+		}
+
+		var explained map[string]string
+		if e, ok := r.(explainer); ok {
+			explained, err = e.Explain(ctx, uid, data)
+		} else {
+			err = r.Restrict(ctx, uid, data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("restricter %d (%T): %w", i+1, r, err)
+		}
+
+		for k, v := range data {
+			if visible[k] && v == nil {
+				reason, ok := explained[k]
+				if !ok {
+					reason = fmt.Sprintf("removed by restricter %d (%T)", i+1, r)
+				}
+				reasons[k] = reason
+			}
+		}
+	}
+	return reasons, nil
+}
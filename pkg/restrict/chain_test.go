@@ -0,0 +1,75 @@
+package restrict_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stripRestricter removes the given keys.
+type stripRestricter []string
+
+func (r stripRestricter) Restrict(ctx context.Context, uid int, data map[string]json.RawMessage) error {
+	for _, key := range r {
+		if _, ok := data[key]; ok {
+			data[key] = nil
+		}
+	}
+	return nil
+}
+
+// explainRestricter sets each value to "restricted" and explains it with
+// "explained".
+type explainRestricter struct{}
+
+func (explainRestricter) Restrict(ctx context.Context, uid int, data map[string]json.RawMessage) error {
+	for k, v := range data {
+		if v != nil {
+			data[k] = []byte(`"restricted"`)
+		}
+	}
+	return nil
+}
+
+func (r explainRestricter) Explain(ctx context.Context, uid int, data map[string]json.RawMessage) (map[string]string, error) {
+	reasons := make(map[string]string, len(data))
+	for k := range data {
+		reasons[k] = "explained"
+	}
+	return reasons, r.Restrict(ctx, uid, data)
+}
+
+func TestChain(t *testing.T) {
+	chain := restrict.NewChain(explainRestricter{}, stripRestricter{"motion/1/text"})
+
+	data := map[string]json.RawMessage{
+		"motion/1/title": []byte(`"title"`),
+		"motion/1/text":  []byte(`"text"`),
+	}
+	require.NoError(t, chain.Restrict(context.Background(), 1, data))
+
+	assert.Equal(t, map[string]json.RawMessage{
+		"motion/1/title": []byte(`"restricted"`),
+		"motion/1/text":  nil,
+	}, data)
+}
+
+func TestChainExplain(t *testing.T) {
+	chain := restrict.NewChain(explainRestricter{}, stripRestricter{"motion/1/text"})
+
+	data := map[string]json.RawMessage{
+		"motion/1/title": []byte(`"title"`),
+		"motion/1/text":  []byte(`"text"`),
+	}
+	reasons, err := chain.Explain(context.Background(), 1, data)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"motion/1/title": "explained",
+		"motion/1/text":  "removed by restricter 1 (restrict_test.stripRestricter)",
+	}, reasons)
+}
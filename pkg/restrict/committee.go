@@ -0,0 +1,142 @@
+package restrict
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+)
+
+// Committee is a Permissioner that lets committee managers see the meetings
+// and the users of their committees, even if they are not a member of the
+// meeting. All other keys are checked by the underlying Permissioner.
+//
+// A user is a manager of the committees in user/committee_as_manager_ids. A
+// meeting belongs to the committee in meeting/committee_id. A user belongs to
+// the committees in user/committee_as_member_ids and
+// user/committee_as_manager_ids.
+type Committee struct {
+	permer Permissioner
+	ds     datastore.Getter
+}
+
+// NewCommittee initializes a Committee Permissioner.
+func NewCommittee(permer Permissioner, ds datastore.Getter) *Committee {
+	return &Committee{
+		permer: permer,
+		ds:     ds,
+	}
+}
+
+// RestrictFQFields implements the Permissioner interface.
+func (c *Committee) RestrictFQFields(ctx context.Context, uid int, fqfields []string) (map[string]bool, error) {
+	allowed, err := c.permer.RestrictFQFields(ctx, uid, fqfields)
+	if err != nil {
+		return nil, fmt.Errorf("check permissions: %w", err)
+	}
+
+	if uid == 0 {
+		return allowed, nil
+	}
+
+	// The denied fqids of meetings and users.
+	var denied []string
+	seen := make(map[string]bool)
+	for _, fqfield := range fqfields {
+		if allowed[fqfield] {
+			continue
+		}
+
+		fqid, _ := splitFQField(fqfield)
+		collection := strings.Split(fqid, "/")[0]
+		if collection != "meeting" && collection != "user" {
+			continue
+		}
+
+		if !seen[fqid] {
+			seen[fqid] = true
+			denied = append(denied, fqid)
+		}
+	}
+
+	if len(denied) == 0 {
+		return allowed, nil
+	}
+
+	var managerOf []int
+	if err := getJSON(ctx, c.ds, fmt.Sprintf("user/%d/committee_as_manager_ids", uid), &managerOf); err != nil {
+		return nil, fmt.Errorf("getting committees of user %d: %w", uid, err)
+	}
+
+	if len(managerOf) == 0 {
+		return allowed, nil
+	}
+
+	managed := make(map[int]bool, len(managerOf))
+	for _, id := range managerOf {
+		managed[id] = true
+	}
+
+	visible, err := c.committeeObjects(ctx, denied, managed)
+	if err != nil {
+		return nil, fmt.Errorf("checking committee objects: %w", err)
+	}
+
+	for _, fqfield := range fqfields {
+		fqid, _ := splitFQField(fqfield)
+		if visible[fqid] {
+			allowed[fqfield] = true
+		}
+	}
+	return allowed, nil
+}
+
+// committeeObjects returns the fqids, that belong to one of the managed
+// committees.
+func (c *Committee) committeeObjects(ctx context.Context, fqids []string, managed map[int]bool) (map[string]bool, error) {
+	var keys []string
+	var keyFQIDs []string
+	for _, fqid := range fqids {
+		if strings.HasPrefix(fqid, "meeting/") {
+			keys = append(keys, fqid+"/committee_id")
+			keyFQIDs = append(keyFQIDs, fqid)
+			continue
+		}
+
+		keys = append(keys, fqid+"/committee_as_member_ids", fqid+"/committee_as_manager_ids")
+		keyFQIDs = append(keyFQIDs, fqid, fqid)
+	}
+
+	values, err := c.ds.Get(ctx, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("getting committee ids: %w", err)
+	}
+
+	visible := make(map[string]bool)
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+
+		var committeeIDs []int
+		if strings.HasSuffix(keys[i], "/committee_id") {
+			var id int
+			if err := json.Unmarshal(value, &id); err != nil {
+				return nil, fmt.Errorf("decoding %s: %w", keys[i], err)
+			}
+			committeeIDs = []int{id}
+		} else if err := json.Unmarshal(value, &committeeIDs); err != nil {
+			return nil, fmt.Errorf("decoding %s: %w", keys[i], err)
+		}
+
+		for _, id := range committeeIDs {
+			if managed[id] {
+				visible[keyFQIDs[i]] = true
+				break
+			}
+		}
+	}
+	return visible, nil
+}
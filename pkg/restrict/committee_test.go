@@ -0,0 +1,78 @@
+package restrict_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
+)
+
+func TestCommittee(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+	committee:
+		1:
+			meeting_ids: [1]
+			manager_ids: [1]
+			member_ids: [2]
+		2:
+			meeting_ids: [2]
+			member_ids: [3]
+
+	meeting:
+		1:
+			committee_id: 1
+		2:
+			committee_id: 2
+
+	user:
+		1:
+			committee_as_manager_ids: [1]
+		2:
+			committee_as_member_ids: [1]
+		3:
+			committee_as_member_ids: [2]
+		4:
+			username: nobody
+	`))
+
+	perms := &test.MockPermission{
+		Data: map[string]bool{
+			"motion/1/title": true,
+		},
+	}
+	committee := restrict.NewCommittee(perms, ds)
+
+	for _, tt := range []struct {
+		name   string
+		uid    int
+		key    string
+		expect bool
+	}{
+		{"permission", 1, "motion/1/title", true},
+		{"own meeting", 1, "meeting/1/name", true},
+		{"other meeting", 1, "meeting/2/name", false},
+		{"committee member", 1, "user/2/username", true},
+		{"committee manager", 1, "user/1/username", true},
+		{"other committee member", 1, "user/3/username", false},
+		{"no committee", 1, "user/4/username", false},
+		{"other collection", 1, "motion/2/title", false},
+		{"no manager", 2, "meeting/1/name", false},
+		{"anonymous", 0, "meeting/1/name", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := committee.RestrictFQFields(context.Background(), tt.uid, []string{tt.key})
+			if err != nil {
+				t.Fatalf("RestrictFQFields returned unexpected error: %v", err)
+			}
+
+			if got[tt.key] != tt.expect {
+				t.Errorf("RestrictFQFields returned %t for %s, expected %t", got[tt.key], tt.key, tt.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+// This tool generates the list of related fields in the file def.go.
+// To call it, just call "go generate ./..." in the root folder of the repository
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"text/template"
+
+	modelsyml "github.com/OpenSlides/openslides-autoupdate-service/pkg/models"
+	models "github.com/OpenSlides/openslides-models-to-go"
+)
+
+const defURL = "https://raw.githubusercontent.com/OpenSlides/OpenSlides/openslides4-dev/docs/models.yml"
+
+func main() {
+	r, err := loadDefition()
+	if err != nil {
+		log.Fatalf("Can not load models defition: %v", err)
+	}
+	defer r.Close()
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		log.Fatalf("Can not read model definition: %v", err)
+	}
+
+	data, err := parse(bytes.NewReader(content))
+	if err != nil {
+		log.Fatalf("Can not parse model definition: %v", err)
+	}
+
+	modes, err := parseModes(bytes.NewReader(content))
+	if err != nil {
+		log.Fatalf("Can not parse restriction modes: %v", err)
+	}
+
+	if err := writeFile(os.Stdout, withExtra(data, extraRelationLists), withExtra(modes, extraRestrictionModes)); err != nil {
+		log.Fatalf("Can not write result: %v", err)
+	}
+}
+
+// extraRelationLists are relation-list fields, that are not part of the
+// models.yml yet.
+var extraRelationLists = map[string]string{
+	"group/meeting_user_ids":                 "meeting_user",
+	"meeting/meeting_user_ids":               "meeting_user",
+	"meeting_user/group_ids":                 "group",
+	"meeting_user/motion_submitter_ids":      "motion_submitter",
+	"meeting_user/personal_note_ids":         "personal_note",
+	"meeting_user/speaker_ids":               "speaker",
+	"meeting_user/supported_motion_ids":      "motion",
+	"meeting_user/vote_delegations_from_ids": "meeting_user",
+	"user/meeting_user_ids":                  "meeting_user",
+}
+
+// extraRestrictionModes are the restriction modes of fields, that are not part
+// of the models.yml yet. The fields of meeting_user have the same modes as the
+// template fields of user, that they replace.
+var extraRestrictionModes = map[string]string{
+	"group/meeting_user_ids":                 "A",
+	"meeting/meeting_user_ids":               "D",
+	"meeting_user/about_me":                  "A",
+	"meeting_user/comment":                   "B",
+	"meeting_user/group_ids":                 "A",
+	"meeting_user/id":                        "A",
+	"meeting_user/meeting_id":                "A",
+	"meeting_user/motion_submitter_ids":      "A",
+	"meeting_user/number":                    "A",
+	"meeting_user/personal_note_ids":         "D",
+	"meeting_user/speaker_ids":               "A",
+	"meeting_user/structure_level":           "A",
+	"meeting_user/supported_motion_ids":      "A",
+	"meeting_user/user_id":                   "A",
+	"meeting_user/vote_delegated_to_id":      "B",
+	"meeting_user/vote_delegations_from_ids": "B",
+	"meeting_user/vote_weight":               "A",
+	"user/meeting_user_ids":                  "A",
+}
+
+// withExtra adds the extra fields to the generated fields. If a field is
+// generated, the generated value wins.
+func withExtra(generated, extra map[string]string) map[string]string {
+	for k, v := range extra {
+		if _, ok := generated[k]; !ok {
+			generated[k] = v
+		}
+	}
+	return generated
+}
+
+func loadDefition() (io.ReadCloser, error) {
+	r, err := http.Get(defURL)
+	if err != nil {
+		return nil, fmt.Errorf("request defition: %w", err)
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request returned status %s", r.Status)
+	}
+	return r.Body, nil
+}
+
+// parse returns all relation-list and generic-relation-list fields and where
+// they point to.
+func parse(r io.Reader) (map[string]string, error) {
+	inData, err := models.Unmarshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshalling models.yml: %w", err)
+	}
+
+	outData := make(map[string]string)
+	for modelName, model := range inData {
+		for fieldName, field := range model.Fields {
+			r := field.Relation()
+
+			if r == nil || !r.List() {
+				continue
+			}
+
+			collection := "*"
+			if _, ok := r.(*models.AttributeRelation); ok {
+				collection = r.ToCollections()[0].Collection
+			}
+
+			outData[fmt.Sprintf("%s/%s", modelName, fieldName)] = collection
+		}
+	}
+
+	return outData, nil
+}
+
+// parseModes returns the restriction mode of all fields.
+func parseModes(r io.Reader) (map[string]string, error) {
+	m, err := modelsyml.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("parsing models.yml: %w", err)
+	}
+	return m.RestrictionModes(), nil
+}
+
+const tpl = `// Code generated with models.txt DO NOT EDIT.
+package restrict
+
+
+// RelationLists is list from all relation-list and generic-relation-list the
+// model where it directs to. generic-relation-list habe '*' als value. The list
+// contains also all template-fields that contain relation-list and
+// geneeric-relation-lists.
+//
+// The map is automaticly created from the models.yml file.
+var RelationLists = map[string]string{
+	{{- range $key, $value := .Def}}
+	"{{$key}}": "{{$value}}",
+	{{- end}}
+}
+
+// RestrictionModes is a map from all fields (collection/field) to their
+// restriction mode. Template fields are given by there template name.
+//
+// The map is automaticly created from the models.yml file.
+var RestrictionModes = map[string]string{
+	{{- range $key, $value := .Modes}}
+	"{{$key}}": "{{$value}}",
+	{{- end}}
+}
+
+// ExtraRelationLists are the fields of RelationLists, that are not part of the
+// models.yml yet. They have to be added to relation lists, that are created
+// from another models.yml.
+var ExtraRelationLists = map[string]string{
+	{{- range $key, $value := .ExtraDef}}
+	"{{$key}}": "{{$value}}",
+	{{- end}}
+}
+
+// ExtraRestrictionModes are the fields of RestrictionModes, that are not part
+// of the models.yml yet. They have to be added to restriction modes, that are
+// created from another models.yml.
+var ExtraRestrictionModes = map[string]string{
+	{{- range $key, $value := .ExtraModes}}
+	"{{$key}}": "{{$value}}",
+	{{- end}}
+}
+`
+
+func writeFile(w io.Writer, rlist map[string]string, modes map[string]string) error {
+	t := template.New("t")
+	t, err := t.Parse(tpl)
+	if err != nil {
+		return fmt.Errorf("parsing template: %w", err)
+	}
+
+	data := map[string]interface{}{
+		"Def":        rlist,
+		"Modes":      modes,
+		"ExtraDef":   extraRelationLists,
+		"ExtraModes": extraRestrictionModes,
+	}
+
+	if err := t.Execute(w, data); err != nil {
+		return fmt.Errorf("writing template: %w", err)
+	}
+	return nil
+}
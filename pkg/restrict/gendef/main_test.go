@@ -65,6 +65,24 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestWithExtra(t *testing.T) {
+	got := withExtra(
+		map[string]string{"model/other_ids": "other"},
+		map[string]string{"model/other_ids": "extra", "extra/model_ids": "model"},
+	)
+
+	expect := map[string]string{"model/other_ids": "other", "extra/model_ids": "model"}
+	if len(got) != len(expect) {
+		t.Errorf("Got %d fields, expected %d", len(got), len(expect))
+	}
+
+	for k, v := range expect {
+		if got[k] != v {
+			t.Errorf("got[%s] == `%s`, expected `%s`", k, got[k], v)
+		}
+	}
+}
+
 const modelsSimple = `
 model:
 	id: number
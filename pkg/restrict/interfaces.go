@@ -24,3 +24,13 @@ type CheckerFunc func(ctx context.Context, uid int, key string, value json.RawMe
 func (f CheckerFunc) Check(ctx context.Context, uid int, key string, value json.RawMessage) (json.RawMessage, error) {
 	return f(ctx, uid, key, value)
 }
+
+// DataRestricter restricts keys. It is the same as autoupdate.Restricter.
+type DataRestricter interface {
+	Restrict(ctx context.Context, uid int, data map[string]json.RawMessage) error
+}
+
+// explainer restricts data and tells, which rule decided about each key.
+type explainer interface {
+	Explain(ctx context.Context, uid int, data map[string]json.RawMessage) (map[string]string, error)
+}
@@ -0,0 +1,185 @@
+package restrict
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+)
+
+// isolationManagementLevels are the organisation management levels, that
+// have a relation to every meeting.
+var isolationManagementLevels = map[string]bool{
+	OMLSuperadmin:             true,
+	"can_manage_organisation": true,
+}
+
+// Isolation is a restricter, that removes the keys of meetings, the user has
+// no relation to. It does not grant anything. It is a second line of
+// defense, that hides a meeting, even when a bug in the Permissioner or a
+// Checker would allow it.
+//
+// A key belongs to a meeting, if it is a key of the meeting object or if the
+// field meeting_id of its object points to the meeting. Keys of objects
+// without a meeting, for example users and committees, are not changed.
+//
+// A user has a relation to a meeting, if
+//
+//   - the user is in a group of the meeting (user/group_$<id>_ids),
+//   - the meeting belongs to a committee of the user
+//     (user/committee_as_member_ids or user/committee_as_manager_ids) or
+//   - the user can manage the organisation.
+//
+// The anonymous user has a relation to the meetings with
+// meeting/enable_anonymous.
+type Isolation struct {
+	ds datastore.Getter
+}
+
+// NewIsolation initializes an Isolation restricter. It is usually used as
+// the last restricter of a Chain.
+func NewIsolation(ds datastore.Getter) *Isolation {
+	return &Isolation{ds: ds}
+}
+
+// Restrict implements the autoupdate.Restricter interface.
+func (i *Isolation) Restrict(ctx context.Context, uid int, data map[string]json.RawMessage) error {
+	meetingOf, err := i.meetings(ctx, data)
+	if err != nil {
+		return fmt.Errorf("getting meetings of keys: %w", err)
+	}
+
+	if len(meetingOf) == 0 {
+		return nil
+	}
+
+	related := make(map[int]bool)
+	for key, meetingID := range meetingOf {
+		ok, found := related[meetingID]
+		if !found {
+			ok, err = i.related(ctx, uid, meetingID)
+			if err != nil {
+				return fmt.Errorf("checking relation to meeting %d: %w", meetingID, err)
+			}
+			related[meetingID] = ok
+		}
+
+		if !ok {
+			data[key] = nil
+		}
+	}
+	return nil
+}
+
+// meetings returns the meeting id of each visible key, that belongs to a
+// meeting.
+func (i *Isolation) meetings(ctx context.Context, data map[string]json.RawMessage) (map[string]int, error) {
+	meetingOf := make(map[string]int)
+	keysOf := make(map[string][]string)
+	var meetingKeys []string
+	for key, value := range data {
+		if value == nil {
+			continue
+		}
+
+		parts := strings.SplitN(key, "/", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		if parts[0] == "meeting" {
+			id, err := strconv.Atoi(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid key %s: %w", key, err)
+			}
+			meetingOf[key] = id
+			continue
+		}
+
+		fqid := parts[0] + "/" + parts[1]
+		if _, ok := keysOf[fqid]; !ok {
+			meetingKeys = append(meetingKeys, fqid+"/meeting_id")
+		}
+		keysOf[fqid] = append(keysOf[fqid], key)
+	}
+
+	if len(meetingKeys) == 0 {
+		return meetingOf, nil
+	}
+
+	values, err := i.ds.Get(ctx, meetingKeys...)
+	if err != nil {
+		return nil, fmt.Errorf("getting meeting_id fields: %w", err)
+	}
+
+	for idx, value := range values {
+		if value == nil {
+			continue
+		}
+
+		var meetingID int
+		if err := json.Unmarshal(value, &meetingID); err != nil {
+			return nil, fmt.Errorf("decoding %s: %w", meetingKeys[idx], err)
+		}
+
+		for _, key := range keysOf[strings.TrimSuffix(meetingKeys[idx], "/meeting_id")] {
+			meetingOf[key] = meetingID
+		}
+	}
+	return meetingOf, nil
+}
+
+// related returns true, if the user has a relation to the meeting.
+func (i *Isolation) related(ctx context.Context, uid int, meetingID int) (bool, error) {
+	if uid == 0 {
+		var enabled bool
+		if err := getJSON(ctx, i.ds, fmt.Sprintf("meeting/%d/enable_anonymous", meetingID), &enabled); err != nil {
+			return false, err
+		}
+		return enabled, nil
+	}
+
+	var level string
+	if err := getJSON(ctx, i.ds, fmt.Sprintf("user/%d/organisation_management_level", uid), &level); err != nil {
+		return false, err
+	}
+
+	if isolationManagementLevels[level] {
+		return true, nil
+	}
+
+	var groupIDs []int
+	if err := getJSON(ctx, i.ds, fmt.Sprintf("user/%d/group_$%d_ids", uid, meetingID), &groupIDs); err != nil {
+		return false, err
+	}
+
+	if len(groupIDs) > 0 {
+		return true, nil
+	}
+
+	var committeeID int
+	if err := getJSON(ctx, i.ds, fmt.Sprintf("meeting/%d/committee_id", meetingID), &committeeID); err != nil {
+		return false, err
+	}
+
+	if committeeID == 0 {
+		return false, nil
+	}
+
+	for _, field := range []string{"committee_as_member_ids", "committee_as_manager_ids"} {
+		var committeeIDs []int
+		if err := getJSON(ctx, i.ds, fmt.Sprintf("user/%d/%s", uid, field), &committeeIDs); err != nil {
+			return false, err
+		}
+
+		for _, id := range committeeIDs {
+			if id == committeeID {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
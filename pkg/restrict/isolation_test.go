@@ -0,0 +1,114 @@
+package restrict_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
+)
+
+func TestIsolation(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+	meeting:
+		1:
+			committee_id: 1
+			enable_anonymous: true
+		2:
+			committee_id: 2
+
+	motion:
+		1:
+			meeting_id: 1
+			title: first
+		2:
+			meeting_id: 2
+			title: second
+
+	committee/1/name: one
+
+	user:
+		1:
+			group_$_ids: ["1"]
+			group_$1_ids: [1]
+		2:
+			committee_as_manager_ids: [2]
+		3:
+			organisation_management_level: can_manage_organisation
+		4:
+			committee_as_member_ids: [3]
+	`))
+
+	isolation := restrict.NewIsolation(ds)
+
+	for _, tt := range []struct {
+		name    string
+		uid     int
+		visible []string
+		hidden  []string
+	}{
+		{
+			"member",
+			1,
+			[]string{"motion/1/title", "meeting/1/committee_id", "committee/1/name", "user/2/committee_as_manager_ids"},
+			[]string{"motion/2/title", "meeting/2/committee_id"},
+		},
+		{
+			"committee manager",
+			2,
+			[]string{"motion/2/title", "meeting/2/committee_id"},
+			[]string{"motion/1/title", "meeting/1/committee_id"},
+		},
+		{
+			"organisation manager",
+			3,
+			[]string{"motion/1/title", "motion/2/title"},
+			nil,
+		},
+		{
+			"other committee",
+			4,
+			[]string{"committee/1/name"},
+			[]string{"motion/1/title", "motion/2/title", "meeting/1/committee_id"},
+		},
+		{
+			"anonymous",
+			0,
+			[]string{"motion/1/title", "meeting/1/committee_id"},
+			[]string{"motion/2/title", "meeting/2/committee_id"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := append(append([]string{}, tt.visible...), tt.hidden...)
+			values, err := ds.Get(context.Background(), keys...)
+			if err != nil {
+				t.Fatalf("Getting keys: %v", err)
+			}
+
+			data := make(map[string]json.RawMessage, len(keys))
+			for i, key := range keys {
+				data[key] = values[i]
+			}
+
+			if err := isolation.Restrict(context.Background(), tt.uid, data); err != nil {
+				t.Fatalf("Restrict returned unexpected error: %v", err)
+			}
+
+			for _, key := range tt.visible {
+				if data[key] == nil {
+					t.Errorf("Key %s was removed", key)
+				}
+			}
+
+			for _, key := range tt.hidden {
+				if data[key] != nil {
+					t.Errorf("Key %s is visible", key)
+				}
+			}
+		})
+	}
+}
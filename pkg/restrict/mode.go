@@ -0,0 +1,164 @@
+package restrict
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/models"
+)
+
+// ModeChecker decides, if a user can see the fields of an object, that belong
+// to one restriction mode.
+//
+// In the OpenSlides permission model, the fields of a collection are grouped
+// into restriction modes (A, B, C, ...). All fields of one mode are visible
+// under the same condition. For example, the fields of motion in mode A are
+// visible for users with motion.can_see and the fields in mode C only for
+// users that can also manage the motion.
+type ModeChecker interface {
+	CheckMode(ctx context.Context, uid int, fqids []string) (map[string]bool, error)
+}
+
+// ModeCheckerFunc is a function that implements the ModeChecker interface.
+type ModeCheckerFunc func(ctx context.Context, uid int, fqids []string) (map[string]bool, error)
+
+// CheckMode calls the function.
+func (f ModeCheckerFunc) CheckMode(ctx context.Context, uid int, fqids []string) (map[string]bool, error) {
+	return f(ctx, uid, fqids)
+}
+
+// Option is an optional argument for restrict.New().
+type Option func(*Restricter)
+
+// WithModes sets the restriction modes.
+//
+// fieldModes is a map from a field (collection/field) to the name of its
+// mode. For template fields, the template has to be used (user/group_$_ids).
+//
+// modeCheckers is a map from a collection and a mode (collection/mode) to the
+// ModeChecker that decides about the visibility.
+//
+// A key, that has a mode and a ModeChecker for that mode, is not checked by
+// the Permissioner but only by the ModeChecker.
+func WithModes(fieldModes map[string]string, modeCheckers map[string]ModeChecker) Option {
+	return func(r *Restricter) {
+		r.fieldModes = fieldModes
+		r.modeCheckers = modeCheckers
+	}
+}
+
+// PermissionModeCheckers returns a ModeChecker for each collection and mode in
+// fieldModes, that decides with the Permissioner.
+//
+// All fields of a mode are visible under the same condition. So the
+// ModeCheckers ask the Permissioner only for one field of the mode for each
+// object and not for each requested field. This is the field id, if it is
+// part of the mode. Normal fields are preferred over template fields.
+func PermissionModeCheckers(fieldModes map[string]string, permer Permissioner) map[string]ModeChecker {
+	// The field, that is checked for each mode.
+	fieldOf := make(map[string]string)
+	for modelField, mode := range fieldModes {
+		collection, field := splitFQField(modelField)
+		idx := collection + "/" + mode
+
+		current, ok := fieldOf[idx]
+		if !ok || lessModeField(field, current) {
+			fieldOf[idx] = field
+		}
+	}
+
+	checkers := make(map[string]ModeChecker, len(fieldOf))
+	for idx, field := range fieldOf {
+		field := field
+		checkers[idx] = ModeCheckerFunc(func(ctx context.Context, uid int, fqids []string) (map[string]bool, error) {
+			fqfields := make([]string, len(fqids))
+			for i, fqid := range fqids {
+				fqfields[i] = fqid + "/" + field
+			}
+
+			allowed, err := permer.RestrictFQFields(ctx, uid, fqfields)
+			if err != nil {
+				return nil, fmt.Errorf("check permissions: %w", err)
+			}
+
+			allowedFQIDs := make(map[string]bool, len(fqids))
+			for i, fqid := range fqids {
+				allowedFQIDs[fqid] = allowed[fqfields[i]]
+			}
+			return allowedFQIDs, nil
+		})
+	}
+	return checkers
+}
+
+// lessModeField returns true, if the field a should be used instead of b to
+// check a restriction mode.
+func lessModeField(a, b string) bool {
+	if a == "id" || b == "id" {
+		return a == "id"
+	}
+
+	aTemplate := strings.Contains(a, "$")
+	bTemplate := strings.Contains(b, "$")
+	if aTemplate != bTemplate {
+		return bTemplate
+	}
+	return a < b
+}
+
+// modeChecker returns the index of the ModeChecker for a key. It returns an
+// empty string, if there is no ModeChecker for the key.
+func (r *Restricter) modeChecker(fqfield string) string {
+	if len(r.fieldModes) == 0 {
+		return ""
+	}
+
+	t := strings.Split(fqfield, "/")
+	if len(t) != 3 {
+		return ""
+	}
+
+	mode, ok := r.fieldModes[t[0]+"/"+models.TemplateName(t[2])]
+	if !ok {
+		return ""
+	}
+
+	idx := t[0] + "/" + mode
+	if _, ok := r.modeCheckers[idx]; !ok {
+		return ""
+	}
+	return idx
+}
+
+// checkModes checks the given keys with the ModeCheckers. The keys have to be
+// a map from the key to the index of the ModeChecker.
+//
+// Each ModeChecker is called only once with all fqids.
+func (r *Restricter) checkModes(ctx context.Context, uid int, keys map[string]string) (map[string]bool, error) {
+	fqids := make(map[string][]string)
+	seen := make(map[string]bool)
+	for key, idx := range keys {
+		fqid := key[:strings.LastIndexByte(key, '/')]
+		if seen[idx+fqid] {
+			continue
+		}
+		seen[idx+fqid] = true
+		fqids[idx] = append(fqids[idx], fqid)
+	}
+
+	allowedFQIDs := make(map[string]map[string]bool, len(fqids))
+	for idx, ids := range fqids {
+		allowed, err := r.modeCheckers[idx].CheckMode(ctx, uid, ids)
+		if err != nil {
+			return nil, fmt.Errorf("checking mode %s: %w", idx, err)
+		}
+		allowedFQIDs[idx] = allowed
+	}
+
+	allowed := make(map[string]bool, len(keys))
+	for key, idx := range keys {
+		allowed[key] = allowedFQIDs[idx][key[:strings.LastIndexByte(key, '/')]]
+	}
+	return allowed, nil
+}
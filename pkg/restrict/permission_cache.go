@@ -0,0 +1,336 @@
+package restrict
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+)
+
+// DefaultPermissionCacheSize is the default maximum number of results in a
+// PermissionCache.
+const DefaultPermissionCacheSize = 1 << 20
+
+// PermissionCache is a Permissioner that remembers the results of another
+// Permissioner for each user.
+//
+// When many keys of the same user are checked, only the keys, that were not
+// checked before, are sent to the underlying Permissioner.
+//
+// The cache has to be invalidated on each datastore update by calling
+// Invalidate.
+type PermissionCache struct {
+	permer  Permissioner
+	ds      datastore.Getter
+	maxSize int
+
+	mu sync.RWMutex
+
+	// data is a map from the user id to the fqfield to the result of the
+	// permission check.
+	data map[int]map[string]cachedResult
+
+	// meetings is a map from a meeting id to the user ids to the fqfields of
+	// the results of the objects of the meeting. Results of objects without a
+	// meeting, for example users, have the meeting id 0.
+	meetings map[int]map[int][]string
+
+	// size is the number of results in data.
+	size int
+
+	// generation is increased on every invalidation. Results, that were
+	// requested in an older generation, are not saved.
+	generation uint64
+}
+
+// cachedResult is a result in PermissionCache.data.
+type cachedResult struct {
+	allowed   bool
+	meetingID int
+}
+
+// NewPermissionCache initializes a PermissionCache.
+//
+// The Getter is used to find the meeting of an object. The cache holds at
+// most maxSize results. If it is full, all results are removed. A maxSize of
+// 0 means no limit.
+func NewPermissionCache(permer Permissioner, ds datastore.Getter, maxSize int) *PermissionCache {
+	return &PermissionCache{
+		permer:   permer,
+		ds:       ds,
+		maxSize:  maxSize,
+		data:     make(map[int]map[string]cachedResult),
+		meetings: make(map[int]map[int][]string),
+	}
+}
+
+// RestrictFQFields implements the Permissioner interface.
+func (c *PermissionCache) RestrictFQFields(ctx context.Context, uid int, fqfields []string) (map[string]bool, error) {
+	allowed := make(map[string]bool, len(fqfields))
+	var missing []string
+
+	c.mu.RLock()
+	generation := c.generation
+	userData := c.data[uid]
+	for _, fqfield := range fqfields {
+		result, ok := userData[fqfield]
+		if !ok {
+			missing = append(missing, fqfield)
+			continue
+		}
+		allowed[fqfield] = result.allowed
+	}
+	c.mu.RUnlock()
+
+	if len(missing) == 0 {
+		return allowed, nil
+	}
+
+	checked, err := c.permer.RestrictFQFields(ctx, uid, missing)
+	if err != nil {
+		return nil, fmt.Errorf("check permissions: %w", err)
+	}
+
+	for _, fqfield := range missing {
+		allowed[fqfield] = checked[fqfield]
+	}
+
+	meetingOf, err := c.meetingIDs(ctx, missing)
+	if err != nil {
+		return nil, fmt.Errorf("getting meetings of the checked objects: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation != generation {
+		return allowed, nil
+	}
+
+	if c.maxSize > 0 && c.size+len(missing) > c.maxSize {
+		c.reset()
+	}
+
+	if c.data[uid] == nil {
+		c.data[uid] = make(map[string]cachedResult)
+	}
+
+	for _, fqfield := range missing {
+		if _, ok := c.data[uid][fqfield]; ok {
+			// A concurrent call has already saved the result.
+			continue
+		}
+
+		fqid, _ := splitFQField(fqfield)
+		meetingID := meetingOf[fqid]
+		c.data[uid][fqfield] = cachedResult{allowed: checked[fqfield], meetingID: meetingID}
+
+		if c.meetings[meetingID] == nil {
+			c.meetings[meetingID] = make(map[int][]string)
+		}
+		c.meetings[meetingID][uid] = append(c.meetings[meetingID][uid], fqfield)
+		c.size++
+	}
+	return allowed, nil
+}
+
+// Invalidate removes the results from the cache, that could be changed by the
+// given data.
+//
+// A change of an object of a meeting removes all results of the meeting. A
+// change of a user removes all results of this user. Every change removes the
+// results of objects without a meeting, for example users. A change of an
+// object without a meeting, that is not a user, removes all results.
+//
+// Invalidate can be used as a datastore change listener.
+func (c *PermissionCache) Invalidate(data map[string]json.RawMessage) error {
+	meetingOf, err := c.changedMeetingIDs(data)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+
+	if err != nil {
+		c.reset()
+		return fmt.Errorf("getting meetings of changed objects: %w", err)
+	}
+
+	invalidMeetings := map[int]bool{0: true}
+	for key := range data {
+		fqid, _ := splitFQField(key)
+		collection, rawID := splitFQID(fqid)
+
+		if collection == "user" {
+			id, err := strconv.Atoi(rawID)
+			if err != nil {
+				c.reset()
+				return fmt.Errorf("invalid user key %s: %w", key, err)
+			}
+			c.removeUser(id)
+			continue
+		}
+
+		meetingID := meetingOf[fqid]
+		if meetingID == 0 {
+			// The change could influence every meeting.
+			c.reset()
+			return nil
+		}
+		invalidMeetings[meetingID] = true
+	}
+
+	for meetingID := range invalidMeetings {
+		c.removeMeeting(meetingID)
+	}
+	return nil
+}
+
+// removeUser removes all results of a user.
+//
+// The cache has to be in write lock to call this method.
+func (c *PermissionCache) removeUser(uid int) {
+	for _, result := range c.data[uid] {
+		delete(c.meetings[result.meetingID], uid)
+		if len(c.meetings[result.meetingID]) == 0 {
+			delete(c.meetings, result.meetingID)
+		}
+	}
+	c.size -= len(c.data[uid])
+	delete(c.data, uid)
+}
+
+// removeMeeting removes all results of the objects of a meeting.
+//
+// The cache has to be in write lock to call this method.
+func (c *PermissionCache) removeMeeting(meetingID int) {
+	for uid, fqfields := range c.meetings[meetingID] {
+		for _, fqfield := range fqfields {
+			delete(c.data[uid], fqfield)
+		}
+		if len(c.data[uid]) == 0 {
+			delete(c.data, uid)
+		}
+		c.size -= len(fqfields)
+	}
+	delete(c.meetings, meetingID)
+}
+
+// reset removes all results.
+//
+// The cache has to be in write lock to call this method.
+func (c *PermissionCache) reset() {
+	c.data = make(map[int]map[string]cachedResult)
+	c.meetings = make(map[int]map[int][]string)
+	c.size = 0
+}
+
+// meetingIDs returns the meeting id of the object of each fqfield. Objects
+// without a meeting get the meeting id 0.
+func (c *PermissionCache) meetingIDs(ctx context.Context, fqfields []string) (map[string]int, error) {
+	meetingOf := make(map[string]int)
+	var keys []string
+	for _, fqfield := range fqfields {
+		fqid, _ := splitFQField(fqfield)
+		if _, ok := meetingOf[fqid]; ok {
+			continue
+		}
+
+		collection, id := splitFQID(fqid)
+		switch collection {
+		case "meeting":
+			meetingOf[fqid], _ = strconv.Atoi(id)
+		case "user":
+			meetingOf[fqid] = 0
+		default:
+			meetingOf[fqid] = 0
+			keys = append(keys, fqid+"/meeting_id")
+		}
+	}
+
+	if len(keys) == 0 {
+		return meetingOf, nil
+	}
+
+	values, err := c.ds.Get(ctx, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("getting meeting ids: %w", err)
+	}
+
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+
+		var meetingID int
+		if err := json.Unmarshal(value, &meetingID); err != nil {
+			return nil, fmt.Errorf("decoding %s: %w", keys[i], err)
+		}
+		meetingOf[strings.TrimSuffix(keys[i], "/meeting_id")] = meetingID
+	}
+	return meetingOf, nil
+}
+
+// changedMeetingIDs returns the meeting id of each changed object. The meeting
+// id is taken from the changed data or from the datastore.
+func (c *PermissionCache) changedMeetingIDs(data map[string]json.RawMessage) (map[string]int, error) {
+	var lookup []string
+	meetingOf := make(map[string]int)
+	for key := range data {
+		fqid, _ := splitFQField(key)
+		if _, ok := meetingOf[fqid]; ok {
+			continue
+		}
+
+		value, ok := data[fqid+"/meeting_id"]
+		if !ok {
+			meetingOf[fqid] = 0
+			lookup = append(lookup, key)
+			continue
+		}
+
+		var meetingID int
+		if value != nil {
+			if err := json.Unmarshal(value, &meetingID); err != nil {
+				return nil, fmt.Errorf("decoding %s/meeting_id: %w", fqid, err)
+			}
+		}
+		meetingOf[fqid] = meetingID
+	}
+
+	if len(lookup) == 0 {
+		return meetingOf, nil
+	}
+
+	looked, err := c.meetingIDs(context.Background(), lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	for fqid, meetingID := range looked {
+		meetingOf[fqid] = meetingID
+	}
+	return meetingOf, nil
+}
+
+// splitFQField splits a fqfield into its fqid and its field.
+func splitFQField(fqfield string) (string, string) {
+	i := strings.LastIndexByte(fqfield, '/')
+	if i < 0 {
+		return fqfield, ""
+	}
+	return fqfield[:i], fqfield[i+1:]
+}
+
+// splitFQID splits a fqid into its collection and its id.
+func splitFQID(fqid string) (string, string) {
+	i := strings.IndexByte(fqid, '/')
+	if i < 0 {
+		return fqid, ""
+	}
+	return fqid[:i], fqid[i+1:]
+}
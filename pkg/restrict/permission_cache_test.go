@@ -0,0 +1,255 @@
+package restrict_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
+)
+
+func TestPermissionCache(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+	motion/1/meeting_id: 1
+	motion/3/meeting_id: 2
+	motion_state/5/meeting_id: 2
+	group/1/meeting_id: 1
+	`))
+
+	keys := []string{"motion/1/title", "motion/3/title"}
+
+	for _, tt := range []struct {
+		name    string
+		changed map[string]json.RawMessage
+		uid     int
+		expect  []string
+	}{
+		{
+			"no change",
+			nil,
+			1,
+			nil,
+		},
+		{
+			"other user",
+			nil,
+			2,
+			keys,
+		},
+		{
+			"changed object",
+			map[string]json.RawMessage{"motion/1/state_id": []byte("2")},
+			1,
+			[]string{"motion/1/title"},
+		},
+		{
+			"changed object in other meeting",
+			map[string]json.RawMessage{"motion_state/5/name": []byte(`"new"`)},
+			1,
+			[]string{"motion/3/title"},
+		},
+		{
+			"new object",
+			map[string]json.RawMessage{"motion_state/9/meeting_id": []byte("1")},
+			1,
+			[]string{"motion/1/title"},
+		},
+		{
+			"changed meeting",
+			map[string]json.RawMessage{"meeting/2/name": []byte(`"new"`)},
+			1,
+			[]string{"motion/3/title"},
+		},
+		{
+			"changed user",
+			map[string]json.RawMessage{"user/1/username": []byte(`"hugo"`)},
+			1,
+			keys,
+		},
+		{
+			"changed other user",
+			map[string]json.RawMessage{"user/2/username": []byte(`"hugo"`)},
+			1,
+			nil,
+		},
+		{
+			"changed group",
+			map[string]json.RawMessage{"group/1/permissions": []byte(`[]`)},
+			1,
+			[]string{"motion/1/title"},
+		},
+		{
+			"changed object without meeting",
+			map[string]json.RawMessage{"committee/1/name": []byte(`"new"`)},
+			1,
+			keys,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			perms := &test.MockPermission{Default: true}
+			cache := restrict.NewPermissionCache(perms, ds, 0)
+
+			if _, err := cache.RestrictFQFields(context.Background(), 1, keys); err != nil {
+				t.Fatalf("RestrictFQFields returned unexpected error: %v", err)
+			}
+
+			if err := cache.Invalidate(tt.changed); err != nil {
+				t.Fatalf("Invalidate returned unexpected error: %v", err)
+			}
+
+			perms.Called = nil
+			got, err := cache.RestrictFQFields(context.Background(), tt.uid, keys)
+			if err != nil {
+				t.Fatalf("RestrictFQFields returned unexpected error: %v", err)
+			}
+
+			for _, key := range keys {
+				if !got[key] {
+					t.Errorf("RestrictFQFields returned false for %s, expected true", key)
+				}
+			}
+
+			if len(perms.Called) != len(tt.expect) {
+				t.Errorf("Permissioner was called with %v, expected %v", perms.Called, tt.expect)
+			}
+			for _, key := range tt.expect {
+				if !perms.Called[key] {
+					t.Errorf("Permissioner was not called with %s", key)
+				}
+			}
+		})
+	}
+}
+
+func TestPermissionCacheMaxSize(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+	motion/1/meeting_id: 1
+	motion/2/meeting_id: 1
+	`))
+	perms := &test.MockPermission{Default: true}
+	cache := restrict.NewPermissionCache(perms, ds, 2)
+
+	for _, keys := range [][]string{
+		{"motion/1/title", "motion/1/text"},
+		{"motion/2/title"},
+	} {
+		if _, err := cache.RestrictFQFields(context.Background(), 1, keys); err != nil {
+			t.Fatalf("RestrictFQFields returned unexpected error: %v", err)
+		}
+	}
+
+	perms.Called = nil
+	if _, err := cache.RestrictFQFields(context.Background(), 1, []string{"motion/1/title", "motion/2/title"}); err != nil {
+		t.Fatalf("RestrictFQFields returned unexpected error: %v", err)
+	}
+
+	if len(perms.Called) != 1 || !perms.Called["motion/1/title"] {
+		t.Errorf("Permissioner was called with %v, expected only motion/1/title", perms.Called)
+	}
+}
+
+func TestPermissionCacheInvalidateUserSize(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+	motion/1/meeting_id: 1
+	`))
+	perms := &test.MockPermission{Default: true}
+	cache := restrict.NewPermissionCache(perms, ds, 2)
+
+	for _, uid := range []int{1, 2} {
+		if _, err := cache.RestrictFQFields(context.Background(), uid, []string{"motion/1/title"}); err != nil {
+			t.Fatalf("RestrictFQFields returned unexpected error: %v", err)
+		}
+	}
+
+	if err := cache.Invalidate(map[string]json.RawMessage{"user/1/username": []byte(`"hugo"`)}); err != nil {
+		t.Fatalf("Invalidate returned unexpected error: %v", err)
+	}
+
+	// The removed result of user 1 makes space for a new one.
+	if _, err := cache.RestrictFQFields(context.Background(), 1, []string{"motion/1/text"}); err != nil {
+		t.Fatalf("RestrictFQFields returned unexpected error: %v", err)
+	}
+
+	perms.Called = nil
+	if _, err := cache.RestrictFQFields(context.Background(), 2, []string{"motion/1/title"}); err != nil {
+		t.Fatalf("RestrictFQFields returned unexpected error: %v", err)
+	}
+
+	if len(perms.Called) != 0 {
+		t.Errorf("Permissioner was called with %v, expected no call", perms.Called)
+	}
+}
+
+// waitingPermer is a Permissioner that allows everything. Its first two calls
+// wait for each other, so they run at the same time.
+type waitingPermer struct {
+	started sync.WaitGroup
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (p *waitingPermer) RestrictFQFields(ctx context.Context, uid int, fqfields []string) (map[string]bool, error) {
+	p.mu.Lock()
+	p.calls++
+	wait := p.calls <= 2
+	p.mu.Unlock()
+
+	if wait {
+		p.started.Done()
+		p.started.Wait()
+	}
+
+	allowed := make(map[string]bool, len(fqfields))
+	for _, fqfield := range fqfields {
+		allowed[fqfield] = true
+	}
+	return allowed, nil
+}
+
+func TestPermissionCacheConcurrentSameKey(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+	motion/1/meeting_id: 1
+	`))
+	permer := new(waitingPermer)
+	permer.started.Add(2)
+	cache := restrict.NewPermissionCache(permer, ds, 2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.RestrictFQFields(context.Background(), 1, []string{"motion/1/title"}); err != nil {
+				t.Errorf("RestrictFQFields returned unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The result was only saved once, so there is space for another one.
+	for _, key := range []string{"motion/1/text", "motion/1/title"} {
+		if _, err := cache.RestrictFQFields(context.Background(), 1, []string{key}); err != nil {
+			t.Fatalf("RestrictFQFields returned unexpected error: %v", err)
+		}
+	}
+
+	if permer.calls != 3 {
+		t.Errorf("Permissioner was called %d times, expected 3", permer.calls)
+	}
+}
@@ -0,0 +1,144 @@
+package restrict
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+)
+
+// PersonalDataFields are the fields of a user (collection/field), that are
+// only visible for the user itself and for users that can manage the user.
+// The password hash is never visible.
+var PersonalDataFields = []string{
+	"user/email",
+	"user/last_login",
+	"user/password",
+	"user/default_password",
+}
+
+// managementLevelsCanManageUsers are the organisation management levels that
+// can see the personal data of all users.
+var managementLevelsCanManageUsers = map[string]bool{
+	OMLSuperadmin:             true,
+	"can_manage_organisation": true,
+	"can_manage_users":        true,
+}
+
+// PersonalDataChecker returns a Checker for the PersonalDataFields.
+//
+// The value is only returned, if the key belongs to the requesting user, if
+// the requesting user has the permission user.can_manage in a meeting of the
+// user of the key or if the requesting user can manage users on the
+// organisation level. The field user/password is never returned.
+func PersonalDataChecker(ds datastore.Getter) Checker {
+	return CheckerFunc(func(ctx context.Context, uid int, key string, value json.RawMessage) (json.RawMessage, error) {
+		parts := strings.Split(key, "/")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid key %s", key)
+		}
+
+		if parts[2] == "password" {
+			return nil, nil
+		}
+
+		if uid != 0 && parts[1] == strconv.Itoa(uid) {
+			return value, nil
+		}
+
+		managed, err := callCached(ctx, fmt.Sprintf("user_manager/%d", uid), func() (interface{}, error) {
+			return loadManagedMeetings(ctx, ds, uid)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("checking user.can_manage: %w", err)
+		}
+
+		canManage, err := managed.(managedMeetings).canManage(ctx, ds, parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("checking meetings of user %s: %w", parts[1], err)
+		}
+
+		if !canManage {
+			return nil, nil
+		}
+		return value, nil
+	})
+}
+
+// managedMeetings are the meetings, where a user can manage users.
+type managedMeetings struct {
+	// all is true, if the user can manage users on the organisation level.
+	all      bool
+	meetings map[string]bool
+}
+
+// loadManagedMeetings returns the meetings, where the user has the permission
+// user.can_manage.
+func loadManagedMeetings(ctx context.Context, ds datastore.Getter, uid int) (managedMeetings, error) {
+	if uid == 0 {
+		return managedMeetings{}, nil
+	}
+
+	var level string
+	if err := getJSON(ctx, ds, fmt.Sprintf("user/%d/organisation_management_level", uid), &level); err != nil {
+		return managedMeetings{}, fmt.Errorf("getting management level: %w", err)
+	}
+
+	if managementLevelsCanManageUsers[level] {
+		return managedMeetings{all: true}, nil
+	}
+
+	var meetingIDs []string
+	if err := getJSON(ctx, ds, fmt.Sprintf("user/%d/group_$_ids", uid), &meetingIDs); err != nil {
+		return managedMeetings{}, fmt.Errorf("getting meetings: %w", err)
+	}
+
+	managed := managedMeetings{meetings: make(map[string]bool)}
+	for _, meetingID := range meetingIDs {
+		var groupIDs []int
+		if err := getJSON(ctx, ds, fmt.Sprintf("user/%d/group_$%s_ids", uid, meetingID), &groupIDs); err != nil {
+			return managedMeetings{}, fmt.Errorf("getting groups in meeting %s: %w", meetingID, err)
+		}
+
+		for _, groupID := range groupIDs {
+			var perms []string
+			if err := getJSON(ctx, ds, fmt.Sprintf("group/%d/permissions", groupID), &perms); err != nil {
+				return managedMeetings{}, fmt.Errorf("getting permissions of group %d: %w", groupID, err)
+			}
+
+			for _, perm := range perms {
+				if perm == "user.can_manage" {
+					managed.meetings[meetingID] = true
+				}
+			}
+		}
+	}
+	return managed, nil
+}
+
+// canManage returns true, if the user with the given id is in one of the
+// managed meetings.
+func (m managedMeetings) canManage(ctx context.Context, ds datastore.Getter, userID string) (bool, error) {
+	if m.all {
+		return true, nil
+	}
+
+	if len(m.meetings) == 0 {
+		return false, nil
+	}
+
+	var meetingIDs []string
+	if err := getJSON(ctx, ds, fmt.Sprintf("user/%s/group_$_ids", userID), &meetingIDs); err != nil {
+		return false, fmt.Errorf("getting meetings: %w", err)
+	}
+
+	for _, meetingID := range meetingIDs {
+		if m.meetings[meetingID] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,143 @@
+package restrict_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
+)
+
+func TestPersonalDataChecker(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+	user:
+		1:
+			email: hugo@example.com
+			group_$_ids: ["1"]
+			group_$1_ids: [1]
+		2:
+			group_$_ids: ["1"]
+			group_$1_ids: [1]
+		3:
+			group_$_ids: ["1"]
+			group_$1_ids: [2]
+		4:
+			organisation_management_level: can_manage_users
+		5:
+			group_$_ids: ["2"]
+			group_$2_ids: [3]
+
+	group:
+		1:
+			permissions: [user.can_see]
+		2:
+			permissions: [user.can_see, user.can_manage]
+		3:
+			permissions: [user.can_see, user.can_manage]
+	`))
+
+	checker := restrict.PersonalDataChecker(ds)
+
+	for _, tt := range []struct {
+		name   string
+		uid    int
+		expect bool
+	}{
+		{"user itself", 1, true},
+		{"normal user", 2, false},
+		{"user manager", 3, true},
+		{"user manager of other meeting", 5, false},
+		{"organisation user manager", 4, true},
+		{"anonymous", 0, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checker.Check(context.Background(), tt.uid, "user/1/email", []byte(`"hugo@example.com"`))
+			if err != nil {
+				t.Fatalf("Check returned unexpected error: %v", err)
+			}
+
+			if (got != nil) != tt.expect {
+				t.Errorf("Check returned `%s`, expected visible: %t", got, tt.expect)
+			}
+		})
+	}
+
+	t.Run("password", func(t *testing.T) {
+		for _, uid := range []int{1, 3, 4} {
+			got, err := checker.Check(context.Background(), uid, "user/1/password", []byte(`"hash"`))
+			if err != nil {
+				t.Fatalf("Check returned unexpected error: %v", err)
+			}
+
+			if got != nil {
+				t.Errorf("Check returned `%s` for user %d, expected nil", got, uid)
+			}
+		}
+	})
+}
+
+// countingGetter counts the requested keys.
+type countingGetter struct {
+	datastore.Getter
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (g *countingGetter) Get(ctx context.Context, keys ...string) ([]json.RawMessage, error) {
+	g.mu.Lock()
+	for _, key := range keys {
+		g.counts[key]++
+	}
+	g.mu.Unlock()
+	return g.Getter.Get(ctx, keys...)
+}
+
+func TestPersonalDataCheckerOncePerRestrict(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := &countingGetter{
+		Getter: dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+		user:
+			1:
+				group_$_ids: ["1"]
+				group_$1_ids: [1]
+			2:
+				email: a@example.com
+				group_$_ids: ["1"]
+			3:
+				email: b@example.com
+				group_$_ids: ["1"]
+
+		group/1/permissions: [user.can_manage]
+		`)),
+		counts: make(map[string]int),
+	}
+
+	personal := restrict.PersonalDataChecker(ds)
+	r := restrict.New(&test.MockPermission{Default: true}, map[string]restrict.Checker{"user/email": personal})
+
+	data := map[string]json.RawMessage{
+		"user/2/email": []byte(`"a@example.com"`),
+		"user/3/email": []byte(`"b@example.com"`),
+	}
+	if err := r.Restrict(context.Background(), 1, data); err != nil {
+		t.Fatalf("Restrict returned unexpected error: %v", err)
+	}
+
+	if data["user/2/email"] == nil || data["user/3/email"] == nil {
+		t.Errorf("Restrict returned %v, expected both emails", data)
+	}
+
+	if got := ds.counts["group/1/permissions"]; got != 1 {
+		t.Errorf("group/1/permissions was requested %d times, expected 1", got)
+	}
+}
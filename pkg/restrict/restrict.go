@@ -0,0 +1,230 @@
+// Package restrict holds the Restricter object that filters data for a specific
+// user.
+package restrict
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+)
+
+// Restricter implements the autoupdate.Restricter interface.
+type Restricter struct {
+	permer Permissioner
+	checks map[string]Checker
+
+	fieldModes   map[string]string
+	modeCheckers map[string]ModeChecker
+
+	superadminDS       datastore.Getter
+	superadminStripped map[string]bool
+}
+
+// New creates an initialized Restricter.
+func New(permer Permissioner, checker map[string]Checker, options ...Option) *Restricter {
+	r := &Restricter{
+		permer: permer,
+		checks: checker,
+	}
+
+	for _, o := range options {
+		o(r)
+	}
+
+	return r
+}
+
+// Restrict filters and manipulates the given data for the user with the given
+// uid.
+//
+// It is not allowed to manipulate a value in the dict. A value can only be
+// replaced with a new value. If the user does not have the permission to see
+// one key, it is not allowed to remove that key, the value has to be set to
+// nil.
+func (r *Restricter) Restrict(ctx context.Context, uid int, data map[string]json.RawMessage) error {
+	return r.restrict(ctx, uid, data, nil)
+}
+
+// Explain restricts the data like Restrict. It returns for each key the rule
+// that decided about the value.
+func (r *Restricter) Explain(ctx context.Context, uid int, data map[string]json.RawMessage) (map[string]string, error) {
+	reasons := make(map[string]string, len(data))
+	if err := r.restrict(ctx, uid, data, reasons); err != nil {
+		return nil, err
+	}
+	return reasons, nil
+}
+
+// restrict restricts the data. If reasons is not nil, the rule that decided
+// about each key is written into it.
+func (r *Restricter) restrict(ctx context.Context, uid int, data map[string]json.RawMessage, reasons map[string]string) error {
+	ctx = context.WithValue(ctx, callCacheKey{}, &callCache{values: make(map[string]interface{})})
+
+	explain := func(key, reason string) {
+		if reasons != nil {
+			reasons[key] = reason
+		}
+	}
+
+	for k, v := range data {
+		if v == nil {
+			explain(k, "does not exist")
+		}
+	}
+
+	superadmin, err := r.isSuperadmin(ctx, uid)
+	if err != nil {
+		return fmt.Errorf("checking superadmin: %w", err)
+	}
+
+	if superadmin {
+		r.restrictSuperadmin(data)
+		for k, v := range data {
+			if _, ok := reasons[k]; ok {
+				continue
+			}
+
+			explain(k, "superadmin")
+			if v == nil {
+				explain(k, "superadmin: stripped field")
+			}
+		}
+		return nil
+	}
+
+	keys := make([]string, 0, len(data))
+	modeKeys := make(map[string]string)
+	for k, v := range data {
+		if v == nil {
+			// If the value is nil, there is no need to check it.
+			continue
+		}
+
+		if idx := r.modeChecker(k); idx != "" {
+			modeKeys[k] = idx
+			continue
+		}
+		keys = append(keys, k)
+	}
+	allowed, err := r.permer.RestrictFQFields(ctx, uid, keys)
+	if err != nil {
+		return fmt.Errorf("check permissions: %w", err)
+	}
+
+	if len(modeKeys) > 0 {
+		allowedModes, err := r.checkModes(ctx, uid, modeKeys)
+		if err != nil {
+			return fmt.Errorf("check restriction modes: %w", err)
+		}
+
+		if allowed == nil {
+			allowed = make(map[string]bool, len(allowedModes))
+		}
+		for k, v := range allowedModes {
+			allowed[k] = v
+		}
+	}
+
+	for k, v := range data {
+		if v == nil {
+			continue
+		}
+
+		rule := "permission"
+		if idx, ok := modeKeys[k]; ok {
+			rule = "mode " + idx
+		}
+
+		if !allowed[k] {
+			explain(k, rule+": denied")
+			data[k] = nil
+			continue
+		}
+
+		idx := checkerIndex(k)
+		checker, ok := r.checks[idx]
+		if !ok {
+			explain(k, rule+": allowed")
+			continue
+		}
+
+		nv, err := checker.Check(ctx, uid, k, v)
+		if err != nil {
+			return fmt.Errorf("checker for key %s: %w", k, err)
+		}
+		data[k] = nv
+
+		switch {
+		case nv == nil:
+			explain(k, "checker "+idx+": denied")
+		case !bytes.Equal(nv, v):
+			explain(k, "checker "+idx+": filtered")
+		default:
+			explain(k, "checker "+idx+": allowed")
+		}
+	}
+	return nil
+}
+
+type callCacheKey struct{}
+
+// callCache holds values, that are computed only once for each call of
+// Restrict.
+type callCache struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+}
+
+// callCached returns the value for the key from the cache of the current call
+// of Restrict. If the value is not cached, fn is called and its result is
+// cached. Outside of Restrict, fn is called each time.
+func callCached(ctx context.Context, key string, fn func() (interface{}, error)) (interface{}, error) {
+	cache, ok := ctx.Value(callCacheKey{}).(*callCache)
+	if !ok {
+		return fn()
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if v, ok := cache.values[key]; ok {
+		return v, nil
+	}
+
+	v, err := fn()
+	if err != nil {
+		return nil, err
+	}
+	cache.values[key] = v
+	return v, nil
+}
+
+func structuredKeys(key string, replecments []string) []string {
+	replaced := make([]string, len(replecments))
+	for i, r := range replecments {
+		replaced[i] = strings.Replace(key, "$", r, 1)
+	}
+	return replaced
+}
+
+// checkerIndex returns the index of the checker list. This is the modelField
+// (fqfield without middle part) or normal fields and template fields
+// (foo/1/prefix_$_bar, foo/1/prefix_$). For fields that are derivated from a
+// template field, this is only the prefix of the field.
+func checkerIndex(fqfield string) string {
+	t := strings.Split(fqfield, "/")
+	modelField := t[0] + "/" + t[2]
+
+	i := strings.IndexByte(modelField, '$')
+	if i < 0 || i == len(modelField)-1 || modelField[i+1] == '_' {
+		// Normal field or $ at the end or $_
+		return modelField
+	}
+
+	return modelField[:i]
+}
@@ -0,0 +1,366 @@
+package restrict_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
+)
+
+func TestRestrict(t *testing.T) {
+	perms := new(test.MockPermission)
+	perms.Data = map[string]bool{
+		"user/1/name":     true,
+		"user/1/password": false,
+	}
+	r := restrict.New(perms, nil)
+	data := map[string]json.RawMessage{
+		"user/1/name":     []byte("uwe"),
+		"user/1/password": []byte("easy"),
+	}
+	if err := r.Restrict(context.Background(), 1, data); err != nil {
+		t.Errorf("Restrict returned unexpected error: %v", err)
+	}
+
+	if got := string(data["user/1/name"]); got != "uwe" {
+		t.Errorf("data[user/1/name] = `%s`, expected `uwe`", got)
+	}
+
+	if got := data["user/1/password"]; got != nil {
+		t.Errorf("data[user/1/password] = `%s`, expected nil", got)
+	}
+}
+
+func TestRestrictDeletedFields(t *testing.T) {
+	perms := new(test.MockPermission)
+	perms.Default = true
+	r := restrict.New(perms, nil)
+	data := map[string]json.RawMessage{
+		"tag/1/name": nil,
+	}
+	if err := r.Restrict(context.Background(), 1, data); err != nil {
+		t.Errorf("Restrict returned unexpected error: %v", err)
+	}
+
+	if got := data["tag/1/name"]; got != nil {
+		t.Errorf("data[tag/1/name] = `%s`, expected nil", got)
+	}
+
+	if len(perms.Called) != 0 {
+		t.Errorf("Permission api was caled")
+	}
+
+}
+
+func TestChecker(t *testing.T) {
+	perms := new(test.MockPermission)
+	perms.Data = map[string]bool{
+		"user/1/name":       true,
+		"user/1/password":   false,
+		"user/1/first_name": true,
+	}
+
+	called := make(map[string]bool)
+	checker := map[string]restrict.Checker{
+		"user/name": restrict.CheckerFunc(func(ctx context.Context, uid int, key string, value json.RawMessage) (json.RawMessage, error) {
+			called[key] = true
+			return []byte("touched"), nil
+		}),
+		"user/password": restrict.CheckerFunc(func(ctx context.Context, uid int, key string, value json.RawMessage) (json.RawMessage, error) {
+			called[key] = true
+			return []byte("touched"), nil
+		}),
+		"user/first_name": restrict.CheckerFunc(func(ctx context.Context, uid int, key string, value json.RawMessage) (json.RawMessage, error) {
+			called[key] = true
+			return []byte("touched"), nil
+		}),
+	}
+
+	r := restrict.New(perms, checker)
+	data := map[string]json.RawMessage{
+		"user/1/name":       []byte("uwe"),
+		"user/1/password":   []byte("easy"),
+		"user/1/first_name": nil,
+	}
+	if err := r.Restrict(context.Background(), 1, data); err != nil {
+		t.Errorf("Restrict returned unexpected error: %v", err)
+	}
+
+	if got := string(data["user/1/name"]); got != "touched" {
+		t.Errorf("data[user/1/name] = `%s`, expected `touched`", got)
+	}
+
+	if got := data["user/1/password"]; got != nil {
+		t.Errorf("data[user/1/password] = `%s`, expected nil", got)
+	}
+
+	if !called["user/1/name"] {
+		t.Errorf("checker for key user/1/name was not called")
+	}
+
+	if called["user/1/password"] {
+		t.Errorf("checker for key user/1/password was called")
+	}
+
+	if called["user/1/first_name"] {
+		t.Errorf("checker for key user/1/first_name was called")
+	}
+}
+
+func TestRestrictModes(t *testing.T) {
+	perms := new(test.MockPermission)
+	perms.Default = true
+
+	var calledWith []string
+	modeC := restrict.ModeCheckerFunc(func(ctx context.Context, uid int, fqids []string) (map[string]bool, error) {
+		calledWith = append(calledWith, fqids...)
+		return map[string]bool{"motion/1": true}, nil
+	})
+
+	r := restrict.New(
+		perms,
+		nil,
+		restrict.WithModes(
+			map[string]string{
+				"motion/title":                 "A",
+				"motion/amendment_paragraph_$": "C",
+			},
+			map[string]restrict.ModeChecker{
+				"motion/C": modeC,
+			},
+		),
+	)
+
+	data := map[string]json.RawMessage{
+		"motion/1/title":                  []byte(`"title"`),
+		"motion/1/amendment_paragraph_$":  []byte(`["1"]`),
+		"motion/1/amendment_paragraph_$1": []byte(`"text"`),
+		"motion/2/amendment_paragraph_$":  []byte(`["1"]`),
+	}
+	if err := r.Restrict(context.Background(), 1, data); err != nil {
+		t.Fatalf("Restrict returned unexpected error: %v", err)
+	}
+
+	if got := string(data["motion/1/title"]); got != `"title"` {
+		t.Errorf("data[motion/1/title] = `%s`, expected `\"title\"`", got)
+	}
+
+	if got := string(data["motion/1/amendment_paragraph_$1"]); got != `"text"` {
+		t.Errorf("data[motion/1/amendment_paragraph_$1] = `%s`, expected `\"text\"`", got)
+	}
+
+	if got := data["motion/2/amendment_paragraph_$"]; got != nil {
+		t.Errorf("data[motion/2/amendment_paragraph_$] = `%s`, expected nil", got)
+	}
+
+	if len(calledWith) != 2 {
+		t.Errorf("Mode checker was called with %v, expected each fqid once", calledWith)
+	}
+
+	if perms.Called["motion/1/amendment_paragraph_$1"] {
+		t.Errorf("Permissioner was called for a key with a restriction mode")
+	}
+
+	if !perms.Called["motion/1/title"] {
+		t.Errorf("Permissioner was not called for a key without a mode checker")
+	}
+}
+
+func TestPermissionModeCheckers(t *testing.T) {
+	perms := new(test.MockPermission)
+	perms.Data = map[string]bool{
+		"user/1/id":       true,
+		"user/1/email":    false,
+		"user/2/id":       false,
+		"user/2/username": true,
+	}
+
+	modes := map[string]string{
+		"user/id":          "A",
+		"user/username":    "A",
+		"user/group_$_ids": "A",
+		"user/email":       "B",
+		"user/last_login":  "B",
+	}
+	r := restrict.New(perms, nil, restrict.WithModes(modes, restrict.PermissionModeCheckers(modes, perms)))
+
+	data := map[string]json.RawMessage{
+		"user/1/username":    []byte(`"hugo"`),
+		"user/1/group_$_ids": []byte(`["1"]`),
+		"user/1/last_login":  []byte(`1`),
+		"user/2/username":    []byte(`"uwe"`),
+	}
+	if err := r.Restrict(context.Background(), 1, data); err != nil {
+		t.Fatalf("Restrict returned unexpected error: %v", err)
+	}
+
+	expect := map[string]bool{
+		"user/1/username":    true,
+		"user/1/group_$_ids": true,
+		"user/1/last_login":  false,
+		"user/2/username":    false,
+	}
+	for key, visible := range expect {
+		if (data[key] != nil) != visible {
+			t.Errorf("data[%s] = `%s`, expected visible: %t", key, data[key], visible)
+		}
+	}
+
+	for _, key := range []string{"user/1/username", "user/1/group_$_ids", "user/2/username"} {
+		if perms.Called[key] {
+			t.Errorf("Permissioner was called for %s, expected only the field of the mode", key)
+		}
+	}
+}
+
+func TestRestrictSuperadmin(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, map[string]string{
+		"user/1/organisation_management_level": `"superadmin"`,
+		"user/2/organisation_management_level": `"can_manage_users"`,
+	})
+
+	perms := &test.MockPermission{Default: false}
+	r := restrict.New(perms, nil, restrict.WithSuperadmin(ds, "user/password"))
+
+	for _, tt := range []struct {
+		name       string
+		uid        int
+		expectName string
+	}{
+		{"superadmin", 1, `"hugo"`},
+		{"other level", 2, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			data := map[string]json.RawMessage{
+				"user/5/username": []byte(`"hugo"`),
+				"user/5/password": []byte(`"hash"`),
+			}
+
+			if err := r.Restrict(context.Background(), tt.uid, data); err != nil {
+				t.Fatalf("Restrict returned unexpected error: %v", err)
+			}
+
+			if got := string(data["user/5/username"]); got != tt.expectName {
+				t.Errorf("data[user/5/username] = `%s`, expected `%s`", got, tt.expectName)
+			}
+
+			if got := data["user/5/password"]; got != nil {
+				t.Errorf("data[user/5/password] = `%s`, expected nil", got)
+			}
+		})
+	}
+}
+
+func TestRestrictBlocked(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, map[string]string{
+		"user/1/organisation_management_level": `"superadmin"`,
+	})
+
+	perms := &test.MockPermission{Default: true}
+	relationLists := map[string]string{
+		"meeting/chat_group_ids": "chat_group",
+		"meeting/motion_ids":     "motion",
+		"tag/tagged_ids":         "*",
+		"user/group_$_ids":       "group",
+	}
+	r := restrict.NewChain(
+		restrict.New(perms, nil, restrict.WithSuperadmin(ds)),
+		restrict.NewBlocked(relationLists, "chat_group", "motion/reason", "user/group_$_ids"),
+	)
+
+	for _, tt := range []struct {
+		name string
+		uid  int
+	}{
+		{"normal user", 2},
+		{"superadmin", 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			data := map[string]json.RawMessage{
+				"chat_group/1/name":              []byte(`"chat"`),
+				"motion/1/reason":                []byte(`"reason"`),
+				"motion/1/title":                 []byte(`"title"`),
+				"user/1/group_$_ids":             []byte(`["1"]`),
+				"user/1/group_$1_ids":            []byte(`[1]`),
+				"projection/1/content_object_id": []byte(`"chat_group/1"`),
+				"projection/2/content_object_id": []byte(`"motion/1"`),
+				"meeting/1/chat_group_ids":       []byte(`[1,2]`),
+				"meeting/1/motion_ids":           []byte(`[1]`),
+				"tag/1/tagged_ids":               []byte(`["chat_group/1","motion/1"]`),
+			}
+
+			reasons, err := r.Explain(context.Background(), tt.uid, data)
+			if err != nil {
+				t.Fatalf("Explain returned unexpected error: %v", err)
+			}
+
+			for _, key := range []string{"chat_group/1/name", "motion/1/reason", "user/1/group_$_ids", "user/1/group_$1_ids", "projection/1/content_object_id"} {
+				if data[key] != nil {
+					t.Errorf("data[%s] = `%s`, expected nil", key, data[key])
+				}
+
+				if reasons[key] != "blocked" {
+					t.Errorf("reason for %s is %q, expected blocked", key, reasons[key])
+				}
+			}
+
+			for key, expect := range map[string]string{
+				"motion/1/title":                 `"title"`,
+				"projection/2/content_object_id": `"motion/1"`,
+				"meeting/1/chat_group_ids":       `[]`,
+				"meeting/1/motion_ids":           `[1]`,
+				"tag/1/tagged_ids":               `["motion/1"]`,
+			} {
+				if got := string(data[key]); got != expect {
+					t.Errorf("data[%s] = `%s`, expected `%s`", key, got, expect)
+				}
+			}
+		})
+	}
+}
+
+func TestExplain(t *testing.T) {
+	perms := &test.MockPermission{Data: map[string]bool{
+		"motion/1/title":   true,
+		"motion/2/title":   false,
+		"group/1/user_ids": true,
+	}}
+	r := restrict.New(perms, restrict.RelationChecker(restrict.RelationLists, perms))
+
+	data := map[string]json.RawMessage{
+		"motion/1/title":   []byte(`"title"`),
+		"motion/2/title":   []byte(`"title"`),
+		"motion/3/title":   nil,
+		"group/1/user_ids": []byte(`[1]`),
+	}
+
+	got, err := r.Explain(context.Background(), 1, data)
+	if err != nil {
+		t.Fatalf("Explain returned unexpected error: %v", err)
+	}
+
+	expect := map[string]string{
+		"motion/1/title":   "permission: allowed",
+		"motion/2/title":   "permission: denied",
+		"motion/3/title":   "does not exist",
+		"group/1/user_ids": "checker group/user_ids: filtered",
+	}
+	for k, v := range expect {
+		if got[k] != v {
+			t.Errorf("Explain for %s returned `%s`, expected `%s`", k, got[k], v)
+		}
+	}
+
+	if data["motion/2/title"] != nil {
+		t.Errorf("Explain did not restrict motion/2/title")
+	}
+}
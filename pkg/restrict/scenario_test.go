@@ -0,0 +1,183 @@
+package restrict_test
+
+import (
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/restricttest"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
+)
+
+// scenarioData is the datastore for the permission scenarios. The user 1 is
+// used by the scenarios with generated groups. The user 2 is admin in meeting
+// 1.
+const scenarioData = `
+meeting:
+	1:
+		admin_group_id: 1
+		default_group_id: 2
+		motion_ids: [1, 2]
+		enable_anonymous: true
+	2:
+		admin_group_id: 3
+		motion_ids: [3]
+
+group:
+	1:
+		meeting_id: 1
+	2:
+		meeting_id: 1
+		permissions: [motion.can_see, mediafile.can_see, agenda_item.can_see]
+	3:
+		meeting_id: 2
+
+motion:
+	1:
+		meeting_id: 1
+		title: first
+		tag_ids: [1, 2]
+		state_id: 1
+	2:
+		meeting_id: 1
+		title: second
+		state_id: 2
+	3:
+		meeting_id: 2
+		title: third
+
+motion_state/1/restrictions: []
+motion_state/2/restrictions: [motion.can_manage]
+
+mediafile:
+	1:
+		meeting_id: 1
+		title: public
+		is_public: true
+	2:
+		meeting_id: 1
+		title: admins only
+		is_public: false
+		access_group_ids: [1]
+		inherited_access_group_ids: [1]
+
+agenda_item:
+	1:
+		meeting_id: 1
+		item_number: "1"
+	2:
+		meeting_id: 1
+		item_number: "2"
+		is_hidden: true
+
+tag:
+	1:
+		meeting_id: 1
+		name: one
+	2:
+		meeting_id: 2
+		name: two
+
+user:
+	1:
+		username: tester
+	2:
+		username: admin
+		email: admin@example.com
+		group_$_ids: ["1"]
+		group_$1_ids: [1]
+	3:
+		username: manager
+		email: manager@example.com
+		organisation_management_level: superadmin
+		password: secret
+	4:
+		username: member
+		email: member@example.com
+		group_$_ids: ["1"]
+		group_$1_ids: [2]
+`
+
+func newScenarioRestricter(ds datastore.Getter) restricttest.Restricter {
+	perms := restrict.NewAnonymous(restricttest.NewPermission(ds, restricttest.RequiredPermissions), ds, restrict.RestrictionModes)
+
+	checker := restrict.RelationChecker(restrict.RelationLists, perms)
+	personal := restrict.PersonalDataChecker(ds)
+	for _, field := range restrict.PersonalDataFields {
+		checker[field] = personal
+	}
+
+	return restrict.New(perms, checker, restrict.WithSuperadmin(ds, restrict.SuperadminStrippedFields...))
+}
+
+func TestScenarios(t *testing.T) {
+	restricttest.Run(t, []restricttest.Scenario{
+		{
+			Name:        "can see",
+			UID:         1,
+			Permissions: map[int][]string{1: {"motion.can_see"}},
+			Visible:     []string{"motion/1/title", "meeting/1/motion_ids"},
+			Hidden:      []string{"motion/3/title", "tag/2/name"},
+		},
+		{
+			Name:        "can manage implies can see",
+			UID:         1,
+			Permissions: map[int][]string{1: {"motion.can_manage"}},
+			Visible:     []string{"motion/1/title"},
+		},
+		{
+			Name:    "no permission",
+			UID:     1,
+			Hidden:  []string{"motion/1/title", "meeting/1/motion_ids", "user/2/username"},
+			Visible: []string{"user/1/username"},
+		},
+		{
+			Name:        "no permission in meeting",
+			UID:         1,
+			Permissions: map[int][]string{2: {"motion.can_see"}},
+			Visible:     []string{"motion/3/title"},
+			Hidden:      []string{"motion/1/title"},
+		},
+		{
+			Name:        "permission in many meetings",
+			UID:         1,
+			Permissions: map[int][]string{1: {"motion.can_see"}, 2: {"motion.can_see"}},
+			Visible:     []string{"motion/1/title", "motion/3/title"},
+		},
+		{
+			Name:        "filtered relation-list",
+			UID:         1,
+			Permissions: map[int][]string{1: {"motion.can_see"}},
+			Values:      map[string]string{"motion/1/tag_ids": "[1]"},
+		},
+		{
+			Name:    "group from data",
+			UID:     4,
+			Visible: []string{"motion/1/title", "user/4/email"},
+			Hidden:  []string{"motion/3/title", "user/2/email"},
+		},
+		{
+			Name:    "meeting admin",
+			UID:     2,
+			Visible: []string{"motion/1/title", "tag/1/name", "user/4/username"},
+			Hidden:  []string{"motion/3/title"},
+		},
+		{
+			Name:    "superadmin",
+			UID:     3,
+			Visible: []string{"motion/1/title", "motion/3/title", "user/2/email"},
+			Hidden:  []string{"user/3/password"},
+		},
+		{
+			Name:    "anonymous",
+			UID:     0,
+			Visible: []string{"motion/1/title", "meeting/1/motion_ids", "mediafile/1/title", "agenda_item/1/item_number"},
+			Hidden:  []string{"motion/3/title", "user/4/username"},
+		},
+		{
+			Name:   "anonymous restricted objects",
+			UID:    0,
+			Hidden: []string{"motion/2/title", "mediafile/2/title", "agenda_item/2/item_number"},
+			Values: map[string]string{"meeting/1/motion_ids": "[1]"},
+		},
+	}, newScenarioRestricter, restricttest.WithData(scenarioData))
+}
@@ -0,0 +1,279 @@
+package restrict
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/models"
+)
+
+// SharedCollections are the collections, whose restricted values only depend
+// on the groups and the organisation management level of a user.
+//
+// Other collections have rules for single users, for example for the
+// submitters of a motion, the speakers or the owner of a personal note. Their
+// values can not be shared.
+var SharedCollections = []string{
+	"agenda_item",
+	"group",
+	"list_of_speakers",
+	"mediafile",
+	"motion_block",
+	"motion_category",
+	"motion_comment_section",
+	"motion_state",
+	"motion_statute_paragraph",
+	"motion_workflow",
+	"projection",
+	"projector",
+	"projector_countdown",
+	"projector_message",
+	"tag",
+	"topic",
+}
+
+// Fingerprinter returns a permission fingerprint for a user. Users with the
+// same fingerprint have to see the same restricted values for all keys of the
+// shared collections.
+//
+// An empty fingerprint means, that the restricted values of this user can
+// not be shared.
+type Fingerprinter interface {
+	Fingerprint(ctx context.Context, uid int) (string, error)
+}
+
+// FingerprinterFunc is a function that implements the Fingerprinter
+// interface.
+type FingerprinterFunc func(ctx context.Context, uid int) (string, error)
+
+// Fingerprint calls the function.
+func (f FingerprinterFunc) Fingerprint(ctx context.Context, uid int) (string, error) {
+	return f(ctx, uid)
+}
+
+// GroupFingerprint returns a Fingerprinter that uses the organisation
+// management level and the groups of a user as fingerprint.
+func GroupFingerprint(ds datastore.Getter) Fingerprinter {
+	return FingerprinterFunc(func(ctx context.Context, uid int) (string, error) {
+		if uid == 0 {
+			return "anonymous", nil
+		}
+
+		var level string
+		if err := getJSON(ctx, ds, fmt.Sprintf("user/%d/organisation_management_level", uid), &level); err != nil {
+			return "", fmt.Errorf("getting management level of user %d: %w", uid, err)
+		}
+		prefix := "oml:" + level + ";groups:"
+
+		var meetingIDs []string
+		if err := getJSON(ctx, ds, fmt.Sprintf("user/%d/group_$_ids", uid), &meetingIDs); err != nil {
+			return "", fmt.Errorf("getting meetings of user %d: %w", uid, err)
+		}
+
+		if len(meetingIDs) == 0 {
+			return prefix, nil
+		}
+
+		keys := make([]string, len(meetingIDs))
+		for i, id := range meetingIDs {
+			keys[i] = fmt.Sprintf("user/%d/group_$%s_ids", uid, id)
+		}
+
+		values, err := ds.Get(ctx, keys...)
+		if err != nil {
+			return "", fmt.Errorf("getting groups of user %d: %w", uid, err)
+		}
+
+		var groupIDs []int
+		for i, value := range values {
+			if value == nil {
+				continue
+			}
+
+			var ids []int
+			if err := json.Unmarshal(value, &ids); err != nil {
+				return "", fmt.Errorf("decoding %s: %w", keys[i], err)
+			}
+			groupIDs = append(groupIDs, ids...)
+		}
+		sort.Ints(groupIDs)
+
+		parts := make([]string, len(groupIDs))
+		for i, id := range groupIDs {
+			parts[i] = strconv.Itoa(id)
+		}
+		return prefix + strings.Join(parts, ","), nil
+	})
+}
+
+// getJSON decodes the value of a key. If the key does not exist, value is
+// not changed.
+func getJSON(ctx context.Context, ds datastore.Getter, key string, value interface{}) error {
+	values, err := ds.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("getting %s: %w", key, err)
+	}
+
+	if values[0] == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(values[0], value); err != nil {
+		return fmt.Errorf("decoding %s: %w", key, err)
+	}
+	return nil
+}
+
+// sharedValue is a restricted value together with the value it was created
+// from.
+type sharedValue struct {
+	original   json.RawMessage
+	restricted json.RawMessage
+}
+
+// Shared is a restricter that shares restricted values between all users
+// with the same permission fingerprint. Each value is only restricted once
+// per fingerprint.
+//
+// Only keys of the given collections are shared. A relation list is only
+// shared, if it points to one of these collections, because its restricted
+// value depends on the visibility of the related objects. All other keys are
+// restricted for each user.
+//
+// The shared values have to be removed on each datastore update by calling
+// Invalidate.
+type Shared struct {
+	restricter    DataRestricter
+	fingerprinter Fingerprinter
+	relationLists map[string]string
+	collections   map[string]bool
+
+	mu         sync.RWMutex
+	data       map[string]map[string]sharedValue
+	generation uint64
+}
+
+// NewShared initializes a Shared restricter.
+//
+// relationLists are the relation lists of the models like RelationLists.
+// collections is the list of collections, whose restricted values only depend
+// on the fingerprint, usually SharedCollections.
+func NewShared(r DataRestricter, f Fingerprinter, relationLists map[string]string, collections ...string) *Shared {
+	s := &Shared{
+		restricter:    r,
+		fingerprinter: f,
+		relationLists: relationLists,
+		collections:   make(map[string]bool, len(collections)),
+		data:          make(map[string]map[string]sharedValue),
+	}
+
+	for _, collection := range collections {
+		s.collections[collection] = true
+	}
+	return s
+}
+
+// Restrict implements the autoupdate.Restricter interface.
+func (s *Shared) Restrict(ctx context.Context, uid int, data map[string]json.RawMessage) error {
+	fingerprint, err := s.fingerprinter.Fingerprint(ctx, uid)
+	if err != nil {
+		return fmt.Errorf("getting fingerprint for user %d: %w", uid, err)
+	}
+
+	if fingerprint == "" {
+		return s.restricter.Restrict(ctx, uid, data)
+	}
+
+	missing := make(map[string]json.RawMessage)
+
+	s.mu.RLock()
+	generation := s.generation
+	shared := s.data[fingerprint]
+	for k, v := range data {
+		if v == nil {
+			continue
+		}
+
+		if cached, ok := shared[k]; ok && s.isShared(k) && bytes.Equal(cached.original, v) {
+			data[k] = cached.restricted
+			continue
+		}
+		missing[k] = v
+	}
+	s.mu.RUnlock()
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	original := make(map[string]json.RawMessage, len(missing))
+	for k, v := range missing {
+		original[k] = v
+	}
+
+	if err := s.restricter.Restrict(ctx, uid, missing); err != nil {
+		return fmt.Errorf("restrict missing values: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	save := s.generation == generation
+	if save && s.data[fingerprint] == nil {
+		s.data[fingerprint] = make(map[string]sharedValue)
+	}
+
+	for k, v := range missing {
+		data[k] = v
+
+		if !save || !s.isShared(k) {
+			continue
+		}
+		s.data[fingerprint][k] = sharedValue{original: original[k], restricted: v}
+	}
+	return nil
+}
+
+// Explain calls Explain of the underlying restricter. The shared values are
+// not used.
+func (s *Shared) Explain(ctx context.Context, uid int, data map[string]json.RawMessage) (map[string]string, error) {
+	e, ok := s.restricter.(explainer)
+	if !ok {
+		return nil, fmt.Errorf("restricter %T does not support explain", s.restricter)
+	}
+	return e.Explain(ctx, uid, data)
+}
+
+// Invalidate removes all shared values.
+//
+// Invalidate can be used as a datastore change listener.
+func (s *Shared) Invalidate(map[string]json.RawMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generation++
+	s.data = make(map[string]map[string]sharedValue)
+	return nil
+}
+
+// isShared returns true, if the restricted value of the key only depends on
+// the fingerprint.
+func (s *Shared) isShared(key string) bool {
+	parts := strings.SplitN(key, "/", 3)
+	if len(parts) != 3 || !s.collections[parts[0]] {
+		return false
+	}
+
+	target, ok := s.relationLists[parts[0]+"/"+models.TemplateName(parts[2])]
+	if !ok {
+		return true
+	}
+	return s.collections[target]
+}
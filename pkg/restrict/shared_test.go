@@ -0,0 +1,97 @@
+package restrict_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
+)
+
+// countRestricter counts how often each key was restricted.
+type countRestricter struct {
+	called map[string]int
+}
+
+func (r *countRestricter) Restrict(ctx context.Context, uid int, data map[string]json.RawMessage) error {
+	for k, v := range data {
+		if v == nil {
+			continue
+		}
+		r.called[k]++
+		data[k] = []byte(`"restricted"`)
+	}
+	return nil
+}
+
+func TestShared(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, map[string]string{
+		"user/1/group_$_ids":  `["1"]`,
+		"user/1/group_$1_ids": `[2]`,
+		"user/2/group_$_ids":  `["1"]`,
+		"user/2/group_$1_ids": `[2]`,
+		"user/3/group_$_ids":  `["1"]`,
+		"user/3/group_$1_ids": `[3]`,
+	})
+
+	counter := &countRestricter{called: make(map[string]int)}
+	shared := restrict.NewShared(counter, restrict.GroupFingerprint(ds), restrict.RelationLists, "motion", "tag")
+
+	restrictFor := func(uid int, value string) {
+		t.Helper()
+
+		data := map[string]json.RawMessage{
+			"motion/1/title":         []byte(value),
+			"motion/1/tag_ids":       []byte(`[1]`),
+			"motion/1/submitter_ids": []byte(`[1]`),
+			"user/1/name":            []byte(`"hugo"`),
+		}
+		if err := shared.Restrict(context.Background(), uid, data); err != nil {
+			t.Fatalf("Restrict returned unexpected error: %v", err)
+		}
+
+		for k, v := range data {
+			if string(v) != `"restricted"` {
+				t.Errorf("data[%s] = `%s`, expected `\"restricted\"`", k, v)
+			}
+		}
+	}
+
+	for _, tt := range []struct {
+		name        string
+		uid         int
+		value       string
+		invalidate  bool
+		expectTitle int
+	}{
+		{"first user", 1, `"title"`, false, 1},
+		{"same groups", 2, `"title"`, false, 1},
+		{"other groups", 3, `"title"`, false, 2},
+		{"changed value", 2, `"new title"`, false, 3},
+		{"invalidated", 2, `"new title"`, true, 4},
+	} {
+		if tt.invalidate {
+			shared.Invalidate(nil)
+		}
+
+		restrictFor(tt.uid, tt.value)
+
+		if got := counter.called["motion/1/title"]; got != tt.expectTitle {
+			t.Errorf("%s: motion/1/title was restricted %d times, expected %d", tt.name, got, tt.expectTitle)
+		}
+	}
+
+	if got := counter.called["motion/1/tag_ids"]; got != 3 {
+		t.Errorf("Shared relation list motion/1/tag_ids was restricted %d times, expected 3", got)
+	}
+
+	for _, key := range []string{"user/1/name", "motion/1/submitter_ids"} {
+		if got := counter.called[key]; got != 5 {
+			t.Errorf("Key %s, that is not shared, was restricted %d times, expected 5", key, got)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package restrict
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/models"
+)
+
+// OMLSuperadmin is the organisation management level of a superadmin.
+const OMLSuperadmin = "superadmin"
+
+// SuperadminStrippedFields are the fields (collection/field), that are removed
+// even for superadmins.
+var SuperadminStrippedFields = []string{
+	"user/password",
+}
+
+// WithSuperadmin lets superadmins see all keys without asking the
+// Permissioner or the Checkers.
+//
+// A user is a superadmin, if the field user/organisation_management_level is
+// "superadmin". The given fields (collection/field) are removed for
+// superadmins anyway.
+func WithSuperadmin(ds datastore.Getter, strippedFields ...string) Option {
+	return func(r *Restricter) {
+		r.superadminDS = ds
+		r.superadminStripped = make(map[string]bool, len(strippedFields))
+		for _, field := range strippedFields {
+			r.superadminStripped[field] = true
+		}
+	}
+}
+
+// isSuperadmin returns true, if the user has the organisation management level
+// superadmin.
+func (r *Restricter) isSuperadmin(ctx context.Context, uid int) (bool, error) {
+	if r.superadminDS == nil || uid == 0 {
+		return false, nil
+	}
+
+	key := fmt.Sprintf("user/%d/organisation_management_level", uid)
+	values, err := r.superadminDS.Get(ctx, key)
+	if err != nil {
+		return false, fmt.Errorf("getting %s: %w", key, err)
+	}
+
+	if values[0] == nil {
+		return false, nil
+	}
+
+	var level string
+	if err := json.Unmarshal(values[0], &level); err != nil {
+		return false, fmt.Errorf("decoding %s: %w", key, err)
+	}
+	return level == OMLSuperadmin, nil
+}
+
+// restrictSuperadmin removes the stripped fields from the data.
+func (r *Restricter) restrictSuperadmin(data map[string]json.RawMessage) {
+	for k := range data {
+		t := strings.Split(k, "/")
+		if len(t) != 3 {
+			continue
+		}
+
+		if r.superadminStripped[t[0]+"/"+models.TemplateName(t[2])] {
+			data[k] = nil
+		}
+	}
+}
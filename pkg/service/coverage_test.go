@@ -0,0 +1,358 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/avatar"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/calllist"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/projector"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/restricttest"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/usercount"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/vote"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
+)
+
+// notVisible is the permission of objects, that can not be seen with
+// restricttest.RequiredPermissions. A fixture with this permission expects
+// the restricted value with and without permission.
+const notVisible = "-"
+
+// fixtureUser is the user, that requests the fixtures. The objects in the
+// fixtures belong to the meeting 1.
+const fixtureUser = 9
+
+// checkerFixture tests one checker with a real permission. The value of the
+// key is checked twice. Once for a user with the permission in meeting 1 and
+// once for a user without any permission. An empty expect or denied means,
+// that the value is removed.
+type checkerFixture struct {
+	key    string
+	perm   string
+	value  string
+	expect string
+	denied string
+}
+
+// relationFixture returns a fixture for a relation list, that points to the
+// object with the id 1.
+func relationFixture(key, perm string) checkerFixture {
+	f := checkerFixture{key: key, perm: perm, value: `[1]`, expect: `[1]`, denied: `[]`}
+	if perm == notVisible {
+		f.expect = `[]`
+	}
+	return f
+}
+
+// checkerFixtures are the fixtures for the checkers of the Restricter.
+// TestCheckerCoverage fails, if a collection and permission of the relation
+// lists or a calculated field has no fixture.
+var checkerFixtures = []checkerFixture{
+	relationFixture("agenda_item/1/tag_ids", ""),
+	relationFixture("agenda_item/1/child_ids", "agenda_item.can_see"),
+	relationFixture("agenda_item/1/projection_ids", "projector.can_see"),
+	relationFixture("assignment/1/tag_ids", ""),
+	relationFixture("assignment/1/poll_ids", notVisible),
+	relationFixture("assignment/1/candidate_ids", "assignment.can_see"),
+	relationFixture("assignment/1/attachment_ids", "mediafile.can_see"),
+	relationFixture("assignment/1/projection_ids", "projector.can_see"),
+	relationFixture("committee/1/meeting_ids", ""),
+	relationFixture("committee/1/forward_to_committee_ids", notVisible),
+	relationFixture("committee/1/member_ids", "user.can_see"),
+	relationFixture("group/1/poll_ids", notVisible),
+	relationFixture("group/1/mediafile_access_group_ids", "mediafile.can_see"),
+	relationFixture("group/1/read_comment_section_ids", "motion.can_see"),
+	relationFixture("group/1/user_ids", "user.can_see"),
+	relationFixture("list_of_speakers/1/speaker_ids", "list_of_speakers.can_see"),
+	relationFixture("list_of_speakers/1/projection_ids", "projector.can_see"),
+	relationFixture("mediafile/1/access_group_ids", ""),
+	{"mediafile/1/attachment_ids", "motion.can_see", `["motion/1"]`, `["motion/1"]`, `[]`},
+	relationFixture("mediafile/1/child_ids", "mediafile.can_see"),
+	relationFixture("mediafile/1/projection_ids", "projector.can_see"),
+	relationFixture("meeting/1/tag_ids", ""),
+	relationFixture("meeting/1/poll_ids", notVisible),
+	relationFixture("meeting/1/topic_ids", "agenda_item.can_see"),
+	relationFixture("meeting/1/assignment_ids", "assignment.can_see"),
+	relationFixture("meeting/1/speaker_ids", "list_of_speakers.can_see"),
+	relationFixture("meeting/1/mediafile_ids", "mediafile.can_see"),
+	relationFixture("meeting/1/motion_ids", "motion.can_see"),
+	relationFixture("meeting/1/projector_ids", "projector.can_see"),
+	relationFixture("meeting/1/guest_ids", "user.can_see"),
+	relationFixture("meeting_user/1/group_ids", ""),
+	relationFixture("meeting_user/1/vote_delegations_from_ids", "user.can_see"),
+	relationFixture("meeting_user/1/speaker_ids", "list_of_speakers.can_see"),
+	relationFixture("meeting_user/1/supported_motion_ids", "motion.can_see"),
+	relationFixture("motion/1/tag_ids", ""),
+	{"motion/1/recommendation_extension_reference_ids", "motion.can_see", `["motion/1"]`, `["motion/1"]`, `[]`},
+	relationFixture("motion/1/poll_ids", notVisible),
+	relationFixture("motion/1/attachment_ids", "mediafile.can_see"),
+	relationFixture("motion/1/comment_ids", "motion.can_see"),
+	relationFixture("motion/1/projection_ids", "projector.can_see"),
+	relationFixture("motion/1/supporter_ids", "user.can_see"),
+	relationFixture("motion_block/1/motion_ids", "motion.can_see"),
+	relationFixture("motion_block/1/projection_ids", "projector.can_see"),
+	relationFixture("motion_category/1/child_ids", "motion.can_see"),
+	relationFixture("motion_comment_section/1/read_group_ids", ""),
+	relationFixture("motion_comment_section/1/comment_ids", "motion.can_see"),
+	relationFixture("motion_state/1/motion_ids", "motion.can_see"),
+	relationFixture("motion_statute_paragraph/1/motion_ids", "motion.can_see"),
+	relationFixture("motion_workflow/1/state_ids", "motion.can_see"),
+	relationFixture("option/1/vote_ids", notVisible),
+	relationFixture("organisation/1/role_ids", notVisible),
+	relationFixture("poll/1/entitled_group_ids", ""),
+	relationFixture("poll/1/option_ids", notVisible),
+	relationFixture("poll/1/projection_ids", "projector.can_see"),
+	relationFixture("poll/1/voted_ids", "user.can_see"),
+	{"projector/1/current_element_ids", "motion.can_see", `["motion/1"]`, `["motion/1"]`, `[]`},
+	relationFixture("projector/1/projectiondefault_ids", notVisible),
+	relationFixture("projector/1/current_projection_ids", "projector.can_see"),
+	relationFixture("projector_countdown/1/projection_ids", "projector.can_see"),
+	relationFixture("projector_message/1/projection_ids", "projector.can_see"),
+	relationFixture("role/1/user_ids", "user.can_see"),
+	{"tag/1/tagged_ids", "motion.can_see", `["motion/1"]`, `["motion/1"]`, `[]`},
+	relationFixture("topic/1/tag_ids", ""),
+	relationFixture("topic/1/option_ids", notVisible),
+	relationFixture("topic/1/attachment_ids", "mediafile.can_see"),
+	relationFixture("topic/1/projection_ids", "projector.can_see"),
+	relationFixture("user/1/group_$1_ids", ""),
+	relationFixture("user/1/vote_$1_ids", notVisible),
+	relationFixture("user/1/assignment_candidate_$1_ids", "assignment.can_see"),
+	relationFixture("user/1/speaker_$1_ids", "list_of_speakers.can_see"),
+	relationFixture("user/1/submitted_motion_$1_ids", "motion.can_see"),
+	relationFixture("user/1/projection_$1_ids", "projector.can_see"),
+	{"user/1/vote_$_ids", "user.can_see", `["1"]`, `["1"]`, `[]`},
+
+	{avatar.Field, "mediafile.can_see", `"/system/media/get/1"`, `"/system/media/get/1"`, ""},
+	{projector.PreviewField, "projector.can_see", `[]`, `[]`, ""},
+	{projector.ChyronField, "projector.can_see", `"chyron"`, `"chyron"`, ""},
+	{projector.ServerTimeField, "projector.can_see", `1`, `1`, ""},
+	{vote.Field, notVisible, `{"poll_id":1,"meeting_id":1}`, "", ""},
+	{vote.CountField, notVisible, `1`, "", ""},
+	{calllist.Field, "motion.can_see", `[1]`, `[1]`, `[]`},
+	{usercount.UserAmount, "", `1`, `1`, ""},
+	{usercount.ActiveUserAmount, "", `1`, `1`, ""},
+	{usercount.PresentUserAmount, "", `1`, `1`, ""},
+	{"user/1/email", "user.can_manage", `"a@example.com"`, `"a@example.com"`, ""},
+	{"user/1/last_login", "user.can_manage", `1`, `1`, ""},
+	{"user/1/default_password", "user.can_manage", `"secret"`, `"secret"`, ""},
+	{"user/1/password", notVisible, `"hash"`, "", ""},
+}
+
+// calculatedPermissions are the permissions of the checkers, that are not
+// created from the relation lists.
+var calculatedPermissions = map[string]string{
+	avatar.Field:                "mediafile.can_see",
+	projector.PreviewField:      "projector.can_see",
+	projector.ChyronField:       "projector.can_see",
+	projector.ServerTimeField:   "projector.can_see",
+	vote.Field:                  notVisible,
+	vote.CountField:             notVisible,
+	calllist.Field:              "motion.can_see",
+	usercount.UserAmount:        "",
+	usercount.ActiveUserAmount:  "",
+	usercount.PresentUserAmount: "",
+	"user/email":                "user.can_manage",
+	"user/last_login":           "user.can_manage",
+	"user/default_password":     "user.can_manage",
+	"user/password":             notVisible,
+}
+
+// coverageCell returns the row and the permission of a checker in the
+// coverage matrix. The checkers of the relation lists are grouped by their
+// collection and the permission of the related collection. All other checkers
+// have their own row.
+//
+// Generic relation lists have the permission "*".
+func coverageCell(idx string) (row string, perm string, ok bool) {
+	if perm, ok := calculatedPermissions[idx]; ok {
+		return idx, perm, true
+	}
+
+	collection := strings.Split(idx, "/")[0]
+	for field, target := range restrict.RelationLists {
+		switch {
+		case field == idx && strings.Contains(field, "$"):
+			// The template field itself.
+			return collection, requiredPermission(collection), true
+
+		case field == idx || strings.Contains(field, "$") && field[:strings.IndexByte(field, '$')] == idx:
+			if target == "*" {
+				return collection, "*", true
+			}
+			return collection, requiredPermission(target), true
+		}
+	}
+	return "", "", false
+}
+
+// requiredPermission returns the permission to see a collection.
+func requiredPermission(collection string) string {
+	perm, ok := restricttest.RequiredPermissions[collection]
+	if !ok {
+		return notVisible
+	}
+	return perm
+}
+
+// fixtureData returns the datastore content for a fixture. If perm is not
+// nil, the fixtureUser is in a group of meeting 1 with this permissions.
+func fixtureData(perms []string) map[string]string {
+	data := map[string]string{
+		"meeting/1/id":        `1`,
+		"user/1/group_$_ids":  `["1"]`,
+		"user/1/group_$1_ids": `[2]`,
+		"group/2/meeting_id":  `1`,
+	}
+	for collection := range restricttest.RequiredPermissions {
+		if collection != "meeting" && collection != "user" {
+			data[collection+"/1/meeting_id"] = `1`
+		}
+	}
+
+	if perms != nil {
+		quoted := make([]string, len(perms))
+		for i, perm := range perms {
+			quoted[i] = fmt.Sprintf("%q", perm)
+		}
+
+		data[fmt.Sprintf("user/%d/group_$_ids", fixtureUser)] = `["1"]`
+		data[fmt.Sprintf("user/%d/group_$1_ids", fixtureUser)] = `[1]`
+		data["group/1/permissions"] = "[" + strings.Join(quoted, ",") + "]"
+	}
+	return data
+}
+
+// fixtureKey returns the key of a fixture. The fixtures of calculated fields
+// only have the collection and the field.
+func fixtureKey(key string) string {
+	if strings.Count(key, "/") == 1 {
+		parts := strings.Split(key, "/")
+		return parts[0] + "/1/" + parts[1]
+	}
+	return key
+}
+
+// checkerIndex returns the index of the checker for a key like the
+// Restricter does.
+func checkerIndex(key string) string {
+	parts := strings.Split(key, "/")
+	field := parts[2]
+
+	i := strings.IndexByte(field, '$')
+	if i < 0 || i == len(field)-1 || field[i+1] == '_' {
+		return parts[0] + "/" + field
+	}
+	return parts[0] + "/" + field[:i]
+}
+
+func TestCheckerFixtures(t *testing.T) {
+	for _, tt := range checkerFixtures {
+		key := fixtureKey(tt.key)
+
+		t.Run(key+" "+tt.perm, func(t *testing.T) {
+			for _, run := range []struct {
+				name   string
+				perms  []string
+				expect string
+			}{
+				{"with permission", []string{tt.perm}, tt.expect},
+				{"without permission", nil, tt.denied},
+			} {
+				closed := make(chan struct{})
+				ds := dsmock.NewMockDatastore(closed, fixtureData(run.perms))
+				perms := restricttest.NewPermission(ds, restricttest.RequiredPermissions)
+
+				checker, ok := newCheckers(ds, perms, restrict.RelationLists)[checkerIndex(key)]
+				if !ok {
+					close(closed)
+					t.Fatalf("No checker for key %s", key)
+				}
+
+				got, err := checker.Check(context.Background(), fixtureUser, key, []byte(tt.value))
+				close(closed)
+				if err != nil {
+					t.Fatalf("Check %s returned unexpected error: %v", run.name, err)
+				}
+
+				if string(got) != run.expect {
+					t.Errorf("Check %s returned `%s`, expected `%s`", run.name, got, run.expect)
+				}
+			}
+		})
+	}
+}
+
+// TestCheckerCoverage builds a matrix of collection × permission from all
+// checkers of the Restricter and fails, if a combination has no fixture.
+//
+// Set the environment variable RESTRICT_COVERAGE_REPORT to a filename to write
+// the matrix into that file.
+func TestCheckerCoverage(t *testing.T) {
+	tested := make(map[string]bool)
+	for _, f := range checkerFixtures {
+		idx := checkerIndex(fixtureKey(f.key))
+		row, perm, ok := coverageCell(idx)
+		if !ok {
+			t.Errorf("Fixture %s has no checker", f.key)
+			continue
+		}
+
+		if perm != "*" && perm != f.perm {
+			t.Errorf("Fixture %s uses permission %q, but the checker needs %q", f.key, f.perm, perm)
+			continue
+		}
+		tested[row+" "+perm] = true
+	}
+
+	// matrix maps from the row to the set of permissions.
+	matrix := make(map[string]map[string]bool)
+	for idx := range newCheckers(nil, nil, restrict.RelationLists) {
+		row, perm, ok := coverageCell(idx)
+		if !ok {
+			t.Errorf("Checker %s has no permission in the coverage matrix. Add it to calculatedPermissions", idx)
+			continue
+		}
+
+		if matrix[row] == nil {
+			matrix[row] = make(map[string]bool)
+		}
+		matrix[row][perm] = true
+	}
+
+	rows := make([]string, 0, len(matrix))
+	for row := range matrix {
+		rows = append(rows, row)
+	}
+	sort.Strings(rows)
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "%-35s %-27s %s\n", "collection", "permission", "tested")
+	for _, row := range rows {
+		perms := make([]string, 0, len(matrix[row]))
+		for perm := range matrix[row] {
+			perms = append(perms, perm)
+		}
+		sort.Strings(perms)
+
+		for _, perm := range perms {
+			ok := tested[row+" "+perm]
+			fmt.Fprintf(&report, "%-35s %-27q %t\n", row, perm, ok)
+			if !ok {
+				t.Errorf("%s with permission %q has no fixture", row, perm)
+			}
+		}
+	}
+
+	t.Log("\n" + report.String())
+
+	if fileName := os.Getenv("RESTRICT_COVERAGE_REPORT"); fileName != "" {
+		if err := os.WriteFile(fileName, []byte(report.String()), 0644); err != nil {
+			t.Fatalf("Writing coverage report: %v", err)
+		}
+	}
+}
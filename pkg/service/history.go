@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+)
+
+// atPositioner is an optional interface for the Datastore. It returns the
+// values at a position of the datastore.
+//
+// The datastore.Datastore from pkg/datastore implements this interface.
+type atPositioner interface {
+	AtPosition(position int) datastore.Getter
+}
+
+// historicData returns the data at a position of the datastore. The data is
+// restricted with the permissions, that the user had at this position.
+type historicData struct {
+	ds         atPositioner
+	permission func(ds datastore.Getter) Permissioner
+	restricter func(ds datastore.Getter, permer Permissioner) Restricter
+}
+
+// RestrictedDataAt returns the restricted values of the keys at the position.
+func (h historicData) RestrictedDataAt(ctx context.Context, uid int, position int, keys ...string) (map[string]json.RawMessage, error) {
+	getter := h.ds.AtPosition(position)
+
+	values, err := getter.Get(ctx, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("get values for keys `%v` at position %d: %w", keys, position, err)
+	}
+
+	data := make(map[string]json.RawMessage, len(keys))
+	for i, key := range keys {
+		data[key] = values[i]
+	}
+
+	restricter := h.restricter(getter, h.permission(getter))
+	if err := restricter.Restrict(ctx, uid, data); err != nil {
+		return nil, fmt.Errorf("restrict data: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// Datastore gets values for keys, informs about changes and can register
+// calculated fields.
+//
+// The datastore.Datastore from pkg/datastore implements this interface.
+type Datastore interface {
+	Get(ctx context.Context, keys ...string) ([]json.RawMessage, error)
+	RegisterChangeListener(f func(map[string]json.RawMessage) error)
+	RegisterCalculatedField(field string, f func(ctx context.Context, key string, changed map[string]json.RawMessage) ([]byte, error))
+	TemplateField(ctx context.Context, fqid, field, replacement string, value interface{}) ([]string, error)
+	CachedIDs(collection string) []int
+	ResetCache()
+	ResetCollection(collection string) []string
+}
+
+// Authenticater gives an user id for an request. Returns 0 for anonymous.
+//
+// The auth.Auth from pkg/auth implements this interface.
+type Authenticater interface {
+	Authenticate(http.ResponseWriter, *http.Request) (context.Context, error)
+	FromContext(context.Context) int
+}
+
+// Restricter restricts the values for a user.
+//
+// It is not allowed to manipulate a value in the dict. A value can only be
+// replaced with a new value. If the user does not have the permission to see
+// one key, the value has to be set to nil.
+type Restricter interface {
+	Restrict(ctx context.Context, uid int, data map[string]json.RawMessage) error
+}
+
+// Permissioner tells, if a user has the required permissions to see
+// fqfields.
+type Permissioner interface {
+	RestrictFQFields(ctx context.Context, uid int, fqfields []string) (map[string]bool, error)
+}
+
+// UserUpdater returns the ids of users, that need a full update after the
+// given data has changed.
+type UserUpdater interface {
+	AdditionalUpdate(ctx context.Context, updated map[string]json.RawMessage) ([]int, error)
+}
+
+// Pinger checks, if a dependency of the service is reachable.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// ConnectionHook is informed about the connections of the clients. It can be
+// used for accounting or debugging.
+//
+// The methods are called from the goroutine of the connection, so they should
+// return fast. ctx is the context of the request.
+type ConnectionHook interface {
+	OnConnect(ctx context.Context, uid int)
+	OnMessage(ctx context.Context, uid int, keys int, size int)
+	OnDisconnect(ctx context.Context, uid int, err error)
+}
+
+// VoteCounter receives the number of votes of open polls from the vote
+// service. VoteCount blocks until there are new counts.
+//
+// The redis and nats message buses from pkg/redis and pkg/nats implement this
+// interface.
+type VoteCounter interface {
+	VoteCount(closing <-chan struct{}) (map[int]int, error)
+}
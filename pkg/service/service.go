@@ -0,0 +1,679 @@
+// Package service contains the autoupdate service as a library.
+//
+// It can be used to embed the autoupdate service into another binary:
+//
+//	restricter := service.DefaultRestricter(ds, permer)
+//	s := service.New(ds, auth, restricter, closed)
+//	http.ListenAndServe(":9012", s.Handler())
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/avatar"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/calllist"
+	autoupdateHttp "github.com/OpenSlides/openslides-autoupdate-service/internal/http"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/projector"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/projector/slide"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/record"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/usercount"
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/vote"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/intern"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/keysbuilder"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/models"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/restrict"
+)
+
+// Service is the autoupdate service.
+type Service struct {
+	autoupdate   *autoupdate.Autoupdate
+	ds           Datastore
+	handler      http.Handler
+	debugHandler http.Handler
+}
+
+// Option is an optional argument for New.
+type Option func(*config)
+
+type config struct {
+	userUpdater UserUpdater
+	voteURL     string
+	disabled    []string
+	kbOptions   []keysbuilder.Option
+	recording   io.Writer
+	debounce    time.Duration
+	resync      bool
+	pruneTime   time.Duration
+	projectors  []int
+	ready       map[string]Pinger
+	password    string
+
+	internalToken  string
+	internalRoutes []string
+
+	connectRate  float64
+	connectBurst int
+	proxies      []*net.IPNet
+
+	bodyLimit autoupdateHttp.BodyLimit
+
+	corsOrigins     []string
+	corsCredentials bool
+
+	historicPermission func(ds datastore.Getter) Permissioner
+
+	hooks []ConnectionHook
+
+	restricters []Restricter
+
+	voteCounter VoteCounter
+
+	serverTimeInterval time.Duration
+
+	collections []string
+
+	models *models.Models
+
+	blocked   []string
+	isolation bool
+}
+
+// WithUserUpdater sets the UserUpdater. Without a UserUpdater, there are no
+// full updates for single users after a datastore update.
+func WithUserUpdater(u UserUpdater) Option {
+	return func(c *config) {
+		c.userUpdater = u
+	}
+}
+
+// WithHistoricPermission sets a function, that creates a Permissioner for a
+// position of the datastore. The returned Permissioner has to use the given
+// Getter. Without this option, the history endpoint can not return the data
+// of a position.
+func WithHistoricPermission(f func(ds datastore.Getter) Permissioner) Option {
+	return func(c *config) {
+		c.historicPermission = f
+	}
+}
+
+// WithConnectionHook registers a ConnectionHook. It is called on connect,
+// after each message and on disconnect of every client connection. The option
+// can be used more then once.
+func WithConnectionHook(hook ConnectionHook) Option {
+	return func(c *config) {
+		c.hooks = append(c.hooks, hook)
+	}
+}
+
+// WithRestricter adds a Restricter, that runs after the Restricter given to
+// New and after the Restricter of the historic data. It gets the data, that
+// the previous Restricters returned. The option can be used more then once.
+// The Restricters are called in the given order.
+//
+// This can be used to strip fields or to test experimental restrictions
+// without changing the default Restricter.
+func WithRestricter(r Restricter) Option {
+	return func(c *config) {
+		c.restricters = append(c.restricters, r)
+	}
+}
+
+// WithKnownCollections validates the keys from the datastore and from the
+// requests of the clients. Keys, that do not have the form
+// collection/id/field or whose collection is not one of the given collections,
+// are logged and dropped. Without this option, the keys are not validated.
+func WithKnownCollections(collections ...string) Option {
+	return func(c *config) {
+		c.collections = collections
+	}
+}
+
+// WithModels uses the models.yml for the relations between the collections,
+// for the validation of keys (see WithKnownCollections), for the check of
+// the relation fields in the requests of the clients, for the restriction of
+// relation fields and for the restriction modes of the anonymous user. The
+// clients can request relation fields without a type.
+//
+// The option has to be given to DefaultRestricter and New.
+func WithModels(m *models.Models) Option {
+	return func(c *config) {
+		c.models = m
+	}
+}
+
+// WithVoteURL sets the url of the vote service, that is published to the
+// clients in the field poll/vote_service. The default is vote.DefaultURL.
+func WithVoteURL(url string) Option {
+	return func(c *config) {
+		c.voteURL = url
+	}
+}
+
+// WithVoteCount creates the field poll/vote_count with the number of votes of
+// open polls. The counts are received from the vote service with the
+// VoteCounter, usually the message bus. The Datastore has to support
+// UpdateCalculatedField like datastore.Datastore does. Without this option,
+// the field does not exist.
+func WithVoteCount(counter VoteCounter) Option {
+	return func(c *config) {
+		c.voteCounter = counter
+	}
+}
+
+// WithServerTime creates the field projector/server_time with the time of the
+// server. The field is updated every interval, so projector screens can
+// compensate the drift of their clock. The Datastore has to support
+// UpdateCalculatedField like datastore.Datastore does. Without this option,
+// the field does not exist.
+func WithServerTime(interval time.Duration) Option {
+	return func(c *config) {
+		c.serverTimeInterval = interval
+	}
+}
+
+// WithBlocked removes the given collections and fields (collection/field)
+// from all responses and from the projector content regardless of the
+// permissions. The ids of blocked collections are also removed from relation
+// fields. See restrict.Blocked.
+func WithBlocked(entries ...string) Option {
+	return func(c *config) {
+		c.blocked = entries
+	}
+}
+
+// WithIsolation removes the keys of meetings, the user has no relation to,
+// after the permission check. It is a second line of defense against bugs in
+// the permission checks. See restrict.Isolation.
+func WithIsolation() Option {
+	return func(c *config) {
+		c.isolation = true
+	}
+}
+
+// WithDisabledFeatures disables the slides of the given features. Instead of
+// the content, disabled slides tell, that the feature is disabled. Unknown
+// features are ignored. See Features for a list of all features.
+func WithDisabledFeatures(features ...string) Option {
+	return func(c *config) {
+		c.disabled = features
+	}
+}
+
+// Features returns the names of all features, that can be disabled.
+func Features() []string {
+	return slide.Slides().Features()
+}
+
+// WithRequestLimits sets the maximum number of relations and keys of a
+// request. A value of 0 means no limit. The defaults are
+// keysbuilder.DefaultMaxDepth and keysbuilder.DefaultMaxKeys.
+func WithRequestLimits(maxDepth, maxKeys int) Option {
+	return func(c *config) {
+		c.kbOptions = []keysbuilder.Option{
+			keysbuilder.WithMaxDepth(maxDepth),
+			keysbuilder.WithMaxKeys(maxKeys),
+		}
+	}
+}
+
+// WithRequestBodyLimits sets the maximum size in bytes and the maximum time to
+// read the body of a request to /system/autoupdate, the notify and the query
+// endpoint. Bigger requests get the status code 413, slower requests 408. A
+// value of 0 means no limit, which is the default.
+func WithRequestBodyLimits(maxSize int64, timeout time.Duration) Option {
+	return func(c *config) {
+		c.bodyLimit = autoupdateHttp.BodyLimit{MaxSize: maxSize, Timeout: timeout}
+	}
+}
+
+// WithCORS adds CORS headers to the public endpoints for requests from the
+// given origins. `*` allows all origins. With credentials, browsers can send
+// the cookies and the auth header, but only for the origins, that are listed
+// explicitly. Without origins, there are no CORS headers, which is the
+// default.
+func WithCORS(origins []string, credentials bool) Option {
+	return func(c *config) {
+		c.corsOrigins = origins
+		c.corsCredentials = credentials
+	}
+}
+
+// WithRecording records all changes and all messages to the clients into the
+// writer. See the package internal/record for the format.
+func WithRecording(w io.Writer) Option {
+	return func(c *config) {
+		c.recording = w
+	}
+}
+
+// WithDebounce collects datastore updates for the duration d and sends them
+// to the clients in one message. The default is 0, which sends each update
+// immediately.
+func WithDebounce(d time.Duration) Option {
+	return func(c *config) {
+		c.debounce = d
+	}
+}
+
+// WithResync sends a full update to clients, that are too slow to read the
+// changes. Without this option, the connections of slow clients are closed
+// with an error. The number of resyncs can be read from the metrics endpoint.
+func WithResync() Option {
+	return func(c *config) {
+		c.resync = true
+	}
+}
+
+// WithProjectorUsers sets the users of projector screens. Their connections
+// are not recalculated, when the permissions of a single user change.
+func WithProjectorUsers(uids ...int) Option {
+	return func(c *config) {
+		c.projectors = uids
+	}
+}
+
+// WithPruneTime sets how long a client can need to read a message. Slower
+// clients get an error or a full update (see WithResync). The default is
+// autoupdate.DefaultPruneTime.
+func WithPruneTime(d time.Duration) Option {
+	return func(c *config) {
+		c.pruneTime = d
+	}
+}
+
+// WithReadyCheck adds a dependency to the endpoint /system/ready. The
+// datastore and the auth service are added automatically, if they implement
+// Pinger.
+func WithReadyCheck(name string, p Pinger) Option {
+	return func(c *config) {
+		c.ready[name] = p
+	}
+}
+
+// WithAdminPassword enables the internal endpoint
+// /internal/autoupdate/full_update. Requests to it have to use http basic auth
+// with this password.
+func WithAdminPassword(password string) Option {
+	return func(c *config) {
+		c.password = password
+	}
+}
+
+// WithInternalAuth lets other backend services send requests on behalf of a
+// user. The request needs the token in the header X-Internal-Auth and the user
+// id in the header X-User-Id. This is only allowed for the given routes, for
+// example `/internal/autoupdate/query`. Without routes, the query and exists
+// endpoints are allowed.
+func WithInternalAuth(token string, routes ...string) Option {
+	return func(c *config) {
+		c.internalToken = token
+		c.internalRoutes = routes
+	}
+}
+
+// WithConnectLimit limits the new connections of each ip address and each user
+// with a token bucket. A client can open burst connections at once and
+// afterwards rate connections per second. Other connections get the status
+// code 429. The default is no limit.
+func WithConnectLimit(rate float64, burst int) Option {
+	return func(c *config) {
+		c.connectRate = rate
+		c.connectBurst = burst
+	}
+}
+
+// WithTrustedProxies sets the proxies, whose header X-Forwarded-For is used to
+// get the ip address of a client for WithConnectLimit. Without trusted proxies,
+// the remote address of the request is used.
+func WithTrustedProxies(proxies ...*net.IPNet) Option {
+	return func(c *config) {
+		c.proxies = proxies
+	}
+}
+
+// New initializes the autoupdate service.
+//
+// The service registers the projector and other calculated fields in the
+// datastore. It runs background tasks until the channel closed is closed.
+func New(ds Datastore, auth Authenticater, restricter Restricter, closed <-chan struct{}, options ...Option) *Service {
+	cfg := newConfig(options)
+
+	relationLists := cfg.relationLists()
+	if cfg.models != nil {
+		cfg.kbOptions = append(cfg.kbOptions, keysbuilder.WithRelations(cfg.models.Relations()), keysbuilder.WithSchema(cfg.models))
+		if cfg.collections == nil {
+			cfg.collections = cfg.models.Collections()
+		}
+	}
+
+	auOptions := []autoupdate.Option{
+		autoupdate.WithRelations(relationLists),
+		autoupdate.WithDebounce(cfg.debounce),
+		autoupdate.WithPruneTime(cfg.pruneTime),
+	}
+	if cfg.resync {
+		auOptions = append(auOptions, autoupdate.WithResync())
+	}
+	if len(cfg.projectors) > 0 {
+		auOptions = append(auOptions, autoupdate.WithProjectorUsers(cfg.projectors...))
+	}
+	if cfg.collections != nil {
+		auOptions = append(auOptions, autoupdate.WithKnownCollections(cfg.collections...))
+	}
+	for _, hook := range cfg.hooks {
+		auOptions = append(auOptions, autoupdate.WithConnectionHook(hook))
+	}
+
+	a := autoupdate.New(ds, cfg.chainRestricters(ds, restricter), cfg.userUpdater, closed, auOptions...)
+
+	var liver autoupdateHttp.Liver = a
+	if cfg.recording != nil {
+		recorder := record.NewRecorder(cfg.recording, func(err error) {
+			log.Printf("Error: %v", err)
+		})
+		ds.RegisterChangeListener(recorder.Change)
+		liver = recorder.Liver(a)
+	}
+
+	mux := http.NewServeMux()
+	autoupdateHttp.Health(mux, cfg.effective(ds))
+	autoupdateHttp.Liveness(mux)
+	autoupdateHttp.Ready(mux, cfg.readyChecks(ds, auth))
+	metricers := []autoupdateHttp.Metricer{a, intern.Default}
+	if cfg.connectRate > 0 {
+		limiter := autoupdateHttp.NewRateLimiter(cfg.connectRate, cfg.connectBurst, cfg.proxies...)
+		auth = autoupdateHttp.ConnectLimit(auth, limiter)
+		metricers = append(metricers, limiter)
+	}
+	if cfg.internalToken != "" {
+		auth = autoupdateHttp.InternalAuth(auth, cfg.internalToken, cfg.internalRoutes...)
+	}
+	autoupdateHttp.Complex(mux, auth, a, liver, cfg.bodyLimit, cfg.kbOptions...)
+	autoupdateHttp.Simple(mux, auth, liver)
+	autoupdateHttp.Query(mux, auth, ds, a, cfg.bodyLimit)
+	autoupdateHttp.Exists(mux, auth, a)
+	autoupdateHttp.Notify(mux, auth, a, cfg.bodyLimit)
+	if historian, ok := ds.(autoupdateHttp.Historian); ok {
+		var historic autoupdateHttp.HistoricDataer
+		if p, ok := ds.(atPositioner); ok && cfg.historicPermission != nil {
+			historic = historicData{ds: p, permission: cfg.historicPermission, restricter: cfg.historicRestricter(options)}
+		}
+		autoupdateHttp.History(mux, auth, a, historian, historic)
+	}
+	if cfg.password != "" {
+		autoupdateHttp.FullUpdate(mux, a, cfg.password)
+	}
+
+	// The endpoints of debugMux, except explain, do not authenticate the
+	// requests. They are only served by DebugHandler.
+	debugMux := http.NewServeMux()
+	autoupdateHttp.Pprof(debugMux)
+	autoupdateHttp.RuntimeMetrics(debugMux)
+	autoupdateHttp.Explain(debugMux, auth, a)
+	autoupdateHttp.Profile(debugMux)
+	if m, ok := ds.(autoupdateHttp.Metricer); ok {
+		metricers = append(metricers, m)
+	}
+	autoupdateHttp.Metrics(debugMux, metricers...)
+	autoupdateHttp.ResetCache(debugMux, a)
+	autoupdateHttp.Connections(debugMux, a)
+
+	slides := slide.Slides()
+	slides.Disable(cfg.disabled...)
+	if len(cfg.blocked) > 0 {
+		slides.Block(restrict.NewBlocked(relationLists, cfg.blocked...))
+	}
+	projector.Register(ds, slides)
+	avatar.Register(ds)
+	vote.Register(ds, cfg.voteURL)
+	var voteCount *vote.Count
+	if cds, ok := ds.(vote.CountDatastore); ok && cfg.voteCounter != nil {
+		voteCount = vote.RegisterCount(cds, cfg.voteCounter, func(err error) {
+			log.Printf("Error: %v", err)
+		})
+	}
+	var serverTime *projector.ServerTime
+	if cds, ok := ds.(projector.ServerTimeDatastore); ok && cfg.serverTimeInterval > 0 {
+		serverTime = projector.RegisterServerTime(cds)
+	}
+	usercount.Register(ds)
+	calllist.Register(ds)
+
+	// The calculated fields are updated in the background after all fields
+	// and listeners are registered.
+	if voteCount != nil {
+		go voteCount.Run(closed)
+	}
+	if serverTime != nil {
+		go serverTime.Run(cfg.serverTimeInterval, closed)
+	}
+
+	return &Service{
+		autoupdate:   a,
+		ds:           ds,
+		handler:      autoupdateHttp.CORS(mux, cfg.corsOrigins, cfg.corsCredentials),
+		debugHandler: debugMux,
+	}
+}
+
+// cacheTTLer is an optional interface for the Datastore. It tells, when unused
+// keys are removed from the cache.
+type cacheTTLer interface {
+	CacheTTL() time.Duration
+}
+
+// effective returns the configuration values, that are shown on the health
+// endpoint.
+func (c config) effective(ds Datastore) map[string]string {
+	values := map[string]string{
+		"prune_time": c.pruneTime.String(),
+		"debounce":   c.debounce.String(),
+	}
+
+	if d, ok := ds.(cacheTTLer); ok {
+		values["cache_ttl"] = d.CacheTTL().String()
+	}
+	return values
+}
+
+// readyChecks returns the dependencies, that are checked by the endpoint
+// /system/ready.
+func (c config) readyChecks(ds Datastore, auth Authenticater) map[string]autoupdateHttp.Pinger {
+	checks := make(map[string]autoupdateHttp.Pinger, len(c.ready)+2)
+	if p, ok := ds.(Pinger); ok {
+		checks["datastore"] = p
+	}
+	if p, ok := auth.(Pinger); ok {
+		checks["auth"] = p
+	}
+	for name, p := range c.ready {
+		checks[name] = p
+	}
+	return checks
+}
+
+// Handler returns the http handler for all urls of the service.
+func (s *Service) Handler() http.Handler {
+	return s.handler
+}
+
+// DebugHandler returns the http handler for the internal endpoints: the
+// profiling endpoints of net/http/pprof under /debug/pprof/, the metrics of
+// the go runtime under /debug/runtime and the endpoints explain, profile,
+// metrics, reset_cache and connections under /internal/autoupdate/.
+//
+// The handler does not authenticate requests. It has to be served on an
+// internal listen address.
+func (s *Service) DebugHandler() http.Handler {
+	return s.debugHandler
+}
+
+// RestrictedData returns the restricted values for the given keys.
+func (s *Service) RestrictedData(ctx context.Context, uid int, keys ...string) (map[string]json.RawMessage, error) {
+	return s.autoupdate.RestrictedData(ctx, uid, keys...)
+}
+
+// DefaultRestricter returns the Restricter that the autoupdate service uses
+// with the given Permissioner.
+//
+// It decides the permissions of the anonymous user, lets committee managers
+// see the meetings and users of their committees, caches the results of the
+// Permissioner, lets superadmins see everything, restricts the values of
+// relation fields and hides the personal data of users. The Permissioner is
+// only asked once for each object and restriction mode.
+//
+// The cache is invalidated by the datastore. Therefore DefaultRestricter has
+// to be called before New.
+//
+// The options are the options of New. Only WithModels is used by the
+// Restricter for the relation lists and the restriction modes. The other
+// settings of the restriction, like WithBlocked and WithIsolation, are added
+// by New.
+func DefaultRestricter(ds Datastore, permer Permissioner, options ...Option) Restricter {
+	cfg := newConfig(options)
+
+	var perms restrict.Permissioner = restrict.NewAnonymous(permer, ds, cfg.restrictionModes())
+	perms = restrict.NewCommittee(perms, ds)
+
+	cache := restrict.NewPermissionCache(perms, ds, restrict.DefaultPermissionCacheSize)
+	ds.RegisterChangeListener(cache.Invalidate)
+
+	return newRestricter(ds, cache, cfg.relationLists(), cfg.restrictionModes())
+}
+
+// HistoricRestricter returns a Restricter like DefaultRestricter for a
+// position of the datastore. The Getter and the Permissioner have to return
+// the data of the same position.
+//
+// The results of the Permissioner are not cached, because the Restricter is
+// only used for one request.
+func HistoricRestricter(ds datastore.Getter, permer Permissioner, options ...Option) Restricter {
+	cfg := newConfig(options)
+
+	var perms restrict.Permissioner = restrict.NewAnonymous(permer, ds, cfg.restrictionModes())
+	perms = restrict.NewCommittee(perms, ds)
+	return newRestricter(ds, perms, cfg.relationLists(), cfg.restrictionModes())
+}
+
+// newRestricter returns the Restricter with all checkers. The keys with a
+// restriction mode are checked once for each object and mode.
+func newRestricter(ds datastore.Getter, perms restrict.Permissioner, relationLists, modes map[string]string) Restricter {
+	return restrict.New(
+		perms,
+		newCheckers(ds, perms, relationLists),
+		restrict.WithSuperadmin(ds, restrict.SuperadminStrippedFields...),
+		restrict.WithModes(modes, restrict.PermissionModeCheckers(modes, perms)),
+	)
+}
+
+// newCheckers returns the checkers of the Restricter.
+func newCheckers(ds datastore.Getter, perms restrict.Permissioner, relationLists map[string]string) map[string]restrict.Checker {
+	checker := restrict.RelationChecker(relationLists, perms)
+	checker[avatar.Field] = avatar.Checker(perms)
+	checker[projector.PreviewField] = projector.PreviewChecker(perms)
+	checker[projector.ChyronField] = projector.ChyronChecker(perms)
+	checker[projector.ServerTimeField] = projector.ServerTimeChecker(perms)
+	checker[vote.Field] = vote.Checker(perms, ds)
+	checker[vote.CountField] = vote.CountChecker(perms)
+	checker[calllist.Field] = calllist.Checker(perms)
+	for _, field := range usercount.Fields {
+		checker[field] = usercount.Checker(perms)
+	}
+
+	personal := restrict.PersonalDataChecker(ds)
+	for _, field := range restrict.PersonalDataFields {
+		checker[field] = personal
+	}
+	return checker
+}
+
+// newConfig returns the config with the defaults and the given options.
+func newConfig(options []Option) config {
+	cfg := config{
+		userUpdater: noUserUpdater{},
+		voteURL:     vote.DefaultURL,
+		pruneTime:   autoupdate.DefaultPruneTime,
+		ready:       make(map[string]Pinger),
+	}
+	for _, o := range options {
+		o(&cfg)
+	}
+	return cfg
+}
+
+// relationLists returns the relation lists of the models or the default
+// relation lists. The relation lists of the models are completed with
+// restrict.ExtraRelationLists.
+func (c config) relationLists() map[string]string {
+	if c.models != nil {
+		return withExtra(c.models.RelationLists(), restrict.ExtraRelationLists)
+	}
+	return restrict.RelationLists
+}
+
+// restrictionModes returns the restriction modes of the models or the default
+// restriction modes. The restriction modes of the models are completed with
+// restrict.ExtraRestrictionModes.
+func (c config) restrictionModes() map[string]string {
+	if c.models != nil {
+		return withExtra(c.models.RestrictionModes(), restrict.ExtraRestrictionModes)
+	}
+	return restrict.RestrictionModes
+}
+
+// withExtra returns a copy of fields with all extra fields, that are not in
+// fields.
+func withExtra(fields, extra map[string]string) map[string]string {
+	merged := make(map[string]string, len(fields)+len(extra))
+	for k, v := range extra {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return merged
+}
+
+// chainRestricters returns a Restricter, that calls r and afterwards the
+// restricters of WithBlocked, WithIsolation and WithRestricter. The Getter
+// has to return the same data as the Getter of r.
+func (c config) chainRestricters(ds datastore.Getter, r Restricter) Restricter {
+	restricters := []restrict.DataRestricter{r}
+	if len(c.blocked) > 0 {
+		restricters = append(restricters, restrict.NewBlocked(c.relationLists(), c.blocked...))
+	}
+	if c.isolation {
+		restricters = append(restricters, restrict.NewIsolation(ds))
+	}
+	for _, extra := range c.restricters {
+		restricters = append(restricters, extra)
+	}
+
+	if len(restricters) == 1 {
+		return r
+	}
+	return restrict.NewChain(restricters...)
+}
+
+// historicRestricter returns a function, that creates the Restricter for a
+// position of the datastore.
+func (c config) historicRestricter(options []Option) func(ds datastore.Getter, permer Permissioner) Restricter {
+	return func(ds datastore.Getter, permer Permissioner) Restricter {
+		return c.chainRestricters(ds, HistoricRestricter(ds, permer, options...))
+	}
+}
+
+// noUserUpdater is a UserUpdater that never returns a user.
+type noUserUpdater struct{}
+
+func (noUserUpdater) AdditionalUpdate(context.Context, map[string]json.RawMessage) ([]int, error) {
+	return nil, nil
+}
@@ -0,0 +1,295 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/models"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+	user/1:
+		username: hugo
+		avatar_mediafile_id: 5
+	`))
+	perms := &test.MockPermission{Default: true}
+	s := service.New(ds, test.Auth(1), service.DefaultRestricter(ds, perms), closed)
+
+	t.Run("Health", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/system/autoupdate/health", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"prune_time":"10m0s"`)
+	})
+
+	t.Run("Query", func(t *testing.T) {
+		// Load the user into the cache.
+		if _, err := ds.Get(context.Background(), "user/1/id"); err != nil {
+			t.Fatalf("Loading user: %v", err)
+		}
+
+		req := httptest.NewRequest("POST", "/internal/autoupdate/query", strings.NewReader(`{"collection":"user","fields":["username","avatar_url"]}`))
+		req.ProtoMajor = 2
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		body, _ := io.ReadAll(rec.Body)
+		assert.JSONEq(t, `[{"id":1,"username":"hugo","avatar_url":"/system/media/get/5"}]`, string(body))
+	})
+
+	t.Run("Internal endpoints", func(t *testing.T) {
+		for _, url := range []string{
+			"/internal/autoupdate/metrics",
+			"/internal/autoupdate/connections",
+			"/internal/autoupdate/profile?type=heap",
+		} {
+			rec := httptest.NewRecorder()
+			s.Handler().ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+			assert.Equal(t, http.StatusNotFound, rec.Code, "public handler serves %s", url)
+
+			rec = httptest.NewRecorder()
+			s.DebugHandler().ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+			assert.Equal(t, http.StatusOK, rec.Code, "debug handler does not serve %s", url)
+		}
+
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest("POST", "/internal/autoupdate/reset_cache", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("RestrictedData", func(t *testing.T) {
+		// All fields of restriction mode A are checked with the field id.
+		perms.Data = map[string]bool{"user/1/id": false}
+
+		data, err := s.RestrictedData(context.Background(), 2, "user/1/username")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]json.RawMessage{"user/1/username": nil}, data)
+	})
+}
+
+// stripRestricter removes the given keys.
+type stripRestricter []string
+
+func (r stripRestricter) Restrict(ctx context.Context, uid int, data map[string]json.RawMessage) error {
+	for _, key := range r {
+		if _, ok := data[key]; ok {
+			data[key] = nil
+		}
+	}
+	return nil
+}
+
+func TestServiceWithRestricter(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+	user/1:
+		username: hugo
+		first_name: Hugo
+	`))
+	perms := &test.MockPermission{Default: true}
+	s := service.New(ds, test.Auth(1), service.DefaultRestricter(ds, perms), closed, service.WithRestricter(stripRestricter{"user/1/first_name"}))
+
+	data, err := s.RestrictedData(context.Background(), 1, "user/1/username", "user/1/first_name")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]json.RawMessage{
+		"user/1/username":   []byte(`"hugo"`),
+		"user/1/first_name": nil,
+	}, data)
+}
+
+func TestServiceRestrictionModes(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+	motion/1:
+		title: title
+		text: text
+	`))
+	perms := &test.MockPermission{Data: map[string]bool{"motion/1/id": true}}
+	s := service.New(ds, test.Auth(1), service.DefaultRestricter(ds, perms), closed)
+
+	data, err := s.RestrictedData(context.Background(), 1, "motion/1/title", "motion/1/text")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]json.RawMessage{
+		"motion/1/title": []byte(`"title"`),
+		"motion/1/text":  []byte(`"text"`),
+	}, data)
+
+	// The fields of restriction mode A are checked with motion/1/id.
+	assert.False(t, perms.Called["motion/1/title"], "permission was called for motion/1/title")
+	assert.False(t, perms.Called["motion/1/text"], "permission was called for motion/1/text")
+}
+
+func TestServiceModelsExtraFields(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	// The models.yml does not know the collection meeting_user.
+	m, err := models.Parse(strings.NewReader(`---
+user:
+  id:
+    type: number
+    restriction_mode: A
+`))
+	require.NoError(t, err)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+	user/1/meeting_user_ids: [1, 2]
+	meeting_user:
+		1:
+			structure_level: level
+			comment: comment
+		2:
+			structure_level: other level
+	`))
+	perms := &test.MockPermission{Data: map[string]bool{
+		"user/1/id":         true,
+		"meeting_user/1/id": true,
+	}}
+	s := service.New(ds, test.Auth(1), service.DefaultRestricter(ds, perms, service.WithModels(m)), closed)
+
+	data, err := s.RestrictedData(context.Background(), 1, "user/1/meeting_user_ids", "meeting_user/1/structure_level", "meeting_user/1/comment")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]json.RawMessage{
+		"user/1/meeting_user_ids":        []byte(`[1]`),
+		"meeting_user/1/structure_level": []byte(`"level"`),
+		"meeting_user/1/comment":         nil,
+	}, data)
+}
+
+func TestServiceHistoricData(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+	motion/1:
+		title: old title
+		text: old text
+	`))
+	perms := &test.MockPermission{Default: true}
+
+	historicPerms := &test.MockPermission{Default: true, Data: map[string]bool{"motion/1/id": false}}
+	var got datastore.Getter
+	s := service.New(ds, test.Auth(1), service.DefaultRestricter(ds, perms), closed, service.WithHistoricPermission(func(g datastore.Getter) service.Permissioner {
+		got = g
+		return historicPerms
+	}))
+
+	req := httptest.NewRequest("GET", "/system/autoupdate/history?fqid=motion/1&position=3&fields=title,text", nil)
+	req.ProtoMajor = 2
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+
+	// The current permission allows the motion, but the historic does not.
+	assert.Equal(t, http.StatusNotFound, rec.Code, rec.Body.String())
+	assert.NotNil(t, got, "historic permission was not created")
+	assert.True(t, historicPerms.Called["motion/1/id"], "historic permission was not used")
+}
+
+func TestServiceHistoricDataWithRestricter(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+	motion/1:
+		title: old title
+		text: old text
+	`))
+	perms := &test.MockPermission{Default: true}
+	s := service.New(
+		ds,
+		test.Auth(1),
+		service.DefaultRestricter(ds, perms),
+		closed,
+		service.WithHistoricPermission(func(g datastore.Getter) service.Permissioner { return perms }),
+		service.WithRestricter(stripRestricter{"motion/1/text"}),
+	)
+
+	req := httptest.NewRequest("GET", "/system/autoupdate/history?fqid=motion/1&position=3&fields=title,text", nil)
+	req.ProtoMajor = 2
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.JSONEq(t, `{"motion/1/id":1,"motion/1/title":"old title"}`, rec.Body.String())
+}
+
+func TestServiceBlocked(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+	motion/1:
+		title: title
+		reason: reason
+	`))
+	perms := &test.MockPermission{Default: true}
+	s := service.New(
+		ds,
+		test.Auth(1),
+		service.DefaultRestricter(ds, perms),
+		closed,
+		service.WithHistoricPermission(func(g datastore.Getter) service.Permissioner { return perms }),
+		service.WithBlocked("motion/reason"),
+	)
+
+	data, err := s.RestrictedData(context.Background(), 1, "motion/1/title", "motion/1/reason")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]json.RawMessage{
+		"motion/1/title":  []byte(`"title"`),
+		"motion/1/reason": nil,
+	}, data)
+
+	req := httptest.NewRequest("GET", "/system/autoupdate/history?fqid=motion/1&position=3&fields=title,reason", nil)
+	req.ProtoMajor = 2
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.JSONEq(t, `{"motion/1/id":1,"motion/1/title":"title"}`, rec.Body.String())
+}
+
+func TestWarmup(t *testing.T) {
+	closed := make(chan struct{})
+	defer close(closed)
+
+	ds := dsmock.NewMockDatastore(closed, dsmock.YAMLData(`
+	organisation/1:
+		name: my org
+		committee_ids: [1]
+	committee/1/meeting_ids: [5, 6]
+	meeting/5:
+		name: first
+		group_ids: [7]
+	meeting/6/name: second
+	group/7/permissions: []
+	`))
+	s := service.New(ds, test.Auth(1), service.DefaultRestricter(ds, &test.MockPermission{Default: true}), closed)
+
+	count, err := s.Warmup(context.Background(), strings.NewReader(service.DefaultWarmup))
+	require.NoError(t, err)
+
+	assert.Greater(t, count, 0)
+	assert.ElementsMatch(t, []int{5, 6}, ds.CachedIDs("meeting"))
+	assert.Equal(t, []int{7}, ds.CachedIDs("group"))
+}
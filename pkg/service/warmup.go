@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/keysbuilder"
+)
+
+// DefaultWarmup is a keys request for the data, that most connections need:
+// the organisation, its committees and their meetings with the groups.
+const DefaultWarmup = `[{
+	"ids": [1],
+	"collection": "organisation",
+	"fields": {
+		"name": null,
+		"committee_ids": {
+			"type": "relation-list",
+			"collection": "committee",
+			"fields": {
+				"name": null,
+				"meeting_ids": {
+					"type": "relation-list",
+					"collection": "meeting",
+					"fields": {
+						"name": null,
+						"enable_anonymous": null,
+						"default_group_id": null,
+						"reference_projector_id": null,
+						"projector_ids": null,
+						"group_ids": {
+							"type": "relation-list",
+							"collection": "group",
+							"fields": {"name": null, "permissions": null}
+						}
+					}
+				}
+			}
+		}
+	}
+}]`
+
+// Warmup loads all keys of a request into the cache of the datastore. It
+// should be called before the http server is started, so the first
+// connections after a restart do not all request the same keys.
+//
+// The request is a list of keys requests like the body of the autoupdate
+// request (see DefaultWarmup). The values are not restricted. Warmup returns
+// the number of loaded keys.
+func (s *Service) Warmup(ctx context.Context, request io.Reader) (int, error) {
+	b, err := keysbuilder.ManyFromJSON(request, unrestricted{s.ds}, 0)
+	if err != nil {
+		return 0, fmt.Errorf("parsing warmup request: %w", err)
+	}
+
+	if err := b.Update(ctx); err != nil {
+		return 0, fmt.Errorf("following relations: %w", err)
+	}
+
+	keys := b.Keys()
+	if _, err := s.ds.Get(ctx, keys...); err != nil {
+		return 0, fmt.Errorf("loading keys: %w", err)
+	}
+	return len(keys), nil
+}
+
+// unrestricted is a keysbuilder.DataProvider that returns the values from the
+// datastore without restricting them.
+type unrestricted struct {
+	ds Datastore
+}
+
+func (u unrestricted) RestrictedData(ctx context.Context, uid int, keys ...string) (map[string]json.RawMessage, error) {
+	values, err := u.ds.Get(ctx, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("getting keys: %w", err)
+	}
+
+	data := make(map[string]json.RawMessage, len(keys))
+	for i, key := range keys {
+		data[key] = values[i]
+	}
+	return data, nil
+}
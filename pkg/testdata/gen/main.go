@@ -0,0 +1,278 @@
+// This tool generates the file meeting.json.
+//
+// The data is created with a fixed random seed, so each run creates the same
+// file.
+//
+// Run it with: go generate ./pkg/testdata
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// These numbers have to be the same as the constants in the testdata package.
+const (
+	meetingID     = 1
+	userCount     = 500
+	motionCount   = 200
+	pollCount     = 20
+	votesPerPoll  = 50
+	speakersPerLS = 3
+)
+
+// Group ids.
+const (
+	groupDefault = iota + 1
+	groupAdmin
+	groupDelegates
+	groupStaff
+)
+
+var (
+	firstNames = []string{"Anna", "Ben", "Clara", "David", "Emma", "Finn", "Greta", "Hugo", "Ida", "Jonas", "Klara", "Leon", "Mia", "Noah", "Olga", "Paul"}
+	lastNames  = []string{"Müller", "Schmidt", "Schneider", "Fischer", "Weber", "Meyer", "Wagner", "Becker", "Schulz", "Hoffmann", "Koch", "Richter"}
+	levels     = []string{"Berlin", "Hamburg", "Bayern", "Sachsen", "Hessen", "Bremen"}
+	words      = []string{"Antrag", "Änderung", "Satzung", "Haushalt", "Bildung", "Umwelt", "Verkehr", "Digitalisierung", "Wohnen", "Gesundheit", "Arbeit", "Kultur"}
+)
+
+type data map[string]interface{}
+
+func (d data) set(collection string, id int, field string, value interface{}) {
+	d[fmt.Sprintf("%s/%d/%s", collection, id, field)] = value
+}
+
+func main() {
+	r := rand.New(rand.NewSource(1))
+	d := make(data)
+
+	meeting(d)
+	groupUsers := users(d, r)
+	groups(d, groupUsers)
+	motions(d, r)
+	speakers(d, r)
+	polls(d, r)
+
+	if err := write(d); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+func ids(from, to int) []int {
+	out := make([]int, 0, to-from+1)
+	for i := from; i <= to; i++ {
+		out = append(out, i)
+	}
+	return out
+}
+
+func meeting(d data) {
+	d.set("meeting", meetingID, "id", meetingID)
+	d.set("meeting", meetingID, "name", "Bundesdelegiertenkonferenz")
+	d.set("meeting", meetingID, "enable_anonymous", false)
+	d.set("meeting", meetingID, "default_group_id", groupDefault)
+	d.set("meeting", meetingID, "admin_group_id", groupAdmin)
+	d.set("meeting", meetingID, "group_ids", ids(1, 4))
+	d.set("meeting", meetingID, "user_ids", ids(1, userCount))
+	d.set("meeting", meetingID, "motion_ids", ids(1, motionCount))
+	d.set("meeting", meetingID, "motion_state_ids", ids(1, 3))
+	d.set("meeting", meetingID, "agenda_item_ids", ids(1, motionCount))
+	d.set("meeting", meetingID, "list_of_speakers_ids", ids(1, motionCount))
+	d.set("meeting", meetingID, "poll_ids", ids(1, pollCount))
+}
+
+// users creates the users and returns the user ids for each group.
+func users(d data, r *rand.Rand) map[int][]int {
+	groupUsers := make(map[int][]int)
+	for id := 1; id <= userCount; id++ {
+		group := groupDelegates
+		switch {
+		case id <= 5:
+			group = groupAdmin
+		case id <= 25:
+			group = groupStaff
+		case id > 450:
+			group = groupDefault
+		}
+		groupUsers[group] = append(groupUsers[group], id)
+
+		first := firstNames[r.Intn(len(firstNames))]
+		last := lastNames[r.Intn(len(lastNames))]
+
+		d.set("user", id, "id", id)
+		d.set("user", id, "username", fmt.Sprintf("%s.%s%d", strings.ToLower(first), strings.ToLower(last), id))
+		d.set("user", id, "first_name", first)
+		d.set("user", id, "last_name", last)
+		d.set("user", id, "email", fmt.Sprintf("user%d@example.com", id))
+		d.set("user", id, "is_active", true)
+		d.set("user", id, "group_$_ids", []string{strconv.Itoa(meetingID)})
+		d.set("user", id, fmt.Sprintf("group_$%d_ids", meetingID), []int{group})
+		d.set("user", id, "structure_level_$", []string{strconv.Itoa(meetingID)})
+		d.set("user", id, fmt.Sprintf("structure_level_$%d", meetingID), levels[r.Intn(len(levels))])
+
+		if r.Intn(10) < 8 {
+			d.set("user", id, "is_present_in_meeting_ids", []int{meetingID})
+		}
+	}
+	return groupUsers
+}
+
+func groups(d data, groupUsers map[int][]int) {
+	for _, g := range []struct {
+		id    int
+		name  string
+		perms []string
+	}{
+		{groupDefault, "Default", []string{"agenda_item.can_see", "motion.can_see", "user.can_see"}},
+		{groupAdmin, "Admin", nil},
+		{groupDelegates, "Delegates", []string{"agenda_item.can_see", "list_of_speakers.can_see", "list_of_speakers.can_be_speaker", "motion.can_see", "motion.can_create", "motion.can_support", "user.can_see"}},
+		{groupStaff, "Staff", []string{"agenda_item.can_manage", "list_of_speakers.can_manage", "motion.can_manage", "poll.can_manage", "user.can_manage"}},
+	} {
+		d.set("group", g.id, "id", g.id)
+		d.set("group", g.id, "name", g.name)
+		d.set("group", g.id, "meeting_id", meetingID)
+		d.set("group", g.id, "user_ids", groupUsers[g.id])
+		if g.perms != nil {
+			d.set("group", g.id, "permissions", g.perms)
+		}
+	}
+}
+
+func motions(d data, r *rand.Rand) {
+	for i, name := range []string{"submitted", "accepted", "rejected"} {
+		id := i + 1
+		d.set("motion_state", id, "id", id)
+		d.set("motion_state", id, "name", name)
+		d.set("motion_state", id, "meeting_id", meetingID)
+		d.set("motion_state", id, "restrictions", []string{})
+	}
+
+	for id := 1; id <= motionCount; id++ {
+		title := fmt.Sprintf("%s zu %s", words[r.Intn(len(words))], words[r.Intn(len(words))])
+		submitter := 26 + r.Intn(425)
+
+		d.set("motion", id, "id", id)
+		d.set("motion", id, "meeting_id", meetingID)
+		d.set("motion", id, "number", fmt.Sprintf("A%03d", id))
+		d.set("motion", id, "sequential_number", id)
+		d.set("motion", id, "title", title)
+		d.set("motion", id, "text", fmt.Sprintf("<p>Die Versammlung möge beschließen: %s.</p>", title))
+		d.set("motion", id, "state_id", 1+r.Intn(3))
+		d.set("motion", id, "submitter_ids", []int{id})
+		d.set("motion", id, "agenda_item_id", id)
+		d.set("motion", id, "list_of_speakers_id", id)
+
+		d.set("motion_submitter", id, "id", id)
+		d.set("motion_submitter", id, "meeting_id", meetingID)
+		d.set("motion_submitter", id, "motion_id", id)
+		d.set("motion_submitter", id, "user_id", submitter)
+		d.set("motion_submitter", id, "weight", 1)
+
+		d.set("agenda_item", id, "id", id)
+		d.set("agenda_item", id, "meeting_id", meetingID)
+		d.set("agenda_item", id, "content_object_id", fmt.Sprintf("motion/%d", id))
+		d.set("agenda_item", id, "item_number", fmt.Sprintf("TOP %d", id))
+		d.set("agenda_item", id, "weight", id)
+		d.set("agenda_item", id, "type", 1)
+
+		d.set("list_of_speakers", id, "id", id)
+		d.set("list_of_speakers", id, "meeting_id", meetingID)
+		d.set("list_of_speakers", id, "content_object_id", fmt.Sprintf("motion/%d", id))
+		d.set("list_of_speakers", id, "closed", id%10 == 0)
+	}
+}
+
+func speakers(d data, r *rand.Rand) {
+	id := 0
+	for los := 1; los <= motionCount; los++ {
+		speakerIDs := make([]int, 0, speakersPerLS)
+		for i := 0; i < speakersPerLS; i++ {
+			id++
+			speakerIDs = append(speakerIDs, id)
+
+			d.set("speaker", id, "id", id)
+			d.set("speaker", id, "meeting_id", meetingID)
+			d.set("speaker", id, "list_of_speakers_id", los)
+			d.set("speaker", id, "user_id", 26+r.Intn(425))
+			d.set("speaker", id, "weight", i+1)
+			d.set("speaker", id, "marked", r.Intn(5) == 0)
+			d.set("speaker", id, "point_of_order", false)
+			if i == 0 {
+				d.set("speaker", id, "begin_time", 1600000000+los*600)
+				d.set("speaker", id, "end_time", 1600000000+los*600+180)
+			}
+		}
+		d.set("list_of_speakers", los, "speaker_ids", speakerIDs)
+	}
+}
+
+func polls(d data, r *rand.Rand) {
+	voteID := 0
+	for id := 1; id <= pollCount; id++ {
+		d.set("poll", id, "id", id)
+		d.set("poll", id, "meeting_id", meetingID)
+		d.set("poll", id, "content_object_id", fmt.Sprintf("motion/%d", id))
+		d.set("poll", id, "title", "Abstimmung")
+		d.set("poll", id, "type", "named")
+		d.set("poll", id, "pollmethod", "YNA")
+		d.set("poll", id, "state", "finished")
+		d.set("poll", id, "option_ids", []int{id})
+		d.set("poll", id, "votescast", strconv.Itoa(votesPerPoll))
+
+		d.set("option", id, "id", id)
+		d.set("option", id, "meeting_id", meetingID)
+		d.set("option", id, "poll_id", id)
+		d.set("option", id, "content_object_id", fmt.Sprintf("motion/%d", id))
+
+		voteIDs := make([]int, 0, votesPerPoll)
+		for i := 0; i < votesPerPoll; i++ {
+			voteID++
+			voteIDs = append(voteIDs, voteID)
+
+			d.set("vote", voteID, "id", voteID)
+			d.set("vote", voteID, "meeting_id", meetingID)
+			d.set("vote", voteID, "option_id", id)
+			d.set("vote", voteID, "user_id", 26+i)
+			d.set("vote", voteID, "value", []string{"Y", "N", "A"}[r.Intn(3)])
+			d.set("vote", voteID, "weight", "1.000000")
+		}
+		d.set("option", id, "vote_ids", voteIDs)
+	}
+}
+
+// write writes the data as json object with one key per line.
+func write(d data) error {
+	keys := make([]string, 0, len(d))
+	for k := range d {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("{\n")
+	for i, k := range keys {
+		var value bytes.Buffer
+		encoder := json.NewEncoder(&value)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(d[k]); err != nil {
+			return fmt.Errorf("encoding %s: %w", k, err)
+		}
+
+		sep := ","
+		if i == len(keys)-1 {
+			sep = ""
+		}
+		fmt.Fprintf(&b, "%q: %s%s\n", k, bytes.TrimSpace(value.Bytes()), sep)
+	}
+	b.WriteString("}\n")
+
+	_, err := os.Stdout.WriteString(b.String())
+	return err
+}
@@ -0,0 +1,125 @@
+package testdata
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Numbers of the workload for the benchmarks.
+const (
+	BenchUserCount       = 1000
+	BenchMotionCount     = 500
+	BenchConnectionCount = 50
+)
+
+// Scaled returns a meeting with the given number of users and motions in the
+// format that dsmock uses.
+//
+// It uses the same groups as the meeting dataset. Each motion has a
+// submitter, an agenda item and a list of speakers. In contrast to Meeting,
+// the data is not random. It is meant for benchmarks, that need more data.
+func Scaled(users, motions int) map[string]string {
+	d := make(map[string]string)
+	set := func(collection string, id int, field string, value interface{}) {
+		bs, err := json.Marshal(value)
+		if err != nil {
+			// All values are simple go values.
+			panic(fmt.Sprintf("encoding %s/%d/%s: %v", collection, id, field, err))
+		}
+		d[fmt.Sprintf("%s/%d/%s", collection, id, field)] = string(bs)
+	}
+
+	set("meeting", MeetingID, "id", MeetingID)
+	set("meeting", MeetingID, "name", "Scaled meeting")
+	set("meeting", MeetingID, "enable_anonymous", false)
+	set("meeting", MeetingID, "default_group_id", GroupDefault)
+	set("meeting", MeetingID, "admin_group_id", GroupAdmin)
+	set("meeting", MeetingID, "group_ids", idRange(1, 4))
+	set("meeting", MeetingID, "user_ids", idRange(1, users))
+	set("meeting", MeetingID, "motion_ids", idRange(1, motions))
+	set("meeting", MeetingID, "agenda_item_ids", idRange(1, motions))
+	set("meeting", MeetingID, "list_of_speakers_ids", idRange(1, motions))
+
+	groupUsers := make(map[int][]int)
+	for id := 1; id <= users; id++ {
+		group := scaledGroup(id, users)
+		groupUsers[group] = append(groupUsers[group], id)
+
+		set("user", id, "id", id)
+		set("user", id, "username", fmt.Sprintf("user%d", id))
+		set("user", id, "first_name", fmt.Sprintf("First%d", id))
+		set("user", id, "last_name", fmt.Sprintf("Last%d", id))
+		set("user", id, "email", fmt.Sprintf("user%d@example.com", id))
+		set("user", id, "is_active", true)
+		set("user", id, "group_$_ids", []string{strconv.Itoa(MeetingID)})
+		set("user", id, fmt.Sprintf("group_$%d_ids", MeetingID), []int{group})
+	}
+
+	for _, g := range []struct {
+		id    int
+		perms []string
+	}{
+		{GroupDefault, []string{"agenda_item.can_see", "motion.can_see", "user.can_see"}},
+		{GroupAdmin, nil},
+		{GroupDelegates, []string{"agenda_item.can_see", "list_of_speakers.can_see", "motion.can_see", "motion.can_create", "user.can_see"}},
+		{GroupStaff, []string{"agenda_item.can_manage", "list_of_speakers.can_manage", "motion.can_manage", "user.can_manage"}},
+	} {
+		set("group", g.id, "id", g.id)
+		set("group", g.id, "meeting_id", MeetingID)
+		set("group", g.id, "user_ids", groupUsers[g.id])
+		if g.perms != nil {
+			set("group", g.id, "permissions", g.perms)
+		}
+	}
+
+	for id := 1; id <= motions; id++ {
+		set("motion", id, "id", id)
+		set("motion", id, "meeting_id", MeetingID)
+		set("motion", id, "number", fmt.Sprintf("A%03d", id))
+		set("motion", id, "title", fmt.Sprintf("Motion %d", id))
+		set("motion", id, "text", fmt.Sprintf("<p>Text of motion %d.</p>", id))
+		set("motion", id, "submitter_ids", []int{id})
+		set("motion", id, "agenda_item_id", id)
+		set("motion", id, "list_of_speakers_id", id)
+
+		set("motion_submitter", id, "id", id)
+		set("motion_submitter", id, "meeting_id", MeetingID)
+		set("motion_submitter", id, "motion_id", id)
+		set("motion_submitter", id, "user_id", 1+id%users)
+
+		set("agenda_item", id, "id", id)
+		set("agenda_item", id, "meeting_id", MeetingID)
+		set("agenda_item", id, "content_object_id", fmt.Sprintf("motion/%d", id))
+		set("agenda_item", id, "weight", id)
+
+		set("list_of_speakers", id, "id", id)
+		set("list_of_speakers", id, "meeting_id", MeetingID)
+		set("list_of_speakers", id, "content_object_id", fmt.Sprintf("motion/%d", id))
+	}
+	return d
+}
+
+// scaledGroup returns the group of a user. The first percent are admins, the
+// next four percent staff and the last ten percent are in the default group.
+// All others are delegates.
+func scaledGroup(id, users int) int {
+	switch {
+	case id <= users/100:
+		return GroupAdmin
+	case id <= users/20:
+		return GroupStaff
+	case id > users-users/10:
+		return GroupDefault
+	default:
+		return GroupDelegates
+	}
+}
+
+func idRange(from, to int) []int {
+	out := make([]int, 0, to-from+1)
+	for i := from; i <= to; i++ {
+		out = append(out, i)
+	}
+	return out
+}
@@ -0,0 +1,81 @@
+// Package testdata contains a realistic dataset of a mid-size meeting for
+// tests and benchmarks.
+//
+// The meeting has 500 users in four groups, 200 motions, each with an agenda
+// item and a list of speakers, and 20 finished named polls.
+//
+// The data is generated by the tool in the gen directory. To change it, change
+// the tool and run
+//
+//	go generate ./pkg/testdata
+package testdata
+
+import (
+	// Needed for the embed directive.
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+)
+
+//go:generate sh -c "go run ./gen > meeting.json"
+
+// Numbers of the meeting dataset.
+const (
+	MeetingID    = 1
+	UserCount    = 500
+	MotionCount  = 200
+	PollCount    = 20
+	VotesPerPoll = 50
+)
+
+// Group ids of the meeting dataset.
+const (
+	GroupDefault = iota + 1
+	GroupAdmin
+	GroupDelegates
+	GroupStaff
+)
+
+//go:embed meeting.json
+var meetingJSON []byte
+
+// Meeting returns the meeting dataset in the format that dsmock uses. It is a
+// map from each key to its json encoded value.
+//
+// Each call returns a new map, so the caller is allowed to change it.
+func Meeting() map[string]string {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(meetingJSON, &raw); err != nil {
+		// The file is embedded and tested. This can only happen, if the file
+		// is broken.
+		panic(fmt.Sprintf("decoding meeting.json: %v", err))
+	}
+
+	data := make(map[string]string, len(raw))
+	for k, v := range raw {
+		data[k] = string(v)
+	}
+	return data
+}
+
+// MeetingDatastore returns a mock datastore that contains the meeting
+// dataset.
+func MeetingDatastore(closed <-chan struct{}) *dsmock.MockDatastore {
+	return dsmock.NewMockDatastore(closed, Meeting())
+}
+
+// Keys returns all keys of the meeting dataset that belong to the given
+// collection.
+func Keys(collection string) []string {
+	prefix := collection + "/"
+	var keys []string
+	for k := range Meeting() {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,296 @@
+// Package testharness runs the autoupdate service with a mock datastore and a
+// mock restricter in a httptest server.
+//
+// It is meant for end-to-end tests of features, so each test does not have to
+// build the service by itself:
+//
+//	h := testharness.New(t, dsmock.YAMLData(`user/1/username: hugo`))
+//	c := h.ConnectKeys(1, "user/1/username")
+//	c.AssertNext(`{"user/1/username":"hugo"}`)
+//
+//	h.Datastore.Send(map[string]string{"user/1/username": `"new"`})
+//	c.AssertNext(`{"user/1/username":"new"}`)
+package testharness
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/internal/test"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/datastore"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/service"
+)
+
+// DefaultTimeout is the default time a connection waits for a message.
+const DefaultTimeout = time.Second
+
+// userHeader is the http header, that contains the user id of a request.
+const userHeader = "X-Testharness-User-Id"
+
+// Harness is a running autoupdate service.
+//
+// Has to be created with New.
+type Harness struct {
+	t testing.TB
+
+	// Datastore is the datastore of the service. Use Send to update values.
+	Datastore *dsmock.MockDatastore
+
+	restricter *test.MockRestricter
+
+	// Service is the autoupdate service.
+	Service *service.Service
+
+	// Server serves the handler of the service.
+	Server *httptest.Server
+
+	// Timeout is the time a connection waits for a message.
+	Timeout time.Duration
+}
+
+// Option is an optional argument for New.
+type Option func(*config)
+
+type config struct {
+	dsOptions      []datastore.Option
+	serviceOptions []service.Option
+}
+
+// WithDatastoreOptions sets options for the mock datastore.
+func WithDatastoreOptions(options ...datastore.Option) Option {
+	return func(c *config) {
+		c.dsOptions = options
+	}
+}
+
+// WithServiceOptions sets options for the service.
+func WithServiceOptions(options ...service.Option) Option {
+	return func(c *config) {
+		c.serviceOptions = options
+	}
+}
+
+// New starts the service with the given data. It is stopped, when the test
+// is finished.
+func New(t testing.TB, data map[string]string, options ...Option) *Harness {
+	t.Helper()
+
+	var cfg config
+	for _, o := range options {
+		o(&cfg)
+	}
+
+	closed := make(chan struct{})
+	ds := dsmock.NewMockDatastore(closed, data, cfg.dsOptions...)
+	restricter := test.RestrictAllowed()
+	s := service.New(ds, headerAuth{}, restricter, closed, cfg.serviceOptions...)
+	ts := httptest.NewServer(s.Handler())
+
+	t.Cleanup(func() {
+		ts.Close()
+		close(closed)
+	})
+
+	return &Harness{
+		t:          t,
+		Datastore:  ds,
+		restricter: restricter,
+		Service:    s,
+		Server:     ts,
+		Timeout:    DefaultTimeout,
+	}
+}
+
+// RestrictValues replaces the values of the given keys for all users. All
+// other values are allowed. It has to be called before a connection is
+// opened.
+func (h *Harness) RestrictValues(values map[string]string) {
+	h.restricter.Values = values
+}
+
+// Connect opens a connection for the user with the given keysbuilder request.
+//
+// The query is added to the url, for example `deleted=1`.
+func (h *Harness) Connect(uid int, request string, query string) *Connection {
+	h.t.Helper()
+
+	url := h.Server.URL + "/system/autoupdate"
+	if query != "" {
+		url += "?" + query
+	}
+	return h.connect(uid, "POST", url, strings.NewReader(request))
+}
+
+// ConnectKeys opens a connection for the user to the given keys.
+func (h *Harness) ConnectKeys(uid int, keys ...string) *Connection {
+	h.t.Helper()
+
+	url := h.Server.URL + "/system/autoupdate/keys?" + strings.Join(keys, ",")
+	return h.connect(uid, "GET", url, nil)
+}
+
+func (h *Harness) connect(uid int, method, url string, body io.Reader) *Connection {
+	h.t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		cancel()
+		h.t.Fatalf("Creating request: %v", err)
+	}
+	req.Header.Set(userHeader, strconv.Itoa(uid))
+
+	resp, err := h.Server.Client().Do(req)
+	if err != nil {
+		cancel()
+		h.t.Fatalf("Sending request: %v", err)
+	}
+
+	c := &Connection{
+		t:       h.t,
+		timeout: h.Timeout,
+		cancel:  cancel,
+		Status:  resp.StatusCode,
+		lines:   make(chan []byte),
+	}
+	h.t.Cleanup(c.Close)
+
+	go c.read(ctx, resp.Body)
+	return c
+}
+
+// Connection is an open request to the service.
+type Connection struct {
+	t       testing.TB
+	timeout time.Duration
+	cancel  context.CancelFunc
+	lines   chan []byte
+
+	// Status is the http status code of the response.
+	Status int
+}
+
+// read sends each line of the body to c.lines. It closes c.lines at the end of
+// the body.
+func (c *Connection) read(ctx context.Context, body io.ReadCloser) {
+	defer close(c.lines)
+	defer body.Close()
+
+	r := bufio.NewReader(body)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			select {
+			case c.lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// NextRaw returns the next message. It fails the test, if there is no message
+// before the timeout.
+func (c *Connection) NextRaw() []byte {
+	c.t.Helper()
+
+	select {
+	case line, ok := <-c.lines:
+		if !ok {
+			c.t.Fatalf("Connection was closed, expected a message")
+		}
+		return line
+
+	case <-time.After(c.timeout):
+		c.t.Fatalf("Got no message after %s", c.timeout)
+	}
+	return nil
+}
+
+// Next returns the next message as map. It fails the test, if there is no
+// message before the timeout or the message is not a json object.
+func (c *Connection) Next() map[string]json.RawMessage {
+	c.t.Helper()
+
+	line := c.NextRaw()
+
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(line, &data); err != nil {
+		c.t.Fatalf("Decoding message `%s`: %v", line, err)
+	}
+	return data
+}
+
+// AssertNext checks, that the next message is equal to the given json.
+func (c *Connection) AssertNext(expect string) {
+	c.t.Helper()
+
+	got := c.NextRaw()
+
+	var expectValue, gotValue interface{}
+	if err := json.Unmarshal([]byte(expect), &expectValue); err != nil {
+		c.t.Fatalf("Decoding expected message `%s`: %v", expect, err)
+	}
+
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		c.t.Fatalf("Decoding message `%s`: %v", got, err)
+	}
+
+	if !reflect.DeepEqual(gotValue, expectValue) {
+		c.t.Errorf("Got message `%s`, expected `%s`", bytes.TrimSpace(got), expect)
+	}
+}
+
+// AssertNoMessage checks, that there is no message in the given duration.
+func (c *Connection) AssertNoMessage(d time.Duration) {
+	c.t.Helper()
+
+	select {
+	case line, ok := <-c.lines:
+		if ok {
+			c.t.Errorf("Got message `%s`, expected none", line)
+		}
+	case <-time.After(d):
+	}
+}
+
+// Close closes the connection. It is called automatically, when the test is
+// finished.
+func (c *Connection) Close() {
+	c.cancel()
+}
+
+// headerAuth reads the user id from the userHeader.
+type headerAuth struct{}
+
+type userKey struct{}
+
+// Authenticate reads the user id from the request.
+func (headerAuth) Authenticate(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+	uid, err := strconv.Atoi(r.Header.Get(userHeader))
+	if err != nil {
+		return nil, fmt.Errorf("invalid user id in header %s: %w", userHeader, err)
+	}
+	return context.WithValue(r.Context(), userKey{}, uid), nil
+}
+
+// FromContext returns the user id from the context.
+func (headerAuth) FromContext(ctx context.Context) int {
+	uid, _ := ctx.Value(userKey{}).(int)
+	return uid
+}
@@ -0,0 +1,53 @@
+package testharness_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/dsmock"
+	"github.com/OpenSlides/openslides-autoupdate-service/pkg/testharness"
+)
+
+func TestHarnessKeys(t *testing.T) {
+	h := testharness.New(t, dsmock.YAMLData(`
+	user/1/username: hugo
+	`))
+
+	c := h.ConnectKeys(1, "user/1/username")
+	c.AssertNext(`{"user/1/username":"hugo"}`)
+
+	h.Datastore.Send(map[string]string{"user/1/username": `"new"`})
+	c.AssertNext(`{"user/1/username":"new"}`)
+
+	h.Datastore.Send(map[string]string{"user/2/username": `"other"`})
+	c.AssertNoMessage(10 * time.Millisecond)
+}
+
+func TestHarnessComplex(t *testing.T) {
+	h := testharness.New(t, dsmock.YAMLData(`
+	user/1:
+		username: hugo
+		first_name: Hugo
+	`))
+	h.RestrictValues(map[string]string{"user/1/first_name": `"hidden"`})
+
+	c := h.Connect(5, `[{"ids":[1],"collection":"user","fields":{"username":null,"first_name":null}}]`, "")
+	if c.Status != http.StatusOK {
+		t.Fatalf("Got status %d, expected %d", c.Status, http.StatusOK)
+	}
+
+	data := c.Next()
+	if got := string(data["user/1/first_name"]); got != `"hidden"` {
+		t.Errorf("Got first_name %s, expected the value of the restricter", got)
+	}
+}
+
+func TestHarnessInvalidRequest(t *testing.T) {
+	h := testharness.New(t, nil)
+
+	c := h.Connect(1, `[{"ids":[1],"collection":"user","fields":{"name":{"type":"unknown"}}}]`, "")
+	if c.Status != http.StatusBadRequest {
+		t.Errorf("Got status %d, expected %d", c.Status, http.StatusBadRequest)
+	}
+}